@@ -1,13 +1,66 @@
 package authz
 
+import (
+	"context"
+	"fmt"
+
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+)
+
 const (
 	// Access token scopes.
-	ScopeUserAll       = "user:all"        // Full control of all resources accessible to the user account.
-	ScopeSiteAdminSudo = "site-admin:sudo" // Ability to perform any action as any other user.
+	ScopeUserAll                = "user:all"                 // Full control of all resources accessible to the user account.
+	ScopeSiteAdminSudo          = "site-admin:sudo"          // Ability to perform any action as any other user.
+	ScopeSearchRead             = "search:read"              // Ability to run searches.
+	ScopeRepoRead               = "repo:read"                // Read-only access to repositories (files, commits, and code intelligence).
+	ScopeSettingsWrite          = "settings:write"           // Ability to change the settings of the user, their organizations, and (for site admins) the site.
+	ScopeLSIFUpload             = "lsif:upload"              // Ability to upload LSIF data.
+	ScopeExternalServicesManage = "external-services:manage" // Ability to add, update, and delete external services (for site admins).
 )
 
 // AllScopes is a list of all known access token scopes.
 var AllScopes = []string{
 	ScopeUserAll,
 	ScopeSiteAdminSudo,
+	ScopeSearchRead,
+	ScopeRepoRead,
+	ScopeSettingsWrite,
+	ScopeLSIFUpload,
+	ScopeExternalServicesManage,
+}
+
+// HasScope reports whether an access token with the given scopes grants scope.
+// The "user:all" scope grants every scope except "site-admin:sudo".
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || (s == ScopeUserAll && scope != ScopeSiteAdminSudo) {
+			return true
+		}
+	}
+	return false
+}
+
+// InsufficientScopeError occurs when the actor authenticated with an access
+// token that does not grant the scope required for an operation.
+type InsufficientScopeError struct {
+	Scope string // the required scope
+}
+
+func (e *InsufficientScopeError) Error() string {
+	return fmt.Sprintf("access token does not have the required scope %q", e.Scope)
+}
+
+// CheckActorScope returns an *InsufficientScopeError if the actor in ctx
+// authenticated with an access token that does not grant scope. Actors that
+// did not authenticate with an access token (such as those with a session
+// cookie) are not restricted by scopes.
+//
+// 🚨 SECURITY: This does not check whether the actor is otherwise permitted to
+// perform the operation; callers must still check that separately.
+func CheckActorScope(ctx context.Context, scope string) error {
+	a := actor.FromContext(ctx)
+	if a.AccessTokenScopes == nil || HasScope(a.AccessTokenScopes, scope) {
+		return nil
+	}
+	return &InsufficientScopeError{Scope: scope}
 }
@@ -0,0 +1,63 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+)
+
+func TestHasScope(t *testing.T) {
+	for _, tc := range []struct {
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{nil, ScopeUserAll, false},
+		{[]string{ScopeUserAll}, ScopeUserAll, true},
+		{[]string{ScopeUserAll}, ScopeSearchRead, true},
+		{[]string{ScopeUserAll}, ScopeExternalServicesManage, true},
+		{[]string{ScopeUserAll}, ScopeSiteAdminSudo, false},
+		{[]string{ScopeSiteAdminSudo}, ScopeSiteAdminSudo, true},
+		{[]string{ScopeSiteAdminSudo}, ScopeRepoRead, false},
+		{[]string{ScopeSearchRead}, ScopeSearchRead, true},
+		{[]string{ScopeSearchRead}, ScopeRepoRead, false},
+		{[]string{ScopeSearchRead}, ScopeUserAll, false},
+		{[]string{ScopeSearchRead, ScopeRepoRead}, ScopeRepoRead, true},
+	} {
+		if have := HasScope(tc.scopes, tc.scope); have != tc.want {
+			t.Errorf("HasScope(%q, %q): have %t, want %t", tc.scopes, tc.scope, have, tc.want)
+		}
+	}
+}
+
+func TestCheckActorScope(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		actor   *actor.Actor
+		scope   string
+		wantErr bool
+	}{
+		{"unauthenticated", nil, ScopeSettingsWrite, false},
+		{"session", &actor.Actor{UID: 1}, ScopeSettingsWrite, false},
+		{"token with scope", &actor.Actor{UID: 1, AccessTokenScopes: []string{ScopeSettingsWrite}}, ScopeSettingsWrite, false},
+		{"token with user:all", &actor.Actor{UID: 1, AccessTokenScopes: []string{ScopeUserAll}}, ScopeSettingsWrite, false},
+		{"token without scope", &actor.Actor{UID: 1, AccessTokenScopes: []string{ScopeLSIFUpload}}, ScopeSettingsWrite, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.actor != nil {
+				ctx = actor.WithActor(ctx, tc.actor)
+			}
+			err := CheckActorScope(ctx, tc.scope)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got err %v, want error: %t", err, tc.wantErr)
+			}
+			if err != nil {
+				if e, ok := err.(*InsufficientScopeError); !ok || e.Scope != tc.scope {
+					t.Errorf("got err %#v, want *InsufficientScopeError for %q", err, tc.scope)
+				}
+			}
+		})
+	}
+}
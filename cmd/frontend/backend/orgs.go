@@ -19,6 +19,9 @@ func CheckOrgAccess(ctx context.Context, orgID int32) error {
 	if hasAuthzBypass(ctx) {
 		return nil
 	}
+	if err := checkAccessTokenScope(ctx); err != nil {
+		return err
+	}
 	currentUser, err := currentUser(ctx)
 	if err != nil {
 		return err
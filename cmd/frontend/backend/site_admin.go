@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/actor"
@@ -18,6 +19,9 @@ func CheckCurrentUserIsSiteAdmin(ctx context.Context) error {
 	if hasAuthzBypass(ctx) {
 		return nil
 	}
+	if err := checkAccessTokenScope(ctx); err != nil {
+		return err
+	}
 	user, err := currentUser(ctx)
 	if err != nil {
 		return err
@@ -69,6 +73,9 @@ func CheckSiteAdminOrSameUser(ctx context.Context, subjectUserID int32) error {
 	if hasAuthzBypass(ctx) {
 		return nil
 	}
+	if err := checkAccessTokenScope(ctx); err != nil {
+		return err
+	}
 	actor := actor.FromContext(ctx)
 	if actor.IsAuthenticated() && actor.UID == subjectUserID {
 		return nil
@@ -108,6 +115,27 @@ func hasAuthzBypass(ctx context.Context) bool {
 	return ctx.Value(authzBypass) != nil
 }
 
+// WithAccessTokenScope returns a context in which the backend.CheckXyz funcs accept actors whose
+// access token grants scope. Otherwise they require access tokens to grant the "user:all" scope,
+// so that access tokens with narrower scopes (such as "search:read") can't be used to administer
+// users, organizations, or the site. It is used by operations that access tokens with a narrower
+// scope may perform, such as editing settings with the "settings:write" scope.
+func WithAccessTokenScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, accessTokenScope, scope)
+}
+
+// checkAccessTokenScope returns an error if the actor authenticated with an access token that
+// grants neither the "user:all" scope nor the scope set with WithAccessTokenScope.
+func checkAccessTokenScope(ctx context.Context) error {
+	if scope, ok := ctx.Value(accessTokenScope).(string); ok {
+		return authz.CheckActorScope(ctx, scope)
+	}
+	return authz.CheckActorScope(ctx, authz.ScopeUserAll)
+}
+
 type contextKey int
 
-const authzBypass contextKey = iota
+const (
+	authzBypass contextKey = iota
+	accessTokenScope
+)
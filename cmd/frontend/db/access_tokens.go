@@ -6,12 +6,16 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"sync"
 	"time"
 
 	"github.com/keegancsmith/sqlf"
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbutil"
+	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
 // AccessToken describes an access token. The actual token (that a caller must supply to
@@ -24,6 +28,14 @@ type AccessToken struct {
 	CreatorUserID int32
 	CreatedAt     time.Time
 	LastUsedAt    *time.Time
+	ExpiresAt     *time.Time // nil if the access token never expires
+}
+
+// AccessTokenUse describes a request authenticated with an access token, which is recorded in the
+// access token audit log.
+type AccessTokenUse struct {
+	IP       string // the IP address of the client
+	Endpoint string // the requested endpoint (such as "POST /.api/graphql")
 }
 
 // ErrAccessTokenNotFound occurs when a database operation expects a specific access token to exist
@@ -46,13 +58,20 @@ type accessTokens struct{}
 // space; also bcrypt is slow and would add noticeable latency to each request that supplied a
 // token.
 //
+// If expiresAt is non-nil, the access token is invalid after that time.
+//
 // 🚨 SECURITY: The caller must ensure that the actor is permitted to create tokens for the
 // specified user (i.e., that the actor is either the user or a site admin).
-func (s *accessTokens) Create(ctx context.Context, subjectUserID int32, scopes []string, note string, creatorUserID int32) (id int64, token string, err error) {
+func (s *accessTokens) Create(ctx context.Context, subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (id int64, token string, err error) {
 	if Mocks.AccessTokens.Create != nil {
-		return Mocks.AccessTokens.Create(subjectUserID, scopes, note, creatorUserID)
+		return Mocks.AccessTokens.Create(subjectUserID, scopes, note, creatorUserID, expiresAt)
 	}
+	return s.create(ctx, dbconn.Global, subjectUserID, scopes, note, creatorUserID, expiresAt)
+}
 
+func (s *accessTokens) create(ctx context.Context, dbh interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}, subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (id int64, token string, err error) {
 	var b [20]byte
 	if _, err := rand.Read(b[:]); err != nil {
 		return 0, "", err
@@ -65,7 +84,7 @@ func (s *accessTokens) Create(ctx context.Context, subjectUserID int32, scopes [
 		return 0, "", errors.New("access tokens without scopes are not supported")
 	}
 
-	if err := dbconn.Global.QueryRowContext(ctx,
+	if err := dbh.QueryRowContext(ctx,
 		// Include users table query (with "FOR UPDATE") to ensure that subject/creator users have
 		// not been deleted. If they were deleted, the query will return an error.
 		`
@@ -76,39 +95,39 @@ creator_user AS (
   SELECT id FROM users WHERE id=$5 AND deleted_at IS NULL FOR UPDATE
 ),
 insert_values AS (
-  SELECT subject_user.id AS subject_user_id, $2::text[] AS scopes, $3::bytea AS value_sha256, $4::text AS note, creator_user.id AS creator_user_id
+  SELECT subject_user.id AS subject_user_id, $2::text[] AS scopes, $3::bytea AS value_sha256, $4::text AS note, creator_user.id AS creator_user_id, $6::timestamptz AS expires_at
   FROM subject_user, creator_user
 )
-INSERT INTO access_tokens(subject_user_id, scopes, value_sha256, note, creator_user_id) SELECT * FROM insert_values RETURNING id
+INSERT INTO access_tokens(subject_user_id, scopes, value_sha256, note, creator_user_id, expires_at) SELECT * FROM insert_values RETURNING id
 `,
-		subjectUserID, pq.Array(scopes), toSHA256Bytes(b[:]), note, creatorUserID,
+		subjectUserID, pq.Array(scopes), toSHA256Bytes(b[:]), note, creatorUserID, expiresAt,
 	).Scan(&id); err != nil {
 		return 0, "", err
 	}
 	return id, token, nil
 }
 
-// Lookup looks up the access token. If it's valid and contains the required scope, it returns the
-// subject's user ID. Otherwise ErrAccessTokenNotFound is returned.
+// Lookup looks up the access token. If it's valid (i.e., not deleted or expired), it returns the
+// access token, including its scopes. Otherwise ErrAccessTokenNotFound is returned.
 //
-// Calling Lookup also updates the access token's last-used-at date.
+// Calling Lookup also updates the access token's last-used-at date and records the use in the
+// access token audit log. Recording the use is best-effort and happens in the background, so that
+// a slow or failing audit log write never delays or fails authentication.
 //
-// 🚨 SECURITY: This returns a user ID if and only if the tokenHexEncoded corresponds to a valid,
-// non-deleted access token.
-func (s *accessTokens) Lookup(ctx context.Context, tokenHexEncoded string, requiredScope string) (subjectUserID int32, err error) {
+// 🚨 SECURITY: This returns an access token if and only if the tokenHexEncoded corresponds to a
+// valid, non-deleted, unexpired access token. The caller must check that the token's scopes permit
+// the operation it is used for.
+func (s *accessTokens) Lookup(ctx context.Context, tokenHexEncoded string, use AccessTokenUse) (*AccessToken, error) {
 	if Mocks.AccessTokens.Lookup != nil {
-		return Mocks.AccessTokens.Lookup(tokenHexEncoded, requiredScope)
-	}
-
-	if requiredScope == "" {
-		return 0, errors.New("no scope provided in access token lookup")
+		return Mocks.AccessTokens.Lookup(tokenHexEncoded, use)
 	}
 
 	token, err := hex.DecodeString(tokenHexEncoded)
 	if err != nil {
-		return 0, errors.Wrap(err, "AccessTokens.Lookup")
+		return nil, errors.Wrap(err, "AccessTokens.Lookup")
 	}
 
+	var t AccessToken
 	if err := dbconn.Global.QueryRowContext(ctx,
 		// Ensure that subject and creator users still exist.
 		`
@@ -116,19 +135,134 @@ UPDATE access_tokens t SET last_used_at=now()
 FROM access_tokens t2
 JOIN users subject_user ON t2.subject_user_id=subject_user.id
 JOIN users creator_user ON t2.creator_user_id=creator_user.id
-WHERE t.value_sha256=$1 AND t.deleted_at IS NULL AND
-  subject_user.deleted_at IS NULL AND creator_user.deleted_at IS NULL AND
-  $2 = ANY (t.scopes)
-RETURNING t.subject_user_id
+WHERE t.value_sha256=$1 AND t2.id=t.id AND t.deleted_at IS NULL AND
+  (t.expires_at IS NULL OR t.expires_at > now()) AND
+  subject_user.deleted_at IS NULL AND creator_user.deleted_at IS NULL
+RETURNING t.id, t.subject_user_id, t.scopes, t.note, t.creator_user_id, t.created_at, t.last_used_at, t.expires_at
 `,
-		toSHA256Bytes(token), requiredScope,
-	).Scan(&subjectUserID); err != nil {
+		toSHA256Bytes(token),
+	).Scan(&t.ID, &t.SubjectUserID, pq.Array(&t.Scopes), &t.Note, &t.CreatorUserID, &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt); err != nil {
 		if err == sql.ErrNoRows {
-			return 0, ErrAccessTokenNotFound
+			return nil, ErrAccessTokenNotFound
+		}
+		return nil, err
+	}
+
+	recordAccessTokenUse(t.ID, use)
+	return &t, nil
+}
+
+// accessTokenAuditLogQueue holds the access token uses that are waiting to be written to the audit
+// log by the goroutine started by accessTokenAuditLogOnce.
+var (
+	accessTokenAuditLogQueue = make(chan accessTokenAuditLogEntry, 1000)
+	accessTokenAuditLogOnce  sync.Once
+)
+
+type accessTokenAuditLogEntry struct {
+	accessTokenID int64
+	use           AccessTokenUse
+}
+
+// recordAccessTokenUse queues the use of the access token to be written to the audit log. If the
+// queue is full (because audit log writes are slow or failing), the use is dropped instead of
+// blocking.
+func recordAccessTokenUse(accessTokenID int64, use AccessTokenUse) {
+	accessTokenAuditLogOnce.Do(func() {
+		goroutine.Go(writeAccessTokenAuditLog)
+	})
+	select {
+	case accessTokenAuditLogQueue <- accessTokenAuditLogEntry{accessTokenID: accessTokenID, use: use}:
+	default:
+		log15.Warn("Dropped access token audit log entry because the queue is full.", "accessTokenID", accessTokenID, "ip", use.IP, "endpoint", use.Endpoint)
+	}
+}
+
+func writeAccessTokenAuditLog() {
+	for e := range accessTokenAuditLogQueue {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if _, err := dbconn.Global.ExecContext(ctx,
+			"INSERT INTO access_token_audit_logs(access_token_id, ip, endpoint) VALUES($1, $2, $3)",
+			e.accessTokenID, e.use.IP, e.use.Endpoint,
+		); err != nil {
+			log15.Error("Failed to write access token audit log entry.", "accessTokenID", e.accessTokenID, "error", err)
 		}
+		cancel()
+	}
+}
+
+// DeleteAuditLogsOlderThan deletes the access token audit log entries that were recorded before t,
+// and returns the number of deleted entries.
+func (s *accessTokens) DeleteAuditLogsOlderThan(ctx context.Context, t time.Time) (int64, error) {
+	if Mocks.AccessTokens.DeleteAuditLogsOlderThan != nil {
+		return Mocks.AccessTokens.DeleteAuditLogsOlderThan(t)
+	}
+	res, err := dbconn.Global.ExecContext(ctx, `DELETE FROM access_token_audit_logs WHERE "timestamp" < $1`, t.UTC())
+	if err != nil {
 		return 0, err
 	}
-	return subjectUserID, nil
+	return res.RowsAffected()
+}
+
+// Rotate replaces the access token with the given ID and subject user by a new access token with the
+// same scopes and note, which is created by creatorUserID and expires at expiresAt (if non-nil). The
+// new secret token value is returned.
+//
+// The old access token remains valid until graceUntil (or until it expires, if that is sooner), so
+// that API clients can switch to the new access token without interruption.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is permitted to create tokens for the specified
+// user (i.e., that the actor is either the user or a site admin).
+func (s *accessTokens) Rotate(ctx context.Context, id int64, subjectUserID, creatorUserID int32, graceUntil time.Time, expiresAt *time.Time) (newID int64, token string, err error) {
+	if Mocks.AccessTokens.Rotate != nil {
+		return Mocks.AccessTokens.Rotate(id, subjectUserID, creatorUserID, graceUntil, expiresAt)
+	}
+
+	err = dbutil.Transaction(ctx, dbconn.Global, func(tx *sql.Tx) error {
+		var (
+			scopes []string
+			note   string
+		)
+		// The old access token's subject user has already been notified of its (new) expiry by
+		// rotating it, so don't send an expiry notification for it.
+		if err := tx.QueryRowContext(ctx, `
+UPDATE access_tokens SET expires_at=LEAST(expires_at, $3), expiry_notified_at=COALESCE(expiry_notified_at, now())
+WHERE id=$1 AND subject_user_id=$2 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+RETURNING scopes, note
+`,
+			id, subjectUserID, graceUntil,
+		).Scan(pq.Array(&scopes), &note); err != nil {
+			if err == sql.ErrNoRows {
+				return ErrAccessTokenNotFound
+			}
+			return err
+		}
+
+		newID, token, err = s.create(ctx, tx, subjectUserID, scopes, note, creatorUserID, expiresAt)
+		return err
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	return newID, token, nil
+}
+
+// ClaimExpiryNotifications returns the valid access tokens that expire before expiresBefore and
+// whose subject users have not yet been notified of their expiry, and marks them as notified. Each
+// access token is returned at most once (even to concurrent callers), so the caller is responsible
+// for notifying the subject users of the returned access tokens.
+func (s *accessTokens) ClaimExpiryNotifications(ctx context.Context, expiresBefore time.Time) ([]*AccessToken, error) {
+	rows, err := dbconn.Global.QueryContext(ctx, `
+UPDATE access_tokens SET expiry_notified_at=now()
+WHERE deleted_at IS NULL AND expiry_notified_at IS NULL AND expires_at > now() AND expires_at < $1
+RETURNING id, subject_user_id, scopes, note, creator_user_id, created_at, last_used_at, expires_at
+`,
+		expiresBefore,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanAccessTokens(rows)
 }
 
 // GetByID retrieves the access token (if any) given its ID.
@@ -181,7 +315,7 @@ func (s *accessTokens) List(ctx context.Context, opt AccessTokensListOptions) ([
 
 func (s *accessTokens) list(ctx context.Context, conds []*sqlf.Query, limitOffset *LimitOffset) ([]*AccessToken, error) {
 	q := sqlf.Sprintf(`
-SELECT id, subject_user_id, scopes, note, creator_user_id, created_at, last_used_at FROM access_tokens
+SELECT id, subject_user_id, scopes, note, creator_user_id, created_at, last_used_at, expires_at FROM access_tokens
 WHERE (%s)
 ORDER BY now() - created_at < interval '5 minutes' DESC, -- show recently created tokens first
 last_used_at DESC NULLS FIRST, -- ensure newly created tokens show first
@@ -195,17 +329,21 @@ created_at DESC
 	if err != nil {
 		return nil, err
 	}
+	return scanAccessTokens(rows)
+}
+
+func scanAccessTokens(rows *sql.Rows) ([]*AccessToken, error) {
 	defer rows.Close()
 
 	var results []*AccessToken
 	for rows.Next() {
 		var t AccessToken
-		if err := rows.Scan(&t.ID, &t.SubjectUserID, pq.Array(&t.Scopes), &t.Note, &t.CreatorUserID, &t.CreatedAt, &t.LastUsedAt); err != nil {
+		if err := rows.Scan(&t.ID, &t.SubjectUserID, pq.Array(&t.Scopes), &t.Note, &t.CreatorUserID, &t.CreatedAt, &t.LastUsedAt, &t.ExpiresAt); err != nil {
 			return nil, err
 		}
 		results = append(results, &t)
 	}
-	return results, nil
+	return results, rows.Err()
 }
 
 // Count counts all access tokens that satisfy the options (ignoring limit and offset).
@@ -264,8 +402,11 @@ func toSHA256Bytes(input []byte) []byte {
 }
 
 type MockAccessTokens struct {
-	Create     func(subjectUserID int32, scopes []string, note string, creatorUserID int32) (id int64, token string, err error)
+	Create     func(subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (id int64, token string, err error)
 	DeleteByID func(id int64, subjectUserID int32) error
-	Lookup     func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error)
+	Lookup     func(tokenHexEncoded string, use AccessTokenUse) (*AccessToken, error)
+	Rotate     func(id int64, subjectUserID, creatorUserID int32, graceUntil time.Time, expiresAt *time.Time) (newID int64, token string, err error)
 	GetByID    func(id int64) (*AccessToken, error)
+
+	DeleteAuditLogsOlderThan func(t time.Time) (int64, error)
 }
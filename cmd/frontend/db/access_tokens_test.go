@@ -1,9 +1,12 @@
 package db
 
 import (
+	"database/sql"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
 )
 
@@ -32,7 +35,7 @@ func TestAccessTokens_Create(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tid0, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a", "b"}, "n0", creator.ID)
+	tid0, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a", "b"}, "n0", creator.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -51,12 +54,12 @@ func TestAccessTokens_Create(t *testing.T) {
 		t.Errorf("got %q, want %q", got.Note, want)
 	}
 
-	gotSubjectUserID, err := AccessTokens.Lookup(ctx, tv0, "a")
+	gotToken, err := AccessTokens.Lookup(ctx, tv0, AccessTokenUse{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if want := subject.ID; gotSubjectUserID != want {
-		t.Errorf("got %v, want %v", gotSubjectUserID, want)
+	if want := subject.ID; gotToken.SubjectUserID != want {
+		t.Errorf("got %v, want %v", gotToken.SubjectUserID, want)
 	}
 
 	ts, err := AccessTokens.List(ctx, AccessTokensListOptions{SubjectUserID: subject.ID})
@@ -105,11 +108,11 @@ func TestAccessTokens_List(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, _, err = AccessTokens.Create(ctx, subject1.ID, []string{"a", "b"}, "n0", subject1.ID)
+	_, _, err = AccessTokens.Create(ctx, subject1.ID, []string{"a", "b"}, "n0", subject1.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, _, err = AccessTokens.Create(ctx, subject1.ID, []string{"a", "b"}, "n1", subject1.ID)
+	_, _, err = AccessTokens.Create(ctx, subject1.ID, []string{"a", "b"}, "n1", subject1.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -183,43 +186,93 @@ func TestAccessTokens_Lookup(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tid0, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a", "b"}, "n0", creator.ID)
+	tid0, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a", "b"}, "n0", creator.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	for _, scope := range []string{"a", "b"} {
-		gotSubjectUserID, err := AccessTokens.Lookup(ctx, tv0, scope)
-		if err != nil {
-			t.Fatal(err)
+	use := AccessTokenUse{IP: "127.0.0.1", Endpoint: "POST /.api/graphql"}
+	gotToken, err := AccessTokens.Lookup(ctx, tv0, use)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := subject.ID; gotToken.SubjectUserID != want {
+		t.Errorf("got %v, want %v", gotToken.SubjectUserID, want)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(gotToken.Scopes, want) {
+		t.Errorf("got token scopes %q, want %q", gotToken.Scopes, want)
+	}
+	if gotToken.LastUsedAt == nil {
+		t.Error("got LastUsedAt == nil, want it to be set by Lookup")
+	}
+
+	// Ensure that the use was recorded in the audit log (which happens in the background).
+	var gotUse AccessTokenUse
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		err := dbconn.Global.QueryRowContext(ctx, "SELECT ip, endpoint FROM access_token_audit_logs WHERE access_token_id=$1", tid0).Scan(&gotUse.IP, &gotUse.Endpoint)
+		if err == nil {
+			break
 		}
-		if want := subject.ID; gotSubjectUserID != want {
-			t.Errorf("got %v, want %v", gotSubjectUserID, want)
+		if err != sql.ErrNoRows || time.Now().After(deadline) {
+			t.Fatal(err)
 		}
 	}
+	if gotUse != use {
+		t.Errorf("got audit log entry %+v, want %+v", gotUse, use)
+	}
 
-	// Lookup with a nonexistent scope and ensure it fails.
-	if _, err := AccessTokens.Lookup(ctx, tv0, "x"); err == nil {
+	// Ensure that old audit log entries are deleted.
+	deleted, err := AccessTokens.DeleteAuditLogsOlderThan(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Lookup with an empty scope and ensure it fails.
-	if _, err := AccessTokens.Lookup(ctx, tv0, ""); err == nil {
+	if want := int64(0); deleted != want {
+		t.Errorf("got %d deleted audit log entries, want %d", deleted, want)
+	}
+	deleted, err = AccessTokens.DeleteAuditLogsOlderThan(ctx, time.Now().Add(time.Hour))
+	if err != nil {
 		t.Fatal(err)
 	}
+	if want := int64(1); deleted != want {
+		t.Errorf("got %d deleted audit log entries, want %d", deleted, want)
+	}
 
 	// Delete a token and ensure Lookup fails on it.
 	if err := AccessTokens.DeleteByID(ctx, tid0, subject.ID); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := AccessTokens.Lookup(ctx, tv0, "a"); err == nil {
+	if _, err := AccessTokens.Lookup(ctx, tv0, AccessTokenUse{}); err == nil {
 		t.Fatal(err)
 	}
 
 	// Try to Lookup a token that was never created.
-	if _, err := AccessTokens.Lookup(ctx, "abcdefg" /* this token value was never created */, "a"); err == nil {
+	if _, err := AccessTokens.Lookup(ctx, "abcdefg" /* this token value was never created */, AccessTokenUse{}); err == nil {
+		t.Fatal(err)
+	}
+
+	// Lookup an expired token and ensure it fails.
+	expired := time.Now().Add(-time.Minute)
+	_, tv1, err := AccessTokens.Create(ctx, subject.ID, []string{"a"}, "n1", creator.ID, &expired)
+	if err != nil {
 		t.Fatal(err)
 	}
+	if _, err := AccessTokens.Lookup(ctx, tv1, AccessTokenUse{}); err != ErrAccessTokenNotFound {
+		t.Fatalf("got error %v, want %v", err, ErrAccessTokenNotFound)
+	}
+
+	// Lookup a token that hasn't expired yet.
+	unexpired := time.Now().Add(time.Hour)
+	_, tv2, err := AccessTokens.Create(ctx, subject.ID, []string{"a"}, "n2", creator.ID, &unexpired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotToken, err = AccessTokens.Lookup(ctx, tv2, AccessTokenUse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotToken.ExpiresAt == nil || gotToken.ExpiresAt.Sub(unexpired) > time.Millisecond || unexpired.Sub(*gotToken.ExpiresAt) > time.Millisecond {
+		t.Errorf("got ExpiresAt %v, want %v", gotToken.ExpiresAt, unexpired)
+	}
 }
 
 // 🚨 SECURITY: This tests that deleting the subject or creator user of an access token invalidates
@@ -250,18 +303,18 @@ func TestAccessTokens_Lookup_deletedUser(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a"}, "n0", creator.ID)
+		_, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a"}, "n0", creator.ID, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
 		if err := Users.Delete(ctx, subject.ID); err != nil {
 			t.Fatal(err)
 		}
-		if _, err := AccessTokens.Lookup(ctx, tv0, "a"); err == nil {
+		if _, err := AccessTokens.Lookup(ctx, tv0, AccessTokenUse{}); err == nil {
 			t.Fatal("Lookup: want error looking up token for deleted subject user")
 		}
 
-		if _, _, err := AccessTokens.Create(ctx, subject.ID, nil, "n0", creator.ID); err == nil {
+		if _, _, err := AccessTokens.Create(ctx, subject.ID, nil, "n0", creator.ID, nil); err == nil {
 			t.Fatal("Create: want error creating token for deleted subject user")
 		}
 	})
@@ -286,19 +339,142 @@ func TestAccessTokens_Lookup_deletedUser(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a"}, "n0", creator.ID)
+		_, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a"}, "n0", creator.ID, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
 		if err := Users.Delete(ctx, creator.ID); err != nil {
 			t.Fatal(err)
 		}
-		if _, err := AccessTokens.Lookup(ctx, tv0, "a"); err == nil {
+		if _, err := AccessTokens.Lookup(ctx, tv0, AccessTokenUse{}); err == nil {
 			t.Fatal("Lookup: want error looking up token for deleted creator user")
 		}
 
-		if _, _, err := AccessTokens.Create(ctx, subject.ID, nil, "n0", creator.ID); err == nil {
+		if _, _, err := AccessTokens.Create(ctx, subject.ID, nil, "n0", creator.ID, nil); err == nil {
 			t.Fatal("Create: want error creating token for deleted creator user")
 		}
 	})
 }
+
+func TestAccessTokens_Rotate(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	subject, err := Users.Create(ctx, NewUser{
+		Email:                 "a@example.com",
+		Username:              "u1",
+		Password:              "p1",
+		EmailVerificationCode: "c1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tid0, tv0, err := AccessTokens.Create(ctx, subject.ID, []string{"a", "b"}, "n0", subject.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotating a token of a different user fails.
+	if _, _, err := AccessTokens.Rotate(ctx, tid0, subject.ID+1, subject.ID, time.Now().Add(time.Hour), nil); err != ErrAccessTokenNotFound {
+		t.Fatalf("got error %v, want %v", err, ErrAccessTokenNotFound)
+	}
+
+	graceUntil := time.Now().Add(time.Hour)
+	expiresAt := time.Now().Add(30 * 24 * time.Hour)
+	tid1, tv1, err := AccessTokens.Rotate(ctx, tid0, subject.ID, subject.ID, graceUntil, &expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tid1 == tid0 || tv1 == tv0 {
+		t.Fatal("want rotated token to have a new ID and value")
+	}
+
+	// The new token has the scopes and note of the old token.
+	gotToken, err := AccessTokens.Lookup(ctx, tv1, AccessTokenUse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(gotToken.Scopes, want) {
+		t.Errorf("got token scopes %q, want %q", gotToken.Scopes, want)
+	}
+	if want := "n0"; gotToken.Note != want {
+		t.Errorf("got note %q, want %q", gotToken.Note, want)
+	}
+	if gotToken.ExpiresAt == nil || gotToken.ExpiresAt.Sub(expiresAt) > time.Millisecond || expiresAt.Sub(*gotToken.ExpiresAt) > time.Millisecond {
+		t.Errorf("got ExpiresAt %v, want %v", gotToken.ExpiresAt, expiresAt)
+	}
+
+	// The old token remains valid until the end of the grace period.
+	gotToken, err = AccessTokens.Lookup(ctx, tv0, AccessTokenUse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotToken.ExpiresAt == nil || gotToken.ExpiresAt.Sub(graceUntil) > time.Millisecond || graceUntil.Sub(*gotToken.ExpiresAt) > time.Millisecond {
+		t.Errorf("got ExpiresAt %v, want %v", gotToken.ExpiresAt, graceUntil)
+	}
+
+	// Rotating without a grace period invalidates the old token immediately.
+	if _, _, err := AccessTokens.Rotate(ctx, tid1, subject.ID, subject.ID, time.Now(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AccessTokens.Lookup(ctx, tv1, AccessTokenUse{}); err != ErrAccessTokenNotFound {
+		t.Fatalf("got error %v, want %v", err, ErrAccessTokenNotFound)
+	}
+	if _, _, err := AccessTokens.Rotate(ctx, tid1, subject.ID, subject.ID, time.Now(), nil); err != ErrAccessTokenNotFound {
+		t.Fatalf("got error %v, want %v", err, ErrAccessTokenNotFound)
+	}
+}
+
+func TestAccessTokens_ClaimExpiryNotifications(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	subject, err := Users.Create(ctx, NewUser{
+		Email:                 "a@example.com",
+		Username:              "u1",
+		Password:              "p1",
+		EmailVerificationCode: "c1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	create := func(expiresAt *time.Time) int64 {
+		t.Helper()
+		id, _, err := AccessTokens.Create(ctx, subject.ID, []string{"a"}, "n", subject.ID, expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	expired := time.Now().Add(-time.Hour)
+	soon := time.Now().Add(time.Hour)
+	later := time.Now().Add(30 * 24 * time.Hour)
+	create(nil)
+	create(&expired)
+	create(&later)
+	tidSoon := create(&soon)
+
+	expiresBefore := time.Now().Add(7 * 24 * time.Hour)
+	claimed, err := AccessTokens.ClaimExpiryNotifications(ctx, expiresBefore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != tidSoon {
+		t.Fatalf("got claimed tokens %+v, want only token %d", claimed, tidSoon)
+	}
+
+	// Tokens are only claimed once.
+	claimed, err = AccessTokens.ClaimExpiryNotifications(ctx, expiresBefore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 0 {
+		t.Errorf("got claimed tokens %+v, want none", claimed)
+	}
+}
@@ -3,10 +3,14 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/keegancsmith/sqlf"
+	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbutil"
 )
 
@@ -47,10 +51,11 @@ INSERT INTO campaigns (
 	author_id,
 	namespace_user_id,
 	namespace_org_id,
+	campaign_plan_id,
 	created_at,
 	updated_at
 )
-VALUES (%s, %s, %s, %s, %s, %s, %s)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s)
 RETURNING
 	id,
 	name,
@@ -58,6 +63,7 @@ RETURNING
 	author_id,
 	namespace_user_id,
 	namespace_org_id,
+	campaign_plan_id,
 	created_at,
 	updated_at
 `
@@ -70,6 +76,7 @@ func createCampaignQuery(c *types.Campaign) *sqlf.Query {
 		c.AuthorID,
 		nullInt32Column(c.NamespaceUserID),
 		nullInt32Column(c.NamespaceOrgID),
+		nullInt64Column(c.CampaignPlanID),
 		c.CreatedAt,
 		c.UpdatedAt,
 	)
@@ -82,6 +89,13 @@ func nullInt32Column(n int32) *int32 {
 	return &n
 }
 
+func nullInt64Column(n int64) *int64 {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
 // UpdateCampaign updates the given Campaign.
 func (s *CampaignsStore) UpdateCampaign(ctx context.Context, c *types.Campaign) error {
 	panic("not implemented")
@@ -151,6 +165,7 @@ SELECT
 	author_id,
 	namespace_user_id,
 	namespace_org_id,
+	campaign_plan_id,
 	created_at,
 	updated_at
 FROM campaigns
@@ -206,7 +221,711 @@ func scanCampaign(c *types.Campaign, s scanner) error {
 		&c.AuthorID,
 		&dbutil.NullInt32{N: &c.NamespaceUserID},
 		&dbutil.NullInt32{N: &c.NamespaceOrgID},
+		&dbutil.NullInt64{N: &c.CampaignPlanID},
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+}
+
+// ErrNoResults is returned by CampaignsStore methods that look up a single
+// entity when no entity matches.
+var ErrNoResults = errors.New("no results")
+
+// GetCampaign gets the Campaign with the given ID. If it doesn't exist,
+// ErrNoResults is returned.
+func (s *CampaignsStore) GetCampaign(ctx context.Context, id int64) (*types.Campaign, error) {
+	q := sqlf.Sprintf(getCampaignQueryFmtstr, id)
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+
+	var c types.Campaign
+	_, count, err := scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		err = scanCampaign(&c, sc)
+		return c.ID, 1, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNoResults
+	}
+
+	return &c, nil
+}
+
+var getCampaignQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:GetCampaign
+SELECT
+	id,
+	name,
+	description,
+	author_id,
+	namespace_user_id,
+	namespace_org_id,
+	campaign_plan_id,
+	created_at,
+	updated_at
+FROM campaigns
+WHERE id = %s
+LIMIT 1
+`
+
+// CreateChangesets creates the given Changesets. The ID, CreatedAt and
+// UpdatedAt fields of each Changeset are set from the created rows.
+func (s *CampaignsStore) CreateChangesets(ctx context.Context, cs ...*types.Changeset) error {
+	if len(cs) == 0 {
+		return nil
+	}
+
+	q, err := createChangesetsQuery(cs)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return err
+	}
+
+	// The created rows are matched to the given changesets by their unique
+	// (campaign_id, repo_id, external_id) triple.
+	type key struct {
+		campaignID int64
+		repoID     api.RepoID
+		externalID string
+	}
+	byKey := make(map[key]*types.Changeset, len(cs))
+	for _, c := range cs {
+		byKey[key{c.CampaignID, c.RepoID, c.ExternalID}] = c
+	}
+
+	_, _, err = scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		var c types.Changeset
+		if err = scanChangeset(&c, sc); err != nil {
+			return 0, 0, err
+		}
+		if dst, ok := byKey[key{c.CampaignID, c.RepoID, c.ExternalID}]; ok {
+			*dst = c
+		}
+		return c.ID, 1, nil
+	})
+
+	return err
+}
+
+var createChangesetsQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:CreateChangesets
+INSERT INTO changesets (
+	campaign_id,
+	repo_id,
+	external_id,
+	title,
+	url,
+	state,
+	review_state,
+	check_state,
+	external_created_at,
+	external_closed_at,
+	external_merged_at,
+	metadata,
+	synced_at,
+	created_at,
+	updated_at
+)
+VALUES %s
+RETURNING
+` + changesetColumns
+
+const changesetColumns = `
+	id,
+	campaign_id,
+	repo_id,
+	external_id,
+	title,
+	url,
+	state,
+	review_state,
+	check_state,
+	external_created_at,
+	external_closed_at,
+	external_merged_at,
+	metadata,
+	synced_at,
+	created_at,
+	updated_at
+`
+
+func createChangesetsQuery(cs []*types.Changeset) (*sqlf.Query, error) {
+	values := make([]*sqlf.Query, 0, len(cs))
+	for _, c := range cs {
+		metadata, err := metadataColumn(c.Metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		createdAt, updatedAt := c.CreatedAt, c.UpdatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now().UTC()
+		}
+		if updatedAt.IsZero() {
+			updatedAt = createdAt
+		}
+
+		values = append(values, sqlf.Sprintf(
+			"(%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)",
+			c.CampaignID,
+			c.RepoID,
+			c.ExternalID,
+			c.Title,
+			c.URL,
+			c.State,
+			c.ReviewState,
+			c.CheckState,
+			nullTimeColumn(c.ExternalCreatedAt),
+			nullTimeColumn(c.ExternalClosedAt),
+			nullTimeColumn(c.ExternalMergedAt),
+			metadata,
+			nullTimeColumn(c.SyncedAt),
+			createdAt,
+			updatedAt,
+		))
+	}
+
+	return sqlf.Sprintf(createChangesetsQueryFmtstr, sqlf.Join(values, ",\n")), nil
+}
+
+// UpdateChangesets updates the fields of the given Changesets that are
+// loaded from their code hosts. UpdatedAt is set to the current time.
+func (s *CampaignsStore) UpdateChangesets(ctx context.Context, cs ...*types.Changeset) error {
+	for _, c := range cs {
+		q, err := updateChangesetQuery(c)
+		if err != nil {
+			return err
+		}
+
+		rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+		if err != nil {
+			return err
+		}
+
+		_, count, err := scanAll(rows, func(sc scanner) (last, count int64, err error) {
+			err = scanChangeset(c, sc)
+			return c.ID, 1, err
+		})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.Wrapf(ErrNoResults, "changeset %d", c.ID)
+		}
+	}
+
+	return nil
+}
+
+var updateChangesetQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:UpdateChangesets
+UPDATE changesets
+SET
+	title = %s,
+	url = %s,
+	state = %s,
+	review_state = %s,
+	check_state = %s,
+	external_created_at = %s,
+	external_closed_at = %s,
+	external_merged_at = %s,
+	metadata = %s,
+	synced_at = %s,
+	updated_at = now()
+WHERE id = %s
+RETURNING
+` + changesetColumns
+
+func updateChangesetQuery(c *types.Changeset) (*sqlf.Query, error) {
+	metadata, err := metadataColumn(c.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlf.Sprintf(
+		updateChangesetQueryFmtstr,
+		c.Title,
+		c.URL,
+		c.State,
+		c.ReviewState,
+		c.CheckState,
+		nullTimeColumn(c.ExternalCreatedAt),
+		nullTimeColumn(c.ExternalClosedAt),
+		nullTimeColumn(c.ExternalMergedAt),
+		metadata,
+		nullTimeColumn(c.SyncedAt),
+		c.ID,
+	), nil
+}
+
+// CountChangesetsOpts captures the query options needed for
+// counting changesets.
+type CountChangesetsOpts struct {
+	CampaignID int64
+}
+
+// CountChangesets returns the number of changesets in the database.
+func (s *CampaignsStore) CountChangesets(ctx context.Context, opts CountChangesetsOpts) (int64, error) {
+	q := sqlf.Sprintf(countChangesetsQueryFmtstr, changesetsPredicates(opts.CampaignID, nil, 0))
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return 0, err
+	}
+
+	_, count, err := scanAll(rows, func(sc scanner) (_, count int64, err error) {
+		err = sc.Scan(&count)
+		return 0, count, err
+	})
+
+	return count, err
+}
+
+var countChangesetsQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:CountChangesets
+SELECT COUNT(id) FROM changesets
+WHERE %s
+`
+
+// ListChangesetsOpts captures the query options needed for
+// listing changesets.
+type ListChangesetsOpts struct {
+	// Cursor is the ID of the first changeset to list. It is the next value
+	// returned by a previous call to ListChangesets.
+	Cursor int64
+	Limit  int
+	// CampaignID, if non-zero, limits the listed changesets to those of the
+	// campaign.
+	CampaignID int64
+	// IDs, if non-empty, limits the listed changesets to those with the given
+	// IDs.
+	IDs []int64
+}
+
+// ListChangesets lists Changesets with the given filters.
+func (s *CampaignsStore) ListChangesets(ctx context.Context, opts ListChangesetsOpts) (cs []*types.Changeset, next int64, err error) {
+	q := listChangesetsQuery(&opts)
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cs = make([]*types.Changeset, 0, opts.Limit)
+	_, _, err = scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		var c types.Changeset
+		if err = scanChangeset(&c, sc); err != nil {
+			return 0, 0, err
+		}
+		cs = append(cs, &c)
+		return c.ID, 1, err
+	})
+
+	if len(cs) == opts.Limit {
+		next = cs[len(cs)-1].ID
+		cs = cs[:len(cs)-1]
+	}
+
+	return cs, next, err
+}
+
+var listChangesetsQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:ListChangesets
+SELECT` + changesetColumns + `FROM changesets
+WHERE %s
+ORDER BY id ASC
+LIMIT %s
+`
+
+func listChangesetsQuery(opts *ListChangesetsOpts) *sqlf.Query {
+	if opts.Limit == 0 {
+		opts.Limit = defaultListLimit
+	}
+	opts.Limit++
+	return sqlf.Sprintf(
+		listChangesetsQueryFmtstr,
+		changesetsPredicates(opts.CampaignID, opts.IDs, opts.Cursor),
+		opts.Limit,
+	)
+}
+
+func changesetsPredicates(campaignID int64, ids []int64, cursor int64) *sqlf.Query {
+	preds := []*sqlf.Query{sqlf.Sprintf("id >= %s", cursor)}
+
+	if campaignID != 0 {
+		preds = append(preds, sqlf.Sprintf("campaign_id = %s", campaignID))
+	}
+
+	if len(ids) > 0 {
+		items := make([]*sqlf.Query, 0, len(ids))
+		for _, id := range ids {
+			items = append(items, sqlf.Sprintf("%s", id))
+		}
+		preds = append(preds, sqlf.Sprintf("id IN (%s)", sqlf.Join(items, ",")))
+	}
+
+	return sqlf.Join(preds, "\n AND ")
+}
+
+func nullTimeColumn(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func metadataColumn(metadata json.RawMessage) (string, error) {
+	if len(metadata) == 0 {
+		return "{}", nil
+	}
+	if !json.Valid(metadata) {
+		return "", errors.New("changeset metadata is not valid JSON")
+	}
+	return string(metadata), nil
+}
+
+func scanChangeset(c *types.Changeset, s scanner) error {
+	var metadata []byte
+	err := s.Scan(
+		&c.ID,
+		&c.CampaignID,
+		&c.RepoID,
+		&c.ExternalID,
+		&c.Title,
+		&c.URL,
+		&c.State,
+		&c.ReviewState,
+		&c.CheckState,
+		&dbutil.NullTime{Time: &c.ExternalCreatedAt},
+		&dbutil.NullTime{Time: &c.ExternalClosedAt},
+		&dbutil.NullTime{Time: &c.ExternalMergedAt},
+		&metadata,
+		&dbutil.NullTime{Time: &c.SyncedAt},
 		&c.CreatedAt,
 		&c.UpdatedAt,
 	)
+	if err != nil {
+		return err
+	}
+	c.Metadata = json.RawMessage(metadata)
+	return nil
+}
+
+// CreateCampaignPlan creates the given CampaignPlan.
+func (s *CampaignsStore) CreateCampaignPlan(ctx context.Context, p *types.CampaignPlan) error {
+	q := sqlf.Sprintf(createCampaignPlanQueryFmtstr, p.Query)
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		err = scanCampaignPlan(p, sc)
+		return p.ID, 1, err
+	})
+
+	return err
+}
+
+var createCampaignPlanQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:CreateCampaignPlan
+INSERT INTO campaign_plans (query)
+VALUES (%s)
+RETURNING
+	id,
+	query,
+	created_at,
+	updated_at
+`
+
+// GetCampaignPlan gets the CampaignPlan with the given ID. If it doesn't
+// exist, ErrNoResults is returned.
+func (s *CampaignsStore) GetCampaignPlan(ctx context.Context, id int64) (*types.CampaignPlan, error) {
+	q := sqlf.Sprintf(getCampaignPlanQueryFmtstr, id)
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+
+	var p types.CampaignPlan
+	_, count, err := scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		err = scanCampaignPlan(&p, sc)
+		return p.ID, 1, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNoResults
+	}
+
+	return &p, nil
+}
+
+var getCampaignPlanQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:GetCampaignPlan
+SELECT
+	id,
+	query,
+	created_at,
+	updated_at
+FROM campaign_plans
+WHERE id = %s
+LIMIT 1
+`
+
+func scanCampaignPlan(p *types.CampaignPlan, s scanner) error {
+	return s.Scan(
+		&p.ID,
+		&p.Query,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+}
+
+// CreateCampaignJobs creates the given CampaignJobs. The ID, CreatedAt and
+// UpdatedAt fields of each CampaignJob are set from the created rows.
+func (s *CampaignsStore) CreateCampaignJobs(ctx context.Context, js ...*types.CampaignJob) error {
+	if len(js) == 0 {
+		return nil
+	}
+
+	values := make([]*sqlf.Query, 0, len(js))
+	for _, j := range js {
+		values = append(values, sqlf.Sprintf(
+			"(%s, %s, %s, %s, %s, %s, %s, %s)",
+			j.CampaignPlanID,
+			j.RepoID,
+			j.Rev,
+			j.Diff,
+			j.Error,
+			j.PublishedRef,
+			nullTimeColumn(j.StartedAt),
+			nullTimeColumn(j.FinishedAt),
+		))
+	}
+	q := sqlf.Sprintf(createCampaignJobsQueryFmtstr, sqlf.Join(values, ",\n"))
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return err
+	}
+
+	// The created rows are matched to the given jobs by their unique
+	// (campaign_plan_id, repo_id) pair.
+	type key struct {
+		campaignPlanID int64
+		repoID         api.RepoID
+	}
+	byKey := make(map[key]*types.CampaignJob, len(js))
+	for _, j := range js {
+		byKey[key{j.CampaignPlanID, j.RepoID}] = j
+	}
+
+	_, _, err = scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		var j types.CampaignJob
+		if err = scanCampaignJob(&j, sc); err != nil {
+			return 0, 0, err
+		}
+		if dst, ok := byKey[key{j.CampaignPlanID, j.RepoID}]; ok {
+			*dst = j
+		}
+		return j.ID, 1, nil
+	})
+
+	return err
+}
+
+var createCampaignJobsQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:CreateCampaignJobs
+INSERT INTO campaign_jobs (
+	campaign_plan_id,
+	repo_id,
+	rev,
+	diff,
+	error,
+	published_ref,
+	started_at,
+	finished_at
+)
+VALUES %s
+RETURNING
+` + campaignJobColumns
+
+const campaignJobColumns = `
+	id,
+	campaign_plan_id,
+	repo_id,
+	rev,
+	diff,
+	error,
+	published_ref,
+	started_at,
+	finished_at,
+	created_at,
+	updated_at
+`
+
+// UpdateCampaignJob updates the result fields (Rev, Diff, Error,
+// PublishedRef, StartedAt and FinishedAt) of the given CampaignJob. UpdatedAt
+// is set to the current time.
+func (s *CampaignsStore) UpdateCampaignJob(ctx context.Context, j *types.CampaignJob) error {
+	q := sqlf.Sprintf(
+		updateCampaignJobQueryFmtstr,
+		j.Rev,
+		j.Diff,
+		j.Error,
+		j.PublishedRef,
+		nullTimeColumn(j.StartedAt),
+		nullTimeColumn(j.FinishedAt),
+		j.ID,
+	)
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return err
+	}
+
+	_, count, err := scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		err = scanCampaignJob(j, sc)
+		return j.ID, 1, err
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.Wrapf(ErrNoResults, "campaign job %d", j.ID)
+	}
+
+	return nil
+}
+
+var updateCampaignJobQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:UpdateCampaignJob
+UPDATE campaign_jobs
+SET
+	rev = %s,
+	diff = %s,
+	error = %s,
+	published_ref = %s,
+	started_at = %s,
+	finished_at = %s,
+	updated_at = now()
+WHERE id = %s
+RETURNING
+` + campaignJobColumns
+
+// ErrorUnfinishedCampaignJobs sets the Error and FinishedAt fields of the
+// CampaignJobs that were created before createdBefore and have not finished,
+// and returns their number. It is used for jobs that were abandoned (e.g.
+// because the frontend that ran them was restarted), which would otherwise
+// never finish.
+func (s *CampaignsStore) ErrorUnfinishedCampaignJobs(ctx context.Context, createdBefore time.Time, message string) (int64, error) {
+	q := sqlf.Sprintf(errorUnfinishedCampaignJobsQueryFmtstr, message, createdBefore)
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return 0, err
+	}
+
+	_, count, err := scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		err = sc.Scan(&last)
+		return last, 1, err
+	})
+
+	return count, err
+}
+
+var errorUnfinishedCampaignJobsQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:ErrorUnfinishedCampaignJobs
+UPDATE campaign_jobs
+SET
+	error = %s,
+	finished_at = now(),
+	updated_at = now()
+WHERE finished_at IS NULL AND created_at < %s
+RETURNING id
+`
+
+// ListCampaignJobsOpts captures the query options needed for
+// listing campaign jobs.
+type ListCampaignJobsOpts struct {
+	// Cursor is the ID of the first job to list. It is the next value
+	// returned by a previous call to ListCampaignJobs.
+	Cursor int64
+	Limit  int
+	// CampaignPlanID, if non-zero, limits the listed jobs to those of the
+	// campaign plan.
+	CampaignPlanID int64
+}
+
+// ListCampaignJobs lists CampaignJobs with the given filters.
+func (s *CampaignsStore) ListCampaignJobs(ctx context.Context, opts ListCampaignJobsOpts) (js []*types.CampaignJob, next int64, err error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultListLimit
+	}
+	opts.Limit++
+
+	preds := []*sqlf.Query{sqlf.Sprintf("id >= %s", opts.Cursor)}
+	if opts.CampaignPlanID != 0 {
+		preds = append(preds, sqlf.Sprintf("campaign_plan_id = %s", opts.CampaignPlanID))
+	}
+	q := sqlf.Sprintf(listCampaignJobsQueryFmtstr, sqlf.Join(preds, "\n AND "), opts.Limit)
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	js = make([]*types.CampaignJob, 0, opts.Limit)
+	_, _, err = scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		var j types.CampaignJob
+		if err = scanCampaignJob(&j, sc); err != nil {
+			return 0, 0, err
+		}
+		js = append(js, &j)
+		return j.ID, 1, err
+	})
+
+	if len(js) == opts.Limit {
+		next = js[len(js)-1].ID
+		js = js[:len(js)-1]
+	}
+
+	return js, next, err
+}
+
+var listCampaignJobsQueryFmtstr = `
+-- source: cmd/frontend/db/campaigns.go:ListCampaignJobs
+SELECT` + campaignJobColumns + `FROM campaign_jobs
+WHERE %s
+ORDER BY id ASC
+LIMIT %s
+`
+
+func scanCampaignJob(j *types.CampaignJob, s scanner) error {
+	return s.Scan(
+		&j.ID,
+		&j.CampaignPlanID,
+		&j.RepoID,
+		&j.Rev,
+		&j.Diff,
+		&j.Error,
+		&j.PublishedRef,
+		&dbutil.NullTime{Time: &j.StartedAt},
+		&dbutil.NullTime{Time: &j.FinishedAt},
+		&j.CreatedAt,
+		&j.UpdatedAt,
+	)
 }
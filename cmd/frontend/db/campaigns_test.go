@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbtest"
 )
 
@@ -93,4 +95,259 @@ func TestCampaignsStore(t *testing.T) {
 			}
 		}
 	}
+
+	{
+		have, err := s.GetCampaign(ctx, campaigns[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := campaigns[1]; !reflect.DeepEqual(have, want) {
+			t.Fatal(cmp.Diff(have, want))
+		}
+
+		if _, err := s.GetCampaign(ctx, -1); err != ErrNoResults {
+			t.Fatalf("have err %v, want %v", err, ErrNoResults)
+		}
+	}
+
+	changesets := make([]*types.Changeset, 3)
+	for i := range changesets {
+		changesets[i] = &types.Changeset{
+			CampaignID: campaigns[i%2].ID,
+			RepoID:     api.RepoID(42 + i),
+			ExternalID: fmt.Sprintf("%d", 100+i),
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+	}
+
+	if err := s.CreateChangesets(ctx, changesets...); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range changesets {
+		if c.ID == 0 {
+			t.Fatalf("changeset %+v was not assigned an ID", c)
+		}
+		if string(c.Metadata) != "{}" {
+			t.Fatalf("have metadata %q, want %q", c.Metadata, "{}")
+		}
+	}
+
+	{
+		count, err := s.CountChangesets(ctx, CountChangesetsOpts{CampaignID: campaigns[0].ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := count, int64(2); have != want {
+			t.Fatalf("have count: %d, want: %d", have, want)
+		}
+	}
+
+	{
+		have, next, err := s.ListChangesets(ctx, ListChangesetsOpts{Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := changesets[:1]; !reflect.DeepEqual(have, want) {
+			t.Fatal(cmp.Diff(have, want))
+		}
+		if want := changesets[1].ID; next != want {
+			t.Fatalf("have next %v, want %v", next, want)
+		}
+
+		have, next, err = s.ListChangesets(ctx, ListChangesetsOpts{Cursor: next})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := changesets[1:]; !reflect.DeepEqual(have, want) {
+			t.Fatal(cmp.Diff(have, want))
+		}
+		if next != 0 {
+			t.Fatalf("have next %v, want 0", next)
+		}
+	}
+
+	{
+		have, _, err := s.ListChangesets(ctx, ListChangesetsOpts{IDs: []int64{changesets[2].ID}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := changesets[2:]; !reflect.DeepEqual(have, want) {
+			t.Fatal(cmp.Diff(have, want))
+		}
+	}
+
+	{
+		c := changesets[0]
+		c.Title = "Upgrade ES-Lint"
+		c.URL = "https://github.com/sourcegraph/sourcegraph/pull/100"
+		c.State = types.ChangesetStateMerged
+		c.ReviewState = types.ChangesetReviewStateApproved
+		c.CheckState = types.ChangesetCheckStatePassed
+		c.ExternalCreatedAt = now.Add(-2 * time.Hour)
+		c.ExternalClosedAt = now.Add(-time.Hour)
+		c.ExternalMergedAt = now.Add(-time.Hour)
+		c.Metadata = []byte(`{"number": 100}`)
+		c.SyncedAt = now
+
+		want := *c
+		if err := s.UpdateChangesets(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		want.UpdatedAt = c.UpdatedAt
+		if !reflect.DeepEqual(c, &want) {
+			t.Fatal(cmp.Diff(c, &want))
+		}
+		if string(c.Metadata) != `{"number": 100}` {
+			t.Fatalf("have metadata %q", c.Metadata)
+		}
+
+		if err := s.UpdateChangesets(ctx, &types.Changeset{ID: -1}); errors.Cause(err) != ErrNoResults {
+			t.Fatalf("have err %v, want %v", err, ErrNoResults)
+		}
+	}
+
+	plan := &types.CampaignPlan{Query: `repo:github "fmt.Sprintf(\"%d\", :[v])" replace:"strconv.Itoa(:[v])"`}
+	if err := s.CreateCampaignPlan(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	{
+		have, err := s.GetCampaignPlan(ctx, plan.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(have, plan) {
+			t.Fatal(cmp.Diff(have, plan))
+		}
+
+		if _, err := s.GetCampaignPlan(ctx, -1); err != ErrNoResults {
+			t.Fatalf("have err %v, want %v", err, ErrNoResults)
+		}
+	}
+
+	{
+		c := &types.Campaign{
+			Name:            "Use strconv.Itoa",
+			AuthorID:        23,
+			NamespaceUserID: 42,
+			CampaignPlanID:  plan.ID,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if err := s.CreateCampaign(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := s.GetCampaign(ctx, c.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(have, c) {
+			t.Fatal(cmp.Diff(have, c))
+		}
+	}
+
+	jobs := make([]*types.CampaignJob, 3)
+	for i := range jobs {
+		jobs[i] = &types.CampaignJob{
+			CampaignPlanID: plan.ID,
+			RepoID:         api.RepoID(42 + i),
+			Rev:            api.CommitID(fmt.Sprintf("deadbeef%d", i)),
+		}
+	}
+
+	if err := s.CreateCampaignJobs(ctx, jobs...); err != nil {
+		t.Fatal(err)
+	}
+	for _, j := range jobs {
+		if j.ID == 0 {
+			t.Fatalf("job %+v was not assigned an ID", j)
+		}
+	}
+
+	{
+		have, next, err := s.ListCampaignJobs(ctx, ListCampaignJobsOpts{CampaignPlanID: plan.ID, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := jobs[:2]; !reflect.DeepEqual(have, want) {
+			t.Fatal(cmp.Diff(have, want))
+		}
+		if want := jobs[2].ID; next != want {
+			t.Fatalf("have next %v, want %v", next, want)
+		}
+
+		have, next, err = s.ListCampaignJobs(ctx, ListCampaignJobsOpts{CampaignPlanID: plan.ID, Cursor: next})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := jobs[2:]; !reflect.DeepEqual(have, want) {
+			t.Fatal(cmp.Diff(have, want))
+		}
+		if next != 0 {
+			t.Fatalf("have next %v, want 0", next)
+		}
+	}
+
+	{
+		j := jobs[0]
+		j.Diff = "diff --git a/main.go b/main.go\n"
+		j.StartedAt = now.Add(-time.Minute)
+		j.FinishedAt = now
+
+		want := *j
+		if err := s.UpdateCampaignJob(ctx, j); err != nil {
+			t.Fatal(err)
+		}
+		want.UpdatedAt = j.UpdatedAt
+		if !reflect.DeepEqual(j, &want) {
+			t.Fatal(cmp.Diff(j, &want))
+		}
+
+		if err := s.UpdateCampaignJob(ctx, &types.CampaignJob{ID: -1}); errors.Cause(err) != ErrNoResults {
+			t.Fatalf("have err %v, want %v", err, ErrNoResults)
+		}
+	}
+
+	{
+		errored, err := s.ErrorUnfinishedCampaignJobs(ctx, now.Add(-time.Hour), "abandoned")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errored != 0 {
+			t.Fatalf("have %d errored jobs, want 0", errored)
+		}
+
+		errored, err = s.ErrorUnfinishedCampaignJobs(ctx, time.Now().Add(time.Hour), "abandoned")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := int64(len(jobs) - 1); errored != want { // jobs[0] has finished
+			t.Fatalf("have %d errored jobs, want %d", errored, want)
+		}
+
+		have, _, err := s.ListCampaignJobs(ctx, ListCampaignJobsOpts{CampaignPlanID: plan.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, j := range have {
+			if j.FinishedAt.IsZero() {
+				t.Errorf("job %d has not finished", j.ID)
+			}
+			if j.ID != jobs[0].ID && j.Error != "abandoned" {
+				t.Errorf("job %d has error %q, want %q", j.ID, j.Error, "abandoned")
+			}
+		}
+	}
+
+	// This must be last, since the error aborts the transaction.
+	if err := s.CreateChangesets(ctx, &types.Changeset{
+		CampaignID: changesets[0].CampaignID,
+		RepoID:     changesets[0].RepoID,
+		ExternalID: changesets[0].ExternalID,
+	}); err == nil {
+		t.Fatal("creating a duplicate changeset succeeded")
+	}
 }
@@ -75,6 +75,9 @@ func (c *discussionComments) Create(ctx context.Context, newComment *types.Discu
 		newComment.UpdatedAt,
 	).Scan(&newComment.ID)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "discussion_comments_thread_id_fkey" {
+			return nil, &ErrThreadNotFound{ThreadID: newComment.ThreadID}
+		}
 		return nil, err
 	}
 	return newComment, nil
@@ -287,7 +290,10 @@ func (*discussionComments) getBySQL(ctx context.Context, query string, args ...i
 			c.contents,
 			c.created_at,
 			c.updated_at,
-			c.reports
+			c.reports,
+			c.external_service_type,
+			c.external_service_id,
+			c.external_id
 		FROM discussion_comments c `+query, args...)
 	if err != nil {
 		return nil, err
@@ -305,6 +311,9 @@ func (*discussionComments) getBySQL(ctx context.Context, query string, args ...i
 			&comment.CreatedAt,
 			&comment.UpdatedAt,
 			pq.Array(&comment.Reports),
+			&comment.ExternalServiceType,
+			&comment.ExternalServiceID,
+			&comment.ExternalID,
 		)
 		if err != nil {
 			return nil, err
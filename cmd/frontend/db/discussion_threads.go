@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/felixfbecker/stringscore"
 	"github.com/karrick/tparse"
@@ -189,6 +190,11 @@ func (t *discussionThreads) Update(ctx context.Context, threadID int64, opts *Di
 			return nil, err
 		}
 
+		// Invalidate the tokens for replying to notifications about the thread.
+		if _, err := dbconn.Global.ExecContext(ctx, "UPDATE mail_reply_tokens SET deleted_at=$1 WHERE kind=$2 AND target=$3 AND deleted_at IS NULL", now, MailReplyKindDiscussionThread, strconv.FormatInt(threadID, 10)); err != nil {
+			return nil, err
+		}
+
 		// Mark all comments in the thread as deleted.
 		comments, err := DiscussionComments.List(ctx, &DiscussionCommentsListOptions{
 			ThreadID: &threadID,
@@ -223,6 +229,18 @@ type DiscussionThreadsListOptions struct {
 	TitleQuery    *string
 	NotTitleQuery *string
 
+	// SearchQuery, when non-nil and not empty, specifies that only threads
+	// whose title or (non-deleted) comments contain all of its words should be
+	// returned, using Postgres full-text search. The words are matched as
+	// prefixes after stemming, so "render" matches "rendering". Results are
+	// ordered by rank (matches in the title rank higher than matches in
+	// comments) instead of by ID.
+	SearchQuery *string
+
+	// Archived, when non-nil, specifies that only threads that are (or are
+	// not) archived should be returned.
+	Archived *bool
+
 	// ThreadIDs, when len() > 0, specifies that only the thread with one of
 	// these IDs should be returned. See also DiscussionThreads.Get.
 	ThreadIDs    []int64
@@ -238,6 +256,10 @@ type DiscussionThreadsListOptions struct {
 	TargetRepoID    *api.RepoID
 	NotTargetRepoID *api.RepoID
 
+	// TargetRepoIDs, when len() > 0, specifies that only threads that have a
+	// repo target with one of these repo IDs should be returned.
+	TargetRepoIDs []api.RepoID
+
 	// TargetRepoPath, when non-nil, specifies that only threads that have a repo target
 	// and this path should be returned.
 	TargetRepoPath    *string
@@ -296,22 +318,6 @@ func (opts *DiscussionThreadsListOptions) SetFromQuery(ctx context.Context, quer
 		return
 	}
 
-	parseTimeOrDuration := func(value string) *time.Time {
-		// Try parsing as RFC3339 / ISO 8601 first.
-		t, err := time.Parse(time.RFC3339, value)
-		if err == nil {
-			return &t
-		}
-
-		// Try parsing as a relative duration, e.g. "3d ago", "3h4m", etc.
-		value = strings.TrimSuffix(value, " ago")
-		t, err = tparse.ParseNow(time.RFC3339, "now-"+value)
-		if err != nil {
-			return nil
-		}
-		return &t
-	}
-
 	var reported bool
 	operators := map[string]func(value string){
 		// syntax: `title:"some title"` or "title:sometitle"
@@ -373,7 +379,7 @@ func (opts *DiscussionThreadsListOptions) SetFromQuery(ctx context.Context, quer
 			opts.NotTargetRepoPath = &value
 		},
 
-		// syntax: "file:dir/file.go" or "file:something.go"
+		// syntax: "before:2019-01-01" or "after:3d ago"
 		"before": func(value string) {
 			opts.CreatedBefore = parseTimeOrDuration(value)
 		},
@@ -381,6 +387,36 @@ func (opts *DiscussionThreadsListOptions) SetFromQuery(ctx context.Context, quer
 			opts.CreatedAfter = parseTimeOrDuration(value)
 		},
 
+		// syntax: "created:>2019-01-01", "created:<=2019-01-01T12:00:00Z",
+		// "created:2019-01-01" (that day) or "created:>1w" (in the last week)
+		"created": func(value string) {
+			after, before, ok := parseCreatedRange(value)
+			if !ok {
+				// Match nothing rather than ignoring the operator.
+				after, before = &time.Time{}, &time.Time{}
+			}
+			if after != nil {
+				opts.CreatedAfter = after
+			}
+			if before != nil {
+				opts.CreatedBefore = before
+			}
+		},
+
+		// syntax: "is:archived" or "-is:archived"
+		"is": func(value string) {
+			if value == "archived" {
+				archived := true
+				opts.Archived = &archived
+			}
+		},
+		"-is": func(value string) {
+			if value == "archived" {
+				archived := false
+				opts.Archived = &archived
+			}
+		},
+
 		// syntax: "order:oldest" OR "order:ascending" etc.
 		"order": func(value string) {
 			value = strings.ToLower(value)
@@ -402,7 +438,7 @@ func (opts *DiscussionThreadsListOptions) SetFromQuery(ctx context.Context, quer
 		// the remaining search query.
 		remaining = strings.Join([]string{remaining, operation + ":" + value}, " ")
 	}
-	opts.TitleQuery = &remaining
+	opts.SearchQuery = &remaining
 
 	if reported {
 		// Searching only for reported threads.
@@ -448,6 +484,86 @@ func (opts *DiscussionThreadsListOptions) SetFromQuery(ctx context.Context, quer
 	}
 }
 
+// tsquery returns the Postgres full-text search query for opts.SearchQuery,
+// which matches the prefixes of all of its words, or "" if there is none.
+func (opts *DiscussionThreadsListOptions) tsquery() string {
+	if opts.SearchQuery == nil {
+		return ""
+	}
+	words := strings.FieldsFunc(*opts.SearchQuery, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// parseTimeOrDuration parses an RFC3339 time, a date (such as "2019-01-01",
+// in UTC), or a duration before now (such as "3d ago" or "3h4m"). It returns
+// nil if value is none of these.
+func parseTimeOrDuration(value string) *time.Time {
+	// Try parsing as RFC3339 / ISO 8601 first.
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return &t
+	}
+	t, err = time.Parse("2006-01-02", value)
+	if err == nil {
+		return &t
+	}
+
+	// Try parsing as a relative duration, e.g. "3d ago", "3h4m", etc.
+	value = strings.TrimSuffix(value, " ago")
+	t, err = tparse.ParseNow(time.RFC3339, "now-"+value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// parseCreatedRange parses the value of a "created:" search operator, which is
+// a time (see parseTimeOrDuration) optionally preceded by >, >=, < or <=, into
+// the exclusive bounds of the creation time. A date without a comparison
+// matches the whole day, and comparisons with a date compare with the whole
+// day, so "created:>2019-01-01" matches threads created on January 2 or
+// later.
+func parseCreatedRange(value string) (after, before *time.Time, ok bool) {
+	var op string
+	for _, prefix := range []string{">=", "<=", ">", "<"} {
+		if strings.HasPrefix(value, prefix) {
+			op, value = prefix, strings.TrimPrefix(value, prefix)
+			break
+		}
+	}
+
+	t := parseTimeOrDuration(value)
+	if t == nil {
+		return nil, nil, false
+	}
+	// The first instant of the range described by value, and the first instant
+	// after it.
+	start, end := *t, t.Add(time.Microsecond)
+	if _, err := time.Parse("2006-01-02", value); err == nil {
+		end = start.AddDate(0, 0, 1)
+	}
+	beforeStart := start.Add(-time.Microsecond)
+	lastInstant := end.Add(-time.Microsecond)
+
+	switch op {
+	case ">":
+		return &lastInstant, nil, true
+	case ">=":
+		return &beforeStart, nil, true
+	case "<":
+		return nil, &start, true
+	case "<=":
+		return nil, &end, true
+	default:
+		return &beforeStart, &end, true
+	}
+}
+
 func (t *discussionThreads) List(ctx context.Context, opts *DiscussionThreadsListOptions) ([]*types.DiscussionThread, error) {
 	if Mocks.DiscussionThreads.List != nil {
 		return Mocks.DiscussionThreads.List(ctx, opts)
@@ -456,11 +572,18 @@ func (t *discussionThreads) List(ctx context.Context, opts *DiscussionThreadsLis
 		return nil, errors.New("options must not be nil")
 	}
 	conds := t.getListSQL(opts)
-	order := "DESC"
+	order := sqlf.Sprintf("id DESC")
 	if opts.AscendingOrder {
-		order = "ASC"
+		order = sqlf.Sprintf("id ASC")
 	}
-	q := sqlf.Sprintf("WHERE %s ORDER BY id "+order+" %s", sqlf.Join(conds, "AND"), opts.LimitOffset.SQL())
+	if tsquery := opts.tsquery(); tsquery != "" {
+		order = sqlf.Sprintf(`ts_rank(
+			setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+			setweight(to_tsvector('english', COALESCE((SELECT string_agg(contents, ' ') FROM discussion_comments c WHERE c.thread_id=t.id AND c.deleted_at IS NULL), '')), 'B'),
+			to_tsquery('english', %v)
+		) DESC, %v`, tsquery, order)
+	}
+	q := sqlf.Sprintf("WHERE %s ORDER BY %s %s", sqlf.Join(conds, "AND"), order, opts.LimitOffset.SQL())
 
 	threads, err := t.getBySQL(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
 	if err != nil {
@@ -531,6 +654,19 @@ func (*discussionThreads) getListSQL(opts *DiscussionThreadsListOptions) (conds
 		// just do prefix/suffix fuzziness for now.
 		conds = append(conds, sqlf.Sprintf("title NOT ILIKE %v", "%"+*opts.NotTitleQuery+"%"))
 	}
+	if tsquery := opts.tsquery(); tsquery != "" {
+		conds = append(conds, sqlf.Sprintf(`(
+			to_tsvector('english', COALESCE(title, '')) @@ to_tsquery('english', %v)
+			OR id IN (SELECT thread_id FROM discussion_comments WHERE deleted_at IS NULL AND to_tsvector('english', contents) @@ to_tsquery('english', %v))
+		)`, tsquery, tsquery))
+	}
+	if opts.Archived != nil {
+		if *opts.Archived {
+			conds = append(conds, sqlf.Sprintf("archived_at IS NOT NULL"))
+		} else {
+			conds = append(conds, sqlf.Sprintf("archived_at IS NULL"))
+		}
+	}
 	if len(opts.ThreadIDs) > 0 {
 		conds = append(conds, sqlf.Sprintf("id = ANY(%v)", pq.Array(opts.ThreadIDs)))
 	}
@@ -550,11 +686,14 @@ func (*discussionThreads) getListSQL(opts *DiscussionThreadsListOptions) (conds
 		conds = append(conds, sqlf.Sprintf("created_at > %v", *opts.CreatedAfter))
 	}
 
-	if opts.TargetRepoID != nil || opts.TargetRepoPath != nil || opts.NotTargetRepoID != nil || opts.NotTargetRepoPath != nil {
+	if opts.TargetRepoID != nil || len(opts.TargetRepoIDs) > 0 || opts.TargetRepoPath != nil || opts.NotTargetRepoID != nil || opts.NotTargetRepoPath != nil {
 		targetRepoConds := []*sqlf.Query{}
 		if opts.TargetRepoID != nil {
 			targetRepoConds = append(targetRepoConds, sqlf.Sprintf("repo_id = %v", *opts.TargetRepoID))
 		}
+		if len(opts.TargetRepoIDs) > 0 {
+			targetRepoConds = append(targetRepoConds, sqlf.Sprintf("repo_id = ANY(%v)", pq.Array(opts.TargetRepoIDs)))
+		}
 		if opts.NotTargetRepoID != nil {
 			targetRepoConds = append(targetRepoConds, sqlf.Sprintf("repo_id != %v", *opts.NotTargetRepoID))
 		}
@@ -2,7 +2,9 @@ package db
 
 import (
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
@@ -297,11 +299,22 @@ func TestDiscussionThreads_Delete(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	token, err := MailReplyTokens.Generate(ctx, user.ID, MailReplyKindDiscussionThread, strconv.FormatInt(thread.ID, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// Delete thread.
 	if _, err := DiscussionThreads.Update(ctx, thread.ID, &DiscussionThreadsUpdateOptions{Delete: true}); err != nil {
 		t.Fatal(err)
 	}
 
+	// The tokens for replying to notifications about the thread are no longer
+	// valid.
+	if _, err := MailReplyTokens.Get(ctx, token); err != ErrInvalidToken {
+		t.Errorf("got error %v, want %v", err, ErrInvalidToken)
+	}
+
 	// Thread no longer exists.
 	_, err = DiscussionThreads.Get(ctx, thread.ID)
 	if _, ok := err.(*ErrThreadNotFound); !ok {
@@ -324,6 +337,145 @@ func TestDiscussionThreads_Delete(t *testing.T) {
 	}
 }
 
+func TestDiscussionThreads_Search(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	user, err := Users.Create(ctx, NewUser{
+		Email:                 "a@a.com",
+		Username:              "u",
+		Password:              "p",
+		EmailVerificationCode: "c",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a repository to comply with the postgres repo constraint.
+	if err := Repos.Upsert(ctx, api.InsertRepoOp{Name: "myrepo", Description: "", Fork: false, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := Repos.GetByName(ctx, "myrepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the threads, and a comment on each.
+	threadIDs := map[string]int64{}
+	for _, thread := range []struct{ title, comment string }{
+		{"Rendering is slow", "The renderer allocates too much."},
+		{"Typo in README", "It says rendr instead of render."},
+		{"Unrelated", "Nothing to see here."},
+	} {
+		created, err := DiscussionThreads.Create(ctx, &types.DiscussionThread{
+			AuthorUserID: user.ID,
+			Title:        thread.title,
+			TargetRepo: &types.DiscussionThreadTargetRepo{
+				RepoID:   repo.ID,
+				Path:     strPtr("foo/bar/mux.go"),
+				Revision: strPtr("0c1a96370c1a96370c1a96370c1a96370c1a9637"),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DiscussionComments.Create(ctx, &types.DiscussionComment{
+			ThreadID:     created.ID,
+			AuthorUserID: user.ID,
+			Contents:     thread.comment,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		threadIDs[thread.title] = created.ID
+	}
+	if _, err := DiscussionThreads.Update(ctx, threadIDs["Typo in README"], &DiscussionThreadsUpdateOptions{Archive: boolPtr(true)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		query string
+		want  []string
+	}{
+		// Matches in the title rank higher than matches in comments.
+		{"render", []string{"Rendering is slow", "Typo in README"}},
+		{"render allocates", []string{"Rendering is slow"}},
+		{"render -is:archived", []string{"Rendering is slow"}},
+		{"is:archived", []string{"Typo in README"}},
+		{"render author:u repo:myrepo", []string{"Rendering is slow", "Typo in README"}},
+		{"render author:nobody", nil},
+		{"created:>2000-01-01 nothing", []string{"Unrelated"}},
+		{"created:<2000-01-01", nil},
+	} {
+		opts := &DiscussionThreadsListOptions{}
+		opts.SetFromQuery(ctx, test.query)
+		threads, err := DiscussionThreads.List(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, thread := range threads {
+			titles = append(titles, thread.Title)
+		}
+		if !reflect.DeepEqual(titles, test.want) {
+			t.Errorf("%q: got threads %q, want %q", test.query, titles, test.want)
+		}
+		if count, err := DiscussionThreads.Count(ctx, opts); err != nil {
+			t.Fatal(err)
+		} else if count != len(test.want) {
+			t.Errorf("%q: got count %d, want %d", test.query, count, len(test.want))
+		}
+	}
+}
+
+func TestDiscussionThreadsListOptions_tsquery(t *testing.T) {
+	for _, test := range []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"render", "render:*"},
+		{"slow render!", "slow:* & render:*"},
+		{"it's (foo|bar) & 'baz':*", "it:* & s:* & foo:* & bar:* & baz:*"},
+	} {
+		opts := &DiscussionThreadsListOptions{SearchQuery: &test.query}
+		if got := opts.tsquery(); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseCreatedRange(t *testing.T) {
+	date := func(s string) *time.Time {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			panic(err)
+		}
+		return &t
+	}
+	for _, test := range []struct {
+		value         string
+		after, before *time.Time
+		ok            bool
+	}{
+		{">2019-01-01", date("2019-01-01T23:59:59.999999Z"), nil, true},
+		{">=2019-01-01", date("2018-12-31T23:59:59.999999Z"), nil, true},
+		{"<2019-01-01", nil, date("2019-01-01T00:00:00Z"), true},
+		{"<=2019-01-01", nil, date("2019-01-02T00:00:00Z"), true},
+		{"2019-01-01", date("2018-12-31T23:59:59.999999Z"), date("2019-01-02T00:00:00Z"), true},
+		{">2019-01-01T12:00:00Z", date("2019-01-01T12:00:00Z"), nil, true},
+		{"<=2019-01-01T12:00:00Z", nil, date("2019-01-01T12:00:00.000001Z"), true},
+		{">yesterday-ish", nil, nil, false},
+	} {
+		after, before, ok := parseCreatedRange(test.value)
+		if ok != test.ok || !reflect.DeepEqual(after, test.after) || !reflect.DeepEqual(before, test.before) {
+			t.Errorf("%q: got %v, %v, %v, want %v, %v, %v", test.value, after, before, ok, test.after, test.before, test.ok)
+		}
+	}
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/pkg/trace"
+)
+
+// eventLogs provides access to the `event_logs` table.
+//
+// For a detailed overview of the schema, see schema.md.
+type eventLogs struct{}
+
+// Sources of logged events.
+const (
+	// EventSourceWeb is the source of the events logged by the web app and
+	// the browser extensions.
+	EventSourceWeb = "WEB"
+
+	// EventSourceBackend is the source of the events logged by the backend
+	// services (through eventlogger.LogEvent).
+	EventSourceBackend = "BACKEND"
+)
+
+// EventLog is a usage event, such as a page view or a search query.
+type EventLog struct {
+	Name string
+
+	// UserID is the ID of the user that caused the event, or 0 if the user is
+	// anonymous. AnonymousUserID is the ID the web app assigned to the user
+	// (which is stored in a cookie) and may be empty for backend events.
+	UserID          int32
+	AnonymousUserID string
+
+	Source string
+
+	// Argument holds the details of the event (such as the type of a search
+	// query). It may be nil.
+	Argument json.RawMessage
+
+	// Repository is the name of the repository that the event happened in,
+	// if any.
+	Repository string
+
+	Timestamp time.Time
+}
+
+// Insert logs the event. If its timestamp is zero, the current time is used.
+func (*eventLogs) Insert(ctx context.Context, e *EventLog) error {
+	if Mocks.EventLogs.Insert != nil {
+		return Mocks.EventLogs.Insert(ctx, e)
+	}
+
+	if e.Name == "" {
+		return errors.New("event name must be present")
+	}
+	if e.Source == "" {
+		return errors.New("event source must be present")
+	}
+
+	var argument, repository, timestamp interface{}
+	if len(e.Argument) > 0 {
+		argument = []byte(e.Argument)
+	}
+	if e.Repository != "" {
+		repository = e.Repository
+	}
+	if !e.Timestamp.IsZero() {
+		timestamp = e.Timestamp.UTC()
+	}
+	_, err := dbconn.Global.ExecContext(ctx,
+		`INSERT INTO event_logs(name, user_id, anonymous_user_id, source, argument, repository, "timestamp") VALUES($1, $2, $3, $4, $5, $6, COALESCE($7, now()))`,
+		e.Name, e.UserID, e.AnonymousUserID, e.Source, argument, repository, timestamp,
+	)
+	return err
+}
+
+// What the event counts returned by EventLogs.Breakdown are broken down by.
+const (
+	// EventLogsByName breaks down the counts by event name.
+	EventLogsByName = "name"
+
+	// EventLogsByRepository breaks down the counts by repository.
+	EventLogsByRepository = "repository"
+
+	// EventLogsByArgument breaks down the counts by the value of one key of
+	// the event arguments (such as the type of search queries).
+	EventLogsByArgument = "argument"
+)
+
+// eventLogsPeriods are the valid EventLogsBreakdownOptions.Period values,
+// which are units understood by PostgreSQL's date_trunc.
+var eventLogsPeriods = map[string]bool{
+	"day":     true,
+	"week":    true,
+	"month":   true,
+	"quarter": true,
+	"year":    true,
+}
+
+// EventLogsBreakdownOptions contains options for breaking down event counts.
+type EventLogsBreakdownOptions struct {
+	// From and To are the (inclusive) start and (exclusive) end of the time
+	// range of the counted events.
+	From, To time.Time
+
+	// Name, if set, counts only the events with this name.
+	Name string
+
+	// By is what the counts are broken down by (EventLogsByName,
+	// EventLogsByRepository or EventLogsByArgument). ArgumentKey is the key
+	// of the event arguments to break down by for EventLogsByArgument.
+	By          string
+	ArgumentKey string
+
+	// Period, if set, additionally breaks down the counts by the period
+	// ("day", "week", "month", "quarter" or "year", in UTC) the events
+	// happened in.
+	Period string
+}
+
+// EventLogsCount is the number of events (and of distinct users that caused
+// them) with one value of what they are broken down by.
+type EventLogsCount struct {
+	// PeriodStart is the start of the period of the events, if they are
+	// broken down by period.
+	PeriodStart *time.Time
+
+	// Value is the event name, repository or argument value (depending on
+	// what the events are broken down by). It is nil for the events without
+	// a repository or argument value.
+	Value *string
+
+	Count     int32
+	UserCount int32
+}
+
+// Breakdown returns the counts of events broken down as specified by opt,
+// ordered by period and then by decreasing count.
+//
+// 🚨 SECURITY: The caller must ensure that the user is a site admin. The
+// counts reveal the names of repositories and details of the events.
+func (*eventLogs) Breakdown(ctx context.Context, opt EventLogsBreakdownOptions) (counts []*EventLogsCount, err error) {
+	if Mocks.EventLogs.Breakdown != nil {
+		return Mocks.EventLogs.Breakdown(ctx, opt)
+	}
+
+	tr, ctx := trace.New(ctx, "db.EventLogs.Breakdown", "")
+	defer func() {
+		tr.SetError(err)
+		tr.LogFields(otlog.Int("count", len(counts)))
+		tr.Finish()
+	}()
+
+	var value *sqlf.Query
+	switch opt.By {
+	case EventLogsByName:
+		value = sqlf.Sprintf("name")
+	case EventLogsByRepository:
+		value = sqlf.Sprintf("repository")
+	case EventLogsByArgument:
+		if opt.ArgumentKey == "" {
+			return nil, errors.New("argument key must be present to break down by argument")
+		}
+		value = sqlf.Sprintf("argument->>%s", opt.ArgumentKey)
+	default:
+		return nil, fmt.Errorf("invalid event breakdown %q", opt.By)
+	}
+
+	periodStart := sqlf.Sprintf("NULL::timestamp")
+	if opt.Period != "" {
+		if !eventLogsPeriods[opt.Period] {
+			return nil, fmt.Errorf("invalid event breakdown period %q", opt.Period)
+		}
+		periodStart = sqlf.Sprintf(`date_trunc(%s, "timestamp" AT TIME ZONE 'UTC')`, opt.Period)
+	}
+
+	conds := []*sqlf.Query{
+		sqlf.Sprintf(`"timestamp" >= %s`, opt.From.UTC()),
+		sqlf.Sprintf(`"timestamp" < %s`, opt.To.UTC()),
+	}
+	if opt.Name != "" {
+		conds = append(conds, sqlf.Sprintf("name=%s", opt.Name))
+	}
+
+	// Anonymous users are told apart by the ID the web app assigned to them.
+	q := sqlf.Sprintf(`SELECT %s, %s, COUNT(*), COUNT(DISTINCT CASE WHEN user_id=0 THEN anonymous_user_id ELSE user_id::text END)
+FROM event_logs
+WHERE %s
+GROUP BY 1, 2
+ORDER BY 1, 3 DESC, 2`, periodStart, value, sqlf.Join(conds, "AND"))
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryContext")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c EventLogsCount
+		if err := rows.Scan(&c.PeriodStart, &c.Value, &c.Count, &c.UserCount); err != nil {
+			return nil, err
+		}
+		if c.PeriodStart != nil {
+			t := c.PeriodStart.UTC()
+			c.PeriodStart = &t
+		}
+		counts = append(counts, &c)
+	}
+	return counts, rows.Err()
+}
+
+// DeleteOlderThan deletes the events that happened before t and returns how
+// many were deleted. It is used to enforce the retention of event logs.
+func (*eventLogs) DeleteOlderThan(ctx context.Context, t time.Time) (int64, error) {
+	if Mocks.EventLogs.DeleteOlderThan != nil {
+		return Mocks.EventLogs.DeleteOlderThan(ctx, t)
+	}
+	res, err := dbconn.Global.ExecContext(ctx, `DELETE FROM event_logs WHERE "timestamp" < $1`, t.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,12 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type MockEventLogs struct {
+	Insert          func(ctx context.Context, e *EventLog) error
+	Breakdown       func(ctx context.Context, opt EventLogsBreakdownOptions) ([]*EventLogsCount, error)
+	DeleteOlderThan func(ctx context.Context, t time.Time) (int64, error)
+}
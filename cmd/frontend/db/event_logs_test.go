@@ -0,0 +1,107 @@
+package db
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+)
+
+func TestEventLogs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := dbtesting.TestContext(t)
+
+	day := func(month time.Month, d int) time.Time { return time.Date(2018, month, d, 12, 0, 0, 0, time.UTC) }
+	events := []*EventLog{
+		{Name: "SEARCHQUERY", UserID: 1, Source: EventSourceWeb, Argument: json.RawMessage(`{"type": "regexp"}`), Timestamp: day(1, 2)},
+		{Name: "SEARCHQUERY", UserID: 1, Source: EventSourceWeb, Argument: json.RawMessage(`{"type": "literal"}`), Timestamp: day(2, 3)},
+		{Name: "SEARCHQUERY", AnonymousUserID: "a", Source: EventSourceWeb, Argument: json.RawMessage(`{"type": "regexp"}`), Timestamp: day(4, 5)},
+		{Name: "CODEINTEL", UserID: 2, Source: EventSourceWeb, Repository: "github.com/foo/bar", Timestamp: day(4, 6)},
+		{Name: "CODEINTEL", AnonymousUserID: "b", Source: EventSourceWeb, Repository: "github.com/foo/bar", Timestamp: day(5, 7)},
+		{Name: "CODEINTEL", AnonymousUserID: "b", Source: EventSourceWeb, Timestamp: day(7, 1)},
+	}
+	for _, e := range events {
+		if err := EventLogs.Insert(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := EventLogs.Insert(ctx, &EventLog{Name: "PAGEVIEW", AnonymousUserID: "c", Source: EventSourceWeb}); err != nil {
+		t.Fatal(err)
+	}
+
+	str := func(s string) *string { return &s }
+	tm := func(t time.Time) *time.Time { return &t }
+	from, to := day(1, 1), day(7, 1) // the first half of 2018
+	tests := map[string]struct {
+		opt  EventLogsBreakdownOptions
+		want []*EventLogsCount
+	}{
+		"by name": {
+			opt: EventLogsBreakdownOptions{From: from, To: to, By: EventLogsByName},
+			want: []*EventLogsCount{
+				{Value: str("SEARCHQUERY"), Count: 3, UserCount: 2},
+				{Value: str("CODEINTEL"), Count: 2, UserCount: 2},
+			},
+		},
+		"by argument": {
+			opt: EventLogsBreakdownOptions{From: from, To: to, Name: "SEARCHQUERY", By: EventLogsByArgument, ArgumentKey: "type"},
+			want: []*EventLogsCount{
+				{Value: str("regexp"), Count: 2, UserCount: 2},
+				{Value: str("literal"), Count: 1, UserCount: 1},
+			},
+		},
+		"by repository": {
+			opt: EventLogsBreakdownOptions{From: day(1, 1), To: day(12, 1), Name: "CODEINTEL", By: EventLogsByRepository},
+			want: []*EventLogsCount{
+				{Value: str("github.com/foo/bar"), Count: 2, UserCount: 2},
+				{Value: nil, Count: 1, UserCount: 1},
+			},
+		},
+		"by quarter": {
+			opt: EventLogsBreakdownOptions{From: from, To: to, By: EventLogsByName, Period: "quarter"},
+			want: []*EventLogsCount{
+				{PeriodStart: tm(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)), Value: str("SEARCHQUERY"), Count: 2, UserCount: 1},
+				{PeriodStart: tm(time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC)), Value: str("CODEINTEL"), Count: 2, UserCount: 2},
+				{PeriodStart: tm(time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC)), Value: str("SEARCHQUERY"), Count: 1, UserCount: 1},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			counts, err := EventLogs.Breakdown(ctx, test.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(counts, test.want) {
+				t.Errorf("got %s, want %s", asJSON(t, counts), asJSON(t, test.want))
+			}
+		})
+	}
+
+	if _, err := EventLogs.Breakdown(ctx, EventLogsBreakdownOptions{By: EventLogsByName, Period: "decade"}); err == nil {
+		t.Error("got no error for an invalid period")
+	}
+
+	deleted, err := EventLogs.DeleteOlderThan(ctx, day(4, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(3); deleted != want {
+		t.Errorf("got %d deleted events, want %d", deleted, want)
+	}
+	counts, err := EventLogs.Breakdown(ctx, EventLogsBreakdownOptions{From: day(1, 1), To: time.Now().Add(time.Hour), By: EventLogsByName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*EventLogsCount{
+		{Value: str("CODEINTEL"), Count: 3, UserCount: 2},
+		{Value: str("PAGEVIEW"), Count: 1, UserCount: 1},
+	}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("got %s, want %s", asJSON(t, counts), asJSON(t, want))
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+)
+
+// mailReplyTokens provides access to the `mail_reply_tokens` table.
+//
+// For a detailed overview of the schema, see schema.md.
+type mailReplyTokens struct{}
+
+// MailReplyToken is a token that gives a user access to act on a target by
+// replying to an email notification. The kind of the token (such as
+// "discussion-thread") determines what the target is and how replies are
+// handled.
+type MailReplyToken struct {
+	UserID int32
+	Kind   string
+	Target string
+}
+
+// Kinds of mail reply tokens whose targets are stored in this package. The
+// tokens of these kinds are deleted along with their targets.
+const (
+	// MailReplyKindDiscussionThread is the kind of the tokens whose target is
+	// the ID of a discussion thread.
+	MailReplyKindDiscussionThread = "discussion-thread"
+
+	// MailReplyKindSavedSearch is the kind of the tokens whose target is the
+	// ID of a saved search.
+	MailReplyKindSavedSearch = "saved-search"
+)
+
+// Generate gets the existing token, or generates a new one, for giving the
+// specified user access to the target of the specified kind through only the
+// token.
+//
+// 🚨 SECURITY: The caller must ensure the token is ONLY given to the user that
+// is passed to this method. Anyone with the token has access to act on the
+// specified target as the specified user (as the handler of replies of the
+// token's kind allows), at ANY point in the future.
+func (*mailReplyTokens) Generate(ctx context.Context, userID int32, kind, target string) (string, error) {
+	if Mocks.MailReplyTokens.Generate != nil {
+		return Mocks.MailReplyTokens.Generate(ctx, userID, kind, target)
+	}
+
+	// Check if there already exists a token for this user, kind and target.
+	// If there is, we do not need to store a new one.
+	var token string
+	err := dbconn.Global.QueryRowContext(ctx, "SELECT token FROM mail_reply_tokens WHERE user_id=$1 AND kind=$2 AND target=$3 AND deleted_at IS NULL", userID, kind, target).Scan(&token)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	if err == nil {
+		return token, nil // use the existing token
+	}
+
+	// Generate a new secure token and store it. We use SHA256 because it is
+	// short and its characters are valid to place in an email address field
+	// like "foo+TOKEN@gmail.com", while still providing good security.
+	h := sha256.New()
+	_, err = io.Copy(h, io.LimitReader(cryptorand.Reader, 128)) // Using 128 bytes just to be on the safe side, but 32 bytes should be enough.
+	if err != nil {
+		return "", err
+	}
+	token = fmt.Sprintf("%x", h.Sum(nil))
+
+	_, err = dbconn.Global.ExecContext(ctx, "INSERT INTO mail_reply_tokens(token, user_id, kind, target) VALUES($1, $2, $3, $4)", token, userID, kind, target)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ErrInvalidToken is returned by MailReplyTokens.Get when the token is
+// invalid.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Get returns the user, kind and target found for the given token. If there
+// is none, the token is invalid and ErrInvalidToken is returned.
+func (*mailReplyTokens) Get(ctx context.Context, token string) (*MailReplyToken, error) {
+	if Mocks.MailReplyTokens.Get != nil {
+		return Mocks.MailReplyTokens.Get(ctx, token)
+	}
+	var t MailReplyToken
+	err := dbconn.Global.QueryRowContext(ctx, "SELECT user_id, kind, target FROM mail_reply_tokens WHERE token=$1 AND deleted_at IS NULL", token).Scan(
+		&t.UserID,
+		&t.Kind,
+		&t.Target,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &t, nil
+}
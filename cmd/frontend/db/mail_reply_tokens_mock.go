@@ -0,0 +1,8 @@
+package db
+
+import "context"
+
+type MockMailReplyTokens struct {
+	Generate func(ctx context.Context, userID int32, kind, target string) (string, error)
+	Get      func(ctx context.Context, token string) (*MailReplyToken, error)
+}
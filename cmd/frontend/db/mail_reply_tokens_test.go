@@ -0,0 +1,65 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+)
+
+func TestMailReplyTokens(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := dbtesting.TestContext(t)
+
+	user, err := Users.Create(ctx, NewUser{
+		Email:                 "a@example.com",
+		Username:              "u1",
+		Password:              "p1",
+		EmailVerificationCode: "c1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := MailReplyTokens.Generate(ctx, user.ID, "saved-search", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing token is reused for the same user, kind and target.
+	if again, err := MailReplyTokens.Generate(ctx, user.ID, "saved-search", "1"); err != nil || again != token {
+		t.Errorf("got token %q (error %v), want the existing token %q", again, err, token)
+	}
+	for _, kindTarget := range [][2]string{{"saved-search", "2"}, {"discussion-thread", "1"}} {
+		other, err := MailReplyTokens.Generate(ctx, user.ID, kindTarget[0], kindTarget[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other == token {
+			t.Errorf("%v: got the token of another target", kindTarget)
+		}
+	}
+
+	got, err := MailReplyTokens.Get(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&MailReplyToken{UserID: user.ID, Kind: "saved-search", Target: "1"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := MailReplyTokens.Get(ctx, "doesnotexist"); err != ErrInvalidToken {
+		t.Errorf("got error %v, want %v", err, ErrInvalidToken)
+	}
+
+	// The tokens of a deleted user are no longer valid.
+	if err := Users.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MailReplyTokens.Get(ctx, token); err != ErrInvalidToken {
+		t.Errorf("got error %v, want %v", err, ErrInvalidToken)
+	}
+}
@@ -6,9 +6,12 @@ var Mocks MockStores
 type MockStores struct {
 	AccessTokens MockAccessTokens
 
-	DiscussionThreads         MockDiscussionThreads
-	DiscussionComments        MockDiscussionComments
-	DiscussionMailReplyTokens MockDiscussionMailReplyTokens
+	DiscussionThreads  MockDiscussionThreads
+	DiscussionComments MockDiscussionComments
+
+	MailReplyTokens MockMailReplyTokens
+
+	EventLogs MockEventLogs
 
 	Repos         MockRepos
 	Orgs          MockOrgs
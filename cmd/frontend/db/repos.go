@@ -133,6 +133,7 @@ var getBySQLColumns = []string{
 	"uri",
 	"description",
 	"language",
+	"pinned",
 }
 
 func (s *repos) getBySQL(ctx context.Context, querySuffix *sqlf.Query) ([]*types.Repo, error) {
@@ -197,6 +198,7 @@ func scanRepo(rows *sql.Rows, r *types.Repo) (err error) {
 		&dbutil.NullString{S: &r.URI},
 		&r.Description,
 		&r.Language,
+		&r.Pinned,
 	)
 }
 
@@ -631,6 +633,24 @@ func (s *repos) SetEnabled(ctx context.Context, id api.RepoID, enabled bool) err
 	return nil
 }
 
+// SetPinned sets whether gitserver keeps the repository on disk even when it
+// needs to free up disk space.
+func (s *repos) SetPinned(ctx context.Context, id api.RepoID, pinned bool) error {
+	q := sqlf.Sprintf("UPDATE repo SET pinned=%t WHERE id=%d", pinned, id)
+	res, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return &repoNotFoundErr{ID: id}
+	}
+	return nil
+}
+
 func (s *repos) UpdateLanguage(ctx context.Context, repo api.RepoID, language string) error {
 	_, err := dbconn.Global.ExecContext(ctx, "UPDATE repo SET language=$1 WHERE id=$2", language, repo)
 	return err
@@ -13,6 +13,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/actor"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
 )
 
 /*
@@ -619,3 +620,31 @@ func TestRepos_Create_dupe(t *testing.T) {
 	// Add another repo with the same name.
 	createRepo(ctx, t, &types.Repo{Name: "a/b"})
 }
+
+func TestRepos_SetPinned(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := dbtesting.TestContext(t)
+
+	repo := mustCreate(ctx, t, &types.Repo{Name: "a/b"})[0]
+	if repo.Pinned {
+		t.Fatal("new repo is pinned")
+	}
+
+	if err := Repos.SetPinned(ctx, repo.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := Repos.Get(ctx, repo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !repo.Pinned {
+		t.Error("repo is not pinned")
+	}
+
+	if err := Repos.SetPinned(ctx, repo.ID+1, true); !errcode.IsNotFound(err) {
+		t.Errorf("got error %v, want not found", err)
+	}
+}
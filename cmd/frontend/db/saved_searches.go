@@ -3,11 +3,15 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
 
 	otlog "github.com/opentracing/opentracing-go/log"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 
 	"github.com/keegancsmith/sqlf"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
@@ -16,6 +20,10 @@ import (
 
 type savedSearches struct{}
 
+// ErrSavedSearchNotFound is returned by SavedSearches methods when the saved
+// search does not exist.
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
 // IsEmpty tells if there are no saved searches (at all) on this Sourcegraph
 // instance.
 func (s *savedSearches) IsEmpty(ctx context.Context) (bool, error) {
@@ -56,7 +64,10 @@ func (s *savedSearches) ListAll(ctx context.Context) (savedSearches []api.SavedQ
 		notify_slack,
 		user_id,
 		org_id,
-		slack_webhook_url FROM saved_searches
+		slack_webhook_url,
+		webhook_url,
+		webhook_secret,
+		webhook_headers FROM saved_searches
 	`)
 	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar))
 	if err != nil {
@@ -64,7 +75,11 @@ func (s *savedSearches) ListAll(ctx context.Context) (savedSearches []api.SavedQ
 	}
 
 	for rows.Next() {
-		var sq api.SavedQuerySpecAndConfig
+		var (
+			sq                        api.SavedQuerySpecAndConfig
+			webhookURL, webhookSecret *string
+			webhookHeaders            []byte
+		)
 		if err := rows.Scan(
 			&sq.Config.Key,
 			&sq.Config.Description,
@@ -73,9 +88,15 @@ func (s *savedSearches) ListAll(ctx context.Context) (savedSearches []api.SavedQ
 			&sq.Config.NotifySlack,
 			&sq.Config.UserID,
 			&sq.Config.OrgID,
-			&sq.Config.SlackWebhookURL); err != nil {
+			&sq.Config.SlackWebhookURL,
+			&webhookURL,
+			&webhookSecret,
+			&webhookHeaders); err != nil {
 			return nil, errors.Wrap(err, "Scan")
 		}
+		if sq.Config.Webhook, err = savedQueryWebhook(webhookURL, webhookSecret, webhookHeaders); err != nil {
+			return nil, err
+		}
 		sq.Spec.Key = sq.Config.Key
 		if sq.Config.UserID != nil {
 			sq.Spec.Subject.User = sq.Config.UserID
@@ -96,7 +117,11 @@ func (s *savedSearches) GetByID(ctx context.Context, id int32) (*api.SavedQueryS
 	if Mocks.SavedSearches.GetByID != nil {
 		return Mocks.SavedSearches.GetByID(ctx, id)
 	}
-	var sq api.SavedQuerySpecAndConfig
+	var (
+		sq                        api.SavedQuerySpecAndConfig
+		webhookURL, webhookSecret *string
+		webhookHeaders            []byte
+	)
 
 	err := dbconn.Global.QueryRowContext(ctx, `SELECT
 		id,
@@ -106,7 +131,10 @@ func (s *savedSearches) GetByID(ctx context.Context, id int32) (*api.SavedQueryS
 		notify_slack,
 		user_id,
 		org_id,
-		slack_webhook_url
+		slack_webhook_url,
+		webhook_url,
+		webhook_secret,
+		webhook_headers
 		FROM saved_searches WHERE id=$1`, id).Scan(
 		&sq.Config.Key,
 		&sq.Config.Description,
@@ -115,10 +143,16 @@ func (s *savedSearches) GetByID(ctx context.Context, id int32) (*api.SavedQueryS
 		&sq.Config.NotifySlack,
 		&sq.Config.UserID,
 		&sq.Config.OrgID,
-		&sq.Config.SlackWebhookURL)
+		&sq.Config.SlackWebhookURL,
+		&webhookURL,
+		&webhookSecret,
+		&webhookHeaders)
 	if err != nil {
 		return nil, err
 	}
+	if sq.Config.Webhook, err = savedQueryWebhook(webhookURL, webhookSecret, webhookHeaders); err != nil {
+		return nil, err
+	}
 	sq.Spec.Key = sq.Config.Key
 	if sq.Config.UserID != nil {
 		sq.Spec.Subject.User = sq.Config.UserID
@@ -166,7 +200,10 @@ func (s *savedSearches) ListSavedSearchesByUserID(ctx context.Context, userID in
 		notify_slack,
 		user_id,
 		org_id,
-		slack_webhook_url
+		slack_webhook_url,
+		webhook_url,
+		webhook_secret,
+		webhook_headers
 		FROM saved_searches %v`, conds)
 
 	rows, err := dbconn.Global.QueryContext(ctx, query.Query(sqlf.PostgresBindVar), query.Args()...)
@@ -174,10 +211,16 @@ func (s *savedSearches) ListSavedSearchesByUserID(ctx context.Context, userID in
 		return nil, errors.Wrap(err, "QueryContext(2)")
 	}
 	for rows.Next() {
-		var ss types.SavedSearch
-		if err := rows.Scan(&ss.ID, &ss.Description, &ss.Query, &ss.Notify, &ss.NotifySlack, &ss.UserID, &ss.OrgID, &ss.SlackWebhookURL); err != nil {
+		var (
+			ss             types.SavedSearch
+			webhookHeaders []byte
+		)
+		if err := rows.Scan(&ss.ID, &ss.Description, &ss.Query, &ss.Notify, &ss.NotifySlack, &ss.UserID, &ss.OrgID, &ss.SlackWebhookURL, &ss.WebhookURL, &ss.WebhookSecret, &webhookHeaders); err != nil {
 			return nil, errors.Wrap(err, "Scan(2)")
 		}
+		if err := json.Unmarshal(webhookHeaders, &ss.WebhookHeaders); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal webhook headers")
+		}
 		savedSearches = append(savedSearches, &ss)
 	}
 	return savedSearches, nil
@@ -201,7 +244,10 @@ func (s *savedSearches) ListSavedSearchesByOrgID(ctx context.Context, orgID int3
 		notify_slack,
 		user_id,
 		org_id,
-		slack_webhook_url
+		slack_webhook_url,
+		webhook_url,
+		webhook_secret,
+		webhook_headers
 		FROM saved_searches %v`, conds)
 
 	rows, err := dbconn.Global.QueryContext(ctx, query.Query(sqlf.PostgresBindVar), query.Args()...)
@@ -209,10 +255,16 @@ func (s *savedSearches) ListSavedSearchesByOrgID(ctx context.Context, orgID int3
 		return nil, errors.Wrap(err, "QueryContext")
 	}
 	for rows.Next() {
-		var ss types.SavedSearch
-		if err := rows.Scan(&ss.ID, &ss.Description, &ss.Query, &ss.Notify, &ss.NotifySlack, &ss.UserID, &ss.OrgID, &ss.SlackWebhookURL); err != nil {
+		var (
+			ss             types.SavedSearch
+			webhookHeaders []byte
+		)
+		if err := rows.Scan(&ss.ID, &ss.Description, &ss.Query, &ss.Notify, &ss.NotifySlack, &ss.UserID, &ss.OrgID, &ss.SlackWebhookURL, &ss.WebhookURL, &ss.WebhookSecret, &webhookHeaders); err != nil {
 			return nil, errors.Wrap(err, "Scan")
 		}
+		if err := json.Unmarshal(webhookHeaders, &ss.WebhookHeaders); err != nil {
+			return nil, errors.Wrap(err, "Unmarshal webhook headers")
+		}
 		savedSearches = append(savedSearches, &ss)
 	}
 	return savedSearches, nil
@@ -240,12 +292,20 @@ func (s *savedSearches) Create(ctx context.Context, newSavedSearch *types.SavedS
 	}()
 
 	savedQuery = &types.SavedSearch{
-		Description: newSavedSearch.Description,
-		Query:       newSavedSearch.Query,
-		Notify:      newSavedSearch.Notify,
-		NotifySlack: newSavedSearch.NotifySlack,
-		UserID:      newSavedSearch.UserID,
-		OrgID:       newSavedSearch.OrgID,
+		Description:    newSavedSearch.Description,
+		Query:          newSavedSearch.Query,
+		Notify:         newSavedSearch.Notify,
+		NotifySlack:    newSavedSearch.NotifySlack,
+		UserID:         newSavedSearch.UserID,
+		OrgID:          newSavedSearch.OrgID,
+		WebhookURL:     newSavedSearch.WebhookURL,
+		WebhookSecret:  newSavedSearch.WebhookSecret,
+		WebhookHeaders: newSavedSearch.WebhookHeaders,
+	}
+
+	webhookHeaders, err := marshalWebhookHeaders(newSavedSearch.WebhookHeaders)
+	if err != nil {
+		return nil, err
 	}
 
 	err = dbconn.Global.QueryRowContext(ctx, `INSERT INTO saved_searches(
@@ -254,14 +314,20 @@ func (s *savedSearches) Create(ctx context.Context, newSavedSearch *types.SavedS
 			notify_owner,
 			notify_slack,
 			user_id,
-			org_id
-		) VALUES($1, $2, $3, $4, $5, $6) RETURNING id`,
+			org_id,
+			webhook_url,
+			webhook_secret,
+			webhook_headers
+		) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
 		newSavedSearch.Description,
 		newSavedSearch.Query,
 		newSavedSearch.Notify,
 		newSavedSearch.NotifySlack,
 		newSavedSearch.UserID,
 		newSavedSearch.OrgID,
+		newSavedSearch.WebhookURL,
+		newSavedSearch.WebhookSecret,
+		webhookHeaders,
 	).Scan(&savedQuery.ID)
 	if err != nil {
 		return nil, err
@@ -293,6 +359,14 @@ func (s *savedSearches) Update(ctx context.Context, savedSearch *types.SavedSear
 		UserID:          savedSearch.UserID,
 		OrgID:           savedSearch.OrgID,
 		SlackWebhookURL: savedSearch.SlackWebhookURL,
+		WebhookURL:      savedSearch.WebhookURL,
+		WebhookSecret:   savedSearch.WebhookSecret,
+		WebhookHeaders:  savedSearch.WebhookHeaders,
+	}
+
+	webhookHeaders, err := marshalWebhookHeaders(savedSearch.WebhookHeaders)
+	if err != nil {
+		return nil, err
 	}
 
 	fieldUpdates := []*sqlf.Query{
@@ -304,6 +378,9 @@ func (s *savedSearches) Update(ctx context.Context, savedSearch *types.SavedSear
 		sqlf.Sprintf("user_id=%v", savedSearch.UserID),
 		sqlf.Sprintf("org_id=%v", savedSearch.OrgID),
 		sqlf.Sprintf("slack_webhook_url=%v", savedSearch.SlackWebhookURL),
+		sqlf.Sprintf("webhook_url=%v", savedSearch.WebhookURL),
+		sqlf.Sprintf("webhook_secret=%v", savedSearch.WebhookSecret),
+		sqlf.Sprintf("webhook_headers=%s", webhookHeaders),
 	}
 
 	updateQuery := sqlf.Sprintf(`UPDATE saved_searches SET %s WHERE ID=%v RETURNING id`, sqlf.Join(fieldUpdates, ", "), savedSearch.ID)
@@ -313,6 +390,35 @@ func (s *savedSearches) Update(ctx context.Context, savedSearch *types.SavedSear
 	return savedQuery, nil
 }
 
+// marshalWebhookHeaders returns the JSON representation of headers, as stored
+// in the webhook_headers column.
+func marshalWebhookHeaders(headers map[string]string) ([]byte, error) {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	b, err := json.Marshal(headers)
+	return b, errors.Wrap(err, "Marshal webhook headers")
+}
+
+// savedQueryWebhook returns the webhook configuration of a saved query from
+// the webhook_* columns of its row, or nil if no webhook is configured.
+func savedQueryWebhook(url, secret *string, headers []byte) (*api.SavedQueryWebhook, error) {
+	if url == nil || *url == "" {
+		return nil, nil
+	}
+	w := &api.SavedQueryWebhook{URL: *url}
+	if secret != nil {
+		w.Secret = *secret
+	}
+	if err := json.Unmarshal(headers, &w.Headers); err != nil {
+		return nil, errors.Wrap(err, "Unmarshal webhook headers")
+	}
+	if len(w.Headers) == 0 {
+		w.Headers = nil
+	}
+	return w, nil
+}
+
 // Delete hard-deletes an existing saved search.
 //
 // 🚨 SECURITY: This method does NOT verify the user's identity or that the
@@ -328,9 +434,72 @@ func (s *savedSearches) Delete(ctx context.Context, id int32) (err error) {
 		tr.SetError(err)
 		tr.Finish()
 	}()
+	_, err = dbconn.Global.ExecContext(ctx, `DELETE FROM mail_reply_tokens WHERE kind=$1 AND target=$2`, MailReplyKindSavedSearch, strconv.FormatInt(int64(id), 10))
+	if err != nil {
+		return err
+	}
 	_, err = dbconn.Global.ExecContext(ctx, `DELETE FROM saved_searches WHERE ID=$1`, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// MuteEmailNotifications stops email notifications about new results of the
+// saved search from being sent to the user until the given time, or
+// indefinitely if until is nil. It replaces any previous mute of the saved
+// search by the user. If the saved search does not exist,
+// ErrSavedSearchNotFound is returned.
+//
+// 🚨 SECURITY: This method does NOT verify the user's identity or that the
+// user can access the saved search. It is the callers responsibility to
+// ensure that only the specified user can mute notifications on their behalf.
+func (s *savedSearches) MuteEmailNotifications(ctx context.Context, id, userID int32, until *time.Time) (err error) {
+	if Mocks.SavedSearches.MuteEmailNotifications != nil {
+		return Mocks.SavedSearches.MuteEmailNotifications(ctx, id, userID, until)
+	}
+
+	tr, ctx := trace.New(ctx, "db.SavedSearches.MuteEmailNotifications", "")
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+	_, err = dbconn.Global.ExecContext(ctx, `
+INSERT INTO saved_search_email_mutes(saved_search_id, user_id, muted_until)
+VALUES($1, $2, $3)
+ON CONFLICT (saved_search_id, user_id) DO UPDATE SET muted_until=excluded.muted_until, created_at=now()`, id, userID, until)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "saved_search_email_mutes_saved_search_id_fkey" {
+		return ErrSavedSearchNotFound
+	}
+	return err
+}
+
+// ListEmailMutedUserIDs returns the IDs of the users who currently muted email
+// notifications about new results of the saved search.
+func (s *savedSearches) ListEmailMutedUserIDs(ctx context.Context, id int32) (userIDs []int32, err error) {
+	if Mocks.SavedSearches.ListEmailMutedUserIDs != nil {
+		return Mocks.SavedSearches.ListEmailMutedUserIDs(ctx, id)
+	}
+
+	tr, ctx := trace.New(ctx, "db.SavedSearches.ListEmailMutedUserIDs", "")
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+	rows, err := dbconn.Global.QueryContext(ctx, `
+SELECT user_id FROM saved_search_email_mutes
+WHERE saved_search_id=$1 AND (muted_until IS NULL OR muted_until > now())
+ORDER BY user_id`, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "QueryContext")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int32
+		if err := rows.Scan(&userID); err != nil {
+			return nil, errors.Wrap(err, "Scan")
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
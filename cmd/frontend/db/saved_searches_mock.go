@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
@@ -14,4 +15,6 @@ type MockSavedSearches struct {
 	Update                    func(ctx context.Context, savedSearch *types.SavedSearch) (*types.SavedSearch, error)
 	Delete                    func(ctx context.Context, id int32) error
 	GetByID                   func(ctx context.Context, id int32) (*api.SavedQuerySpecAndConfig, error)
+	MuteEmailNotifications    func(ctx context.Context, id, userID int32, until *time.Time) error
+	ListEmailMutedUserIDs     func(ctx context.Context, id int32) ([]int32, error)
 }
@@ -3,6 +3,7 @@ package db
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
@@ -160,12 +161,22 @@ func TestSavedSearchesDelete(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	token, err := MailReplyTokens.Generate(ctx, userID, MailReplyKindSavedSearch, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	err = SavedSearches.Delete(ctx, 1)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	// The tokens for replying to notifications about the saved search are
+	// deleted with it.
+	if _, err := MailReplyTokens.Get(ctx, token); err != ErrInvalidToken {
+		t.Errorf("got error %v, want %v", err, ErrInvalidToken)
+	}
+
 	allQueries, err := SavedSearches.ListAll(ctx)
 	if err != nil {
 		t.Fatal(err)
@@ -380,3 +391,58 @@ func TestListSavedSearchesByUserID(t *testing.T) {
 		t.Errorf("got %v, want %v", savedSearches, want)
 	}
 }
+
+func TestSavedSearchesMuteEmailNotifications(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := dbtesting.TestContext(t)
+	var userIDs []int32
+	for _, username := range []string{"u1", "u2", "u3"} {
+		user, err := Users.Create(ctx, NewUser{Email: username + "@example.com", Username: username, Password: "p", EmailVerificationCode: "c"})
+		if err != nil {
+			t.Fatal("can't create user", err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	ss, err := SavedSearches.Create(ctx, &types.SavedSearch{
+		Query:       "test",
+		Description: "test",
+		Notify:      true,
+		UserID:      &userIDs[0],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	for _, mute := range []struct {
+		userID int32
+		until  *time.Time
+	}{
+		{userIDs[0], &past},   // mute already expired
+		{userIDs[1], &future}, // muted for an hour
+		{userIDs[2], &past},   // replaced by the indefinite mute below
+		{userIDs[2], nil},
+	} {
+		if err := SavedSearches.MuteEmailNotifications(ctx, ss.ID, mute.userID, mute.until); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	muted, err := SavedSearches.ListEmailMutedUserIDs(ctx, ss.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := userIDs[1:]; !reflect.DeepEqual(muted, want) {
+		t.Errorf("got muted users %v, want %v", muted, want)
+	}
+
+	if err := SavedSearches.Delete(ctx, ss.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavedSearches.MuteEmailNotifications(ctx, ss.ID, userIDs[0], nil); err != ErrSavedSearchNotFound {
+		t.Errorf("got error %v, want %v", err, ErrSavedSearchNotFound)
+	}
+}
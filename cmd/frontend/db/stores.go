@@ -1,21 +1,22 @@
 package db
 
 var (
-	AccessTokens              = &accessTokens{}
-	ExternalServices          = &ExternalServicesStore{}
-	DefaultRepos              = &defaultRepos{}
-	DiscussionThreads         = &discussionThreads{}
-	DiscussionComments        = &discussionComments{}
-	DiscussionMailReplyTokens = &discussionMailReplyTokens{}
-	Repos                     = &repos{}
-	Phabricator               = &phabricator{}
-	QueryRunnerState          = &queryRunnerState{}
-	Orgs                      = &orgs{}
-	OrgMembers                = &orgMembers{}
-	SavedSearches             = &savedSearches{}
-	Settings                  = &settings{}
-	Users                     = &users{}
-	UserEmails                = &userEmails{}
+	AccessTokens       = &accessTokens{}
+	ExternalServices   = &ExternalServicesStore{}
+	DefaultRepos       = &defaultRepos{}
+	DiscussionThreads  = &discussionThreads{}
+	DiscussionComments = &discussionComments{}
+	EventLogs          = &eventLogs{}
+	MailReplyTokens    = &mailReplyTokens{}
+	Repos              = &repos{}
+	Phabricator        = &phabricator{}
+	QueryRunnerState   = &queryRunnerState{}
+	Orgs               = &orgs{}
+	OrgMembers         = &orgMembers{}
+	SavedSearches      = &savedSearches{}
+	Settings           = &settings{}
+	Users              = &users{}
+	UserEmails         = &userEmails{}
 
 	SurveyResponses = &surveyResponses{}
 
@@ -402,10 +402,11 @@ func (u *users) Delete(ctx context.Context, id int32) error {
 		return err
 	}
 
-	// Soft-delete discussions data.
-	if _, err := tx.ExecContext(ctx, "UPDATE discussion_mail_reply_tokens SET deleted_at=now() WHERE deleted_at IS NULL AND user_id=$1", id); err != nil {
+	if _, err := tx.ExecContext(ctx, "UPDATE mail_reply_tokens SET deleted_at=now() WHERE deleted_at IS NULL AND user_id=$1", id); err != nil {
 		return err
 	}
+
+	// Soft-delete discussions data.
 	if _, err := tx.ExecContext(ctx, "UPDATE discussion_comments SET deleted_at=now() WHERE deleted_at IS NULL AND author_user_id=$1", id); err != nil {
 		return err
 	}
@@ -471,8 +472,12 @@ func (u *users) HardDelete(ctx context.Context, id int32) error {
 		return err
 	}
 
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mail_reply_tokens WHERE user_id=$1", id); err != nil {
+		return err
+	}
+
 	// Hard-delete discussions data.
-	if _, err := tx.ExecContext(ctx, "DELETE FROM discussion_mail_reply_tokens WHERE user_id=$1", id); err != nil {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mail_reply_tokens WHERE kind=$1 AND target IN (SELECT id::text FROM discussion_threads WHERE author_user_id=$2)", MailReplyKindDiscussionThread, id); err != nil {
 		return err
 	}
 	if _, err := tx.ExecContext(ctx, "UPDATE discussion_threads SET target_repo_id=null WHERE author_user_id=$1", id); err != nil {
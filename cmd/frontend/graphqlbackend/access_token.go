@@ -64,3 +64,7 @@ func (r *accessTokenResolver) CreatedAt() DateTime { return DateTime{Time: r.acc
 func (r *accessTokenResolver) LastUsedAt() *DateTime {
 	return DateTimeOrNil(r.accessToken.LastUsedAt)
 }
+
+func (r *accessTokenResolver) ExpiresAt() *DateTime {
+	return DateTimeOrNil(r.accessToken.ExpiresAt)
+}
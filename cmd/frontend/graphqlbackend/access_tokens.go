@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
@@ -17,9 +18,10 @@ import (
 )
 
 type createAccessTokenInput struct {
-	User   graphql.ID
-	Scopes []string
-	Note   string
+	User      graphql.ID
+	Scopes    []string
+	Note      string
+	ExpiresAt *DateTime
 }
 
 func (r *schemaResolver) CreateAccessToken(ctx context.Context, args *createAccessTokenInput) (*createAccessTokenResult, error) {
@@ -31,33 +33,23 @@ func (r *schemaResolver) CreateAccessToken(ctx context.Context, args *createAcce
 	if err := backend.CheckSiteAdminOrSameUser(ctx, userID); err != nil {
 		return nil, err
 	}
-
-	switch conf.AccessTokensAllow() {
-	case conf.AccessTokensAll:
-		// Allow
-	case conf.AccessTokensAdmin:
-		if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
-			return nil, errors.New("Access token creation has been restricted to admin users. Contact an admin user to create a new access token.")
-		}
-	case conf.AccessTokensNone:
-		fallthrough
-	default:
-		return nil, errors.New("Access token creation is disabled. Contact an admin user to enable.")
+	if err := checkAccessTokenCreationAllowed(ctx); err != nil {
+		return nil, err
 	}
 
 	// Validate scopes.
-	var hasUserAllScope bool
+	var hasNonSudoScope bool
 	seenScope := map[string]struct{}{}
 	sort.Strings(args.Scopes)
 	for _, scope := range args.Scopes {
 		switch scope {
-		case authz.ScopeUserAll:
-			hasUserAllScope = true
 		case authz.ScopeSiteAdminSudo:
 			// 🚨 SECURITY: Only site admins may create a token with the "site-admin:sudo" scope.
 			if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
 				return nil, err
 			}
+		case authz.ScopeUserAll, authz.ScopeSearchRead, authz.ScopeRepoRead, authz.ScopeSettingsWrite, authz.ScopeLSIFUpload, authz.ScopeExternalServicesManage:
+			hasNonSudoScope = true
 		default:
 			return nil, fmt.Errorf("unknown access token scope %q (valid scopes: %q)", scope, authz.AllScopes)
 		}
@@ -67,14 +59,103 @@ func (r *schemaResolver) CreateAccessToken(ctx context.Context, args *createAcce
 		}
 		seenScope[scope] = struct{}{}
 	}
-	if !hasUserAllScope {
-		return nil, fmt.Errorf("all access tokens must have scope %q", authz.ScopeUserAll)
+	if !hasNonSudoScope {
+		return nil, fmt.Errorf("access tokens must have at least one scope other than %q", authz.ScopeSiteAdminSudo)
 	}
 
-	id, token, err := db.AccessTokens.Create(ctx, userID, args.Scopes, args.Note, actor.FromContext(ctx).UID)
+	var expiresAt *time.Time
+	if args.ExpiresAt != nil {
+		if !args.ExpiresAt.Time.After(time.Now()) {
+			return nil, errAccessTokenExpiresAtInPast
+		}
+		expiresAt = &args.ExpiresAt.Time
+	}
+
+	id, token, err := db.AccessTokens.Create(ctx, userID, args.Scopes, args.Note, actor.FromContext(ctx).UID, expiresAt)
 	return &createAccessTokenResult{id: marshalAccessTokenID(id), token: token}, err
 }
 
+var errAccessTokenExpiresAtInPast = errors.New("access token expiration date must be in the future")
+
+// checkAccessTokenCreationAllowed returns an error if the site configuration does not allow the
+// current user to create access tokens.
+func checkAccessTokenCreationAllowed(ctx context.Context) error {
+	switch conf.AccessTokensAllow() {
+	case conf.AccessTokensAll:
+		return nil
+	case conf.AccessTokensAdmin:
+		if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+			return errors.New("Access token creation has been restricted to admin users. Contact an admin user to create a new access token.")
+		}
+		return nil
+	case conf.AccessTokensNone:
+		fallthrough
+	default:
+		return errors.New("Access token creation is disabled. Contact an admin user to enable.")
+	}
+}
+
+// defaultAccessTokenRotationGracePeriod is how long a rotated access token remains valid if the
+// rotateAccessToken mutation does not specify a grace period.
+const defaultAccessTokenRotationGracePeriod = 24 * time.Hour
+
+type rotateAccessTokenInput struct {
+	ByID               graphql.ID
+	GracePeriodSeconds *int32
+	ExpiresAt          *DateTime
+}
+
+func (r *schemaResolver) RotateAccessToken(ctx context.Context, args *rotateAccessTokenInput) (*createAccessTokenResult, error) {
+	accessTokenID, err := unmarshalAccessTokenID(args.ByID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := db.AccessTokens.GetByID(ctx, accessTokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: Only site admins and the user can rotate a user's access token, and only if they
+	// could create it in the first place.
+	if err := backend.CheckSiteAdminOrSameUser(ctx, token.SubjectUserID); err != nil {
+		return nil, err
+	}
+	if err := checkAccessTokenCreationAllowed(ctx); err != nil {
+		return nil, err
+	}
+	for _, scope := range token.Scopes {
+		if scope == authz.ScopeSiteAdminSudo {
+			// 🚨 SECURITY: Only site admins may create a token with the "site-admin:sudo" scope.
+			if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	gracePeriod := defaultAccessTokenRotationGracePeriod
+	if args.GracePeriodSeconds != nil {
+		if *args.GracePeriodSeconds < 0 {
+			return nil, errors.New("access token rotation grace period must not be negative")
+		}
+		gracePeriod = time.Duration(*args.GracePeriodSeconds) * time.Second
+	}
+
+	// By default, the new access token is valid for as long as the old access token was.
+	var expiresAt *time.Time
+	if args.ExpiresAt != nil {
+		if !args.ExpiresAt.Time.After(time.Now()) {
+			return nil, errAccessTokenExpiresAtInPast
+		}
+		expiresAt = &args.ExpiresAt.Time
+	} else if token.ExpiresAt != nil {
+		t := time.Now().Add(token.ExpiresAt.Sub(token.CreatedAt))
+		expiresAt = &t
+	}
+
+	id, value, err := db.AccessTokens.Rotate(ctx, token.ID, token.SubjectUserID, actor.FromContext(ctx).UID, time.Now().Add(gracePeriod), expiresAt)
+	return &createAccessTokenResult{id: marshalAccessTokenID(id), token: value}, err
+}
+
 type createAccessTokenResult struct {
 	id    graphql.ID
 	token string
@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/gqltesting"
@@ -17,7 +18,7 @@ import (
 // 🚨 SECURITY: This tests that users can't create tokens for users they aren't allowed to do so for.
 func TestMutation_CreateAccessToken(t *testing.T) {
 	mockAccessTokensCreate := func(t *testing.T, wantCreatorUserID int32, wantScopes []string) {
-		db.Mocks.AccessTokens.Create = func(subjectUserID int32, scopes []string, note string, creatorUserID int32) (int64, string, error) {
+		db.Mocks.AccessTokens.Create = func(subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (int64, string, error) {
 			if want := int32(1); subjectUserID != want {
 				t.Errorf("got %v, want %v", subjectUserID, want)
 			}
@@ -97,6 +98,69 @@ func TestMutation_CreateAccessToken(t *testing.T) {
 		}
 	})
 
+	t.Run("authenticated as user, using restricted scopes with expiration", func(t *testing.T) {
+		resetMocks()
+		wantExpiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		db.Mocks.AccessTokens.Create = func(subjectUserID int32, scopes []string, note string, creatorUserID int32, expiresAt *time.Time) (int64, string, error) {
+			if want := []string{authz.ScopeRepoRead, authz.ScopeSearchRead}; !reflect.DeepEqual(scopes, want) {
+				t.Errorf("got %q, want %q", scopes, want)
+			}
+			if expiresAt == nil || !expiresAt.Equal(wantExpiresAt) {
+				t.Errorf("got expiresAt %v, want %v", expiresAt, wantExpiresAt)
+			}
+			return 1, "t", nil
+		}
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		result, err := (&schemaResolver{}).CreateAccessToken(ctx, &createAccessTokenInput{
+			User:      uid1GQLID,
+			Scopes:    []string{authz.ScopeSearchRead, authz.ScopeRepoRead},
+			Note:      "n",
+			ExpiresAt: &DateTime{Time: wantExpiresAt},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "t"; result.Token() != want {
+			t.Errorf("got token %q, want %q", result.Token(), want)
+		}
+	})
+
+	t.Run("authenticated as user, using expiration in the past", func(t *testing.T) {
+		resetMocks()
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		result, err := (&schemaResolver{}).CreateAccessToken(ctx, &createAccessTokenInput{
+			User:      uid1GQLID,
+			Scopes:    []string{authz.ScopeUserAll},
+			Note:      "n",
+			ExpiresAt: &DateTime{Time: time.Now().Add(-time.Hour)},
+		})
+		if err == nil {
+			t.Error("err == nil")
+		}
+		if result != nil {
+			t.Errorf("got result %v, want nil", result)
+		}
+	})
+
+	t.Run("authenticated with restricted access token", func(t *testing.T) {
+		resetMocks()
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1, AccessTokenScopes: []string{authz.ScopeSearchRead}})
+		result, err := (&schemaResolver{}).CreateAccessToken(ctx, &createAccessTokenInput{
+			User:   uid1GQLID,
+			Scopes: []string{authz.ScopeUserAll},
+			Note:   "n",
+		})
+		if _, ok := err.(*authz.InsufficientScopeError); !ok {
+			t.Errorf("got err %v, want *authz.InsufficientScopeError", err)
+		}
+		if result != nil {
+			t.Errorf("got result %v, want nil", result)
+		}
+	})
+
 	t.Run("authenticated as site admin, using site-admin-only scopes", func(t *testing.T) {
 		resetMocks()
 		mockAccessTokensCreate(t, 1, []string{authz.ScopeSiteAdminSudo, authz.ScopeUserAll})
@@ -321,3 +385,105 @@ func TestMutation_DeleteAccessToken(t *testing.T) {
 		}
 	})
 }
+
+// 🚨 SECURITY: This tests that users can't rotate tokens of other users, and that rotated tokens
+// keep their lifetime.
+func TestMutation_RotateAccessToken(t *testing.T) {
+	createdAt := time.Now().Add(-24 * time.Hour)
+	mockAccessTokens := func(t *testing.T, scopes []string, wantExpiresIn time.Duration) {
+		db.Mocks.AccessTokens.GetByID = func(id int64) (*db.AccessToken, error) {
+			if want := int64(1); id != want {
+				t.Errorf("got %d, want %d", id, want)
+			}
+			expiresAt := createdAt.Add(30 * 24 * time.Hour)
+			return &db.AccessToken{ID: 1, SubjectUserID: 2, Scopes: scopes, CreatedAt: createdAt, ExpiresAt: &expiresAt}, nil
+		}
+		db.Mocks.AccessTokens.Rotate = func(id int64, subjectUserID, creatorUserID int32, graceUntil time.Time, expiresAt *time.Time) (int64, string, error) {
+			if want := int64(1); id != want {
+				t.Errorf("got %d, want %d", id, want)
+			}
+			if want := int32(2); subjectUserID != want {
+				t.Errorf("got %v, want %v", subjectUserID, want)
+			}
+			if d := time.Until(graceUntil) - defaultAccessTokenRotationGracePeriod; d > time.Minute || d < -time.Minute {
+				t.Errorf("got graceUntil %v, want about %v from now", graceUntil, defaultAccessTokenRotationGracePeriod)
+			}
+			if expiresAt == nil {
+				t.Fatal("got expiresAt == nil")
+			}
+			if d := time.Until(*expiresAt) - wantExpiresIn; d > time.Minute || d < -time.Minute {
+				t.Errorf("got expiresAt %v, want about %v from now", expiresAt, wantExpiresIn)
+			}
+			return 3, "t", nil
+		}
+	}
+
+	token1GQLID := graphql.ID("QWNjZXNzVG9rZW46MQ==")
+
+	t.Run("authenticated as user", func(t *testing.T) {
+		resetMocks()
+		mockAccessTokens(t, []string{authz.ScopeUserAll}, 30*24*time.Hour)
+		gqltesting.RunTests(t, []*gqltesting.Test{
+			{
+				Context: actor.WithActor(context.Background(), &actor.Actor{UID: 2}),
+				Schema:  GraphQLSchema,
+				Query: `
+				mutation {
+					rotateAccessToken(byID: "` + string(token1GQLID) + `") {
+						id
+						token
+					}
+				}
+			`,
+				ExpectedResult: `
+				{
+					"rotateAccessToken": {
+						"id": "QWNjZXNzVG9rZW46Mw==",
+						"token": "t"
+					}
+				}
+			`,
+			},
+		})
+	})
+
+	t.Run("authenticated as different user", func(t *testing.T) {
+		resetMocks()
+		mockAccessTokens(t, []string{authz.ScopeUserAll}, 30*24*time.Hour)
+		db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 3, SiteAdmin: false}, nil
+		}
+		defer func() { db.Mocks.Users.GetByCurrentAuthUser = nil }()
+		db.Mocks.Users.MockGetByID_Return(t, &types.User{ID: 2, Username: "username"}, nil)
+		defer func() { db.Mocks.Users.GetByID = nil }()
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 3})
+		result, err := (&schemaResolver{}).RotateAccessToken(ctx, &rotateAccessTokenInput{ByID: token1GQLID})
+		if _, ok := err.(*backend.InsufficientAuthorizationError); !ok {
+			t.Errorf("got err %v, want *backend.InsufficientAuthorizationError", err)
+		}
+		if result != nil {
+			t.Errorf("got result %v, want nil", result)
+		}
+	})
+
+	t.Run("authenticated as user, rotating sudo token after being demoted", func(t *testing.T) {
+		resetMocks()
+		mockAccessTokens(t, []string{authz.ScopeUserAll, authz.ScopeSiteAdminSudo}, 30*24*time.Hour)
+		db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+			return &types.User{ID: 2, SiteAdmin: false}, nil
+		}
+		defer func() { db.Mocks.Users.GetByCurrentAuthUser = nil }()
+		db.Mocks.Users.MockGetByID_Return(t, &types.User{ID: 2, Username: "username"}, nil)
+		defer func() { db.Mocks.Users.GetByID = nil }()
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 2})
+		result, err := (&schemaResolver{}).RotateAccessToken(ctx, &rotateAccessTokenInput{ByID: token1GQLID})
+		if want := backend.ErrMustBeSiteAdmin; err != want {
+			t.Errorf("got err %v, want %v", err, want)
+		}
+		if result != nil {
+			t.Errorf("got result %v, want nil", result)
+		}
+	})
+}
@@ -0,0 +1,707 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/go-diff/diff"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
+	repoupdaterprotocol "github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+func (r *schemaResolver) CreateCampaignPlan(ctx context.Context, args *struct {
+	Query string
+}) (*campaignPlanResolver, error) {
+	// 🚨 SECURITY: Only site admins may create campaign plans.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	q, err := query.ParseAndCheck(args.Query)
+	if err != nil {
+		return nil, err
+	}
+	if replace, _ := q.StringValues(query.FieldReplace); len(replace) == 0 {
+		return nil, errors.New("the query of a campaign plan must contain a 'replace:' field")
+	}
+	cmodArgs, err := validateQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	sr := &searchResolver{query: q, zoekt: IndexedSearch()}
+	repoRevs, _, overLimit, err := sr.resolveRepositories(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if overLimit {
+		return nil, errors.New("the query of the campaign plan matches too many repositories, use 'repo:' filters to narrow it down")
+	}
+	if len(repoRevs) == 0 {
+		return nil, errors.New("the query of the campaign plan doesn't match any repositories")
+	}
+
+	plan := &types.CampaignPlan{Query: args.Query}
+	if err = r.CampaignsStore.CreateCampaignPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*types.CampaignJob, 0, len(repoRevs))
+	for _, repoRev := range repoRevs {
+		jobs = append(jobs, &types.CampaignJob{
+			CampaignPlanID: plan.ID,
+			RepoID:         repoRev.Repo.ID,
+		})
+	}
+	if err = r.CampaignsStore.CreateCampaignJobs(ctx, jobs...); err != nil {
+		return nil, err
+	}
+
+	runCampaignJobs(r.CampaignsStore, jobs, repoRevs, cmodArgs)
+
+	return &campaignPlanResolver{store: r.CampaignsStore, CampaignPlan: plan}, nil
+}
+
+const (
+	// campaignJobConcurrency is the maximum number of jobs of a campaign plan
+	// that call the codemod backend at the same time.
+	campaignJobConcurrency = 8
+
+	// campaignJobTimeout is the maximum duration of a single campaign job.
+	campaignJobTimeout = 5 * time.Minute
+
+	// campaignPlanTimeout is the maximum duration of all jobs of a campaign
+	// plan. Jobs that haven't started by then are marked as errored. Jobs that
+	// still haven't finished some time after that were abandoned (e.g. because
+	// the frontend running them was restarted) and are marked as errored by
+	// bg.ErrorAbandonedCampaignJobs.
+	campaignPlanTimeout = time.Hour
+
+	// campaignPublishTimeout is the maximum duration of publishing all diffs
+	// of a campaign. Diffs that weren't published by then are published when
+	// the campaign is published again.
+	campaignPublishTimeout = time.Hour
+)
+
+// runCampaignJobs runs the given jobs in the background. jobs[i] computes the
+// diff of the codemod described by args in repoRevs[i].
+func runCampaignJobs(store *db.CampaignsStore, jobs []*types.CampaignJob, repoRevs []*search.RepositoryRevisions, args *args) {
+	goroutine.Go(func() {
+		// The request that created the plan has long finished, so the jobs
+		// run in their own context.
+		ctx, cancel := context.WithTimeout(context.Background(), campaignPlanTimeout)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, campaignJobConcurrency)
+		for i := range jobs {
+			job, repoRev := jobs[i], repoRevs[i]
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				finishCampaignJob(store, job, errors.New("campaign plan timed out before the job started"))
+				continue
+			}
+			wg.Add(1)
+			goroutine.Go(func() {
+				defer func() { <-sem; wg.Done() }()
+				runCampaignJob(ctx, store, job, repoRev, args)
+			})
+		}
+		wg.Wait()
+	})
+}
+
+// runCampaignJob runs the codemod described by args in repoRev and stores its
+// diff (or the error that occurred) in the given job.
+func runCampaignJob(ctx context.Context, store *db.CampaignsStore, job *types.CampaignJob, repoRev *search.RepositoryRevisions, args *args) {
+	job.StartedAt = time.Now().UTC()
+	if err := store.UpdateCampaignJob(ctx, job); err != nil {
+		log15.Error("runCampaignJob: starting job failed", "job", job.ID, "error", err)
+		return
+	}
+
+	err := func() error {
+		ctx, cancel := context.WithTimeout(ctx, campaignJobTimeout)
+		defer cancel()
+
+		results, err := callCodemodInRepo(ctx, repoRev, args)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+
+		job.Rev = api.CommitID(results[0].commit.oid)
+		job.Diff, err = campaignJobDiff(results)
+		return err
+	}()
+	finishCampaignJob(store, job, err)
+}
+
+// finishCampaignJob stores the result of the given job, which failed if err
+// is non-nil.
+func finishCampaignJob(store *db.CampaignsStore, job *types.CampaignJob, err error) {
+	if err != nil {
+		job.Error = err.Error()
+	}
+	job.FinishedAt = time.Now().UTC()
+
+	// Store the result even if the job's context has expired.
+	if err := store.UpdateCampaignJob(context.Background(), job); err != nil {
+		log15.Error("runCampaignJob: finishing job failed", "job", job.ID, "error", err)
+	}
+}
+
+// campaignJobDiff combines the per-file diffs of the given codemod results
+// into a single unified diff of the repository that can be applied with
+// `git apply`. The codemod backend names both sides of a file diff by the
+// file's path, so the usual a/ and b/ prefixes are added. Each file diff also
+// gets a "diff --git" header, without which a "--- " line of the next file
+// can't be told apart from a removed line when the diff is parsed again.
+func campaignJobDiff(results []codemodResultResolver) (string, error) {
+	fileDiffs := make([]*diff.FileDiff, 0, len(results))
+	for _, r := range results {
+		fileDiff, err := diff.ParseFileDiff([]byte(r.diff))
+		if err != nil {
+			return "", errors.Wrapf(err, "parsing diff of %s", r.path)
+		}
+		fileDiff.OrigName = "a/" + r.path
+		fileDiff.NewName = "b/" + r.path
+		fileDiff.Extended = []string{"diff --git " + fileDiff.OrigName + " " + fileDiff.NewName}
+		for _, hunk := range fileDiff.Hunks {
+			if n := len(hunk.Body); n > 0 && hunk.Body[n-1] != '\n' {
+				hunk.Body = append(hunk.Body, '\n')
+			}
+		}
+		fileDiffs = append(fileDiffs, fileDiff)
+	}
+
+	sort.Slice(fileDiffs, func(i, j int) bool {
+		return fileDiffs[i].NewName < fileDiffs[j].NewName
+	})
+
+	b, err := diff.PrintMultiFileDiff(fileDiffs)
+	return string(b), err
+}
+
+type campaignPlanResolver struct {
+	store *db.CampaignsStore
+	*types.CampaignPlan
+
+	// cache jobs because they are used by multiple fields
+	once sync.Once
+	jobs []*types.CampaignJob
+	err  error
+}
+
+const campaignPlanIDKind = "CampaignPlan"
+
+func marshalCampaignPlanID(id int64) graphql.ID {
+	return relay.MarshalID(campaignPlanIDKind, id)
+}
+
+func unmarshalCampaignPlanID(id graphql.ID) (campaignPlanID int64, err error) {
+	err = relay.UnmarshalSpec(id, &campaignPlanID)
+	return
+}
+
+func campaignPlanByID(ctx context.Context, id graphql.ID) (*campaignPlanResolver, error) {
+	// 🚨 SECURITY: Only site admins may read campaign plans.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	planID, err := unmarshalCampaignPlanID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	store := db.NewCampaignsStore(dbconn.Global)
+	plan, err := store.GetCampaignPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &campaignPlanResolver{store: store, CampaignPlan: plan}, nil
+}
+
+func (r *campaignPlanResolver) ID() graphql.ID {
+	return marshalCampaignPlanID(r.CampaignPlan.ID)
+}
+
+func (r *campaignPlanResolver) Query() string {
+	return r.CampaignPlan.Query
+}
+
+func (r *campaignPlanResolver) Status(ctx context.Context) (*campaignPlanStatusResolver, error) {
+	jobs, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return campaignPlanStatus(jobs), nil
+}
+
+func (r *campaignPlanResolver) RepositoryDiffs(ctx context.Context, args *struct {
+	graphqlutil.ConnectionArgs
+}) (*campaignPlanRepositoryDiffConnectionResolver, error) {
+	jobs, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var withDiffs []*types.CampaignJob
+	for _, j := range jobs {
+		if j.Diff != "" {
+			withDiffs = append(withDiffs, j)
+		}
+	}
+	return &campaignPlanRepositoryDiffConnectionResolver{
+		jobs:  withDiffs,
+		first: args.ConnectionArgs.First,
+	}, nil
+}
+
+func (r *campaignPlanResolver) DiffStat(ctx context.Context) (*diffStat, error) {
+	jobs, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stat diffStat
+	for _, j := range jobs {
+		s, err := campaignJobDiffStat(j)
+		if err != nil {
+			return nil, err
+		}
+		stat.added += s.added
+		stat.changed += s.changed
+		stat.deleted += s.deleted
+	}
+	return &stat, nil
+}
+
+func (r *campaignPlanResolver) CreatedAt() DateTime {
+	return DateTime{Time: r.CampaignPlan.CreatedAt}
+}
+
+func (r *campaignPlanResolver) UpdatedAt() DateTime {
+	return DateTime{Time: r.CampaignPlan.UpdatedAt}
+}
+
+func (r *campaignPlanResolver) compute(ctx context.Context) ([]*types.CampaignJob, error) {
+	r.once.Do(func() {
+		r.jobs, r.err = allCampaignJobs(ctx, r.store, r.CampaignPlan.ID)
+	})
+	return r.jobs, r.err
+}
+
+// allCampaignJobs returns all jobs of the campaign plan with the given ID.
+func allCampaignJobs(ctx context.Context, store *db.CampaignsStore, planID int64) (js []*types.CampaignJob, err error) {
+	opts := db.ListCampaignJobsOpts{CampaignPlanID: planID, Limit: 1000}
+	for {
+		page, next, err := store.ListCampaignJobs(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		js = append(js, page...)
+		if next == 0 {
+			return js, nil
+		}
+		opts.Cursor = next
+	}
+}
+
+// allCampaignChangesets returns all changesets of the given campaign.
+func allCampaignChangesets(ctx context.Context, store *db.CampaignsStore, campaignID int64) (cs []*types.Changeset, err error) {
+	opts := db.ListChangesetsOpts{CampaignID: campaignID, Limit: 1000}
+	for {
+		page, next, err := store.ListChangesets(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, page...)
+		if next == 0 {
+			return cs, nil
+		}
+		opts.Cursor = next
+	}
+}
+
+// campaignJobDiffStat returns the statistics of the diff of the given job.
+func campaignJobDiffStat(j *types.CampaignJob) (*diffStat, error) {
+	var stat diffStat
+	if j.Diff == "" {
+		return &stat, nil
+	}
+
+	fileDiffs, err := diff.ParseMultiFileDiff([]byte(j.Diff))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing diff of campaign job %d", j.ID)
+	}
+	for _, fileDiff := range fileDiffs {
+		s := fileDiff.Stat()
+		stat.added += s.Added
+		stat.changed += s.Changed
+		stat.deleted += s.Deleted
+	}
+	return &stat, nil
+}
+
+type campaignPlanState string
+
+const (
+	campaignPlanStateProcessing campaignPlanState = "PROCESSING"
+	campaignPlanStateCompleted  campaignPlanState = "COMPLETED"
+	campaignPlanStateErrored    campaignPlanState = "ERRORED"
+)
+
+type campaignPlanStatusResolver struct {
+	state                        campaignPlanState
+	completedCount, pendingCount int32
+	errors                       []string
+}
+
+func (r *campaignPlanStatusResolver) State() string         { return string(r.state) }
+func (r *campaignPlanStatusResolver) CompletedCount() int32 { return r.completedCount }
+func (r *campaignPlanStatusResolver) PendingCount() int32   { return r.pendingCount }
+func (r *campaignPlanStatusResolver) Errors() []string      { return r.errors }
+
+// campaignPlanStatus computes the status of a campaign plan from its jobs. The
+// plan is processing until all jobs have finished, after which it is errored
+// if any of them failed.
+func campaignPlanStatus(jobs []*types.CampaignJob) *campaignPlanStatusResolver {
+	status := &campaignPlanStatusResolver{errors: []string{}}
+	for _, j := range jobs {
+		if j.FinishedAt.IsZero() {
+			status.pendingCount++
+			continue
+		}
+		status.completedCount++
+		if j.Error != "" {
+			status.errors = append(status.errors, j.Error)
+		}
+	}
+
+	switch {
+	case status.pendingCount > 0:
+		status.state = campaignPlanStateProcessing
+	case len(status.errors) > 0:
+		status.state = campaignPlanStateErrored
+	default:
+		status.state = campaignPlanStateCompleted
+	}
+	return status
+}
+
+type campaignPlanRepositoryDiffConnectionResolver struct {
+	jobs  []*types.CampaignJob
+	first *int32
+}
+
+func (r *campaignPlanRepositoryDiffConnectionResolver) Nodes() []*campaignPlanRepositoryDiffResolver {
+	jobs := r.jobs
+	if r.first != nil && int(*r.first) < len(jobs) {
+		jobs = jobs[:*r.first]
+	}
+	resolvers := make([]*campaignPlanRepositoryDiffResolver, 0, len(jobs))
+	for _, j := range jobs {
+		resolvers = append(resolvers, &campaignPlanRepositoryDiffResolver{job: j})
+	}
+	return resolvers
+}
+
+func (r *campaignPlanRepositoryDiffConnectionResolver) TotalCount() int32 {
+	return int32(len(r.jobs))
+}
+
+func (r *campaignPlanRepositoryDiffConnectionResolver) PageInfo() *graphqlutil.PageInfo {
+	return graphqlutil.HasNextPage(r.first != nil && int(*r.first) < len(r.jobs))
+}
+
+type campaignPlanRepositoryDiffResolver struct {
+	job *types.CampaignJob
+}
+
+func (r *campaignPlanRepositoryDiffResolver) Repository(ctx context.Context) (*RepositoryResolver, error) {
+	return repositoryByIDInt32(ctx, r.job.RepoID)
+}
+
+func (r *campaignPlanRepositoryDiffResolver) BaseCommit(ctx context.Context) (*GitCommitResolver, error) {
+	repo, err := repositoryByIDInt32(ctx, r.job.RepoID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.Commit(ctx, &repositoryCommitArgs{Rev: string(r.job.Rev)})
+}
+
+func (r *campaignPlanRepositoryDiffResolver) RawDiff() string {
+	return r.job.Diff
+}
+
+func (r *campaignPlanRepositoryDiffResolver) DiffStat() (*diffStat, error) {
+	return campaignJobDiffStat(r.job)
+}
+
+func (r *campaignPlanRepositoryDiffResolver) PublishedRef() *string {
+	return nonEmptyStringOrNil(r.job.PublishedRef)
+}
+
+func (r *schemaResolver) CreateCampaign(ctx context.Context, args *struct {
+	Input struct {
+		Namespace   graphql.ID
+		Name        string
+		Description string
+		Plan        *graphql.ID
+	}
+}) (*campaignResolver, error) {
+	// 🚨 SECURITY: Only site admins may create campaigns.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, backend.ErrNotAuthenticated
+	}
+
+	now := time.Now().UTC()
+	campaign := &types.Campaign{
+		Name:        args.Input.Name,
+		Description: args.Input.Description,
+		AuthorID:    user.DatabaseID(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	switch relay.UnmarshalKind(args.Input.Namespace) {
+	case "User":
+		campaign.NamespaceUserID, err = UnmarshalUserID(args.Input.Namespace)
+	case "Org":
+		campaign.NamespaceOrgID, err = UnmarshalOrgID(args.Input.Namespace)
+	default:
+		err = errors.New("invalid ID for namespace")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if args.Input.Plan != nil {
+		if campaign.CampaignPlanID, err = unmarshalCampaignPlanID(*args.Input.Plan); err != nil {
+			return nil, err
+		}
+		if _, err = r.CampaignsStore.GetCampaignPlan(ctx, campaign.CampaignPlanID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = r.CampaignsStore.CreateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+
+	return &campaignResolver{store: r.CampaignsStore, Campaign: campaign}, nil
+}
+
+func (r *schemaResolver) PublishCampaign(ctx context.Context, args *struct {
+	Campaign graphql.ID
+}) (*campaignResolver, error) {
+	// 🚨 SECURITY: Only site admins may publish campaigns.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	campaignID, err := unmarshalCampaignID(args.Campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign, err := r.CampaignsStore.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.CampaignPlanID == 0 {
+		return nil, errors.New("only campaigns created from a campaign plan can be published")
+	}
+
+	jobs, err := allCampaignJobs(ctx, r.CampaignsStore, campaign.CampaignPlanID)
+	if err != nil {
+		return nil, err
+	}
+	if status := campaignPlanStatus(jobs); status.state == campaignPlanStateProcessing {
+		return nil, errors.New("the campaign plan is still being processed")
+	}
+
+	publishingCampaigns.Lock()
+	defer publishingCampaigns.Unlock()
+	if publishingCampaigns.ids[campaign.ID] {
+		return nil, errors.New("the campaign is already being published")
+	}
+	publishingCampaigns.ids[campaign.ID] = true
+
+	goroutine.Go(func() {
+		defer func() {
+			publishingCampaigns.Lock()
+			delete(publishingCampaigns.ids, campaign.ID)
+			publishingCampaigns.Unlock()
+		}()
+
+		// The request that published the campaign has long finished, so the
+		// campaign is published in its own context.
+		ctx, cancel := context.WithTimeout(context.Background(), campaignPublishTimeout)
+		defer cancel()
+
+		publishCampaignJobs(ctx, r.CampaignsStore, campaign, jobs)
+	})
+
+	return &campaignResolver{store: r.CampaignsStore, Campaign: campaign}, nil
+}
+
+// publishingCampaigns is the set of IDs of the campaigns that are being
+// published in the background, which can't be published again until that has
+// finished.
+var publishingCampaigns = struct {
+	sync.Mutex
+	ids map[int64]bool
+}{ids: make(map[int64]bool)}
+
+// publishCampaignJobs publishes the diffs of the given jobs, each of which
+// becomes a changeset of the campaign. Jobs whose repository already has a
+// changeset in the campaign are skipped, so that publishing a campaign again
+// after it failed or timed out (e.g. because the frontend publishing it was
+// restarted) only publishes the remaining jobs.
+func publishCampaignJobs(ctx context.Context, store *db.CampaignsStore, campaign *types.Campaign, jobs []*types.CampaignJob) {
+	changesets, err := allCampaignChangesets(ctx, store, campaign.ID)
+	if err != nil {
+		log15.Error("publishCampaignJobs: listing changesets failed", "campaign", campaign.ID, "error", err)
+		return
+	}
+	published := make(map[api.RepoID]bool, len(changesets))
+	for _, c := range changesets {
+		published[c.RepoID] = true
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, campaignJobConcurrency)
+	for i := range jobs {
+		job := jobs[i]
+		if job.Diff == "" || published[job.RepoID] {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			log15.Error("publishCampaignJobs: campaign timed out before the job was published", "campaign", campaign.ID, "job", job.ID)
+			continue
+		}
+		wg.Add(1)
+		goroutine.Go(func() {
+			defer func() { <-sem; wg.Done() }()
+
+			ctx, cancel := context.WithTimeout(ctx, campaignJobTimeout)
+			defer cancel()
+
+			if err := publishCampaignJob(ctx, store, campaign, job); err != nil {
+				log15.Error("publishCampaignJobs: publishing job failed", "campaign", campaign.ID, "job", job.ID, "error", err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// publishCampaignJob creates a commit from the diff of the given job on top of
+// its base commit, pushes it to a new branch of the campaign on the code host
+// and records the pushed ref in the job. It then opens a changeset that
+// proposes to merge the branch into the repository's default branch and adds
+// it to the campaign.
+func publishCampaignJob(ctx context.Context, store *db.CampaignsStore, campaign *types.Campaign, job *types.CampaignJob) error {
+	repo, err := db.Repos.Get(ctx, job.RepoID)
+	if err != nil {
+		return err
+	}
+
+	branch := fmt.Sprintf("sourcegraph/campaign-%d", campaign.ID)
+
+	// The branch was already pushed if opening the changeset failed when the
+	// campaign was published before.
+	if job.PublishedRef == "" {
+		message := campaign.Name
+		if campaign.Description != "" {
+			message += "\n\n" + campaign.Description
+		}
+
+		res, err := gitserver.DefaultClient.CreateCommitFromPatchAndPush(ctx, protocol.CreateCommitFromPatchRequest{
+			Repo:       repo.Name,
+			BaseCommit: job.Rev,
+			TargetRef:  fmt.Sprintf("refs/sourcegraph/campaigns/%d", campaign.ID),
+			Patch:      job.Diff,
+			CommitInfo: protocol.PatchCommitInfo{
+				Message: message,
+				Date:    time.Now().UTC(),
+			},
+		}, protocol.PushConfig{Branch: branch})
+		if err != nil {
+			return errors.Wrapf(err, "creating commit in repository %q", repo.Name)
+		}
+		if res.RejectedReason != "" {
+			return errors.Errorf("pushing branch %q of repository %q was rejected: %s", branch, repo.Name, res.RejectedReason)
+		}
+
+		job.PublishedRef = res.Ref
+		if err := store.UpdateCampaignJob(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	base, err := NewRepositoryResolver(repo).DefaultBranch(ctx)
+	if err != nil {
+		return err
+	}
+	if base == nil {
+		return errors.Errorf("repository %q has no default branch", repo.Name)
+	}
+
+	externalID, err := repoupdater.DefaultClient.CreateChangeset(ctx, &repoupdaterprotocol.ChangesetCreateRequest{
+		RepoID:  repo.ID,
+		Title:   campaign.Name,
+		Body:    campaign.Description,
+		BaseRef: base.Name(),
+		HeadRef: "refs/heads/" + branch,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "opening changeset in repository %q", repo.Name)
+	}
+
+	c := &types.Changeset{CampaignID: campaign.ID, RepoID: repo.ID, ExternalID: externalID}
+	if err = store.CreateChangesets(ctx, c); err != nil {
+		return err
+	}
+
+	// Eagerly sync the new changeset. If it fails, it will be synced
+	// periodically by repo-updater anyway.
+	if err = repoupdater.DefaultClient.SyncChangesets(ctx, []int64{c.ID}); err != nil {
+		log15.Error("publishCampaignJob: syncing changeset failed", "id", c.ID, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
+	repoupdaterprotocol "github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
+
+func TestCampaignJobDiff(t *testing.T) {
+	results := []codemodResultResolver{
+		{path: "sub/b.go", diff: "--- sub/b.go\n+++ sub/b.go\n@@ -1,1 +1,2 @@\n-foo\n+bar\n+baz"},
+		{path: "a.go", diff: "--- a.go\n+++ a.go\n@@ -1,2 +1,1 @@\n-foo\n-bar\n+baz\n"},
+	}
+
+	have, err := campaignJobDiff(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,1 @@\n-foo\n-bar\n+baz\n" +
+		"diff --git a/sub/b.go b/sub/b.go\n--- a/sub/b.go\n+++ b/sub/b.go\n@@ -1,1 +1,2 @@\n-foo\n+bar\n+baz\n"
+	if have != want {
+		t.Fatalf("have diff\n%q\nwant\n%q", have, want)
+	}
+
+	stat, err := campaignJobDiffStat(&types.CampaignJob{Diff: have})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (diffStat{added: 1, changed: 2, deleted: 1}); *stat != want {
+		t.Errorf("have diff stat %+v, want %+v", *stat, want)
+	}
+}
+
+func TestCampaignPlanStatus(t *testing.T) {
+	finished := time.Date(2019, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name string
+		jobs []*types.CampaignJob
+		want campaignPlanStatusResolver
+	}{
+		{
+			name: "processing",
+			jobs: []*types.CampaignJob{
+				{FinishedAt: finished, Error: "boom"},
+				{},
+			},
+			want: campaignPlanStatusResolver{
+				state:          campaignPlanStateProcessing,
+				completedCount: 1,
+				pendingCount:   1,
+				errors:         []string{"boom"},
+			},
+		},
+		{
+			name: "errored",
+			jobs: []*types.CampaignJob{
+				{FinishedAt: finished, Error: "boom"},
+				{FinishedAt: finished},
+			},
+			want: campaignPlanStatusResolver{
+				state:          campaignPlanStateErrored,
+				completedCount: 2,
+				errors:         []string{"boom"},
+			},
+		},
+		{
+			name: "completed",
+			jobs: []*types.CampaignJob{
+				{FinishedAt: finished},
+				{FinishedAt: finished, Diff: "diff"},
+			},
+			want: campaignPlanStatusResolver{
+				state:          campaignPlanStateCompleted,
+				completedCount: 2,
+				errors:         []string{},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if have := campaignPlanStatus(tc.jobs); !reflect.DeepEqual(*have, tc.want) {
+				t.Errorf("have status %+v, want %+v", *have, tc.want)
+			}
+		})
+	}
+}
+
+func TestPublishCampaignJobs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := dbtesting.TestContext(t)
+	ctx = backend.WithAuthzBypass(ctx)
+	store := db.NewCampaignsStore(dbconn.Global)
+
+	user, err := db.Users.Create(ctx, db.NewUser{Username: "alice", Email: "alice@example.com", EmailIsVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var repoIDs []api.RepoID
+	for _, name := range []string{"github.com/foo/bar", "github.com/foo/baz"} {
+		var id api.RepoID
+		if err := dbconn.Global.QueryRowContext(ctx, "INSERT INTO repo (name, enabled) VALUES ($1, true) RETURNING id", name).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		repoIDs = append(repoIDs, id)
+	}
+
+	plan := &types.CampaignPlan{Query: "repo:foo replace:bar"}
+	if err := store.CreateCampaignPlan(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	jobs := []*types.CampaignJob{
+		{CampaignPlanID: plan.ID, RepoID: repoIDs[0], Rev: "deadbeef", Diff: "diff"},
+		{CampaignPlanID: plan.ID, RepoID: repoIDs[1], Rev: "deadbeef"}, // no changes
+	}
+	if err := store.CreateCampaignJobs(ctx, jobs...); err != nil {
+		t.Fatal(err)
+	}
+	campaign := &types.Campaign{Name: "Campaign", AuthorID: user.ID, NamespaceUserID: user.ID, CampaignPlanID: plan.ID}
+	if err := store.CreateCampaign(ctx, campaign); err != nil {
+		t.Fatal(err)
+	}
+
+	var pushes, creates int
+	gitserver.MockCreateCommitFromPatchAndPush = func(req protocol.CreateCommitFromPatchRequest, push protocol.PushConfig) (*protocol.PushResult, error) {
+		pushes++
+		return &protocol.PushResult{Ref: "refs/heads/" + push.Branch}, nil
+	}
+	repoupdater.MockCreateChangeset = func(ctx context.Context, req *repoupdaterprotocol.ChangesetCreateRequest) (string, error) {
+		creates++
+		if req.BaseRef != "refs/heads/master" || req.HeadRef != fmt.Sprintf("refs/heads/sourcegraph/campaign-%d", campaign.ID) {
+			t.Errorf("unexpected changeset request %+v", req)
+		}
+		return "42", nil
+	}
+	repoupdater.MockSyncChangesets = func(ctx context.Context, ids []int64) error { return nil }
+	git.Mocks.ExecSafe = func(params []string) ([]byte, []byte, int, error) {
+		return []byte("refs/heads/master\n"), nil, 0, nil
+	}
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		return "deadbeef", nil
+	}
+	defer func() {
+		gitserver.MockCreateCommitFromPatchAndPush = nil
+		repoupdater.MockCreateChangeset = nil
+		repoupdater.MockSyncChangesets = nil
+		git.ResetMocks()
+	}()
+
+	publishCampaignJobs(ctx, store, campaign, jobs)
+
+	changesets, err := allCampaignChangesets(ctx, store, campaign.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changesets) != 1 || changesets[0].RepoID != repoIDs[0] || changesets[0].ExternalID != "42" {
+		t.Fatalf("got changesets %+v, want one for repository %d", changesets, repoIDs[0])
+	}
+	if jobs[0].PublishedRef == "" {
+		t.Error("the published ref of the job was not recorded")
+	}
+
+	// Publishing the campaign again doesn't open another changeset.
+	publishCampaignJobs(ctx, store, campaign, jobs)
+	if pushes != 1 || creates != 1 {
+		t.Errorf("got %d pushes and %d changesets created, want 1 each", pushes, creates)
+	}
+}
@@ -2,8 +2,11 @@ package graphqlbackend
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"path"
 	"sync"
+	"time"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
@@ -11,6 +14,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
 )
 
 func (r *schemaResolver) Campaigns(ctx context.Context, args *struct {
@@ -47,7 +51,7 @@ func (r *campaignsConnectionResolver) Nodes(ctx context.Context) ([]*campaignRes
 	}
 	resolvers := make([]*campaignResolver, 0, len(campaigns))
 	for _, c := range campaigns {
-		resolvers = append(resolvers, &campaignResolver{Campaign: c})
+		resolvers = append(resolvers, &campaignResolver{store: r.store, Campaign: c})
 	}
 	return resolvers, nil
 }
@@ -72,7 +76,10 @@ func (r *campaignsConnectionResolver) compute(ctx context.Context) ([]*types.Cam
 	return r.campaigns, r.next, r.err
 }
 
-type campaignResolver struct{ *types.Campaign }
+type campaignResolver struct {
+	store *db.CampaignsStore
+	*types.Campaign
+}
 
 const campaignIDKind = "Campaign"
 
@@ -80,6 +87,31 @@ func marshalCampaignID(id int64) graphql.ID {
 	return relay.MarshalID(campaignIDKind, id)
 }
 
+func unmarshalCampaignID(id graphql.ID) (campaignID int64, err error) {
+	err = relay.UnmarshalSpec(id, &campaignID)
+	return
+}
+
+func campaignByID(ctx context.Context, id graphql.ID) (*campaignResolver, error) {
+	// 🚨 SECURITY: Only site admins may read campaigns.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	campaignID, err := unmarshalCampaignID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	store := db.NewCampaignsStore(dbconn.Global)
+	campaign, err := store.GetCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &campaignResolver{store: store, Campaign: campaign}, nil
+}
+
 func (r *campaignResolver) ID() graphql.ID {
 	return marshalCampaignID(r.Campaign.ID)
 }
@@ -115,6 +147,17 @@ func (r *campaignResolver) Namespace(ctx context.Context) (n namespaceResolver,
 	return n, err
 }
 
+func (r *campaignResolver) Plan(ctx context.Context) (*campaignPlanResolver, error) {
+	if r.Campaign.CampaignPlanID == 0 {
+		return nil, nil
+	}
+	plan, err := r.store.GetCampaignPlan(ctx, r.Campaign.CampaignPlanID)
+	if err != nil {
+		return nil, err
+	}
+	return &campaignPlanResolver{store: r.store, CampaignPlan: plan}, nil
+}
+
 func (r *campaignResolver) CreatedAt() DateTime {
 	return DateTime{Time: r.Campaign.CreatedAt}
 }
@@ -122,3 +165,74 @@ func (r *campaignResolver) CreatedAt() DateTime {
 func (r *campaignResolver) UpdatedAt() DateTime {
 	return DateTime{Time: r.Campaign.UpdatedAt}
 }
+
+func (r *campaignResolver) Changesets(ctx context.Context, args *struct {
+	graphqlutil.ConnectionArgs
+}) *changesetsConnectionResolver {
+	return &changesetsConnectionResolver{
+		store: r.store,
+		opts: db.ListChangesetsOpts{
+			CampaignID: r.Campaign.ID,
+			Limit:      int(args.ConnectionArgs.GetFirst()),
+		},
+	}
+}
+
+func (r *campaignResolver) ChangesetCounts(ctx context.Context) (*changesetCountsResolver, error) {
+	cs, err := r.allChangesets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return countChangesets(cs), nil
+}
+
+// maxChangesetCountsOverTimeDays is the maximum number of days (and thus data
+// points) that changesetCountsOverTime may span, which bounds the work done
+// for a single request.
+const maxChangesetCountsOverTimeDays = 2 * 366
+
+func (r *campaignResolver) ChangesetCountsOverTime(ctx context.Context, args *struct {
+	From *DateTime
+	To   *DateTime
+}) ([]*changesetCountsAtTimeResolver, error) {
+	maxRange := maxChangesetCountsOverTimeDays * 24 * time.Hour
+
+	from, to := r.Campaign.CreatedAt, time.Now()
+	if args.To != nil {
+		to = args.To.Time
+	}
+	if args.From != nil {
+		from = args.From.Time
+	} else if to.Sub(from) > maxRange {
+		// Default to the most recent days of old campaigns.
+		from = to.Add(-maxRange)
+	}
+	if to.Before(from) {
+		return nil, errors.New("to must not be before from")
+	}
+	if to.Sub(from) > maxRange {
+		return nil, fmt.Errorf("the time range from %s to %s is longer than the maximum of %d days", from.Format(time.RFC3339), to.Format(time.RFC3339), maxChangesetCountsOverTimeDays)
+	}
+
+	cs, err := r.allChangesets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return countChangesetsOverTime(cs, from, to), nil
+}
+
+// allChangesets returns all changesets of the campaign.
+func (r *campaignResolver) allChangesets(ctx context.Context) (cs []*types.Changeset, err error) {
+	opts := db.ListChangesetsOpts{CampaignID: r.Campaign.ID, Limit: 1000}
+	for {
+		page, next, err := r.store.ListChangesets(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, page...)
+		if next == 0 {
+			return cs, nil
+		}
+		opts.Cursor = next
+	}
+}
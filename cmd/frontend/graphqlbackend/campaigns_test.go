@@ -1,6 +1,7 @@
 package graphqlbackend
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -142,3 +143,25 @@ func TestCampaigns(t *testing.T) {
 		t.Fatalf("wrong result: %s", cmp.Diff(have, want))
 	}
 }
+
+func TestCampaignResolver_ChangesetCountsOverTime_range(t *testing.T) {
+	r := &campaignResolver{Campaign: &types.Campaign{CreatedAt: time.Date(2019, 9, 1, 0, 0, 0, 0, time.UTC)}}
+	to := &DateTime{Time: time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)}
+
+	for name, from := range map[string]*DateTime{
+		"to before from":  {Time: to.Time.Add(time.Hour)},
+		"too many days":   {Time: to.Time.AddDate(0, 0, -maxChangesetCountsOverTimeDays-1)},
+		"zero start time": {Time: time.Time{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The range is checked before the changesets are loaded, so no store is needed.
+			_, err := r.ChangesetCountsOverTime(context.Background(), &struct {
+				From *DateTime
+				To   *DateTime
+			}{From: from, To: to})
+			if err == nil {
+				t.Error("got no error, want error for invalid time range")
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/externallink"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/github"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+func (r *schemaResolver) CreateChangesets(ctx context.Context, args *struct {
+	Campaign   graphql.ID
+	Changesets []struct {
+		Repository graphql.ID
+		ExternalID string
+	}
+}) ([]*changesetResolver, error) {
+	// 🚨 SECURITY: Only site admins may create changesets.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	campaignID, err := unmarshalCampaignID(args.Campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = r.CampaignsStore.GetCampaign(ctx, campaignID); err != nil {
+		return nil, err
+	}
+
+	cs := make([]*types.Changeset, 0, len(args.Changesets))
+	for _, c := range args.Changesets {
+		repoID, err := unmarshalRepositoryID(c.Repository)
+		if err != nil {
+			return nil, err
+		}
+
+		repo, err := db.Repos.Get(ctx, repoID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch repo.ExternalRepo.ServiceType {
+		case github.ServiceType, bitbucketserver.ServiceType:
+		default:
+			return nil, fmt.Errorf("repository %q is not hosted on GitHub or Bitbucket Server", repo.Name)
+		}
+
+		if n, err := strconv.ParseInt(c.ExternalID, 10, 64); err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid pull request number %q", c.ExternalID)
+		}
+
+		cs = append(cs, &types.Changeset{
+			CampaignID: campaignID,
+			RepoID:     repoID,
+			ExternalID: c.ExternalID,
+		})
+	}
+
+	if err = r.CampaignsStore.CreateChangesets(ctx, cs...); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(cs))
+	for i, c := range cs {
+		ids[i] = c.ID
+	}
+
+	// Eagerly trigger a sync of the new changesets. If it fails, they will be
+	// synced periodically by repo-updater anyway.
+	if err = repoupdater.DefaultClient.SyncChangesets(ctx, ids); err != nil {
+		log15.Error("CreateChangesets: syncing changesets failed", "ids", ids, "error", err)
+	} else {
+		cs, _, err = r.CampaignsStore.ListChangesets(ctx, db.ListChangesetsOpts{
+			IDs:   ids,
+			Limit: len(ids),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resolvers := make([]*changesetResolver, 0, len(cs))
+	for _, c := range cs {
+		resolvers = append(resolvers, &changesetResolver{store: r.CampaignsStore, Changeset: c})
+	}
+	return resolvers, nil
+}
+
+type changesetsConnectionResolver struct {
+	store *db.CampaignsStore
+	opts  db.ListChangesetsOpts
+
+	// cache results because they are used by multiple fields
+	once       sync.Once
+	changesets []*types.Changeset
+	next       int64
+	err        error
+}
+
+func (r *changesetsConnectionResolver) Nodes(ctx context.Context) ([]*changesetResolver, error) {
+	changesets, _, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*changesetResolver, 0, len(changesets))
+	for _, c := range changesets {
+		resolvers = append(resolvers, &changesetResolver{store: r.store, Changeset: c})
+	}
+	return resolvers, nil
+}
+
+func (r *changesetsConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := r.store.CountChangesets(ctx, db.CountChangesetsOpts{CampaignID: r.opts.CampaignID})
+	return int32(count), err
+}
+
+func (r *changesetsConnectionResolver) PageInfo(ctx context.Context) (*graphqlutil.PageInfo, error) {
+	_, next, err := r.compute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return graphqlutil.HasNextPage(next != 0), nil
+}
+
+func (r *changesetsConnectionResolver) compute(ctx context.Context) ([]*types.Changeset, int64, error) {
+	r.once.Do(func() {
+		r.changesets, r.next, r.err = r.store.ListChangesets(ctx, r.opts)
+	})
+	return r.changesets, r.next, r.err
+}
+
+type changesetResolver struct {
+	store *db.CampaignsStore
+	*types.Changeset
+}
+
+const changesetIDKind = "Changeset"
+
+func marshalChangesetID(id int64) graphql.ID {
+	return relay.MarshalID(changesetIDKind, id)
+}
+
+func unmarshalChangesetID(id graphql.ID) (changesetID int64, err error) {
+	err = relay.UnmarshalSpec(id, &changesetID)
+	return
+}
+
+func changesetByID(ctx context.Context, id graphql.ID) (*changesetResolver, error) {
+	// 🚨 SECURITY: Only site admins may read changesets.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	changesetID, err := unmarshalChangesetID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	store := db.NewCampaignsStore(dbconn.Global)
+	cs, _, err := store.ListChangesets(ctx, db.ListChangesetsOpts{IDs: []int64{changesetID}, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(cs) == 0 {
+		return nil, errors.Wrapf(db.ErrNoResults, "changeset %d", changesetID)
+	}
+
+	return &changesetResolver{store: store, Changeset: cs[0]}, nil
+}
+
+func (r *changesetResolver) ID() graphql.ID {
+	return marshalChangesetID(r.Changeset.ID)
+}
+
+func (r *changesetResolver) Campaign(ctx context.Context) (*campaignResolver, error) {
+	campaign, err := r.store.GetCampaign(ctx, r.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+	return &campaignResolver{store: r.store, Campaign: campaign}, nil
+}
+
+func (r *changesetResolver) Repository(ctx context.Context) (*RepositoryResolver, error) {
+	return repositoryByIDInt32(ctx, r.RepoID)
+}
+
+func (r *changesetResolver) ExternalID() string {
+	return r.Changeset.ExternalID
+}
+
+func (r *changesetResolver) Title() string {
+	return r.Changeset.Title
+}
+
+func (r *changesetResolver) ExternalURL(ctx context.Context) (*externallink.Resolver, error) {
+	if r.Changeset.URL == "" {
+		return nil, nil
+	}
+	repo, err := db.Repos.Get(ctx, r.RepoID)
+	if err != nil {
+		return nil, err
+	}
+	return externallink.NewResolver(r.Changeset.URL, repo.ExternalRepo.ServiceType), nil
+}
+
+func (r *changesetResolver) State() *string {
+	return nonEmptyStringOrNil(string(r.Changeset.State))
+}
+
+func (r *changesetResolver) ReviewState() *string {
+	return nonEmptyStringOrNil(string(r.Changeset.ReviewState))
+}
+
+func (r *changesetResolver) CheckState() *string {
+	return nonEmptyStringOrNil(string(r.Changeset.CheckState))
+}
+
+func (r *changesetResolver) SyncedAt() *DateTime {
+	if r.Changeset.SyncedAt.IsZero() {
+		return nil
+	}
+	return &DateTime{Time: r.Changeset.SyncedAt}
+}
+
+func (r *changesetResolver) CreatedAt() DateTime {
+	return DateTime{Time: r.Changeset.CreatedAt}
+}
+
+func (r *changesetResolver) UpdatedAt() DateTime {
+	return DateTime{Time: r.Changeset.UpdatedAt}
+}
+
+func nonEmptyStringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type changesetCountsResolver struct {
+	total, open, merged, closed                           int32
+	openApproved, openChangesRequested, openPending       int32
+	openChecksPassed, openChecksFailed, openChecksPending int32
+}
+
+func (r *changesetCountsResolver) Total() int32                { return r.total }
+func (r *changesetCountsResolver) Open() int32                 { return r.open }
+func (r *changesetCountsResolver) Merged() int32               { return r.merged }
+func (r *changesetCountsResolver) Closed() int32               { return r.closed }
+func (r *changesetCountsResolver) OpenApproved() int32         { return r.openApproved }
+func (r *changesetCountsResolver) OpenChangesRequested() int32 { return r.openChangesRequested }
+func (r *changesetCountsResolver) OpenPending() int32          { return r.openPending }
+func (r *changesetCountsResolver) OpenChecksPassed() int32     { return r.openChecksPassed }
+func (r *changesetCountsResolver) OpenChecksFailed() int32     { return r.openChecksFailed }
+func (r *changesetCountsResolver) OpenChecksPending() int32    { return r.openChecksPending }
+
+// countChangesets counts the given changesets by their current state.
+// Changesets that were never synced only count towards the total.
+func countChangesets(cs []*types.Changeset) *changesetCountsResolver {
+	counts := &changesetCountsResolver{total: int32(len(cs))}
+	for _, c := range cs {
+		switch c.State {
+		case types.ChangesetStateMerged:
+			counts.merged++
+		case types.ChangesetStateClosed:
+			counts.closed++
+		case types.ChangesetStateOpen:
+			counts.open++
+
+			switch c.ReviewState {
+			case types.ChangesetReviewStateApproved:
+				counts.openApproved++
+			case types.ChangesetReviewStateChangesRequested:
+				counts.openChangesRequested++
+			case types.ChangesetReviewStatePending:
+				counts.openPending++
+			}
+
+			switch c.CheckState {
+			case types.ChangesetCheckStatePassed:
+				counts.openChecksPassed++
+			case types.ChangesetCheckStateFailed:
+				counts.openChecksFailed++
+			case types.ChangesetCheckStatePending:
+				counts.openChecksPending++
+			}
+		}
+	}
+	return counts
+}
+
+type changesetCountsAtTimeResolver struct {
+	date                        time.Time
+	total, open, merged, closed int32
+}
+
+func (r *changesetCountsAtTimeResolver) Date() DateTime { return DateTime{Time: r.date} }
+func (r *changesetCountsAtTimeResolver) Total() int32   { return r.total }
+func (r *changesetCountsAtTimeResolver) Open() int32    { return r.open }
+func (r *changesetCountsAtTimeResolver) Merged() int32  { return r.merged }
+func (r *changesetCountsAtTimeResolver) Closed() int32  { return r.closed }
+
+// countChangesetsOverTime counts the given changesets by their state on the
+// code host at daily intervals from the given start time up to and including
+// the given end time. The state at a point in time is derived from the times
+// at which each changeset was created, closed and merged on its code host.
+func countChangesetsOverTime(cs []*types.Changeset, from, to time.Time) []*changesetCountsAtTimeResolver {
+	var dates []time.Time
+	for t := from; t.Before(to); t = t.AddDate(0, 0, 1) {
+		dates = append(dates, t)
+	}
+	dates = append(dates, to)
+
+	counts := make([]*changesetCountsAtTimeResolver, 0, len(dates))
+	for _, t := range dates {
+		count := &changesetCountsAtTimeResolver{date: t}
+		for _, c := range cs {
+			if c.ExternalCreatedAt.IsZero() || c.ExternalCreatedAt.After(t) {
+				continue
+			}
+
+			count.total++
+			switch {
+			case !c.ExternalMergedAt.IsZero() && !c.ExternalMergedAt.After(t):
+				count.merged++
+			case !c.ExternalClosedAt.IsZero() && !c.ExternalClosedAt.After(t):
+				count.closed++
+			default:
+				count.open++
+			}
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
@@ -0,0 +1,70 @@
+package graphqlbackend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestCountChangesets(t *testing.T) {
+	cs := []*types.Changeset{
+		{State: types.ChangesetStateOpen, ReviewState: types.ChangesetReviewStateApproved, CheckState: types.ChangesetCheckStatePassed},
+		{State: types.ChangesetStateOpen, ReviewState: types.ChangesetReviewStateChangesRequested, CheckState: types.ChangesetCheckStateFailed},
+		{State: types.ChangesetStateOpen, ReviewState: types.ChangesetReviewStatePending},
+		{State: types.ChangesetStateMerged, ReviewState: types.ChangesetReviewStateApproved, CheckState: types.ChangesetCheckStatePassed},
+		{State: types.ChangesetStateClosed, ReviewState: types.ChangesetReviewStatePending, CheckState: types.ChangesetCheckStatePending},
+		{}, // not synced yet
+	}
+
+	have := countChangesets(cs)
+	want := &changesetCountsResolver{
+		total:                6,
+		open:                 3,
+		merged:               1,
+		closed:               1,
+		openApproved:         1,
+		openChangesRequested: 1,
+		openPending:          1,
+		openChecksPassed:     1,
+		openChecksFailed:     1,
+	}
+	if *have != *want {
+		t.Errorf("got counts %+v, want %+v", *have, *want)
+	}
+}
+
+func TestCountChangesetsOverTime(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2019, 9, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	cs := []*types.Changeset{
+		{ExternalCreatedAt: day(1)},
+		{ExternalCreatedAt: day(1), ExternalClosedAt: day(3), ExternalMergedAt: day(3)},
+		{ExternalCreatedAt: day(2), ExternalClosedAt: day(4)},
+		{ExternalCreatedAt: day(5)},
+		{}, // not synced yet
+	}
+
+	type counts struct {
+		Date                        time.Time
+		Total, Open, Merged, Closed int32
+	}
+	var have []counts
+	for _, c := range countChangesetsOverTime(cs, day(1), day(4).Add(time.Hour)) {
+		have = append(have, counts{c.date, c.total, c.open, c.merged, c.closed})
+	}
+
+	want := []counts{
+		{day(1), 2, 2, 0, 0},
+		{day(2), 3, 3, 0, 0},
+		{day(3), 3, 2, 1, 0},
+		{day(4), 3, 1, 1, 1},
+		{day(4).Add(time.Hour), 3, 1, 1, 1},
+	}
+	if diff := cmp.Diff(have, want); diff != "" {
+		t.Errorf("unexpected counts:\n%s", diff)
+	}
+}
@@ -59,6 +59,10 @@ func (r *codemodResultResolver) ToCodemodResult() (*codemodResultResolver, bool)
 	return r, true
 }
 
+func (r *codemodResultResolver) ToDiscussionThread() (*discussionThreadResolver, bool) {
+	return nil, false
+}
+
 func (r *codemodResultResolver) searchResultURIs() (string, string) {
 	return string(r.commit.repo.repo.Name), r.path
 }
@@ -1,12 +1,14 @@
 package graphqlbackend
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
@@ -436,46 +438,55 @@ func (r *discussionThreadTargetRepoResolver) Selection() *discussionThreadTarget
 func (r *discussionThreadTargetRepoResolver) RelativePath(ctx context.Context, args *struct {
 	Rev string
 }) (*string, error) {
+	path, _, err := r.relativePath(ctx, args.Rev)
+	return path, err
+}
+
+// relativePath returns the path of the thread's file at rev and, if the
+// thread was created on a specific revision or branch, the diff hunks of the
+// file between that revision and rev.
+func (r *discussionThreadTargetRepoResolver) relativePath(ctx context.Context, rev string) (*string, []*diffHunk, error) {
 	if r.t.Path == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 	repo, err := repositoryByIDInt32(ctx, r.t.RepoID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if r.t.Revision == nil && r.t.Branch == nil {
 		// The thread wasn't created on a specific revision or branch, so we
 		// cannot walk the history. Instead, we must assume its location and
 		// check in the relative revision.
-		commit, err := repo.Commit(ctx, &repositoryCommitArgs{Rev: args.Rev})
+		commit, err := repo.Commit(ctx, &repositoryCommitArgs{Rev: rev})
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		_, err = commit.File(ctx, &struct{ Path string }{Path: *r.t.Path})
 		if err != nil {
 			// File does not exist in this revision.
-			return nil, nil
+			return nil, nil, nil
 		}
-		return r.t.Path, nil // File exists at that path.
+		return r.t.Path, nil, nil // File exists at that path.
 	}
 
-	var rev string
+	var base string
 	if r.t.Revision != nil {
-		rev = *r.t.Revision
+		base = *r.t.Revision
 	} else if r.t.Branch != nil {
-		rev = *r.t.Branch
+		base = *r.t.Branch
 	}
 	comparison, err := repo.Comparison(ctx, &RepositoryComparisonInput{
-		Base: &rev,
-		Head: &args.Rev,
+		Base: &base,
+		Head: &rev,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	currentPath := *r.t.Path
+	var hunks []*diffHunk
 	fileDiffs, err := comparison.FileDiffs(&struct{ First *int32 }{}).Nodes(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, fileDiff := range fileDiffs {
 		oldPath := fileDiff.OldPath()
@@ -488,82 +499,284 @@ func (r *discussionThreadTargetRepoResolver) RelativePath(ctx context.Context, a
 			// oldPath was removed
 			if currentPath == *oldPath {
 				// The file we are tracking was removed!
-				return nil, nil
+				return nil, nil, nil
 			}
 		} else if oldPath != nil && newPath != nil {
-			// oldPath was renamed to newPath
+			// oldPath was renamed to newPath (or modified, if they are equal)
 			if currentPath == *oldPath {
-				// The file we are tracking was renamed.
+				// The file we are tracking was renamed or modified.
 				currentPath = *newPath
+				hunks = fileDiff.Hunks()
 			}
 		}
 	}
-	return &currentPath, nil
+	return &currentPath, hunks, nil
 }
 
 type discussionSelectionRangeResolver struct {
 	startLine, startCharacter, endLine, endCharacter int32
+	confidence                                       float64
+	outdated                                         bool
 }
 
 func (r *discussionSelectionRangeResolver) StartLine() int32      { return r.startLine }
 func (r *discussionSelectionRangeResolver) StartCharacter() int32 { return r.startCharacter }
 func (r *discussionSelectionRangeResolver) EndLine() int32        { return r.endLine }
 func (r *discussionSelectionRangeResolver) EndCharacter() int32   { return r.endCharacter }
+func (r *discussionSelectionRangeResolver) Confidence() float64   { return r.confidence }
+func (r *discussionSelectionRangeResolver) Outdated() bool        { return r.outdated }
+
+const (
+	// discussionSelectionMinConfidence is the confidence below which a
+	// selection is considered to no longer exist in a file.
+	discussionSelectionMinConfidence = 0.5
+
+	// discussionSelectionSearchLines is how many lines around the position
+	// given by the diff hunks are searched for a selection whose lines were
+	// changed.
+	discussionSelectionSearchLines = 50
+
+	// discussionSelectionContextWeight is the weight of each line before and
+	// after a selection relative to a selected line when matching it.
+	discussionSelectionContextWeight = 0.5
+)
 
-func discussionSelectionRelativeTo(oldSel *types.DiscussionThreadTargetRepo, newContent string) *discussionSelectionRangeResolver {
-	mustFindLines := 4
-
-	search := func(searchForLines string) *discussionSelectionRangeResolver {
-		if len(strings.Split(searchForLines, "\n")) < mustFindLines {
-			// We do not have enough search lines to find a good match.
-			return nil
-		}
-		matches := strings.Count(newContent, searchForLines)
-		switch {
-		case matches > 1:
-			// The lines we are searching for produced too many matches.
-			return nil
-		case matches == 1:
-			// We found a perfect match.
-			idx := strings.Index(newContent, searchForLines)
-			startLine := int32(len(strings.Split(newContent[:idx], "\n")))
-			return &discussionSelectionRangeResolver{
-				startCharacter: *oldSel.StartCharacter,
-				endCharacter:   *oldSel.EndCharacter,
-				startLine:      startLine,
-				endLine:        startLine + int32(len(*oldSel.Lines)),
-			}
-		default:
-			return nil
+// discussionSelectionRelativeTo returns where the selection oldSel is in
+// newContent, the content of the file at another revision.
+//
+// If hunks is non-nil, they are the diff hunks of the file between the
+// revision of oldSel and the other revision. If none of them touch the
+// selected lines, the selection is moved by the lines added and removed above
+// it. Otherwise the stored lines (and lines before and after them) are
+// fuzzy-matched against the lines near the position given by the hunks. If
+// hunks is nil the whole file is searched.
+//
+// It returns nil if the selection is not found with at least
+// discussionSelectionMinConfidence.
+func discussionSelectionRelativeTo(oldSel *types.DiscussionThreadTargetRepo, hunks []*diffHunk, newContent string) *discussionSelectionRangeResolver {
+	selLines := *oldSel.Lines
+	newSel := func(startLine int32, confidence float64, outdated bool) *discussionSelectionRangeResolver {
+		return &discussionSelectionRangeResolver{
+			startLine:      startLine,
+			startCharacter: *oldSel.StartCharacter,
+			endLine:        startLine + int32(len(selLines)),
+			endCharacter:   *oldSel.EndCharacter,
+			confidence:     confidence,
+			outdated:       outdated,
 		}
 	}
 
-	// Start removing lines until we find a result (or fail to find one).
-	allLines := *oldSel.LinesBefore
-	allLines = append(allLines, *oldSel.Lines...)
-	allLines = append(allLines, *oldSel.LinesAfter...)
-	removeLines := 0
-	for {
-		if removeLines > len(allLines) {
-			return nil
+	expected := *oldSel.StartLine
+	if hunks != nil {
+		var unchanged bool
+		expected, unchanged = discussionMapLines(hunks, *oldSel.StartLine, int32(len(selLines)))
+		if unchanged {
+			return newSel(expected, 1, false)
 		}
-		// Try removing N lines from the top.
-		if r := search(strings.Join(allLines[removeLines:], "\n")); r != nil {
-			offset := int32(len(*oldSel.LinesBefore) - 1 - removeLines)
-			r.startLine += offset
-			r.endLine += offset
-			return r
+	}
+
+	newLines := strings.Split(newContent, "\n")
+	var before, after []string
+	if oldSel.LinesBefore != nil {
+		before = *oldSel.LinesBefore
+	}
+	if oldSel.LinesAfter != nil {
+		after = *oldSel.LinesAfter
+	}
+
+	// Longer lines are weighted more so that matching short and common lines
+	// (such as "}") counts for little.
+	lineWeight := func(line string) float64 {
+		if n := utf8.RuneCountInString(strings.TrimSpace(line)); n > 1 {
+			return float64(n)
 		}
+		return 1
+	}
+	var totalWeight float64
+	for _, line := range selLines {
+		totalWeight += lineWeight(line)
+	}
+	for _, line := range before {
+		totalWeight += discussionSelectionContextWeight * lineWeight(line)
+	}
+	for _, line := range after {
+		totalWeight += discussionSelectionContextWeight * lineWeight(line)
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+
+	// score returns how well the lines starting at start in newLines match
+	// the wanted lines.
+	sim := newLineSimilarity(newLines)
+	score := func(start int, wantLines []string, weight float64) float64 {
+		var s float64
+		for i, want := range wantLines {
+			s += weight * lineWeight(want) * sim.similarity(want, start+i)
+		}
+		return s
+	}
 
-		// Try removing N lines from the bottom.
-		if r := search(strings.Join(allLines[:len(allLines)-removeLines], "\n")); r != nil {
-			offset := int32(len(*oldSel.LinesAfter) - 1 - removeLines)
-			r.startLine += offset
-			r.endLine += offset
-			return r
+	first, last := 0, len(newLines)-len(selLines)
+	if last < 0 {
+		last = 0
+	}
+	if hunks != nil {
+		if lo := int(expected) - discussionSelectionSearchLines; lo > first {
+			first = lo
+		}
+		if hi := int(expected) + discussionSelectionSearchLines; hi < last {
+			last = hi
+		}
+	}
+	best, bestScore := -1, 0.0
+	for start := first; start <= last; start++ {
+		s := score(start-len(before), before, discussionSelectionContextWeight) +
+			score(start, selLines, 1) +
+			score(start+len(selLines), after, discussionSelectionContextWeight)
+		if s > bestScore || (s == bestScore && best != -1 && absInt(start-int(expected)) < absInt(best-int(expected))) {
+			best, bestScore = start, s
 		}
-		removeLines++
 	}
+	confidence := bestScore / totalWeight
+	if best == -1 || confidence < discussionSelectionMinConfidence {
+		return nil
+	}
+
+	outdated := false
+	for i, want := range selLines {
+		if best+i >= len(newLines) || newLines[best+i] != want {
+			outdated = true
+			break
+		}
+	}
+	return newSel(int32(best), confidence, outdated)
+}
+
+// discussionMapLines returns the line in the new file that the n lines
+// starting at line (zero-based) in the old file begin at according to hunks,
+// and whether those lines are unchanged and still adjacent.
+func discussionMapLines(hunks []*diffHunk, line, n int32) (newLine int32, unchanged bool) {
+	newLine, unchanged = discussionMapLine(hunks, line)
+	for i := int32(1); i < n && unchanged; i++ {
+		l, ok := discussionMapLine(hunks, line+i)
+		unchanged = ok && l == newLine+i
+	}
+	return newLine, unchanged
+}
+
+// discussionMapLine returns the line in the new file that line (zero-based)
+// in the old file corresponds to according to hunks, and whether it is
+// unchanged. A removed line corresponds to the line that follows it in the new
+// file.
+func discussionMapLine(hunks []*diffHunk, line int32) (newLine int32, unchanged bool) {
+	var delta int32
+	for _, h := range hunks {
+		// Hunk start lines are one-based, except that an empty range starts
+		// at the line before it.
+		origStart, newStart := h.hunk.OrigStartLine-1, h.hunk.NewStartLine-1
+		if h.hunk.OrigLines == 0 {
+			origStart++
+		}
+		if h.hunk.NewLines == 0 {
+			newStart++
+		}
+
+		if line < origStart {
+			break
+		}
+		if line >= origStart+h.hunk.OrigLines {
+			delta += h.hunk.NewLines - h.hunk.OrigLines
+			continue
+		}
+
+		// The line is in this hunk.
+		o, n := origStart, newStart
+		for _, l := range bytes.Split(h.hunk.Body, []byte("\n")) {
+			if len(l) == 0 {
+				continue
+			}
+			switch l[0] {
+			case ' ':
+				if o == line {
+					return n, true
+				}
+				o++
+				n++
+			case '-':
+				if o == line {
+					return n, false
+				}
+				o++
+			case '+':
+				n++
+			}
+		}
+		return n, false
+	}
+	return line + delta, true
+}
+
+// lineSimilarity compares lines to the lines of a file, caching the bigrams of
+// the file's lines.
+type lineSimilarity struct {
+	lines   []string
+	bigrams []map[string]int
+}
+
+func newLineSimilarity(lines []string) *lineSimilarity {
+	return &lineSimilarity{lines: lines, bigrams: make([]map[string]int, len(lines))}
+}
+
+// similarity returns the similarity of want to line i of the file, from 0
+// (nothing in common or i is out of range) to 1 (equal ignoring leading and
+// trailing whitespace). It is the Dice coefficient of the lines' character
+// bigrams.
+func (s *lineSimilarity) similarity(want string, i int) float64 {
+	if i < 0 || i >= len(s.lines) {
+		return 0
+	}
+	want, have := strings.TrimSpace(want), strings.TrimSpace(s.lines[i])
+	if want == have {
+		return 1
+	}
+	if s.bigrams[i] == nil {
+		s.bigrams[i] = bigrams(have)
+	}
+	haveBigrams := s.bigrams[i]
+	wantBigrams := bigrams(want)
+	var total, common int
+	for b, n := range wantBigrams {
+		total += n
+		if m := haveBigrams[b]; m < n {
+			common += m
+		} else {
+			common += n
+		}
+	}
+	for _, n := range haveBigrams {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(common) / float64(total)
+}
+
+func bigrams(s string) map[string]int {
+	r := []rune(s)
+	m := make(map[string]int, len(r))
+	for i := 0; i+1 < len(r); i++ {
+		m[string(r[i:i+2])]++
+	}
+	return m
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
 }
 
 func (r *discussionThreadTargetRepoResolver) RelativeSelection(ctx context.Context, args *struct {
@@ -572,7 +785,7 @@ func (r *discussionThreadTargetRepoResolver) RelativeSelection(ctx context.Conte
 	if !r.t.HasSelection() {
 		return nil, nil
 	}
-	path, err := r.RelativePath(ctx, args)
+	path, hunks, err := r.relativePath(ctx, args.Rev)
 	if err != nil {
 		return nil, err
 	}
@@ -592,6 +805,7 @@ func (r *discussionThreadTargetRepoResolver) RelativeSelection(ctx context.Conte
 		startCharacter: *r.t.StartCharacter,
 		endLine:        *r.t.EndLine,
 		endCharacter:   *r.t.EndCharacter,
+		confidence:     1,
 	}
 	if r.t.Revision != nil && *r.t.Revision == string(commit.OID()) {
 		return oldSel, nil // nothing to do (requested relative revision is identical to the stored revision)
@@ -605,6 +819,9 @@ func (r *discussionThreadTargetRepoResolver) RelativeSelection(ctx context.Conte
 			return oldSel, nil // nothing to do (requested relative revision is identical to the stored branch revision)
 		}
 	}
+	if (r.t.Revision != nil || r.t.Branch != nil) && len(hunks) == 0 {
+		return oldSel, nil // nothing to do (the file is unchanged)
+	}
 	file, err := commit.File(ctx, &struct{ Path string }{Path: *path})
 	if err != nil {
 		return nil, err
@@ -613,7 +830,7 @@ func (r *discussionThreadTargetRepoResolver) RelativeSelection(ctx context.Conte
 	if err != nil {
 		return nil, err
 	}
-	return discussionSelectionRelativeTo(r.t, newContent), nil
+	return discussionSelectionRelativeTo(r.t, hunks, newContent), nil
 }
 
 type discussionThreadTargetResolver struct {
@@ -2,10 +2,13 @@ package graphqlbackend
 
 import (
 	"context"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/graph-gophers/graphql-go/gqltesting"
+	"github.com/sourcegraph/go-diff/diff"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
@@ -53,82 +56,111 @@ func TestDiscussionSelectionRelativeTo(t *testing.T) {
 	i32 := func(i int32) *int32 {
 		return &i
 	}
+	digits := &types.DiscussionThreadTargetRepo{
+		StartLine: i32(3), StartCharacter: i32(0), EndLine: i32(4), EndCharacter: i32(1),
+		LinesBefore: &[]string{"0", "1", "2"},
+		Lines:       &[]string{"3"},
+		LinesAfter:  &[]string{"4", "5", "6"},
+	}
+	const goCode = "package main\n\nfunc hello() {\n\tfmt.Println(\"hello\")\n}\n\nfunc goodbye() {\n\tfmt.Println(\"goodbye\")\n}\n"
+	goodbye := &types.DiscussionThreadTargetRepo{
+		StartLine: i32(6), StartCharacter: i32(0), EndLine: i32(9), EndCharacter: i32(0),
+		LinesBefore: &[]string{"\tfmt.Println(\"hello\")", "}", ""},
+		Lines:       &[]string{"func goodbye() {", "\tfmt.Println(\"goodbye\")", "}"},
+		LinesAfter:  &[]string{},
+	}
+	hunk := func(origStart, origLines, newStart, newLines int32, body string) *diffHunk {
+		return &diffHunk{hunk: &diff.Hunk{
+			OrigStartLine: origStart, OrigLines: origLines,
+			NewStartLine: newStart, NewLines: newLines,
+			Body: []byte(body),
+		}}
+	}
 	tests := []struct {
 		name         string
 		oldSelection *types.DiscussionThreadTargetRepo
+		hunks        []*diffHunk
 		newContent   string
 		want         *discussionSelectionRangeResolver
 	}{
 		{
-			name: "added_content_before",
-			oldSelection: &types.DiscussionThreadTargetRepo{
-				StartLine: i32(3), StartCharacter: i32(0), EndLine: i32(4), EndCharacter: i32(1),
-				LinesBefore: &[]string{"0", "1", "2"},
-				Lines:       &[]string{"3"},
-				LinesAfter:  &[]string{"4", "5", "6"},
-			},
-			newContent: "a\nb\nc\n0\n1\n2\n3\n4\n5\n6",
-			want:       &discussionSelectionRangeResolver{startLine: 6, startCharacter: 0, endLine: 7, endCharacter: 1},
+			name:         "added_content_before",
+			oldSelection: digits,
+			newContent:   "a\nb\nc\n0\n1\n2\n3\n4\n5\n6",
+			want:         &discussionSelectionRangeResolver{startLine: 6, startCharacter: 0, endLine: 7, endCharacter: 1, confidence: 1},
 		},
 		{
-			name: "added_content_after",
-			oldSelection: &types.DiscussionThreadTargetRepo{
-				StartLine: i32(3), StartCharacter: i32(0), EndLine: i32(4), EndCharacter: i32(1),
-				LinesBefore: &[]string{"0", "1", "2"},
-				Lines:       &[]string{"3"},
-				LinesAfter:  &[]string{"4", "5", "6"},
-			},
-			newContent: "0\n1\n2\n3\n4\n5\n6\na\nb\nc",
-			want:       &discussionSelectionRangeResolver{startLine: 3, startCharacter: 0, endLine: 4, endCharacter: 1},
+			name:         "added_content_after",
+			oldSelection: digits,
+			newContent:   "0\n1\n2\n3\n4\n5\n6\na\nb\nc",
+			want:         &discussionSelectionRangeResolver{startLine: 3, startCharacter: 0, endLine: 4, endCharacter: 1, confidence: 1},
+		},
+		{
+			name:         "added_content_before_and_after",
+			oldSelection: digits,
+			newContent:   "a\nb\nc\n0\n1\n2\n3\n4\n5\n6\na\nb\nc",
+			want:         &discussionSelectionRangeResolver{startLine: 6, startCharacter: 0, endLine: 7, endCharacter: 1, confidence: 1},
+		},
+		{
+			name:         "removed_content_before",
+			oldSelection: digits,
+			newContent:   "3\n4\n5\n6",
+			want:         &discussionSelectionRangeResolver{startLine: 0, startCharacter: 0, endLine: 1, endCharacter: 1, confidence: 0.625},
+		},
+		{
+			name:         "removed_content_after",
+			oldSelection: digits,
+			newContent:   "0\n1\n2\n3\n",
+			want:         &discussionSelectionRangeResolver{startLine: 3, startCharacter: 0, endLine: 4, endCharacter: 1, confidence: 0.625},
+		},
+		{
+			name:         "no_match",
+			oldSelection: digits,
+			newContent:   "0\n2\n3\n1\n",
+			want:         nil,
 		},
 		{
-			name: "added_content_before_and_after",
-			oldSelection: &types.DiscussionThreadTargetRepo{
-				StartLine: i32(3), StartCharacter: i32(0), EndLine: i32(4), EndCharacter: i32(1),
-				LinesBefore: &[]string{"0", "1", "2"},
-				Lines:       &[]string{"3"},
-				LinesAfter:  &[]string{"4", "5", "6"},
+			name:         "hunk_added_lines_before",
+			oldSelection: goodbye,
+			hunks: []*diffHunk{
+				hunk(1, 5, 1, 7, " package main\n \n+import \"fmt\"\n+\n func hello() {\n \tfmt.Println(\"hello\")\n }\n"),
 			},
-			newContent: "a\nb\nc\n0\n1\n2\n3\n4\n5\n6\na\nb\nc",
-			want:       &discussionSelectionRangeResolver{startLine: 6, startCharacter: 0, endLine: 7, endCharacter: 1},
+			want: &discussionSelectionRangeResolver{startLine: 8, startCharacter: 0, endLine: 11, endCharacter: 0, confidence: 1},
 		},
 		{
-			name: "removed_content_before",
-			oldSelection: &types.DiscussionThreadTargetRepo{
-				StartLine: i32(3), StartCharacter: i32(0), EndLine: i32(4), EndCharacter: i32(1),
-				LinesBefore: &[]string{"0", "1", "2"},
-				Lines:       &[]string{"3"},
-				LinesAfter:  &[]string{"4", "5", "6"},
+			name:         "hunk_changed_selection",
+			oldSelection: goodbye,
+			hunks: []*diffHunk{
+				hunk(6, 4, 6, 4, " \n func goodbye() {\n-\tfmt.Println(\"goodbye\")\n+\tfmt.Println(\"bye\")\n }\n"),
 			},
-			newContent: "3\n4\n5\n6",
-			want:       &discussionSelectionRangeResolver{startLine: 0, startCharacter: 0, endLine: 1, endCharacter: 1},
+			newContent: strings.Replace(goCode, `"goodbye"`, `"bye"`, 1),
+			want:       &discussionSelectionRangeResolver{startLine: 6, startCharacter: 0, endLine: 9, endCharacter: 0, confidence: 0.9305, outdated: true},
 		},
 		{
-			name: "removed_content_after",
-			oldSelection: &types.DiscussionThreadTargetRepo{
-				StartLine: i32(3), StartCharacter: i32(0), EndLine: i32(4), EndCharacter: i32(1),
-				LinesBefore: &[]string{"0", "1", "2"},
-				Lines:       &[]string{"3"},
-				LinesAfter:  &[]string{"4", "5", "6"},
+			name:         "hunk_added_lines_before_and_changed_selection",
+			oldSelection: goodbye,
+			hunks: []*diffHunk{
+				hunk(4, 6, 4, 10, " \tfmt.Println(\"hello\")\n }\n \n+func main() {\n+\thello()\n+}\n+\n func goodbye() {\n-\tfmt.Println(\"goodbye\")\n+\tfmt.Println(\"goodbye!\")\n }\n"),
 			},
-			newContent: "0\n1\n2\n3\n",
-			want:       &discussionSelectionRangeResolver{startLine: 0, startCharacter: 0, endLine: 1, endCharacter: 1},
+			newContent: "package main\n\nfunc hello() {\n\tfmt.Println(\"hello\")\n}\n\nfunc main() {\n\thello()\n}\n\nfunc goodbye() {\n\tfmt.Println(\"goodbye!\")\n}\n",
+			want:       &discussionSelectionRangeResolver{startLine: 10, startCharacter: 0, endLine: 13, endCharacter: 0, confidence: 0.8333, outdated: true},
 		},
 		{
-			name: "no_match",
-			oldSelection: &types.DiscussionThreadTargetRepo{
-				StartLine: i32(3), StartCharacter: i32(0), EndLine: i32(4), EndCharacter: i32(1),
-				LinesBefore: &[]string{"0", "1", "2"},
-				Lines:       &[]string{"3"},
-				LinesAfter:  &[]string{"4", "5", "6"},
+			name:         "hunk_removed_selection",
+			oldSelection: goodbye,
+			hunks: []*diffHunk{
+				hunk(5, 5, 5, 1, " }\n-\n-func goodbye() {\n-\tfmt.Println(\"goodbye\")\n-}\n"),
 			},
-			newContent: "0\n2\n3\n1\n",
+			newContent: "package main\n\nfunc hello() {\n\tfmt.Println(\"hello\")\n}\n",
 			want:       nil,
 		},
 	}
 	for _, tst := range tests {
 		t.Run(tst.name, func(t *testing.T) {
-			got := discussionSelectionRelativeTo(tst.oldSelection, tst.newContent)
+			got := discussionSelectionRelativeTo(tst.oldSelection, tst.hunks, tst.newContent)
+			if got != nil && tst.want != nil && math.Abs(got.confidence-tst.want.confidence) < 1e-3 {
+				got.confidence = tst.want.confidence
+			}
 			if !reflect.DeepEqual(got, tst.want) {
 				t.Logf("got  %+v\n", got)
 				t.Fatalf("want %+v\n", tst.want)
@@ -137,6 +169,39 @@ func TestDiscussionSelectionRelativeTo(t *testing.T) {
 	}
 }
 
+func TestDiscussionMapLine(t *testing.T) {
+	// Old file a b c d e f g h, new file x a b c d f G h.
+	hunks := []*diffHunk{
+		{hunk: &diff.Hunk{OrigStartLine: 0, OrigLines: 0, NewStartLine: 1, NewLines: 1, Body: []byte("+x\n")}},
+		{hunk: &diff.Hunk{OrigStartLine: 5, OrigLines: 1, NewStartLine: 5, NewLines: 0, Body: []byte("-e\n")}},
+		{hunk: &diff.Hunk{OrigStartLine: 7, OrigLines: 1, NewStartLine: 7, NewLines: 1, Body: []byte("-g\n+G\n")}},
+	}
+	tests := []struct {
+		line          int32
+		wantLine      int32
+		wantUnchanged bool
+	}{
+		{line: 0, wantLine: 1, wantUnchanged: true},
+		{line: 3, wantLine: 4, wantUnchanged: true},
+		{line: 4, wantLine: 5, wantUnchanged: false},
+		{line: 5, wantLine: 5, wantUnchanged: true},
+		{line: 6, wantLine: 6, wantUnchanged: false},
+		{line: 7, wantLine: 7, wantUnchanged: true},
+	}
+	for _, test := range tests {
+		gotLine, gotUnchanged := discussionMapLine(hunks, test.line)
+		if gotLine != test.wantLine || gotUnchanged != test.wantUnchanged {
+			t.Errorf("line %d: got %d, %v, want %d, %v", test.line, gotLine, gotUnchanged, test.wantLine, test.wantUnchanged)
+		}
+	}
+	if _, unchanged := discussionMapLines(hunks, 1, 3); !unchanged {
+		t.Error("got lines 1-3 changed, want unchanged")
+	}
+	if _, unchanged := discussionMapLines(hunks, 3, 3); unchanged {
+		t.Error("got lines 3-5 unchanged, want changed")
+	}
+}
+
 func TestDiscussionsMutations_UpdateThread(t *testing.T) {
 	resetMocks()
 	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) { return &types.User{}, nil }
@@ -10,6 +10,7 @@ import (
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
@@ -30,6 +31,7 @@ func (r *schemaResolver) AddExternalService(ctx context.Context, args *struct {
 	}
 }) (*externalServiceResolver, error) {
 	// 🚨 SECURITY: Only site admins may add external services.
+	ctx = backend.WithAccessTokenScope(ctx, authz.ScopeExternalServicesManage)
 	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
 		return nil, err
 	}
@@ -68,6 +70,7 @@ func (*schemaResolver) UpdateExternalService(ctx context.Context, args *struct {
 	}
 
 	// 🚨 SECURITY: Only site admins are allowed to update the user.
+	ctx = backend.WithAccessTokenScope(ctx, authz.ScopeExternalServicesManage)
 	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
 		return nil, err
 	}
@@ -123,6 +126,7 @@ func (*schemaResolver) DeleteExternalService(ctx context.Context, args *struct {
 	ExternalService graphql.ID
 }) (*EmptyResponse, error) {
 	// 🚨 SECURITY: Only site admins can delete external services.
+	ctx = backend.WithAccessTokenScope(ctx, authz.ScopeExternalServicesManage)
 	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
 		return nil, err
 	}
@@ -155,6 +159,7 @@ func (r *schemaResolver) ExternalServices(ctx context.Context, args *struct {
 	graphqlutil.ConnectionArgs
 }) (*externalServiceConnectionResolver, error) {
 	// 🚨 SECURITY: Only site admins may read external services (they have secrets).
+	ctx = backend.WithAccessTokenScope(ctx, authz.ScopeExternalServicesManage)
 	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
 		return nil, err
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/graph-gophers/graphql-go/trace"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
@@ -91,6 +92,16 @@ func (r *NodeResolver) ToCampaign() (*campaignResolver, bool) {
 	return n, ok
 }
 
+func (r *NodeResolver) ToCampaignPlan() (*campaignPlanResolver, bool) {
+	n, ok := r.Node.(*campaignPlanResolver)
+	return n, ok
+}
+
+func (r *NodeResolver) ToChangeset() (*changesetResolver, bool) {
+	n, ok := r.Node.(*changesetResolver)
+	return n, ok
+}
+
 func (r *NodeResolver) ToDiscussionComment() (*discussionCommentResolver, bool) {
 	n, ok := r.Node.(*discussionCommentResolver)
 	return n, ok
@@ -206,6 +217,12 @@ func NodeByID(ctx context.Context, id graphql.ID) (Node, error) {
 	switch relay.UnmarshalKind(id) {
 	case "AccessToken":
 		return accessTokenByID(ctx, id)
+	case campaignIDKind:
+		return campaignByID(ctx, id)
+	case campaignPlanIDKind:
+		return campaignPlanByID(ctx, id)
+	case changesetIDKind:
+		return changesetByID(ctx, id)
 	case "DiscussionComment":
 		return discussionCommentByID(ctx, id)
 	case "DiscussionThread":
@@ -253,6 +270,11 @@ func (r *schemaResolver) Repository(ctx context.Context, args *struct {
 	// TODO(chris): Remove URI in favor of Name.
 	URI *string
 }) (*RepositoryResolver, error) {
+	// 🚨 SECURITY: Access tokens must have the "repo:read" scope to access repositories.
+	if err := authz.CheckActorScope(ctx, authz.ScopeRepoRead); err != nil {
+		return nil, err
+	}
+
 	var name api.RepoName
 	if args.URI != nil {
 		// Deprecated query by "URI"
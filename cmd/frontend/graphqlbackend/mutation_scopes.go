@@ -0,0 +1,301 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+)
+
+// mutationScopes lists the mutations that access tokens with a narrower scope than "user:all" may
+// perform, and the scope they need. All other mutations need the "user:all" scope.
+//
+// 🚨 SECURITY: The resolvers of these mutations must check the scope themselves (usually through
+// backend.WithAccessTokenScope), because a token with the listed scope may perform them.
+var mutationScopes = map[string]string{
+	"settingsMutation":      authz.ScopeSettingsWrite,
+	"configurationMutation": authz.ScopeSettingsWrite,
+	"addExternalService":    authz.ScopeExternalServicesManage,
+	"updateExternalService": authz.ScopeExternalServicesManage,
+	"deleteExternalService": authz.ScopeExternalServicesManage,
+}
+
+// CheckMutationScopes returns an *authz.InsufficientScopeError if the actor in ctx authenticated
+// with an access token whose scopes do not permit the operation operationName (or the only
+// operation, if it is empty) of the GraphQL query. Mutations need the "user:all" scope unless they
+// are listed in mutationScopes. Queries are permitted here; their resolvers check the scopes of
+// the data they access.
+//
+// 🚨 SECURITY: This denies by default: a query that can't be parsed, or a mutation that selects
+// fields through fragments, needs the "user:all" scope.
+func CheckMutationScopes(ctx context.Context, query, operationName string) error {
+	if actor.FromContext(ctx).AccessTokenScopes == nil {
+		return nil // not authenticated with an access token
+	}
+	fields, isMutation, err := mutationFields(query, operationName)
+	if err != nil {
+		return authz.CheckActorScope(ctx, authz.ScopeUserAll)
+	}
+	if !isMutation {
+		return nil
+	}
+	for _, field := range fields {
+		scope, ok := mutationScopes[field]
+		if !ok {
+			scope = authz.ScopeUserAll
+		}
+		if err := authz.CheckActorScope(ctx, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mutationFields returns the top-level fields that the operation operationName (or the only
+// operation, if it is empty) in the GraphQL query selects, and whether it is a mutation. A
+// fragment spread among the top-level fields is returned as the field "...".
+func mutationFields(query, operationName string) (fields []string, isMutation bool, err error) {
+	p := &gqlParser{src: query}
+	p.next()
+
+	found := false
+	for p.tok != "" {
+		var opType, name string
+		switch {
+		case p.tok == "{":
+			opType = "query" // shorthand query
+		case p.tok == "fragment":
+			// fragment Name on Type @directives { ... }
+			for p.tok != "{" && p.tok != "" {
+				p.next()
+			}
+			if err := p.skipBalanced(); err != nil {
+				return nil, false, err
+			}
+			continue
+		case p.tok == "query" || p.tok == "mutation" || p.tok == "subscription":
+			opType = p.tok
+			p.next()
+			if p.isName() {
+				name = p.tok
+				p.next()
+			}
+			for p.tok != "{" && p.tok != "" {
+				if p.tok == "(" {
+					if err := p.skipBalanced(); err != nil {
+						return nil, false, err
+					}
+					continue
+				}
+				p.next() // directives
+			}
+		default:
+			return nil, false, fmt.Errorf("unexpected %q", p.tok)
+		}
+
+		selected := operationName == "" || name == operationName
+		if selected && found {
+			return nil, false, fmt.Errorf("operation %q is ambiguous", operationName)
+		}
+		if !selected {
+			if err := p.skipBalanced(); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		found = true
+		isMutation = opType == "mutation"
+		if fields, err = p.topLevelFields(); err != nil {
+			return nil, false, err
+		}
+	}
+	if !found {
+		return nil, false, fmt.Errorf("operation %q not found", operationName)
+	}
+	return fields, isMutation, nil
+}
+
+// gqlParser is a minimal parser of GraphQL documents that finds only the structure
+// mutationFields needs.
+type gqlParser struct {
+	src string
+	pos int
+	tok string // the current token, or "" at the end of the source
+	err error
+}
+
+// topLevelFields parses the selection set at the current token and returns the names (not the
+// aliases) of its fields.
+func (p *gqlParser) topLevelFields() ([]string, error) {
+	if p.tok != "{" {
+		return nil, fmt.Errorf("expected {, got %q", p.tok)
+	}
+	p.next()
+	var fields []string
+	for p.tok != "}" {
+		switch {
+		case p.tok == "":
+			return nil, p.errOrEOF()
+		case p.tok == "...":
+			fields = append(fields, "...")
+			p.next()
+			if p.tok == "on" {
+				p.next() // inline fragment with a type condition
+			}
+			if p.isName() {
+				p.next() // the fragment name or type condition
+			}
+		case p.isName():
+			field := p.tok
+			p.next()
+			if p.tok == ":" { // alias
+				p.next()
+				if !p.isName() {
+					return nil, fmt.Errorf("expected field name after alias, got %q", p.tok)
+				}
+				field = p.tok
+				p.next()
+			}
+			fields = append(fields, field)
+		default:
+			return nil, fmt.Errorf("unexpected %q in selection set", p.tok)
+		}
+
+		// Arguments, directives and the selection set of the field.
+		for p.tok != "}" && p.tok != "" && !(p.isName() && !p.afterAt()) && p.tok != "..." {
+			if p.tok == "(" || p.tok == "{" {
+				if err := p.skipBalanced(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			p.next()
+			if p.isName() && p.afterAt() {
+				p.next() // directive name
+			}
+		}
+	}
+	p.next()
+	return fields, nil
+}
+
+// skipBalanced skips the tokens from the current "{" or "(" to the matching closing token.
+func (p *gqlParser) skipBalanced() error {
+	depth := 0
+	for {
+		switch p.tok {
+		case "":
+			return p.errOrEOF()
+		case "{", "(", "[":
+			depth++
+		case "}", ")", "]":
+			depth--
+		}
+		p.next()
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func (p *gqlParser) errOrEOF() error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("unexpected end of query")
+}
+
+func (p *gqlParser) isName() bool {
+	if p.tok == "" {
+		return false
+	}
+	c := p.tok[0]
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// afterAt reports whether the current token directly follows an "@", i.e. is a directive name.
+func (p *gqlParser) afterAt() bool {
+	start := p.pos - len(p.tok)
+	return start > 0 && p.src[start-1] == '@'
+}
+
+// next advances to the next token, skipping whitespace, commas and comments. Strings are
+// returned as the token `"`.
+func (p *gqlParser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+			continue
+		case c == '#':
+			if i := strings.IndexAny(p.src[p.pos:], "\r\n"); i >= 0 {
+				p.pos += i
+			} else {
+				p.pos = len(p.src)
+			}
+			continue
+		case strings.HasPrefix(p.src[p.pos:], "..."):
+			p.pos += 3
+			p.tok = "..."
+			return
+		case c == '"':
+			p.skipString()
+			p.tok = `"`
+			return
+		case c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'):
+			start := p.pos
+			for p.pos < len(p.src) {
+				c := p.src[p.pos]
+				if c == '_' || c == '-' || c == '.' || c == '+' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+					p.pos++
+					continue
+				}
+				break
+			}
+			p.tok = p.src[start:p.pos]
+			return
+		case c < utf8.RuneSelf:
+			p.pos++
+			p.tok = string(c)
+			return
+		default:
+			p.err = fmt.Errorf("unexpected character at offset %d", p.pos)
+			p.pos = len(p.src)
+		}
+	}
+	p.tok = ""
+}
+
+// skipString skips the string (or block string) starting at the current position.
+func (p *gqlParser) skipString() {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		for i := p.pos + 3; i < len(p.src); i++ {
+			if p.src[i] == '\\' && strings.HasPrefix(p.src[i:], `\"""`) {
+				i += 3
+				continue
+			}
+			if strings.HasPrefix(p.src[i:], `"""`) {
+				p.pos = i + 3
+				return
+			}
+		}
+	} else {
+		for i := p.pos + 1; i < len(p.src); i++ {
+			switch p.src[i] {
+			case '\\':
+				i++
+			case '"':
+				p.pos = i + 1
+				return
+			case '\n', '\r':
+				i = len(p.src)
+			}
+		}
+	}
+	p.err = fmt.Errorf("unterminated string")
+	p.pos = len(p.src)
+}
@@ -0,0 +1,95 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+)
+
+func TestMutationFields(t *testing.T) {
+	tests := []struct {
+		query, operationName string
+		wantFields           []string
+		wantMutation         bool
+		wantErr              bool
+	}{
+		{query: `{ currentUser { id } }`},
+		{query: `query Q($x: String) { search(query: $x) { results { limitHit } } }`},
+		{
+			query:        `mutation { createThread(input: {title: "}{", contents: """ { """}) { id } }`,
+			wantFields:   []string{"createThread"},
+			wantMutation: true,
+		},
+		{
+			query:        `mutation M { a: settingsMutation(input: {subject: "x"}) @include(if: true) { editSettings(edit: {keyPath: []}) { empty { alwaysNil } } } b: deleteUser(user: "u") { alwaysNil } }`,
+			wantFields:   []string{"settingsMutation", "deleteUser"},
+			wantMutation: true,
+		},
+		{
+			query:        "# comment }\nmutation { ...F ... on Mutation { deleteUser(user: \"u\") { alwaysNil } } }\nfragment F on Mutation { deleteUser(user: \"u\") { alwaysNil } }",
+			wantFields:   []string{"...", "..."},
+			wantMutation: true,
+		},
+		{
+			query:         `query A { currentUser { id } } mutation B { deleteUser(user: "u") { alwaysNil } }`,
+			operationName: "B",
+			wantFields:    []string{"deleteUser"},
+			wantMutation:  true,
+		},
+		{query: `query A { currentUser { id } } mutation B { deleteUser(user: "u") { alwaysNil } }`, operationName: "A"},
+		{query: `query A { currentUser { id } } mutation B { deleteUser(user: "u") { alwaysNil } }`, wantErr: true},
+		{query: `query A { currentUser { id } }`, operationName: "C", wantErr: true},
+		{query: `mutation { deleteUser(user: "u") { alwaysNil }`, wantErr: true},
+		{query: `mutation { deleteUser(user: "u`, wantErr: true},
+	}
+	for _, test := range tests {
+		fields, isMutation, err := mutationFields(test.query, test.operationName)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%q: got error %v, want error %v", test.query, err, test.wantErr)
+			continue
+		}
+		if test.wantMutation && !reflect.DeepEqual(fields, test.wantFields) {
+			t.Errorf("%q: got fields %q, want %q", test.query, fields, test.wantFields)
+		}
+		if isMutation != test.wantMutation {
+			t.Errorf("%q: got mutation %v, want %v", test.query, isMutation, test.wantMutation)
+		}
+	}
+}
+
+func TestCheckMutationScopes(t *testing.T) {
+	createThread := `mutation { createThread(input: {title: "t", contents: "c"}) { id } }`
+	editSettings := `mutation { settingsMutation(input: {subject: "s"}) { editSettings(edit: {keyPath: []}) { empty { alwaysNil } } } }`
+	search := `query { search(query: "x") { results { limitHit } } }`
+
+	tests := []struct {
+		name    string
+		scopes  []string
+		query   string
+		wantErr bool
+	}{
+		{"session cookie", nil, createThread, false},
+		{"user:all token", []string{authz.ScopeUserAll}, createThread, false},
+		{"search:read token, mutation", []string{authz.ScopeSearchRead}, createThread, true},
+		{"lsif:upload token, mutation", []string{authz.ScopeLSIFUpload}, createThread, true},
+		{"search:read token, query", []string{authz.ScopeSearchRead}, search, false},
+		{"settings:write token, allowlisted mutation", []string{authz.ScopeSettingsWrite}, editSettings, false},
+		{"search:read token, allowlisted mutation", []string{authz.ScopeSearchRead}, editSettings, true},
+		{"search:read token, invalid query", []string{authz.ScopeSearchRead}, `mutation {`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1, AccessTokenScopes: test.scopes})
+			err := CheckMutationScopes(ctx, test.query, "")
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error %v", err, test.wantErr)
+			}
+			if _, ok := err.(*authz.InsufficientScopeError); err != nil && !ok {
+				t.Errorf("got error %T, want *authz.InsufficientScopeError", err)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/google/zoekt"
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
@@ -20,7 +21,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
 )
 
-func (r *schemaResolver) Repositories(args *struct {
+func (r *schemaResolver) Repositories(ctx context.Context, args *struct {
 	graphqlutil.ConnectionArgs
 	Query           *string
 	Names           *[]string
@@ -34,6 +35,11 @@ func (r *schemaResolver) Repositories(args *struct {
 	OrderBy         string
 	Descending      bool
 }) (*repositoryConnectionResolver, error) {
+	// 🚨 SECURITY: Access tokens must have the "repo:read" scope to list repositories.
+	if err := authz.CheckActorScope(ctx, authz.ScopeRepoRead); err != nil {
+		return nil, err
+	}
+
 	// New call sites don't specify Enable and Disable. Assume if disabled
 	// isn't specified we want Enabled since all repos are enabled.
 	if !args.Disabled {
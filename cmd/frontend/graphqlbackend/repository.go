@@ -10,6 +10,7 @@ import (
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/externallink"
@@ -40,6 +41,11 @@ func NewRepositoryResolver(repo *types.Repo) *RepositoryResolver {
 var RepositoryByID = repositoryByID
 
 func repositoryByID(ctx context.Context, id graphql.ID) (*RepositoryResolver, error) {
+	// 🚨 SECURITY: Access tokens must have the "repo:read" scope to access repositories.
+	if err := authz.CheckActorScope(ctx, authz.ScopeRepoRead); err != nil {
+		return nil, err
+	}
+
 	var repoID api.RepoID
 	if err := relay.UnmarshalSpec(id, &repoID); err != nil {
 		return nil, err
@@ -225,6 +231,9 @@ func (r *RepositoryResolver) ToCommitSearchResult() (*commitSearchResultResolver
 func (r *RepositoryResolver) ToCodemodResult() (*codemodResultResolver, bool) {
 	return nil, false
 }
+func (r *RepositoryResolver) ToDiscussionThread() (*discussionThreadResolver, bool) {
+	return nil, false
+}
 
 func (r *RepositoryResolver) searchResultURIs() (string, string) {
 	return string(r.repo.Name), ""
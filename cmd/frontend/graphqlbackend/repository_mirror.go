@@ -114,6 +114,21 @@ func (r *repositoryMirrorInfoResolver) UpdatedAt(ctx context.Context) (*DateTime
 	return DateTimeOrNil(info.LastFetched), nil
 }
 
+func (r *repositoryMirrorInfoResolver) LastAccessedAt(ctx context.Context) (*DateTime, error) {
+	info, err := r.gitserverRepoInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return DateTimeOrNil(info.LastAccessed), nil
+}
+
+func (r *repositoryMirrorInfoResolver) Pinned(ctx context.Context) (bool, error) {
+	if err := r.repository.hydrate(ctx); err != nil {
+		return false, err
+	}
+	return r.repository.repo.Pinned, nil
+}
+
 func (r *repositoryMirrorInfoResolver) UpdateSchedule(ctx context.Context) (*updateScheduleResolver, error) {
 	info, err := r.repoUpdateSchedulerInfo(ctx)
 	if err != nil {
@@ -248,6 +263,40 @@ func (r *schemaResolver) UpdateMirrorRepository(ctx context.Context, args *struc
 	return &EmptyResponse{}, nil
 }
 
+func (r *schemaResolver) SetMirrorRepositoryPinned(ctx context.Context, args *struct {
+	Repository graphql.ID
+	Pinned     bool
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may decide which repositories are kept on disk.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	repo, err := repositoryByID(ctx, args.Repository)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Repos.SetPinned(ctx, repo.repo.ID, args.Pinned); err != nil {
+		return nil, err
+	}
+
+	// Apply the flag to the gitservers that have cloned the repository now.
+	// The repo-update requests of repo-updater carry it to the others, and
+	// enqueuing one makes repo-updater use the new flag for its scheduled
+	// updates of the repository.
+	if err := gitserver.DefaultClient.SetPinned(ctx, repo.repo.Name, args.Pinned); err != nil {
+		return nil, err
+	}
+	gitserverRepo, err := backend.GitRepo(ctx, repo.repo)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repoupdater.DefaultClient.EnqueueRepoUpdate(ctx, gitserverRepo); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
+
 func (r *schemaResolver) UpdateAllMirrorRepositories(ctx context.Context) (*EmptyResponse, error) {
 	// Only usable for self-hosted instances
 	if envvar.SourcegraphDotComMode() {
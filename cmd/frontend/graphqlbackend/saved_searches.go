@@ -3,6 +3,8 @@ package graphqlbackend
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
@@ -10,6 +12,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/cmd/query-runner/queryrunnerapi"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
 )
 
 type savedSearchResolver struct {
@@ -60,6 +63,11 @@ func savedSearchByID(ctx context.Context, id graphql.ID) (*savedSearchResolver,
 			SlackWebhookURL: ss.Config.SlackWebhookURL,
 		},
 	}
+	if w := ss.Config.Webhook; w != nil {
+		savedSearch.s.WebhookURL = &w.URL
+		savedSearch.s.WebhookSecret = &w.Secret
+		savedSearch.s.WebhookHeaders = w.Headers
+	}
 	return savedSearch, nil
 }
 
@@ -96,6 +104,54 @@ func (r savedSearchResolver) OrgID() *graphql.ID {
 }
 func (r savedSearchResolver) SlackWebhookURL() *string { return r.s.SlackWebhookURL }
 
+// 🚨 SECURITY: The webhook secret and headers (which may contain credentials)
+// are write-only and never returned.
+func (r savedSearchResolver) WebhookURL() *string { return r.s.WebhookURL }
+
+type savedSearchWebhookInput struct {
+	URL     string
+	Secret  *string
+	Headers *[]struct {
+		Name  string
+		Value string
+	}
+}
+
+// applyTo validates the webhook input and sets the webhook fields of s. An
+// empty URL removes the webhook.
+func (w *savedSearchWebhookInput) applyTo(s *types.SavedSearch) error {
+	s.WebhookURL, s.WebhookSecret, s.WebhookHeaders = nil, nil, nil
+	if w.URL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid webhook URL %q: scheme must be http or https", w.URL)
+	}
+
+	s.WebhookURL = &w.URL
+	s.WebhookSecret = w.Secret
+	if w.Headers != nil {
+		s.WebhookHeaders = make(map[string]string, len(*w.Headers))
+		for _, h := range *w.Headers {
+			if h.Name == "" {
+				return errors.New("invalid webhook header: empty name")
+			}
+			// 🚨 SECURITY: Don't allow headers that change where or how the
+			// query-runner sends the request.
+			if api.IsReservedWebhookHeader(h.Name) {
+				return fmt.Errorf("invalid webhook header %q: header may not be set", h.Name)
+			}
+			s.WebhookHeaders[h.Name] = h.Value
+		}
+	}
+	return nil
+}
+
 func toSavedSearchResolver(entry types.SavedSearch) *savedSearchResolver {
 	return &savedSearchResolver{entry}
 }
@@ -147,6 +203,7 @@ func (r *schemaResolver) CreateSavedSearch(ctx context.Context, args *struct {
 	NotifySlack bool
 	OrgID       *graphql.ID
 	UserID      *graphql.ID
+	Webhook     *savedSearchWebhookInput
 }) (*savedSearchResolver, error) {
 	var userID *int32
 	var orgID *int32
@@ -173,14 +230,21 @@ func (r *schemaResolver) CreateSavedSearch(ctx context.Context, args *struct {
 		return nil, errors.New("failed to create saved search: no Org ID or User ID associated with saved search")
 	}
 
-	ss, err := db.SavedSearches.Create(ctx, &types.SavedSearch{
+	newSavedSearch := &types.SavedSearch{
 		Description: args.Description,
 		Query:       args.Query,
 		Notify:      args.NotifyOwner,
 		NotifySlack: args.NotifySlack,
 		UserID:      userID,
 		OrgID:       orgID,
-	})
+	}
+	if args.Webhook != nil {
+		if err := args.Webhook.applyTo(newSavedSearch); err != nil {
+			return nil, err
+		}
+	}
+
+	ss, err := db.SavedSearches.Create(ctx, newSavedSearch)
 	if err != nil {
 		return nil, err
 	}
@@ -196,6 +260,7 @@ func (r *schemaResolver) UpdateSavedSearch(ctx context.Context, args *struct {
 	NotifySlack bool
 	OrgID       *graphql.ID
 	UserID      *graphql.ID
+	Webhook     *savedSearchWebhookInput
 }) (*savedSearchResolver, error) {
 	var userID, orgID *int32
 	// 🚨 SECURITY: Make sure the current user has permission to update a saved search for the specified user or org.
@@ -226,7 +291,7 @@ func (r *schemaResolver) UpdateSavedSearch(ctx context.Context, args *struct {
 		return nil, err
 	}
 
-	ss, err := db.SavedSearches.Update(ctx, &types.SavedSearch{
+	savedSearch := &types.SavedSearch{
 		ID:          id,
 		Description: args.Description,
 		Query:       args.Query,
@@ -234,7 +299,26 @@ func (r *schemaResolver) UpdateSavedSearch(ctx context.Context, args *struct {
 		NotifySlack: args.NotifySlack,
 		UserID:      userID,
 		OrgID:       orgID,
-	})
+	}
+	if args.Webhook != nil {
+		if err := args.Webhook.applyTo(savedSearch); err != nil {
+			return nil, err
+		}
+	} else {
+		// Keep the existing webhook, since clients which don't know about
+		// webhooks would otherwise remove it.
+		old, err := db.SavedSearches.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if w := old.Config.Webhook; w != nil {
+			savedSearch.WebhookURL = &w.URL
+			savedSearch.WebhookSecret = &w.Secret
+			savedSearch.WebhookHeaders = w.Headers
+		}
+	}
+
+	ss, err := db.SavedSearches.Update(ctx, savedSearch)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +67,7 @@ func TestCreateSavedSearch(t *testing.T) {
 		NotifySlack bool
 		OrgID       *graphql.ID
 		UserID      *graphql.ID
+		Webhook     *savedSearchWebhookInput
 	}{Description: "test query", Query: "test type:diff", NotifyOwner: true, NotifySlack: false, OrgID: nil, UserID: &userID})
 	if err != nil {
 		t.Fatal(err)
@@ -98,6 +99,9 @@ func TestUpdateSavedSearch(t *testing.T) {
 	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
 		return &types.User{SiteAdmin: true, ID: key}, nil
 	}
+	db.Mocks.SavedSearches.GetByID = func(ctx context.Context, id int32) (*api.SavedQuerySpecAndConfig, error) {
+		return &api.SavedQuerySpecAndConfig{Spec: api.SavedQueryIDSpec{Subject: api.SettingsSubject{User: &key}, Key: "1"}, Config: api.ConfigSavedQuery{Key: "1", Description: "test query", Query: "test type:diff", UserID: &key}}, nil
+	}
 	updateSavedSearchCalled := false
 
 	db.Mocks.SavedSearches.Update = func(ctx context.Context, savedSearch *types.SavedSearch) (*types.SavedSearch, error) {
@@ -113,6 +117,7 @@ func TestUpdateSavedSearch(t *testing.T) {
 		NotifySlack bool
 		OrgID       *graphql.ID
 		UserID      *graphql.ID
+		Webhook     *savedSearchWebhookInput
 	}{ID: marshalSavedSearchID(key), Description: "updated query description", Query: "test type:diff", NotifyOwner: true, NotifySlack: false, OrgID: nil, UserID: &userID})
 	if err != nil {
 		t.Fatal(err)
@@ -137,6 +142,156 @@ func TestUpdateSavedSearch(t *testing.T) {
 	}
 }
 
+func TestSavedSearchWebhooks(t *testing.T) {
+	ctx := context.Background()
+	defer resetMocks()
+
+	key := int32(1)
+	userID := marshalUserID(key)
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true, ID: key}, nil
+	}
+	var saved *types.SavedSearch
+	db.Mocks.SavedSearches.Create = func(ctx context.Context, newSavedSearch *types.SavedSearch) (*types.SavedSearch, error) {
+		saved = newSavedSearch
+		return newSavedSearch, nil
+	}
+	db.Mocks.SavedSearches.Update = func(ctx context.Context, savedSearch *types.SavedSearch) (*types.SavedSearch, error) {
+		saved = savedSearch
+		return savedSearch, nil
+	}
+	db.Mocks.SavedSearches.GetByID = func(ctx context.Context, id int32) (*api.SavedQuerySpecAndConfig, error) {
+		return &api.SavedQuerySpecAndConfig{
+			Spec: api.SavedQueryIDSpec{Subject: api.SettingsSubject{User: &key}, Key: "1"},
+			Config: api.ConfigSavedQuery{Key: "1", Query: "a", UserID: &key, Webhook: &api.SavedQueryWebhook{
+				URL:     "https://example.com/old",
+				Secret:  "oldsecret",
+				Headers: map[string]string{"X-Old": "1"},
+			}},
+		}, nil
+	}
+
+	secret := "s"
+	webhook := &savedSearchWebhookInput{
+		URL:     "https://example.com/hook",
+		Secret:  &secret,
+		Headers: &[]struct{ Name, Value string }{{Name: "Authorization", Value: "token t"}},
+	}
+	wantWebhook := func(t *testing.T, url, secret string, headers map[string]string) {
+		t.Helper()
+		if saved.WebhookURL == nil || *saved.WebhookURL != url {
+			t.Errorf("got webhook URL %v, want %q", saved.WebhookURL, url)
+		}
+		if saved.WebhookSecret == nil || *saved.WebhookSecret != secret {
+			t.Errorf("got webhook secret %v, want %q", saved.WebhookSecret, secret)
+		}
+		if !reflect.DeepEqual(saved.WebhookHeaders, headers) {
+			t.Errorf("got webhook headers %v, want %v", saved.WebhookHeaders, headers)
+		}
+	}
+
+	t.Run("create", func(t *testing.T) {
+		_, err := (&schemaResolver{}).CreateSavedSearch(ctx, &struct {
+			Description string
+			Query       string
+			NotifyOwner bool
+			NotifySlack bool
+			OrgID       *graphql.ID
+			UserID      *graphql.ID
+			Webhook     *savedSearchWebhookInput
+		}{Description: "d", Query: "a", UserID: &userID, Webhook: webhook})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantWebhook(t, "https://example.com/hook", "s", map[string]string{"Authorization": "token t"})
+	})
+
+	update := func(webhook *savedSearchWebhookInput) error {
+		_, err := (&schemaResolver{}).UpdateSavedSearch(ctx, &struct {
+			ID          graphql.ID
+			Description string
+			Query       string
+			NotifyOwner bool
+			NotifySlack bool
+			OrgID       *graphql.ID
+			UserID      *graphql.ID
+			Webhook     *savedSearchWebhookInput
+		}{ID: marshalSavedSearchID(key), Description: "d", Query: "a", UserID: &userID, Webhook: webhook})
+		return err
+	}
+
+	t.Run("update", func(t *testing.T) {
+		if err := update(webhook); err != nil {
+			t.Fatal(err)
+		}
+		wantWebhook(t, "https://example.com/hook", "s", map[string]string{"Authorization": "token t"})
+	})
+
+	t.Run("update keeps existing webhook", func(t *testing.T) {
+		if err := update(nil); err != nil {
+			t.Fatal(err)
+		}
+		wantWebhook(t, "https://example.com/old", "oldsecret", map[string]string{"X-Old": "1"})
+	})
+
+	t.Run("update removes webhook", func(t *testing.T) {
+		if err := update(&savedSearchWebhookInput{URL: ""}); err != nil {
+			t.Fatal(err)
+		}
+		if saved.WebhookURL != nil || saved.WebhookSecret != nil || saved.WebhookHeaders != nil {
+			t.Errorf("got webhook %v %v %v, want none", saved.WebhookURL, saved.WebhookSecret, saved.WebhookHeaders)
+		}
+	})
+}
+
+func TestSavedSearchWebhookInput_applyTo(t *testing.T) {
+	tests := map[string]struct {
+		input   savedSearchWebhookInput
+		wantErr string
+	}{
+		"http": {
+			input: savedSearchWebhookInput{URL: "http://example.com/hook"},
+		},
+		"https with headers": {
+			input: savedSearchWebhookInput{URL: "https://example.com/hook", Headers: &[]struct{ Name, Value string }{{Name: "X-Token", Value: "t"}}},
+		},
+		"non-http scheme": {
+			input:   savedSearchWebhookInput{URL: "file:///etc/passwd"},
+			wantErr: `invalid webhook URL "file:///etc/passwd": scheme must be http or https`,
+		},
+		"empty header name": {
+			input:   savedSearchWebhookInput{URL: "https://example.com/hook", Headers: &[]struct{ Name, Value string }{{Name: "", Value: "t"}}},
+			wantErr: "invalid webhook header: empty name",
+		},
+		"Host header": {
+			input:   savedSearchWebhookInput{URL: "https://example.com/hook", Headers: &[]struct{ Name, Value string }{{Name: "host", Value: "internal"}}},
+			wantErr: `invalid webhook header "host": header may not be set`,
+		},
+		"hop-by-hop header": {
+			input:   savedSearchWebhookInput{URL: "https://example.com/hook", Headers: &[]struct{ Name, Value string }{{Name: "Transfer-Encoding", Value: "chunked"}}},
+			wantErr: `invalid webhook header "Transfer-Encoding": header may not be set`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var s types.SavedSearch
+			err := test.input.applyTo(&s)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s.WebhookURL == nil || *s.WebhookURL != test.input.URL {
+					t.Errorf("got webhook URL %v, want %q", s.WebhookURL, test.input.URL)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
 func TestDeleteSavedSearch(t *testing.T) {
 	ctx := context.Background()
 	defer resetMocks()
@@ -85,6 +85,16 @@ type Mutation {
         # The mirror repository to update.
         repository: ID!
     ): EmptyResponse!
+    # Sets whether the mirror repository is pinned. A pinned repository is never removed from gitserver to free
+    # up disk space. The gitservers that clone the repository later keep it pinned too.
+    #
+    # Only site admins may perform this mutation.
+    setMirrorRepositoryPinned(
+        # The mirror repository to pin or unpin.
+        repository: ID!
+        # Whether the repository is pinned.
+        pinned: Boolean!
+    ): EmptyResponse!
     # DEPRECATED: All repositories are scheduled for updates periodically. This
     # mutation will be removed in 3.6.
     #
@@ -161,9 +171,28 @@ type Mutation {
     # - "user:all": Full control of all resources accessible to the user account.
     # - "site-admin:sudo": Ability to perform any action as any other user. (Only site admins may create tokens
     #   with this scope.)
+    # - "search:read": Ability to run searches.
+    # - "repo:read": Read-only access to repositories (files, commits, and code intelligence).
+    # - "settings:write": Ability to change the settings of the user, their organizations, and (for site admins)
+    #   the site.
+    # - "lsif:upload": Ability to upload LSIF data.
+    # - "external-services:manage": Ability to add, update, and delete external services. (Only useful for site
+    #   admins.)
+    #
+    # Tokens without the "user:all" scope may only perform the operations allowed by their other scopes. If
+    # expiresAt is given, the token may not be used after that date.
     #
     # Only the user or site admins may perform this mutation.
-    createAccessToken(user: ID!, scopes: [String!]!, note: String!): CreateAccessTokenResult!
+    createAccessToken(user: ID!, scopes: [String!]!, note: String!, expiresAt: DateTime): CreateAccessTokenResult!
+    # Replaces the access token with a new access token that has the same scopes and note. The result is the
+    # new access token value, which the caller is responsible for storing.
+    #
+    # The old access token remains valid for gracePeriodSeconds (default: 1 day), or until it expires if that
+    # is sooner, so that API clients can switch to the new access token. If expiresAt is not given, the new
+    # access token is valid for as long as the old one was (or never expires, if the old one never expired).
+    #
+    # Only the user or site admins may perform this mutation.
+    rotateAccessToken(byID: ID!, gracePeriodSeconds: Int, expiresAt: DateTime): CreateAccessTokenResult!
     # Deletes and immediately revokes the specified access token, specified by either its ID or by the token
     # itself.
     #
@@ -263,7 +292,14 @@ type Mutation {
         date: String
     ): GitCommit
     # Logs a user event.
-    logUserEvent(event: UserEvent!, userCookieID: String!): EmptyResponse
+    logUserEvent(
+        event: UserEvent!
+        userCookieID: String!
+        # Details of the event (such as the type of a search query), recorded in the site's event log.
+        argument: JSONValue
+        # The name of the repository that the event happened in, if any.
+        repository: String
+    ): EmptyResponse
     # Sends a test notification for the saved search. Be careful: this will send a notifcation (email and other
     # types of notifications, if configured) to all subscribers of the saved search, which could be bothersome.
     #
@@ -328,6 +364,8 @@ type Mutation {
         notifySlack: Boolean!
         orgID: ID
         userID: ID
+        # A generic webhook that is notified about new results of the saved search.
+        webhook: SavedSearchWebhookInput
     ): SavedSearch!
     # Updates a saved search
     updateSavedSearch(
@@ -338,9 +376,62 @@ type Mutation {
         notifySlack: Boolean!
         orgID: ID
         userID: ID
+        # A generic webhook that is notified about new results of the saved search.
+        # If null, the existing webhook is left unchanged. To remove the webhook, use an empty URL.
+        webhook: SavedSearchWebhookInput
     ): SavedSearch!
     # Deletes a saved search
     deleteSavedSearch(id: ID!): EmptyResponse
+    # Adds existing changesets (such as GitHub or Bitbucket Server pull requests)
+    # on code hosts to a campaign, and starts syncing their state.
+    #
+    # Only site admins may perform this mutation.
+    createChangesets(campaign: ID!, changesets: [CreateChangesetInput!]!): [Changeset!]!
+    # Creates a campaign plan from a codemod search query (i.e., a query with a
+    # replace: field). The diffs of the codemod in all repositories matched by
+    # the query are computed in the background.
+    #
+    # Only site admins may perform this mutation.
+    createCampaignPlan(query: String!): CampaignPlan!
+    # Creates a campaign.
+    #
+    # Only site admins may perform this mutation.
+    createCampaign(input: CreateCampaignInput!): Campaign!
+    # Publishes the diffs of the campaign plan of a campaign by creating a commit
+    # for each changed repository, pushing it to a new branch on the code host
+    # and opening a changeset (such as a pull request) for the branch, which is
+    # added to the campaign. Publishing happens in the background: the
+    # campaign's changesets appear as they are opened. Publishing a campaign
+    # again only publishes the diffs that don't have a changeset yet.
+    #
+    # Only site admins may perform this mutation.
+    publishCampaign(campaign: ID!): Campaign!
+}
+
+# A new campaign.
+input CreateCampaignInput {
+    # The ID of the namespace (user or organization) of the campaign.
+    namespace: ID!
+
+    # The name of the campaign.
+    name: String!
+
+    # The description of the campaign as Markdown.
+    description: String!
+
+    # The ID of the campaign plan whose diffs the campaign publishes, if any.
+    plan: ID
+}
+
+# A changeset on a code host to be added to a campaign.
+input CreateChangesetInput {
+    # The repository of the changeset. It must be hosted on GitHub or Bitbucket
+    # Server.
+    repository: ID!
+
+    # The ID of the changeset on the code host (e.g., the pull request number on
+    # GitHub or the pull request ID on Bitbucket Server).
+    externalID: String!
 }
 
 # A collection of threads.
@@ -363,11 +454,34 @@ type Campaign implements Node {
     # The URL to this campaign.
     url: String!
 
+    # The campaign plan whose diffs this campaign publishes, if any.
+    plan: CampaignPlan
+
     # The date and time when the campaign was created.
     createdAt: DateTime!
 
     # The date and time when the campaign was updated.
     updatedAt: DateTime!
+
+    # The changesets in this campaign.
+    changesets(
+        # Returns the first n changesets from the list.
+        first: Int
+    ): ChangesetConnection!
+
+    # The current number of changesets in this campaign, by state.
+    changesetCounts: ChangesetCounts!
+
+    # The number of changesets in this campaign by state at daily intervals in
+    # the given time range, including its end (e.g., for a burndown chart). The
+    # time range may span at most 732 days.
+    changesetCountsOverTime(
+        # The start of the time range. Defaults to the creation of the campaign
+        # (or to 732 days before the end of the time range, if that is later).
+        from: DateTime
+        # The end of the time range. Defaults to now.
+        to: DateTime
+    ): [ChangesetCountsAtTime!]!
 }
 
 # A list of campaigns.
@@ -382,6 +496,221 @@ type CampaignConnection {
     pageInfo: PageInfo!
 }
 
+# A plan for a campaign, consisting of the diffs that a codemod search query
+# produces in each of the repositories it matches.
+type CampaignPlan implements Node {
+    # The unique ID for the campaign plan.
+    id: ID!
+
+    # The codemod search query of the campaign plan.
+    query: String!
+
+    # The status of computing the diffs of the campaign plan.
+    status: CampaignPlanStatus!
+
+    # The diffs of the repositories changed by the codemod.
+    repositoryDiffs(
+        # Returns the first n repository diffs from the list.
+        first: Int
+    ): CampaignPlanRepositoryDiffConnection!
+
+    # The statistics of the diffs of all repositories.
+    diffStat: DiffStat!
+
+    # The date and time when the campaign plan was created.
+    createdAt: DateTime!
+
+    # The date and time when the campaign plan was updated.
+    updatedAt: DateTime!
+}
+
+# The state of a campaign plan.
+enum CampaignPlanState {
+    # The diffs of some repositories are still being computed.
+    PROCESSING
+    # The diffs of all repositories were computed.
+    COMPLETED
+    # The diffs of all repositories were computed, but some failed.
+    ERRORED
+}
+
+# The status of computing the diffs of a campaign plan.
+type CampaignPlanStatus {
+    # The state of the campaign plan.
+    state: CampaignPlanState!
+
+    # The number of repositories whose diffs were computed.
+    completedCount: Int!
+
+    # The number of repositories whose diffs are still being computed.
+    pendingCount: Int!
+
+    # The errors that occurred when computing diffs.
+    errors: [String!]!
+}
+
+# A list of repository diffs of a campaign plan.
+type CampaignPlanRepositoryDiffConnection {
+    # A list of repository diffs.
+    nodes: [CampaignPlanRepositoryDiff!]!
+
+    # The total number of repository diffs in the connection.
+    totalCount: Int!
+
+    # Pagination information.
+    pageInfo: PageInfo!
+}
+
+# The diff that a campaign plan's codemod produces in a repository.
+type CampaignPlanRepositoryDiff {
+    # The repository changed by the diff.
+    repository: Repository!
+
+    # The commit that the diff is based on.
+    baseCommit: GitCommit!
+
+    # The diff in unified format.
+    rawDiff: String!
+
+    # The statistics of the diff.
+    diffStat: DiffStat!
+
+    # The Git ref on the code host that the commit created from the diff was
+    # pushed to when the campaign was published, or null if it wasn't published
+    # yet.
+    publishedRef: String
+}
+
+# A change to a repository on a code host (such as a GitHub pull request) that
+# is tracked by a campaign.
+type Changeset implements Node {
+    # The unique ID for the changeset.
+    id: ID!
+
+    # The campaign that tracks this changeset.
+    campaign: Campaign!
+
+    # The repository changed by this changeset.
+    repository: Repository!
+
+    # The ID of the changeset on the code host.
+    externalID: String!
+
+    # The title of the changeset on the code host. It is empty until the
+    # changeset is synced.
+    title: String!
+
+    # The URL of the changeset on the code host, or null if the changeset was
+    # not synced yet.
+    externalURL: ExternalLink
+
+    # The state of the changeset, or null if the changeset was not synced yet.
+    state: ChangesetState
+
+    # The review state of the changeset, or null if the changeset was not synced
+    # yet.
+    reviewState: ChangesetReviewState
+
+    # The combined state of the CI checks of the latest commit of the changeset,
+    # or null if the commit has no checks.
+    checkState: ChangesetCheckState
+
+    # The date and time when the changeset was last synced from the code host,
+    # or null if it was never synced.
+    syncedAt: DateTime
+
+    # The date and time when the changeset was added to the campaign.
+    createdAt: DateTime!
+
+    # The date and time when the changeset was updated.
+    updatedAt: DateTime!
+}
+
+# The state of a changeset on its code host.
+enum ChangesetState {
+    OPEN
+    CLOSED
+    MERGED
+}
+
+# The review state of a changeset on its code host.
+enum ChangesetReviewState {
+    APPROVED
+    CHANGES_REQUESTED
+    PENDING
+}
+
+# The combined state of the CI checks of a changeset.
+enum ChangesetCheckState {
+    PENDING
+    PASSED
+    FAILED
+}
+
+# A list of changesets.
+type ChangesetConnection {
+    # A list of changesets.
+    nodes: [Changeset!]!
+
+    # The total number of changesets in the connection.
+    totalCount: Int!
+
+    # Pagination information.
+    pageInfo: PageInfo!
+}
+
+# The number of changesets of a campaign, by state.
+type ChangesetCounts {
+    # The total number of changesets.
+    total: Int!
+
+    # The number of open changesets.
+    open: Int!
+
+    # The number of merged changesets.
+    merged: Int!
+
+    # The number of closed (and not merged) changesets.
+    closed: Int!
+
+    # The number of open changesets that were approved.
+    openApproved: Int!
+
+    # The number of open changesets with requested changes.
+    openChangesRequested: Int!
+
+    # The number of open changesets pending review.
+    openPending: Int!
+
+    # The number of open changesets whose checks passed.
+    openChecksPassed: Int!
+
+    # The number of open changesets whose checks failed.
+    openChecksFailed: Int!
+
+    # The number of open changesets whose checks are pending.
+    openChecksPending: Int!
+}
+
+# The number of changesets of a campaign by state at a point in time. Only
+# changesets that were synced from their code host are counted.
+type ChangesetCountsAtTime {
+    # The point in time.
+    date: DateTime!
+
+    # The number of changesets that existed on their code host at that time.
+    total: Int!
+
+    # The number of changesets that were open at that time.
+    open: Int!
+
+    # The number of changesets that were merged at that time.
+    merged: Int!
+
+    # The number of changesets that were closed (and not merged) at that time.
+    closed: Int!
+}
+
 # A new external service.
 input AddExternalServiceInput {
     # The kind of the external service.
@@ -649,7 +978,7 @@ type UpdateSettingsPayload {
     empty: EmptyResponse
 }
 
-# The result for Mutation.createAccessToken.
+# The result for Mutation.createAccessToken and Mutation.rotateAccessToken.
 type CreateAccessTokenResult {
     # The ID of the newly created access token.
     id: ID!
@@ -850,6 +1179,20 @@ type Query {
         # The search query (such as "foo" or "repo:myrepo foo").
         query: String = ""
     ): Search
+    # Finds candidate references to a symbol across repositories, for languages that have no precise code
+    # intelligence data. The definitions of the symbol are looked up in the symbols index, and the references
+    # are found by a text search for the symbol's name (on word boundaries). They are ranked by how likely they
+    # are to refer to one of the definitions, so they may include references to other symbols with the same name.
+    references(
+        # The name of the symbol (an identifier, such as "NewClient").
+        symbol: String!
+        # A search query that limits the repositories and files that are searched (such as
+        # "repo:^github\.com/foo/ lang:go"). It must not contain a search pattern. All repositories are
+        # searched if empty.
+        scope: String = ""
+        # Returns the first n references.
+        first: Int
+    ): SymbolReferenceConnection!
     # All saved searches configured for the current user, merged from all configurations.
     savedSearches: [SavedSearch!]!
     # All repository groups for the current user, merged from all configurations.
@@ -915,7 +1258,7 @@ type Search {
 }
 
 # A search result.
-union SearchResult = FileMatch | CommitSearchResult | Repository | CodemodResult
+union SearchResult = FileMatch | CommitSearchResult | Repository | CodemodResult | DiscussionThread
 
 # An object representing a markdown string.
 type Markdown {
@@ -1059,6 +1402,28 @@ type SavedSearch implements Node {
     orgID: ID
     # The Slack webhook URL associated with this saved search, if any.
     slackWebhookURL: String
+    # The URL of the generic webhook that is notified about new results of this saved search, if any.
+    webhookURL: String
+}
+
+# A generic webhook that is notified about new results of a saved search. New results are POSTed
+# as JSON to the webhook URL.
+input SavedSearchWebhookInput {
+    # The URL to POST new results to. An empty URL removes the webhook.
+    url: String!
+    # The secret used to sign the payloads. If set, the hex-encoded HMAC-SHA256 of the request
+    # body is sent in the X-Sourcegraph-Signature header as "sha256=<signature>".
+    secret: String
+    # Additional HTTP headers sent with each request.
+    headers: [SavedSearchWebhookHeaderInput!]
+}
+
+# An HTTP header sent to a saved search webhook.
+input SavedSearchWebhookHeaderInput {
+    # The header name.
+    name: String!
+    # The header value.
+    value: String!
 }
 
 # A search query description.
@@ -1371,6 +1736,10 @@ type MirrorRepositoryInfo {
     cloned: Boolean!
     # When the repository was last successfully updated from the remote source repository..
     updatedAt: DateTime
+    # When the repository was last read from gitserver (to the minute).
+    lastAccessedAt: DateTime
+    # Whether the repository is pinned, which exempts it from being removed from gitserver to free up disk space.
+    pinned: Boolean!
     # The state of this repository in the update schedule.
     updateSchedule: UpdateSchedule
     # The state of this repository in the update queue.
@@ -1615,6 +1984,29 @@ type Symbol {
     fileLocal: Boolean!
 }
 
+# A list of candidate references to a symbol, ordered from most to least likely to refer to one of the
+# symbol's definitions.
+type SymbolReferenceConnection {
+    # The definitions of the symbol that were found in the scope.
+    definitions: [Symbol!]!
+    # A list of references.
+    nodes: [SymbolReference!]!
+    # The total number of references found. More references may exist if pageInfo.hasNextPage is true.
+    totalCount: Int!
+    # Pagination information.
+    pageInfo: PageInfo!
+}
+
+# A candidate reference to a symbol, found by a text search for the symbol's name.
+type SymbolReference {
+    # The location of the reference.
+    location: Location!
+    # The contents of the line containing the reference.
+    preview: String!
+    # The definition that the reference most likely refers to, or null if no definition is likely.
+    definition: Symbol
+}
+
 # A location inside a resource (in a repository at a specific commit).
 type Location {
     # The file that this location refers to.
@@ -2153,6 +2545,10 @@ type FileMatch {
     lineMatches: [LineMatch!]!
     # Whether or not the limit was hit.
     limitHit: Boolean!
+    # The revisions (such as branch names) of the repository whose trees contain this file with the same
+    # contents, when multiple revisions were searched (for example, with "rev:*refs/heads/*"). Empty when a
+    # single revision was searched.
+    branches: [String!]!
 }
 
 # A line match.
@@ -2163,6 +2559,9 @@ type LineMatch {
     lineNumber: Int!
     # Tuples of [offset, length] measured in characters (not bytes).
     offsetAndLengths: [[Int!]!]!
+    # The ranges of the matches that start on this line. Unlike offsetAndLengths, a range ends on a later line
+    # if the match spans multiple lines. Positions are measured in characters (not bytes).
+    ranges: [Range!]!
     # Whether or not the limit was hit.
     limitHit: Boolean!
 }
@@ -2327,6 +2726,8 @@ type AccessToken implements Node {
     createdAt: DateTime!
     # The date when the access token was last used to authenticate a request.
     lastUsedAt: DateTime
+    # The date after which the access token may no longer be used, or null if it never expires.
+    expiresAt: DateTime
 }
 
 # A list of access tokens.
@@ -2556,6 +2957,14 @@ type DiscussionSelectionRange {
 
     # The character (not byte) of the end line that the selection ended on (zero-based, exclusive).
     endCharacter: Int!
+
+    # How confident the placement of the selection is, from 0 to 1. It is 1 if the selected lines
+    # are unchanged and were tracked through the diff. Otherwise it is how well the selected lines
+    # (and the lines around them) match the lines at this placement.
+    confidence: Float!
+
+    # Whether the selected lines were changed since the selection was made.
+    outdated: Boolean!
 }
 
 # A selection within a file.
@@ -2628,14 +3037,15 @@ type DiscussionThreadTargetRepo {
     # Where the selection would be relative to the given Git revision specifier
     # (branch/commit/etc).
     #
-    # The implementation relies on a hueristic which is generally good enough,
-    # but under certain circumstances may not be as accurate as e.g. determining
-    # this placement by walking through the Git history.
+    # If the thread was created on a specific revision or branch, the selection
+    # is moved by the lines added and removed above it in the diff to the given
+    # revision. If the selected lines were changed (or the thread has no
+    # revision or branch), the selection is placed where the selected lines and
+    # the lines around them best match, and is marked outdated if they changed.
     #
-    # If determining the relative placement is not possible (file was renamed
-    # or removed, the selection no longer exists in the file, or the hueristic
-    # failed) null is returned and it should be assumed the selection does not
-    # exist in this revision.
+    # If determining the relative placement is not possible (file was removed,
+    # or the selection no longer exists in the file) null is returned and it
+    # should be assumed the selection does not exist in this revision.
     relativeSelection(rev: String!): DiscussionSelectionRange
 }
 
@@ -2902,6 +3312,26 @@ type Site implements SettingsSubject {
         # Months of history.
         months: Int
     ): SiteUsageStatistics!
+    # The counts of the usage events logged in the given time range, broken down by event name, repository or
+    # event argument (and optionally by period). Unlike usageStatistics, which covers only the last few months,
+    # the counts are computed from the site's event log and can cover any time range that it retains.
+    #
+    # Only site admins may perform this query.
+    usageEventCounts(
+        # The (inclusive) start of the time range.
+        from: DateTime!
+        # The (exclusive) end of the time range.
+        to: DateTime!
+        # Count only the events with this name (such as "SEARCHQUERY").
+        event: String
+        # What to break down the counts by.
+        by: UsageEventBreakdown!
+        # The key of the event arguments (such as "type") whose values to break down the counts by, if by is
+        # ARGUMENT.
+        argument: String
+        # Additionally break down the counts by the period (in UTC) that the events happened in.
+        period: UsageEventPeriod
+    ): [UsageEventCount!]!
     # Information about this site's management console.
     #
     # Only site admins may retrieve this information.
@@ -3075,6 +3505,38 @@ enum UserEvent {
     STAGEAUTOMATE
 }
 
+# What usage event counts are broken down by.
+enum UsageEventBreakdown {
+    # The event name.
+    NAME
+    # The repository that the event happened in.
+    REPOSITORY
+    # The value of a key of the event arguments.
+    ARGUMENT
+}
+
+# A period that usage event counts are broken down by.
+enum UsageEventPeriod {
+    DAY
+    WEEK
+    MONTH
+    QUARTER
+    YEAR
+}
+
+# The number of usage events with one value of what they are broken down by.
+type UsageEventCount {
+    # The start of the period of the events, if they are broken down by period.
+    periodStart: DateTime
+    # The event name, repository or argument value of the events (depending on what they are broken down by). It is
+    # null for the events without a repository or argument value.
+    value: String
+    # The number of events.
+    count: Int!
+    # The number of distinct users (including anonymous users) that caused the events.
+    userCount: Int!
+}
+
 # A period of time in which a set of users have been active.
 enum UserActivePeriod {
     # Since today at 00:00 UTC.
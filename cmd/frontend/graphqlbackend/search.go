@@ -832,8 +832,6 @@ func handleRepoSearchResult(common *searchResultsCommon, repoRev *search.Reposit
 	return nil
 }
 
-var errMultipleRevsNotSupported = errors.New("not yet supported: searching multiple revs in the same repo")
-
 var (
 	zoektAddr   = env.Get("ZOEKT_HOST", "indexed-search:80", "host:port of the zoekt instance")
 	searcherURL = env.Get("SEARCHER_URL", "k8s+http://searcher:3181", "searcher server URL")
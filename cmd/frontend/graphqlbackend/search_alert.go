@@ -284,7 +284,7 @@ outer:
 		newExpr := addQueryRegexpField(r.query, query.FieldRepo, repoParentPattern)
 		alert.proposedQueries = append(alert.proposedQueries, &searchQueryDescription{
 			description: "in repositories under " + repoParent + more,
-			query:       syntax.ExprString(newExpr),
+			query:       queryExprString(r.query, newExpr),
 		})
 	}
 	if len(alert.proposedQueries) == 0 || ctx.Err() == context.DeadlineExceeded {
@@ -302,7 +302,7 @@ outer:
 			newExpr := addQueryRegexpField(r.query, query.FieldRepo, "^"+regexp.QuoteMeta(pathToPropose)+"$")
 			alert.proposedQueries = append(alert.proposedQueries, &searchQueryDescription{
 				description: "in the repository " + strings.TrimPrefix(pathToPropose, "github.com/"),
-				query:       syntax.ExprString(newExpr),
+				query:       queryExprString(r.query, newExpr),
 			})
 		}
 	}
@@ -332,7 +332,14 @@ func (r *searchResolver) alertForMissingRepoRevs(missingRepoRevs []*search.Repos
 }
 
 func omitQueryFields(r *searchResolver, field string) string {
-	return syntax.ExprString(omitQueryExprWithField(r.query, field))
+	return queryExprString(r.query, omitQueryExprWithField(r.query, field))
+}
+
+// queryExprString returns the query string for expr, which are (modified)
+// expressions of query.
+func queryExprString(query *query.Query, expr []*syntax.Expr) string {
+	q := syntax.Query{Expr: expr, ExplicitAnd: query.Syntax.ExplicitAnd}
+	return q.String()
 }
 
 func omitQueryExprWithField(query *query.Query, field string) []*syntax.Expr {
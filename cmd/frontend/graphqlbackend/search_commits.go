@@ -72,6 +72,10 @@ func (r *commitSearchResultResolver) ToCodemodResult() (*codemodResultResolver,
 	return nil, false
 }
 
+func (r *commitSearchResultResolver) ToDiscussionThread() (*discussionThreadResolver, bool) {
+	return nil, false
+}
+
 func (r *commitSearchResultResolver) searchResultURIs() (string, string) {
 	// Diffs aren't going to be returned with other types of results
 	// and are already ordered in the desired order, so we'll just leave them in place.
@@ -461,6 +465,7 @@ func searchCommitDiffsInRepos(ctx context.Context, args *search.Args) ([]searchR
 		mu          sync.Mutex
 		unflattened [][]*commitSearchResultResolver
 		common      = &searchResultsCommon{}
+		stream      = searchStreamFromContext(ctx)
 	)
 	for _, repoRev := range args.Repos {
 		wg.Add(1)
@@ -477,7 +482,13 @@ func searchCommitDiffsInRepos(ctx context.Context, args *search.Args) ([]searchR
 				tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.String("searchErr", searchErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)))
 			}
 			mu.Lock()
-			defer mu.Unlock()
+			defer func() {
+				// Send the matches to the stream (if any) without holding mu,
+				// so that a slow client doesn't block the other searches.
+				update := stream.update(common)
+				mu.Unlock()
+				stream.commitMatches(results, update)
+			}()
 			if fatalErr := handleRepoSearchResult(common, repoRev, repoLimitHit, repoTimedOut, searchErr); fatalErr != nil {
 				err = errors.Wrapf(searchErr, "failed to search commit diffs %s", repoRev.String())
 				cancel()
@@ -522,6 +533,7 @@ func searchCommitLogInRepos(ctx context.Context, args *search.Args) ([]searchRes
 		mu          sync.Mutex
 		unflattened [][]*commitSearchResultResolver
 		common      = &searchResultsCommon{}
+		stream      = searchStreamFromContext(ctx)
 	)
 	for _, repoRev := range args.Repos {
 		wg.Add(1)
@@ -538,7 +550,13 @@ func searchCommitLogInRepos(ctx context.Context, args *search.Args) ([]searchRes
 				tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.String("searchErr", searchErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)))
 			}
 			mu.Lock()
-			defer mu.Unlock()
+			defer func() {
+				// Send the matches to the stream (if any) without holding mu,
+				// so that a slow client doesn't block the other searches.
+				update := stream.update(common)
+				mu.Unlock()
+				stream.commitMatches(results, update)
+			}()
 			if fatalErr := handleRepoSearchResult(common, repoRev, repoLimitHit, repoTimedOut, searchErr); fatalErr != nil {
 				err = errors.Wrapf(searchErr, "failed to search commit log %s", repoRev.String())
 				cancel()
@@ -0,0 +1,83 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+)
+
+var mockSearchDiscussions func(args *search.Args) ([]searchResultResolver, *searchResultsCommon, error)
+
+// searchDiscussions searches the titles and comments of the discussion threads
+// that are not archived for the words of the query's search pattern (see
+// db.DiscussionThreadsListOptions.SearchQuery). If the query has repo: or
+// repogroup: fields, only threads on the resolved repositories are returned.
+// It returns no results if the viewer can't use discussions.
+func searchDiscussions(ctx context.Context, args *search.Args, limit int32) (res []searchResultResolver, common *searchResultsCommon, err error) {
+	if mockSearchDiscussions != nil {
+		return mockSearchDiscussions(args)
+	}
+
+	if viewerCanUseDiscussions(ctx) != nil {
+		return nil, nil, nil
+	}
+
+	var words []string
+	for _, v := range args.Query.Values(query.FieldDefault) {
+		if !v.Not() {
+			words = append(words, asString(v))
+		}
+	}
+	searchQuery := strings.Join(words, " ")
+	if strings.TrimSpace(searchQuery) == "" {
+		return nil, nil, nil
+	}
+
+	archived := false
+	opt := &db.DiscussionThreadsListOptions{
+		LimitOffset: &db.LimitOffset{Limit: int(limit)},
+		SearchQuery: &searchQuery,
+		Archived:    &archived,
+	}
+	if len(args.Query.Values(query.FieldRepo)) > 0 || len(args.Query.Values(query.FieldRepoGroup)) > 0 {
+		for _, r := range args.Repos {
+			opt.TargetRepoIDs = append(opt.TargetRepoIDs, r.Repo.ID)
+		}
+	}
+
+	threads, err := db.DiscussionThreads.List(ctx, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, t := range threads {
+		res = append(res, &discussionThreadResolver{t: t})
+	}
+	return res, &searchResultsCommon{limitHit: len(threads) >= int(limit)}, nil
+}
+
+func (d *discussionThreadResolver) ToRepository() (*RepositoryResolver, bool) { return nil, false }
+func (d *discussionThreadResolver) ToFileMatch() (*fileMatchResolver, bool)   { return nil, false }
+func (d *discussionThreadResolver) ToCommitSearchResult() (*commitSearchResultResolver, bool) {
+	return nil, false
+}
+
+func (d *discussionThreadResolver) ToCodemodResult() (*codemodResultResolver, bool) {
+	return nil, false
+}
+
+func (d *discussionThreadResolver) ToDiscussionThread() (*discussionThreadResolver, bool) {
+	return d, true
+}
+
+func (d *discussionThreadResolver) searchResultURIs() (string, string) {
+	// Discussion threads are already ordered by rank, so list them after the
+	// other results (including diffs and commits) in that order.
+	return "~~", ""
+}
+
+func (d *discussionThreadResolver) resultCount() int32 {
+	return 1
+}
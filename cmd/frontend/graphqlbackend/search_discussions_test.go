@@ -0,0 +1,95 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+func TestSearchDiscussions(t *testing.T) {
+	resetMocks()
+	mockViewerCanUseDiscussions = func() error { return nil }
+	defer func() { mockViewerCanUseDiscussions = nil }()
+
+	repos := []*search.RepositoryRevisions{
+		{Repo: &types.Repo{ID: 1, Name: "foo/one"}},
+		{Repo: &types.Repo{ID: 2, Name: "foo/two"}},
+	}
+
+	var listed *db.DiscussionThreadsListOptions
+	db.Mocks.DiscussionThreads.List = func(ctx context.Context, opt *db.DiscussionThreadsListOptions) ([]*types.DiscussionThread, error) {
+		listed = opt
+		return []*types.DiscussionThread{{ID: 2, Title: "b"}, {ID: 1, Title: "a"}}, nil
+	}
+
+	for _, test := range []struct {
+		query             string
+		wantSearchQuery   string
+		wantTargetRepoIDs []api.RepoID
+	}{
+		{"type:discussion render slow", "render slow", nil},
+		{"type:discussion repo:foo render", "render", []api.RepoID{1, 2}},
+	} {
+		q, err := query.ParseAndCheck(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, common, err := searchDiscussions(context.Background(), &search.Args{Query: q, Repos: repos}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if *listed.SearchQuery != test.wantSearchQuery {
+			t.Errorf("%q: got search query %q, want %q", test.query, *listed.SearchQuery, test.wantSearchQuery)
+		}
+		if !reflect.DeepEqual(listed.TargetRepoIDs, test.wantTargetRepoIDs) {
+			t.Errorf("%q: got target repo IDs %v, want %v", test.query, listed.TargetRepoIDs, test.wantTargetRepoIDs)
+		}
+		if listed.Archived == nil || *listed.Archived {
+			t.Errorf("%q: got archived threads included", test.query)
+		}
+
+		// Results keep the order of the threads (by rank).
+		var ids []int64
+		for _, r := range res {
+			thread, ok := r.ToDiscussionThread()
+			if !ok {
+				t.Fatalf("%q: got result %T, want a discussion thread", test.query, r)
+			}
+			ids = append(ids, thread.t.ID)
+		}
+		if want := []int64{2, 1}; !reflect.DeepEqual(ids, want) {
+			t.Errorf("%q: got threads %v, want %v", test.query, ids, want)
+		}
+		if !common.limitHit {
+			t.Errorf("%q: got limit not hit, want hit", test.query)
+		}
+	}
+
+	// Without search words, there are no results.
+	listed = nil
+	q, err := query.ParseAndCheck("type:discussion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, _, err := searchDiscussions(context.Background(), &search.Args{Query: q, Repos: repos}, 2); err != nil || len(res) != 0 || listed != nil {
+		t.Errorf("got %d results (error %v), want none", len(res), err)
+	}
+
+	// Nor if the viewer can't use discussions.
+	mockViewerCanUseDiscussions = func() error { return errors.New("disabled") }
+	q, err = query.ParseAndCheck("type:discussion render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, _, err := searchDiscussions(context.Background(), &search.Args{Query: q, Repos: repos}, 2); err != nil || len(res) != 0 || listed != nil {
+		t.Errorf("got %d results (error %v), want none", len(res), err)
+	}
+}
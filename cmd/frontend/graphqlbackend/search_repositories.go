@@ -44,6 +44,10 @@ func searchRepositories(ctx context.Context, args *search.Args, limit int32) (re
 			return nil, nil, nil
 		}
 	}
+	// Matching repository names against AND/OR/NOT expressions of patterns is not yet implemented.
+	if args.Pattern.PatternExpr != nil {
+		return nil, nil, nil
+	}
 
 	pattern, err := regexp.Compile(args.Pattern.Pattern)
 	if err != nil {
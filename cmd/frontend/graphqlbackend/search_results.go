@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/bg"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/inventory"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
@@ -29,6 +30,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/syntax"
+	searchquerytypes "github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/types"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/rcache"
@@ -438,7 +441,7 @@ loop:
 				}
 				addPoint(t)
 			})
-		case *codemodResultResolver:
+		case *codemodResultResolver, *discussionThreadResolver:
 			continue
 		default:
 			panic("SearchResults.Sparkline unexpected union type state")
@@ -450,6 +453,11 @@ loop:
 }
 
 func (r *searchResolver) Results(ctx context.Context) (*searchResultsResolver, error) {
+	// 🚨 SECURITY: Access tokens must have the "search:read" scope to search.
+	if err := authz.CheckActorScope(ctx, authz.ScopeSearchRead); err != nil {
+		return nil, err
+	}
+
 	rr, err := r.resultsWithTimeoutSuggestion(ctx)
 	if err != nil {
 		return nil, err
@@ -555,6 +563,11 @@ func init() {
 }
 
 func (r *searchResolver) Stats(ctx context.Context) (stats *searchResultsStats, err error) {
+	// 🚨 SECURITY: Access tokens must have the "search:read" scope to search.
+	if err := authz.CheckActorScope(ctx, authz.ScopeSearchRead); err != nil {
+		return nil, err
+	}
+
 	// Override user context to ensure that stats for this query are cached
 	// regardless of the user context's cancellation. For example, if
 	// stats/sparklines are slow to load on the homepage and all users navigate
@@ -641,6 +654,17 @@ type getPatternInfoOptions struct {
 
 // getPatternInfo gets the search pattern info for the query in the resolver.
 func (r *searchResolver) getPatternInfo(opts *getPatternInfoOptions) (*search.PatternInfo, error) {
+	patternType, _ := r.query.StringValue(query.FieldPatternType)
+	switch patternType {
+	case "", "regexp":
+	case "structural":
+		if opts == nil || !opts.forceFileSearch {
+			return r.getStructuralPatternInfo()
+		}
+	default:
+		return nil, fmt.Errorf("invalid patterntype:%q (valid values are: regexp, structural)", patternType)
+	}
+
 	var patternsToCombine []string
 	if opts == nil || !opts.forceFileSearch {
 		for _, v := range r.query.Values(query.FieldDefault) {
@@ -698,9 +722,74 @@ func (r *searchResolver) getPatternInfo(opts *getPatternInfoOptions) (*search.Pa
 	if len(excludePatterns) > 0 {
 		patternInfo.ExcludePattern = unionRegExps(excludePatterns)
 	}
+	if r.query.Pattern != nil && (opts == nil || !opts.forceFileSearch) {
+		patternInfo.PatternExpr = toPatternExpr(r.query.Pattern)
+		patternInfo.Pattern = unionRegExps(patternInfo.PatternExpr.Patterns(true))
+	}
 	return patternInfo, nil
 }
 
+// getStructuralPatternInfo gets the search pattern info for a
+// patterntype:structural query, whose search patterns together form a single
+// comby match template.
+func (r *searchResolver) getStructuralPatternInfo() (*search.PatternInfo, error) {
+	if r.query.Pattern != nil {
+		return nil, errors.New("AND/OR/NOT operators and negated search patterns are not supported for patterntype:structural searches")
+	}
+	var matchTemplates []string
+	for _, v := range r.query.Values(query.FieldDefault) {
+		if s := asString(v); s != "" {
+			matchTemplates = append(matchTemplates, s)
+		}
+	}
+
+	includePatterns, excludePatterns := r.query.RegexpPatterns(query.FieldFile)
+	langIncludePatterns, langExcludePatterns, err := langIncludeExcludePatterns(r.query.StringValues(query.FieldLang))
+	if err != nil {
+		return nil, err
+	}
+	includePatterns = append(includePatterns, langIncludePatterns...)
+	excludePatterns = append(excludePatterns, langExcludePatterns...)
+	filePatternsReposMustInclude, filePatternsReposMustExclude := r.query.RegexpPatterns(query.FieldRepoHasFile)
+
+	patternInfo := &search.PatternInfo{
+		IsStructuralPat:              true,
+		FileMatchLimit:               r.maxResults(),
+		Pattern:                      strings.Join(matchTemplates, " "),
+		IncludePatterns:              includePatterns,
+		FilePatternsReposMustInclude: filePatternsReposMustInclude,
+		FilePatternsReposMustExclude: filePatternsReposMustExclude,
+		PathPatternsAreRegExps:       true,
+		PathPatternsAreCaseSensitive: r.query.IsCaseSensitive(),
+	}
+	if len(excludePatterns) > 0 {
+		patternInfo.ExcludePattern = unionRegExps(excludePatterns)
+	}
+	return patternInfo, nil
+}
+
+// toPatternExpr converts the query's boolean expression of search patterns to
+// the equivalent expression of regexp patterns.
+func toPatternExpr(n *searchquerytypes.Node) *search.PatternExpr {
+	if n.Op == syntax.OperatorNone {
+		pattern := asString(n.Value)
+		if n.Value.String != nil {
+			// Treat quoted strings as literal strings to match, not regexps.
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		return &search.PatternExpr{Op: search.PatternLeaf, Not: n.Not, Pattern: pattern}
+	}
+
+	e := &search.PatternExpr{Op: search.PatternAnd, Not: n.Not}
+	if n.Op == syntax.OperatorOr {
+		e.Op = search.PatternOr
+	}
+	for _, operand := range n.Operands {
+		e.Operands = append(e.Operands, toPatternExpr(operand))
+	}
+	return e
+}
+
 var (
 	// The default timeout to use for queries.
 	defaultTimeout = 10 * time.Second
@@ -769,6 +858,8 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		return &searchResultsResolver{alert: alert, start: start}, nil
 	}
 
+	searchStreamFromContext(ctx).start(len(repos))
+
 	p, err := r.getPatternInfo(nil)
 	if err != nil {
 		return nil, err
@@ -799,6 +890,9 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		resultTypes, _ = r.query.StringValues(query.FieldType)
 		if len(resultTypes) == 0 {
 			resultTypes = []string{"file", "path", "repo", "ref"}
+			if args.Pattern.IsStructuralPat {
+				resultTypes = []string{"file"}
+			}
 		}
 	}
 	seenResultTypes := make(map[string]struct{}, len(resultTypes))
@@ -810,6 +904,23 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		}
 	}
 	tr.LazyPrintf("resultTypes: %v", resultTypes)
+	if args.Pattern.IsStructuralPat {
+		for _, resultType := range resultTypes {
+			switch resultType {
+			case "file", "codemod":
+			default:
+				return nil, &badRequestError{fmt.Errorf("type:%s is not supported for patterntype:structural searches", resultType)}
+			}
+		}
+	}
+	if args.Pattern.PatternExpr != nil {
+		for _, resultType := range resultTypes {
+			switch resultType {
+			case "diff", "commit", "symbol", "codemod", "discussion":
+				return nil, &badRequestError{fmt.Errorf("AND/OR/NOT operators on search patterns are not yet supported for type:%s searches", resultType)}
+			}
+		}
+	}
 
 	var (
 		requiredWg sync.WaitGroup
@@ -989,6 +1100,30 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					commonMu.Unlock()
 				}
 			})
+		case "discussion":
+			wg := waitGroup(len(resultTypes) == 1)
+			wg.Add(1)
+			goroutine.Go(func() {
+				defer wg.Done()
+
+				discussionResults, discussionCommon, err := searchDiscussions(ctx, &args, r.maxResults())
+				// Timeouts are reported through searchResultsCommon so don't report an error for them
+				if err != nil && !isContextError(ctx, err) {
+					multiErrMu.Lock()
+					multiErr = multierror.Append(multiErr, errors.Wrap(err, "discussion search failed"))
+					multiErrMu.Unlock()
+				}
+				if discussionResults != nil {
+					resultsMu.Lock()
+					results = append(results, discussionResults...)
+					resultsMu.Unlock()
+				}
+				if discussionCommon != nil {
+					commonMu.Lock()
+					common.update(*discussionCommon)
+					commonMu.Unlock()
+				}
+			})
 		case "codemod":
 			wg := waitGroup(true)
 			wg.Add(1)
@@ -1072,6 +1207,7 @@ func isContextError(ctx context.Context, err error) bool {
 //   - *fileMatchResolver          // text match
 //   - *commitSearchResultResolver // diff or commit match
 //   - *codemodResultResolver      // code modification
+//   - *discussionThreadResolver   // discussion thread match
 //
 // Note: Any new result types added here also need to be handled properly in search_results.go:301 (sparklines)
 type searchResultResolver interface {
@@ -1079,6 +1215,7 @@ type searchResultResolver interface {
 	ToFileMatch() (*fileMatchResolver, bool)
 	ToCommitSearchResult() (*commitSearchResultResolver, bool)
 	ToCodemodResult() (*codemodResultResolver, bool)
+	ToDiscussionThread() (*discussionThreadResolver, bool)
 
 	// SearchResultURIs returns the repo name and file uri respectiveley
 	searchResultURIs() (string, string)
@@ -1099,7 +1236,7 @@ func compareSearchResults(a, b searchResultResolver) bool {
 }
 
 func sortResults(r []searchResultResolver) {
-	sort.Slice(r, func(i, j int) bool { return compareSearchResults(r[i], r[j]) })
+	sort.SliceStable(r, func(i, j int) bool { return compareSearchResults(r[i], r[j]) })
 }
 
 // regexpPatternMatchingExprsInOrder returns a regexp that matches lines that contain
@@ -437,6 +437,22 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 			PathPatternsAreRegExps: true,
 			ExcludePattern:         `f|(\.graphql$|\.gql$|\.graphqls$)`,
 		},
+		"patterntype:regexp p": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+		},
+		`patterntype:structural "fmt.Println(:[args])"`: {
+			Pattern:                "fmt.Println(:[args])",
+			IsStructuralPat:        true,
+			PathPatternsAreRegExps: true,
+		},
+		"patterntype:structural foo(:[x]) bar file:f": {
+			Pattern:                "foo(:[x]) bar",
+			IsStructuralPat:        true,
+			PathPatternsAreRegExps: true,
+			IncludePatterns:        []string{"f"},
+		},
 	}
 	for queryStr, want := range tests {
 		t.Run(queryStr, func(t *testing.T) {
@@ -458,6 +474,27 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 	}
 }
 
+func TestSearchResolver_getPatternInfo_errors(t *testing.T) {
+	tests := map[string]string{
+		"patterntype:foo p":               `invalid patterntype:"foo" (valid values are: regexp, structural)`,
+		"patterntype:structural a -b":     "AND/OR/NOT operators and negated search patterns are not supported for patterntype:structural searches",
+		"patterntype:structural (a OR b)": "AND/OR/NOT operators and negated search patterns are not supported for patterntype:structural searches",
+	}
+	for queryStr, want := range tests {
+		t.Run(queryStr, func(t *testing.T) {
+			query, err := query.ParseAndCheck(queryStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sr := searchResolver{query: query}
+			_, err = sr.getPatternInfo(nil)
+			if err == nil || err.Error() != want {
+				t.Errorf("got error %v, want %q", err, want)
+			}
+		})
+	}
+}
+
 func TestSearchResolver_DynamicFilters(t *testing.T) {
 	repo := &types.Repo{Name: "testRepo"}
 
@@ -726,6 +763,20 @@ func TestCompareSearchResults(t *testing.T) {
 			JPath: "a",
 		},
 		aIsLess: true,
+	}, {
+		// Discussion threads are listed after other results
+		a: &discussionThreadResolver{t: &types.DiscussionThread{ID: 1}},
+		b: &fileMatchResolver{
+			repo: &types.Repo{Name: api.RepoName("~z")},
+
+			JPath: "a",
+		},
+		aIsLess: false,
+	}, {
+		// Discussion threads keep their order
+		a:       &discussionThreadResolver{t: &types.DiscussionThread{ID: 2}},
+		b:       &discussionThreadResolver{t: &types.DiscussionThread{ID: 1}},
+		aIsLess: false,
 	}}
 
 	for i, test := range tests {
@@ -0,0 +1,521 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// This file contains the streaming variant of search. Instead of waiting for
+// all results (as searchResolver.Results does), StreamSearch emits results as
+// each repository is searched.
+
+// The names of the events sent by StreamSearch.
+const (
+	SearchStreamEventFileMatches   = "filematches"   // data is []*SearchStreamFileMatch
+	SearchStreamEventCommitMatches = "commitmatches" // data is []*SearchStreamCommitMatch
+	SearchStreamEventRepoMatches   = "repomatches"   // data is []*SearchStreamRepoMatch
+	SearchStreamEventProgress      = "progress"      // data is *SearchStreamProgress
+	SearchStreamEventAlert         = "alert"         // data is *SearchStreamAlert
+	SearchStreamEventError         = "error"         // data is *SearchStreamError
+	SearchStreamEventDone          = "done"          // data is *SearchStreamSummary
+)
+
+// SearchStreamFileMatch is a file with matches of the search query.
+type SearchStreamFileMatch struct {
+	Repository  string                    `json:"repository"`
+	Commit      string                    `json:"commit"`
+	Path        string                    `json:"path"`
+	URL         string                    `json:"url"`
+	LineMatches []*SearchStreamLineMatch  `json:"lineMatches"`
+	Symbols     []*SearchStreamSymbolInfo `json:"symbols,omitempty"`
+	LimitHit    bool                      `json:"limitHit"`
+}
+
+// SearchStreamLineMatch is a line in a file with matches of the search
+// query.
+type SearchStreamLineMatch struct {
+	Preview          string     `json:"preview"`
+	LineNumber       int32      `json:"lineNumber"`
+	OffsetAndLengths [][2]int32 `json:"offsetAndLengths"`
+}
+
+// SearchStreamSymbolInfo is a symbol that matches the search query.
+type SearchStreamSymbolInfo struct {
+	Name          string `json:"name"`
+	ContainerName string `json:"containerName,omitempty"`
+	Kind          string `json:"kind"`
+	URL           string `json:"url"`
+}
+
+// SearchStreamCommitMatch is a commit (or commit diff) that matches the
+// search query.
+type SearchStreamCommitMatch struct {
+	Repository     string `json:"repository"`
+	OID            string `json:"oid"`
+	URL            string `json:"url"`
+	Label          string `json:"label"`
+	Detail         string `json:"detail"`
+	MessagePreview string `json:"messagePreview,omitempty"`
+	DiffPreview    string `json:"diffPreview,omitempty"`
+}
+
+// SearchStreamRepoMatch is a repository whose name matches the search query.
+type SearchStreamRepoMatch struct {
+	Repository string `json:"repository"`
+	URL        string `json:"url"`
+}
+
+// SearchStreamProgress describes how many of the repositories to search have
+// been searched so far. The repository lists are cumulative.
+type SearchStreamProgress struct {
+	RepositoriesCount           int      `json:"repositoriesCount"`
+	RepositoriesSearched        int      `json:"repositoriesSearched"`
+	IndexedRepositoriesSearched int      `json:"indexedRepositoriesSearched"`
+	Cloning                     []string `json:"cloning"`
+	Missing                     []string `json:"missing"`
+	Timedout                    []string `json:"timedout"`
+}
+
+// SearchStreamAlert is an alert about the search query (e.g., a suggestion
+// to fix the query when it has no results).
+type SearchStreamAlert struct {
+	Title           string                       `json:"title"`
+	Description     string                       `json:"description,omitempty"`
+	ProposedQueries []*SearchStreamProposedQuery `json:"proposedQueries,omitempty"`
+}
+
+// SearchStreamProposedQuery is a query proposed by a SearchStreamAlert.
+type SearchStreamProposedQuery struct {
+	Description string `json:"description,omitempty"`
+	Query       string `json:"query"`
+}
+
+// SearchStreamError is a fatal error that ended the search.
+type SearchStreamError struct {
+	Message string `json:"message"`
+}
+
+// SearchStreamSummary is the final event of a search. It mirrors the
+// searchResultsCommon of the (non-streaming) search results.
+type SearchStreamSummary struct {
+	LimitHit                    bool     `json:"limitHit"`
+	ResultCount                 int32    `json:"resultCount"`
+	Repositories                []string `json:"repositories"`
+	RepositoriesSearched        []string `json:"repositoriesSearched"`
+	IndexedRepositoriesSearched []string `json:"indexedRepositoriesSearched"`
+	Cloning                     []string `json:"cloning"`
+	Missing                     []string `json:"missing"`
+	Timedout                    []string `json:"timedout"`
+	IndexUnavailable            bool     `json:"indexUnavailable"`
+	ElapsedMilliseconds         int32    `json:"elapsedMilliseconds"`
+}
+
+// MockStreamSearch, if non-nil, is called instead of StreamSearch.
+var MockStreamSearch func(ctx context.Context, rawQuery string, send func(event string, data interface{}))
+
+// searchStreamBufferSize is the number of events other than file and commit
+// matches that may be queued (see searchStream).
+const searchStreamBufferSize = 8
+
+// StreamSearch runs the search query and calls send with each event (one of
+// the SearchStreamEvent* names and its data) as results are found. The last
+// event is always SearchStreamEventDone or SearchStreamEventError. Calls to
+// send are serialized, and StreamSearch returns once the last event is sent.
+//
+// 🚨 SECURITY: The caller MUST ensure that ctx carries the actor on whose
+// behalf the search is performed, as with the GraphQL search resolver.
+func StreamSearch(ctx context.Context, rawQuery string, send func(event string, data interface{})) {
+	if MockStreamSearch != nil {
+		MockStreamSearch(ctx, rawQuery, send)
+		return
+	}
+
+	var sr interface {
+		Results(context.Context) (*searchResultsResolver, error)
+	}
+	resultLimit := defaultMaxSearchResults
+	if q, err := query.ParseAndCheck(rawQuery); err != nil {
+		sr = &didYouMeanQuotedResolver{query: rawQuery, err: err}
+	} else {
+		r := &searchResolver{query: q, zoekt: IndexedSearch()}
+		resultLimit = int(r.maxResults())
+		sr = r
+	}
+
+	s := newSearchStream(send, resultLimit)
+	results, err := sr.Results(withSearchStream(ctx, s))
+	if err != nil {
+		s.error(err)
+		return
+	}
+	s.done(ctx, results)
+}
+
+// searchStream receives the results of a search as each repository is
+// searched and sends them as events.
+//
+// Events are queued and sent by a separate goroutine, so that the searches
+// that report results are not blocked by a client that is slow to receive
+// them. At most resultLimit file and commit matches are sent, and at most one
+// progress event is queued at a time, so the queue (which has room for
+// resultLimit+searchStreamBufferSize events) does not fill up.
+type searchStream struct {
+	send    func(event string, data interface{}) // only called by run
+	events  chan func()                          // queued calls to send
+	sentAll chan struct{}                        // closed when run returns
+
+	progressQueued int32 // whether a progress event is queued (accessed atomically)
+
+	mu          sync.Mutex
+	closed      bool                              // no more events may be queued
+	sent        map[searchResultResolver]struct{} // results that have already been queued
+	resultLimit int                               // the maximum number of results to send
+	results     int                               // the number of results queued so far
+	limitHit    bool                              // whether results were not sent because of resultLimit
+
+	reposCount int // the number of repositories to search
+
+	// snapshots contains the latest state of the searchResultsCommon of each
+	// search that reported its progress.
+	snapshots map[*searchResultsCommon]searchResultsCommon
+}
+
+func newSearchStream(send func(event string, data interface{}), resultLimit int) *searchStream {
+	s := &searchStream{
+		send:        send,
+		events:      make(chan func(), resultLimit+searchStreamBufferSize),
+		sentAll:     make(chan struct{}),
+		sent:        make(map[searchResultResolver]struct{}),
+		resultLimit: resultLimit,
+		snapshots:   make(map[*searchResultsCommon]searchResultsCommon),
+	}
+	go s.run()
+	return s
+}
+
+// run sends the queued events until the stream is closed.
+func (s *searchStream) run() {
+	defer close(s.sentAll)
+	for send := range s.events {
+		send()
+	}
+}
+
+type searchStreamContextKey struct{}
+
+func withSearchStream(ctx context.Context, s *searchStream) context.Context {
+	return context.WithValue(ctx, searchStreamContextKey{}, s)
+}
+
+// searchStreamFromContext returns the stream that results should be sent to,
+// or nil if the search is not streaming. The start, update, fileMatches and
+// commitMatches methods may be called on a nil *searchStream.
+func searchStreamFromContext(ctx context.Context) *searchStream {
+	s, _ := ctx.Value(searchStreamContextKey{}).(*searchStream)
+	return s
+}
+
+// searchStreamUpdate is a snapshot of the progress of a search, which is
+// identified by the searchResultsCommon that it accumulates its progress in.
+type searchStreamUpdate struct {
+	common   *searchResultsCommon
+	snapshot searchResultsCommon
+}
+
+// update returns a snapshot of the progress of the search whose progress is
+// accumulated in common, to be passed to fileMatches or commitMatches. The
+// caller must hold the lock that protects common, and should release it
+// before calling fileMatches or commitMatches.
+func (s *searchStream) update(common *searchResultsCommon) *searchStreamUpdate {
+	if s == nil || common == nil {
+		return nil
+	}
+	return &searchStreamUpdate{
+		common: common,
+		snapshot: searchResultsCommon{
+			searched: append([]*types.Repo(nil), common.searched...),
+			indexed:  append([]*types.Repo(nil), common.indexed...),
+			cloning:  append([]*types.Repo(nil), common.cloning...),
+			missing:  append([]*types.Repo(nil), common.missing...),
+			timedout: append([]*types.Repo(nil), common.timedout...),
+		},
+	}
+}
+
+// start records the number of repositories that will be searched.
+func (s *searchStream) start(reposCount int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reposCount = reposCount
+	s.queueProgressLocked()
+}
+
+// fileMatches sends the matches found in one or more repositories, along with
+// the updated progress of the search that found them (see update).
+func (s *searchStream) fileMatches(ctx context.Context, matches []*fileMatchResolver, u *searchStreamUpdate) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queueFileMatchesLocked(ctx, matches)
+	s.progressUpdateLocked(u)
+}
+
+// commitMatches sends the commit matches found in a repository, along with the
+// updated progress of the search that found them (see update).
+func (s *searchStream) commitMatches(matches []*commitSearchResultResolver, u *searchStreamUpdate) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queueCommitMatchesLocked(matches)
+	s.progressUpdateLocked(u)
+}
+
+// error sends the error that ended the search and waits until all events are
+// sent.
+func (s *searchStream) error(err error) {
+	s.mu.Lock()
+	s.queueLocked(func() {
+		s.send(SearchStreamEventError, &SearchStreamError{Message: err.Error()})
+	})
+	s.closeLocked()
+	s.mu.Unlock()
+	<-s.sentAll
+}
+
+// done sends the results that were not streamed while searching (such as
+// repository and symbol matches), the alert (if any), and the summary, and
+// waits until all events are sent.
+func (s *searchStream) done(ctx context.Context, results *searchResultsResolver) {
+	s.mu.Lock()
+	defer func() {
+		s.closeLocked()
+		s.mu.Unlock()
+		<-s.sentAll
+	}()
+
+	var (
+		fileMatches   []*fileMatchResolver
+		commitMatches []*commitSearchResultResolver
+		repoMatches   []*SearchStreamRepoMatch
+	)
+	for _, result := range results.Results() {
+		if fm, ok := result.ToFileMatch(); ok {
+			fileMatches = append(fileMatches, fm)
+		} else if r, ok := result.ToRepository(); ok {
+			repoMatches = append(repoMatches, &SearchStreamRepoMatch{Repository: r.Name(), URL: r.URL()})
+		} else if c, ok := result.ToCommitSearchResult(); ok {
+			commitMatches = append(commitMatches, c)
+		}
+	}
+	s.queueFileMatchesLocked(ctx, fileMatches)
+	s.queueCommitMatchesLocked(commitMatches)
+	if len(repoMatches) > 0 {
+		s.queueLocked(func() { s.send(SearchStreamEventRepoMatches, repoMatches) })
+	}
+
+	if a := results.alert; a != nil {
+		alert := &SearchStreamAlert{Title: a.title, Description: a.description}
+		for _, pq := range a.proposedQueries {
+			alert.ProposedQueries = append(alert.ProposedQueries, &SearchStreamProposedQuery{Description: pq.description, Query: pq.query})
+		}
+		s.queueLocked(func() { s.send(SearchStreamEventAlert, alert) })
+	}
+
+	common := &results.searchResultsCommon
+	summary := &SearchStreamSummary{
+		LimitHit:                    common.LimitHit() || s.limitHit,
+		ResultCount:                 results.MatchCount(),
+		Repositories:                repoNames(common.repos),
+		RepositoriesSearched:        repoNames(common.searched),
+		IndexedRepositoriesSearched: repoNames(common.indexed),
+		Cloning:                     repoNames(common.cloning),
+		Missing:                     repoNames(common.missing),
+		Timedout:                    repoNames(common.timedout),
+		IndexUnavailable:            common.indexUnavailable,
+	}
+	if !results.start.IsZero() {
+		summary.ElapsedMilliseconds = results.ElapsedMilliseconds()
+	}
+	s.queueLocked(func() { s.send(SearchStreamEventDone, summary) })
+}
+
+// queueLocked queues a call to send.
+func (s *searchStream) queueLocked(send func()) {
+	if !s.closed {
+		s.events <- send
+	}
+}
+
+// closeLocked stops run once all queued events are sent.
+func (s *searchStream) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
+
+// unsentLocked reports whether the result r should be sent, i.e. whether it
+// has not been sent yet and the result limit has not been reached, and records
+// it as sent if so.
+func (s *searchStream) unsentLocked(r searchResultResolver) bool {
+	if _, sent := s.sent[r]; sent {
+		return false
+	}
+	if s.results >= s.resultLimit {
+		s.limitHit = true
+		return false
+	}
+	s.sent[r] = struct{}{}
+	s.results++
+	return true
+}
+
+// queueFileMatchesLocked queues the file matches that have not been sent yet.
+// The events are constructed (which may be slow) when they are sent.
+func (s *searchStream) queueFileMatchesLocked(ctx context.Context, matches []*fileMatchResolver) {
+	var unsent []*fileMatchResolver
+	for _, fm := range matches {
+		if s.unsentLocked(fm) {
+			unsent = append(unsent, fm)
+		}
+	}
+	if len(unsent) == 0 {
+		return
+	}
+	s.queueLocked(func() {
+		events := make([]*SearchStreamFileMatch, len(unsent))
+		for i, fm := range unsent {
+			events[i] = toSearchStreamFileMatch(ctx, fm)
+		}
+		s.send(SearchStreamEventFileMatches, events)
+	})
+}
+
+func (s *searchStream) queueCommitMatchesLocked(matches []*commitSearchResultResolver) {
+	var events []*SearchStreamCommitMatch
+	for _, m := range matches {
+		if s.unsentLocked(m) {
+			events = append(events, toSearchStreamCommitMatch(m))
+		}
+	}
+	if len(events) > 0 {
+		s.queueLocked(func() { s.send(SearchStreamEventCommitMatches, events) })
+	}
+}
+
+func (s *searchStream) progressUpdateLocked(u *searchStreamUpdate) {
+	if u == nil {
+		return
+	}
+	s.snapshots[u.common] = u.snapshot
+	s.queueProgressLocked()
+}
+
+// queueProgressLocked queues a progress event, unless one is already queued
+// (in which case a later progress event or the summary supersedes this one).
+func (s *searchStream) queueProgressLocked() {
+	if !atomic.CompareAndSwapInt32(&s.progressQueued, 0, 1) {
+		return
+	}
+	progress := s.progressLocked()
+	s.queueLocked(func() {
+		atomic.StoreInt32(&s.progressQueued, 0)
+		s.send(SearchStreamEventProgress, progress)
+	})
+}
+
+// progressLocked combines the progress of all searches (e.g., a search for
+// file matches and a search for commit matches may run concurrently).
+func (s *searchStream) progressLocked() *SearchStreamProgress {
+	var total searchResultsCommon
+	for _, snapshot := range s.snapshots {
+		total.update(snapshot)
+	}
+	return &SearchStreamProgress{
+		RepositoriesCount:           s.reposCount,
+		RepositoriesSearched:        len(repoNames(total.searched)),
+		IndexedRepositoriesSearched: len(repoNames(total.indexed)),
+		Cloning:                     repoNames(total.cloning),
+		Missing:                     repoNames(total.missing),
+		Timedout:                    repoNames(total.timedout),
+	}
+}
+
+func toSearchStreamFileMatch(ctx context.Context, fm *fileMatchResolver) *SearchStreamFileMatch {
+	url, err := fm.File().URL(ctx)
+	if err != nil {
+		log15.Warn("Failed to get URL of streamed file match", "uri", fm.uri, "error", err)
+	}
+	e := &SearchStreamFileMatch{
+		Repository:  string(fm.repo.Name),
+		Commit:      string(fm.commitID),
+		Path:        fm.JPath,
+		URL:         url,
+		LineMatches: make([]*SearchStreamLineMatch, len(fm.JLineMatches)),
+		LimitHit:    fm.JLimitHit,
+	}
+	for i, lm := range fm.JLineMatches {
+		e.LineMatches[i] = &SearchStreamLineMatch{
+			Preview:          lm.JPreview,
+			LineNumber:       lm.JLineNumber,
+			OffsetAndLengths: lm.JOffsetAndLengths,
+		}
+	}
+	for _, sym := range fm.Symbols() {
+		url, err := sym.URL(ctx)
+		if err != nil {
+			log15.Warn("Failed to get URL of streamed symbol", "symbol", sym.Name(), "error", err)
+		}
+		info := &SearchStreamSymbolInfo{Name: sym.Name(), Kind: sym.Kind(), URL: url}
+		if containerName := sym.ContainerName(); containerName != nil {
+			info.ContainerName = *containerName
+		}
+		e.Symbols = append(e.Symbols, info)
+	}
+	return e
+}
+
+func toSearchStreamCommitMatch(r *commitSearchResultResolver) *SearchStreamCommitMatch {
+	e := &SearchStreamCommitMatch{
+		Repository: r.commit.repo.Name(),
+		OID:        string(r.commit.oid),
+		URL:        r.url,
+		Label:      r.label,
+		Detail:     r.detail,
+	}
+	if r.messagePreview != nil {
+		e.MessagePreview = r.messagePreview.value
+	}
+	if r.diffPreview != nil {
+		e.DiffPreview = r.diffPreview.value
+	}
+	return e
+}
+
+// repoNames returns the sorted, deduplicated names of repos.
+func repoNames(repos []*types.Repo) []string {
+	names := make([]string, 0, len(repos))
+	seen := make(map[string]struct{}, len(repos))
+	for _, repo := range repos {
+		if _, ok := seen[string(repo.Name)]; ok {
+			continue
+		}
+		seen[string(repo.Name)] = struct{}{}
+		names = append(names, string(repo.Name))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,94 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestSearchStream(t *testing.T) {
+	var (
+		mu              sync.Mutex
+		events          []string
+		fileMatchesSent int
+		unblock         = make(chan struct{})
+	)
+	send := func(event string, data interface{}) {
+		<-unblock // a client that is slow to receive events
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+		if fms, ok := data.([]*SearchStreamFileMatch); ok {
+			fileMatchesSent += len(fms)
+		}
+	}
+
+	repo := &types.Repo{ID: 1, Name: "r"}
+	fileMatch := func(path string) *fileMatchResolver {
+		return &fileMatchResolver{JPath: path, repo: repo, commitID: "c"}
+	}
+	fms := []*fileMatchResolver{fileMatch("a"), fileMatch("b"), fileMatch("c")}
+
+	s := newSearchStream(send, 2)
+	s.start(2)
+
+	// Reporting results must not block while the client is slow, even when
+	// there are more results than the limit.
+	common := &searchResultsCommon{searched: []*types.Repo{repo}}
+	reported := make(chan struct{})
+	go func() {
+		s.fileMatches(context.Background(), fms[:1], s.update(common))
+		s.fileMatches(context.Background(), fms[:1], s.update(common)) // already sent
+		s.fileMatches(context.Background(), fms[1:], s.update(common))
+		close(reported)
+	}()
+	select {
+	case <-reported:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reporting results blocked on a slow client")
+	}
+
+	close(unblock)
+	s.done(context.Background(), &searchResultsResolver{
+		results:             []searchResultResolver{fms[0], fileMatch("d")},
+		searchResultsCommon: *common,
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fileMatchesSent != 2 {
+		t.Errorf("got %d file matches sent, want 2 (the result limit)", fileMatchesSent)
+	}
+	if len(events) == 0 || events[len(events)-1] != SearchStreamEventDone {
+		t.Fatalf("got events %v, want the last to be %q", events, SearchStreamEventDone)
+	}
+	if events[0] != SearchStreamEventProgress {
+		t.Errorf("got first event %q, want %q", events[0], SearchStreamEventProgress)
+	}
+}
+
+func TestSearchStream_limitHit(t *testing.T) {
+	var summary *SearchStreamSummary
+	s := newSearchStream(func(event string, data interface{}) {
+		if event == SearchStreamEventDone {
+			summary = data.(*SearchStreamSummary)
+		}
+	}, 1)
+
+	repo := &types.Repo{ID: 1, Name: "r"}
+	s.fileMatches(context.Background(), []*fileMatchResolver{
+		{JPath: "a", repo: repo, commitID: "c"},
+		{JPath: "b", repo: repo, commitID: "c"},
+	}, nil)
+	s.done(context.Background(), &searchResultsResolver{})
+
+	if summary == nil {
+		t.Fatal("got no summary")
+	}
+	if !summary.LimitHit {
+		t.Error("got LimitHit false, want true (results were not sent because of the limit)")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/neelance/parallel"
 	"github.com/pkg/errors"
 	lsp "github.com/sourcegraph/go-lsp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
@@ -33,6 +34,11 @@ func (a *searchSuggestionsArgs) applyDefaultsAndConstraints() {
 }
 
 func (r *searchResolver) Suggestions(ctx context.Context, args *searchSuggestionsArgs) ([]*searchSuggestionResolver, error) {
+	// 🚨 SECURITY: Access tokens must have the "search:read" scope to search.
+	if err := authz.CheckActorScope(ctx, authz.ScopeSearchRead); err != nil {
+		return nil, err
+	}
+
 	args.applyDefaultsAndConstraints()
 
 	if len(r.query.Syntax.Expr) == 0 {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/pkg/jsonc"
 )
@@ -67,8 +68,13 @@ func (r *settingsCascade) Subjects(ctx context.Context) ([]*settingsSubject, err
 	return subjects, nil
 }
 
-// viewerFinalSettings returns the final (merged) settings for the viewer.
+// viewerFinalSettings returns the final (merged) settings for the viewer. They are for internal
+// use (such as resolving search repository groups) and must not be returned to the client.
 func viewerFinalSettings(ctx context.Context) (*configurationResolver, error) {
+	// 🚨 SECURITY: The settings cascade only includes the viewer's own settings subjects, so it's
+	// safe to bypass the permission checks on them. This lets access tokens with narrower scopes
+	// than "user:all" (such as "search:read") use operations that depend on the viewer's settings.
+	ctx = backend.WithAuthzBypass(ctx)
 	cascade, err := (&schemaResolver{}).ViewerSettings(ctx)
 	if err != nil {
 		return nil, err
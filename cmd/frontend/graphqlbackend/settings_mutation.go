@@ -9,6 +9,8 @@ import (
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/sourcegraph/jsonx"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/pkg/actor"
 	"github.com/sourcegraph/sourcegraph/pkg/conf"
@@ -43,6 +45,9 @@ type settingsMutation struct {
 func (r *schemaResolver) SettingsMutation(ctx context.Context, args *struct {
 	Input *settingsMutationGroupInput
 }) (*settingsMutation, error) {
+	// 🚨 SECURITY: Access tokens need the "settings:write" scope (or "user:all") to edit settings.
+	ctx = backend.WithAccessTokenScope(ctx, authz.ScopeSettingsWrite)
+
 	subject, err := settingsSubjectByID(ctx, args.Input.Subject)
 	if err != nil {
 		return nil, err
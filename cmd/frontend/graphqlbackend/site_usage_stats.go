@@ -3,8 +3,11 @@ package graphqlbackend
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/usagestats"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
@@ -38,6 +41,57 @@ func (r *siteResolver) UsageStatistics(ctx context.Context, args *struct {
 	return &siteUsageStatisticsResolver{activity}, nil
 }
 
+func (r *siteResolver) UsageEventCounts(ctx context.Context, args *struct {
+	From     DateTime
+	To       DateTime
+	Event    *string
+	By       string
+	Argument *string
+	Period   *string
+}) ([]*usageEventCountResolver, error) {
+	// 🚨 SECURITY: Only site admins may see the usage event counts, which
+	// reveal the names of repositories and details of the events.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	opt := db.EventLogsBreakdownOptions{
+		From: args.From.Time,
+		To:   args.To.Time,
+		By:   strings.ToLower(args.By),
+	}
+	if args.Event != nil {
+		opt.Name = *args.Event
+	}
+	if args.Argument != nil {
+		opt.ArgumentKey = *args.Argument
+	}
+	if args.Period != nil {
+		opt.Period = strings.ToLower(*args.Period)
+	}
+	counts, err := db.EventLogs.Breakdown(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*usageEventCountResolver, len(counts))
+	for i, c := range counts {
+		resolvers[i] = &usageEventCountResolver{c}
+	}
+	return resolvers, nil
+}
+
+type usageEventCountResolver struct {
+	count *db.EventLogsCount
+}
+
+func (r *usageEventCountResolver) PeriodStart() *DateTime { return DateTimeOrNil(r.count.PeriodStart) }
+
+func (r *usageEventCountResolver) Value() *string { return r.count.Value }
+
+func (r *usageEventCountResolver) Count() int32 { return r.count.Count }
+
+func (r *usageEventCountResolver) UserCount() int32 { return r.count.UserCount }
+
 type siteUsageStatisticsResolver struct {
 	siteUsageStatistics *types.SiteUsageStatistics
 }
@@ -0,0 +1,77 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go/gqltesting"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestSite_UsageEventCounts(t *testing.T) {
+	resetMocks()
+	db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: true}, nil
+	}
+	q1 := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.Mocks.EventLogs.Breakdown = func(ctx context.Context, opt db.EventLogsBreakdownOptions) ([]*db.EventLogsCount, error) {
+		want := db.EventLogsBreakdownOptions{
+			From:        q1,
+			To:          time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+			Name:        "SEARCHQUERY",
+			By:          db.EventLogsByArgument,
+			ArgumentKey: "type",
+			Period:      "quarter",
+		}
+		if !reflect.DeepEqual(opt, want) {
+			t.Errorf("got options %+v, want %+v", opt, want)
+		}
+		regexp := "regexp"
+		return []*db.EventLogsCount{
+			{PeriodStart: &q1, Value: &regexp, Count: 3, UserCount: 2},
+			{PeriodStart: &q1, Count: 1, UserCount: 1},
+		}, nil
+	}
+	defer resetMocks()
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: GraphQLSchema,
+			Query: `
+				{
+					site {
+						usageEventCounts(from: "2018-01-01T00:00:00Z", to: "2019-01-01T00:00:00Z", event: "SEARCHQUERY", by: ARGUMENT, argument: "type", period: QUARTER) {
+							periodStart
+							value
+							count
+							userCount
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"site": {
+						"usageEventCounts": [
+							{
+								"periodStart": "2018-01-01T00:00:00Z",
+								"value": "regexp",
+								"count": 3,
+								"userCount": 2
+							},
+							{
+								"periodStart": "2018-01-01T00:00:00Z",
+								"value": null,
+								"count": 1,
+								"userCount": 1
+							}
+						]
+					}
+				}
+			`,
+		},
+	})
+}
@@ -0,0 +1,311 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/go-langserver/pkg/lsp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/pkg/trace"
+)
+
+const (
+	defaultSymbolReferencesLimit = 100
+	maxSymbolReferencesLimit     = 1000
+
+	// maxSymbolDefinitions is the maximum number of definitions of a symbol
+	// that are used to rank its references.
+	maxSymbolDefinitions = 50
+)
+
+// symbolNamePattern matches the symbol names accepted by the references
+// query. Names are restricted to identifiers so that they can be embedded in
+// a search query without escaping.
+var symbolNamePattern = regexp.MustCompile(`^[\pL\pN_$]+$`)
+
+var mockSymbolReferencesSearch func(ctx context.Context, q *query.Query, resultType string) (*searchResultsResolver, error)
+
+type symbolReferencesArgs struct {
+	graphqlutil.ConnectionArgs
+	Symbol string
+	Scope  string
+}
+
+// References finds candidate references to a symbol across repositories. The
+// symbol's definitions are looked up in the symbols index, and the references
+// are the results of a word-boundary text search for the symbol's name,
+// ranked by how likely they are to refer to one of the definitions. It is a
+// fallback for languages without precise code intelligence data.
+func (r *schemaResolver) References(ctx context.Context, args *symbolReferencesArgs) (_ *symbolReferenceConnectionResolver, err error) {
+	if !symbolNamePattern.MatchString(args.Symbol) {
+		return nil, fmt.Errorf("invalid symbol name %q (must be an identifier)", args.Symbol)
+	}
+	if err := checkReferencesScope(args.Scope); err != nil {
+		return nil, err
+	}
+	limit := defaultSymbolReferencesLimit
+	if args.First != nil {
+		limit = int(*args.First)
+	}
+	if limit < 0 || limit > maxSymbolReferencesLimit {
+		return nil, fmt.Errorf("first must be between 0 and %d", maxSymbolReferencesLimit)
+	}
+
+	tr, ctx := trace.New(ctx, "graphql.References", args.Symbol)
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	definitionsQuery, err := query.ParseAndCheck(fmt.Sprintf("%s case:yes count:%d ^%s$", args.Scope, maxSymbolDefinitions, regexp.QuoteMeta(args.Symbol)))
+	if err != nil {
+		return nil, err
+	}
+	referencesQuery, err := query.ParseAndCheck(fmt.Sprintf("%s case:yes count:%d %s", args.Scope, limit+1, wordBoundaryPattern(args.Symbol)))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg                            sync.WaitGroup
+		definitions, references       *searchResultsResolver
+		definitionsErr, referencesErr error
+	)
+	wg.Add(2)
+	goroutine.Go(func() {
+		defer wg.Done()
+		definitions, definitionsErr = symbolReferencesSearch(ctx, definitionsQuery, "symbol")
+	})
+	goroutine.Go(func() {
+		defer wg.Done()
+		references, referencesErr = symbolReferencesSearch(ctx, referencesQuery, "file")
+	})
+	wg.Wait()
+	if definitionsErr != nil {
+		return nil, errors.Wrap(definitionsErr, "searching for definitions")
+	}
+	if referencesErr != nil {
+		return nil, errors.Wrap(referencesErr, "searching for references")
+	}
+
+	var defs []*searchSymbolResult
+	for _, fm := range searchFileMatches(definitions.results) {
+		for _, s := range fm.symbols {
+			if s.symbol.Name == args.Symbol {
+				defs = append(defs, s)
+			}
+		}
+	}
+	return &symbolReferenceConnectionResolver{
+		definitions: defs,
+		references:  rankSymbolReferences(defs, searchFileMatches(references.results)),
+		limit:       limit,
+		limitHit:    references.limitHit,
+	}, nil
+}
+
+// checkReferencesScope returns an error if the scope of a references query
+// is not a valid search query consisting only of fields that limit the
+// repositories and files that are searched.
+func checkReferencesScope(scope string) error {
+	q, err := query.ParseAndCheck(scope)
+	if err != nil {
+		return errors.Wrap(err, "invalid scope")
+	}
+	for _, field := range []string{query.FieldDefault, query.FieldType, query.FieldReplace, query.FieldPatternType, query.FieldCase, query.FieldCount, query.FieldMax} {
+		if len(q.Values(field)) > 0 {
+			if field == query.FieldDefault {
+				return errors.New("invalid scope (must not contain a search pattern)")
+			}
+			return fmt.Errorf("invalid scope (must not contain a %q field)", field)
+		}
+	}
+	return nil
+}
+
+// wordBoundaryPattern returns a regular expression that matches name where
+// it is not part of a longer identifier.
+func wordBoundaryPattern(name string) string {
+	isWordChar := func(c byte) bool {
+		return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+	}
+	pattern := regexp.QuoteMeta(name)
+	if isWordChar(name[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordChar(name[len(name)-1]) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+func symbolReferencesSearch(ctx context.Context, q *query.Query, resultType string) (*searchResultsResolver, error) {
+	if mockSymbolReferencesSearch != nil {
+		return mockSymbolReferencesSearch(ctx, q, resultType)
+	}
+	sr := &searchResolver{query: q, zoekt: IndexedSearch()}
+	return sr.doResults(ctx, resultType)
+}
+
+func searchFileMatches(results []searchResultResolver) []*fileMatchResolver {
+	var fms []*fileMatchResolver
+	for _, result := range results {
+		if fm, ok := result.(*fileMatchResolver); ok {
+			fms = append(fms, fm)
+		}
+	}
+	return fms
+}
+
+// rankSymbolReferences returns a reference for each occurrence of the symbol
+// in the text search results fms, ordered from most to least likely to refer
+// to one of the definitions defs. Occurrences at the location of a definition
+// are omitted.
+//
+// A reference is scored by the definition that is closest to it: one in the
+// same file ranks highest, followed by one in the same directory, in the same
+// repository, and finally one in another repository written in the same
+// language. References with no definition in the same language rank lowest.
+func rankSymbolReferences(defs []*searchSymbolResult, fms []*fileMatchResolver) []*symbolReferenceResolver {
+	var refs []*symbolReferenceResolver
+	for _, fm := range fms {
+	lineMatches:
+		for _, lm := range fm.JLineMatches {
+			for _, def := range defs {
+				if def.commit.repo.repo.Name == fm.repo.Name && def.symbol.Path == fm.JPath && int32(def.symbol.Line-1) == lm.JLineNumber {
+					continue lineMatches
+				}
+			}
+
+			var (
+				definition *searchSymbolResult
+				score      int
+			)
+			for _, def := range defs {
+				if s := symbolReferenceScore(def, fm); definition == nil || s > score {
+					definition, score = def, s
+				}
+			}
+			if score == 0 {
+				definition = nil
+			}
+
+			for _, offsetAndLength := range lm.JOffsetAndLengths {
+				refs = append(refs, &symbolReferenceResolver{
+					fileMatch:  fm,
+					line:       lm.JLineNumber,
+					character:  offsetAndLength[0],
+					length:     offsetAndLength[1],
+					preview:    lm.JPreview,
+					definition: definition,
+					score:      score,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.fileMatch.repo.Name != b.fileMatch.repo.Name {
+			return a.fileMatch.repo.Name < b.fileMatch.repo.Name
+		}
+		if a.fileMatch.JPath != b.fileMatch.JPath {
+			return a.fileMatch.JPath < b.fileMatch.JPath
+		}
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		return a.character < b.character
+	})
+	return refs
+}
+
+// symbolReferenceScore returns how likely a reference in the file fm is to
+// refer to the definition def. A score of 0 means that it is unlikely.
+func symbolReferenceScore(def *searchSymbolResult, fm *fileMatchResolver) int {
+	sameLanguage := path.Ext(def.symbol.Path) == path.Ext(fm.JPath)
+	if def.commit.repo.repo.Name != fm.repo.Name {
+		if sameLanguage {
+			return 1
+		}
+		return 0
+	}
+	switch {
+	case def.symbol.Path == fm.JPath:
+		return 4
+	case path.Dir(def.symbol.Path) == path.Dir(fm.JPath):
+		return 3
+	default:
+		return 2
+	}
+}
+
+type symbolReferenceConnectionResolver struct {
+	definitions []*searchSymbolResult
+	references  []*symbolReferenceResolver
+	limit       int
+	limitHit    bool // whether the text search stopped before finding all references
+}
+
+func (r *symbolReferenceConnectionResolver) Definitions() []*symbolResolver {
+	symbols := make([]*symbolResolver, len(r.definitions))
+	for i, s := range r.definitions {
+		symbols[i] = toSymbolResolver(s.symbol, s.baseURI, s.lang, s.commit)
+	}
+	return symbols
+}
+
+func (r *symbolReferenceConnectionResolver) Nodes() []*symbolReferenceResolver {
+	if len(r.references) > r.limit {
+		return r.references[:r.limit]
+	}
+	return r.references
+}
+
+func (r *symbolReferenceConnectionResolver) TotalCount() int32 {
+	return int32(len(r.references))
+}
+
+func (r *symbolReferenceConnectionResolver) PageInfo() *graphqlutil.PageInfo {
+	return graphqlutil.HasNextPage(r.limitHit || len(r.references) > r.limit)
+}
+
+// symbolReferenceResolver is a resolver for the GraphQL type
+// `SymbolReference`.
+type symbolReferenceResolver struct {
+	fileMatch         *fileMatchResolver
+	line              int32 // zero-based
+	character, length int32
+	preview           string
+	definition        *searchSymbolResult
+	score             int
+}
+
+func (r *symbolReferenceResolver) Location() *locationResolver {
+	return &locationResolver{
+		resource: r.fileMatch.File(),
+		lspRange: &lsp.Range{
+			Start: lsp.Position{Line: int(r.line), Character: int(r.character)},
+			End:   lsp.Position{Line: int(r.line), Character: int(r.character + r.length)},
+		},
+	}
+}
+
+func (r *symbolReferenceResolver) Preview() string { return r.preview }
+
+func (r *symbolReferenceResolver) Definition() *symbolResolver {
+	if r.definition == nil {
+		return nil
+	}
+	return toSymbolResolver(r.definition.symbol, r.definition.baseURI, r.definition.lang, r.definition.commit)
+}
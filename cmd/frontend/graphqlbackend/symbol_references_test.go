@@ -0,0 +1,155 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gituri"
+	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+)
+
+func TestReferences(t *testing.T) {
+	repoA := &types.Repo{ID: 1, Name: "a"}
+	repoB := &types.Repo{ID: 2, Name: "b"}
+	baseURI, err := gituri.Parse("git://a?c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := &searchSymbolResult{
+		symbol:  protocol.Symbol{Name: "NewClient", Path: "client/client.go", Line: 10},
+		baseURI: baseURI,
+		lang:    "go",
+		commit:  &GitCommitResolver{repo: &RepositoryResolver{repo: repoA}, oid: "c1"},
+	}
+
+	var queries []string
+	mockSymbolReferencesSearch = func(ctx context.Context, q *query.Query, resultType string) (*searchResultsResolver, error) {
+		queries = append(queries, resultType+": "+q.Syntax.Input)
+		switch resultType {
+		case "symbol":
+			other := *def
+			other.symbol.Name = "NewClientFoo"
+			return &searchResultsResolver{results: []searchResultResolver{
+				&fileMatchResolver{JPath: "client/client.go", repo: repoA, symbols: []*searchSymbolResult{def, &other}},
+			}}, nil
+		case "file":
+			return &searchResultsResolver{results: []searchResultResolver{
+				&fileMatchResolver{JPath: "main.py", repo: repoB, JLineMatches: []*lineMatch{
+					{JLineNumber: 1, JOffsetAndLengths: [][2]int32{{0, 9}}},
+				}},
+				&fileMatchResolver{JPath: "main.go", repo: repoB, JLineMatches: []*lineMatch{
+					{JLineNumber: 5, JOffsetAndLengths: [][2]int32{{4, 9}}},
+				}},
+				&fileMatchResolver{JPath: "client/client.go", repo: repoA, JLineMatches: []*lineMatch{
+					{JLineNumber: 9, JOffsetAndLengths: [][2]int32{{5, 9}}}, // the definition
+					{JLineNumber: 20, JOffsetAndLengths: [][2]int32{{1, 9}, {20, 9}}},
+				}},
+				&fileMatchResolver{JPath: "cmd/main.go", repo: repoA, JLineMatches: []*lineMatch{
+					{JLineNumber: 3, JOffsetAndLengths: [][2]int32{{2, 9}}},
+				}},
+			}}, nil
+		}
+		t.Errorf("unexpected result type %q", resultType)
+		return &searchResultsResolver{}, nil
+	}
+	defer func() { mockSymbolReferencesSearch = nil }()
+
+	first := int32(4)
+	conn, err := (&schemaResolver{}).References(context.Background(), &symbolReferencesArgs{
+		ConnectionArgs: graphqlutil.ConnectionArgs{First: &first},
+		Symbol:         "NewClient",
+		Scope:          "repo:^a|b$",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantQueries := []string{
+		`symbol: repo:^a|b$ case:yes count:50 ^NewClient$`,
+		`file: repo:^a|b$ case:yes count:5 \bNewClient\b`,
+	}
+	if len(queries) != 2 || !(queries[0] == wantQueries[0] && queries[1] == wantQueries[1] || queries[0] == wantQueries[1] && queries[1] == wantQueries[0]) {
+		t.Errorf("got queries %q, want %q", queries, wantQueries)
+	}
+
+	if defs := conn.Definitions(); len(defs) != 1 || defs[0].Name() != "NewClient" {
+		t.Errorf("got definitions %+v, want only NewClient", defs)
+	}
+
+	type ref struct {
+		repo      api.RepoName
+		path      string
+		line      int32
+		character int32
+		score     int
+	}
+	var refs []ref
+	for _, r := range conn.references {
+		refs = append(refs, ref{r.fileMatch.repo.Name, r.fileMatch.JPath, r.line, r.character, r.score})
+		if (r.score > 0) != (r.Definition() != nil) {
+			t.Errorf("reference %s:%d has score %d and definition %v", r.fileMatch.JPath, r.line, r.score, r.definition)
+		}
+	}
+	wantRefs := []ref{
+		{"a", "client/client.go", 20, 1, 4},
+		{"a", "client/client.go", 20, 20, 4},
+		{"a", "cmd/main.go", 3, 2, 2},
+		{"b", "main.go", 5, 4, 1},
+		{"b", "main.py", 1, 0, 0},
+	}
+	if !reflect.DeepEqual(refs, wantRefs) {
+		t.Errorf("got references %+v, want %+v", refs, wantRefs)
+	}
+
+	if have, want := len(conn.Nodes()), 4; have != want {
+		t.Errorf("got %d nodes, want %d", have, want)
+	}
+	if have, want := conn.TotalCount(), int32(5); have != want {
+		t.Errorf("got total count %d, want %d", have, want)
+	}
+	if !conn.PageInfo().HasNextPage() {
+		t.Error("got hasNextPage false, want true")
+	}
+}
+
+func TestReferences_invalidArgs(t *testing.T) {
+	mockSymbolReferencesSearch = func(ctx context.Context, q *query.Query, resultType string) (*searchResultsResolver, error) {
+		t.Error("unexpected search")
+		return &searchResultsResolver{}, nil
+	}
+	defer func() { mockSymbolReferencesSearch = nil }()
+
+	for _, test := range []struct{ symbol, scope string }{
+		{"", ""},
+		{"foo bar", ""},
+		{`foo"`, ""},
+		{"foo", "bar"},
+		{"foo", "repo:x type:diff"},
+		{"foo", "repo:x case:no"},
+		{"foo", "repo:x replace:y"},
+	} {
+		_, err := (&schemaResolver{}).References(context.Background(), &symbolReferencesArgs{Symbol: test.symbol, Scope: test.scope})
+		if err == nil {
+			t.Errorf("symbol %q, scope %q: expected error", test.symbol, test.scope)
+		}
+	}
+}
+
+func TestWordBoundaryPattern(t *testing.T) {
+	for name, want := range map[string]string{
+		"foo":     `\bfoo\b`,
+		"$foo":    `\$foo\b`,
+		"foo$":    `\bfoo\$`,
+		"ÄÖ":      `ÄÖ`,
+		"foo_bar": `\bfoo_bar\b`,
+	} {
+		if have := wordBoundaryPattern(name); have != want {
+			t.Errorf("%q: got %q, want %q", name, have, want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"math"
 	"net/http"
 	"net/url"
+	"regexp"
 	"regexp/syntax"
 	"sort"
 	"strconv"
@@ -17,6 +18,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/go-langserver/pkg/lsp"
 
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
 	"github.com/opentracing/opentracing-go"
@@ -70,6 +72,9 @@ type fileMatchResolver struct {
 	// preserve the original revision specifier from the user instead of navigating them to the
 	// absolute commit ID when they select a result.
 	inputRev *string
+	// branches are the revisions whose trees contain this file with the same contents, when
+	// multiple revisions of the repository were searched.
+	branches []string
 }
 
 func (fm *fileMatchResolver) Key() string {
@@ -114,6 +119,13 @@ func (fm *fileMatchResolver) LimitHit() bool {
 	return fm.JLimitHit
 }
 
+func (fm *fileMatchResolver) Branches() []string {
+	if fm.branches == nil {
+		return []string{}
+	}
+	return fm.branches
+}
+
 func (fm *fileMatchResolver) ToRepository() (*RepositoryResolver, bool) { return nil, false }
 func (fm *fileMatchResolver) ToFileMatch() (*fileMatchResolver, bool)   { return fm, true }
 func (fm *fileMatchResolver) ToCommitSearchResult() (*commitSearchResultResolver, bool) {
@@ -124,6 +136,10 @@ func (r *fileMatchResolver) ToCodemodResult() (*codemodResultResolver, bool) {
 	return nil, false
 }
 
+func (fm *fileMatchResolver) ToDiscussionThread() (*discussionThreadResolver, bool) {
+	return nil, false
+}
+
 func (fm *fileMatchResolver) searchResultURIs() (string, string) {
 	return string(fm.repo.Name), fm.JPath
 }
@@ -138,10 +154,24 @@ func (fm *fileMatchResolver) resultCount() int32 {
 
 // LineMatch is the struct used by vscode to receive search results for a line
 type lineMatch struct {
-	JPreview          string     `json:"Preview"`
-	JOffsetAndLengths [][2]int32 `json:"OffsetAndLengths"`
-	JLineNumber       int32      `json:"LineNumber"`
-	JLimitHit         bool       `json:"LimitHit"`
+	JPreview          string           `json:"Preview"`
+	JOffsetAndLengths [][2]int32       `json:"OffsetAndLengths"`
+	JRanges           []lineMatchRange `json:"Ranges"`
+	JLineNumber       int32            `json:"LineNumber"`
+	JLimitHit         bool             `json:"LimitHit"`
+}
+
+// lineMatchRange is the range of a match that starts on a line, which may end
+// on a later line. Lines and columns are 0-based, and columns are measured in
+// characters (not bytes).
+type lineMatchRange struct {
+	Start lineMatchLocation
+	End   lineMatchLocation
+}
+
+type lineMatchLocation struct {
+	Line   int32
+	Column int32
 }
 
 func (lm *lineMatch) Preview() string {
@@ -160,6 +190,17 @@ func (lm *lineMatch) OffsetAndLengths() [][]int32 {
 	return r
 }
 
+func (lm *lineMatch) Ranges() []*rangeResolver {
+	r := make([]*rangeResolver, len(lm.JRanges))
+	for i, rg := range lm.JRanges {
+		r[i] = &rangeResolver{lspRange: lsp.Range{
+			Start: lsp.Position{Line: int(rg.Start.Line), Character: int(rg.Start.Column)},
+			End:   lsp.Position{Line: int(rg.End.Line), Character: int(rg.End.Column)},
+		}}
+	}
+	return r
+}
+
 func (lm *lineMatch) LimitHit() bool {
 	return lm.JLimitHit
 }
@@ -218,6 +259,9 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 	if p.IsCaseSensitive {
 		q.Set("IsCaseSensitive", "true")
 	}
+	if p.IsStructuralPat {
+		q.Set("IsStructuralPat", "true")
+	}
 	if p.PathPatternsAreRegExps {
 		q.Set("PathPatternsAreRegExps", "true")
 	}
@@ -365,6 +409,17 @@ func searchFilesInRepo(ctx context.Context, repo *types.Repo, gitserverRepo gits
 	if err != nil {
 		return nil, false, err
 	}
+	return searchFilesInRepoCommit(ctx, repo, gitserverRepo, rev, commit, info, fetchTimeout)
+}
+
+var mockSearchFilesInRepoCommit func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, commit api.CommitID, info *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error)
+
+// searchFilesInRepoCommit is like searchFilesInRepo, but searches the commit
+// that the revision rev was already resolved to.
+func searchFilesInRepoCommit(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, commit api.CommitID, info *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
+	if mockSearchFilesInRepoCommit != nil {
+		return mockSearchFilesInRepoCommit(ctx, repo, gitserverRepo, rev, commit, info, fetchTimeout)
+	}
 
 	shouldBeSearched, err := repoShouldBeSearched(ctx, info, gitserverRepo, commit, fetchTimeout)
 	if err != nil {
@@ -374,7 +429,11 @@ func searchFilesInRepo(ctx context.Context, repo *types.Repo, gitserverRepo gits
 		return matches, false, err
 	}
 
-	matches, limitHit, err = textSearch(ctx, gitserverRepo, commit, info, fetchTimeout)
+	if info.PatternExpr != nil {
+		matches, limitHit, err = textSearchPatternExpr(ctx, gitserverRepo, commit, info, fetchTimeout)
+	} else {
+		matches, limitHit, err = textSearch(ctx, gitserverRepo, commit, info, fetchTimeout)
+	}
 
 	workspace := fileMatchURI(repo.Name, rev, "")
 	for _, fm := range matches {
@@ -387,6 +446,379 @@ func searchFilesInRepo(ctx context.Context, repo *types.Repo, gitserverRepo gits
 	return matches, limitHit, err
 }
 
+// maxRevsPerRepo is the maximum number of revisions of a single repository that
+// are searched when multiple revisions (or ref globs) are specified.
+const maxRevsPerRepo = 50
+
+// searchFilesInRepoRevs searches all of the revisions of repoRev. Each commit is
+// searched once, even if several revisions resolve to it, and files with the same
+// contents in several commits are only returned once, with the names of all
+// revisions that contain them. At most info.FileMatchLimit files are returned for
+// all revisions together.
+func searchFilesInRepoRevs(ctx context.Context, repoRev *search.RepositoryRevisions, info *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
+	if len(repoRev.Revs) == 1 && !repoRev.HasRefGlobs() {
+		return searchFilesInRepo(ctx, repoRev.Repo, repoRev.GitserverRepo(), repoRev.Revs[0].RevSpec, info, fetchTimeout)
+	}
+
+	gitserverRepo := repoRev.GitserverRepo()
+	names, commits, limitHit, err := resolveRepoRevs(ctx, repoRev)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Files are deduplicated by their path and blob OID, so that a file that
+	// is the same on many branches is only returned once.
+	type blobKey struct {
+		path string
+		oid  git.OID
+	}
+	seen := map[blobKey]*fileMatchResolver{}
+	for _, commit := range commits {
+		if len(matches) >= int(info.FileMatchLimit) {
+			limitHit = true
+			break
+		}
+
+		// Search the resolved commit rather than resolving the name again, which
+		// could resolve to another commit (such as a tag with the same name as a
+		// branch) than the one whose blobs are compared below.
+		commitMatches, commitLimitHit, err := searchFilesInRepoCommit(ctx, repoRev.Repo, gitserverRepo, names[commit][0], commit, info, fetchTimeout)
+		if err != nil {
+			return nil, false, err
+		}
+		limitHit = limitHit || commitLimitHit
+		if len(commitMatches) == 0 {
+			continue
+		}
+
+		paths := make([]string, len(commitMatches))
+		for i, fm := range commitMatches {
+			paths[i] = fm.JPath
+		}
+		oids, err := git.BlobOIDs(ctx, gitserverRepo, commit, paths)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, fm := range commitMatches {
+			oid, ok := oids[fm.JPath]
+			if !ok {
+				// Not a blob we can compare (such as a submodule), so don't
+				// deduplicate it.
+				fm.branches = names[commit]
+				matches = append(matches, fm)
+				continue
+			}
+			key := blobKey{path: fm.JPath, oid: oid}
+			if prev, ok := seen[key]; ok {
+				prev.branches = append(prev.branches, names[commit]...)
+				continue
+			}
+			fm.branches = append([]string{}, names[commit]...)
+			seen[key] = fm
+			matches = append(matches, fm)
+		}
+	}
+	if len(matches) > int(info.FileMatchLimit) {
+		matches = matches[:info.FileMatchLimit]
+		limitHit = true
+	}
+	return matches, limitHit, nil
+}
+
+// resolveRepoRevs resolves the revspecs and expands the ref globs of repoRev to
+// commits. It returns the commits in the order they were first found and, for each
+// commit, the names of the revisions that resolve to it. At most maxRevsPerRepo
+// revisions are resolved; limitHit is true if there were more.
+func resolveRepoRevs(ctx context.Context, repoRev *search.RepositoryRevisions) (names map[api.CommitID][]string, commits []api.CommitID, limitHit bool, err error) {
+	repo := repoRev.GitserverRepo()
+	names = map[api.CommitID][]string{}
+	add := func(name string, commit api.CommitID) bool {
+		if _, ok := names[commit]; !ok {
+			if len(commits) == maxRevsPerRepo {
+				limitHit = true
+				return false
+			}
+			commits = append(commits, commit)
+		}
+		for _, n := range names[commit] {
+			if n == name {
+				return true
+			}
+		}
+		names[commit] = append(names[commit], name)
+		return true
+	}
+
+	for _, rev := range repoRev.Revs {
+		if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
+			continue
+		}
+		name := rev.RevSpec
+		if name == "" {
+			name = "HEAD"
+		}
+		// Like searchFilesInRepo, do not trigger a repo-updater lookup.
+		commit, err := git.ResolveRevision(ctx, repo, nil, name, &git.ResolveRevisionOptions{NoEnsureRevision: true})
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if !add(name, commit) {
+			return names, commits, limitHit, nil
+		}
+	}
+
+	if !repoRev.HasRefGlobs() {
+		return names, commits, limitHit, nil
+	}
+	matcher, err := search.NewRefGlobMatcher(repoRev.Revs)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	refs, err := git.ListRefs(ctx, repo)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	for _, ref := range refs {
+		if !matcher.Match(ref.Name) {
+			continue
+		}
+		if !add(strings.TrimPrefix(ref.Name, "refs/heads/"), ref.CommitID) {
+			break
+		}
+	}
+	return names, commits, limitHit, nil
+}
+
+// textSearchPatternExpr searches repo@commit for files matching the boolean
+// expression of patterns p.PatternExpr.
+//
+// Searcher only supports a single pattern per request, so each pattern in the
+// expression is searched for separately and the results are combined by file
+// path. The operands of an "AND" are searched only within the files matched by
+// its preceding operands, which bounds the work needed for negated operands.
+func textSearchPatternExpr(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
+	tr, ctx := trace.New(ctx, "textSearchPatternExpr", fmt.Sprintf("%s@%s %s", repo.Name, commit, p.PatternExpr))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	byPath, limitHit, err := evalPatternExpr(ctx, repo, commit, p, p.PatternExpr, nil, fetchTimeout)
+	if err != nil {
+		return nil, false, err
+	}
+	matches = make([]*fileMatchResolver, 0, len(byPath))
+	for _, fm := range byPath {
+		matches = append(matches, fm)
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].JPath < matches[j].JPath })
+	if len(matches) > int(p.FileMatchLimit) {
+		matches = matches[:p.FileMatchLimit]
+		limitHit = true
+	}
+	return matches, limitHit, nil
+}
+
+var (
+	// maxPatternExprCandidates is the maximum number of files matched by an
+	// operand of an "AND" pattern expression that its later operands are
+	// searched within. If more files match, the results are incomplete and
+	// limitHit is reported.
+	maxPatternExprCandidates = 1000
+
+	// patternExprCandidatesPerSearch is the maximum number of candidate file
+	// paths included in the path pattern of a single searcher request.
+	patternExprCandidatesPerSearch = 100
+)
+
+// evalPatternExpr returns the files in repo@commit that match e, keyed by
+// path. If candidates is non-nil, only the files in candidates are searched.
+//
+// The query typechecker guarantees that every negated expression is an
+// operand of an "and" with at least one non-negated operand.
+func evalPatternExpr(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.PatternInfo, e *search.PatternExpr, candidates map[string]*fileMatchResolver, fetchTimeout time.Duration) (matches map[string]*fileMatchResolver, limitHit bool, err error) {
+	if e.Not {
+		return nil, false, errors.Errorf("negated pattern expression %s must be combined with a non-negated pattern", e)
+	}
+
+	switch e.Op {
+	case search.PatternAnd:
+		// Evaluate the non-negated operands first so that they narrow down
+		// the files that the negated operands need to be searched in.
+		operands := make([]*search.PatternExpr, len(e.Operands))
+		copy(operands, e.Operands)
+		sort.SliceStable(operands, func(i, j int) bool { return !operands[i].Not && operands[j].Not })
+
+		// The first operand is not narrowed down by any candidates, so it may
+		// match many files that the later operands don't. Search for more
+		// files than requested, but no more than maxPatternExprCandidates so
+		// that the later operands' searches stay bounded.
+		pCandidates := *p
+		if int(pCandidates.FileMatchLimit) < maxPatternExprCandidates {
+			pCandidates.FileMatchLimit = int32(maxPatternExprCandidates)
+		}
+
+		matches = candidates
+		for _, operand := range operands {
+			if !operand.Not {
+				operandMatches, operandLimitHit, err := evalPatternExpr(ctx, repo, commit, &pCandidates, operand, matches, fetchTimeout)
+				if err != nil {
+					return nil, false, err
+				}
+				limitHit = limitHit || operandLimitHit
+				for path, fm := range operandMatches {
+					if prev, ok := matches[path]; ok {
+						operandMatches[path] = mergeFileMatches(prev, fm)
+					}
+				}
+				matches = operandMatches
+				if len(matches) > maxPatternExprCandidates {
+					matches = truncatePatternExprMatches(matches, maxPatternExprCandidates)
+					limitHit = true
+				}
+				continue
+			}
+
+			nonNegated := *operand
+			nonNegated.Not = false
+			excluded, _, err := evalPatternExpr(ctx, repo, commit, &pCandidates, &nonNegated, matches, fetchTimeout)
+			if err != nil {
+				return nil, false, err
+			}
+			remaining := make(map[string]*fileMatchResolver, len(matches))
+			for path, fm := range matches {
+				if _, ok := excluded[path]; !ok {
+					remaining[path] = fm
+				}
+			}
+			matches = remaining
+		}
+		return matches, limitHit, nil
+
+	case search.PatternOr:
+		matches = map[string]*fileMatchResolver{}
+		for _, operand := range e.Operands {
+			operandMatches, operandLimitHit, err := evalPatternExpr(ctx, repo, commit, p, operand, candidates, fetchTimeout)
+			if err != nil {
+				return nil, false, err
+			}
+			limitHit = limitHit || operandLimitHit
+			for path, fm := range operandMatches {
+				if prev, ok := matches[path]; ok {
+					fm = mergeFileMatches(prev, fm)
+				}
+				matches[path] = fm
+			}
+		}
+		return matches, limitHit, nil
+
+	default:
+		p2 := *p
+		p2.Pattern = e.Pattern
+		p2.PatternExpr = nil
+		if candidates == nil {
+			fms, leafLimitHit, err := textSearch(ctx, repo, commit, &p2, fetchTimeout)
+			if err != nil {
+				return nil, false, err
+			}
+			matches = make(map[string]*fileMatchResolver, len(fms))
+			for _, fm := range fms {
+				matches[fm.JPath] = fm
+			}
+			return matches, leafLimitHit, nil
+		}
+
+		// Search only within the candidate files, in batches so that the
+		// include pattern sent to searcher stays small.
+		paths := make([]string, 0, len(candidates))
+		for path := range candidates {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		matches = map[string]*fileMatchResolver{}
+		for len(paths) > 0 {
+			batch := paths
+			if len(batch) > patternExprCandidatesPerSearch {
+				batch = batch[:patternExprCandidatesPerSearch]
+			}
+			paths = paths[len(batch):]
+
+			quoted := make([]string, len(batch))
+			for i, path := range batch {
+				quoted[i] = regexp.QuoteMeta(path)
+			}
+			p3 := p2
+			p3.IncludePatterns = append(append([]string(nil), p.IncludePatterns...), "^(?:"+strings.Join(quoted, "|")+")$")
+			p3.FileMatchLimit = int32(len(batch))
+			fms, leafLimitHit, err := textSearch(ctx, repo, commit, &p3, fetchTimeout)
+			if err != nil {
+				return nil, false, err
+			}
+			limitHit = limitHit || leafLimitHit
+			for _, fm := range fms {
+				matches[fm.JPath] = fm
+			}
+		}
+		return matches, limitHit, nil
+	}
+}
+
+// truncatePatternExprMatches returns the first limit matches (ordered by path).
+func truncatePatternExprMatches(matches map[string]*fileMatchResolver, limit int) map[string]*fileMatchResolver {
+	paths := make([]string, 0, len(matches))
+	for path := range matches {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	truncated := make(map[string]*fileMatchResolver, limit)
+	for _, path := range paths[:limit] {
+		truncated[path] = matches[path]
+	}
+	return truncated
+}
+
+// mergeFileMatches returns a file match with the line matches of a and b,
+// which must be matches in the same file.
+func mergeFileMatches(a, b *fileMatchResolver) *fileMatchResolver {
+	merged := *a
+	merged.JLimitHit = a.JLimitHit || b.JLimitHit
+
+	byLine := make(map[int32]*lineMatch, len(a.JLineMatches)+len(b.JLineMatches))
+	merged.JLineMatches = nil
+	for _, lm := range append(append([]*lineMatch(nil), a.JLineMatches...), b.JLineMatches...) {
+		prev, ok := byLine[lm.JLineNumber]
+		if !ok {
+			lm2 := *lm
+			byLine[lm.JLineNumber] = &lm2
+			merged.JLineMatches = append(merged.JLineMatches, &lm2)
+			continue
+		}
+		prev.JLimitHit = prev.JLimitHit || lm.JLimitHit
+	offsets:
+		for _, ol := range lm.JOffsetAndLengths {
+			for _, prevOL := range prev.JOffsetAndLengths {
+				if ol == prevOL {
+					continue offsets
+				}
+			}
+			prev.JOffsetAndLengths = append(prev.JOffsetAndLengths, ol)
+		}
+		sort.Slice(prev.JOffsetAndLengths, func(i, j int) bool { return prev.JOffsetAndLengths[i][0] < prev.JOffsetAndLengths[j][0] })
+	ranges:
+		for _, rg := range lm.JRanges {
+			for _, prevRange := range prev.JRanges {
+				if rg == prevRange {
+					continue ranges
+				}
+			}
+			prev.JRanges = append(prev.JRanges, rg)
+		}
+		sort.Slice(prev.JRanges, func(i, j int) bool { return prev.JRanges[i].Start.Column < prev.JRanges[j].Start.Column })
+	}
+	sort.Slice(merged.JLineMatches, func(i, j int) bool { return merged.JLineMatches[i].JLineNumber < merged.JLineMatches[j].JLineNumber })
+	return &merged
+}
+
 // repoShouldBeSearched determines whether a repository should be searched in, based on whether the repository
 // fits in the subset of repositories specified in the query's `repohasfile` and `-repohasfile` flags if they exist.
 func repoShouldBeSearched(ctx context.Context, searchPattern *search.PatternInfo, gitserverRepo gitserver.Repo, commit api.CommitID, fetchTimeout time.Duration) (shouldBeSearched bool, err error) {
@@ -624,9 +1056,18 @@ func zoektSearchHEAD(ctx context.Context, query *search.PatternInfo, repos []*se
 					l.LineFragments = l.LineFragments[:maxLineFragmentMatches]
 				}
 				offsets := make([][2]int32, len(l.LineFragments))
+				ranges := make([]lineMatchRange, len(l.LineFragments))
 				for k, m := range l.LineFragments {
+					ranges[k] = zoektLineFragmentRange(file.LineMatches, l, m)
+					// A match that spans multiple lines is reported on
+					// its first line, with a length that extends past
+					// the end of the line.
+					end := m.LineOffset + m.MatchLength
+					if end > len(l.Line) {
+						end = len(l.Line)
+					}
 					offset := utf8.RuneCount(l.Line[:m.LineOffset])
-					length := utf8.RuneCount(l.Line[m.LineOffset : m.LineOffset+m.MatchLength])
+					length := utf8.RuneCount(l.Line[m.LineOffset:end])
 					offsets[k] = [2]int32{int32(offset), int32(length)}
 					if isSymbol && m.SymbolInfo != nil {
 						commit := &GitCommitResolver{
@@ -655,6 +1096,7 @@ func zoektSearchHEAD(ctx context.Context, query *search.PatternInfo, repos []*se
 						JPreview:          string(l.Line),
 						JLineNumber:       int32(l.LineNumber - 1),
 						JOffsetAndLengths: offsets,
+						JRanges:           ranges,
 					})
 				}
 			}
@@ -673,6 +1115,35 @@ func zoektSearchHEAD(ctx context.Context, query *search.PatternInfo, repos []*se
 	return matches, limitHit, reposLimitHit, nil
 }
 
+// zoektLineFragmentRange returns the range of the match m on line l. Zoekt
+// only returns the content of the lines with matches, so the end of a match
+// that spans multiple lines is located on the line it ends on if that line
+// is also in lines, and at the end of l otherwise.
+func zoektLineFragmentRange(lines []zoekt.LineMatch, l zoekt.LineMatch, m zoekt.LineFragmentMatch) lineMatchRange {
+	start := lineMatchLocation{
+		Line:   int32(l.LineNumber - 1),
+		Column: int32(utf8.RuneCount(l.Line[:m.LineOffset])),
+	}
+	if end := m.LineOffset + m.MatchLength; end <= len(l.Line) {
+		return lineMatchRange{Start: start, End: lineMatchLocation{Line: start.Line, Column: int32(utf8.RuneCount(l.Line[:end]))}}
+	}
+
+	endOffset := int(m.Offset) + m.MatchLength
+	for _, l2 := range lines {
+		if !l2.FileName && l2.LineStart <= endOffset && endOffset-l2.LineStart <= len(l2.Line) {
+			return lineMatchRange{Start: start, End: lineMatchLocation{
+				Line:   int32(l2.LineNumber - 1),
+				Column: int32(utf8.RuneCount(l2.Line[:endOffset-l2.LineStart])),
+			}}
+		}
+	}
+	if endOffset == l.LineEnd+1 {
+		// The match ends with the newline at the end of l.
+		return lineMatchRange{Start: start, End: lineMatchLocation{Line: start.Line + 1}}
+	}
+	return lineMatchRange{Start: start, End: lineMatchLocation{Line: start.Line, Column: int32(utf8.RuneCount(l.Line))}}
+}
+
 // Returns a new repoSet which accounts for the `repohasfile` and `-repohasfile` flags that may have been passed in the query.
 func createNewRepoSetWithRepoHasFileInputs(ctx context.Context, query *search.PatternInfo, searcher zoekt.Searcher, repoSet zoektquery.RepoSet) (*zoektquery.RepoSet, error) {
 	newRepoSet := repoSet.Set
@@ -793,7 +1264,13 @@ func queryToZoektQuery(query *search.PatternInfo, isSymbol bool) (zoektquery.Q,
 	var and []zoektquery.Q
 
 	var q zoektquery.Q
-	if query.IsRegExp {
+	if query.PatternExpr != nil {
+		var err error
+		q, err = patternExprToZoektQuery(query.PatternExpr, query.IsCaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+	} else if query.IsRegExp {
 		var err error
 		q, err = parseRe(query.Pattern, false, query.IsCaseSensitive)
 		if err != nil {
@@ -841,6 +1318,38 @@ func queryToZoektQuery(query *search.PatternInfo, isSymbol bool) (zoektquery.Q,
 	return zoektquery.Simplify(zoektquery.NewAnd(and...)), nil
 }
 
+// patternExprToZoektQuery converts a boolean expression of regexp patterns to
+// the equivalent Zoekt query tree.
+func patternExprToZoektQuery(e *search.PatternExpr, queryIsCaseSensitive bool) (zoektquery.Q, error) {
+	var q zoektquery.Q
+	switch e.Op {
+	case search.PatternAnd, search.PatternOr:
+		operands := make([]zoektquery.Q, len(e.Operands))
+		for i, operand := range e.Operands {
+			var err error
+			operands[i], err = patternExprToZoektQuery(operand, queryIsCaseSensitive)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if e.Op == search.PatternAnd {
+			q = zoektquery.NewAnd(operands...)
+		} else {
+			q = zoektquery.NewOr(operands...)
+		}
+	default:
+		var err error
+		q, err = parseRe(e.Pattern, false, queryIsCaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if e.Not {
+		q = &zoektquery.Not{Child: q}
+	}
+	return q, nil
+}
+
 // queryToZoektFileOnlyQueries constructs a list of Zoekt queries that search for a file pattern(s).
 // `listOfFilePaths` specifies which field on `query` should be the list of file patterns to look for.
 //  A separate zoekt query is created for each file path that should be searched.
@@ -866,7 +1375,7 @@ func queryToZoektFileOnlyQueries(query *search.PatternInfo, listOfFilePaths []st
 func zoektIndexedRepos(ctx context.Context, z *searchbackend.Zoekt, revs []*search.RepositoryRevisions, filter func(*zoekt.Repository) bool) (indexed, unindexed []*search.RepositoryRevisions, err error) {
 	count := 0
 	for _, r := range revs {
-		if len(r.Revs) > 0 && r.Revs[0].RevSpec == "" {
+		if searchesOnlyHEAD(r) {
 			count++
 		}
 	}
@@ -888,7 +1397,7 @@ func zoektIndexedRepos(ctx context.Context, z *searchbackend.Zoekt, revs []*sear
 
 	for _, rev := range revs {
 		repo, ok := set[strings.ToLower(string(rev.Repo.Name))]
-		if !ok || (filter != nil && !filter(repo)) {
+		if !ok || (filter != nil && !filter(repo)) || !searchesOnlyHEAD(rev) {
 			unindexed = append(unindexed, rev)
 			continue
 		}
@@ -906,6 +1415,12 @@ func zoektIndexedRepos(ctx context.Context, z *searchbackend.Zoekt, revs []*sear
 	return indexed, unindexed, nil
 }
 
+// searchesOnlyHEAD returns true if r searches only the default branch, which
+// is the only branch indexed search is used for.
+func searchesOnlyHEAD(r *search.RepositoryRevisions) bool {
+	return len(r.Revs) == 1 && r.Revs[0].RevSpec == "" && !r.HasRefGlobs()
+}
+
 var mockSearchFilesInRepos func(args *search.Args) ([]*fileMatchResolver, *searchResultsCommon, error)
 
 // searchFilesInRepos searches a set of repos for a pattern.
@@ -982,6 +1497,13 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 		}
 	}
 
+	if args.Pattern.IsStructuralPat && len(zoektRepos) > 0 {
+		// Zoekt does not support structural search, so search indexed repos with searcher.
+		tr.LazyPrintf("patterntype:structural, bypassing zoekt (using searcher) for %d indexed repos", len(zoektRepos))
+		searcherRepos = append(searcherRepos, zoektRepos...)
+		zoektRepos = nil
+	}
+
 	var (
 		// TODO: convert wg to an errgroup
 		wg                sync.WaitGroup
@@ -989,6 +1511,7 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 		unflattened       [][]*fileMatchResolver
 		flattenedSize     int
 		overLimitCanceled bool // canceled because we were over the limit
+		stream            = searchStreamFromContext(ctx)
 	)
 
 	// addMatches assumes the caller holds mu.
@@ -1023,7 +1546,13 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 		opts := zoektSearchOpts(k, query)
 		matches, limitHit, reposLimitHit, searchErr := zoektSearchHEAD(ctx, query, zoektRepos, args.UseFullDeadline, args.Zoekt.Client, opts, false, time.Since)
 		mu.Lock()
-		defer mu.Unlock()
+		defer func() {
+			// Send the matches to the stream (if any) without holding mu, so
+			// that a slow client doesn't block the other searches.
+			update := stream.update(common)
+			mu.Unlock()
+			stream.fileMatches(ctx, matches, update)
+		}()
 		if ctx.Err() == nil {
 			for _, repo := range zoektRepos {
 				common.searched = append(common.searched, repo.Repo)
@@ -1080,10 +1609,6 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 		if len(repoRev.Revs) == 0 {
 			continue
 		}
-		if len(repoRev.Revs) >= 2 {
-			return nil, common, errMultipleRevsNotSupported
-		}
-
 		// Only reason acquire can fail is if ctx is cancelled. So we can stop
 		// looping through searcherRepos.
 		limitCtx, limitDone, acquireErr := textSearchLimiter.Acquire(ctx)
@@ -1096,14 +1621,21 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 			defer wg.Done()
 			defer done()
 
-			rev := repoRev.RevSpecs()[0] // TODO(sqs): search multiple revs
-			matches, repoLimitHit, searchErr := searchFilesInRepo(ctx, repoRev.Repo, repoRev.GitserverRepo(), rev, args.Pattern, fetchTimeout)
+			matches, repoLimitHit, searchErr := searchFilesInRepoRevs(ctx, repoRev, args.Pattern, fetchTimeout)
 			if searchErr != nil {
 				tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.String("searchErr", searchErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(searchErr)), otlog.Bool("temporary", errcode.IsTemporary(searchErr)))
 				log15.Warn("searchFilesInRepo failed", "error", searchErr, "repo", repoRev.Repo.Name)
 			}
 			mu.Lock()
-			defer mu.Unlock()
+			streamMatches := false
+			defer func() {
+				// As above, send the matches without holding mu.
+				update := stream.update(common)
+				mu.Unlock()
+				if streamMatches {
+					stream.fileMatches(ctx, matches, update)
+				}
+			}()
 			if ctx.Err() == nil {
 				common.searched = append(common.searched, repoRev.Repo)
 			}
@@ -1126,6 +1658,7 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 				cancel()
 			}
 			addMatches(matches)
+			streamMatches = true
 		}(limitCtx, limitDone, repoRev)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 	"testing"
@@ -21,6 +22,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	searchbackend "github.com/sourcegraph/sourcegraph/pkg/search/backend"
 	"github.com/sourcegraph/sourcegraph/pkg/vcs"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
 
 func TestQueryToZoektQuery(t *testing.T) {
@@ -94,6 +96,25 @@ func TestQueryToZoektQuery(t *testing.T) {
 			},
 			Query: `foo case:yes f:\.go$ f:\.yaml$ -f:\bvendor\b`,
 		},
+		{
+			Name: "pattern expression",
+			Pattern: &search.PatternInfo{
+				IsRegExp:        true,
+				IsCaseSensitive: false,
+				Pattern:         "foo|bar",
+				PatternExpr: &search.PatternExpr{Op: search.PatternAnd, Operands: []*search.PatternExpr{
+					{Op: search.PatternOr, Operands: []*search.PatternExpr{
+						{Pattern: "foo"},
+						{Pattern: "bar"},
+					}},
+					{Not: true, Pattern: "baz"},
+				}},
+				IncludePatterns:              []string{`\.go$`},
+				PathPatternsAreRegExps:       true,
+				PathPatternsAreCaseSensitive: false,
+			},
+			Query: `(foo or bar) -baz case:no f:\.go$`,
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
@@ -306,6 +327,85 @@ func TestSearchFilesInRepos(t *testing.T) {
 	}
 }
 
+func TestSearchFilesInRepoRevs(t *testing.T) {
+	defer git.ResetMocks()
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		if spec != "dev" {
+			t.Fatalf("unexpected ResolveRevision(%q)", spec)
+		}
+		return "c1", nil
+	}
+	git.Mocks.ListRefs = func() ([]git.Ref, error) {
+		return []git.Ref{
+			{Name: "refs/heads/dev", CommitID: "c1"},
+			{Name: "refs/heads/master", CommitID: "c2"},
+			{Name: "refs/heads/release", CommitID: "c2"},
+			{Name: "refs/tags/v1", CommitID: "c3"},
+		}, nil
+	}
+	// a.go is the same in c1 and c2, b.go differs.
+	git.Mocks.BlobOIDs = func(commit api.CommitID, paths []string) (map[string]git.OID, error) {
+		oids := map[string]git.OID{"a.go": {1}, "b.go": {2}}
+		if commit == "c2" {
+			oids["b.go"] = git.OID{3}
+		}
+		return oids, nil
+	}
+	var searchedRevs []string
+	mockSearchFilesInRepoCommit = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, commit api.CommitID, info *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
+		searchedRevs = append(searchedRevs, rev+"@"+string(commit))
+		return []*fileMatchResolver{{JPath: "a.go", inputRev: &rev}, {JPath: "b.go", inputRev: &rev}}, false, nil
+	}
+	defer func() { mockSearchFilesInRepoCommit = nil }()
+
+	repoRev := &search.RepositoryRevisions{
+		Repo: &types.Repo{Name: "foo/one"},
+		Revs: []search.RevisionSpecifier{{RevSpec: "dev"}, {RefGlob: "refs/heads/*"}},
+	}
+	matches, limitHit, err := searchFilesInRepoRevs(context.Background(), repoRev, &search.PatternInfo{Pattern: "foo", FileMatchLimit: defaultMaxSearchResults}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limitHit {
+		t.Error("got limitHit")
+	}
+	if want := []string{"dev@c1", "master@c2"}; !reflect.DeepEqual(searchedRevs, want) {
+		t.Errorf("got searched revs %v, want %v", searchedRevs, want)
+	}
+	type match struct {
+		Path, Rev string
+		Branches  []string
+	}
+	var got []match
+	for _, fm := range matches {
+		got = append(got, match{Path: fm.JPath, Rev: *fm.inputRev, Branches: fm.Branches()})
+	}
+	want := []match{
+		{Path: "a.go", Rev: "dev", Branches: []string{"dev", "master", "release"}},
+		{Path: "b.go", Rev: "dev", Branches: []string{"dev"}},
+		{Path: "b.go", Rev: "master", Branches: []string{"master", "release"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// The limit applies to the files of all revisions together, and no more
+	// revisions are searched once it is reached.
+	searchedRevs = nil
+	matches, limitHit, err = searchFilesInRepoRevs(context.Background(), repoRev, &search.PatternInfo{Pattern: "foo", FileMatchLimit: 2}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limitHit {
+		t.Error("got no limitHit")
+	}
+	if len(matches) != 2 {
+		t.Errorf("got %d matches, want 2", len(matches))
+	}
+	if want := []string{"dev@c1"}; !reflect.DeepEqual(searchedRevs, want) {
+		t.Errorf("got searched revs %v, want %v", searchedRevs, want)
+	}
+}
+
 func TestRepoShouldBeSearched(t *testing.T) {
 	mockTextSearch = func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
 		repoName := repo.Name
@@ -346,6 +446,132 @@ func TestRepoShouldBeSearched(t *testing.T) {
 	}
 }
 
+func TestTextSearchPatternExpr(t *testing.T) {
+	files := map[string]string{
+		"a.go": "foo\nbar",
+		"b.go": "foo\nbaz",
+		"c.go": "bar",
+	}
+	mockTextSearch = func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.PatternInfo, fetchTimeout time.Duration) (matches []*fileMatchResolver, limitHit bool, err error) {
+		if p.PatternExpr != nil {
+			return nil, false, errors.New("unexpected pattern expression")
+		}
+		pattern := regexp.MustCompile(p.Pattern)
+	files:
+		for _, path := range []string{"a.go", "b.go", "c.go"} {
+			for _, include := range p.IncludePatterns {
+				if !regexp.MustCompile(include).MatchString(path) {
+					continue files
+				}
+			}
+			fm := &fileMatchResolver{JPath: path}
+			for i, line := range strings.Split(files[path], "\n") {
+				if loc := pattern.FindStringIndex(line); loc != nil {
+					fm.JLineMatches = append(fm.JLineMatches, &lineMatch{
+						JPreview:          line,
+						JLineNumber:       int32(i),
+						JOffsetAndLengths: [][2]int32{{int32(loc[0]), int32(loc[1] - loc[0])}},
+					})
+				}
+			}
+			if len(fm.JLineMatches) > 0 {
+				if len(matches) == int(p.FileMatchLimit) {
+					return matches, true, nil
+				}
+				matches = append(matches, fm)
+			}
+		}
+		return matches, false, nil
+	}
+	defer func() { mockTextSearch = nil }()
+
+	leaf := func(pattern string) *search.PatternExpr { return &search.PatternExpr{Pattern: pattern} }
+	not := func(e *search.PatternExpr) *search.PatternExpr { e.Not = true; return e }
+	and := func(operands ...*search.PatternExpr) *search.PatternExpr {
+		return &search.PatternExpr{Op: search.PatternAnd, Operands: operands}
+	}
+	or := func(operands ...*search.PatternExpr) *search.PatternExpr {
+		return &search.PatternExpr{Op: search.PatternOr, Operands: operands}
+	}
+
+	tests := []struct {
+		expr *search.PatternExpr
+		want []string // path:line,...
+	}{
+		{expr: and(leaf("foo"), leaf("bar")), want: []string{"a.go:0,1"}},
+		{expr: and(not(leaf("baz")), leaf("foo")), want: []string{"a.go:0"}},
+		{expr: or(leaf("bar"), leaf("baz")), want: []string{"a.go:1", "b.go:1", "c.go:0"}},
+		{expr: or(and(leaf("foo"), not(leaf("bar"))), and(leaf("bar"), not(leaf("foo")))), want: []string{"b.go:0", "c.go:0"}},
+		{expr: and(leaf("o"), not(or(leaf("bar"), leaf("baz")))), want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.expr.String(), func(t *testing.T) {
+			p := &search.PatternInfo{FileMatchLimit: defaultMaxSearchResults, PathPatternsAreRegExps: true, PatternExpr: test.expr}
+			matches, _, err := textSearchPatternExpr(context.Background(), gitserver.Repo{Name: "foo"}, "1a2b3c", p, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, fm := range matches {
+				lines := make([]string, len(fm.JLineMatches))
+				for i, lm := range fm.JLineMatches {
+					lines[i] = fmt.Sprint(lm.JLineNumber)
+				}
+				got = append(got, fm.JPath+":"+strings.Join(lines, ","))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	// An explicit AND matches files that contain both patterns, on any lines
+	// and in any order (a.go has foo and bar on separate lines).
+	for _, queryStr := range []string{"foo AND bar", "bar AND foo"} {
+		t.Run(queryStr, func(t *testing.T) {
+			q, err := query.ParseAndCheck(queryStr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sr := searchResolver{query: q}
+			p, err := sr.getPatternInfo(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.PatternExpr == nil {
+				t.Fatalf("got pattern %q without a pattern expression, want file-level AND", p.Pattern)
+			}
+			matches, _, err := textSearchPatternExpr(context.Background(), gitserver.Repo{Name: "foo"}, "1a2b3c", p, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(matches) != 1 || matches[0].JPath != "a.go" || len(matches[0].JLineMatches) != 2 {
+				t.Errorf("got %d matches, want a.go with 2 line matches", len(matches))
+			}
+		})
+	}
+
+	t.Run("candidates limit hit", func(t *testing.T) {
+		defer func(max, perSearch int) {
+			maxPatternExprCandidates, patternExprCandidatesPerSearch = max, perSearch
+		}(maxPatternExprCandidates, patternExprCandidatesPerSearch)
+		maxPatternExprCandidates, patternExprCandidatesPerSearch = 2, 1
+
+		// All 3 files match "ba", but only the first 2 are searched for "r", so c.go is missed.
+		p := &search.PatternInfo{FileMatchLimit: 10, PathPatternsAreRegExps: true, PatternExpr: and(leaf("ba"), leaf("r"))}
+		matches, limitHit, err := textSearchPatternExpr(context.Background(), gitserver.Repo{Name: "foo"}, "1a2b3c", p, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 || matches[0].JPath != "a.go" {
+			t.Errorf("got %d matches, want only a.go", len(matches))
+		}
+		if !limitHit {
+			t.Error("got limitHit false, want true (more than maxPatternExprCandidates files match the first operand)")
+		}
+	})
+}
+
 func makeRepositoryRevisions(repos ...string) []*search.RepositoryRevisions {
 	r := make([]*search.RepositoryRevisions, len(repos))
 	for i, repospec := range repos {
@@ -502,6 +728,45 @@ func Test_zoektSearchHEAD(t *testing.T) {
 	}
 }
 
+func Test_zoektLineFragmentRange(t *testing.T) {
+	// The lines of "foo(\n\tbar)\n" that Zoekt returns.
+	line1 := zoekt.LineMatch{Line: []byte("foo("), LineStart: 0, LineEnd: 4, LineNumber: 1}
+	line2 := zoekt.LineMatch{Line: []byte("\tbar)"), LineStart: 5, LineEnd: 10, LineNumber: 2}
+	loc := func(line, column int32) lineMatchLocation { return lineMatchLocation{Line: line, Column: column} }
+
+	tests := map[string]struct {
+		lines []zoekt.LineMatch
+		m     zoekt.LineFragmentMatch
+		want  lineMatchRange
+	}{
+		"single line": {
+			lines: []zoekt.LineMatch{line1},
+			m:     zoekt.LineFragmentMatch{LineOffset: 1, Offset: 1, MatchLength: 3},
+			want:  lineMatchRange{Start: loc(0, 1), End: loc(0, 4)},
+		},
+		"multiple lines": {
+			lines: []zoekt.LineMatch{line1, line2},
+			m:     zoekt.LineFragmentMatch{LineOffset: 0, Offset: 0, MatchLength: 9},
+			want:  lineMatchRange{Start: loc(0, 0), End: loc(1, 4)},
+		},
+		"multiple lines, end line not returned": {
+			lines: []zoekt.LineMatch{line1},
+			m:     zoekt.LineFragmentMatch{LineOffset: 0, Offset: 0, MatchLength: 9},
+			want:  lineMatchRange{Start: loc(0, 0), End: loc(0, 4)},
+		},
+		"ends with newline": {
+			lines: []zoekt.LineMatch{line1},
+			m:     zoekt.LineFragmentMatch{LineOffset: 0, Offset: 0, MatchLength: 5},
+			want:  lineMatchRange{Start: loc(0, 0), End: loc(1, 0)},
+		},
+	}
+	for name, test := range tests {
+		if got := zoektLineFragmentRange(test.lines, line1, test.m); got != test.want {
+			t.Errorf("%s: got %+v, want %+v", name, got, test.want)
+		}
+	}
+}
+
 func Test_createNewRepoSetWithRepoHasFileInputs(t *testing.T) {
 	type args struct {
 		ctx                             context.Context
@@ -709,6 +974,16 @@ func Test_zoektIndexedRepos(t *testing.T) {
 		repos:     repos[:1],
 		indexed:   makeIndexed(repos[:1]),
 		unindexed: repos[:0],
+	}, {
+		name:      "ref glob",
+		repos:     makeRepositoryRevisions("foo/indexed-one@*refs/heads/*"),
+		indexed:   nil,
+		unindexed: makeRepositoryRevisions("foo/indexed-one@*refs/heads/*"),
+	}, {
+		name:      "non-default revs",
+		repos:     append(makeRepositoryRevisions("foo/indexed-two@foobar", "foo/indexed-three@HEAD:foobar"), repos[0]),
+		indexed:   makeIndexed(repos[:1]),
+		unindexed: makeRepositoryRevisions("foo/indexed-two@foobar", "foo/indexed-three@HEAD:foobar"),
 	}}
 
 	for _, tc := range cases {
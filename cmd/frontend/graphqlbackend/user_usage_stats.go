@@ -2,6 +2,7 @@ package graphqlbackend
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -60,10 +61,26 @@ func (s *userUsageStatisticsResolver) LastActiveCodeHostIntegrationTime() *strin
 func (*schemaResolver) LogUserEvent(ctx context.Context, args *struct {
 	Event        string
 	UserCookieID string
+	Argument     *JSONValue
+	Repository   *string
 }) (*EmptyResponse, error) {
 	if envvar.SourcegraphDotComMode() {
 		return nil, nil
 	}
-	actor := actor.FromContext(ctx)
-	return nil, usagestats.LogActivity(actor.IsAuthenticated(), actor.UID, args.UserCookieID, args.Event)
+	e := &usagestats.Event{
+		Name:         args.Event,
+		UserID:       actor.FromContext(ctx).UID,
+		UserCookieID: args.UserCookieID,
+	}
+	if args.Argument != nil {
+		argument, err := json.Marshal(args.Argument)
+		if err != nil {
+			return nil, err
+		}
+		e.Argument = argument
+	}
+	if args.Repository != nil {
+		e.Repository = *args.Repository
+	}
+	return nil, usagestats.LogEvent(ctx, e)
 }
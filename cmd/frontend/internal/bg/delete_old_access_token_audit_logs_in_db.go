@@ -0,0 +1,26 @@
+package bg
+
+import (
+	"context"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// accessTokenAuditLogsRetention is how long entries are kept in the access token audit log.
+const accessTokenAuditLogsRetention = 90 * 24 * time.Hour
+
+// DeleteOldAccessTokenAuditLogsInDB periodically deletes the entries in the
+// access_token_audit_logs table that are older than accessTokenAuditLogsRetention.
+func DeleteOldAccessTokenAuditLogsInDB(ctx context.Context) {
+	for {
+		deleted, err := db.AccessTokens.DeleteAuditLogsOlderThan(ctx, time.Now().Add(-accessTokenAuditLogsRetention))
+		if err != nil {
+			log15.Error("deleting old entries from access_token_audit_logs table", "error", err)
+		} else if deleted > 0 {
+			log15.Debug("deleted old entries from access_token_audit_logs table", "count", deleted)
+		}
+		time.Sleep(time.Hour)
+	}
+}
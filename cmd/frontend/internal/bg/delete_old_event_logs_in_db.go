@@ -0,0 +1,33 @@
+package bg
+
+import (
+	"context"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// defaultEventLogsRetentionDays is the number of days that events are kept in
+// the event log if usageStatistics.retentionDays is not set.
+const defaultEventLogsRetentionDays = 365
+
+// DeleteOldEventLogsInDB periodically deletes the events in the event_logs
+// table that are older than the retention configured in
+// usageStatistics.retentionDays.
+func DeleteOldEventLogsInDB(ctx context.Context) {
+	for {
+		days := conf.Get().UsageStatisticsRetentionDays
+		if days <= 0 {
+			days = defaultEventLogsRetentionDays
+		}
+		deleted, err := db.EventLogs.DeleteOlderThan(ctx, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log15.Error("deleting old events from event_logs table", "error", err)
+		} else if deleted > 0 {
+			log15.Debug("deleted old events from event_logs table", "count", deleted, "retentionDays", days)
+		}
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,32 @@
+package bg
+
+import (
+	"context"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// abandonedCampaignJobAge is the age after which an unfinished campaign job is
+// considered abandoned. It is longer than the timeout of all jobs of a
+// campaign plan (campaignPlanTimeout in package graphqlbackend), so jobs that
+// are still running on another frontend are never affected.
+const abandonedCampaignJobAge = 2 * time.Hour
+
+// ErrorAbandonedCampaignJobs periodically marks campaign jobs that were
+// abandoned (e.g. because the frontend that ran them was restarted) as
+// errored. Otherwise their campaign plans would be processing forever.
+func ErrorAbandonedCampaignJobs(ctx context.Context) {
+	store := db.NewCampaignsStore(dbconn.Global)
+	for {
+		errored, err := store.ErrorUnfinishedCampaignJobs(ctx, time.Now().Add(-abandonedCampaignJobAge), "The job was interrupted (e.g. because Sourcegraph was restarted). Create a new campaign plan to retry it.")
+		if err != nil {
+			log15.Error("marking abandoned campaign jobs as errored", "error", err)
+		} else if errored > 0 {
+			log15.Info("marked abandoned campaign jobs as errored", "count", errored)
+		}
+		time.Sleep(10 * time.Minute)
+	}
+}
@@ -0,0 +1,111 @@
+package bg
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/mailreply"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/txemail"
+	"github.com/sourcegraph/sourcegraph/pkg/txemail/txtypes"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// accessTokenExpiryNotificationPeriod is how long before an access token expires that its subject
+// user is notified of the expiry.
+const accessTokenExpiryNotificationPeriod = 7 * 24 * time.Hour
+
+// NotifyExpiringAccessTokens periodically emails users whose access tokens are about to expire.
+// Each access token's subject user is notified once, and can revoke the access token immediately
+// by replying "revoke" (if email.imap is configured).
+func NotifyExpiringAccessTokens(ctx context.Context) {
+	for {
+		if conf.CanSendEmail() {
+			if err := notifyExpiringAccessTokens(ctx, time.Now().Add(accessTokenExpiryNotificationPeriod)); err != nil {
+				log15.Error("notifying users of expiring access tokens", "error", err)
+			}
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+func notifyExpiringAccessTokens(ctx context.Context, expiresBefore time.Time) error {
+	tokens, err := db.AccessTokens.ClaimExpiryNotifications(ctx, expiresBefore)
+	if err != nil {
+		return errors.Wrap(err, "AccessTokens.ClaimExpiryNotifications")
+	}
+	for _, token := range tokens {
+		// The access tokens are already claimed, so keep notifying the other users if one fails.
+		if err := notifyExpiringAccessToken(ctx, token); err != nil {
+			log15.Error("notifying user of expiring access token", "accessTokenID", token.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+func notifyExpiringAccessToken(ctx context.Context, token *db.AccessToken) error {
+	user, err := db.Users.GetByID(ctx, token.SubjectUserID)
+	if err != nil {
+		return errors.Wrap(err, "Users.GetByID")
+	}
+	email, verified, err := db.UserEmails.GetPrimaryEmail(ctx, user.ID)
+	if err != nil && !errcode.IsNotFound(err) {
+		return errors.Wrap(err, "UserEmails.GetPrimaryEmail")
+	}
+	if errcode.IsNotFound(err) || !verified {
+		// User has no email or it is not verified, do not send them any emails.
+		return nil
+	}
+
+	// 🚨 SECURITY: The reply-to address allows anyone who has it to revoke the access token, so it
+	// must only be sent to the access token's subject user (whose primary email we send to).
+	replyTo, err := mailreply.ReplyTo(ctx, user.ID, mailreply.KindAccessToken, strconv.FormatInt(token.ID, 10))
+	if err != nil {
+		return errors.Wrap(err, "mailreply.ReplyTo")
+	}
+
+	return txemail.Send(ctx, txemail.Message{
+		To:       []string{email},
+		ReplyTo:  replyTo,
+		Template: expiringAccessTokenEmailTemplates,
+		Data: struct {
+			Note      string
+			ExpiresAt string
+			URL       string
+			CanReply  bool
+		}{
+			Note:      token.Note,
+			ExpiresAt: token.ExpiresAt.UTC().Format("January 2, 2006 at 15:04 UTC"),
+			URL: globals.ExternalURL().ResolveReference(&url.URL{
+				Path: "/users/" + user.Username + "/settings/tokens",
+			}).String(),
+			CanReply: replyTo != nil,
+		},
+	})
+}
+
+var expiringAccessTokenEmailTemplates = txemail.MustValidate(txtypes.Templates{
+	Subject: `Your Sourcegraph access token {{printf "%q" .Note}} expires soon`,
+	Text: `
+Your Sourcegraph access token {{printf "%q" .Note}} expires on {{.ExpiresAt}}. API clients that use it will no longer be able to authenticate after then.
+
+To create or rotate access tokens, follow this link:
+
+  {{.URL}}
+{{if .CanReply}}
+If you no longer need this access token, reply "revoke" to this email to revoke it now.
+{{end}}`,
+	HTML: `
+<p>Your Sourcegraph access token <strong>{{.Note}}</strong> expires on {{.ExpiresAt}}. API clients that use it will no longer be able to authenticate after then.</p>
+
+<p><strong><a href="{{.URL}}">Manage access tokens</a></strong></p>
+{{if .CanReply}}
+<p>If you no longer need this access token, reply <strong>revoke</strong> to this email to revoke it now.</p>
+{{end}}`,
+})
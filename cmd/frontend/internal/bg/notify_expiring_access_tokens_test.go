@@ -0,0 +1,69 @@
+package bg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+	"github.com/sourcegraph/sourcegraph/pkg/txemail"
+)
+
+func TestNotifyExpiringAccessTokens(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	ctx := dbtesting.TestContext(t)
+
+	verified, err := db.Users.Create(ctx, db.NewUser{Username: "u1", Email: "u1@example.com", EmailIsVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unverified, err := db.Users.Create(ctx, db.NewUser{Username: "u2", Email: "u2@example.com", EmailVerificationCode: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	soon := time.Now().Add(24 * time.Hour)
+	later := time.Now().Add(30 * 24 * time.Hour)
+	for _, user := range []int32{verified.ID, unverified.ID} {
+		if _, _, err := db.AccessTokens.Create(ctx, user, []string{"user:all"}, "soon", user, &soon); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := db.AccessTokens.Create(ctx, user, []string{"user:all"}, "later", user, &later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sent []txemail.Message
+	txemail.MockSend = func(ctx context.Context, message txemail.Message) error {
+		sent = append(sent, message)
+		return nil
+	}
+	defer func() { txemail.MockSend = nil }()
+
+	expiresBefore := time.Now().Add(accessTokenExpiryNotificationPeriod)
+	if err := notifyExpiringAccessTokens(ctx, expiresBefore); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("got %d emails, want 1", len(sent))
+	}
+	if want := []string{"u1@example.com"}; !reflect.DeepEqual(sent[0].To, want) {
+		t.Errorf("got recipients %q, want %q", sent[0].To, want)
+	}
+	if sent[0].ReplyTo != nil {
+		t.Errorf("got reply-to %q, want none (email.imap is not configured)", *sent[0].ReplyTo)
+	}
+
+	// Users are only notified once per access token.
+	sent = nil
+	if err := notifyExpiringAccessTokens(ctx, expiresBefore); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("got %d emails, want 0", len(sent))
+	}
+}
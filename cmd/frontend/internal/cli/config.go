@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"os/user"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -239,8 +240,10 @@ func serviceConnections() conftypes.ServiceConnections {
 		}
 
 		serviceConnectionsVal = conftypes.ServiceConnections{
-			GitServers:  gitServers(),
-			PostgresDSN: postgresDSN(username, os.Getenv),
+			GitServers:                 gitServers(),
+			GitServerReplicationFactor: gitServerReplicationFactor(),
+			GitServerHashRing:          gitServerHashRing(),
+			PostgresDSN:                postgresDSN(username, os.Getenv),
 		}
 	})
 	return serviceConnectionsVal
@@ -258,6 +261,36 @@ func gitServers() []string {
 	return strings.Fields(v)
 }
 
+// gitServerReplicationFactor returns the number of gitservers each repository
+// is cloned on, as specified by SRC_GIT_SERVER_REPLICATION_FACTOR.
+func gitServerReplicationFactor() int {
+	v := os.Getenv("SRC_GIT_SERVER_REPLICATION_FACTOR")
+	if v == "" {
+		return 1
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log15.Warn("Invalid SRC_GIT_SERVER_REPLICATION_FACTOR (must be a positive integer), cloning each repository on a single gitserver.", "value", v)
+		return 1
+	}
+	return n
+}
+
+// gitServerHashRing returns whether repositories are assigned to gitservers
+// with a consistent hash ring, as specified by SRC_GIT_SERVER_HASH_RING.
+func gitServerHashRing() bool {
+	v := os.Getenv("SRC_GIT_SERVER_HASH_RING")
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log15.Warn("Invalid SRC_GIT_SERVER_HASH_RING (must be a boolean), not using the hash ring.", "value", v)
+		return false
+	}
+	return b
+}
+
 func postgresDSN(currentUser string, getenv func(string) string) string {
 	// PGDATASOURCE is a sourcegraph specific variable for just setting the DSN
 	if dsn := getenv("PGDATASOURCE"); dsn != "" {
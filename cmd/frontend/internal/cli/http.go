@@ -10,6 +10,7 @@ import (
 	gcontext "github.com/gorilla/context"
 	"github.com/gorilla/mux"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/hooks"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/app"
@@ -47,9 +48,10 @@ func newExternalHTTPHandler(ctx context.Context) (http.Handler, error) {
 	appHandler = handlerutil.CSRFMiddleware(appHandler, func() bool {
 		return globals.ExternalURL().Scheme == "https"
 	}) // after appAuthMiddleware because SAML IdP posts data to us w/o a CSRF token
-	appHandler = authMiddlewares.App(appHandler)               // 🚨 SECURITY: auth middleware
-	appHandler = session.CookieMiddleware(appHandler)          // app accepts cookies
-	appHandler = httpapi.AccessTokenAuthMiddleware(appHandler) // app accepts access tokens
+	appHandler = authMiddlewares.App(appHandler)                                  // 🚨 SECURITY: auth middleware
+	appHandler = session.CookieMiddleware(appHandler)                             // app accepts cookies
+	appHandler = httpapi.RequireAccessTokenScope(authz.ScopeRepoRead, appHandler) // 🚨 SECURITY: restricted tokens need "repo:read"
+	appHandler = httpapi.AccessTokenAuthMiddleware(appHandler)                    // app accepts access tokens
 
 	// Mount handlers and assets.
 	sm := http.NewServeMux()
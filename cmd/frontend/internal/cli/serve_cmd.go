@@ -15,14 +15,16 @@ import (
 	"time"
 
 	"github.com/keegancsmith/tmpfriend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/hooks"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/app/pkg/updatecheck"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/bg"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/cli/loghandlers"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
-	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/discussions/mailreply"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/mailreply"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/siteid"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/usagestats"
 	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
 	"github.com/sourcegraph/sourcegraph/pkg/debugserver"
@@ -154,6 +156,13 @@ func Main() error {
 	goroutine.Go(func() { bg.LogSearchQueries(context.Background()) })
 	goroutine.Go(func() { bg.CheckRedisCacheEvictionPolicy() })
 	goroutine.Go(func() { bg.DeleteOldCacheDataInRedis() })
+	goroutine.Go(func() { bg.DeleteOldEventLogsInDB(context.Background()) })
+	goroutine.Go(func() { bg.DeleteOldAccessTokenAuditLogsInDB(context.Background()) })
+	goroutine.Go(func() { bg.NotifyExpiringAccessTokens(context.Background()) })
+	goroutine.Go(func() { bg.ErrorAbandonedCampaignJobs(context.Background()) })
+	if !envvar.SourcegraphDotComMode() {
+		goroutine.Go(usagestats.UpdateMetrics)
+	}
 	goroutine.Go(mailreply.StartWorker)
 	go updatecheck.Start()
 	if hooks.AfterDBInit != nil {
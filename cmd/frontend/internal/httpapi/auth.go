@@ -1,7 +1,9 @@
 package httpapi
 
 import (
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
@@ -58,21 +60,27 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 			}
 
 			// Validate access token.
-			//
-			// 🚨 SECURITY: It's important we check for the correct scopes to know what this token
-			// is allowed to do.
-			var requiredScope string
-			if sudoUser == "" {
-				requiredScope = authz.ScopeUserAll
-			} else {
-				requiredScope = authz.ScopeSiteAdminSudo
-			}
-			subjectUserID, err := db.AccessTokens.Lookup(r.Context(), token, requiredScope)
+			tok, err := db.AccessTokens.Lookup(r.Context(), token, accessTokenUse(r))
 			if err != nil {
 				log15.Error("Invalid access token.", "token", token, "err", err)
 				http.Error(w, "Invalid access token.", http.StatusUnauthorized)
 				return
 			}
+			subjectUserID := tok.SubjectUserID
+
+			// 🚨 SECURITY: It's important we check for the correct scopes to know what this token
+			// is allowed to do. Sudo requires the "site-admin:sudo" scope. Any other scopes are
+			// attached to the actor and checked by the handlers and resolvers that require them.
+			if sudoUser != "" && !authz.HasScope(tok.Scopes, authz.ScopeSiteAdminSudo) {
+				log15.Error("Access token used for sudo lacks the sudo scope.", "tokenID", tok.ID)
+				http.Error(w, "Invalid access token.", http.StatusUnauthorized)
+				return
+			}
+			if !hasNonSudoScope(tok.Scopes) {
+				log15.Error("Access token has no usable scopes.", "tokenID", tok.ID)
+				http.Error(w, "Invalid access token.", http.StatusUnauthorized)
+				return
+			}
 
 			// Determine the actor's user ID.
 			var actorUserID int32
@@ -88,7 +96,7 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 				}
 
 				// Sudo to the other user if this is a sudo token. We already checked that the token has
-				// the necessary scope in the Lookup call above.
+				// the necessary scope above.
 				user, err := db.Users.GetByUsername(r.Context(), sudoUser)
 				if err != nil {
 					log15.Error("Invalid username used with sudo access token.", "sudoUser", sudoUser, "err", err)
@@ -105,9 +113,94 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 				log15.Debug("HTTP request used sudo token.", "requestURI", r.URL.RequestURI(), "tokenSubjectUserID", subjectUserID, "actorUserID", actorUserID, "actorUsername", user.Username)
 			}
 
-			r = r.WithContext(actor.WithActor(r.Context(), &actor.Actor{UID: actorUserID}))
+			r = r.WithContext(actor.WithActor(r.Context(), &actor.Actor{UID: actorUserID, AccessTokenScopes: tok.Scopes}))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// accessTokenUse describes the request for the access token audit log.
+func accessTokenUse(r *http.Request) db.AccessTokenUse {
+	var trustedProxies []string
+	if c := conf.Get().AuthAccessTokens; c != nil {
+		trustedProxies = c.TrustedProxies
+	}
+	// 🚨 SECURITY: Don't record the query string, which may contain the access token itself.
+	return db.AccessTokenUse{IP: clientIP(r, trustedProxies), Endpoint: r.Method + " " + r.URL.Path}
+}
+
+// clientIP returns the address of the client that sent r. If r comes from one of the trusted
+// proxies (CIDRs), the address is taken from the X-Forwarded-For header: it is the last address
+// in the header that is not a trusted proxy itself.
+//
+// 🚨 SECURITY: The X-Forwarded-For header is only used for requests from trusted proxies, because
+// the client controls it and could otherwise use it to forge the audit log.
+func clientIP(r *http.Request, trustedProxies []string) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	var nets []*net.IPNet
+	for _, cidr := range trustedProxies {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log15.Warn("Ignoring invalid trusted proxy address.", "cidr", cidr, "err", err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	trusted := func(ip string) bool {
+		parsed := net.ParseIP(ip)
+		for _, n := range nets {
+			if parsed != nil && n.Contains(parsed) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !trusted(ip) {
+		return ip
+	}
+	forwardedFor := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwardedFor) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwardedFor[i])
+		if net.ParseIP(addr) == nil {
+			break // not set by a trusted proxy
+		}
+		ip = addr
+		if !trusted(addr) {
+			break
+		}
+	}
+	return ip
+}
+
+// hasNonSudoScope reports whether scopes contains any scope other than
+// "site-admin:sudo" (which only permits impersonation and grants no access of
+// its own).
+func hasNonSudoScope(scopes []string) bool {
+	for _, scope := range scopes {
+		if scope != authz.ScopeSiteAdminSudo {
+			return true
 		}
+	}
+	return false
+}
 
+// RequireAccessTokenScope wraps next so that requests authenticated with an
+// access token that does not grant scope are rejected. Requests that did not
+// authenticate with an access token are passed through unchanged.
+func RequireAccessTokenScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 🚨 SECURITY: Restricted access tokens may only be used for the operations their scopes
+		// allow.
+		if err := authz.CheckActorScope(r.Context(), scope); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -69,9 +69,9 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/", nil)
 		req.Header.Set("Authorization", "token badbad")
 		var calledAccessTokensLookup bool
-		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
 			calledAccessTokensLookup = true
-			return 0, errors.New("x")
+			return nil, errors.New("x")
 		}
 		defer func() { db.Mocks = db.MockStores{} }()
 		checkHTTPResponse(t, req, http.StatusUnauthorized, "Invalid access token.\n")
@@ -85,15 +85,12 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 			req, _ := http.NewRequest("GET", "/", nil)
 			req.Header.Set("Authorization", headerValue)
 			var calledAccessTokensLookup bool
-			db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+			db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
 				calledAccessTokensLookup = true
 				if want := "abcdef"; tokenHexEncoded != want {
 					t.Errorf("got %q, want %q", tokenHexEncoded, want)
 				}
-				if want := authz.ScopeUserAll; requiredScope != want {
-					t.Errorf("got %q, want %q", requiredScope, want)
-				}
-				return 123, nil
+				return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeUserAll}}, nil
 			}
 			defer func() { db.Mocks = db.MockStores{} }()
 			checkHTTPResponse(t, req, http.StatusOK, "user 123")
@@ -109,15 +106,12 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 		req.Header.Set("Authorization", "token abcdef")
 		req = req.WithContext(actor.WithActor(context.Background(), &actor.Actor{UID: 456}))
 		var calledAccessTokensLookup bool
-		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
 			calledAccessTokensLookup = true
 			if want := "abcdef"; tokenHexEncoded != want {
 				t.Errorf("got %q, want %q", tokenHexEncoded, want)
 			}
-			if want := authz.ScopeUserAll; requiredScope != want {
-				t.Errorf("got %q, want %q", requiredScope, want)
-			}
-			return 123, nil
+			return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeUserAll}}, nil
 		}
 		defer func() { db.Mocks = db.MockStores{} }()
 		checkHTTPResponse(t, req, http.StatusOK, "user 123")
@@ -141,17 +135,24 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 			} else {
 				req.SetBasicAuth("abcdef", "")
 			}
+			req.RemoteAddr = "203.0.113.1:1234"
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
 			req = req.WithContext(actor.WithActor(context.Background(), &actor.Actor{UID: 456}))
 			var calledAccessTokensLookup bool
-			db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+			db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
 				calledAccessTokensLookup = true
 				if want := "abcdef"; tokenHexEncoded != want {
 					t.Errorf("got %q, want %q", tokenHexEncoded, want)
 				}
-				if want := authz.ScopeUserAll; requiredScope != want {
-					t.Errorf("got %q, want %q", requiredScope, want)
+				// The query string (which may contain the token) must not be recorded.
+				if want := "GET /"; use.Endpoint != want {
+					t.Errorf("got endpoint %q, want %q", use.Endpoint, want)
+				}
+				// The client-controlled X-Forwarded-For header must not be recorded.
+				if want := "203.0.113.1"; use.IP != want {
+					t.Errorf("got IP %q, want %q", use.IP, want)
 				}
-				return 123, nil
+				return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeUserAll}}, nil
 			}
 			defer func() { db.Mocks = db.MockStores{} }()
 			checkHTTPResponse(t, req, http.StatusOK, "user 123")
@@ -165,15 +166,12 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/", nil)
 		req.Header.Set("Authorization", `token-sudo token="abcdef",user="alice"`)
 		var calledAccessTokensLookup bool
-		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
 			calledAccessTokensLookup = true
 			if want := "abcdef"; tokenHexEncoded != want {
 				t.Errorf("got %q, want %q", tokenHexEncoded, want)
 			}
-			if want := authz.ScopeSiteAdminSudo; requiredScope != want {
-				t.Errorf("got %q, want %q", requiredScope, want)
-			}
-			return 123, nil
+			return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeSiteAdminSudo, authz.ScopeUserAll}}, nil
 		}
 		var calledUsersGetByID bool
 		db.Mocks.Users.GetByID = func(ctx context.Context, userID int32) (*types.User, error) {
@@ -210,15 +208,12 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/", nil)
 		req.Header.Set("Authorization", `token-sudo token="abcdef",user="alice"`)
 		var calledAccessTokensLookup bool
-		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
 			calledAccessTokensLookup = true
 			if want := "abcdef"; tokenHexEncoded != want {
 				t.Errorf("got %q, want %q", tokenHexEncoded, want)
 			}
-			if want := authz.ScopeSiteAdminSudo; requiredScope != want {
-				t.Errorf("got %q, want %q", requiredScope, want)
-			}
-			return 123, nil
+			return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeSiteAdminSudo, authz.ScopeUserAll}}, nil
 		}
 		var calledUsersGetByID bool
 		db.Mocks.Users.GetByID = func(ctx context.Context, userID int32) (*types.User, error) {
@@ -242,15 +237,12 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/", nil)
 		req.Header.Set("Authorization", `token-sudo token="abcdef",user="doesntexist"`)
 		var calledAccessTokensLookup bool
-		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
 			calledAccessTokensLookup = true
 			if want := "abcdef"; tokenHexEncoded != want {
 				t.Errorf("got %q, want %q", tokenHexEncoded, want)
 			}
-			if want := authz.ScopeSiteAdminSudo; requiredScope != want {
-				t.Errorf("got %q, want %q", requiredScope, want)
-			}
-			return 123, nil
+			return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeSiteAdminSudo, authz.ScopeUserAll}}, nil
 		}
 		var calledUsersGetByID bool
 		db.Mocks.Users.GetByID = func(ctx context.Context, userID int32) (*types.User, error) {
@@ -280,4 +272,89 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 			t.Error("!calledUsersGetByUsername")
 		}
 	})
+
+	t.Run("non-sudo token without sudo scope used for sudo", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", `token-sudo token="abcdef",user="alice"`)
+		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
+			return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeUserAll}}, nil
+		}
+		defer func() { db.Mocks = db.MockStores{} }()
+		checkHTTPResponse(t, req, http.StatusUnauthorized, "Invalid access token.\n")
+	})
+
+	t.Run("sudo-only token used without sudo", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "token abcdef")
+		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
+			return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeSiteAdminSudo}}, nil
+		}
+		defer func() { db.Mocks = db.MockStores{} }()
+		checkHTTPResponse(t, req, http.StatusUnauthorized, "Invalid access token.\n")
+	})
+
+	t.Run("restricted token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "token abcdef")
+		db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded string, use db.AccessTokenUse) (*db.AccessToken, error) {
+			return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeSearchRead}}, nil
+		}
+		defer func() { db.Mocks = db.MockStores{} }()
+		rr := httptest.NewRecorder()
+		AccessTokenAuthMiddleware(RequireAccessTokenScope(authz.ScopeRepoRead, http.NotFoundHandler())).ServeHTTP(rr, req)
+		if want := http.StatusForbidden; rr.Code != want {
+			t.Errorf("got response status %d, want %d", rr.Code, want)
+		}
+		rr = httptest.NewRecorder()
+		AccessTokenAuthMiddleware(RequireAccessTokenScope(authz.ScopeSearchRead, http.NotFoundHandler())).ServeHTTP(rr, req)
+		if want := http.StatusNotFound; rr.Code != want {
+			t.Errorf("got response status %d, want %d", rr.Code, want)
+		}
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	trustedProxies := []string{"127.0.0.1/32", "10.0.0.0/8", "invalid"}
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		forwardedFor  []string
+		wantClientIP  string
+		noTrustConfig bool
+	}{
+		{name: "no proxy", remoteAddr: "203.0.113.1:1234", wantClientIP: "203.0.113.1"},
+		{name: "untrusted proxy", remoteAddr: "203.0.113.1:1234", forwardedFor: []string{"198.51.100.1"}, wantClientIP: "203.0.113.1"},
+		{name: "no trusted proxies configured", remoteAddr: "127.0.0.1:1234", forwardedFor: []string{"198.51.100.1"}, wantClientIP: "127.0.0.1", noTrustConfig: true},
+		{name: "trusted proxy", remoteAddr: "127.0.0.1:1234", forwardedFor: []string{"198.51.100.1"}, wantClientIP: "198.51.100.1"},
+		{name: "trusted proxy without header", remoteAddr: "127.0.0.1:1234", wantClientIP: "127.0.0.1"},
+		{
+			name:         "forged address before the client's",
+			remoteAddr:   "127.0.0.1:1234",
+			forwardedFor: []string{"192.0.2.1, 198.51.100.1"},
+			wantClientIP: "198.51.100.1",
+		},
+		{
+			name:         "chain of trusted proxies",
+			remoteAddr:   "127.0.0.1:1234",
+			forwardedFor: []string{"198.51.100.1, 10.0.0.2", "10.0.0.3"},
+			wantClientIP: "198.51.100.1",
+		},
+		{name: "invalid header", remoteAddr: "127.0.0.1:1234", forwardedFor: []string{"unknown"}, wantClientIP: "127.0.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			for _, v := range test.forwardedFor {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			proxies := trustedProxies
+			if test.noTrustConfig {
+				proxies = nil
+			}
+			if got := clientIP(req, proxies); got != test.wantClientIP {
+				t.Errorf("got %q, want %q", got, test.wantClientIP)
+			}
+		})
+	}
 }
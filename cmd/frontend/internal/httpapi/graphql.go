@@ -1,12 +1,16 @@
 package httpapi
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 
 	"github.com/graph-gophers/graphql-go/relay"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
 )
 
 var relayHandler = &relay.Handler{Schema: graphqlbackend.GraphQLSchema}
@@ -18,6 +22,29 @@ func serveGraphQL(w http.ResponseWriter, r *http.Request) (err error) {
 		return errors.New("method must be POST")
 	}
 
+	// 🚨 SECURITY: Access tokens with a narrower scope than "user:all" may only perform the
+	// mutations their scopes allow.
+	if actor.FromContext(r.Context()).AccessTokenScopes != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		var params struct {
+			Query         string `json:"query"`
+			OperationName string `json:"operationName"`
+		}
+		if err := json.Unmarshal(body, &params); err != nil {
+			http.Error(w, "invalid GraphQL request", http.StatusBadRequest)
+			return nil
+		}
+		if err := graphqlbackend.CheckMutationScopes(r.Context(), params.Query, params.OperationName); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return nil
+		}
+	}
+
 	relayHandler.ServeHTTP(w, r)
 	return nil
 }
@@ -0,0 +1,23 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+)
+
+func TestServeGraphQL_accessTokenScopes(t *testing.T) {
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "mutation { createThread(input: {title: \"t\", contents: \"c\"}) { id } }"}`))
+	req = req.WithContext(actor.WithActor(req.Context(), &actor.Actor{UID: 1, AccessTokenScopes: []string{authz.ScopeSearchRead}}))
+	rec := httptest.NewRecorder()
+	if err := serveGraphQL(rec, req); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
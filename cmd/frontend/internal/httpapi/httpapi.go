@@ -12,6 +12,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/app/pkg/updatecheck"
 	apirouter "github.com/sourcegraph/sourcegraph/cmd/frontend/internal/httpapi/router"
@@ -37,9 +38,9 @@ func NewHandler(m *mux.Router) http.Handler {
 	m.StrictSlash(true)
 
 	// Set handlers for the installed routes.
-	m.Get(apirouter.RepoShield).Handler(trace.TraceRoute(handler(serveRepoShield)))
+	m.Get(apirouter.RepoShield).Handler(trace.TraceRoute(RequireAccessTokenScope(authz.ScopeRepoRead, handler(serveRepoShield))))
 
-	m.Get(apirouter.RepoRefresh).Handler(trace.TraceRoute(handler(serveRepoRefresh)))
+	m.Get(apirouter.RepoRefresh).Handler(trace.TraceRoute(RequireAccessTokenScope(authz.ScopeRepoRead, handler(serveRepoRefresh))))
 
 	m.Get(apirouter.Telemetry).Handler(trace.TraceRoute(telemetryHandler))
 
@@ -49,15 +50,19 @@ func NewHandler(m *mux.Router) http.Handler {
 
 	m.Get(apirouter.GraphQL).Handler(trace.TraceRoute(handler(serveGraphQL)))
 
+	m.Get(apirouter.SearchStream).Handler(trace.TraceRoute(RequireAccessTokenScope(authz.ScopeSearchRead, handler(serveSearchStream))))
+
+	m.Get(apirouter.UsageStatisticsExport).Handler(trace.TraceRoute(handler(serveUsageStatisticsExport)))
+
 	lsifServerURL, err := url.Parse(lsifServerURLFromEnv)
 	if err != nil {
 		log15.Error("skipping initialization of the LSIF HTTP API because the environment variable LSIF_SERVER_URL is not a valid URL", "parse_error", err, "value", lsifServerURLFromEnv)
 	} else {
 		proxy := httputil.NewSingleHostReverseProxy(lsifServerURL)
-		m.Get(apirouter.LSIFUpload).Handler(trace.TraceRoute(http.HandlerFunc(lsifUploadProxyHandler(proxy))))
-		m.Get(apirouter.LSIFChallenge).Handler(trace.TraceRoute(http.HandlerFunc(lsifChallengeHandler)))
-		m.Get(apirouter.LSIFVerify).Handler(trace.TraceRoute(http.HandlerFunc(lsifVerifyHandler)))
-		m.Get(apirouter.LSIF).Handler(trace.TraceRoute(http.HandlerFunc(lsifProxyHandler(proxy))))
+		m.Get(apirouter.LSIFUpload).Handler(trace.TraceRoute(RequireAccessTokenScope(authz.ScopeLSIFUpload, http.HandlerFunc(lsifUploadProxyHandler(proxy)))))
+		m.Get(apirouter.LSIFChallenge).Handler(trace.TraceRoute(RequireAccessTokenScope(authz.ScopeLSIFUpload, http.HandlerFunc(lsifChallengeHandler))))
+		m.Get(apirouter.LSIFVerify).Handler(trace.TraceRoute(RequireAccessTokenScope(authz.ScopeLSIFUpload, http.HandlerFunc(lsifVerifyHandler))))
+		m.Get(apirouter.LSIF).Handler(trace.TraceRoute(RequireAccessTokenScope(authz.ScopeRepoRead, http.HandlerFunc(lsifProxyHandler(proxy)))))
 	}
 
 	m.Get(apirouter.Registry).Handler(trace.TraceRoute(handler(registry.HandleRegistry)))
@@ -95,6 +100,7 @@ func NewInternalHandler(m *mux.Router) http.Handler {
 	m.Get(apirouter.SavedQueriesGetInfo).Handler(trace.TraceRoute(handler(serveSavedQueriesGetInfo)))
 	m.Get(apirouter.SavedQueriesSetInfo).Handler(trace.TraceRoute(handler(serveSavedQueriesSetInfo)))
 	m.Get(apirouter.SavedQueriesDeleteInfo).Handler(trace.TraceRoute(handler(serveSavedQueriesDeleteInfo)))
+	m.Get(apirouter.SavedQueriesMutedUsers).Handler(trace.TraceRoute(handler(serveSavedQueriesMutedUsers)))
 	m.Get(apirouter.OrgsListUsers).Handler(trace.TraceRoute(handler(serveOrgsListUsers)))
 	m.Get(apirouter.OrgsGetByName).Handler(trace.TraceRoute(handler(serveOrgsGetByName)))
 	m.Get(apirouter.UsersGetByUsername).Handler(trace.TraceRoute(handler(serveUsersGetByUsername)))
@@ -102,9 +108,10 @@ func NewInternalHandler(m *mux.Router) http.Handler {
 	m.Get(apirouter.ExternalURL).Handler(trace.TraceRoute(handler(serveExternalURL)))
 	m.Get(apirouter.CanSendEmail).Handler(trace.TraceRoute(handler(serveCanSendEmail)))
 	m.Get(apirouter.SendEmail).Handler(trace.TraceRoute(handler(serveSendEmail)))
+	m.Get(apirouter.MailReplyTo).Handler(trace.TraceRoute(handler(serveMailReplyTo)))
 	m.Get(apirouter.GitResolveRevision).Handler(trace.TraceRoute(handler(serveGitResolveRevision)))
 	m.Get(apirouter.GitTar).Handler(trace.TraceRoute(handler(serveGitTar)))
-	m.Get(apirouter.Telemetry).Handler(trace.TraceRoute(telemetryHandler))
+	m.Get(apirouter.Telemetry).Handler(trace.TraceRoute(internalTelemetryHandler))
 	m.Get(apirouter.GraphQL).Handler(trace.TraceRoute(handler(serveGraphQL)))
 	m.Get(apirouter.Configuration).Handler(trace.TraceRoute(handler(serveConfiguration)))
 	m.Get(apirouter.SearchConfiguration).Handler(trace.TraceRoute(handler(serveSearchConfiguration)))
@@ -3,6 +3,7 @@ package httpapi
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/mailreply"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/conf"
@@ -292,10 +294,11 @@ func serveSavedQueriesSetInfo(w http.ResponseWriter, r *http.Request) error {
 		return errors.Wrap(err, "Decode")
 	}
 	err = db.QueryRunnerState.Set(r.Context(), &db.SavedQueryInfo{
-		Query:        info.Query,
-		LastExecuted: info.LastExecuted,
-		LatestResult: info.LatestResult,
-		ExecDuration: info.ExecDuration,
+		Query:              info.Query,
+		LastExecuted:       info.LastExecuted,
+		LatestResult:       info.LatestResult,
+		ExecDuration:       info.ExecDuration,
+		ResultFingerprints: info.ResultFingerprints,
 	})
 	if err != nil {
 		return errors.Wrap(err, "SavedQueries.Set")
@@ -320,6 +323,25 @@ func serveSavedQueriesDeleteInfo(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func serveSavedQueriesMutedUsers(w http.ResponseWriter, r *http.Request) error {
+	var key string
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		return errors.Wrap(err, "Decode")
+	}
+	id, err := strconv.ParseInt(key, 10, 32)
+	if err != nil {
+		return errors.Wrap(err, "ParseInt")
+	}
+	userIDs, err := db.SavedSearches.ListEmailMutedUserIDs(r.Context(), int32(id))
+	if err != nil {
+		return errors.Wrap(err, "SavedSearches.ListEmailMutedUserIDs")
+	}
+	if err := json.NewEncoder(w).Encode(userIDs); err != nil {
+		return errors.Wrap(err, "Encode")
+	}
+	return nil
+}
+
 func serveSettingsGetForSubject(w http.ResponseWriter, r *http.Request) error {
 	var subject api.SettingsSubject
 	if err := json.NewDecoder(r.Body).Decode(&subject); err != nil {
@@ -426,6 +448,21 @@ func serveSendEmail(w http.ResponseWriter, r *http.Request) error {
 	return txemail.Send(r.Context(), msg)
 }
 
+func serveMailReplyTo(w http.ResponseWriter, r *http.Request) error {
+	var args api.MailReplyToArgs
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		return errors.Wrap(err, "Decode")
+	}
+	replyTo, err := mailreply.ReplyTo(r.Context(), args.UserID, args.Kind, args.Target)
+	if err != nil {
+		return errors.Wrap(err, "mailreply.ReplyTo")
+	}
+	if err := json.NewEncoder(w).Encode(replyTo); err != nil {
+		return errors.Wrap(err, "Encode")
+	}
+	return nil
+}
+
 func serveGitResolveRevision(w http.ResponseWriter, r *http.Request) error {
 	// used by zoekt-sourcegraph-mirror
 	vars := mux.Vars(r)
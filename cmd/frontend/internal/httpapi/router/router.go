@@ -15,14 +15,18 @@ const (
 
 	Registry = "registry"
 
-	RepoShield  = "repo.shield"
-	RepoRefresh = "repo.refresh"
-	Telemetry   = "telemetry"
+	RepoShield   = "repo.shield"
+	RepoRefresh  = "repo.refresh"
+	Telemetry    = "telemetry"
+	SearchStream = "search.stream"
+
+	UsageStatisticsExport = "usage-statistics.export"
 
 	SavedQueriesListAll    = "internal.saved-queries.list-all"
 	SavedQueriesGetInfo    = "internal.saved-queries.get-info"
 	SavedQueriesSetInfo    = "internal.saved-queries.set-info"
 	SavedQueriesDeleteInfo = "internal.saved-queries.delete-info"
+	SavedQueriesMutedUsers = "internal.saved-queries.muted-users"
 	SettingsGetForSubject  = "internal.settings.get-for-subject"
 	OrgsListUsers          = "internal.orgs.list-users"
 	OrgsGetByName          = "internal.orgs.get-by-name"
@@ -31,6 +35,7 @@ const (
 	ExternalURL            = "internal.app-url"
 	CanSendEmail           = "internal.can-send-email"
 	SendEmail              = "internal.send-email"
+	MailReplyTo            = "internal.mail-reply-to"
 	Extension              = "internal.extension"
 	GitResolveRevision     = "internal.git.resolve-revision"
 	GitTar                 = "internal.git.tar"
@@ -64,6 +69,8 @@ func New(base *mux.Router) *mux.Router {
 	base.Path("/lsif/challenge").Methods("GET").Name(LSIFChallenge)
 	base.Path("/lsif/verify").Methods("GET").Name(LSIFVerify)
 	base.Path("/lsif/{rest:.*}").Methods("POST").Name(LSIF)
+	base.Path("/search/stream").Methods("GET").Name(SearchStream)
+	base.Path("/usage-statistics/export").Methods("GET").Name(UsageStatisticsExport)
 
 	// repo contains routes that are NOT specific to a revision. In these routes, the URL may not contain a revspec after the repo (that is, no "github.com/foo/bar@myrevspec").
 	repoPath := `/repos/` + routevar.Repo
@@ -89,6 +96,7 @@ func NewInternal(base *mux.Router) *mux.Router {
 	base.Path("/saved-queries/get-info").Methods("POST").Name(SavedQueriesGetInfo)
 	base.Path("/saved-queries/set-info").Methods("POST").Name(SavedQueriesSetInfo)
 	base.Path("/saved-queries/delete-info").Methods("POST").Name(SavedQueriesDeleteInfo)
+	base.Path("/saved-queries/muted-users").Methods("POST").Name(SavedQueriesMutedUsers)
 	base.Path("/settings/get-for-subject").Methods("POST").Name(SettingsGetForSubject)
 	base.Path("/orgs/list-users").Methods("POST").Name(OrgsListUsers)
 	base.Path("/orgs/get-by-name").Methods("POST").Name(OrgsGetByName)
@@ -97,6 +105,7 @@ func NewInternal(base *mux.Router) *mux.Router {
 	base.Path("/app-url").Methods("POST").Name(ExternalURL)
 	base.Path("/can-send-email").Methods("POST").Name(CanSendEmail)
 	base.Path("/send-email").Methods("POST").Name(SendEmail)
+	base.Path("/mail-reply-to").Methods("POST").Name(MailReplyTo)
 	base.Path("/extension").Methods("POST").Name(Extension)
 	base.Path("/git/{RepoName:.*}/resolve-revision/{Spec}").Methods("GET").Name(GitResolveRevision)
 	base.Path("/git/{RepoName:.*}/tar/{Commit}").Methods("GET").Name(GitTar)
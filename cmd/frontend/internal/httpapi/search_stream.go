@@ -0,0 +1,66 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+)
+
+// serveSearchStream streams the results of the search query in the "q" URL
+// query parameter as Server-Sent Events
+// (https://html.spec.whatwg.org/multipage/server-sent-events.html). See
+// graphqlbackend.StreamSearch for the names and data of the events.
+func serveSearchStream(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: errors.New("no search query specified (in the q URL query parameter)")}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("streaming is not supported by the HTTP response writer")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // don't let nginx buffer the events
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ew := &eventStreamWriter{w: w, flusher: flusher}
+	graphqlbackend.StreamSearch(r.Context(), query, ew.event)
+
+	// Once the response has started, errors can't be reported through the HTTP status code. Search
+	// errors are sent as events, and write errors mean that the client went away.
+	return nil
+}
+
+// eventStreamWriter writes Server-Sent Events with JSON data.
+type eventStreamWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	err     error // the first error that occurred while writing, if any
+}
+
+// event writes and flushes an event. After an error has occurred, it does
+// nothing.
+func (ew *eventStreamWriter) event(event string, data interface{}) {
+	if ew.err != nil {
+		return
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		ew.err = err
+		return
+	}
+	// The JSON encoding of data contains no newlines, so it fits on a single "data:" line.
+	if _, err := fmt.Fprintf(ew.w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+		ew.err = err
+		return
+	}
+	ew.flusher.Flush()
+}
@@ -0,0 +1,59 @@
+package httpapi
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+)
+
+func TestSearchStream(t *testing.T) {
+	c := newTest()
+
+	var gotQuery string
+	graphqlbackend.MockStreamSearch = func(ctx context.Context, rawQuery string, send func(event string, data interface{})) {
+		gotQuery = rawQuery
+		send(graphqlbackend.SearchStreamEventFileMatches, []*graphqlbackend.SearchStreamFileMatch{{Repository: "r", Path: "a\nb"}})
+		send(graphqlbackend.SearchStreamEventDone, &graphqlbackend.SearchStreamSummary{ResultCount: 1})
+	}
+	defer func() { graphqlbackend.MockStreamSearch = nil }()
+
+	resp, err := c.GetOK("/search/stream?q=foo+bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "foo bar"; gotQuery != want {
+		t.Errorf("got query %q, want %q", gotQuery, want)
+	}
+	if got, want := resp.Header.Get("Content-Type"), "text/event-stream"; got != want {
+		t.Errorf("got Content-Type %q, want %q", got, want)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `event: filematches
+data: [{"repository":"r","commit":"","path":"a\nb","url":"","lineMatches":null,"limitHit":false}]
+
+event: done
+data: {"limitHit":false,"resultCount":1,"repositories":null,"repositoriesSearched":null,"indexedRepositoriesSearched":null,"cloning":null,"missing":null,"timedout":null,"indexUnavailable":false,"elapsedMilliseconds":0}
+
+`
+	if string(body) != want {
+		t.Errorf("got body\n%s\nwant\n%s", body, want)
+	}
+}
+
+func TestSearchStream_noQuery(t *testing.T) {
+	c := newTest()
+
+	resp, err := c.Get("/search/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -11,37 +11,86 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/usagestats"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
 	"github.com/sourcegraph/sourcegraph/pkg/env"
 	"github.com/sourcegraph/sourcegraph/pkg/eventlogger"
 )
 
-var telemetryHandler http.Handler
+// telemetryHandler serves telemetry on the external API, and internalTelemetryHandler on the
+// internal API (used by backend services through eventlogger.LogEvent).
+var telemetryHandler, internalTelemetryHandler http.Handler
 
 func init() {
 	if envvar.SourcegraphDotComMode() {
-		telemetryHandler = &httputil.ReverseProxy{
+		proxy := &httputil.ReverseProxy{
 			Director: func(req *http.Request) {
 				// Removed due to our event logging ETL pipeline sunsetting schedule.
 				// TODO(Dan): update with new logging URL.
 			},
 			ErrorLog: log.New(env.DebugOut, "telemetry proxy: ", log.LstdFlags),
 		}
+		telemetryHandler, internalTelemetryHandler = proxy, proxy
 	} else {
-		telemetryHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			var tr eventlogger.TelemetryRequest
-			err := json.NewDecoder(r.Body).Decode(&tr)
+		telemetryHandler = serveTelemetry(false)
+		internalTelemetryHandler = serveTelemetry(true)
+	}
+}
+
+const (
+	// maxTelemetryRequestSize is the maximum size of a telemetry request body.
+	maxTelemetryRequestSize = 64 * 1024
+
+	// maxTelemetryArgumentSize is the maximum size of the argument of an event sent to the
+	// external API. Larger arguments are not recorded.
+	maxTelemetryArgumentSize = 2 * 1024
+)
+
+// serveTelemetry returns a handler that records telemetry events in the event log.
+//
+// 🚨 SECURITY: Only backend services are trusted to log events on behalf of the user in the
+// request body (trusted). Otherwise, the event is attributed to the actor of the request, so that
+// callers of the external API cannot forge events of other users. Because the external API is
+// accessible without authentication even on private instances, it also records only events of
+// authenticated users with known names, so that anonymous callers can't fill the event log.
+func serveTelemetry(trusted bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tr eventlogger.TelemetryRequest
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTelemetryRequestSize)).Decode(&tr)
+		if err != nil {
+			log15.Error("telemetryHandler: Decode(2)", "error", err)
+			http.Error(w, "invalid telemetry request", http.StatusBadRequest)
+			return
+		}
+
+		var argument json.RawMessage
+		if tr.Payload != nil && len(tr.Payload.Events) > 0 && tr.Payload.Events[0].Context != nil {
+			argument = tr.Payload.Events[0].Context.Backend
+		}
+		userID := tr.UserID
+		if !trusted {
+			userID = actor.FromContext(r.Context()).UID
+			if userID == 0 || !usagestats.IsKnownEvent(tr.EventLabel) {
+				tr.EventLabel = "" // ignore the event
+			}
+			if len(argument) > maxTelemetryArgumentSize {
+				argument = nil
+			}
+		}
+
+		if tr.EventLabel != "" {
+			err = usagestats.LogBackendEvent(r.Context(), userID, tr.EventLabel, argument)
 			if err != nil {
-				log15.Error("telemetryHandler: Decode(2)", "error", err)
+				log15.Error("telemetryHandler: usagestats.LogBackendEvent", "error", err)
 			}
-			if tr.UserID != 0 && tr.EventLabel == "SavedSearchEmailNotificationSent" {
-				err = usagestats.LogActivity(true, tr.UserID, "", "STAGEVERIFY")
-				if err != nil {
-					log15.Error("telemetryHandler: usagestats.LogActivity", "error", err)
-				}
+		}
+		if userID != 0 && tr.EventLabel == "SavedSearchEmailNotificationSent" {
+			err = usagestats.LogActivity(true, userID, "", "STAGEVERIFY")
+			if err != nil {
+				log15.Error("telemetryHandler: usagestats.LogActivity", "error", err)
 			}
+		}
 
-			fmt.Fprintln(w, "event-level telemetry is disabled")
-			w.WriteHeader(http.StatusNoContent)
-		})
-	}
+		fmt.Fprintln(w, "event-level telemetry is disabled")
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
@@ -0,0 +1,87 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+)
+
+func TestServeTelemetry(t *testing.T) {
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	var logged []*db.EventLog
+	db.Mocks.EventLogs.Insert = func(ctx context.Context, e *db.EventLog) error {
+		logged = append(logged, e)
+		return nil
+	}
+
+	request := func(label, argument string) string {
+		return fmt.Sprintf(`{"UserID": 2, "EventLabel": %q, "Payload": {"events": [{"ctx": {"backend": %s}}]}}`, label, argument)
+	}
+	largeArgument := `"` + strings.Repeat("x", maxTelemetryArgumentSize) + `"`
+
+	tests := map[string]struct {
+		trusted    bool
+		actorUID   int32
+		body       string
+		wantStatus int
+		want       []*db.EventLog
+	}{
+		"external API, anonymous": {
+			body:       request("ServerUpdateCheck", "{}"),
+			wantStatus: http.StatusOK,
+		},
+		"external API, authenticated": {
+			actorUID:   3,
+			body:       request("ServerUpdateCheck", `{"a":1}`),
+			wantStatus: http.StatusOK,
+			want:       []*db.EventLog{{Name: "ServerUpdateCheck", UserID: 3, Source: db.EventSourceBackend, Argument: json.RawMessage(`{"a":1}`)}},
+		},
+		"external API, unknown event": {
+			actorUID:   3,
+			body:       request("Foo", "{}"),
+			wantStatus: http.StatusOK,
+		},
+		"external API, large argument": {
+			actorUID:   3,
+			body:       request("ServerUpdateCheck", largeArgument),
+			wantStatus: http.StatusOK,
+			want:       []*db.EventLog{{Name: "ServerUpdateCheck", UserID: 3, Source: db.EventSourceBackend}},
+		},
+		"external API, large request": {
+			actorUID:   3,
+			body:       request("ServerUpdateCheck", `"`+strings.Repeat("x", maxTelemetryRequestSize)+`"`),
+			wantStatus: http.StatusBadRequest,
+		},
+		"internal API": {
+			trusted:    true,
+			body:       request("Foo", largeArgument),
+			wantStatus: http.StatusOK,
+			want:       []*db.EventLog{{Name: "Foo", UserID: 2, Source: db.EventSourceBackend, Argument: json.RawMessage(largeArgument)}},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			logged = nil
+			req := httptest.NewRequest("POST", "/telemetry", strings.NewReader(test.body))
+			req = req.WithContext(actor.WithActor(req.Context(), actor.FromUser(test.actorUID)))
+			rec := httptest.NewRecorder()
+			serveTelemetry(test.trusted).ServeHTTP(rec, req)
+
+			if rec.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", rec.Code, test.wantStatus)
+			}
+			if !reflect.DeepEqual(logged, test.want) {
+				t.Errorf("got logged events %+v, want %+v", logged, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/usagestats"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+)
+
+// serveUsageStatisticsExport streams the site's usage statistics as CSV or
+// JSON lines (in the "format" URL query parameter, "csv" by default), for
+// importing into BI tools. The "data" URL query parameter selects the exported
+// statistics: "daily" for the activity of the site's users on each of the last
+// "days" days (all days that are kept by default), or "users" for the
+// activity of each user.
+func serveUsageStatisticsExport(w http.ResponseWriter, r *http.Request) error {
+	// 🚨 SECURITY: Only site admins may export usage statistics, which
+	// include the usernames and activity of all users.
+	if err := backend.CheckCurrentUserIsSiteAdmin(r.Context()); err != nil {
+		return &errcode.HTTPErr{Status: http.StatusUnauthorized, Err: err}
+	}
+	if envvar.SourcegraphDotComMode() {
+		return &errcode.HTTPErr{Status: http.StatusNotFound, Err: errors.New("usage statistics are not available on sourcegraph.com")}
+	}
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	var contentType string
+	switch format {
+	case "", usagestats.ExportCSV:
+		format = usagestats.ExportCSV
+		contentType = "text/csv; charset=utf-8"
+	case usagestats.ExportJSONLines:
+		contentType = "application/x-ndjson"
+	default:
+		return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: fmt.Errorf("invalid format %q (must be %q or %q)", format, usagestats.ExportCSV, usagestats.ExportJSONLines)}
+	}
+
+	var export func() error
+	data := q.Get("data")
+	switch data {
+	case "daily":
+		var days int
+		if s := q.Get("days"); s != "" {
+			var err error
+			days, err = strconv.Atoi(s)
+			if err != nil || days <= 0 {
+				return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: fmt.Errorf("invalid number of days %q", s)}
+			}
+		}
+		export = func() error { return usagestats.ExportDaily(w, format, days) }
+	case "users":
+		export = func() error { return usagestats.ExportUsers(r.Context(), w, format) }
+	default:
+		return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: fmt.Errorf("invalid data %q (must be \"daily\" or \"users\")", data)}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=usage-statistics-%s.%s", data, format))
+	w.Header().Set("X-Accel-Buffering", "no") // don't let nginx buffer the export
+	return export()
+}
@@ -0,0 +1,42 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestUsageStatisticsExport_invalid(t *testing.T) {
+	c := newTest()
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	var siteAdmin bool
+	db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: siteAdmin}, nil
+	}
+
+	tests := []struct {
+		url        string
+		siteAdmin  bool
+		wantStatus int
+	}{
+		{"/usage-statistics/export?data=users", false, http.StatusUnauthorized},
+		{"/usage-statistics/export?data=users&format=xml", true, http.StatusBadRequest},
+		{"/usage-statistics/export?data=events", true, http.StatusBadRequest},
+		{"/usage-statistics/export", true, http.StatusBadRequest},
+		{"/usage-statistics/export?data=daily&days=-1", true, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		siteAdmin = test.siteAdmin
+		resp, err := c.Get(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.wantStatus {
+			t.Errorf("%s (site admin %v): got status %d, want %d", test.url, test.siteAdmin, resp.StatusCode, test.wantStatus)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package discussions
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/mailreply"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+// mailReplyKind is the kind of the email notifications about discussion
+// threads. The target of a reply is the ID of the thread.
+const mailReplyKind = db.MailReplyKindDiscussionThread
+
+func init() {
+	mailreply.RegisterHandler(mailReplyKind, mailreply.HandlerFunc(handleMailReply))
+}
+
+// handleMailReply adds the contents of an email reply to a notification about
+// a thread as a new comment to the thread.
+func handleMailReply(ctx context.Context, r *mailreply.Reply) error {
+	threadID, err := strconv.ParseInt(r.Target, 10, 64)
+	if err != nil {
+		return mailreply.ErrInvalidReply
+	}
+
+	// Replies to notifications about threads that have been deleted since are
+	// ignored.
+	if _, err := db.DiscussionThreads.Get(ctx, threadID); err != nil {
+		if _, ok := err.(*db.ErrThreadNotFound); ok {
+			return mailreply.ErrInvalidReply
+		}
+		return err
+	}
+
+	_, err = InsecureAddCommentToThread(ctx, &types.DiscussionComment{
+		ThreadID:     threadID,
+		AuthorUserID: r.UserID,
+		Contents:     r.Contents,
+	})
+	if _, ok := err.(*db.ErrThreadNotFound); ok {
+		return mailreply.ErrInvalidReply // deleted while adding the comment
+	}
+	return err
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"html/template"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/discussions/mentions"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/mailreply"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/errcode"
@@ -152,15 +154,14 @@ func (n *notifier) notifyUsername(ctx context.Context, username string) error {
 		// 🚨 SECURITY: It is crucial that the user ID and thread ID passed here
 		// are correct, as the token effectively grants anonymous posting in the
 		// specified thread on the specified user's behalf.
-		secureToken, err := db.DiscussionMailReplyTokens.Generate(ctx, user.ID, n.thread.ID)
+		var err error
+		replyTo, err = mailreply.ReplyTo(ctx, user.ID, mailReplyKind, strconv.FormatInt(n.thread.ID, 10))
 		if err != nil {
-			return errors.Wrap(err, "DiscussionMailReplyTokens.Generate")
+			return errors.Wrap(err, "mailreply.ReplyTo")
 		}
 
 		conf := conf.Get()
 		emailParts := strings.Split(conf.EmailImap.Username, "@")
-		secureReplyTo := fmt.Sprintf("%s+%s@%s", emailParts[0], secureToken, emailParts[1])
-		replyTo = &secureReplyTo
 
 		// Generate a unique message ID. This is used by e.g. Gmail to uniquely
 		// identify this email message and so that we can reference it in later
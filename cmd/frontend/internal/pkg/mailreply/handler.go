@@ -0,0 +1,112 @@
+package mailreply
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+)
+
+// Reply is an email reply to a notification, sent to the reply-to address
+// returned by ReplyTo.
+type Reply struct {
+	// UserID and Target are the user and target that the reply-to address
+	// was generated for.
+	UserID int32
+	Target string
+
+	Subject string
+
+	// Contents is the text of the reply, without the quoted notification and
+	// surrounding whitespace. It is never empty.
+	Contents string
+}
+
+// A Handler acts on the email replies to notifications of one kind.
+type Handler interface {
+	// HandleReply acts on the reply as the user that it was sent by. If the
+	// reply can't be acted on (for example, because it is not a command
+	// that the handler understands), it returns ErrInvalidReply. Replies
+	// for which another error is returned are handled again later.
+	HandleReply(ctx context.Context, r *Reply) error
+}
+
+// HandlerFunc is an adapter to allow the use of ordinary functions as
+// Handlers.
+type HandlerFunc func(ctx context.Context, r *Reply) error
+
+// HandleReply calls f(ctx, r).
+func (f HandlerFunc) HandleReply(ctx context.Context, r *Reply) error {
+	return f(ctx, r)
+}
+
+// ErrInvalidReply is returned by a Handler for a reply that it can't act on.
+// The reply is then ignored.
+var ErrInvalidReply = errors.New("invalid reply")
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = map[string]Handler{}
+)
+
+// RegisterHandler registers the handler of the replies to notifications of
+// the given kind (such as "discussion-thread"). It is typically called from
+// an init function of the package that sends the notifications, and panics if
+// a handler is already registered for the kind.
+func RegisterHandler(kind string, h Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	if _, ok := handlers[kind]; ok {
+		panic(fmt.Sprintf("mailreply: handler of kind %q already registered", kind))
+	}
+	handlers[kind] = h
+}
+
+func handlerOf(kind string) Handler {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	return handlers[kind]
+}
+
+// ReplyTo returns the reply-to address of a notification of the given kind
+// sent to the user, so that replies to it are passed to the handler of kind
+// along with target. It returns nil if email replies can't be read (because
+// email.imap is not configured).
+//
+// 🚨 SECURITY: The caller must ensure the address is ONLY sent to the given
+// user, as anyone with it can act on the target as that user (as the handler
+// allows) by sending an email to it.
+func ReplyTo(ctx context.Context, userID int32, kind, target string) (*string, error) {
+	if !conf.CanReadEmail() {
+		return nil, nil
+	}
+	if handlerOf(kind) == nil {
+		return nil, fmt.Errorf("no mail reply handler of kind %q", kind)
+	}
+
+	emailParts := strings.Split(conf.Get().EmailImap.Username, "@")
+	if len(emailParts) != 2 {
+		return nil, errors.New("email.imap username is not an email address")
+	}
+
+	token, err := db.MailReplyTokens.Generate(ctx, userID, kind, target)
+	if err != nil {
+		return nil, errors.Wrap(err, "MailReplyTokens.Generate")
+	}
+	replyTo := fmt.Sprintf("%s+%s@%s", emailParts[0], token, emailParts[1])
+	return &replyTo, nil
+}
+
+// replyCommand returns the first word of the reply's contents in lower case,
+// for handlers of replies that are commands (such as "unsubscribe").
+func replyCommand(r *Reply) string {
+	fields := strings.Fields(r.Contents)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
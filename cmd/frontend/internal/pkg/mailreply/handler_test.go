@@ -0,0 +1,236 @@
+package mailreply
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+)
+
+// newTestMailReader starts a local IMAP server standing in for the configured
+// one and returns a reader of its INBOX, which contains only the given unread
+// messages.
+func newTestMailReader(t *testing.T, messages ...string) (reader *MailReader, done func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := server.New(memory.New())
+	s.AllowInsecureAuth = true
+	go s.Serve(l)
+
+	c, err := client.Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Login("username", "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the example message that the stand-in starts with.
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	seqSet := new(imap.SeqSet)
+	seqSet.AddRange(1, 0) // all messages
+	if err := c.Store(seqSet, imap.FormatFlagsOp(imap.AddFlags, true), []interface{}{imap.DeletedFlag}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Expunge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range messages {
+		if err := c.Append("INBOX", nil, time.Now(), bytes.NewBufferString(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reader, err = newMailReader(c, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reader, func() {
+		reader.Close()
+		s.Close()
+	}
+}
+
+// testReply returns a plain text email replying with the given contents to the
+// reply-to address with the given token.
+func testReply(token, contents string) string {
+	return fmt.Sprintf("From: alice@example.com\r\nTo: notifications+%s@example.com\r\nSubject: Re: notification\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", token, contents)
+}
+
+// searchFlag returns the sequence numbers of the messages with (or without)
+// the given flag.
+func searchFlag(t *testing.T, reader *MailReader, flag string, with bool) []uint32 {
+	criteria := imap.NewSearchCriteria()
+	if with {
+		criteria.WithFlags = []string{flag}
+	} else {
+		criteria.WithoutFlags = []string{flag}
+	}
+	seqNums, err := reader.client.Search(criteria)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return seqNums
+}
+
+func TestHandleUnread(t *testing.T) {
+	defer func(old map[string]Handler) { handlers = old }(handlers)
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	var replies []Reply
+	handlers = map[string]Handler{
+		"test": HandlerFunc(func(ctx context.Context, r *Reply) error {
+			replies = append(replies, *r)
+			switch r.Contents {
+			case "invalid":
+				return ErrInvalidReply
+			case "fail":
+				return errors.New("fail")
+			}
+			return nil
+		}),
+	}
+	db.Mocks.MailReplyTokens.Get = func(ctx context.Context, token string) (*db.MailReplyToken, error) {
+		switch token {
+		case "valid":
+			return &db.MailReplyToken{UserID: 1, Kind: "test", Target: "t"}, nil
+		case "unknownkind":
+			return &db.MailReplyToken{UserID: 1, Kind: "unknown", Target: "t"}, nil
+		}
+		return nil, db.ErrInvalidToken
+	}
+
+	notReply := "From: alice@example.com\r\nTo: notifications@example.com\r\nSubject: Hi\r\n\r\nNot a reply\r\n"
+	quoted := "Hello\r\n\r\nOn Wed, Aug 29, 2018 at 3:22 AM, Sourcegraph <notifications@example.com>\r\nwrote:\r\n\r\n> notification\r\n"
+	reader, done := newTestMailReader(t,
+		notReply,                                // 1
+		testReply("valid", quoted),              // 2
+		testReply("invalidtoken", "Hello"),      // 3
+		testReply("unknownkind", "Hello"),       // 4
+		testReply("valid", "  "),                // 5
+		testReply("valid", "invalid"),           // 6
+		testReply("valid", "fail"),              // 7
+		testReply("valid", "\r\nWorld\r\n\r\n"), // 8
+	)
+	defer done()
+
+	if err := handleUnread(context.Background(), reader); err != nil {
+		t.Fatal(err)
+	}
+
+	wantReplies := []Reply{
+		{UserID: 1, Target: "t", Subject: "Re: notification", Contents: "Hello"},
+		{UserID: 1, Target: "t", Subject: "Re: notification", Contents: "invalid"},
+		{UserID: 1, Target: "t", Subject: "Re: notification", Contents: "fail"},
+		{UserID: 1, Target: "t", Subject: "Re: notification", Contents: "World"},
+	}
+	if !reflect.DeepEqual(replies, wantReplies) {
+		t.Errorf("got replies %+v, want %+v", replies, wantReplies)
+	}
+
+	// The message that is not a reply and the reply whose handler failed are
+	// left unread, so that the failed reply is handled again later.
+	if unread, want := searchFlag(t, reader, imap.SeenFlag, false), []uint32{1, 7}; !reflect.DeepEqual(unread, want) {
+		t.Errorf("got unread messages %v, want %v", unread, want)
+	}
+	if deleted, want := searchFlag(t, reader, imap.DeletedFlag, true), []uint32{2, 3, 4, 5, 6, 8}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("got deleted messages %v, want %v", deleted, want)
+	}
+}
+
+func TestHandleUnread_savedSearch(t *testing.T) {
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	type mute struct {
+		id, userID int32
+		until      *time.Time
+	}
+	var mutes []mute
+	db.Mocks.SavedSearches.MuteEmailNotifications = func(ctx context.Context, id, userID int32, until *time.Time) error {
+		if id == 404 {
+			return db.ErrSavedSearchNotFound
+		}
+		mutes = append(mutes, mute{id, userID, until})
+		return nil
+	}
+	db.Mocks.MailReplyTokens.Get = func(ctx context.Context, token string) (*db.MailReplyToken, error) {
+		return &db.MailReplyToken{UserID: 1, Kind: KindSavedSearch, Target: token}, nil
+	}
+
+	reader, done := newTestMailReader(t,
+		testReply("2", "Mute"),
+		testReply("3", "unsubscribe please"),
+		testReply("4", "Looks good"),
+		testReply("404", "mute"), // saved search deleted since the notification
+	)
+	defer done()
+
+	if err := handleUnread(context.Background(), reader); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mutes) != 2 {
+		t.Fatalf("got mutes %+v, want 2", mutes)
+	}
+	if m := mutes[0]; m.id != 2 || m.userID != 1 || m.until == nil || time.Until(*m.until) < 6*24*time.Hour {
+		t.Errorf("got mute %+v, want saved search 2 muted for a week", m)
+	}
+	if m := mutes[1]; m.id != 3 || m.userID != 1 || m.until != nil {
+		t.Errorf("got mute %+v, want saved search 3 muted indefinitely", m)
+	}
+	if unread := searchFlag(t, reader, imap.SeenFlag, false); len(unread) != 0 {
+		t.Errorf("got unread messages %v, want none", unread)
+	}
+}
+
+func TestHandleUnread_accessToken(t *testing.T) {
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	var deleted []int64
+	db.Mocks.AccessTokens.DeleteByID = func(id int64, subjectUserID int32) error {
+		if subjectUserID != 1 {
+			t.Errorf("got subject user %d, want 1", subjectUserID)
+		}
+		if id == 404 {
+			return db.ErrAccessTokenNotFound
+		}
+		deleted = append(deleted, id)
+		return nil
+	}
+	db.Mocks.MailReplyTokens.Get = func(ctx context.Context, token string) (*db.MailReplyToken, error) {
+		return &db.MailReplyToken{UserID: 1, Kind: KindAccessToken, Target: token}, nil
+	}
+
+	reader, done := newTestMailReader(t,
+		testReply("5", "REVOKE"),
+		testReply("6", "keep it"),
+		testReply("404", "revoke"),
+	)
+	defer done()
+
+	if err := handleUnread(context.Background(), reader); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int64{5}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("got deleted access tokens %v, want %v", deleted, want)
+	}
+	if unread := searchFlag(t, reader, imap.SeenFlag, false); len(unread) != 0 {
+		t.Errorf("got unread messages %v, want none", unread)
+	}
+}
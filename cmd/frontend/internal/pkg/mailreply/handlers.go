@@ -0,0 +1,82 @@
+package mailreply
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+)
+
+// Kinds of notifications whose replies are handled by this package.
+const (
+	// KindSavedSearch is the kind of the notifications about new results of
+	// a saved search. The target is the ID of the saved search.
+	KindSavedSearch = db.MailReplyKindSavedSearch
+
+	// KindAccessToken is the kind of the notifications about an access
+	// token. The target is the ID of the access token.
+	KindAccessToken = "access-token"
+)
+
+// savedSearchMuteDuration is how long a "mute" reply to a saved search
+// notification mutes the saved search's notifications for.
+const savedSearchMuteDuration = 7 * 24 * time.Hour
+
+func init() {
+	RegisterHandler(KindSavedSearch, HandlerFunc(handleSavedSearchReply))
+	RegisterHandler(KindAccessToken, HandlerFunc(handleAccessTokenReply))
+}
+
+// handleSavedSearchReply handles a reply to a saved search notification. The
+// reply "mute" stops the user from receiving email notifications about the
+// saved search for a week, and "unsubscribe" stops them indefinitely.
+func handleSavedSearchReply(ctx context.Context, r *Reply) error {
+	id, err := strconv.ParseInt(r.Target, 10, 32)
+	if err != nil {
+		return ErrInvalidReply
+	}
+
+	var until *time.Time
+	switch replyCommand(r) {
+	case "mute":
+		t := time.Now().Add(savedSearchMuteDuration)
+		until = &t
+	case "unsubscribe":
+	default:
+		return ErrInvalidReply
+	}
+
+	err = db.SavedSearches.MuteEmailNotifications(ctx, int32(id), r.UserID, until)
+	if err == db.ErrSavedSearchNotFound {
+		return ErrInvalidReply // deleted since the notification was sent
+	}
+	if err != nil {
+		return errors.Wrap(err, "SavedSearches.MuteEmailNotifications")
+	}
+	return nil
+}
+
+// handleAccessTokenReply handles a reply to an access token notification. The
+// reply "revoke" deletes the access token.
+func handleAccessTokenReply(ctx context.Context, r *Reply) error {
+	id, err := strconv.ParseInt(r.Target, 10, 64)
+	if err != nil {
+		return ErrInvalidReply
+	}
+	if replyCommand(r) != "revoke" {
+		return ErrInvalidReply
+	}
+
+	// 🚨 SECURITY: Only the access tokens of the user that the reply-to
+	// address was generated for can be deleted.
+	err = db.AccessTokens.DeleteByID(ctx, id, r.UserID)
+	if err == db.ErrAccessTokenNotFound {
+		return ErrInvalidReply // already deleted
+	}
+	if err != nil {
+		return errors.Wrap(err, "AccessTokens.DeleteByID")
+	}
+	return nil
+}
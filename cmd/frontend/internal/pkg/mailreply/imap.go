@@ -150,16 +150,21 @@ func NewMailReader() (*MailReader, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "DialTLS")
 	}
+	return newMailReader(c, conf.EmailImap.Username, conf.EmailImap.Password)
+}
 
+// newMailReader returns a new reader that reads mail from the INBOX of the
+// IMAP server that c is connected to, logging in first if a username is given.
+func newMailReader(c *client.Client, username, password string) (*MailReader, error) {
 	// Login, if needed.
-	if conf.EmailImap.Username != "" {
-		if err := c.Login(conf.EmailImap.Username, conf.EmailImap.Password); err != nil {
+	if username != "" {
+		if err := c.Login(username, password); err != nil {
 			return nil, errors.Wrap(err, "Login")
 		}
 	}
 
 	readOnly := false
-	_, err = c.Select("INBOX", readOnly)
+	_, err := c.Select("INBOX", readOnly)
 	if err != nil {
 		return nil, errors.Wrap(err, "Select INBOX")
 	}
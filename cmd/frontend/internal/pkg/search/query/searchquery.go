@@ -20,6 +20,7 @@ const (
 	FieldType               = "type"
 	FieldRepoHasFile        = "repohasfile"
 	FieldRepoHasCommitAfter = "repohascommitafter"
+	FieldPatternType        = "patterntype"
 
 	// For diff and commit search only:
 	FieldBefore    = "before"
@@ -42,7 +43,7 @@ var (
 
 	conf = types.Config{
 		FieldTypes: map[string]types.FieldType{
-			FieldDefault:   {Literal: types.RegexpType, Quoted: types.StringType},
+			FieldDefault:   {Literal: types.RegexpType, Quoted: types.StringType, Negatable: true},
 			FieldCase:      {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
 			FieldRepo:      regexpNegatableFieldType,
 			FieldRepoGroup: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
@@ -54,6 +55,7 @@ var (
 
 			FieldRepoHasFile:        regexpNegatableFieldType,
 			FieldRepoHasCommitAfter: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldPatternType:        {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 
 			FieldBefore:    stringFieldType,
 			FieldAfter:     stringFieldType,
@@ -16,6 +16,7 @@ type parser struct {
 	tokens      []Token
 	pos         int
 	allowErrors bool
+	explicitAnd bool // whether an "AND" keyword was parsed
 }
 
 // context holds settings active within a given scope during parsing.
@@ -28,11 +29,28 @@ type context struct {
 //
 // BNF-ish query syntax:
 //
-//   exprList  := {exprSign} | exprSign (sep exprSign)*
-//   exprSign  := {"-"} expr
-//   expr      := fieldExpr | lit | quoted | pattern
-//   fieldExpr := lit ":" value
-//   value     := lit | quoted
+//	exprList  := {orExpr}
+//	orExpr    := andExpr (sep "OR" sep andExpr)*
+//	andExpr   := exprSign (sep ["AND" sep] exprSign)*
+//	exprSign  := {"-" | "NOT" sep} primary
+//	primary   := "(" orExpr ")" | expr
+//	expr      := fieldExpr | lit | quoted | pattern
+//	fieldExpr := lit ":" value
+//	value     := lit | quoted
+//
+// The operator keywords must be written in uppercase, so that the words "and",
+// "or", and "not" remain ordinary search terms. A keyword that is not followed
+// by an operand (as in "a OR") is parsed as an ordinary literal. Implicit
+// concatenation of expressions is equivalent to "AND". A "(" only begins a
+// group if it is preceded by whitespace, "-", "(", or the start of the query
+// and has a matching ")" that is followed by whitespace, ")", or the end of the
+// query, so that parentheses within terms (as in "foo(", "()", or the regexp
+// "(a|b)c") remain part of literals.
+//
+// A top-level "and" expression is returned as the list of its operands, so
+// queries without operators or groups have the same parse tree as they did
+// before operators were introduced. Query.ExplicitAnd records whether the
+// "AND" keyword was used.
 func Parse(input string) (*Query, error) {
 	tokens := Scan(input)
 	p := parser{tokens: tokens}
@@ -41,7 +59,7 @@ func Parse(input string) (*Query, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Query{Expr: exprs, Input: input}, nil
+	return &Query{Expr: exprs, Input: input, ExplicitAnd: p.explicitAnd}, nil
 }
 
 // ParseAllowingErrors works like Parse except that any errors are
@@ -54,7 +72,7 @@ func ParseAllowingErrors(input string) *Query {
 	if err != nil {
 		panic(fmt.Sprintf("(bug) error returned by parseExprList despite allowErrors=true (this should never happen): %v", err))
 	}
-	return &Query{Expr: exprs, Input: input}
+	return &Query{Expr: exprs, Input: input, ExplicitAnd: p.explicitAnd}
 }
 
 // peek returns the next token without consuming it. Peeking beyond the end of
@@ -88,51 +106,194 @@ func (p *parser) parseExprList(ctx context) (exprList []*Expr, err error) {
 		return nil, nil
 	}
 
+	expr, err := p.parseOr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Type != TokenEOF {
+		// Only reachable if the scanner emitted an unbalanced TokenRParen.
+		if p.allowErrors {
+			return append(exprListOf(expr), p.errorExpr(p.next())), nil
+		}
+		return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want EOF", tok.Type)}
+	}
+	return exprListOf(expr), nil
+}
+
+// exprListOf returns the top-level expression list for expr.
+func exprListOf(expr *Expr) []*Expr {
+	switch {
+	case expr == nil:
+		return nil
+	case expr.Op == OperatorAnd && !expr.Not:
+		return expr.Operands
+	default:
+		return []*Expr{expr}
+	}
+}
+
+// parseOr parses a (possibly empty) list of "AND" expressions separated by the
+// "OR" keyword. It returns nil if there are no expressions.
+func (p *parser) parseOr(ctx context) (*Expr, error) {
+	var operands []*Expr
+	for {
+		expr, err := p.parseAnd(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if expr != nil {
+			operands = append(operands, expr)
+		}
+		if len(operands) == 0 || !p.atKeyword("OR") {
+			break
+		}
+		p.next()
+	}
+	return newOperatorExpr(OperatorOr, operands), nil
+}
+
+// parseAnd parses a (possibly empty) list of expressions that are separated by
+// separators or the "AND" keyword. It stops at EOF, at the end of the current
+// group, or at an "OR" keyword. It returns nil if there are no expressions.
+func (p *parser) parseAnd(ctx context) (*Expr, error) {
+	var operands []*Expr
 	for {
+		p.skipSeps()
 		tok := p.peek()
-		if tok.Type == TokenEOF {
+		if tok.Type == TokenEOF || tok.Type == TokenRParen {
 			break
 		}
-		if tok.Type == TokenSep {
-			p.next()
-			continue
+		if len(operands) > 0 {
+			if p.atKeyword("OR") {
+				break
+			}
+			if p.atKeyword("AND") {
+				p.next()
+				p.explicitAnd = true
+				continue
+			}
 		}
 
 		expr, err := p.parseExprSign(ctx)
 		if err != nil {
 			return nil, err
 		}
-		exprList = append(exprList, expr)
+		operands = append(operands, expr)
 	}
-
-	return exprList, nil
+	return newOperatorExpr(OperatorAnd, operands), nil
 }
 
-// exprSign := {"-"} expr
 func (p *parser) parseExprSign(ctx context) (*Expr, error) {
+	if p.atKeyword("NOT") {
+		p.next()
+		p.skipSeps()
+		expr, err := p.parseExprSign(ctx)
+		if err != nil {
+			return nil, err
+		}
+		expr.Not = !expr.Not
+		return expr, nil
+	}
+
 	tok := p.next()
 	switch tok.Type {
 	case TokenMinus:
-		// consume token
 	default:
 		tok = Token{Type: TokenEOF}
 		p.backup()
 	}
 
-	expr, err := p.parseExpr(ctx)
+	expr, err := p.parsePrimary(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	switch tok.Type {
 	case TokenMinus:
-		expr.Not = true
+		expr.Not = !expr.Not
 	}
 
 	return expr, nil
 }
 
-// expr := exprField | lit | quoted | pattern
+func (p *parser) parsePrimary(ctx context) (*Expr, error) {
+	if p.peek().Type != TokenLParen {
+		return p.parseExpr(ctx)
+	}
+
+	lparen := p.next()
+	expr, err := p.parseOr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok.Type != TokenRParen {
+		if p.allowErrors {
+			p.backup()
+			if expr == nil {
+				return &Expr{Pos: lparen.Pos, Value: lparen.Value, ValueType: TokenError}, nil
+			}
+			return expr, nil
+		}
+		return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want %s", tok.Type, TokenRParen)}
+	}
+	if expr == nil {
+		if p.allowErrors {
+			return &Expr{Pos: lparen.Pos, Value: "()", ValueType: TokenError}, nil
+		}
+		return nil, &ParseError{Pos: lparen.Pos, Msg: "empty group"}
+	}
+	if expr.Op != OperatorNone {
+		expr.Pos = lparen.Pos
+	}
+	return expr, nil
+}
+
+// atKeyword reports whether the next token is the given (uppercase) operator
+// keyword that is followed by an operand.
+func (p *parser) atKeyword(keyword string) bool {
+	tok := p.peek()
+	if tok.Type != TokenLiteral || tok.Value != keyword {
+		return false
+	}
+	i := p.pos + 1
+	if i < len(p.tokens) && p.tokens[i].Type == TokenColon {
+		return false // a field name (e.g., "or:foo")
+	}
+	for i < len(p.tokens) && p.tokens[i].Type == TokenSep {
+		i++
+	}
+	return i < len(p.tokens) && p.tokens[i].Type != TokenEOF && p.tokens[i].Type != TokenRParen
+}
+
+// skipSeps advances the cursor past any separators.
+func (p *parser) skipSeps() {
+	for p.peek().Type == TokenSep {
+		p.next()
+	}
+}
+
+// newOperatorExpr returns an expression that combines operands with op. It
+// returns nil if there are no operands and the operand itself if there is only
+// one. Non-negated operands that use the same operator are flattened, so that
+// "a AND (b AND c)" is represented as "a AND b AND c".
+func newOperatorExpr(op Operator, operands []*Expr) *Expr {
+	switch len(operands) {
+	case 0:
+		return nil
+	case 1:
+		return operands[0]
+	}
+	expr := &Expr{Pos: operands[0].Pos, Op: op}
+	for _, operand := range operands {
+		if operand.Op == op && !operand.Not {
+			expr.Operands = append(expr.Operands, operand.Operands...)
+		} else {
+			expr.Operands = append(expr.Operands, operand)
+		}
+	}
+	return expr
+}
+
 func (p *parser) parseExpr(ctx context) (*Expr, error) {
 	tok := p.next()
 	switch tok.Type {
@@ -143,14 +304,15 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 			valueTok := p.next()
 			switch valueTok.Type {
 			case TokenLiteral, TokenQuoted:
-				if tok3 := p.next(); tok3.Type != TokenSep && tok3.Type != TokenEOF {
+				if tok3 := p.next(); !p.endOfExpr(tok3) {
 					if p.allowErrors {
 						return p.errorExpr(tok, tok2, tok3), nil
 					}
 					return nil, &ParseError{Pos: tok3.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok3.Type)}
 				}
 				return &Expr{Pos: tok.Pos, Field: tok.Value, Value: valueTok.Value, ValueType: valueTok.Type}, nil
-			case TokenSep, TokenEOF:
+			case TokenSep, TokenEOF, TokenRParen:
+				p.endOfExpr(valueTok)
 				return &Expr{Pos: tok.Pos, Field: tok.Value, Value: "", ValueType: TokenLiteral}, nil
 			default:
 				if p.allowErrors {
@@ -158,7 +320,8 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 				}
 				return nil, &ParseError{Pos: valueTok.Pos, Msg: fmt.Sprintf("got %s, want value", valueTok.Type)}
 			}
-		case TokenSep, TokenEOF:
+		case TokenSep, TokenEOF, TokenRParen:
+			p.endOfExpr(tok2)
 			return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
 		default:
 			panic("unreachable")
@@ -166,7 +329,8 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 	case TokenQuoted, TokenPattern:
 		tok2 := p.next()
 		switch tok2.Type {
-		case TokenSep, TokenEOF:
+		case TokenSep, TokenEOF, TokenRParen:
+			p.endOfExpr(tok2)
 			return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
 		default:
 			if p.allowErrors {
@@ -182,8 +346,23 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 	return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want expr", tok.Type)}
 }
 
+// endOfExpr reports whether tok (which was just consumed) ends an expression.
+// A TokenRParen ends an expression but belongs to the enclosing group, so it is
+// unconsumed.
+func (p *parser) endOfExpr(tok Token) bool {
+	switch tok.Type {
+	case TokenSep, TokenEOF:
+		return true
+	case TokenRParen:
+		p.backup()
+		return true
+	}
+	return false
+}
+
 // errorExpr makes an Expr with type TokenError, whose value is built from the
-// given tokens plus any others up to the next separator (space) or EOF.
+// given tokens plus any others up to the next separator (space), end of group,
+// or EOF.
 func (p *parser) errorExpr(toks ...Token) *Expr {
 	e := &Expr{Pos: toks[0].Pos, Value: toks[0].Value, ValueType: TokenError}
 	for _, t := range toks[1:] {
@@ -191,8 +370,7 @@ func (p *parser) errorExpr(toks ...Token) *Expr {
 	}
 	for {
 		t := p.next()
-		switch t.Type {
-		case TokenSep, TokenEOF:
+		if p.endOfExpr(t) {
 			return e
 		}
 		e.Value = e.Value + t.Value
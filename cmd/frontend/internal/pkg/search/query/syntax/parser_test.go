@@ -67,6 +67,124 @@ func TestParser(t *testing.T) {
 				{Field: "b", Value: "", ValueType: TokenLiteral},
 			},
 		},
+		"a AND b": {
+			wantExpr: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			},
+			wantString: "a b",
+		},
+		"a OR b": {
+			wantExpr: []*Expr{{Op: OperatorOr, Operands: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			}}},
+			wantString: "(a OR b)",
+		},
+		"a OR b c": {
+			wantExpr: []*Expr{{Op: OperatorOr, Operands: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Op: OperatorAnd, Operands: []*Expr{
+					{Value: "b", ValueType: TokenLiteral},
+					{Value: "c", ValueType: TokenLiteral},
+				}},
+			}}},
+			wantString: "(a OR (b AND c))",
+		},
+		"(a OR b) c": {
+			wantExpr: []*Expr{
+				{Op: OperatorOr, Operands: []*Expr{
+					{Value: "a", ValueType: TokenLiteral},
+					{Value: "b", ValueType: TokenLiteral},
+				}},
+				{Value: "c", ValueType: TokenLiteral},
+			},
+		},
+		"NOT a b": {
+			wantExpr: []*Expr{
+				{Not: true, Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			},
+			wantString: "-a b",
+		},
+		"NOT (a OR b:c)": {
+			wantExpr: []*Expr{{Not: true, Op: OperatorOr, Operands: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Field: "b", Value: "c", ValueType: TokenLiteral},
+			}}},
+			wantString: "-(a OR b:c)",
+		},
+		"-(a b)": {
+			wantExpr: []*Expr{{Not: true, Op: OperatorAnd, Operands: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			}}},
+			wantString: "-(a AND b)",
+		},
+		"((a))": {
+			wantExpr:   []*Expr{{Value: "a", ValueType: TokenLiteral}},
+			wantString: "a",
+		},
+		`("a" OR /b/)`: {
+			wantExpr: []*Expr{{Op: OperatorOr, Operands: []*Expr{
+				{Value: `"a"`, ValueType: TokenQuoted},
+				{Value: "b", ValueType: TokenPattern},
+			}}},
+		},
+		"(a: OR b)": {
+			wantExpr: []*Expr{{Op: OperatorOr, Operands: []*Expr{
+				{Field: "a", Value: "", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+			}}},
+			wantString: "(a: OR b)",
+		},
+		"a OR": {
+			wantExpr: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "OR", ValueType: TokenLiteral},
+			},
+		},
+		"or:a": {
+			wantExpr: []*Expr{{Field: "or", Value: "a", ValueType: TokenLiteral}},
+		},
+		// Lowercase operator words are ordinary search terms.
+		"a and b or c": {
+			wantExpr: []*Expr{
+				{Value: "a", ValueType: TokenLiteral},
+				{Value: "and", ValueType: TokenLiteral},
+				{Value: "b", ValueType: TokenLiteral},
+				{Value: "or", ValueType: TokenLiteral},
+				{Value: "c", ValueType: TokenLiteral},
+			},
+		},
+		"not a": {
+			wantExpr: []*Expr{
+				{Value: "not", ValueType: TokenLiteral},
+				{Value: "a", ValueType: TokenLiteral},
+			},
+		},
+		// Regexp groups that are adjacent to other text are part of the pattern.
+		"(a|b)c": {
+			wantExpr: []*Expr{{Value: "(a|b)c", ValueType: TokenLiteral}},
+		},
+		"c(a|b)": {
+			wantExpr: []*Expr{{Value: "c(a|b)", ValueType: TokenLiteral}},
+		},
+		"x (a|b)c y": {
+			wantExpr: []*Expr{
+				{Value: "x", ValueType: TokenLiteral},
+				{Value: "(a|b)c", ValueType: TokenLiteral},
+				{Value: "y", ValueType: TokenLiteral},
+			},
+		},
+		"((a|b)c OR d)": {
+			wantExpr: []*Expr{{Op: OperatorOr, Operands: []*Expr{
+				{Value: "(a|b)c", ValueType: TokenLiteral},
+				{Value: "d", ValueType: TokenLiteral},
+			}}},
+		},
+		"f(":  {wantExpr: []*Expr{{Value: "f(", ValueType: TokenLiteral}}},
+		"( )": {wantErr: &ParseError{Pos: 0, Msg: "empty group"}},
 		"--": {
 			wantErr: &ParseError{Pos: 1, Msg: "got TokenMinus, want expr"},
 		},
@@ -96,9 +214,7 @@ func TestParser(t *testing.T) {
 			if len(query.Expr) == 0 {
 				query.Expr = []*Expr{}
 			}
-			for _, expr := range query.Expr {
-				expr.Pos = 0
-			}
+			zeroPos(query.Expr)
 			if !reflect.DeepEqual(query.Expr, test.wantExpr) {
 				t.Errorf("expr: %s\ngot  %v\nwant %v", input, query.Expr, test.wantExpr)
 			}
@@ -112,6 +228,51 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestParser_explicitAnd(t *testing.T) {
+	tests := map[string]struct {
+		wantExplicitAnd bool
+		wantString      string
+	}{
+		"a b":            {wantString: "a b"},
+		"a AND b":        {wantExplicitAnd: true, wantString: "a AND b"},
+		"r:a b AND c":    {wantExplicitAnd: true, wantString: "r:a AND b AND c"},
+		"(a OR b) AND c": {wantExplicitAnd: true, wantString: "(a OR b) AND c"},
+		"a OR (b AND c)": {wantExplicitAnd: true, wantString: "(a OR (b AND c))"},
+		"a AND":          {wantString: "a AND"},
+		`a "AND" b`:      {wantString: `a "AND" b`},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			query, err := Parse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.ExplicitAnd != test.wantExplicitAnd {
+				t.Errorf("got ExplicitAnd %v, want %v", query.ExplicitAnd, test.wantExplicitAnd)
+			}
+			if s := query.String(); s != test.wantString {
+				t.Errorf("got string %q, want %q", s, test.wantString)
+			}
+
+			// The string form of the query must have the same meaning.
+			query2, err := Parse(query.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query2.ExplicitAnd != query.ExplicitAnd {
+				t.Errorf("got ExplicitAnd %v after reparsing, want %v", query2.ExplicitAnd, query.ExplicitAnd)
+			}
+		})
+	}
+}
+
+func zeroPos(exprs []*Expr) {
+	for _, expr := range exprs {
+		expr.Pos = 0
+		zeroPos(expr.Operands)
+	}
+}
+
 func TestParseAllowingErrors(t *testing.T) {
 	type args struct {
 		input string
@@ -139,6 +300,19 @@ func TestParseAllowingErrors(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "empty group",
+			args: args{input: "( )"},
+			want: &Query{
+				Input: "( )",
+				Expr: []*Expr{
+					{
+						Value:     "()",
+						ValueType: TokenError,
+					},
+				},
+			},
+		},
 		{
 			name: ":=",
 			args: args{input: ":="},
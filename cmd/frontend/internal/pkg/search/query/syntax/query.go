@@ -11,15 +11,26 @@ import (
 type Query struct {
 	Input string  // the original input query string
 	Expr  []*Expr // expressions in this query
+
+	// ExplicitAnd is whether the query uses the "AND" keyword, as in "a AND b"
+	// (whose expressions are otherwise parsed like "a b").
+	ExplicitAnd bool
 }
 
 func (q *Query) String() string {
+	if q.ExplicitAnd {
+		s := make([]string, len(q.Expr))
+		for i, e := range q.Expr {
+			s[i] = e.String()
+		}
+		return strings.Join(s, " AND ")
+	}
 	return ExprString(q.Expr)
 }
 
 // WithErrorsQuoted converts a query like `f:foo b(ar` to `f:foo "b(ar"`.
 func (q *Query) WithErrorsQuoted() *Query {
-	q2 := &Query{}
+	q2 := &Query{ExplicitAnd: q.ExplicitAnd}
 	for _, e := range q.Expr {
 		e2 := e.WithErrorsQuoted()
 		q2.Expr = append(q2.Expr, &e2)
@@ -27,13 +38,26 @@ func (q *Query) WithErrorsQuoted() *Query {
 	return q2
 }
 
+// Operator is a boolean operator that combines expressions.
+type Operator int
+
+// All Operator values.
+const (
+	OperatorNone Operator = iota // not an operator expression (i.e., a field:value or term expression)
+	OperatorAnd
+	OperatorOr
+)
+
 // An Expr describes an expression in a query.
 type Expr struct {
 	Pos       int       // the starting character position of the query expression
-	Not       bool      // the expression is negated (e.g., -term or -field:term)
+	Not       bool      // the expression is negated (e.g., -term, -field:term, or NOT (a OR b))
 	Field     string    // the field that this expression applies to
 	Value     string    // the raw field value
 	ValueType TokenType // the type of the value
+
+	Op       Operator // the operator combining Operands (OperatorNone if this is not an operator expression)
+	Operands []*Expr  // the operands of Op (e.g., a and b in "(a OR b)")
 }
 
 func (e Expr) String() string {
@@ -41,6 +65,21 @@ func (e Expr) String() string {
 	if e.Not {
 		buf.WriteByte('-')
 	}
+	if e.Op != OperatorNone {
+		sep := " AND "
+		if e.Op == OperatorOr {
+			sep = " OR "
+		}
+		buf.WriteByte('(')
+		for i, operand := range e.Operands {
+			if i > 0 {
+				buf.WriteString(sep)
+			}
+			buf.WriteString(operand.String())
+		}
+		buf.WriteByte(')')
+		return buf.String()
+	}
 	if e.Field != "" {
 		buf.WriteString(e.Field)
 		buf.WriteByte(':')
@@ -59,6 +98,14 @@ func (e Expr) String() string {
 // quoting in case of TokenError or an invalid regular expression.
 func (e Expr) WithErrorsQuoted() Expr {
 	e2 := e
+	if e.Op != OperatorNone {
+		e2.Operands = make([]*Expr, len(e.Operands))
+		for i, operand := range e.Operands {
+			operand2 := operand.WithErrorsQuoted()
+			e2.Operands[i] = &operand2
+		}
+		return e2
+	}
 	needsQuoting := false
 	switch e.ValueType {
 	case TokenError:
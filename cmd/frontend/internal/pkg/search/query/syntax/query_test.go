@@ -68,6 +68,7 @@ func TestQuery_WithErrorsQuoted(t *testing.T) {
 		{in: "f:foo b(ar b[az", want: `f:foo "b(ar" "b[az"`},
 		{name: "invalid regex in field", in: `f:(a`, want: `"f:(a"`},
 		{name: "invalid regex in negated field", in: `-f:(a`, want: `"-f:(a"`},
+		{name: "invalid regex in group", in: `(a OR b[)`, want: `(a OR "b[")`},
 	}
 	for _, c := range cases {
 		name := c.name
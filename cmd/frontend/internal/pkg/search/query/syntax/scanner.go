@@ -22,6 +22,8 @@ const (
 	TokenColon
 	TokenMinus
 	TokenSep // separator (like a semicolon)
+	TokenLParen
+	TokenRParen
 )
 
 var singleCharTokens = map[rune]TokenType{
@@ -54,6 +56,7 @@ type scanner struct {
 	pos     int
 	prevPos int
 	start   int
+	depth   int // number of currently open groups (TokenLParen without a matching TokenRParen)
 }
 
 func (s *scanner) next() rune {
@@ -118,12 +121,73 @@ func scanDefault(s *scanner) stateFn {
 		if r == '/' {
 			return scanPattern
 		}
+		if r == '(' && s.atGroupStart() && closesGroup(s.input[s.pos+1:]) {
+			s.next()
+			s.depth++
+			s.emit(TokenLParen)
+			return scanDefault
+		}
+		if r == ')' && s.depth > 0 {
+			s.next()
+			s.depth--
+			s.emit(TokenRParen)
+			return scanDefault
+		}
 
 		return scanText
 	}
 	return scanSpace
 }
 
+// atGroupStart reports whether a '(' at the current position may begin a
+// group, which is only the case if it is at the start of the query or follows
+// whitespace, a '-', or another group's '('. Otherwise it is part of a literal
+// (as in "foo(a)").
+func (s *scanner) atGroupStart() bool {
+	if s.pos == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(s.input[:s.pos])
+	return unicode.IsSpace(r) || r == '-' || (r == '(' && s.depth > 0 && s.tokens[len(s.tokens)-1].Type == TokenLParen)
+}
+
+// closesGroup reports whether a '(' immediately preceding rest is closed by a
+// matching ')' in rest that is followed by whitespace, another ')', or the end
+// of the query. It is used to decide whether '(' begins a group or is part of
+// a literal (as in "foo(", "()", or the regexp "(a|b)c"), so that existing
+// queries with parentheses keep working.
+func closesGroup(rest string) bool {
+	if strings.HasPrefix(rest, ")") {
+		return false
+	}
+	depth := 1
+	var quote rune
+	escaped := false
+	for i, r := range rest {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				next, _ := utf8.DecodeRuneInString(rest[i+1:])
+				return i+1 == len(rest) || unicode.IsSpace(next) || next == ')'
+			}
+		}
+	}
+	return false
+}
+
 func scanText(s *scanner) stateFn {
 	// Characters that may come before a ':' (TokenColon) in a TokenLiteral.
 	preColonChars := "abcdefghijklmnopqrstuvwxyz0123456789"
@@ -146,6 +210,7 @@ func scanText(s *scanner) stateFn {
 			return scanValue
 		}
 		if !strings.ContainsRune(preColonChars, r) {
+			s.backup()
 			return scanLiteral
 		}
 	}
@@ -169,6 +234,9 @@ func scanValue(s *scanner) stateFn {
 }
 
 func scanLiteral(s *scanner) stateFn {
+	// Parentheses that are balanced within the literal (as in "foo()") are
+	// part of it. An unbalanced ')' ends the literal if it closes a group.
+	parens := 0
 	for {
 		if s.eof() {
 			break
@@ -178,9 +246,20 @@ func scanLiteral(s *scanner) stateFn {
 			s.backup()
 			break
 		}
+		if r == '(' {
+			parens++
+		} else if r == ')' {
+			if parens == 0 && s.depth > 0 {
+				s.backup()
+				break
+			}
+			parens--
+		}
 	}
 
-	s.emit(TokenLiteral)
+	if s.pos > s.start {
+		s.emit(TokenLiteral)
+	}
 	return scanDefault
 }
 
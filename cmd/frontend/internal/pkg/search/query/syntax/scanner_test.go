@@ -67,6 +67,25 @@ func TestScanner(t *testing.T) {
 		"a /b/ c":  {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenPattern, TokenSep, TokenLiteral}, wantValues: []string{"a", " ", "b", " ", "c"}},
 		"a /b c":   {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenPattern}, wantValues: []string{"a", " ", "b c"}},
 		"a /b c/":  {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenPattern}, wantValues: []string{"a", " ", "b c"}},
+		"(a)":      {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", ")"}},
+		"(a b)":    {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", " ", "b", ")"}},
+		"-(a)":     {wantTypes: []TokenType{TokenMinus, TokenLParen, TokenLiteral, TokenRParen}, wantValues: []string{"-", "(", "a", ")"}},
+		"((a))":    {wantTypes: []TokenType{TokenLParen, TokenLParen, TokenLiteral, TokenRParen, TokenRParen}},
+		"(a:b)":    {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenColon, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", ":", "b", ")"}},
+		"(a:(b))":  {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenColon, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", ":", "(b)", ")"}},
+		"(f())":    {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenRParen}, wantValues: []string{"(", "f()", ")"}},
+		`("a")`:    {wantTypes: []TokenType{TokenLParen, TokenQuoted, TokenRParen}, wantValues: []string{"(", `"a"`, ")"}},
+		"(/a/)":    {wantTypes: []TokenType{TokenLParen, TokenPattern, TokenRParen}, wantValues: []string{"(", "a", ")"}},
+		"(":        {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"("}},
+		"()":       {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"()"}},
+		"(a":       {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"(a"}},
+		"a)":       {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"a)"}},
+		"f(a)":     {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"f(a)"}},
+		`(")")`:    {wantTypes: []TokenType{TokenLParen, TokenQuoted, TokenRParen}, wantValues: []string{"(", `")"`, ")"}},
+		`("(")`:    {wantTypes: []TokenType{TokenLParen, TokenQuoted, TokenRParen}, wantValues: []string{"(", `"("`, ")"}},
+		"(a|b)c":   {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"(a|b)c"}},
+		"a (b)c":   {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenLiteral}, wantValues: []string{"a", " ", "(b)c"}},
+		"((a)b)":   {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenRParen}, wantValues: []string{"(", "(a)b", ")"}},
 	}
 	for input, test := range tests {
 		t.Run(input, func(t *testing.T) {
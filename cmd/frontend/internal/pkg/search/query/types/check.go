@@ -44,7 +44,33 @@ func (c *Config) Check(query *syntax.Query) (*Query, error) {
 		Syntax: query,
 		Fields: map[string][]*Value{},
 	}
+	var (
+		patterns      []*Node // search patterns, ANDed together
+		usesOperators bool    // whether search patterns are negated or combined using operators
+	)
 	for _, expr := range query.Expr {
+		if expr.Op != syntax.OperatorNone {
+			field, err := c.operatorExprField(expr)
+			if err != nil {
+				return nil, err
+			}
+			if field == "" {
+				pattern, err := c.checkPatternExpr(expr)
+				if err != nil {
+					return nil, err
+				}
+				patterns = append(patterns, pattern)
+				usesOperators = true
+				continue
+			}
+			values, err := c.checkFieldOperatorExpr(field, expr)
+			if err != nil {
+				return nil, err
+			}
+			checkedQuery.Fields[field] = append(checkedQuery.Fields[field], values...)
+			continue
+		}
+
 		field, fieldType, value, err := c.checkExpr(expr)
 		if err != nil {
 			return nil, err
@@ -52,11 +78,181 @@ func (c *Config) Check(query *syntax.Query) (*Query, error) {
 		if fieldType.Singular && len(checkedQuery.Fields[field]) >= 1 {
 			return nil, &TypeError{Pos: expr.Pos, Err: fmt.Errorf("field %q may not be used more than once", field)}
 		}
+		if field == "" {
+			patterns = append(patterns, &Node{syntax: expr, Not: expr.Not, Value: value})
+			if expr.Not {
+				usesOperators = true
+				continue
+			}
+		}
 		checkedQuery.Fields[field] = append(checkedQuery.Fields[field], value)
 	}
+
+	// Search patterns that are combined with an explicit "AND" (as in
+	// "a AND b") must each match somewhere in the file, unlike "a b", which
+	// matches a and b on the same line.
+	if query.ExplicitAnd && len(patterns) > 1 {
+		usesOperators = true
+	}
+
+	if usesOperators {
+		pattern := patterns[0]
+		if len(patterns) > 1 {
+			pattern = &Node{Op: syntax.OperatorAnd, Operands: patterns}
+		}
+		if err := checkPositive(pattern); err != nil {
+			return nil, err
+		}
+		checkedQuery.Pattern = pattern
+	}
 	return &checkedQuery, nil
 }
 
+// operatorExprField returns the (resolved) field that all field:value
+// expressions in the operator expression expr use. Operator expressions may
+// only combine search patterns (the default field) or values of a single
+// other field, because the values of different fields are matched against
+// different things (such as repository names and file paths).
+func (c *Config) operatorExprField(expr *syntax.Expr) (string, error) {
+	var (
+		field string
+		seen  bool
+	)
+	var walk func(*syntax.Expr) error
+	walk = func(e *syntax.Expr) error {
+		if e.Op != syntax.OperatorNone {
+			for _, operand := range e.Operands {
+				if err := walk(operand); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		f := e.Field
+		if resolvedField, ok := c.FieldAliases[f]; ok {
+			f = resolvedField
+		}
+		if seen && f != field {
+			return &TypeError{Pos: e.Pos, Err: fmt.Errorf("AND/OR/NOT operators may only combine search patterns or values of a single field (got %q and %q)", field, f)}
+		}
+		field, seen = f, true
+		return nil
+	}
+	return field, walk(expr)
+}
+
+// checkPatternExpr typechecks an expression that only consists of search
+// patterns and returns its boolean expression tree.
+func (c *Config) checkPatternExpr(expr *syntax.Expr) (*Node, error) {
+	if expr.Op == syntax.OperatorNone {
+		_, _, value, err := c.checkExpr(expr)
+		if err != nil {
+			return nil, err
+		}
+		return &Node{syntax: expr, Not: expr.Not, Value: value}, nil
+	}
+
+	if expr.Not {
+		if _, _, err := c.resolveField("", true); err != nil {
+			return nil, &TypeError{Pos: expr.Pos, Err: err}
+		}
+	}
+	node := &Node{syntax: expr, Op: expr.Op, Not: expr.Not}
+	for _, operand := range expr.Operands {
+		operandNode, err := c.checkPatternExpr(operand)
+		if err != nil {
+			return nil, err
+		}
+		node.Operands = append(node.Operands, operandNode)
+	}
+	return node, nil
+}
+
+// checkPositive returns an error if n (or one of its operands) can't be
+// evaluated by searching for its search patterns. See (*Node).Positive.
+func checkPositive(n *Node) error {
+	if !n.Positive() {
+		pos := 0
+		if n.syntax != nil {
+			pos = n.syntax.Pos
+		} else if len(n.Operands) > 0 && n.Operands[0].syntax != nil {
+			pos = n.Operands[0].syntax.Pos
+		}
+		return &TypeError{Pos: pos, Err: errors.New("negated search patterns must be combined with a non-negated search pattern using \"AND\"")}
+	}
+	for _, operand := range n.Operands {
+		if operand.Not {
+			// A negated operand of an "and" is evaluated by searching for the
+			// non-negated operand (and excluding the results), so the
+			// non-negated operand must be positive.
+			nonNegated := *operand
+			nonNegated.Not = false
+			operand = &nonNegated
+		}
+		if err := checkPositive(operand); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFieldOperatorExpr typechecks an operator expression whose field:value
+// expressions all use the given (non-default) field, such as
+// "(repo:a or repo:b)". It returns the equivalent list of values, which are
+// ANDed together like the values of any other field. Only regexp-typed fields
+// are supported, because "or" expressions are combined into a single regexp.
+func (c *Config) checkFieldOperatorExpr(field string, expr *syntax.Expr) ([]*Value, error) {
+	_, fieldType, err := c.resolveField(field, expr.Not)
+	if err != nil {
+		return nil, &TypeError{Pos: expr.Pos, Err: err}
+	}
+	if fieldType.Literal != RegexpType || fieldType.Quoted != RegexpType || fieldType.Singular {
+		return nil, &TypeError{Pos: expr.Pos, Err: fmt.Errorf("field %q may not be used with AND/OR/NOT operators", field)}
+	}
+
+	switch expr.Op {
+	case syntax.OperatorNone:
+		_, _, value, err := c.checkExpr(expr)
+		if err != nil {
+			return nil, err
+		}
+		return []*Value{value}, nil
+
+	case syntax.OperatorAnd:
+		if expr.Not {
+			return nil, &TypeError{Pos: expr.Pos, Err: fmt.Errorf("negated \"AND\" expressions of field %q are not supported", field)}
+		}
+		var values []*Value
+		for _, operand := range expr.Operands {
+			operandValues, err := c.checkFieldOperatorExpr(field, operand)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, operandValues...)
+		}
+		return values, nil
+
+	case syntax.OperatorOr:
+		patterns := make([]string, len(expr.Operands))
+		for i, operand := range expr.Operands {
+			operandValues, err := c.checkFieldOperatorExpr(field, operand)
+			if err != nil {
+				return nil, err
+			}
+			if len(operandValues) != 1 || operandValues[0].Not() {
+				return nil, &TypeError{Pos: operand.Pos, Err: fmt.Errorf("operands of \"OR\" expressions of field %q must be non-negated values", field)}
+			}
+			patterns[i] = "(?:" + operandValues[0].Regexp.String() + ")"
+		}
+		p, err := regexp.Compile(strings.Join(patterns, "|"))
+		if err != nil {
+			return nil, &TypeError{Pos: expr.Pos, Err: err}
+		}
+		return []*Value{{syntax: expr, Regexp: p}}, nil
+	}
+	panic("unreachable")
+}
+
 func (c *Config) resolveField(field string, not bool) (resolvedField string, typ FieldType, err error) {
 	// Resolve field alias, if any.
 	if resolvedField, ok := c.FieldAliases[field]; ok {
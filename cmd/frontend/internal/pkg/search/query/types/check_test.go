@@ -4,6 +4,7 @@ import (
 	"errors"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/leanovate/gopter"
@@ -18,18 +19,6 @@ type value struct {
 }
 
 func TestCheck(t *testing.T) {
-	toValue := func(v *Value) value { return value{Not: v.Not(), Value: v.Value()} }
-	toTestValueMap := func(fields map[string][]*Value) map[string][]value {
-		m := make(map[string][]value, len(fields))
-		for f, vs := range fields {
-			m[f] = make([]value, len(vs))
-			for i, v := range vs {
-				m[f][i] = toValue(v)
-			}
-		}
-		return m
-	}
-
 	conf := Config{
 		FieldTypes: map[string]FieldType{
 			"": {
@@ -126,6 +115,138 @@ func TestCheck(t *testing.T) {
 	}
 }
 
+func TestCheck_operators(t *testing.T) {
+	conf := Config{
+		FieldTypes: map[string]FieldType{
+			"": {
+				Literal:   RegexpType,
+				Quoted:    StringType,
+				Negatable: true,
+			},
+			"r": {
+				Literal:   RegexpType,
+				Quoted:    RegexpType,
+				Negatable: true,
+			},
+			"s": {
+				Literal: StringType,
+				Quoted:  StringType,
+			},
+		},
+		FieldAliases: map[string]string{
+			"r2": "r",
+		},
+	}
+	tests := map[string]struct {
+		wantFields  map[string][]value
+		wantPattern string
+		wantErr     *TypeError
+	}{
+		"a b": {
+			wantFields: map[string][]value{"": {{Value: regexp.MustCompile("a")}, {Value: regexp.MustCompile("b")}}},
+		},
+		"a -b": {
+			wantFields:  map[string][]value{"": {{Value: regexp.MustCompile("a")}}},
+			wantPattern: "(and a -b)",
+		},
+		"a AND b": {
+			wantFields:  map[string][]value{"": {{Value: regexp.MustCompile("a")}, {Value: regexp.MustCompile("b")}}},
+			wantPattern: "(and a b)",
+		},
+		"r:c AND a": {
+			wantFields: map[string][]value{"": {{Value: regexp.MustCompile("a")}}, "r": {{Value: regexp.MustCompile("c")}}},
+		},
+		"a OR b": {
+			wantFields:  map[string][]value{},
+			wantPattern: "(or a b)",
+		},
+		`(a OR "b") NOT c`: {
+			wantFields:  map[string][]value{},
+			wantPattern: `(and (or a "b") -c)`,
+		},
+		"x (a OR b) r:c": {
+			wantFields:  map[string][]value{"": {{Value: regexp.MustCompile("x")}}, "r": {{Value: regexp.MustCompile("c")}}},
+			wantPattern: "(and x (or a b))",
+		},
+		"(r:a OR r2:b) c": {
+			wantFields: map[string][]value{
+				"":  {{Value: regexp.MustCompile("c")}},
+				"r": {{Value: regexp.MustCompile("(?:a)|(?:b)")}},
+			},
+		},
+		"NOT (r:a OR r:b)": {
+			wantFields: map[string][]value{"r": {{Not: true, Value: regexp.MustCompile("(?:a)|(?:b)")}}},
+		},
+		"-(r:a r:b) c":  {wantErr: &TypeError{Pos: 1, Err: errors.New(`negated "AND" expressions of field "r" are not supported`)}},
+		"(r:a OR -r:b)": {wantErr: &TypeError{Pos: 9, Err: errors.New(`operands of "OR" expressions of field "r" must be non-negated values`)}},
+		"(r:a OR b)":    {wantErr: &TypeError{Pos: 8, Err: errors.New(`AND/OR/NOT operators may only combine search patterns or values of a single field (got "r" and "")`)}},
+		"(s:a OR s:b)":  {wantErr: &TypeError{Pos: 0, Err: errors.New(`field "s" may not be used with AND/OR/NOT operators`)}},
+		"-a":            {wantErr: &TypeError{Pos: 1, Err: errors.New(`negated search patterns must be combined with a non-negated search pattern using "AND"`)}},
+		"a OR -b":       {wantErr: &TypeError{Pos: 0, Err: errors.New(`negated search patterns must be combined with a non-negated search pattern using "AND"`)}},
+		"a -(b OR -c)":  {wantErr: &TypeError{Pos: 3, Err: errors.New(`negated search patterns must be combined with a non-negated search pattern using "AND"`)}},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			syntaxQuery, err := syntax.Parse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query, err := conf.Check(syntaxQuery)
+			if err != nil && test.wantErr == nil {
+				t.Fatal(err)
+			} else if err == nil && test.wantErr != nil {
+				t.Fatalf("got err == nil, want %q", test.wantErr)
+			} else if test.wantErr != nil && err.Error() != test.wantErr.Error() {
+				t.Fatalf("got err == %q, want %q", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := toTestValueMap(query.Fields); !reflect.DeepEqual(got, test.wantFields) {
+				t.Errorf("fields\ngot  %+v\nwant %+v", got, test.wantFields)
+			}
+			var gotPattern string
+			if query.Pattern != nil {
+				gotPattern = nodeString(query.Pattern)
+			}
+			if gotPattern != test.wantPattern {
+				t.Errorf("pattern\ngot  %s\nwant %s", gotPattern, test.wantPattern)
+			}
+		})
+	}
+}
+
+func toTestValueMap(fields map[string][]*Value) map[string][]value {
+	m := make(map[string][]value, len(fields))
+	for f, vs := range fields {
+		m[f] = make([]value, len(vs))
+		for i, v := range vs {
+			m[f][i] = value{Not: v.Not(), Value: v.Value()}
+		}
+	}
+	return m
+}
+
+// nodeString returns an s-expression representation of n for testing.
+func nodeString(n *Node) string {
+	if n.Op == syntax.OperatorNone {
+		return n.syntax.String()
+	}
+	op := "and"
+	if n.Op == syntax.OperatorOr {
+		op = "or"
+	}
+	operands := make([]string, len(n.Operands))
+	for i, operand := range n.Operands {
+		operands[i] = nodeString(operand)
+	}
+	s := "(" + op + " " + strings.Join(operands, " ") + ")"
+	if n.Not {
+		s = "-" + s
+	}
+	return s
+}
+
 func TestUnquoteString(t *testing.T) {
 	tests := map[string]string{
 		`"ab"`:    "ab",
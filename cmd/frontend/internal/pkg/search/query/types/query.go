@@ -10,6 +10,53 @@ import (
 type Query struct {
 	Syntax *syntax.Query       // the query syntax
 	Fields map[string][]*Value // map of field name -> values
+
+	// Pattern is the boolean expression that the search patterns (the values
+	// of the default field) must satisfy. It is only set if the query negates
+	// search patterns or combines them using AND/OR/NOT operators. In that
+	// case, Fields only contains the search patterns that are not negated and
+	// not part of an operator expression.
+	Pattern *Node
+}
+
+// A Node is a node in the boolean expression tree of a query's search
+// patterns.
+type Node struct {
+	syntax *syntax.Expr // the underlying query expression (nil for the root of the tree)
+
+	Op       syntax.Operator // the operator combining Operands, or OperatorNone if this node is a search pattern
+	Not      bool            // whether the node is negated
+	Value    *Value          // if OperatorNone, the search pattern
+	Operands []*Node         // the operands of Op
+}
+
+// Positive reports whether n matches a finite set of documents that can be
+// determined by searching for n's search patterns, without having to
+// enumerate all documents. Negated nodes are not positive, "and" nodes are
+// positive if at least one of their operands is, and "or" nodes are positive if
+// all of their operands are.
+func (n *Node) Positive() bool {
+	if n.Not {
+		return false
+	}
+	switch n.Op {
+	case syntax.OperatorAnd:
+		for _, operand := range n.Operands {
+			if operand.Positive() {
+				return true
+			}
+		}
+		return false
+	case syntax.OperatorOr:
+		for _, operand := range n.Operands {
+			if !operand.Positive() {
+				return false
+			}
+		}
+		return true
+	default:
+		return true
+	}
 }
 
 // ValueType is the set of types of values in queries.
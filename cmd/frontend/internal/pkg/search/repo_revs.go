@@ -159,6 +159,102 @@ func (r *RepositoryRevisions) RevSpecs() []string {
 	return revspecs
 }
 
+// HasRefGlobs returns true if any of r's revisions is a ref glob or an
+// excluded ref glob.
+func (r *RepositoryRevisions) HasRefGlobs() bool {
+	for _, rev := range r.Revs {
+		if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// RefGlobMatcher matches full ref names (such as "refs/heads/master") against
+// the ref globs of revision specifiers, as git does for the "--glob" and
+// "--exclude" flags of git-log.
+type RefGlobMatcher struct {
+	include, exclude []*regexp.Regexp
+}
+
+// NewRefGlobMatcher returns a matcher for the ref globs and excluded ref globs
+// in revs. Revspecs in revs are ignored.
+func NewRefGlobMatcher(revs []RevisionSpecifier) (*RefGlobMatcher, error) {
+	var m RefGlobMatcher
+	for _, rev := range revs {
+		switch {
+		case rev.RefGlob != "":
+			// As in git, a glob that doesn't start with "refs/" is relative
+			// to it, and a glob without wildcards matches the refs in it.
+			glob := rev.RefGlob
+			if !strings.HasPrefix(glob, "refs/") {
+				glob = "refs/" + glob
+			}
+			if !strings.ContainsAny(glob, "*?[") {
+				glob = strings.TrimSuffix(glob, "/") + "/*"
+			}
+			re, err := compileRefGlob(glob)
+			if err != nil {
+				return nil, err
+			}
+			m.include = append(m.include, re)
+		case rev.ExcludeRefGlob != "":
+			re, err := compileRefGlob(rev.ExcludeRefGlob)
+			if err != nil {
+				return nil, err
+			}
+			m.exclude = append(m.exclude, re)
+		}
+	}
+	return &m, nil
+}
+
+// Match returns true if ref matches any of the ref globs and none of the
+// excluded ref globs.
+func (m *RefGlobMatcher) Match(ref string) bool {
+	for _, re := range m.exclude {
+		if re.MatchString(ref) {
+			return false
+		}
+	}
+	for _, re := range m.include {
+		if re.MatchString(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileRefGlob compiles a shell glob to a regexp. Unlike in file path
+// globs, '*' also matches '/'.
+func compileRefGlob(glob string) (*regexp.Regexp, error) {
+	var buf strings.Builder
+	buf.WriteByte('^')
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteByte('.')
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				return nil, errors.Errorf("invalid ref glob %q: missing ']'", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteByte('$')
+	return regexp.Compile(buf.String())
+}
+
 // RepoRevisionsQuery evaulates ref specifiers in q to find out which
 // revisions need to be searched for each repository.
 func RepoRevisionsQuery(q query.Q, repos []*types.Repo) ([]RepositoryRevisions, error) {
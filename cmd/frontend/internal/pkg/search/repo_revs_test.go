@@ -193,3 +193,43 @@ func TestRepositoryRevisions(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestRefGlobMatcher(t *testing.T) {
+	tests := []struct {
+		revs []RevisionSpecifier
+		refs map[string]bool
+	}{
+		{
+			revs: []RevisionSpecifier{{RefGlob: "refs/heads/*"}},
+			refs: map[string]bool{"refs/heads/master": true, "refs/heads/feature/x": true, "refs/tags/v1": false},
+		},
+		{
+			revs: []RevisionSpecifier{{RefGlob: "heads/release-?"}},
+			refs: map[string]bool{"refs/heads/release-1": true, "refs/heads/release-10": false},
+		},
+		{
+			// A glob without wildcards matches the refs in it.
+			revs: []RevisionSpecifier{{RefGlob: "tags"}},
+			refs: map[string]bool{"refs/tags/v1": true, "refs/tagsx": false, "refs/heads/master": false},
+		},
+		{
+			revs: []RevisionSpecifier{{RefGlob: "refs/heads/*"}, {ExcludeRefGlob: "refs/heads/[!m]*"}, {RevSpec: "v1"}},
+			refs: map[string]bool{"refs/heads/master": true, "refs/heads/dev": false, "v1": false},
+		},
+	}
+	for _, test := range tests {
+		m, err := NewRefGlobMatcher(test.revs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for ref, want := range test.refs {
+			if got := m.Match(ref); got != want {
+				t.Errorf("%v: got Match(%q) == %v, want %v", test.revs, ref, got, want)
+			}
+		}
+	}
+
+	if _, err := NewRefGlobMatcher([]RevisionSpecifier{{RefGlob: "heads/[a"}}); err == nil {
+		t.Error("got no error for an invalid ref glob")
+	}
+}
@@ -3,6 +3,8 @@ package search
 
 import (
 	"regexp/syntax"
+	"strconv"
+	"strings"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
 	searchbackend "github.com/sourcegraph/sourcegraph/pkg/search/backend"
@@ -15,6 +17,7 @@ type PatternInfo struct {
 	IsRegExp        bool
 	IsWordMatch     bool
 	IsCaseSensitive bool
+	IsStructuralPat bool
 	FileMatchLimit  int32
 
 	// We do not support IsMultiline
@@ -31,6 +34,72 @@ type PatternInfo struct {
 
 	PatternMatchesContent bool
 	PatternMatchesPath    bool
+
+	// PatternExpr, if non-nil, is the boolean expression over regexp patterns
+	// that matching files must satisfy (e.g., for the query "a and not b").
+	// Pattern is then the union of the expression's non-negated patterns.
+	PatternExpr *PatternExpr
+}
+
+// PatternOp is the operator of a PatternExpr.
+type PatternOp int
+
+// All PatternOp values.
+const (
+	PatternLeaf PatternOp = iota // a single regexp pattern
+	PatternAnd
+	PatternOr
+)
+
+// PatternExpr is a boolean expression over regexp search patterns.
+type PatternExpr struct {
+	Op       PatternOp
+	Not      bool           // whether the expression is negated
+	Pattern  string         // the regexp pattern (only for PatternLeaf)
+	Operands []*PatternExpr // the operands (only for PatternAnd and PatternOr)
+}
+
+func (e *PatternExpr) String() string {
+	var s string
+	switch e.Op {
+	case PatternAnd, PatternOr:
+		op := " and "
+		if e.Op == PatternOr {
+			op = " or "
+		}
+		operands := make([]string, len(e.Operands))
+		for i, operand := range e.Operands {
+			operands[i] = operand.String()
+		}
+		s = "(" + strings.Join(operands, op) + ")"
+	default:
+		s = strconv.Quote(e.Pattern)
+	}
+	if e.Not {
+		s = "not " + s
+	}
+	return s
+}
+
+// Patterns returns the regexp patterns in e. If positive is true, only the
+// patterns that are not (an odd number of times) negated are returned.
+func (e *PatternExpr) Patterns(positive bool) []string {
+	var patterns []string
+	var walk func(*PatternExpr, bool)
+	walk = func(e *PatternExpr, not bool) {
+		not = not != e.Not
+		if e.Op == PatternLeaf {
+			if !positive || !not {
+				patterns = append(patterns, e.Pattern)
+			}
+			return
+		}
+		for _, operand := range e.Operands {
+			walk(operand, not)
+		}
+	}
+	walk(e, false)
+	return patterns
 }
 
 func (p *PatternInfo) IsEmpty() bool {
@@ -43,6 +112,13 @@ func (p *PatternInfo) Validate() error {
 		if _, err := syntax.Parse(p.Pattern, syntax.Perl); err != nil {
 			return err
 		}
+		if p.PatternExpr != nil {
+			for _, pattern := range p.PatternExpr.Patterns(false) {
+				if _, err := syntax.Parse(pattern, syntax.Perl); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
 	if p.PathPatternsAreRegExps {
@@ -1,11 +1,16 @@
 package usagestats
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/pkg/errors"
 	log15 "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 )
 
 var (
@@ -13,9 +18,105 @@ var (
 	findRefsOccurred = false
 )
 
+// eventLogInsertTimeout bounds the time that LogEvent waits for the event to be recorded in the
+// event log, because it is called in the path of user requests.
+const eventLogInsertTimeout = 5 * time.Second
+
+// Event is a user event logged by LogEvent.
+type Event struct {
+	// Name is the name of the event (such as "PAGEVIEW").
+	Name string
+
+	// UserID is the ID of the user, or 0 if the user is anonymous.
+	// UserCookieID is the unique ID of the user stored in a cookie by the web
+	// app.
+	UserID       int32
+	UserCookieID string
+
+	// Argument holds the details of the event (such as the type of a search
+	// query), and Repository is the repository that it happened in. Both are
+	// optional and only recorded in the event log.
+	Argument   json.RawMessage
+	Repository string
+}
+
 // LogActivity logs any user activity (page view, integration usage, etc) to their "last active" time, and
-// adds their unique ID to the set of active users
+// adds their unique ID to the set of active users. The event is also recorded in the event log.
 func LogActivity(isAuthenticated bool, userID int32, userCookieID string, event string) error {
+	if !isAuthenticated {
+		userID = 0
+	}
+	return LogEvent(context.Background(), &Event{Name: event, UserID: userID, UserCookieID: userCookieID})
+}
+
+// LogEvent is like LogActivity, but also records the details of the event in the event log (the
+// event_logs table), which is kept for longer than the user activity and can be broken down by
+// these details.
+func LogEvent(ctx context.Context, e *Event) error {
+	if e.UserID == 0 && e.UserCookieID == "" {
+		log15.Warn("usagestats.LogEvent: no user ID provided")
+		return nil
+	}
+	if _, ok := eventHandlers[e.Name]; !ok {
+		return fmt.Errorf("unknown user event %s", e.Name)
+	}
+
+	// The user activity and the event log are independent, so that an outage of the database
+	// does not affect the active user counts (and vice versa).
+	activityErr := logActivity(e.UserID != 0, e.UserID, e.UserCookieID, e.Name)
+
+	ctx, cancel := context.WithTimeout(ctx, eventLogInsertTimeout)
+	defer cancel()
+	err := db.EventLogs.Insert(ctx, &db.EventLog{
+		Name:            e.Name,
+		UserID:          e.UserID,
+		AnonymousUserID: e.UserCookieID,
+		Source:          db.EventSourceWeb,
+		Argument:        e.Argument,
+		Repository:      e.Repository,
+		Timestamp:       timeNow(),
+	})
+	if err != nil {
+		err = errors.Wrap(err, "EventLogs.Insert")
+	}
+	if activityErr != nil {
+		if err != nil {
+			log15.Error("usagestats.LogEvent: failed to record event", "event", e.Name, "error", err)
+		}
+		return activityErr
+	}
+	return err
+}
+
+// backendEvents are the names of the events that backend services log through
+// eventlogger.LogEvent.
+var backendEvents = map[string]bool{
+	"ServerUpdateCheck":                  true,
+	"SavedSearchEmailNotificationSent":   true,
+	"SavedSearchSlackNotificationSent":   true,
+	"SavedSearchWebhookNotificationSent": true,
+}
+
+// IsKnownEvent reports whether name is the name of an event that is logged by LogEvent or
+// LogBackendEvent.
+func IsKnownEvent(name string) bool {
+	_, ok := eventHandlers[name]
+	return ok || backendEvents[name]
+}
+
+// LogBackendEvent records an event logged by a backend service (through eventlogger.LogEvent) in
+// the event log. The user ID is 0 for events that are not caused by a user.
+func LogBackendEvent(ctx context.Context, userID int32, event string, argument json.RawMessage) error {
+	err := db.EventLogs.Insert(ctx, &db.EventLog{
+		Name:     event,
+		UserID:   userID,
+		Source:   db.EventSourceBackend,
+		Argument: argument,
+	})
+	return errors.Wrap(err, "EventLogs.Insert")
+}
+
+func logActivity(isAuthenticated bool, userID int32, userCookieID string, event string) error {
 	// Setup our GC of active key goroutine
 	gcOnce.Do(func() {
 		go gc()
@@ -40,27 +141,18 @@ func LogActivity(isAuthenticated bool, userID int32, userCookieID string, event
 		}
 	}
 
-	if uniqueID == "" {
-		log15.Warn("usagestats.LogActivity: no user ID provided")
-		return nil
-	}
-
 	// Regardless of authenicatation status, add the user's unique ID to the set of active users.
 	if err := c.Send("SADD", usersActiveKeyFromDaysAgo(0), uniqueID); err != nil {
 		return err
 	}
 
-	if handlers, ok := eventHandlers[event]; ok {
-		for _, handler := range handlers {
-			err := handler(userID, event, isAuthenticated)
-			if err != nil {
-				return err
-			}
+	for _, handler := range eventHandlers[event] {
+		err := handler(userID, event, isAuthenticated)
+		if err != nil {
+			return err
 		}
-		return nil
 	}
-
-	return fmt.Errorf("unknown user event %s", event)
+	return nil
 }
 
 // Custom event handlers
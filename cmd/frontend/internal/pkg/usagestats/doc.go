@@ -2,6 +2,10 @@ package usagestats
 
 // Usage data is stored in four categories of redis data structures.
 // Each key is prefixed by the value below.
+//
+// Every event is also recorded in the event_logs table in Postgres (see
+// LogEvent). The event log is kept for longer than the redis data, and is
+// used to report usage over arbitrary time ranges.
 
 var keyPrefix = "user_activity:"
 
@@ -0,0 +1,259 @@
+package usagestats
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+// Formats of exported usage statistics.
+const (
+	// ExportCSV is CSV with a header row.
+	ExportCSV = "csv"
+
+	// ExportJSONLines is one JSON object per line (http://jsonlines.org).
+	ExportJSONLines = "jsonl"
+)
+
+// stageNames are the names of the product stages, in the order of the fields
+// of types.Stages. The "user-last-active-STAGE<name>" fields of a user's HASH
+// hold the last time the user did an action of the stage.
+var stageNames = []string{"MANAGE", "PLAN", "CODE", "REVIEW", "VERIFY", "PACKAGE", "DEPLOY", "CONFIGURE", "MONITOR", "SECURE", "AUTOMATE"}
+
+func stageCounts(s *types.Stages) []int32 {
+	return []int32{s.Manage, s.Plan, s.Code, s.Review, s.Verify, s.Package, s.Deploy, s.Configure, s.Monitor, s.Secure, s.Automate}
+}
+
+// dailyUsage is the exported activity of the site's users on one day.
+type dailyUsage struct {
+	Date                 string `json:"date"`
+	UserCount            int32  `json:"userCount"`
+	RegisteredUserCount  int32  `json:"registeredUserCount"`
+	AnonymousUserCount   int32  `json:"anonymousUserCount"`
+	IntegrationUserCount int32  `json:"integrationUserCount"`
+
+	// Stages is the number of registered users that did an action of each
+	// product stage.
+	Stages map[string]int32 `json:"stages"`
+}
+
+func (dailyUsage) header() []string {
+	h := []string{"date", "user_count", "registered_user_count", "anonymous_user_count", "integration_user_count"}
+	for _, name := range stageNames {
+		h = append(h, "stage_"+strings.ToLower(name)+"_user_count")
+	}
+	return h
+}
+
+func (u dailyUsage) record() []string {
+	r := []string{u.Date, itoa(u.UserCount), itoa(u.RegisteredUserCount), itoa(u.AnonymousUserCount), itoa(u.IntegrationUserCount)}
+	for _, name := range stageNames {
+		r = append(r, itoa(u.Stages[name]))
+	}
+	return r
+}
+
+// userUsage is the exported activity of one user.
+type userUsage struct {
+	UserID                      int32      `json:"userID"`
+	Username                    string     `json:"username"`
+	PageViews                   int32      `json:"pageViews"`
+	SearchQueries               int32      `json:"searchQueries"`
+	CodeIntelligenceActions     int32      `json:"codeIntelligenceActions"`
+	FindReferencesActions       int32      `json:"findReferencesActions"`
+	LastActiveTime              *time.Time `json:"lastActiveTime"`
+	LastCodeHostIntegrationTime *time.Time `json:"lastCodeHostIntegrationTime"`
+
+	// StageLastActiveTimes is the last time the user did an action of each
+	// product stage (if they ever did).
+	StageLastActiveTimes map[string]time.Time `json:"stageLastActiveTimes"`
+}
+
+func (userUsage) header() []string {
+	h := []string{"user_id", "username", "page_views", "search_queries", "code_intelligence_actions", "find_references_actions", "last_active_time", "last_code_host_integration_time"}
+	for _, name := range stageNames {
+		h = append(h, "stage_"+strings.ToLower(name)+"_last_active_time")
+	}
+	return h
+}
+
+func (u userUsage) record() []string {
+	r := []string{itoa(u.UserID), u.Username, itoa(u.PageViews), itoa(u.SearchQueries), itoa(u.CodeIntelligenceActions), itoa(u.FindReferencesActions), formatTime(u.LastActiveTime), formatTime(u.LastCodeHostIntegrationTime)}
+	for _, name := range stageNames {
+		var t *time.Time
+		if st, ok := u.StageLastActiveTimes[name]; ok {
+			t = &st
+		}
+		r = append(r, formatTime(t))
+	}
+	return r
+}
+
+// exportRow is a row of exported usage statistics. In CSV, all rows of an
+// export have the same header.
+type exportRow interface {
+	header() []string
+	record() []string
+}
+
+// exportWriter writes rows of exported usage statistics in a format.
+type exportWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+	rows int
+}
+
+func newExportWriter(w io.Writer, format string) (*exportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return &exportWriter{csv: csv.NewWriter(w)}, nil
+	case ExportJSONLines:
+		return &exportWriter{json: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("invalid usage statistics export format %q", format)
+}
+
+func (ew *exportWriter) write(row exportRow) error {
+	ew.rows++
+	if ew.json != nil {
+		return ew.json.Encode(row) // Encode terminates each value with a newline
+	}
+	if ew.rows == 1 {
+		if err := ew.csv.Write(row.header()); err != nil {
+			return err
+		}
+	}
+	if err := ew.csv.Write(row.record()); err != nil {
+		return err
+	}
+	// Flush each row so that the export is streamed.
+	ew.csv.Flush()
+	return ew.csv.Error()
+}
+
+// ExportDaily writes the activity of the site's users on each of the last days
+// days (including the current, partial day), most recent first, to w in the
+// given format. Each day's activity includes the number of users that did an
+// action of each product stage. At most 93 days are kept, and all of them are
+// written if days is 0.
+func ExportDaily(w io.Writer, format string, days int) error {
+	ew, err := newExportWriter(w, format)
+	if err != nil {
+		return err
+	}
+	if days == 0 {
+		days = maxStorageDays
+	}
+	now := timeNow().UTC()
+	for daysAgo := 0; daysAgo < minIntOrZero(maxStorageDays, days); daysAgo++ {
+		day, err := uniquesCount(now.AddDate(0, 0, -daysAgo), &UsageDuration{Days: 1}, true)
+		if err != nil {
+			return err
+		}
+		row := dailyUsage{
+			Date:                 day.StartTime.Format("2006-01-02"),
+			UserCount:            day.UserCount,
+			RegisteredUserCount:  day.RegisteredUserCount,
+			AnonymousUserCount:   day.AnonymousUserCount,
+			IntegrationUserCount: day.IntegrationUserCount,
+			Stages:               map[string]int32{},
+		}
+		for i, count := range stageCounts(day.Stages) {
+			row.Stages[stageNames[i]] = count
+		}
+		if err := ew.write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportUsers writes the activity of each user of the site, ordered by user
+// ID, to w in the given format.
+func ExportUsers(ctx context.Context, w io.Writer, format string) error {
+	ew, err := newExportWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	// List the users in pages, so that the export is streamed and large
+	// sites don't need to hold all of their users in memory.
+	const pageSize = 1000
+	for offset := 0; ; offset += pageSize {
+		users, err := db.Users.List(ctx, &db.UsersListOptions{LimitOffset: &db.LimitOffset{Limit: pageSize, Offset: offset}})
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			row, err := getUserUsage(user)
+			if err != nil {
+				return err
+			}
+			if err := ew.write(row); err != nil {
+				return err
+			}
+		}
+		if len(users) < pageSize {
+			return nil
+		}
+	}
+}
+
+func getUserUsage(user *types.User) (*userUsage, error) {
+	stats, err := GetByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	u := &userUsage{
+		UserID:                      user.ID,
+		Username:                    user.Username,
+		PageViews:                   stats.PageViews,
+		SearchQueries:               stats.SearchQueries,
+		CodeIntelligenceActions:     stats.CodeIntelligenceActions,
+		FindReferencesActions:       stats.FindReferencesActions,
+		LastActiveTime:              stats.LastActiveTime,
+		LastCodeHostIntegrationTime: stats.LastCodeHostIntegrationTime,
+		StageLastActiveTimes:        map[string]time.Time{},
+	}
+
+	fields := make([]interface{}, 0, 1+len(stageNames))
+	fields = append(fields, keyPrefix+strconv.Itoa(int(user.ID)))
+	for _, name := range stageNames {
+		fields = append(fields, keyFromStage("STAGE"+name))
+	}
+	c := pool.Get()
+	values, err := redis.Strings(c.Do("HMGET", fields...))
+	c.Close()
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, err
+		}
+		u.StageLastActiveTimes[stageNames[i]] = t
+	}
+	return u, nil
+}
+
+func itoa(i int32) string { return strconv.Itoa(int(i)) }
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,90 @@
+package usagestats
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestExportUsers(t *testing.T) {
+	setupForTest(t)
+	defer func() { db.Mocks = db.MockStores{} }()
+	mockTimeNow(time.Date(2018, 3, 4, 5, 6, 7, 0, time.UTC))
+	defer func() { timeNow = time.Now }()
+
+	for _, event := range []string{"SEARCHQUERY", "SEARCHQUERY", "CODEINTELREFS", "STAGECODE"} {
+		if err := LogActivity(true, 1, "", event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Mocks.Users.List = func(ctx context.Context, opt *db.UsersListOptions) ([]*types.User, error) {
+		if opt.Offset > 0 {
+			return nil, nil
+		}
+		return []*types.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}}, nil
+	}
+
+	tests := map[string]string{
+		ExportCSV: `user_id,username,page_views,search_queries,code_intelligence_actions,find_references_actions,last_active_time,last_code_host_integration_time,stage_manage_last_active_time,stage_plan_last_active_time,stage_code_last_active_time,stage_review_last_active_time,stage_verify_last_active_time,stage_package_last_active_time,stage_deploy_last_active_time,stage_configure_last_active_time,stage_monitor_last_active_time,stage_secure_last_active_time,stage_automate_last_active_time
+1,alice,0,2,1,1,2018-03-04T05:06:07Z,,,,2018-03-04T05:06:07Z,,,,,,,,
+2,bob,0,0,0,0,,,,,,,,,,,,,
+`,
+		ExportJSONLines: `{"userID":1,"username":"alice","pageViews":0,"searchQueries":2,"codeIntelligenceActions":1,"findReferencesActions":1,"lastActiveTime":"2018-03-04T05:06:07Z","lastCodeHostIntegrationTime":null,"stageLastActiveTimes":{"CODE":"2018-03-04T05:06:07Z"}}
+{"userID":2,"username":"bob","pageViews":0,"searchQueries":0,"codeIntelligenceActions":0,"findReferencesActions":0,"lastActiveTime":null,"lastCodeHostIntegrationTime":null,"stageLastActiveTimes":{}}
+`,
+	}
+	for format, want := range tests {
+		var buf bytes.Buffer
+		if err := ExportUsers(context.Background(), &buf, format); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", format, got, want)
+		}
+	}
+
+	if err := ExportUsers(context.Background(), &bytes.Buffer{}, "xml"); err == nil {
+		t.Error("got no error for an invalid format")
+	}
+}
+
+func TestExportDaily(t *testing.T) {
+	setupForTest(t)
+	mockTimeNow(time.Date(2018, 3, 4, 5, 6, 7, 0, time.UTC))
+	defer func() { timeNow = time.Now }()
+	MockStageUniques = func(dayStart time.Time, period *UsageDuration, registeredActives []string) (*types.Stages, error) {
+		return &types.Stages{Code: int32(len(registeredActives))}, nil
+	}
+	defer func() { MockStageUniques = nil }()
+
+	if err := LogActivity(true, 1, "", "PAGEVIEW"); err != nil {
+		t.Fatal(err)
+	}
+	if err := LogActivity(false, 0, "068ccbfa-8529-4fa7-859e-2c3514af2434", "PAGEVIEW"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportDaily(&buf, ExportCSV, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := `date,user_count,registered_user_count,anonymous_user_count,integration_user_count,stage_manage_user_count,stage_plan_user_count,stage_code_user_count,stage_review_user_count,stage_verify_user_count,stage_package_user_count,stage_deploy_user_count,stage_configure_user_count,stage_monitor_user_count,stage_secure_user_count,stage_automate_user_count
+2018-03-04,2,1,1,0,0,0,1,0,0,0,0,0,0,0,0
+2018-03-03,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := ExportDaily(&buf, ExportJSONLines, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{"date":"2018-03-04","userCount":2,"registeredUserCount":1,"anonymousUserCount":1,"integrationUserCount":0,"stages":{"AUTOMATE":0,"CODE":1,"CONFIGURE":0,"DEPLOY":0,"MANAGE":0,"MONITOR":0,"PACKAGE":0,"PLAN":0,"REVIEW":0,"SECURE":0,"VERIFY":0}}`+"\n"; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package usagestats
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// The usage metrics are labeled with the period that they cover: "day" (since
+// today at 00:00 UTC), "week" (since the latest Sunday at 00:00 UTC) or
+// "month" (since the first day of the current month at 00:00 UTC).
+var (
+	activeUsersGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "usage",
+		Name:      "active_users",
+		Help:      "Number of unique users (registered and anonymous) active in the current period.",
+	}, []string{"period"})
+	searchQueriesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "usage",
+		Name:      "search_queries",
+		Help:      "Number of search queries in the current period.",
+	}, []string{"period"})
+	codeIntelActionsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "usage",
+		Name:      "code_intel_actions",
+		Help:      "Number of code intelligence actions (hovers, definitions and references) in the current period.",
+	}, []string{"period"})
+)
+
+func init() {
+	prometheus.MustRegister(activeUsersGauge)
+	prometheus.MustRegister(searchQueriesGauge)
+	prometheus.MustRegister(codeIntelActionsGauge)
+}
+
+// codeIntelEvents are the names of the events that are code intelligence
+// actions.
+var codeIntelEvents = map[string]bool{
+	"CODEINTEL":                true,
+	"CODEINTELREFS":            true,
+	"CODEINTELINTEGRATION":     true,
+	"CODEINTELINTEGRATIONREFS": true,
+}
+
+// metricsUpdateInterval is how often the usage metrics are updated.
+const metricsUpdateInterval = 5 * time.Minute
+
+// UpdateMetrics periodically updates the Prometheus gauges of aggregate usage
+// statistics (active users, search queries and code intelligence actions). It
+// never returns.
+func UpdateMetrics() {
+	ctx := context.Background()
+	for {
+		if err := updateMetrics(ctx); err != nil {
+			log15.Error("Updating usage statistics metrics failed.", "error", err)
+		}
+		time.Sleep(metricsUpdateInterval)
+	}
+}
+
+func updateMetrics(ctx context.Context) error {
+	now := timeNow().UTC()
+	periods := []struct {
+		name     string
+		start    time.Time
+		duration *UsageDuration
+	}{
+		{"day", time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), &UsageDuration{Days: 1}},
+		{"week", startOfWeek(0), &UsageDuration{Days: 7}},
+		{"month", startOfMonth(0), &UsageDuration{Months: 1}},
+	}
+	for _, p := range periods {
+		users, err := uniques(p.start, p.duration)
+		if err != nil {
+			return err
+		}
+		activeUsersGauge.WithLabelValues(p.name).Set(float64(len(users.All)))
+
+		// The event log keeps the number of events, which the active user
+		// sets don't.
+		counts, err := db.EventLogs.Breakdown(ctx, db.EventLogsBreakdownOptions{
+			From: p.start,
+			To:   now.Add(time.Minute),
+			By:   db.EventLogsByName,
+		})
+		if err != nil {
+			return err
+		}
+		var searchQueries, codeIntelActions int32
+		for _, c := range counts {
+			switch {
+			case *c.Value == "SEARCHQUERY":
+				searchQueries += c.Count
+			case codeIntelEvents[*c.Value]:
+				codeIntelActions += c.Count
+			}
+		}
+		searchQueriesGauge.WithLabelValues(p.name).Set(float64(searchQueries))
+		codeIntelActionsGauge.WithLabelValues(p.name).Set(float64(codeIntelActions))
+	}
+	return nil
+}
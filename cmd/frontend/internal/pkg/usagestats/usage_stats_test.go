@@ -1,6 +1,8 @@
 package usagestats
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/gomodule/redigo/redis"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 )
 
@@ -117,6 +120,79 @@ func TestUserUsageStatistics_LogCodeHostIntegrationUsage(t *testing.T) {
 	}
 }
 
+func TestLogEvent(t *testing.T) {
+	setupForTest(t)
+	defer func() { db.Mocks = db.MockStores{} }()
+	mockTimeNow(time.Date(2018, 3, 4, 5, 6, 7, 0, time.UTC))
+	defer func() { timeNow = time.Now }()
+
+	var logged []*db.EventLog
+	db.Mocks.EventLogs.Insert = func(ctx context.Context, e *db.EventLog) error {
+		logged = append(logged, e)
+		return nil
+	}
+
+	err := LogEvent(context.Background(), &Event{
+		Name:         "SEARCHQUERY",
+		UserCookieID: "test-cookie-id",
+		Argument:     json.RawMessage(`{"type":"regexp"}`),
+		Repository:   "github.com/foo/bar",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := LogEvent(context.Background(), &Event{Name: "UNKNOWN", UserID: 1}); err == nil {
+		t.Error("got no error for an unknown event")
+	}
+
+	want := []*db.EventLog{{
+		Name:            "SEARCHQUERY",
+		AnonymousUserID: "test-cookie-id",
+		Source:          db.EventSourceWeb,
+		Argument:        json.RawMessage(`{"type":"regexp"}`),
+		Repository:      "github.com/foo/bar",
+		Timestamp:       timeNow(),
+	}}
+	if !reflect.DeepEqual(logged, want) {
+		t.Errorf("got logged events %+v, want %+v", logged, want)
+	}
+
+	// The event is also recorded in the user activity.
+	c := pool.Get()
+	defer c.Close()
+	userIDs, err := redis.Strings(c.Do("SMEMBERS", usersActiveKeyFromDaysAgo(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"test-cookie-id"}; !reflect.DeepEqual(userIDs, want) {
+		t.Errorf("got active users %v, want %v", userIDs, want)
+	}
+}
+
+func TestLogEvent_eventLogUnavailable(t *testing.T) {
+	setupForTest(t)
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	db.Mocks.EventLogs.Insert = func(ctx context.Context, e *db.EventLog) error {
+		return errors.New("database unavailable")
+	}
+
+	if err := LogEvent(context.Background(), &Event{Name: "PAGEVIEW", UserCookieID: "test-cookie-id"}); err == nil {
+		t.Error("got no error, want the error of the event log")
+	}
+
+	// The event is still recorded in the user activity.
+	c := pool.Get()
+	defer c.Close()
+	userIDs, err := redis.Strings(c.Do("SMEMBERS", usersActiveKeyFromDaysAgo(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"test-cookie-id"}; !reflect.DeepEqual(userIDs, want) {
+		t.Errorf("got active users %v, want %v", userIDs, want)
+	}
+}
+
 func TestUserUsageStatistics_getUsersActiveToday(t *testing.T) {
 	setupForTest(t)
 
@@ -373,6 +449,8 @@ func setupForTest(t *testing.T) {
 		t.Skip()
 	}
 
+	db.Mocks.EventLogs.Insert = func(context.Context, *db.EventLog) error { return nil }
+
 	keyPrefix = "__test__" + t.Name() + ":"
 	pool = &redis.Pool{
 		MaxIdle:     3,
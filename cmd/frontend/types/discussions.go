@@ -55,4 +55,11 @@ type DiscussionComment struct {
 	UpdatedAt    time.Time
 	DeletedAt    *time.Time
 	Reports      []string
+
+	// ExternalServiceType, ExternalServiceID and ExternalID identify the code
+	// host comment (such as a pull request review comment) that this comment
+	// was imported from. They are nil for comments made on Sourcegraph.
+	ExternalServiceType *string
+	ExternalServiceID   *string
+	ExternalID          *string
 }
@@ -4,10 +4,13 @@ package types
 type SavedSearch struct {
 	ID              int32 // the globally unique DB ID
 	Description     string
-	Query           string  // the literal search query to be ran
-	Notify          bool    // whether or not to notify the owner(s) of this saved search via email
-	NotifySlack     bool    // whether or not to notify the owner(s) of this saved search via Slack
-	UserID          *int32  // if non-nil, the owner is this user. UserID/OrgID are mutually exclusive.
-	OrgID           *int32  // if non-nil, the owner is this organization. UserID/OrgID are mutually exclusive.
-	SlackWebhookURL *string // if non-nil && NotifySlack == true, indicates that this Slack webhook URL should be used instead of the owners default Slack webhook.
+	Query           string            // the literal search query to be ran
+	Notify          bool              // whether or not to notify the owner(s) of this saved search via email
+	NotifySlack     bool              // whether or not to notify the owner(s) of this saved search via Slack
+	UserID          *int32            // if non-nil, the owner is this user. UserID/OrgID are mutually exclusive.
+	OrgID           *int32            // if non-nil, the owner is this organization. UserID/OrgID are mutually exclusive.
+	SlackWebhookURL *string           // if non-nil && NotifySlack == true, indicates that this Slack webhook URL should be used instead of the owners default Slack webhook.
+	WebhookURL      *string           // if non-nil, new results are POSTed to this URL.
+	WebhookSecret   *string           // if non-nil, the secret used to sign the payloads POSTed to WebhookURL.
+	WebhookHeaders  map[string]string // additional HTTP headers sent to WebhookURL.
 }
@@ -2,6 +2,7 @@
 package types
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/pkg/api"
@@ -15,10 +16,106 @@ type Campaign struct {
 	AuthorID        int32
 	NamespaceUserID int32
 	NamespaceOrgID  int32
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	// CampaignPlanID is the ID of the CampaignPlan the campaign was created
+	// from, or zero if it wasn't created from a plan.
+	CampaignPlanID int64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
+// A CampaignPlan is a codemod search query (i.e. a search query with a
+// replace: field) whose per-repository diffs are computed by CampaignJobs, to
+// be previewed and published by a Campaign.
+type CampaignPlan struct {
+	ID        int64
+	Query     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// A CampaignJob computes the diff produced by the codemod of a CampaignPlan in
+// a single Repo.
+type CampaignJob struct {
+	ID             int64
+	CampaignPlanID int64
+	RepoID         api.RepoID
+	// Rev is the base commit of the diff.
+	Rev api.CommitID
+	// Diff is the unified diff produced by the codemod. It is empty if the
+	// codemod didn't change anything, or if the job didn't finish.
+	Diff string
+	// Error is the error that occurred when running the job, if any.
+	Error string
+	// PublishedRef is the ref on the code host that the commit created from
+	// Diff was pushed to when the job was published by a Campaign, or empty if
+	// it wasn't published.
+	PublishedRef string
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// A Changeset is a change to a Repo on a code host (such as a GitHub pull
+// request or a Bitbucket Server pull request) that is tracked by a Campaign.
+//
+// The fields prefixed with External and the Title, URL, State, ReviewState and
+// CheckState fields are loaded from the code host when the changeset is synced.
+// They are zero-valued until the changeset is synced for the first time.
+type Changeset struct {
+	ID         int64
+	CampaignID int64
+	RepoID     api.RepoID
+	// ExternalID is the ID of the changeset on the code host (e.g., the
+	// pull request number on GitHub).
+	ExternalID        string
+	Title             string
+	URL               string
+	State             ChangesetState
+	ReviewState       ChangesetReviewState
+	CheckState        ChangesetCheckState
+	ExternalCreatedAt time.Time
+	ExternalClosedAt  time.Time
+	ExternalMergedAt  time.Time
+	// Metadata contains the raw code host JSON metadata of the changeset.
+	Metadata  json.RawMessage
+	SyncedAt  time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ChangesetState is the state of a Changeset on its code host.
+type ChangesetState string
+
+// ChangesetState constants.
+const (
+	ChangesetStateOpen   ChangesetState = "OPEN"
+	ChangesetStateClosed ChangesetState = "CLOSED"
+	ChangesetStateMerged ChangesetState = "MERGED"
+)
+
+// ChangesetReviewState is the review state of a Changeset on its code host.
+type ChangesetReviewState string
+
+// ChangesetReviewState constants.
+const (
+	ChangesetReviewStateApproved         ChangesetReviewState = "APPROVED"
+	ChangesetReviewStateChangesRequested ChangesetReviewState = "CHANGES_REQUESTED"
+	ChangesetReviewStatePending          ChangesetReviewState = "PENDING"
+)
+
+// ChangesetCheckState is the combined state of the CI checks (such as commit
+// statuses or build statuses) of the latest commit of a Changeset. It is empty
+// if the commit has no checks.
+type ChangesetCheckState string
+
+// ChangesetCheckState constants.
+const (
+	ChangesetCheckStatePending ChangesetCheckState = "PENDING"
+	ChangesetCheckStatePassed  ChangesetCheckState = "PASSED"
+	ChangesetCheckStateFailed  ChangesetCheckState = "FAILED"
+)
+
 // RepoFields are lazy loaded data fields on a Repo (from the DB).
 type RepoFields struct {
 	// URI is the full name for this repository (e.g.,
@@ -36,6 +133,10 @@ type RepoFields struct {
 
 	// Fork is whether this repository is a fork of another repository.
 	Fork bool
+
+	// Pinned is whether gitserver keeps this repository on disk even when it
+	// needs to free up disk space.
+	Pinned bool
 }
 
 // Repo represents a source code repository.
@@ -29,6 +29,9 @@ var (
 	runRepoCleanup, _ = strconv.ParseBool(env.Get("SRC_RUN_REPO_CLEANUP", "", "Periodically remove inactive repositories."))
 	wantPctFree       = env.Get("SRC_REPOS_DESIRED_PERCENT_FREE", "10", "Target percentage of free space on disk.")
 	janitorInterval   = env.Get("SRC_REPOS_JANITOR_INTERVAL", "1m", "Interval between cleanup runs")
+	rebalance, _      = strconv.ParseBool(env.Get("SRC_REPOS_REBALANCE", "", "Move repositories this gitserver no longer holds to the gitservers that do, instead of leaving them to be recloned."))
+	rebalanceInterval = env.Get("SRC_REPOS_REBALANCE_INTERVAL", "1m", "Interval between passes that move repositories to the gitservers that hold them")
+	gitServerAddr     = env.Get("SRC_GIT_SERVER_ADDR", "", "The address of this gitserver in SRC_GIT_SERVERS. Defaults to the address matching the host name.")
 )
 
 func main() {
@@ -51,6 +54,7 @@ func main() {
 		ReposDir:                reposDir,
 		DeleteStaleRepositories: runRepoCleanup,
 		DesiredPercentFree:      wantPctFree2,
+		Addr:                    gitServerAddr,
 	}
 	gitserver.RegisterMetrics()
 
@@ -78,6 +82,19 @@ func main() {
 		}
 	}()
 
+	if rebalance {
+		rebalanceInterval2, err := time.ParseDuration(rebalanceInterval)
+		if err != nil {
+			log.Fatalf("parsing $SRC_REPOS_REBALANCE_INTERVAL: %v", err)
+		}
+		go func() {
+			for {
+				gitserver.RebalanceRepos()
+				time.Sleep(rebalanceInterval2)
+			}
+		}()
+	}
+
 	port := "3178"
 	host := ""
 	if env.InsecureDev {
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -27,10 +28,10 @@ import (
 func init() {
 	prometheus.MustRegister(reposRemoved)
 	prometheus.MustRegister(reposRecloned)
+	prometheus.MustRegister(repoMaintenanceTasks)
+	prometheus.MustRegister(repoMaintenanceDuration)
 }
 
-const repoTTL = time.Hour * 24 * 45
-
 var reposRemoved = prometheus.NewCounter(prometheus.CounterOpts{
 	Namespace: "src",
 	Subsystem: "gitserver",
@@ -42,15 +43,31 @@ var reposRecloned = prometheus.NewCounter(prometheus.CounterOpts{
 	Namespace: "src",
 	Subsystem: "gitserver",
 	Name:      "repos_recloned",
-	Help:      "number of repos removed and recloned due to age",
+	Help:      "number of repos removed and recloned due to corruption",
 })
 
+var repoMaintenanceTasks = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "repo_maintenance_tasks",
+	Help:      "number of git maintenance tasks run on repos, by task and status",
+}, []string{"task", "status"})
+
+var repoMaintenanceDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "repo_maintenance_duration_seconds",
+	Help:      "time taken by git maintenance tasks on repos, by task",
+	Buckets:   prometheus.ExponentialBuckets(0.1, 4, 8),
+}, []string{"task"})
+
 // cleanupRepos walks the repos directory and performs maintenance tasks:
 //
 // 1. Remove corrupt repos.
 // 2. Remove stale lock files.
 // 3. Remove inactive repos on sourcegraph.com
-// 4. Reclone repos after a while. (simulate git gc)
+// 4. Run git maintenance tasks (gc, repack, commit-graph) once in a while,
+// and reclone repos that they find to be corrupt.
 func (s *Server) cleanupRepos() {
 	bCtx, bCancel := s.serverContext()
 	defer bCancel()
@@ -75,35 +92,76 @@ func (s *Server) cleanupRepos() {
 		return false, setGitAttributes(gitDir)
 	}
 
-	maybeReclone := func(gitDir string) (done bool, err error) {
-		recloneTime, err := getRecloneTime(gitDir)
-		if err != nil {
-			return false, err
-		}
-
-		// Add a jitter to spread out recloning of repos cloned at the same
-		// time.
-		if time.Since(recloneTime) <= repoTTL+randDuration(repoTTL/4) {
-			return false, nil
-		}
-
+	reclone := func(gitDir string) error {
 		ctx, cancel := context.WithTimeout(bCtx, longGitCommandTimeout)
 		defer cancel()
 
 		// name is the relative path to ReposDir, but without the .git suffix.
 		repo := protocol.NormalizeRepo(api.RepoName(strings.TrimPrefix(filepath.Dir(gitDir), s.ReposDir+"/")))
-		log15.Info("recloning expired repo", "repo", repo)
+		log15.Info("recloning corrupt repo", "repo", repo)
 
 		remoteURL, err := repoRemoteURL(ctx, gitDir)
 		if err != nil {
-			return false, errors.Wrap(err, "failed to get remote URL")
+			return errors.Wrap(err, "failed to get remote URL")
 		}
 
 		if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true, Overwrite: true}); err != nil {
-			return true, err
+			return err
 		}
 		reposRecloned.Inc()
-		return true, nil
+		return nil
+	}
+
+	maybeMaintain := func(gitDir string) (done bool, err error) {
+		var maintained bool
+		defer func() {
+			if maintained && !done {
+				if err := updateRepoSize(gitDir); err != nil {
+					log15.Warn("Failed to record repository size", "repo", gitDir, "error", err)
+				}
+			}
+		}()
+
+		for _, task := range maintenanceTasks {
+			lastRun, err := getMaintenanceTime(gitDir, task.name)
+			if err != nil {
+				return false, err
+			}
+
+			// Add a jitter to spread out maintenance of repos cloned at the
+			// same time.
+			if time.Since(lastRun) <= task.interval+randDuration(task.interval/4) {
+				continue
+			}
+
+			maintained = true
+			output, err := runMaintenanceTask(bCtx, gitDir, task)
+			if err != nil && corruptRepoPattern.Match(output) {
+				// Recloning is the only way to repair a corrupt repo.
+				if err2 := reclone(gitDir); err2 != nil {
+					return true, multierror.Append(err, err2)
+				}
+				return true, nil
+			}
+			// Record the attempt even if the task failed, so that a task that
+			// keeps failing is retried after its interval rather than on
+			// every run.
+			if err2 := setMaintenanceTime(gitDir, task.name, time.Now()); err2 != nil {
+				return false, multierror.Append(err, err2)
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	maybeUpdateSize := func(gitDir string) (done bool, err error) {
+		u, err := readRepoUsage(gitDir)
+		if err != nil || time.Since(u.SizeUpdated) > repoUsageSizeTTL {
+			return false, updateRepoSize(gitDir)
+		}
+		return false, nil
 	}
 
 	removeStaleLocks := func(gitDir string) (done bool, err error) {
@@ -156,11 +214,12 @@ func (s *Server) cleanupRepos() {
 		// info/attributes.
 		{"ensure git attributes", ensureGitAttributes},
 	}
-	// Old git clones accumulate loose git objects that waste space and
-	// slow down git operations. Periodically do a fresh clone to avoid
-	// these problems. git gc is slow and resource intensive. It is
-	// cheaper and faster to just reclone the repository.
-	cleanups = append(cleanups, cleanupFn{"maybe reclone", maybeReclone})
+	// Old git clones accumulate loose objects and packs that waste space and
+	// slow down git operations. Periodically run git's maintenance commands
+	// to avoid these problems.
+	cleanups = append(cleanups, cleanupFn{"maybe maintain", maybeMaintain})
+	// Keep the size in the usage record used by freeUpSpace up to date.
+	cleanups = append(cleanups, cleanupFn{"maybe update size", maybeUpdateSize})
 
 	err := filepath.Walk(s.ReposDir, func(gitDir string, fi os.FileInfo, fileErr error) error {
 		if fileErr != nil {
@@ -319,23 +378,48 @@ func device(f string) (int64, error) {
 // freeUpSpace removes git directories under ReposDir, in order from least
 // recently to most recently used, until it has freed howManyBytesToFree.
 func (s *Server) freeUpSpace(howManyBytesToFree int64) error {
-	// Get the git directories and their mod times.
-	gitDirs, err := s.findGitDirs(s.ReposDir)
+	// Get the git directories and their usage, leaving out pinned repos.
+	allGitDirs, err := s.findGitDirs(s.ReposDir)
 	if err != nil {
 		return errors.Wrap(err, "finding git dirs")
 	}
-	dirModTimes := make(map[string]time.Time, len(gitDirs))
-	for _, d := range gitDirs {
-		mt, err := gitDirModTime(d)
+	gitDirs := allGitDirs[:0]
+	usages := make(map[string]repoUsage, len(allGitDirs))
+	for _, d := range allGitDirs {
+		u, err := readRepoUsage(d)
 		if err != nil {
-			return errors.Wrap(err, "computing mod time of git dir")
+			log15.Warn("cleanup: failed to read repository usage", "repo", d, "error", err)
+		}
+		if u.Pinned {
+			continue
+		}
+		if u.LastAccessed.IsZero() {
+			// Not accessed since usage was first recorded.
+			if u.LastAccessed, err = gitDirModTime(d); err != nil {
+				return errors.Wrap(err, "computing mod time of git dir")
+			}
 		}
-		dirModTimes[d] = mt
+		if u.SizeUpdated.IsZero() {
+			if u.Size, err = dirSize(d); err != nil {
+				return errors.Wrapf(err, "computing size of directory %s", d)
+			}
+		}
+		gitDirs = append(gitDirs, d)
+		usages[d] = u
 	}
 
-	// Sort the repos from least to most recently used.
-	sort.Slice(gitDirs, func(i, j int) bool {
-		return dirModTimes[gitDirs[i]].Before(dirModTimes[gitDirs[j]])
+	// Sort the repos from least to most recently used. Among repos that have
+	// been idle for the same number of days, remove the largest first.
+	now := time.Now()
+	idleDays := func(d string) int64 {
+		return int64(now.Sub(usages[d].LastAccessed) / (24 * time.Hour))
+	}
+	sort.SliceStable(gitDirs, func(i, j int) bool {
+		di, dj := idleDays(gitDirs[i]), idleDays(gitDirs[j])
+		if di != dj {
+			return di > dj
+		}
+		return usages[gitDirs[i]].Size > usages[gitDirs[j]].Size
 	})
 
 	// Remove repos until howManyBytesToFree is met or exceeded.
@@ -369,7 +453,7 @@ func (s *Server) freeUpSpace(howManyBytesToFree int64) error {
 		G := float64(1024 * 1024 * 1024)
 		log15.Warn("cleanup: removed least recently used repo",
 			"repo", d,
-			"how old", time.Since(dirModTimes[d]),
+			"how old", time.Since(usages[d].LastAccessed),
 			"free space in GiB", float64(actualFreeBytes)/G,
 			"actual percent of disk space free", float64(actualFreeBytes)/float64(diskSizeBytes)*100.0,
 			"desired percent of disk space free", float64(s.DesiredPercentFree),
@@ -623,6 +707,107 @@ func getRecloneTime(gitDir string) (time.Time, error) {
 	return time.Unix(sec, 0), nil
 }
 
+// maintenanceTask is a set of git commands that cleanupRepos runs on each
+// repo at most once per interval.
+type maintenanceTask struct {
+	name     string // names the task in the repo's git config and in metrics
+	interval time.Duration
+	commands [][]string // the arguments of each git command
+}
+
+var maintenanceTasks = []maintenanceTask{
+	// Pack loose objects and prune unreachable ones if there are enough of
+	// them to slow down git.
+	{name: "gc", interval: 24 * time.Hour, commands: [][]string{
+		{"gc", "--auto", "--quiet"},
+	}},
+	// Every fetch adds a pack. Index all packs in a multi-pack-index so that
+	// lookups don't search each pack, and incrementally combine small packs
+	// instead of rewriting all objects.
+	{name: "repack", interval: 24 * time.Hour, commands: [][]string{
+		{"repack", "-d", "-q", "--write-midx"},
+		{"multi-pack-index", "expire"},
+		{"multi-pack-index", "repack", "--batch-size=512m"},
+	}},
+	// Reachability bitmaps speed up clones and fetches from the repo and
+	// counting objects, but can only be written by repacking all objects.
+	{name: "bitmaps", interval: 7 * 24 * time.Hour, commands: [][]string{
+		{"repack", "-a", "-d", "-b", "-q"},
+	}},
+	// The commit-graph speeds up walking the history (git log, merge-base,
+	// etc.).
+	{name: "commit-graph", interval: 24 * time.Hour, commands: [][]string{
+		{"commit-graph", "write", "--reachable", "--split"},
+	}},
+}
+
+// corruptRepoPattern matches the output of git commands that failed because
+// the repo is corrupt.
+var corruptRepoPattern = regexp.MustCompile(`bad (tree |blob |commit |tag )?object|is corrupt|corrupt (loose|packed) object|missing (blob|tree|commit|tag) |unable to read [0-9a-f]{40}|packfile .* cannot be accessed|invalid sha1 pointer`)
+
+// runMaintenanceTask runs the commands of task in gitDir. If a command
+// fails, its output is returned along with the error.
+func runMaintenanceTask(ctx context.Context, gitDir string, task maintenanceTask) (output []byte, err error) {
+	start := time.Now()
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "failure"
+		}
+		repoMaintenanceTasks.WithLabelValues(task.name, status).Inc()
+		repoMaintenanceDuration.WithLabelValues(task.name).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, longGitCommandTimeout)
+	defer cancel()
+	for _, args := range task.commands {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = gitDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return output, errors.Wrapf(err, "git %s failed. Output: %s", strings.Join(args, " "), output)
+		}
+	}
+	return nil, nil
+}
+
+// getMaintenanceTime returns the time the maintenance task named task last
+// ran on the repository. If the value is not stored in the repository, it is
+// set to now, so that the task first runs on a fresh clone after its
+// interval.
+func getMaintenanceTime(gitDir, task string) (time.Time, error) {
+	key := "sourcegraph.maintenance." + task
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = gitDir
+	out, err := cmd.Output()
+	if err != nil {
+		// Exit code 1 means the key is not set.
+		if ee, ok := err.(*exec.ExitError); ok && ee.Sys().(syscall.WaitStatus).ExitStatus() == 1 {
+			now := time.Now()
+			return now, setMaintenanceTime(gitDir, task, now)
+		}
+		return time.Unix(0, 0), errors.Wrapf(wrapCmdError(cmd, err), "failed to determine %s maintenance timestamp", task)
+	}
+
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 0)
+	if err != nil {
+		// If the value is bad update it to the current time
+		now := time.Now()
+		return now, setMaintenanceTime(gitDir, task, now)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// setMaintenanceTime records t as the time the maintenance task named task
+// last ran on the repository.
+func setMaintenanceTime(gitDir, task string, t time.Time) error {
+	cmd := exec.Command("git", "config", "sourcegraph.maintenance."+task, strconv.FormatInt(t.Unix(), 10))
+	cmd.Dir = gitDir
+	if _, err := cmd.Output(); err != nil {
+		return errors.Wrapf(wrapCmdError(cmd, err), "failed to update %s maintenance timestamp", task)
+	}
+	return nil
+}
+
 // randDuration returns a psuedo-random duration between [0, d)
 func randDuration(d time.Duration) time.Duration {
 	return time.Duration(rand.Int63n(int64(d)))
@@ -10,7 +10,6 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -53,28 +52,39 @@ func TestCleanupInactive(t *testing.T) {
 	}
 }
 
-func TestCleanupExpired(t *testing.T) {
-	root, err := ioutil.TempDir("", "gitserver-test-")
-	if err != nil {
-		t.Fatal(err)
+func TestCleanupMaintenance(t *testing.T) {
+	root, cleanup1 := tmpDir(t)
+	defer cleanup1()
+	remote, cleanup2 := tmpDir(t)
+	defer cleanup2()
+
+	git := func(dir string, arg ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command("git", arg...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	mustGit := func(dir string, arg ...string) string {
+		t.Helper()
+		out, err := git(dir, arg...)
+		if err != nil {
+			t.Fatalf("git %s failed: %s\n%s", strings.Join(arg, " "), err, out)
+		}
+		return out
 	}
-	defer os.RemoveAll(root)
 
-	repoA := path.Join(root, testRepoA, ".git")
-	cmd := exec.Command("git", "--bare", "init", repoA)
-	if err := cmd.Run(); err != nil {
-		t.Fatal(err)
-	}
-	repoB := path.Join(root, testRepoB, ".git")
-	cmd = exec.Command("git", "--bare", "init", repoB)
-	if err := cmd.Run(); err != nil {
-		t.Fatal(err)
-	}
-	remote := path.Join(root, testRepoC, ".git")
-	cmd = exec.Command("git", "--bare", "init", remote)
-	if err := cmd.Run(); err != nil {
-		t.Fatal(err)
-	}
+	mustGit(remote, "init", ".")
+	mkFiles(t, remote, "a.txt")
+	mustGit(remote, "add", "a.txt")
+	mustGit(remote, "commit", "-m", "a")
+	tree := strings.TrimSpace(mustGit(remote, "rev-parse", "HEAD^{tree}"))
 
 	origRepoRemoteURL := repoRemoteURL
 	repoRemoteURL = func(ctx context.Context, dir string) (string, error) {
@@ -82,13 +92,22 @@ func TestCleanupExpired(t *testing.T) {
 	}
 	defer func() { repoRemoteURL = origRepoRemoteURL }()
 
-	atime, err := os.Stat(filepath.Join(repoA, "HEAD"))
-	if err != nil {
-		t.Fatal(err)
+	// repoA was just cloned, repoB is due for maintenance and repoC is
+	// due for maintenance but corrupt.
+	repoA := path.Join(root, testRepoA, ".git")
+	repoB := path.Join(root, testRepoB, ".git")
+	repoC := path.Join(root, testRepoC, ".git")
+	for _, dir := range []string{repoA, repoB, repoC} {
+		mustGit(root, "clone", "--mirror", remote, dir)
 	}
-	cmd = exec.Command("git", "config", "--add", "sourcegraph.recloneTimestamp", strconv.FormatInt(time.Now().Add(-(2*repoTTL)).Unix(), 10))
-	cmd.Dir = repoB
-	if err := cmd.Run(); err != nil {
+	for _, dir := range []string{repoB, repoC} {
+		for _, task := range maintenanceTasks {
+			if err := setMaintenanceTime(dir, task.name, time.Now().Add(-30*24*time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.Remove(filepath.Join(repoC, "objects", tree[:2], tree[2:])); err != nil {
 		t.Fatal(err)
 	}
 
@@ -96,24 +115,65 @@ func TestCleanupExpired(t *testing.T) {
 	s.Handler() // Handler as a side-effect sets up Server
 	s.cleanupRepos()
 
-	fi, err := os.Stat(filepath.Join(repoA, "HEAD"))
-	if err != nil {
-		// repoA should still exist.
-		t.Fatal(err)
+	hasBitmap := func(gitDir string) bool {
+		bitmaps, _ := filepath.Glob(filepath.Join(gitDir, "objects", "pack", "*.bitmap"))
+		return len(bitmaps) > 0
 	}
-	if atime.ModTime().Before(fi.ModTime()) {
-		// repoA should not have been recloned.
-		t.Error("expected repoA to not be modified")
+	if hasBitmap(repoA) {
+		t.Error("expected repoA to not be maintained")
 	}
-	fi, err = os.Stat(repoB)
-	if err != nil {
-		// repoB should still exist after being recloned.
+	if !hasBitmap(repoB) {
+		t.Error("expected repoB to be repacked with a bitmap")
+	}
+	if _, err := os.Stat(filepath.Join(repoB, "objects", "info", "commit-graphs")); err != nil {
+		t.Errorf("expected repoB to have a commit-graph: %s", err)
+	}
+	for _, task := range maintenanceTasks {
+		if lastRun, err := getMaintenanceTime(repoB, task.name); err != nil {
+			t.Fatal(err)
+		} else if time.Since(lastRun) > time.Hour {
+			t.Errorf("expected %s maintenance timestamp of repoB to be updated, got %s", task.name, lastRun)
+		}
+	}
+	if out, err := git(repoC, "cat-file", "-e", tree); err != nil {
+		t.Errorf("expected corrupt repoC to be recloned: %s\n%s", err, out)
+	}
+}
+
+func TestCleanupMaintenance_failingTask(t *testing.T) {
+	root, cleanup1 := tmpDir(t)
+	defer cleanup1()
+
+	repo := path.Join(root, testRepoA, ".git")
+	cmd := exec.Command("git", "init", "--bare", repo)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %s\n%s", err, out)
+	}
+
+	origMaintenanceTasks := maintenanceTasks
+	maintenanceTasks = []maintenanceTask{
+		{name: "failing", interval: 24 * time.Hour, commands: [][]string{
+			{"rev-parse", "--verify", "--quiet", "refs/heads/does-not-exist"},
+		}},
+	}
+	defer func() { maintenanceTasks = origMaintenanceTasks }()
+	if err := setMaintenanceTime(repo, "failing", time.Now().Add(-30*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{ReposDir: root}
+	s.Handler() // Handler as a side-effect sets up Server
+	s.cleanupRepos()
+
+	// The failed attempt is recorded, so that the task isn't retried on
+	// every run, and the repo isn't recloned since it isn't corrupt.
+	if lastRun, err := getMaintenanceTime(repo, "failing"); err != nil {
 		t.Fatal(err)
+	} else if time.Since(lastRun) > time.Hour {
+		t.Errorf("expected maintenance timestamp of failed task to be updated, got %s", lastRun)
 	}
-	// Expect the repo to be recloned hand have a recent mod time.
-	ti := time.Now().Add(-repoTTL)
-	if fi.ModTime().Before(ti) {
-		t.Error("expected repoB to be recloned during clean up")
+	if _, err := os.Stat(repo); err != nil {
+		t.Errorf("expected repo to be kept: %s", err)
 	}
 }
 
@@ -161,26 +221,32 @@ func TestCleanupOldLocks(t *testing.T) {
 	assertPaths(t, root,
 		"github.com/foo/empty/.git/HEAD",
 		"github.com/foo/empty/.git/info/attributes",
+		"github.com/foo/empty/.git/sg_usage.json",
 
 		"github.com/foo/freshconfiglock/.git/HEAD",
 		"github.com/foo/freshconfiglock/.git/config.lock",
 		"github.com/foo/freshconfiglock/.git/info/attributes",
+		"github.com/foo/freshconfiglock/.git/sg_usage.json",
 
 		"github.com/foo/freshpacked/.git/HEAD",
 		"github.com/foo/freshpacked/.git/packed-refs.lock",
 		"github.com/foo/freshpacked/.git/info/attributes",
+		"github.com/foo/freshpacked/.git/sg_usage.json",
 
 		"github.com/foo/staleconfiglock/.git/HEAD",
 		"github.com/foo/staleconfiglock/.git/info/attributes",
+		"github.com/foo/staleconfiglock/.git/sg_usage.json",
 
 		"github.com/foo/stalepacked/.git/HEAD",
 		"github.com/foo/stalepacked/.git/info/attributes",
+		"github.com/foo/stalepacked/.git/sg_usage.json",
 
 		"github.com/foo/refslock/.git/HEAD",
 		"github.com/foo/refslock/.git/refs/heads/fresh",
 		"github.com/foo/refslock/.git/refs/heads/fresh.lock",
 		"github.com/foo/refslock/.git/refs/heads/stale",
 		"github.com/foo/refslock/.git/info/attributes",
+		"github.com/foo/refslock/.git/sg_usage.json",
 	)
 }
 
@@ -522,6 +588,61 @@ func TestFreeUpSpace(t *testing.T) {
 			t.Errorf("repo dir size is %d, want no more than %d", rds, wantSize)
 		}
 	})
+	t.Run("larger and pinned repos", func(t *testing.T) {
+		rd, err := ioutil.TempDir("", "freeUpSpace")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(rd)
+		now := time.Now()
+		for _, r := range []struct {
+			name         string
+			size         int
+			lastAccessed time.Time
+			pinned       bool
+		}{
+			{"small-old", 100, now.Add(-72 * time.Hour), false},
+			{"large-recent", 10000, now.Add(-time.Hour), false},
+			{"small-recent", 500, now.Add(-2 * time.Hour), false},
+			{"large-old-pinned", 10000, now.Add(-96 * time.Hour), true},
+		} {
+			d := filepath.Join(rd, r.name)
+			if err := makeFakeRepo(d, r.size); err != nil {
+				t.Fatal(err)
+			}
+			r := r
+			if err := updateRepoUsage(d, func(u *repoUsage) {
+				u.LastAccessed = r.lastAccessed
+				u.Pinned = r.pinned
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		s := Server{
+			ReposDir:  rd,
+			DiskSizer: &fakeDiskSizer{},
+		}
+		if err := s.freeUpSpace(1000); err != nil {
+			t.Fatal(err)
+		}
+
+		// The long idle repo is removed first, even though it is small. Of
+		// the repos used on the same day, the larger one is removed next. The
+		// pinned repo is never removed.
+		assertPaths(t, rd,
+			".tmp",
+			"large-old-pinned/.git/HEAD",
+			"large-old-pinned/.git/space_eater",
+			"large-old-pinned/.git/sg_usage.json",
+			"small-recent/.git/HEAD",
+			"small-recent/.git/space_eater",
+			"small-recent/.git/sg_usage.json")
+
+		if err := s.freeUpSpace(100000); err == nil {
+			t.Error("want error, since only pinned repos are left")
+		}
+	})
 }
 
 func makeFakeRepo(d string, sizeBytes int) error {
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -12,7 +13,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
@@ -31,6 +35,7 @@ func (s *Server) handleCreateCommitFromPatch(w http.ResponseWriter, r *http.Requ
 		repoGitDir = filepath.Join(s.ReposDir, repo)
 		if _, err := os.Stat(repoGitDir); os.IsNotExist(err) {
 			http.Error(w, "gitserver: repo does not exist - "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
 
@@ -182,14 +187,86 @@ func (s *Server) handleCreateCommitFromPatch(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	sendResp(w, "refs/"+ref)
+	resp := protocol.CreatePatchFromPatchResponse{
+		Rev: "refs/" + ref,
+	}
+
+	if req.Push != nil {
+		resp.Push, err = pushCommit(ctx, repoGitDir, api.CommitID(cmtHash), req.Push)
+		if err != nil {
+			log15.Error("Failed to push commit.", "ref", req.TargetRef, "commit", cmtHash, "branch", req.Push.Branch, "error", err)
+
+			http.Error(w, "gitserver: pushing commit - "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	sendResp(w, resp)
 }
 
-func sendResp(w http.ResponseWriter, commitID string) {
-	resp := protocol.CreatePatchFromPatchResponse{
-		Rev: commitID,
+// pushCommit pushes commit to the branch given by push on the remote of the
+// repository in dir, using the credentials in the remote's URL. The push only
+// succeeds if the branch points to push.ExpectedOID on the remote (or doesn't
+// exist if that is empty). A push rejected by the remote is reported in the
+// returned result, not as an error.
+func pushCommit(ctx context.Context, dir string, commit api.CommitID, push *protocol.PushConfig) (*protocol.PushResult, error) {
+	ref := "refs/heads/" + push.Branch
+	if err := checkSpecArgSafety(push.Branch); err != nil {
+		return nil, err
+	}
+	if out, err := exec.CommandContext(ctx, "git", "check-ref-format", ref).CombinedOutput(); err != nil {
+		return nil, errors.Errorf("invalid branch name %q: %s", push.Branch, strings.TrimSpace(string(out)))
+	}
+	if push.ExpectedOID != "" && !git.IsAbsoluteRevision(string(push.ExpectedOID)) {
+		return nil, errors.Errorf("invalid expected commit ID %q", push.ExpectedOID)
+	}
+
+	remoteURL, err := repoRemoteURL(ctx, dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine Git remote URL")
 	}
+	redactor := newURLRedactor(remoteURL)
+
+	cmd := exec.CommandContext(ctx, "git", "push", "--porcelain",
+		"--force-with-lease="+ref+":"+string(push.ExpectedOID),
+		remoteURL, string(commit)+":"+ref)
+	cmd.Dir = dir
+
+	out, err := runWithRemoteOpts(ctx, cmd, nil)
+
+	result := &protocol.PushResult{Ref: ref, OID: commit}
+	if reason, rejected := pushRejectedReason(out, ref); rejected {
+		result.RejectedReason = redactor.redact(reason)
+		return result, nil
+	}
+	if err != nil {
+		return nil, errors.Errorf("git push failed: %s (output follows)\n\n%s", redactor.redact(err.Error()), redactor.redact(string(out)))
+	}
+	return result, nil
+}
+
+// pushRejectedReason returns the reason why the push of ref was rejected,
+// according to the output of `git push --porcelain`. Reference lines of the
+// output have the form "<flag>\t<from>:<to>\t<summary>", where the flag "!"
+// denotes a rejected ref and the summary is something like
+// "[rejected] (stale info)".
+func pushRejectedReason(output []byte, ref string) (reason string, rejected bool) {
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || fields[0] != "!" || !strings.HasSuffix(fields[1], ":"+ref) {
+			continue
+		}
+
+		summary := strings.TrimSpace(fields[2])
+		if i := strings.Index(summary, "("); i >= 0 && strings.HasSuffix(summary, ")") {
+			return summary[i+1 : len(summary)-1], true
+		}
+		return summary, true
+	}
+	return "", false
+}
 
+func sendResp(w http.ResponseWriter, resp protocol.CreatePatchFromPatchResponse) {
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+func TestPushRejectedReason(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		output   string
+		reason   string
+		rejected bool
+	}{
+		{
+			name:   "new branch",
+			output: "To https://github.com/foo/bar\n*\t32cef81e876cea405dcba1b3ec43298fa69fe1af:refs/heads/feature\t[new branch]\nDone\n",
+		},
+		{
+			name:     "stale info",
+			output:   "To https://github.com/foo/bar\n!\te792d17f234e3800c46d067bf35258894ac6e6fa:refs/heads/feature\t[rejected] (stale info)\nDone\n",
+			reason:   "stale info",
+			rejected: true,
+		},
+		{
+			name:     "remote rejected",
+			output:   "To https://github.com/foo/bar\n!\te792d17f234e3800c46d067bf35258894ac6e6fa:refs/heads/feature\t[remote rejected] (pre-receive hook declined)\nDone\n",
+			reason:   "pre-receive hook declined",
+			rejected: true,
+		},
+		{
+			name:   "other ref rejected",
+			output: "To https://github.com/foo/bar\n!\te792d17f234e3800c46d067bf35258894ac6e6fa:refs/heads/other\t[rejected] (stale info)\nDone\n",
+		},
+		{
+			name:   "no output",
+			output: "fatal: could not read Username for 'https://github.com': terminal prompts disabled\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, rejected := pushRejectedReason([]byte(tc.output), "refs/heads/feature")
+			if reason != tc.reason || rejected != tc.rejected {
+				t.Errorf("got (%q, %v), want (%q, %v)", reason, rejected, tc.reason, tc.rejected)
+			}
+		})
+	}
+}
+
+func TestHandleCreateCommitFromPatch_Push(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	remote := filepath.Join(root, "remote")
+	reposDir := filepath.Join(root, "repos")
+	work := filepath.Join(root, "work")
+
+	run := func(dir, name string, arg ...string) string {
+		t.Helper()
+		c := exec.Command(name, arg...)
+		c.Dir = dir
+		c.Env = []string{
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		}
+		b, err := c.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %s failed: %s\n%s", name, strings.Join(arg, " "), err, b)
+		}
+		return strings.TrimSpace(string(b))
+	}
+
+	// Setup a remote with a commit, and a mirror of it in gitserver.
+	mkFiles(t, work, "hello.txt")
+	run(work, "git", "init", ".")
+	run(work, "sh", "-c", "echo hello world > hello.txt")
+	run(work, "git", "add", "hello.txt")
+	run(work, "git", "commit", "-m", "hello")
+	base := run(work, "git", "rev-parse", "HEAD")
+	run(root, "git", "clone", "--bare", work, remote)
+	run(root, "git", "clone", "--bare", remote, filepath.Join(reposDir, "example.com/foo/bar/.git"))
+
+	s := &Server{ReposDir: reposDir}
+
+	createCommit := func(message string, push *protocol.PushConfig) *protocol.CreatePatchFromPatchResponse {
+		t.Helper()
+		body, err := json.Marshal(protocol.CreateCommitFromPatchRequest{
+			Repo:       "example.com/foo/bar",
+			BaseCommit: api.CommitID(base),
+			Patch:      "--- a/hello.txt\n+++ b/hello.txt\n@@ -1 +1 @@\n-hello world\n+" + message + "\n",
+			TargetRef:  "campaigns/1",
+			CommitInfo: protocol.PatchCommitInfo{
+				Message: message,
+				Date:    time.Date(2019, 9, 1, 12, 0, 0, 0, time.UTC),
+			},
+			Push: push,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		s.handleCreateCommitFromPatch(w, httptest.NewRequest("POST", "/create-commit-from-patch", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", w.Code, w.Body)
+		}
+
+		var resp protocol.CreatePatchFromPatchResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return &resp
+	}
+
+	resp := createCommit("hello campaigns", &protocol.PushConfig{Branch: "campaigns/1"})
+	if resp.Push == nil || resp.Push.RejectedReason != "" {
+		t.Fatalf("push failed: %+v", resp.Push)
+	}
+	if have, want := resp.Push.Ref, "refs/heads/campaigns/1"; have != want {
+		t.Errorf("got ref %q, want %q", have, want)
+	}
+	if have := run(remote, "git", "rev-parse", "refs/heads/campaigns/1"); have != string(resp.Push.OID) {
+		t.Errorf("remote branch points to %s, want %s", have, resp.Push.OID)
+	}
+	first := resp.Push.OID
+
+	// The branch exists on the remote now, so pushing it again must be
+	// rejected unless it is expected to point to the first commit.
+	resp = createCommit("hello again", &protocol.PushConfig{Branch: "campaigns/1"})
+	if resp.Push == nil || resp.Push.RejectedReason != "stale info" {
+		t.Fatalf("got push result %+v, want rejection", resp.Push)
+	}
+	if have := run(remote, "git", "rev-parse", "refs/heads/campaigns/1"); have != string(first) {
+		t.Errorf("remote branch points to %s, want %s", have, first)
+	}
+
+	resp = createCommit("hello again", &protocol.PushConfig{Branch: "campaigns/1", ExpectedOID: first})
+	if resp.Push == nil || resp.Push.RejectedReason != "" {
+		t.Fatalf("push failed: %+v", resp.Push)
+	}
+	if have := run(remote, "git", "rev-parse", "refs/heads/campaigns/1"); have != string(resp.Push.OID) {
+		t.Errorf("remote branch points to %s, want %s", have, resp.Push.OID)
+	}
+}
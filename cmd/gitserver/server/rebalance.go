@@ -0,0 +1,444 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// remoteURLHeader and pinnedHeader are the headers of a /receive-repo request
+// containing the remote URL of the repository being sent and whether it is
+// pinned.
+const (
+	remoteURLHeader = "X-Sourcegraph-Remote-URL"
+	pinnedHeader    = "X-Sourcegraph-Pinned"
+)
+
+// gitServerAddrs returns the addresses of all gitservers, the number of
+// gitservers holding a replica of each repository, and whether clients assign
+// repositories to gitservers with the consistent hash ring. It is a variable
+// so that tests can mock it.
+var gitServerAddrs = func() (addrs []string, replicationFactor int, hashRing bool) {
+	sc := conf.Get().ServiceConnections
+	return sc.GitServers, sc.GitServerReplicationFactor, sc.GitServerHashRing
+}
+
+var (
+	reposRebalanced = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "repos_rebalanced",
+		Help:      "number of repos moved to the gitservers that hold them after the set of gitservers changed",
+	})
+	reposCopied = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "repos_copied_for_rebalance",
+		Help:      "number of repos copied to the gitservers that hold them on the hash ring before it is enabled",
+	})
+	reposReceived = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "repos_received",
+		Help:      "number of repos received from another gitserver",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(reposRebalanced)
+	prometheus.MustRegister(reposCopied)
+	prometheus.MustRegister(reposReceived)
+}
+
+// maxReposPerRebalancePass is the number of repositories a rebalance pass
+// moves or copies at most. The next pass continues with the rest, so that a
+// pass after many repositories changed gitservers doesn't run for hours.
+var maxReposPerRebalancePass = 50
+
+// errRebalancePassLimit stops a rebalance pass that reached
+// maxReposPerRebalancePass.
+var errRebalancePassLimit = errors.New("rebalance pass limit reached")
+
+// RebalanceRepos moves the repositories that this gitserver doesn't hold a
+// replica of on the consistent hash ring (see gitserver.RingReplicaAddrs) to
+// the gitservers that do, so that they aren't recloned from the code host
+// when gitservers are added or removed, or when the hash ring is enabled.
+//
+// Until the hash ring is enabled, clients read repositories from the
+// gitservers assigned by gitserver.ReplicaAddrs. The repositories this
+// gitserver holds under that assignment are therefore only copied to their
+// gitservers on the ring, and are deleted by a pass after the ring is
+// enabled. A pass that leaves no repository to move or copy is logged, after
+// which the ring can be enabled without recloning.
+//
+// A pass moves or copies at most maxReposPerRebalancePass repositories.
+func (s *Server) RebalanceRepos() {
+	addrs, replicationFactor, hashRing := gitServerAddrs()
+	if len(addrs) == 0 {
+		return
+	}
+	self, ok := s.selfAddr(addrs)
+	if !ok {
+		log15.Warn("rebalance: unable to determine the address of this gitserver, set SRC_GIT_SERVER_ADDR")
+		return
+	}
+
+	ctx, cancel := s.serverContext()
+	defer cancel()
+
+	var moved, copied, failed int
+	err := filepath.Walk(s.ReposDir, func(gitDir string, fi os.FileInfo, fileErr error) error {
+		if fileErr != nil || ctx.Err() != nil {
+			return nil
+		}
+
+		if s.ignorePath(gitDir) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Look for $GIT_DIR
+		if !fi.IsDir() || fi.Name() != ".git" {
+			return nil
+		}
+
+		repo := api.RepoName(strings.TrimPrefix(filepath.Dir(gitDir), s.ReposDir+"/"))
+		replicas := gitserver.RingReplicaAddrs(string(repo), addrs, replicationFactor)
+		if containsString(replicas, self) {
+			return filepath.SkipDir
+		}
+
+		// Keep the repository while clients still read it from this
+		// gitserver.
+		keep := !hashRing && containsString(gitserver.ReplicaAddrs(string(repo), addrs, replicationFactor), self)
+		if keep && s.rebalanceCopiedRepo(repo) {
+			return filepath.SkipDir
+		}
+
+		if err := s.rebalanceRepo(ctx, repo, gitDir, replicas, keep); err != nil {
+			log15.Error("rebalance: failed to move repository", "repo", repo, "to", replicas, "error", err)
+			failed++
+		} else if keep {
+			copied++
+		} else {
+			moved++
+		}
+		if moved+copied+failed >= maxReposPerRebalancePass {
+			return errRebalancePassLimit
+		}
+		return filepath.SkipDir
+	})
+	if err == errRebalancePassLimit {
+		log15.Info("rebalance: pass limit reached, continuing in the next pass", "moved", moved, "copied", copied, "failed", failed)
+		s.rebalanceMu.Lock()
+		s.rebalanceDone = false
+		s.rebalanceMu.Unlock()
+		return
+	}
+	if err != nil {
+		log15.Error("rebalance: error iterating over repositories", "error", err)
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.rebalanceMu.Lock()
+	defer s.rebalanceMu.Unlock()
+	if failed > 0 {
+		log15.Warn("rebalance: some repositories could not be moved, retrying in the next pass", "moved", moved, "copied", copied, "failed", failed)
+		s.rebalanceDone = false
+	} else if moved > 0 || copied > 0 || !s.rebalanceDone {
+		log15.Info("rebalance: all repositories are on their gitservers on the hash ring", "moved", moved, "copied", copied, "hashRing", hashRing)
+		s.rebalanceDone = true
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// selfAddr returns the address of this gitserver in addrs, and whether it is
+// known. It is s.Addr if set, and otherwise the address whose host is the
+// host name of this machine (such as the gitserver-0 address of a gitserver
+// running in a pod named gitserver-0).
+func (s *Server) selfAddr(addrs []string) (string, bool) {
+	if s.Addr != "" {
+		return s.Addr, true
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", false
+	}
+	for _, addr := range addrs {
+		host := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			host = h
+		}
+		if host == hostname || strings.HasPrefix(host, hostname+".") {
+			return addr, true
+		}
+	}
+	return "", false
+}
+
+// rebalanceRepo sends the repository in gitDir to each of the gitservers in
+// replicas, and then deletes it unless keep is true.
+func (s *Server) rebalanceRepo(ctx context.Context, repo api.RepoName, gitDir string, replicas []string, keep bool) error {
+	defer s.setRebalanceStatus(repo, "")
+
+	verb := "moving"
+	if keep {
+		verb = "copying"
+	}
+
+	remoteURL, err := repoRemoteURL(ctx, gitDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to get remote URL")
+	}
+	for i, addr := range replicas {
+		s.setRebalanceStatus(repo, fmt.Sprintf("%s to %s (%d/%d)", verb, addr, i+1, len(replicas)))
+		if err := sendRepo(ctx, addr, repo, gitDir, remoteURL); err != nil {
+			return errors.Wrapf(err, "failed to send to %s", addr)
+		}
+	}
+
+	if keep {
+		s.rebalanceMu.Lock()
+		if s.rebalanceCopied == nil {
+			s.rebalanceCopied = make(map[api.RepoName]bool)
+		}
+		s.rebalanceCopied[repo] = true
+		s.rebalanceMu.Unlock()
+		log15.Info("rebalance: copied repository", "repo", repo, "to", replicas)
+		reposCopied.Inc()
+		return nil
+	}
+
+	s.setRebalanceStatus(repo, "deleting")
+	if err := s.deleteRepo(repo); err != nil {
+		return err
+	}
+	s.rebalanceMu.Lock()
+	delete(s.rebalanceCopied, repo)
+	s.rebalanceMu.Unlock()
+	log15.Info("rebalance: moved repository", "repo", repo, "to", replicas)
+	reposRebalanced.Inc()
+	return nil
+}
+
+// rebalanceHTTPClient is the client used to send repositories to other
+// gitservers. The receiver responds after cloning the bundle, which takes at
+// most longGitCommandTimeout.
+var rebalanceHTTPClient = newReplicaHTTPClient(longGitCommandTimeout)
+
+// sendRepo sends the repository in gitDir as a bundle to the gitserver at
+// addr.
+func sendRepo(ctx context.Context, addr string, repo api.RepoName, gitDir, remoteURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, longGitCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "bundle", "create", "-", "--all")
+	cmd.Dir = gitDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	bundle, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", "http://"+addr+"/receive-repo?"+url.Values{"repo": {string(repo)}}.Encode(), bundle)
+	if err == nil {
+		req.Header.Set(remoteURLHeader, remoteURL)
+		if usage, _ := readRepoUsage(gitDir); usage.Pinned {
+			req.Header.Set(pinnedHeader, "true")
+		}
+		var resp *http.Response
+		resp, err = rebalanceHTTPClient.Do(req.WithContext(ctx))
+		if err == nil {
+			defer resp.Body.Close()
+			switch resp.StatusCode {
+			case http.StatusOK:
+			case http.StatusNoContent:
+				// The receiver already has the repository and didn't read
+				// the bundle, so git bundle is stopped.
+				cmd.Process.Kill()
+				cmd.Wait()
+				return nil
+			default:
+				msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 200))
+				err = fmt.Errorf("http status %d: %s", resp.StatusCode, msg)
+			}
+		}
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		if stderr.Len() > 0 {
+			return errors.Wrapf(err, "git bundle output: %s", stderr.String())
+		}
+		return err
+	}
+
+	// The receiver cloned the bundle it read, but if git bundle failed, that
+	// may have been a truncated bundle.
+	if err := cmd.Wait(); err != nil {
+		return errors.Wrapf(err, "git bundle failed: %s", stderr.String())
+	}
+	return nil
+}
+
+// rebalanceCopiedRepo returns whether repo was already copied to its
+// gitservers on the hash ring.
+func (s *Server) rebalanceCopiedRepo(repo api.RepoName) bool {
+	s.rebalanceMu.Lock()
+	defer s.rebalanceMu.Unlock()
+	return s.rebalanceCopied[repo]
+}
+
+func (s *Server) setRebalanceStatus(repo api.RepoName, status string) {
+	s.rebalanceMu.Lock()
+	defer s.rebalanceMu.Unlock()
+	if status == "" {
+		delete(s.rebalanceStatus, repo)
+		return
+	}
+	if s.rebalanceStatus == nil {
+		s.rebalanceStatus = make(map[api.RepoName]string)
+	}
+	s.rebalanceStatus[repo] = status
+}
+
+// rebalanceProgress returns the progress of moving repo to other gitservers,
+// and whether it is being moved.
+func (s *Server) rebalanceProgress(repo api.RepoName) (string, bool) {
+	s.rebalanceMu.Lock()
+	defer s.rebalanceMu.Unlock()
+	status, ok := s.rebalanceStatus[repo]
+	return status, ok
+}
+
+// handleReceiveRepo clones a repository from a bundle sent by another
+// gitserver that no longer holds it. It responds with 204 No Content without
+// reading the bundle if it already has the repository, and with 409 Conflict
+// if the repository is being cloned.
+func (s *Server) handleReceiveRepo(w http.ResponseWriter, r *http.Request) {
+	repo := protocol.NormalizeRepo(api.RepoName(r.URL.Query().Get("repo")))
+	if repo == "" || strings.Contains(string(repo), "..") {
+		http.Error(w, fmt.Sprintf("invalid repo %q", repo), http.StatusBadRequest)
+		return
+	}
+	dir := filepath.Join(s.ReposDir, string(repo))
+	if repoCloned(dir) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	lock, ok := s.locker.TryAcquire(dir, "receiving from another gitserver")
+	if !ok {
+		http.Error(w, "repository is being cloned", http.StatusConflict)
+		return
+	}
+	defer lock.Release()
+
+	pinned := r.Header.Get(pinnedHeader) == "true"
+	if err := s.receiveRepo(r.Context(), repo, dir, r.Header.Get(remoteURLHeader), pinned, r.Body, lock); err != nil {
+		log15.Error("failed to receive repository", "repo", repo, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (s *Server) receiveRepo(ctx context.Context, repo api.RepoName, dir, remoteURL string, pinned bool, bundle io.Reader, lock *RepositoryLock) error {
+	tmp, err := s.tempDir("receive-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	bundlePath := filepath.Join(tmp, "repo.bundle")
+	f, err := os.Create(bundlePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, bundle)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to receive bundle")
+	}
+
+	lock.SetStatus("cloning from bundle received from another gitserver")
+	tmpPath := filepath.Join(tmp, ".git")
+	cmd := exec.CommandContext(ctx, "git", "clone", "--mirror", bundlePath, tmpPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "clone from bundle failed. Output: %s", output)
+	}
+	args := []string{"remote", "set-url", "origin", "--", remoteURL}
+	if remoteURL == "" {
+		args = []string{"remote", "remove", "origin"}
+	}
+	cmd = exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = tmpPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to set remote URL. Output: %s", output)
+	}
+
+	// Update the last-changed stamp.
+	if err := setLastChanged(tmpPath); err != nil {
+		return errors.Wrapf(err, "failed to update last changed time")
+	}
+
+	// Set gitattributes
+	if err := setGitAttributes(tmpPath); err != nil {
+		return err
+	}
+
+	if err := updateRepoSize(tmpPath); err != nil {
+		log15.Warn("Failed to record repository size", "repo", repo, "error", err)
+	}
+	if pinned {
+		if err := updateRepoUsage(tmpPath, func(u *repoUsage) { u.Pinned = true }); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	if err := renameAndSync(tmpPath, filepath.Join(dir, ".git")); err != nil {
+		return err
+	}
+
+	log15.Info("received repository from another gitserver", "repo", repo)
+	reposReceived.Inc()
+	return nil
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+)
+
+func TestRebalanceRepos(t *testing.T) {
+	remote, cleanup1 := tmpDir(t)
+	defer cleanup1()
+	srcDir, cleanup2 := tmpDir(t)
+	defer cleanup2()
+	dstDir, cleanup3 := tmpDir(t)
+	defer cleanup3()
+
+	git := func(dir string, arg ...string) string {
+		t.Helper()
+		c := exec.Command("git", arg...)
+		c.Dir = dir
+		c.Env = []string{
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		}
+		b, err := c.Output()
+		if err != nil {
+			t.Fatalf("git %s failed: %s", strings.Join(arg, " "), err)
+		}
+		return string(b)
+	}
+
+	git(remote, "init", ".")
+	git(remote, "commit", "--allow-empty", "-m", "hello")
+	git(remote, "tag", "v1")
+
+	src := &Server{ReposDir: srcDir, Addr: "gitserver-0"}
+	src.Handler()
+	dst := &Server{ReposDir: dstDir}
+	ts := httptest.NewServer(dst.Handler())
+	defer ts.Close()
+	dstAddr := strings.TrimPrefix(ts.URL, "http://")
+
+	// Find a repo that moves from gitserver-0 to the new gitserver.
+	var repo string
+	for i := 0; repo == ""; i++ {
+		name := fmt.Sprintf("example.com/foo/repo%d", i)
+		if gitserver.RingReplicaAddrs(name, []string{"gitserver-0", dstAddr}, 1)[0] == dstAddr {
+			repo = name
+		}
+	}
+	// And one that stays.
+	var staying string
+	for i := 0; staying == ""; i++ {
+		name := fmt.Sprintf("example.com/foo/staying%d", i)
+		if gitserver.RingReplicaAddrs(name, []string{"gitserver-0", dstAddr}, 1)[0] == "gitserver-0" {
+			staying = name
+		}
+	}
+	for _, name := range []string{repo, staying} {
+		if err := os.MkdirAll(filepath.Join(srcDir, name), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		git(filepath.Join(srcDir, name), "clone", "--mirror", remote, ".git")
+	}
+
+	origGitServerAddrs := gitServerAddrs
+	gitServerAddrs = func() ([]string, int, bool) { return []string{"gitserver-0", dstAddr}, 1, true }
+	defer func() { gitServerAddrs = origGitServerAddrs }()
+
+	src.RebalanceRepos()
+
+	movedDir := filepath.Join(dstDir, repo, ".git")
+	if have, want := git(movedDir, "show-ref"), git(remote, "show-ref"); have != want {
+		t.Errorf("got refs %q, want %q", have, want)
+	}
+	if have, err := repoRemoteURL(context.Background(), movedDir); err != nil || have != remote {
+		t.Errorf("got remote URL %q (error %v), want %q", have, err, remote)
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, repo)); !os.IsNotExist(err) {
+		t.Errorf("moved repo still exists on the old gitserver: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(srcDir, staying, ".git")); err != nil {
+		t.Errorf("repo that was not moved was removed: %v", err)
+	}
+	if _, moving := src.rebalanceProgress(api.RepoName(repo)); moving {
+		t.Error("rebalance still in progress")
+	}
+}
+
+func TestRebalanceRepos_beforeHashRing(t *testing.T) {
+	remote, cleanup1 := tmpDir(t)
+	defer cleanup1()
+	srcDir, cleanup2 := tmpDir(t)
+	defer cleanup2()
+	dstDir, cleanup3 := tmpDir(t)
+	defer cleanup3()
+
+	for _, arg := range [][]string{{"init", "."}, {"commit", "--allow-empty", "-m", "hello"}} {
+		c := exec.Command("git", arg...)
+		c.Dir = remote
+		c.Env = []string{"GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@a.com", "GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@a.com"}
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %s: %s", strings.Join(arg, " "), err, out)
+		}
+	}
+
+	src := &Server{ReposDir: srcDir, Addr: "gitserver-0"}
+	src.Handler()
+	dst := &Server{ReposDir: dstDir}
+	ts := httptest.NewServer(dst.Handler())
+	defer ts.Close()
+	addrs := []string{"gitserver-0", strings.TrimPrefix(ts.URL, "http://")}
+
+	// Find a repo that gitserver-0 holds without the hash ring, but the new
+	// gitserver holds on the ring.
+	var repo string
+	for i := 0; repo == ""; i++ {
+		name := fmt.Sprintf("example.com/foo/repo%d", i)
+		if gitserver.ReplicaAddrs(name, addrs, 1)[0] == addrs[0] && gitserver.RingReplicaAddrs(name, addrs, 1)[0] == addrs[1] {
+			repo = name
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(srcDir, repo), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	c := exec.Command("git", "clone", "--mirror", remote, ".git")
+	c.Dir = filepath.Join(srcDir, repo)
+	if out, err := c.CombinedOutput(); err != nil {
+		t.Fatalf("git clone failed: %s: %s", err, out)
+	}
+
+	origGitServerAddrs := gitServerAddrs
+	defer func() { gitServerAddrs = origGitServerAddrs }()
+
+	// Before the hash ring is enabled, clients still read the repo from
+	// gitserver-0, so it is only copied.
+	gitServerAddrs = func() ([]string, int, bool) { return addrs, 1, false }
+	src.RebalanceRepos()
+	if !repoCloned(filepath.Join(dstDir, repo)) {
+		t.Error("repo was not copied to the new gitserver")
+	}
+	if !repoCloned(filepath.Join(srcDir, repo)) {
+		t.Error("repo was removed before the hash ring was enabled")
+	}
+
+	// Once it is enabled, the copy on gitserver-0 is deleted.
+	gitServerAddrs = func() ([]string, int, bool) { return addrs, 1, true }
+	src.RebalanceRepos()
+	if _, err := os.Stat(filepath.Join(srcDir, repo)); !os.IsNotExist(err) {
+		t.Errorf("repo still exists on the old gitserver after the hash ring was enabled: %v", err)
+	}
+}
+
+func TestRebalanceRepos_passLimit(t *testing.T) {
+	remote, cleanup1 := tmpDir(t)
+	defer cleanup1()
+	srcDir, cleanup2 := tmpDir(t)
+	defer cleanup2()
+	dstDir, cleanup3 := tmpDir(t)
+	defer cleanup3()
+
+	for _, arg := range [][]string{{"init", "."}, {"commit", "--allow-empty", "-m", "hello"}} {
+		c := exec.Command("git", arg...)
+		c.Dir = remote
+		c.Env = []string{"GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@a.com", "GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@a.com"}
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %s: %s", strings.Join(arg, " "), err, out)
+		}
+	}
+
+	src := &Server{ReposDir: srcDir, Addr: "gitserver-0"}
+	src.Handler()
+	dst := &Server{ReposDir: dstDir}
+	ts := httptest.NewServer(dst.Handler())
+	defer ts.Close()
+	addrs := []string{"gitserver-0", strings.TrimPrefix(ts.URL, "http://")}
+
+	// Find two repos that move to the new gitserver.
+	var repos []string
+	for i := 0; len(repos) < 2; i++ {
+		name := fmt.Sprintf("example.com/foo/repo%d", i)
+		if gitserver.RingReplicaAddrs(name, addrs, 1)[0] == addrs[1] {
+			repos = append(repos, name)
+		}
+	}
+	for _, repo := range repos {
+		if err := os.MkdirAll(filepath.Join(srcDir, repo), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		c := exec.Command("git", "clone", "--mirror", remote, ".git")
+		c.Dir = filepath.Join(srcDir, repo)
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git clone failed: %s: %s", err, out)
+		}
+	}
+
+	origGitServerAddrs := gitServerAddrs
+	gitServerAddrs = func() ([]string, int, bool) { return addrs, 1, true }
+	defer func() { gitServerAddrs = origGitServerAddrs }()
+	origMaxRepos := maxReposPerRebalancePass
+	maxReposPerRebalancePass = 1
+	defer func() { maxReposPerRebalancePass = origMaxRepos }()
+
+	remaining := func() (n int) {
+		for _, repo := range repos {
+			if repoCloned(filepath.Join(srcDir, repo)) {
+				n++
+			}
+		}
+		return n
+	}
+	for pass, want := range []int{1, 0} {
+		src.RebalanceRepos()
+		if got := remaining(); got != want {
+			t.Errorf("after pass %d: got %d repos left to move, want %d", pass+1, got, want)
+		}
+	}
+}
+
+func TestSendRepo_bundleFailed(t *testing.T) {
+	notARepo, cleanup := tmpDir(t)
+	defer cleanup()
+
+	// A receiver that accepts whatever it is sent.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	err := sendRepo(context.Background(), strings.TrimPrefix(ts.URL, "http://"), "example.com/foo/bar", notARepo, "")
+	if err == nil || !strings.Contains(err.Error(), "git bundle failed") {
+		t.Errorf("got error %v, want git bundle to fail", err)
+	}
+}
+
+func TestSelfAddr(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := []string{"other:3178", hostname + ".gitserver:3178"}
+
+	if addr, ok := (&Server{}).selfAddr(addrs); !ok || addr != addrs[1] {
+		t.Errorf("got %q, %v, want %q", addr, ok, addrs[1])
+	}
+	if addr, ok := (&Server{Addr: "explicit:3178"}).selfAddr(addrs); !ok || addr != "explicit:3178" {
+		t.Errorf("got %q, %v, want explicit:3178", addr, ok)
+	}
+	if _, ok := (&Server{}).selfAddr(addrs[:1]); ok {
+		t.Error("got address for a gitserver not in the list")
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// replicaDialTimeout bounds connecting to another gitserver.
+	replicaDialTimeout = 5 * time.Second
+
+	// replicaUpdateTimeout bounds forwarding a repo-update request to the
+	// other replicas of a repository. A replica continues the update after
+	// the forwarding request times out.
+	replicaUpdateTimeout = time.Minute
+)
+
+// replicaHTTPClient is the client used to forward requests to the other
+// replicas of a repository.
+var replicaHTTPClient = newReplicaHTTPClient(replicaUpdateTimeout)
+
+// newReplicaHTTPClient returns a client for requests to other gitservers that
+// fails if it takes longer than replicaDialTimeout to connect, or longer than
+// responseHeaderTimeout to receive the response headers after sending the
+// request.
+func newReplicaHTTPClient(responseHeaderTimeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   replicaDialTimeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+var replicaUpdateErrors = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "replica_update_errors",
+	Help:      "number of repo-update requests forwarded to another replica of a repo that failed",
+})
+
+func init() {
+	prometheus.MustRegister(replicaUpdateErrors)
+}
+
+// updateReplicas forwards the repo-update request req to the other gitservers
+// holding a replica of the repository (req.Replicas), so that they clone or
+// fetch it too, and waits for their responses or for ctx to be done. Failures
+// are logged, since they do not affect the update on this gitserver.
+func updateReplicas(ctx context.Context, req protocol.RepoUpdateRequest) {
+	replicas := req.Replicas
+	// The replicas must not forward the request again.
+	req.Replicas = nil
+	body, err := json.Marshal(req)
+	if err != nil {
+		log15.Error("failed to encode repo-update request for replicas", "repo", req.Repo, "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, addr := range replicas {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			if err := updateReplica(ctx, addr, body); err != nil {
+				replicaUpdateErrors.Inc()
+				log15.Warn("failed to update replica of repo", "repo", req.Repo, "replica", addr, "error", err)
+			}
+		}(addr)
+	}
+	wg.Wait()
+}
+
+func updateReplica(ctx context.Context, addr string, body []byte) error {
+	req, err := http.NewRequest("POST", "http://"+addr+"/repo-update", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := replicaHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("http status %d: %s", resp.StatusCode, msg)
+	}
+	var updateResp protocol.RepoUpdateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&updateResp); err != nil {
+		return err
+	}
+	if updateResp.Error != "" {
+		return fmt.Errorf("update failed: %s", updateResp.Error)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+func TestUpdateReplicas(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []protocol.RepoUpdateRequest
+	)
+	newReplica := func(updateErr string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/repo-update" {
+				t.Errorf("got request for %s, want /repo-update", r.URL.Path)
+			}
+			var req protocol.RepoUpdateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			received = append(received, req)
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(&protocol.RepoUpdateResponse{Cloned: true, Error: updateErr})
+		}))
+	}
+	host := func(s *httptest.Server) string {
+		u, _ := url.Parse(s.URL)
+		return u.Host
+	}
+	ok := newReplica("")
+	defer ok.Close()
+	failing := newReplica("fetch failed")
+	defer failing.Close()
+
+	updateReplicas(context.Background(), protocol.RepoUpdateRequest{
+		Repo:     "github.com/foo/bar",
+		URL:      "https://github.com/foo/bar.git",
+		Replicas: []string{host(ok), host(failing)},
+	})
+
+	// The replicas must not forward the request again.
+	want := []protocol.RepoUpdateRequest{
+		{Repo: "github.com/foo/bar", URL: "https://github.com/foo/bar.git"},
+		{Repo: "github.com/foo/bar", URL: "https://github.com/foo/bar.git"},
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("got requests %+v, want %+v", received, want)
+	}
+}
+
+func TestUpdateReplicas_timeout(t *testing.T) {
+	unblock := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer slow.Close()
+	defer close(unblock)
+	u, _ := url.Parse(slow.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		updateReplicas(ctx, protocol.RepoUpdateRequest{
+			Repo:     "github.com/foo/bar",
+			Replicas: []string{u.Host},
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("updateReplicas did not return after its context was done")
+	}
+}
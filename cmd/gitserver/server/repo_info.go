@@ -34,6 +34,7 @@ func (s *Server) repoInfo(ctx context.Context, repo api.RepoName) (*protocol.Rep
 			resp.CloneProgress = "This will never finish cloning"
 		}
 	}
+	resp.RebalanceProgress, resp.RebalanceInProgress = s.rebalanceProgress(repo)
 	if resp.Cloned {
 		if mtime, err := repoLastFetched(dir); err != nil {
 			log15.Warn("error computing last-fetched date", "repo", repo, "err", err)
@@ -52,6 +53,16 @@ func (s *Server) repoInfo(ctx context.Context, repo api.RepoName) (*protocol.Rep
 		} else {
 			resp.LastChanged = &lastChanged
 		}
+
+		if usage, err := readRepoUsage(dir); err != nil {
+			log15.Warn("error getting usage", "repo", repo, "err", err)
+		} else {
+			resp.Size = usage.Size
+			if !usage.LastAccessed.IsZero() {
+				resp.LastAccessed = &usage.LastAccessed
+			}
+			resp.Pinned = usage.Pinned
+		}
 	}
 	return &resp, nil
 }
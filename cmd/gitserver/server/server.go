@@ -117,6 +117,11 @@ type Server struct {
 	// DiskSizer tells how much disk is free and how large the disk is.
 	DiskSizer DiskSizer
 
+	// Addr is the address of this gitserver in the list of gitserver
+	// addresses. RebalanceRepos uses it to find the repositories that this
+	// gitserver no longer holds. If empty, it is guessed from the host name.
+	Addr string
+
 	// skipCloneForTests is set by tests to avoid clones.
 	skipCloneForTests bool
 
@@ -139,6 +144,14 @@ type Server struct {
 
 	repoUpdateLocksMu sync.Mutex // protects the map below and also updates to locks.once
 	repoUpdateLocks   map[api.RepoName]*locks
+
+	rebalanceMu     sync.Mutex              // protects the fields below
+	rebalanceStatus map[api.RepoName]string // progress of repos being moved to other gitservers
+	rebalanceCopied map[api.RepoName]bool   // repos already copied to their gitservers on the hash ring
+	rebalanceDone   bool                    // whether the last rebalance pass left no repos to move
+
+	lastAccessMu       sync.Mutex                 // protects lastAccessRecorded
+	lastAccessRecorded map[api.RepoName]time.Time // when recordRepoAccess last wrote each repo's usage file
 }
 
 type locks struct {
@@ -234,6 +247,8 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/repos", s.handleRepoInfo)
 	mux.HandleFunc("/delete", s.handleRepoDelete)
 	mux.HandleFunc("/repo-update", s.handleRepoUpdate)
+	mux.HandleFunc("/receive-repo", s.handleReceiveRepo)
+	mux.HandleFunc("/repo-pin", s.handleRepoPin)
 	mux.HandleFunc("/getGitolitePhabricatorMetadata", s.handleGetGitolitePhabricatorMetadata)
 	mux.HandleFunc("/create-commit-from-patch", s.handleCreateCommitFromPatch)
 	mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
@@ -399,11 +414,24 @@ func (s *Server) handleRepoUpdate(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel2 := context.WithTimeout(ctx, longGitCommandTimeout)
 	defer cancel2()
 	resp.QueueCap, resp.QueueLen = s.queryCloneLimiter()
+
+	// Update the other replicas of the repo at the same time as this one,
+	// without making the caller wait for them.
+	if len(req.Replicas) > 0 {
+		replicasCtx, cancel := s.serverContext()
+		replicasCtx, cancel2 := context.WithTimeout(replicasCtx, replicaUpdateTimeout)
+		go func() {
+			defer cancel()
+			defer cancel2()
+			updateReplicas(replicasCtx, req)
+		}()
+	}
+
 	if !repoCloned(dir) && !s.skipCloneForTests {
 		// optimistically, we assume that our cloning attempt might
 		// succeed.
 		resp.CloneInProgress = true
-		_, err := s.cloneRepo(ctx, req.Repo, req.URL, nil)
+		_, err := s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{Pinned: req.Pinned})
 		if err != nil {
 			log15.Warn("error cloning repo", "repo", req.Repo, "err", err)
 			resp.Error = err.Error()
@@ -412,6 +440,10 @@ func (s *Server) handleRepoUpdate(w http.ResponseWriter, r *http.Request) {
 		resp.Cloned = true
 		var statusErr, updateErr error
 
+		if err := setRepoPinned(dir, req.Pinned); err != nil {
+			log15.Warn("failed to record whether repo is pinned", "repo", req.Repo, "error", err)
+		}
+
 		if debounce(req.Repo, req.Since) {
 			updateErr = s.doRepoUpdate(ctx, req.Repo, req.URL)
 		}
@@ -636,6 +668,8 @@ func (s *Server) exec(w http.ResponseWriter, r *http.Request, req *protocol.Exec
 		return
 	}
 
+	s.recordRepoAccess(req.Repo, dir)
+
 	didUpdate := s.ensureRevision(ctx, req.Repo, req.URL, req.EnsureRevision, dir)
 	if didUpdate {
 		ensureRevisionStatus = "fetched"
@@ -720,6 +754,10 @@ type cloneOptions struct {
 
 	// Overwrite will overwrite the existing clone.
 	Overwrite bool
+
+	// Pinned marks the clone as pinned, so that it is never removed to free
+	// up disk space. A reclone of a pinned repo stays pinned regardless.
+	Pinned bool
 }
 
 // cloneRepo issues a git clone command for the given repo. It is
@@ -822,6 +860,20 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 			return err
 		}
 
+		// Record the size of the clone. A reclone stays pinned.
+		if err := updateRepoSize(tmpPath); err != nil {
+			log15.Warn("Failed to record repository size", "repo", repo, "error", err)
+		}
+		pinned := opts != nil && opts.Pinned
+		if oldUsage, err := readRepoUsage(dstPath); err == nil && oldUsage.Pinned {
+			pinned = true
+		}
+		if pinned {
+			if err := updateRepoUsage(tmpPath, func(u *repoUsage) { u.Pinned = true }); err != nil {
+				return err
+			}
+		}
+
 		if overwrite {
 			// remove the current repo by putting it into our temporary directory
 			err := renameAndSync(dstPath, filepath.Join(filepath.Dir(tmpPath), "old"))
@@ -1265,6 +1317,10 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 		log15.Warn("Failed to update last changed time", "repo", repo, "error", err)
 	}
 
+	if err := updateRepoSize(dir); err != nil {
+		log15.Warn("Failed to record repository size", "repo", repo, "error", err)
+	}
+
 	headBranch := "master"
 
 	// try to fetch HEAD from origin
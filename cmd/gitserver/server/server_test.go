@@ -341,7 +341,7 @@ func TestCloneRepo(t *testing.T) {
 		cloneLimiter:     mutablelimiter.New(1),
 		cloneableLimiter: mutablelimiter.New(1),
 	}
-	_, err := s.cloneRepo(context.Background(), "example.com/foo/bar", remote, nil)
+	_, err := s.cloneRepo(context.Background(), "example.com/foo/bar", remote, &cloneOptions{Pinned: true})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -381,6 +381,11 @@ func TestCloneRepo(t *testing.T) {
 	if _, err := os.Stat(filepath.Join(dst, ".git/HELLO")); !os.IsNotExist(err) {
 		t.Fatalf("expected clone to be overwritten: %s", err)
 	}
+	if u, err := readRepoUsage(dst); err != nil {
+		t.Fatal(err)
+	} else if !u.Pinned {
+		t.Fatal("expected reclone of pinned repo to stay pinned")
+	}
 
 	repo = dst
 	gotCommit = cmd("git", "rev-parse", "HEAD")
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// repoUsageFile is the name of the file in a repository's git directory that
+// records the repository's usage, which freeUpSpace uses to decide which
+// repositories to evict.
+const repoUsageFile = "sg_usage.json"
+
+// accessRecordInterval is how often the last access time of a repository is
+// written to its usage file, to avoid a write for every request.
+const accessRecordInterval = time.Minute
+
+// repoUsageSizeTTL is how long the recorded size of a repository is used
+// before the janitor computes it again. The size is also updated when the
+// repository is cloned, fetched or maintained.
+const repoUsageSizeTTL = 24 * time.Hour
+
+// repoUsage is the usage record of a repository.
+type repoUsage struct {
+	Size         int64     // size of the git directory in bytes
+	SizeUpdated  time.Time // when Size was computed
+	LastAccessed time.Time // time of the last /exec or /archive request
+	Pinned       bool      // if true, the repository is never evicted
+}
+
+// repoUsageMu serializes updates to usage files.
+var repoUsageMu sync.Mutex
+
+// repoGitDir returns the git directory of the repository in dir, which is dir
+// itself for old style repositories (and if dir is the git directory) and
+// dir/.git otherwise.
+func repoGitDir(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); os.IsNotExist(err) {
+		return filepath.Join(dir, ".git")
+	}
+	return dir
+}
+
+// readRepoUsage returns the usage record of the repository in dir. It is
+// empty if none was written yet.
+func readRepoUsage(dir string) (repoUsage, error) {
+	var u repoUsage
+	b, err := ioutil.ReadFile(filepath.Join(repoGitDir(dir), repoUsageFile))
+	if os.IsNotExist(err) {
+		return u, nil
+	} else if err != nil {
+		return u, err
+	}
+	if err := json.Unmarshal(b, &u); err != nil {
+		return u, errors.Wrapf(err, "invalid %s", repoUsageFile)
+	}
+	return u, nil
+}
+
+// updateRepoUsage calls update with the usage record of the repository in
+// dir and writes the updated record.
+func updateRepoUsage(dir string, update func(*repoUsage)) error {
+	repoUsageMu.Lock()
+	defer repoUsageMu.Unlock()
+
+	u, err := readRepoUsage(dir)
+	if err != nil {
+		// Start over rather than never recording usage again.
+		log15.Warn("Failed to read repository usage, resetting it", "dir", dir, "error", err)
+		u = repoUsage{}
+	}
+	update(&u)
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	// Write the file atomically so that it is never read half written.
+	path := filepath.Join(repoGitDir(dir), repoUsageFile)
+	if err := ioutil.WriteFile(path+".tmp", b, 0666); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// updateRepoSize records the current size of the repository in dir.
+func updateRepoSize(dir string) error {
+	size, err := dirSize(repoGitDir(dir))
+	if err != nil {
+		return err
+	}
+	return updateRepoUsage(dir, func(u *repoUsage) {
+		u.Size = size
+		u.SizeUpdated = time.Now()
+	})
+}
+
+// setRepoPinned records whether the repository in dir is pinned, unless that
+// is already recorded.
+func setRepoPinned(dir string, pinned bool) error {
+	if u, err := readRepoUsage(dir); err == nil && u.Pinned == pinned {
+		return nil
+	}
+	return updateRepoUsage(dir, func(u *repoUsage) { u.Pinned = pinned })
+}
+
+// recordRepoAccess records that the repository in dir was accessed now, unless
+// an access was recorded less than accessRecordInterval ago.
+func (s *Server) recordRepoAccess(repo api.RepoName, dir string) {
+	now := time.Now()
+	s.lastAccessMu.Lock()
+	if t, ok := s.lastAccessRecorded[repo]; ok && now.Sub(t) < accessRecordInterval {
+		s.lastAccessMu.Unlock()
+		return
+	}
+	if s.lastAccessRecorded == nil {
+		s.lastAccessRecorded = make(map[api.RepoName]time.Time)
+	}
+	s.lastAccessRecorded[repo] = now
+	s.lastAccessMu.Unlock()
+
+	if err := updateRepoUsage(dir, func(u *repoUsage) { u.LastAccessed = now }); err != nil {
+		log15.Warn("Failed to record repository access", "repo", repo, "error", err)
+	}
+}
+
+func (s *Server) handleRepoPin(w http.ResponseWriter, r *http.Request) {
+	var req protocol.RepoPinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dir := filepath.Join(s.ReposDir, string(protocol.NormalizeRepo(req.Repo)))
+	if !repoCloned(dir) {
+		http.Error(w, "repository not cloned", http.StatusNotFound)
+		return
+	}
+	if err := setRepoPinned(dir, req.Pinned); err != nil {
+		log15.Error("failed to pin repository", "repo", req.Repo, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log15.Info("set repository pinned", "repo", req.Repo, "pinned", req.Pinned)
+}
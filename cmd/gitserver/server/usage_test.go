@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
+)
+
+func TestRecordRepoAccess(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	dir := filepath.Join(root, "example.com/foo/bar")
+	if err := makeFakeRepo(dir, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{ReposDir: root}
+	s.recordRepoAccess("example.com/foo/bar", dir)
+	u, err := readRepoUsage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(u.LastAccessed) > time.Minute {
+		t.Fatalf("got last accessed %s, want now", u.LastAccessed)
+	}
+
+	// Accesses within accessRecordInterval are not written.
+	if err := updateRepoUsage(dir, func(u *repoUsage) { u.LastAccessed = time.Time{} }); err != nil {
+		t.Fatal(err)
+	}
+	s.recordRepoAccess("example.com/foo/bar", dir)
+	if u, err := readRepoUsage(dir); err != nil {
+		t.Fatal(err)
+	} else if !u.LastAccessed.IsZero() {
+		t.Errorf("got last accessed %s, want it not to be updated", u.LastAccessed)
+	}
+}
+
+func TestHandleRepoPin(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	dir := filepath.Join(root, "example.com/foo/bar")
+	if err := makeFakeRepo(dir, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateRepoSize(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	origRepoCloned := repoCloned
+	repoCloned = func(d string) bool { return d == dir }
+	defer func() { repoCloned = origRepoCloned }()
+
+	s := &Server{ReposDir: root}
+	for _, test := range []struct {
+		body       string
+		wantStatus int
+		wantPinned bool
+	}{
+		{`{"Repo": "example.com/foo/bar", "Pinned": true}`, http.StatusOK, true},
+		{`{"Repo": "example.com/foo/other", "Pinned": true}`, http.StatusNotFound, true},
+		{`{"Repo": "example.com/foo/bar", "Pinned": false}`, http.StatusOK, false},
+	} {
+		w := httptest.NewRecorder()
+		s.handleRepoPin(w, httptest.NewRequest("POST", "/repo-pin", bytes.NewBufferString(test.body)))
+		if w.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d", test.body, w.Code, test.wantStatus)
+		}
+		u, err := readRepoUsage(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Pinned != test.wantPinned {
+			t.Errorf("%s: got pinned %v, want %v", test.body, u.Pinned, test.wantPinned)
+		}
+		// Pinning keeps the rest of the usage record.
+		if u.Size != 10 {
+			t.Errorf("%s: got size %d, want 10", test.body, u.Size)
+		}
+	}
+}
+
+func TestHandleRepoUpdate_pinned(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	dir := filepath.Join(root, "example.com/foo/bar")
+	if err := makeFakeRepo(dir, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	origRepoCloned := repoCloned
+	repoCloned = func(d string) bool { return d == dir }
+	defer func() { repoCloned = origRepoCloned }()
+
+	// Don't fetch the fake repo.
+	debounce("example.com/foo/bar", 0)
+
+	s := &Server{
+		ReposDir:     root,
+		ctx:          context.Background(),
+		cloneLimiter: mutablelimiter.New(1),
+	}
+	for _, pinned := range []bool{true, false} {
+		body := fmt.Sprintf(`{"repo": "example.com/foo/bar", "since": %d, "pinned": %t}`, time.Hour, pinned)
+		w := httptest.NewRecorder()
+		s.handleRepoUpdate(w, httptest.NewRequest("POST", "/repo-update", bytes.NewBufferString(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+		}
+		u, err := readRepoUsage(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Pinned != pinned {
+			t.Errorf("got pinned %v, want %v", u.Pinned, pinned)
+		}
+	}
+}
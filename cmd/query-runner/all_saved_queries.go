@@ -164,5 +164,10 @@ func serveTestNotification(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := webhookNotifyTest(r.Context(), args.SavedSearch.Config); err != nil {
+		writeError(w, fmt.Errorf("error sending webhook notification: %s", err))
+		return
+	}
+
 	log15.Info("saved query test notification sent", "spec", args.SavedSearch.Spec, "key", args.SavedSearch.Spec.Key)
 }
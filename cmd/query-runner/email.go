@@ -23,6 +23,11 @@ func canSendEmail(ctx context.Context) error {
 	return nil
 }
 
+// mailReplyKindSavedSearch is the kind of the replies to email notifications
+// about new saved search results, which the frontend handles (see the
+// mailreply package).
+const mailReplyKindSavedSearch = "saved-search"
+
 func (n *notifier) emailNotify(ctx context.Context) {
 	if err := canSendEmail(ctx); err != nil {
 		log15.Error("Failed to send email notification for saved search.", "error", err)
@@ -35,6 +40,10 @@ func (n *notifier) emailNotify(ctx context.Context) {
 		defer cancel()
 
 		for _, recipient := range n.recipients {
+			if !recipient.email {
+				continue
+			}
+
 			ownership := "the" // example: "new search results have been found for {{.Ownership}} saved search"
 			if n.spec.Subject.User != nil && *n.spec.Subject.User == recipient.spec.userID {
 				ownership = "your"
@@ -47,13 +56,22 @@ func (n *notifier) emailNotify(ctx context.Context) {
 			if n.results.Data.Search.Results.ApproximateResultCount != "1" {
 				plural = "s"
 			}
-			if err := sendEmail(ctx, recipient.spec.userID, "results", newSearchResultsEmailTemplates, struct {
+
+			// The recipient can mute or unsubscribe from the notifications
+			// by replying to the email, if the frontend reads replies.
+			replyTo, err := api.InternalClient.MailReplyTo(ctx, recipient.spec.userID, mailReplyKindSavedSearch, n.spec.Key)
+			if err != nil {
+				log15.Error("Failed to get reply-to address of email notification for new saved search results.", "userID", recipient.spec.userID, "error", err)
+			}
+
+			if err := sendEmail(ctx, recipient.spec.userID, "results", newSearchResultsEmailTemplates, replyTo, struct {
 				URL                    string
 				Description            string
 				Query                  string
 				ApproximateResultCount string
 				Ownership              string
 				PluralResults          string
+				CanReply               bool
 			}{
 				URL:                    searchURL(n.newQuery, utmSourceEmail),
 				Description:            n.query.Description,
@@ -61,6 +79,7 @@ func (n *notifier) emailNotify(ctx context.Context) {
 				ApproximateResultCount: n.results.Data.Search.Results.ApproximateResultCount,
 				Ownership:              ownership,
 				PluralResults:          plural,
+				CanReply:               replyTo != nil,
 			}); err != nil {
 				log15.Error("Failed to send email notification for new saved search results.", "userID", recipient.spec.userID, "error", err)
 			}
@@ -76,14 +95,18 @@ var newSearchResultsEmailTemplates = txemail.MustValidate(txtypes.Templates{
   "{{.Description}}"
 
 View the new result{{.PluralResults}} on Sourcegraph: {{.URL}}
-`,
+{{if .CanReply}}
+Reply "mute" to stop these notifications for a week, or "unsubscribe" to stop them.
+{{end}}`,
 	HTML: `
 <strong>{{.ApproximateResultCount}}</strong> new search result{{.PluralResults}} found for {{.Ownership}} saved search:
 
 <p style="padding-left: 16px">&quot;{{.Description}}&quot;</p>
 
 <p><a href="{{.URL}}">View the new result{{.PluralResults}} on Sourcegraph</a></p>
-`,
+{{if .CanReply}}
+<p>Reply &quot;mute&quot; to stop these notifications for a week, or &quot;unsubscribe&quot; to stop them.</p>
+{{end}}`,
 })
 
 func emailNotifySubscribeUnsubscribe(ctx context.Context, recipient *recipient, query api.SavedQuerySpecAndConfig, template txtypes.Templates) error {
@@ -116,7 +139,7 @@ func emailNotifySubscribeUnsubscribe(ctx context.Context, recipient *recipient,
 		ownership = "your organization's"
 	}
 
-	return sendEmail(ctx, recipient.spec.userID, eventType, template, struct {
+	return sendEmail(ctx, recipient.spec.userID, eventType, template, nil, struct {
 		Ownership   string
 		Description string
 	}{
@@ -125,7 +148,9 @@ func emailNotifySubscribeUnsubscribe(ctx context.Context, recipient *recipient,
 	})
 }
 
-func sendEmail(ctx context.Context, userID int32, eventType string, template txtypes.Templates, data interface{}) error {
+// sendEmail sends an email to the user, with the given reply-to address if
+// it is not nil.
+func sendEmail(ctx context.Context, userID int32, eventType string, template txtypes.Templates, replyTo *string, data interface{}) error {
 	email, err := api.InternalClient.UserEmailsGetEmail(ctx, userID)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("InternalClient.UserEmailsGetEmail for userID=%d", userID))
@@ -136,6 +161,7 @@ func sendEmail(ctx context.Context, userID int32, eventType string, template txt
 
 	if err := api.InternalClient.SendEmail(ctx, txtypes.Message{
 		To:       []string{*email},
+		ReplyTo:  replyTo,
 		Template: template,
 		Data:     data,
 	}); err != nil {
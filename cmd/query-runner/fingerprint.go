@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// fingerprint returns an opaque identifier for a search result described by
+// the given fields. It is a hash so that the fingerprints of a saved query's
+// results can be stored compactly.
+func fingerprint(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// maxFingerprints is the maximum number of fingerprints kept for a saved
+// query whose search hit its result limit. The fingerprints of the latest
+// results are kept first, so the previous results that haven't been seen for
+// the longest time are forgotten first.
+const maxFingerprints = 10000
+
+// filterNewResults removes the results which were already found by a
+// previous execution of the query (identified by prevFingerprints) from v,
+// and updates its result count accordingly. It returns the fingerprints of
+// all results in v, including the removed ones.
+//
+// Commit and diff results are fingerprinted by their repository and commit
+// OID, and repository results by their name. Each line match of a file match
+// is fingerprinted by its repository, path and line contents, so that a new
+// match in a file which already had matches is detected even if line numbers
+// have shifted.
+//
+// If the search hit its result limit, v only holds a page of the results, and
+// a result which was previously found may simply have fallen out of that page.
+// In that case the returned fingerprints also include prevFingerprints (up to
+// a total of maxFingerprints), so that results outside the page are not
+// reported as new once they come back.
+func filterNewResults(v *gqlSearchResponse, prevFingerprints []string) (fingerprints []string) {
+	prev := make(map[string]bool, len(prevFingerprints))
+	for _, fp := range prevFingerprints {
+		prev[fp] = true
+	}
+
+	var (
+		newResults []interface{}
+		count      int
+	)
+	for _, result := range v.Data.Search.Results.Results {
+		m, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch typeName, _ := m["__typename"].(string); typeName {
+		case "FileMatch":
+			repo, path := fileMatchRepoAndPath(m)
+			lineMatches, _ := m["lineMatches"].([]interface{})
+			if len(lineMatches) == 0 {
+				// Only the path matched.
+				fp := fingerprint(typeName, repo, path)
+				fingerprints = append(fingerprints, fp)
+				if !prev[fp] {
+					newResults = append(newResults, m)
+					count++
+				}
+				continue
+			}
+
+			// Identical lines are distinguished by their occurrence in the
+			// file, so that adding another copy of a matched line is
+			// detected as a new match.
+			occurrences := map[string]int{}
+			var newLineMatches []interface{}
+			for _, lm := range lineMatches {
+				lm, _ := lm.(map[string]interface{})
+				preview, _ := lm["preview"].(string)
+				preview = strings.TrimSpace(preview)
+				occurrences[preview]++
+
+				fp := fingerprint(typeName, repo, path, preview, strconv.Itoa(occurrences[preview]))
+				fingerprints = append(fingerprints, fp)
+				if !prev[fp] {
+					newLineMatches = append(newLineMatches, lm)
+				}
+			}
+			if len(newLineMatches) > 0 {
+				newMatch := make(map[string]interface{}, len(m))
+				for k, v := range m {
+					newMatch[k] = v
+				}
+				newMatch["lineMatches"] = newLineMatches
+				newResults = append(newResults, newMatch)
+				count += len(newLineMatches)
+			}
+
+		case "CommitSearchResult":
+			commit, _ := m["commit"].(map[string]interface{})
+			repo, _ := commit["repository"].(map[string]interface{})
+			name, _ := repo["name"].(string)
+			oid, _ := commit["oid"].(string)
+			fp := fingerprint(typeName, name, oid)
+			fingerprints = append(fingerprints, fp)
+			if !prev[fp] {
+				newResults = append(newResults, m)
+				count++
+			}
+
+		case "Repository":
+			name, _ := m["name"].(string)
+			fp := fingerprint(typeName, name)
+			fingerprints = append(fingerprints, fp)
+			if !prev[fp] {
+				newResults = append(newResults, m)
+				count++
+			}
+		}
+	}
+
+	if v.Data.Search.Results.LimitHit {
+		seen := make(map[string]bool, len(fingerprints))
+		for _, fp := range fingerprints {
+			seen[fp] = true
+		}
+		for _, fp := range prevFingerprints {
+			if len(fingerprints) >= maxFingerprints {
+				break
+			}
+			if !seen[fp] {
+				fingerprints = append(fingerprints, fp)
+			}
+		}
+	}
+
+	v.Data.Search.Results.Results = newResults
+	v.Data.Search.Results.ApproximateResultCount = strconv.Itoa(count)
+	return fingerprints
+}
+
+// fileMatchRepoAndPath returns the repository name and file path of a file
+// match from its resource URI (git://repo?rev#path).
+func fileMatchRepoAndPath(m map[string]interface{}) (repo, path string) {
+	resource, _ := m["resource"].(string)
+	u, err := url.Parse(resource)
+	if err != nil {
+		return resource, ""
+	}
+	return u.Host + u.Path, u.Fragment
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFilterNewResults(t *testing.T) {
+	search := func(results string) *gqlSearchResponse {
+		t.Helper()
+		var v gqlSearchResponse
+		if err := json.Unmarshal([]byte(results), &v.Data.Search.Results.Results); err != nil {
+			t.Fatal(err)
+		}
+		return &v
+	}
+
+	v := search(`[
+		{
+			"__typename": "FileMatch",
+			"resource": "git://github.com/foo/bar#main.go",
+			"lineMatches": [{"preview": "\tdeprecated.Do()", "lineNumber": 10}]
+		},
+		{
+			"__typename": "CommitSearchResult",
+			"commit": {"repository": {"name": "github.com/foo/bar"}, "oid": "deadbeef"}
+		},
+		{"__typename": "Repository", "name": "github.com/foo/baz"}
+	]`)
+	fingerprints := filterNewResults(v, nil)
+	if have, want := len(fingerprints), 3; have != want {
+		t.Fatalf("have %d fingerprints, want %d", have, want)
+	}
+	if have, want := len(v.Data.Search.Results.Results), 3; have != want {
+		t.Errorf("have %d new results, want %d", have, want)
+	}
+	if have, want := v.Data.Search.Results.ApproximateResultCount, "3"; have != want {
+		t.Errorf("have result count %q, want %q", have, want)
+	}
+
+	// The same match on a different line (and a second, identical match) in
+	// the file, plus a new file match and commit.
+	v = search(`[
+		{
+			"__typename": "FileMatch",
+			"resource": "git://github.com/foo/bar#main.go",
+			"lineMatches": [
+				{"preview": "\tdeprecated.Do()", "lineNumber": 12},
+				{"preview": "deprecated.Do()", "lineNumber": 20}
+			]
+		},
+		{
+			"__typename": "FileMatch",
+			"resource": "git://github.com/foo/bar#util.go",
+			"lineMatches": [{"preview": "deprecated.Do()", "lineNumber": 1}]
+		},
+		{
+			"__typename": "CommitSearchResult",
+			"commit": {"repository": {"name": "github.com/foo/bar"}, "oid": "deadbeef"}
+		},
+		{
+			"__typename": "CommitSearchResult",
+			"commit": {"repository": {"name": "github.com/foo/bar"}, "oid": "cafebabe"}
+		},
+		{"__typename": "Repository", "name": "github.com/foo/baz"}
+	]`)
+	fingerprints = filterNewResults(v, fingerprints)
+	if have, want := len(fingerprints), 6; have != want {
+		t.Fatalf("have %d fingerprints, want %d", have, want)
+	}
+
+	want := search(`[
+		{
+			"__typename": "FileMatch",
+			"resource": "git://github.com/foo/bar#main.go",
+			"lineMatches": [{"preview": "deprecated.Do()", "lineNumber": 20}]
+		},
+		{
+			"__typename": "FileMatch",
+			"resource": "git://github.com/foo/bar#util.go",
+			"lineMatches": [{"preview": "deprecated.Do()", "lineNumber": 1}]
+		},
+		{
+			"__typename": "CommitSearchResult",
+			"commit": {"repository": {"name": "github.com/foo/bar"}, "oid": "cafebabe"}
+		}
+	]`)
+	if have, want := v.Data.Search.Results.Results, want.Data.Search.Results.Results; !reflect.DeepEqual(have, want) {
+		t.Errorf("have new results %+v, want %+v", have, want)
+	}
+	if have, want := v.Data.Search.Results.ApproximateResultCount, "3"; have != want {
+		t.Errorf("have result count %q, want %q", have, want)
+	}
+
+	// Nothing changed since the last execution.
+	filterNewResults(v, fingerprints)
+	if have := v.Data.Search.Results.Results; len(have) != 0 {
+		t.Errorf("have new results %+v, want none", have)
+	}
+	if have, want := v.Data.Search.Results.ApproximateResultCount, "0"; have != want {
+		t.Errorf("have result count %q, want %q", have, want)
+	}
+}
+
+func TestFilterNewResults_limitHit(t *testing.T) {
+	search := func(limitHit bool, results string) *gqlSearchResponse {
+		t.Helper()
+		var v gqlSearchResponse
+		v.Data.Search.Results.LimitHit = limitHit
+		if err := json.Unmarshal([]byte(results), &v.Data.Search.Results.Results); err != nil {
+			t.Fatal(err)
+		}
+		return &v
+	}
+
+	fingerprints := filterNewResults(search(false, `[
+		{"__typename": "Repository", "name": "github.com/foo/a"},
+		{"__typename": "Repository", "name": "github.com/foo/b"}
+	]`), nil)
+
+	// The search now hits its limit: github.com/foo/a fell out of the page and
+	// github.com/foo/c took its place. github.com/foo/c is new.
+	v := search(true, `[
+		{"__typename": "Repository", "name": "github.com/foo/b"},
+		{"__typename": "Repository", "name": "github.com/foo/c"},
+		{
+			"__typename": "CommitSearchResult",
+			"commit": {"repository": {"name": "github.com/foo/b"}, "oid": "deadbeef"}
+		}
+	]`)
+	fingerprints = filterNewResults(v, fingerprints)
+	if have, want := len(fingerprints), 4; have != want {
+		t.Fatalf("have %d fingerprints, want %d", have, want)
+	}
+	want := search(false, `[
+		{"__typename": "Repository", "name": "github.com/foo/c"},
+		{
+			"__typename": "CommitSearchResult",
+			"commit": {"repository": {"name": "github.com/foo/b"}, "oid": "deadbeef"}
+		}
+	]`)
+	if have, want := v.Data.Search.Results.Results, want.Data.Search.Results.Results; !reflect.DeepEqual(have, want) {
+		t.Errorf("have new results %+v, want %+v", have, want)
+	}
+	if have, want := v.Data.Search.Results.ApproximateResultCount, "2"; have != want {
+		t.Errorf("have result count %q, want %q", have, want)
+	}
+
+	// The search no longer hits its limit, and github.com/foo/a is back. It
+	// is not new, while github.com/foo/d is.
+	v = search(false, `[
+		{"__typename": "Repository", "name": "github.com/foo/a"},
+		{"__typename": "Repository", "name": "github.com/foo/b"},
+		{"__typename": "Repository", "name": "github.com/foo/c"},
+		{"__typename": "Repository", "name": "github.com/foo/d"}
+	]`)
+	filterNewResults(v, fingerprints)
+	want = search(false, `[
+		{"__typename": "Repository", "name": "github.com/foo/d"}
+	]`)
+	if have, want := v.Data.Search.Results.Results, want.Data.Search.Results.Results; !reflect.DeepEqual(have, want) {
+		t.Errorf("have new results %+v, want %+v", have, want)
+	}
+
+	// The previous fingerprints kept at the limit are bounded, and those of
+	// the latest page are kept first.
+	prev := make([]string, maxFingerprints)
+	for i := range prev {
+		prev[i] = fmt.Sprint(i)
+	}
+	fingerprints = filterNewResults(search(true, `[
+		{"__typename": "Repository", "name": "github.com/foo/e"}
+	]`), prev)
+	if have, want := len(fingerprints), maxFingerprints; have != want {
+		t.Fatalf("have %d fingerprints, want %d", have, want)
+	}
+	if have, want := fingerprints[0], fingerprint("Repository", "github.com/foo/e"); have != want {
+		t.Errorf("have first fingerprint %q, want that of the latest result %q", have, want)
+	}
+}
@@ -91,6 +91,9 @@ const gqlSearchQuery = `query Search(
 						message
 					}
 				}
+				... on Repository {
+					name
+				}
 			}
 			alert {
 				title
@@ -113,6 +116,7 @@ type gqlSearchResponse struct {
 		Search struct {
 			Results struct {
 				ApproximateResultCount string
+				LimitHit               bool
 				Cloning                []*api.Repo
 				Timedout               []*api.Repo
 				Results                []interface{}
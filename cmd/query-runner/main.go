@@ -138,16 +138,10 @@ func (e *executorT) run(ctx context.Context) error {
 // runQuery runs the given query if an appropriate amount of time has elapsed
 // since it last ran.
 func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, query api.ConfigSavedQuery) error {
-	if !query.Notify && !query.NotifySlack {
+	if !query.Notify && !query.NotifySlack && query.Webhook == nil {
 		// No need to run this query because there will be nobody to notify.
 		return nil
 	}
-	if !strings.Contains(query.Query, "type:diff") && !strings.Contains(query.Query, "type:commit") {
-		// TODO(slimsag): we temporarily do not support non-commit search
-		// queries, since those do not support the after:"time" operator.
-		return nil
-	}
-
 	info, err := api.InternalClient.SavedQueriesGetInfo(ctx, query.Query)
 	if err != nil {
 		return errors.Wrap(err, "SavedQueriesGetInfo")
@@ -175,19 +169,38 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 		}
 	}
 
-	// Construct a new query which finds search results introduced after the
-	// last time we queried.
-	var latestKnownResult time.Time
+	// Commit and diff searches support the after:"time" operator, so construct
+	// a new query which finds search results introduced after the last time we
+	// queried. Other searches are run as-is, and their new results are
+	// determined by comparing the fingerprints of their results with those of
+	// the previous execution.
+	newQuery := query.Query
+	commitSearch := isCommitSearch(query.Query)
+	if commitSearch {
+		var latestKnownResult time.Time
+		if info != nil {
+			latestKnownResult = info.LatestResult
+		} else {
+			// We've never executed this search query before, so use the current
+			// time. We'll most certainly find nothing, which is okay.
+			latestKnownResult = time.Now()
+		}
+		afterTime := latestKnownResult.UTC().Format(time.RFC3339)
+		newQuery = strings.Join([]string{query.Query, fmt.Sprintf(`after:"%s"`, afterTime)}, " ")
+	} else if !strings.Contains(query.Query, "count:") {
+		// Only the results in the returned page can be detected as new, so
+		// run the search with a larger result limit than the default.
+		newQuery = strings.Join([]string{query.Query, fmt.Sprintf("count:%d", savedSearchResultCount)}, " ")
+	}
+	var prevFingerprints []string
 	if info != nil {
-		latestKnownResult = info.LatestResult
-	} else {
-		// We've never executed this search query before, so use the current
-		// time. We'll most certainly find nothing, which is okay.
-		latestKnownResult = time.Now()
+		prevFingerprints = info.ResultFingerprints
 	}
-	afterTime := latestKnownResult.UTC().Format(time.RFC3339)
-	newQuery := strings.Join([]string{query.Query, fmt.Sprintf(`after:"%s"`, afterTime)}, " ")
+	// Without previous fingerprints, all results of a non-commit search would
+	// be considered new. Instead, only record the fingerprints this time.
+	baseline := !commitSearch && prevFingerprints == nil
 	if debugPretendSavedQueryResultsExist {
+		baseline = false
 		debugPretendSavedQueryResultsExist = false
 		newQuery = query.Query
 	}
@@ -198,11 +211,24 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 	// constantly and potentially causing harm to the system. We'll retry at
 	// our normal interval, regardless of errors.
 	v, execDuration, searchErr := performSearch(ctx, newQuery)
+	latestResult := time.Now()
+	if commitSearch {
+		latestResult = latestResultTime(info, v, searchErr)
+	}
+	fingerprints := prevFingerprints
+	if searchErr == nil {
+		// Remove the results we already notified about.
+		fingerprints = filterNewResults(v, prevFingerprints)
+		if fingerprints == nil {
+			fingerprints = []string{}
+		}
+	}
 	if err := api.InternalClient.SavedQueriesSetInfo(ctx, &api.SavedQueryInfo{
-		Query:        query.Query,
-		LastExecuted: time.Now(),
-		LatestResult: latestResultTime(info, v, searchErr),
-		ExecDuration: execDuration,
+		Query:              query.Query,
+		LastExecuted:       time.Now(),
+		LatestResult:       latestResult,
+		ExecDuration:       execDuration,
+		ResultFingerprints: fingerprints,
 	}); err != nil {
 		return errors.Wrap(err, "SavedQueriesSetInfo")
 	}
@@ -210,6 +236,9 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 	if searchErr != nil {
 		return searchErr
 	}
+	if baseline {
+		return nil
+	}
 
 	// Send notifications for new search results in a separate goroutine, so
 	// that we don't block other search queries from running in sequence (which
@@ -222,6 +251,15 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 	return nil
 }
 
+// savedSearchResultCount is the result limit of saved searches that are not
+// commit or diff searches and don't specify their own count:.
+const savedSearchResultCount = 1000
+
+// isCommitSearch reports whether query is a commit or diff search.
+func isCommitSearch(query string) bool {
+	return strings.Contains(query, "type:diff") || strings.Contains(query, "type:commit")
+}
+
 func performSearch(ctx context.Context, query string) (v *gqlSearchResponse, execDuration time.Duration, err error) {
 	attempts := 0
 	for {
@@ -287,6 +325,13 @@ func notify(ctx context.Context, spec api.SavedQueryIDSpec, query api.ConfigSave
 	if err != nil {
 		return err
 	}
+	if query.Notify {
+		if r, err := withoutMutedEmail(ctx, spec, recipients); err != nil {
+			log15.Error("Failed to list users who muted email notifications for saved search.", "description", query.Description, "error", err)
+		} else {
+			recipients = r
+		}
+	}
 
 	// Send slack notifications.
 	n := &notifier{
@@ -297,9 +342,10 @@ func notify(ctx context.Context, spec api.SavedQueryIDSpec, query api.ConfigSave
 		recipients: recipients,
 	}
 
-	// Send Slack and email notifications.
+	// Send Slack, email and webhook notifications.
 	n.slackNotify(ctx)
 	n.emailNotify(ctx)
+	n.webhookNotify(ctx)
 	return nil
 }
 
@@ -312,8 +358,9 @@ type notifier struct {
 }
 
 const (
-	utmSourceEmail = "saved-search-email"
-	utmSourceSlack = "saved-search-slack"
+	utmSourceEmail   = "saved-search-email"
+	utmSourceSlack   = "saved-search-slack"
+	utmSourceWebhook = "saved-search-webhook"
 )
 
 func searchURL(query, utmSource string) string {
@@ -70,6 +70,29 @@ func getNotificationRecipients(ctx context.Context, spec api.SavedQueryIDSpec, q
 	return recipients, nil
 }
 
+// withoutMutedEmail returns the recipients without the email notifications of
+// the users who muted email notifications about the saved search (by replying
+// to one).
+func withoutMutedEmail(ctx context.Context, spec api.SavedQueryIDSpec, rs recipients) (recipients, error) {
+	userIDs, err := api.InternalClient.SavedQueriesMutedUsers(ctx, spec.Key)
+	if err != nil {
+		return nil, err
+	}
+	muted := make(map[int32]bool, len(userIDs))
+	for _, id := range userIDs {
+		muted[id] = true
+	}
+
+	var result recipients
+	for _, r := range rs {
+		if r.email && r.spec.userID != 0 && muted[r.spec.userID] {
+			r = &recipient{spec: r.spec, slack: r.slack}
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
 type recipients []*recipient
 
 // add adds the new recipient, merging it into an existing slice element if one already exists for
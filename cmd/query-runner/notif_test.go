@@ -63,6 +63,34 @@ func TestGetNotificationRecipients(t *testing.T) {
 	})
 }
 
+func TestWithoutMutedEmail(t *testing.T) {
+	api.MockSavedQueriesMutedUsers = func(key string) ([]int32, error) {
+		if want := "7"; key != want {
+			t.Errorf("got key %q, want %q", key, want)
+		}
+		return []int32{2, 3}, nil
+	}
+	defer func() { api.MockSavedQueriesMutedUsers = nil }()
+
+	have, err := withoutMutedEmail(context.Background(), api.SavedQueryIDSpec{Key: "7"}, recipients{
+		{spec: recipientSpec{userID: 1}, email: true},
+		{spec: recipientSpec{userID: 2}, email: true},
+		{spec: recipientSpec{userID: 3}, email: true, slack: true},
+		{spec: recipientSpec{orgID: 2}, slack: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (recipients{
+		{spec: recipientSpec{userID: 1}, email: true},
+		{spec: recipientSpec{userID: 2}},
+		{spec: recipientSpec{userID: 3}, slack: true},
+		{spec: recipientSpec{orgID: 2}, slack: true},
+	}); !reflect.DeepEqual(have, want) {
+		t.Errorf("got %v, want %v", have, want)
+	}
+}
+
 func TestDiffNotificationRecipients(t *testing.T) {
 	tests := []struct {
 		old, new               recipients
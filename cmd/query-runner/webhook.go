@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context/ctxhttp"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/env"
+)
+
+const (
+	webhookEventResults = "results"
+	webhookEventTest    = "test"
+
+	// webhookSignatureHeader is the header containing the HMAC-SHA256 of the
+	// request body, keyed with the webhook secret.
+	webhookSignatureHeader = "X-Sourcegraph-Signature"
+	webhookEventHeader     = "X-Sourcegraph-Event"
+
+	webhookMaxAttempts = 5
+)
+
+var (
+	// 🚨 SECURITY: Any user who can create a saved search can choose its
+	// webhook URL, so the client refuses to connect to addresses inside the
+	// cluster (see webhookDialControl). It doesn't use a proxy, since that
+	// would bypass the check.
+	webhookClient = &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+				Control:   webhookDialControl,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+
+	webhookAllowedNetworks = mustParseCIDRs(env.Get("SAVED_SEARCH_WEBHOOK_ALLOWED_NETWORKS", "", "comma-separated CIDRs of private networks (such as 10.0.0.0/8) that saved search webhooks may be sent to"))
+
+	// webhookBlockedNetworks are the loopback, private, link-local, and
+	// unspecified networks that webhooks may not be sent to, unless they are
+	// in webhookAllowedNetworks.
+	webhookBlockedNetworks = mustParseCIDRs("0.0.0.0/8,10.0.0.0/8,100.64.0.0/10,127.0.0.0/8,169.254.0.0/16,172.16.0.0/12,192.168.0.0/16,::/128,::1/128,fc00::/7,fe80::/10")
+
+	// webhookBackoff is the delay before the first retry of a failed webhook
+	// request. It doubles with each subsequent retry.
+	webhookBackoff = 2 * time.Second
+)
+
+// webhookPayload is the JSON body POSTed to a saved search webhook.
+type webhookPayload struct {
+	Event       string          `json:"event"`
+	Description string          `json:"description"`
+	Query       string          `json:"query"`
+	ResultCount string          `json:"resultCount"`
+	SearchURL   string          `json:"searchURL"`
+	Results     []webhookResult `json:"results"`
+}
+
+// webhookResult describes a single new search result in a webhookPayload.
+type webhookResult struct {
+	Type       string `json:"type"`
+	Repository string `json:"repository"`
+
+	// Set for commit and diff search results.
+	Commit  string `json:"commit,omitempty"`
+	Message string `json:"message,omitempty"`
+	Diff    string `json:"diff,omitempty"`
+
+	// Set for file search results.
+	Path  string             `json:"path,omitempty"`
+	Lines []webhookLineMatch `json:"lines,omitempty"`
+}
+
+type webhookLineMatch struct {
+	LineNumber int    `json:"lineNumber"`
+	Preview    string `json:"preview"`
+}
+
+func (n *notifier) webhookNotify(ctx context.Context) {
+	if n.query.Webhook == nil {
+		return
+	}
+
+	payload := &webhookPayload{
+		Event:       webhookEventResults,
+		Description: n.query.Description,
+		Query:       n.newQuery,
+		ResultCount: n.results.Data.Search.Results.ApproximateResultCount,
+		SearchURL:   searchURL(n.newQuery, utmSourceWebhook),
+		Results:     webhookResults(n.results.Data.Search.Results.Results),
+	}
+	if err := postWebhook(ctx, n.query.Webhook, payload); err != nil {
+		log15.Error("Failed to post saved search webhook notification.", "description", n.query.Description, "error", err)
+		return
+	}
+	logEvent(0, "", "SavedSearchWebhookNotificationSent", "results")
+}
+
+// webhookNotifyTest sends a test notification without any results to the
+// webhook of the saved search.
+func webhookNotifyTest(ctx context.Context, query api.ConfigSavedQuery) error {
+	if query.Webhook == nil {
+		return nil
+	}
+	return postWebhook(ctx, query.Webhook, &webhookPayload{
+		Event:       webhookEventTest,
+		Description: query.Description,
+		Query:       query.Query,
+		ResultCount: "0",
+		SearchURL:   searchURL(query.Query, utmSourceWebhook),
+		Results:     []webhookResult{},
+	})
+}
+
+// webhookResults converts the search results returned by the GraphQL API
+// into their webhook payload representation. Results of unknown types are
+// skipped.
+func webhookResults(results []interface{}) []webhookResult {
+	converted := make([]webhookResult, 0, len(results))
+	for _, result := range results {
+		m, ok := result.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch typeName, _ := m["__typename"].(string); typeName {
+		case "CommitSearchResult":
+			commit, _ := m["commit"].(map[string]interface{})
+			repo, _ := commit["repository"].(map[string]interface{})
+			r := webhookResult{Type: "commit"}
+			r.Repository, _ = repo["name"].(string)
+			r.Commit, _ = commit["oid"].(string)
+			r.Message, _ = commit["message"].(string)
+			if diff, ok := m["diffPreview"].(map[string]interface{}); ok {
+				r.Type = "diff"
+				r.Diff, _ = diff["value"].(string)
+			}
+			converted = append(converted, r)
+
+		case "FileMatch":
+			r := webhookResult{Type: "file"}
+			r.Repository, r.Path = fileMatchRepoAndPath(m)
+			lineMatches, _ := m["lineMatches"].([]interface{})
+			for _, lm := range lineMatches {
+				lm, _ := lm.(map[string]interface{})
+				preview, _ := lm["preview"].(string)
+				lineNumber, _ := lm["lineNumber"].(float64)
+				r.Lines = append(r.Lines, webhookLineMatch{LineNumber: int(lineNumber), Preview: preview})
+			}
+			converted = append(converted, r)
+
+		case "Repository":
+			r := webhookResult{Type: "repository"}
+			r.Repository, _ = m["name"].(string)
+			converted = append(converted, r)
+		}
+	}
+	return converted
+}
+
+// postWebhook POSTs the JSON encoded payload to the webhook, retrying with
+// exponential backoff if the request fails or the server responds with a
+// 429 or 5xx status.
+func postWebhook(ctx context.Context, webhook *api.SavedQueryWebhook, payload *webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := postWebhookOnce(ctx, webhook, payload.Event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= webhookMaxAttempts {
+			return errors.Wrapf(err, "webhook %s failed after %d attempt(s)", webhook.URL, attempt)
+		}
+
+		log15.Warn("saved search webhook request failed (retrying)", "url", webhook.URL, "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// postWebhookOnce sends a single webhook request. It reports whether a
+// failed request should be retried.
+func postWebhookOnce(ctx context.Context, webhook *api.SavedQueryWebhook, event string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, value := range webhook.Headers {
+		// 🚨 SECURITY: These are also rejected when the webhook is saved, but
+		// check again in case the saved search was stored some other way.
+		if api.IsReservedWebhookHeader(name) {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, event)
+	if webhook.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, "sha256="+webhookSignature(webhook.Secret, body))
+	}
+
+	resp, err := ctxhttp.Do(ctx, webhookClient, req)
+	if err != nil {
+		return ctx.Err() == nil && !isBlockedWebhookAddress(err), err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// webhookDialControl refuses connections to addresses in
+// webhookBlockedNetworks. It is called with the resolved IP address for every
+// connection, including those for redirects, so it can't be circumvented with
+// DNS names that resolve to internal addresses.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("webhook address %q is not an IP address", host)
+	}
+	for _, n := range webhookAllowedNetworks {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	for _, n := range webhookBlockedNetworks {
+		if n.Contains(ip) {
+			return &blockedWebhookAddressError{ip: ip}
+		}
+	}
+	return nil
+}
+
+type blockedWebhookAddressError struct {
+	ip net.IP
+}
+
+func (e *blockedWebhookAddressError) Error() string {
+	return fmt.Sprintf("webhook address %s is not allowed (loopback, private, and link-local addresses are blocked unless listed in SAVED_SEARCH_WEBHOOK_ALLOWED_NETWORKS)", e.ip)
+}
+
+// isBlockedWebhookAddress reports whether err is the error returned by the
+// HTTP client when webhookDialControl refused the connection.
+func isBlockedWebhookAddress(err error) bool {
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	if e, ok := err.(*net.OpError); ok {
+		err = e.Err
+	}
+	_, ok := err.(*blockedWebhookAddressError)
+	return ok
+}
+
+func mustParseCIDRs(s string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Fatalf("invalid CIDR %q: %s", cidr, err)
+		}
+		networks = append(networks, n)
+	}
+	return networks
+}
+
+// webhookSignature returns the hex encoded HMAC-SHA256 of body keyed with
+// secret.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+func TestPostWebhook(t *testing.T) {
+	defer func(d time.Duration) { webhookBackoff = d }(webhookBackoff)
+	webhookBackoff = time.Millisecond
+	defer func(n []*net.IPNet) { webhookAllowedNetworks = n }(webhookAllowedNetworks)
+	webhookAllowedNetworks = mustParseCIDRs("127.0.0.0/8,::1/128")
+
+	payload := &webhookPayload{
+		Event:       webhookEventResults,
+		Description: "new TODOs",
+		Query:       `TODO type:diff after:"2019-09-01T12:00:00Z"`,
+		ResultCount: "1",
+		Results:     []webhookResult{{Type: "diff", Repository: "github.com/foo/bar", Commit: "deadbeef"}},
+	}
+
+	t.Run("retries", func(t *testing.T) {
+		var requests int
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if have, want := r.Header.Get(webhookSignatureHeader), "sha256="+webhookSignature("s3cr3t", body); have != want {
+				t.Errorf("have signature %q, want %q", have, want)
+			}
+			if have, want := r.Header.Get(webhookEventHeader), webhookEventResults; have != want {
+				t.Errorf("have event %q, want %q", have, want)
+			}
+			if have, want := r.Header.Get("Authorization"), "token abc"; have != want {
+				t.Errorf("have Authorization header %q, want %q", have, want)
+			}
+
+			var have webhookPayload
+			if err := json.Unmarshal(body, &have); err != nil {
+				t.Error(err)
+				return
+			}
+			if !reflect.DeepEqual(&have, payload) {
+				t.Errorf("have payload %+v, want %+v", have, payload)
+			}
+		}))
+		defer s.Close()
+
+		err := postWebhook(context.Background(), &api.SavedQueryWebhook{
+			URL:     s.URL,
+			Secret:  "s3cr3t",
+			Headers: map[string]string{"Authorization": "token abc"},
+		}, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requests != 3 {
+			t.Errorf("have %d requests, want 3", requests)
+		}
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		var requests int
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.Header.Get(webhookSignatureHeader) != "" {
+				t.Error("request without secret was signed")
+			}
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}))
+		defer s.Close()
+
+		if err := postWebhook(context.Background(), &api.SavedQueryWebhook{URL: s.URL}, payload); err == nil {
+			t.Fatal("expected error")
+		}
+		if requests != 1 {
+			t.Errorf("have %d requests, want 1", requests)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		var requests int
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer s.Close()
+
+		if err := postWebhook(context.Background(), &api.SavedQueryWebhook{URL: s.URL}, payload); err == nil {
+			t.Fatal("expected error")
+		}
+		if requests != webhookMaxAttempts {
+			t.Errorf("have %d requests, want %d", requests, webhookMaxAttempts)
+		}
+	})
+}
+
+func TestPostWebhook_blockedAddresses(t *testing.T) {
+	var requests int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer s.Close()
+
+	if err := postWebhook(context.Background(), &api.SavedQueryWebhook{URL: s.URL}, &webhookPayload{}); err == nil {
+		t.Fatal("expected error for loopback webhook address")
+	}
+	if requests != 0 {
+		t.Errorf("have %d requests, want 0", requests)
+	}
+}
+
+func TestWebhookDialControl(t *testing.T) {
+	defer func(n []*net.IPNet) { webhookAllowedNetworks = n }(webhookAllowedNetworks)
+	webhookAllowedNetworks = mustParseCIDRs("10.1.0.0/16")
+
+	for address, wantErr := range map[string]bool{
+		"93.184.216.34:443":     false,
+		"[2606:2800:220::]:443": false,
+		"10.1.2.3:80":           false,
+		"127.0.0.1:80":          true,
+		"[::1]:80":              true,
+		"0.0.0.0:80":            true,
+		"10.2.0.1:80":           true,
+		"172.16.0.1:80":         true,
+		"192.168.1.1:80":        true,
+		"169.254.169.254:80":    true,
+		"[fe80::1]:80":          true,
+		"[fd00::1]:80":          true,
+		"[::ffff:127.0.0.1]:80": true,
+	} {
+		if err := webhookDialControl("tcp", address, nil); (err != nil) != wantErr {
+			t.Errorf("%s: got error %v, want error: %t", address, err, wantErr)
+		}
+	}
+}
+
+func TestWebhookResults(t *testing.T) {
+	var results []interface{}
+	err := json.Unmarshal([]byte(`[
+		{
+			"__typename": "CommitSearchResult",
+			"diffPreview": {"value": "-foo\n+bar\n"},
+			"commit": {"repository": {"name": "github.com/foo/bar"}, "oid": "deadbeef", "message": "fix foo"}
+		},
+		{
+			"__typename": "FileMatch",
+			"resource": "git://github.com/foo/baz?master#sub/main.go",
+			"lineMatches": [{"preview": "// TODO", "lineNumber": 41}]
+		},
+		{"__typename": "Repository", "name": "github.com/foo/qux"},
+		{"__typename": "Unknown"}
+	]`), &results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []webhookResult{
+		{Type: "diff", Repository: "github.com/foo/bar", Commit: "deadbeef", Message: "fix foo", Diff: "-foo\n+bar\n"},
+		{Type: "file", Repository: "github.com/foo/baz", Path: "sub/main.go", Lines: []webhookLineMatch{{LineNumber: 41, Preview: "// TODO"}}},
+		{Type: "repository", Repository: "github.com/foo/qux"},
+	}
+	if have := webhookResults(results); !reflect.DeepEqual(have, want) {
+		t.Errorf("have results %+v, want %+v", have, want)
+	}
+}
@@ -132,6 +132,35 @@ func main() {
 		go func() { log.Fatal(syncer.Run(ctx, repos.GetUpdateInterval())) }()
 	}
 
+	// The changeset syncer needs the undecorated sources, since observed
+	// sources don't implement repos.ChangesetSource.
+	changesetSyncer := &repos.ChangesetSyncer{
+		Store:      store,
+		Changesets: repos.NewDBStore(db, sql.TxOptions{}),
+		Sourcer:    repos.NewSourcer(cf),
+	}
+	server.ChangesetSyncer = changesetSyncer
+
+	if !envvar.SourcegraphDotComMode() {
+		go func() { log.Fatal(changesetSyncer.Run(ctx, repos.GetChangesetSyncInterval())) }()
+	}
+
+	reviewCommentSyncer := &repos.ReviewCommentSyncer{
+		Store:    store,
+		Comments: repos.NewDBStore(db, sql.TxOptions{}),
+		Sourcer:  repos.NewSourcer(cf),
+		Config: func() *schema.ImportReviewComments {
+			if d := conf.Get().Discussions; d != nil {
+				return d.ImportReviewComments
+			}
+			return nil
+		},
+	}
+
+	if !envvar.SourcegraphDotComMode() {
+		go func() { log.Fatal(reviewCommentSyncer.Run(ctx, repos.GetReviewCommentSyncInterval())) }()
+	}
+
 	gps := repos.NewGitolitePhabricatorMetadataSyncer(store)
 
 	// Start new repo syncer updates scheduler relay thread.
@@ -2,15 +2,18 @@ package repos
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
@@ -109,6 +112,251 @@ func (s BitbucketServerSource) ExternalServices() ExternalServices {
 	return ExternalServices{s.svc}
 }
 
+// LoadChangesets loads the pull requests of the given changesets, and the
+// build statuses of their latest commits, from the Bitbucket Server API. The
+// ExternalID of each changeset must be a pull request ID.
+func (s BitbucketServerSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
+	for _, c := range cs {
+		repo, ok := c.Repo.Metadata.(*bitbucketserver.Repo)
+		if !ok {
+			return errors.Errorf("changeset %d: repository %q is not a Bitbucket Server repository", c.ID, c.Repo.Name)
+		}
+
+		id, err := strconv.Atoi(c.ExternalID)
+		if err != nil {
+			return errors.Wrapf(err, "changeset %d: invalid pull request ID %q", c.ID, c.ExternalID)
+		}
+
+		pr := &bitbucketserver.PullRequest{ID: id}
+		pr.ToRef.Repository.Slug = repo.Slug
+		pr.ToRef.Repository.Project = repo.Project
+
+		if err := s.client.LoadPullRequest(ctx, pr); err != nil {
+			return errors.Wrapf(err, "changeset %d", c.ID)
+		}
+
+		var statuses []*bitbucketserver.BuildStatus
+		if commit := pr.FromRef.LatestCommit; commit != "" {
+			var next *bitbucketserver.PageToken
+			for next.HasMore() {
+				var page []*bitbucketserver.BuildStatus
+				if page, next, err = s.client.CommitBuildStatuses(ctx, commit, next); err != nil {
+					return errors.Wrapf(err, "changeset %d", c.ID)
+				}
+				statuses = append(statuses, page...)
+			}
+		}
+
+		metadata, err := json.Marshal(pr)
+		if err != nil {
+			return err
+		}
+
+		c.Title = pr.Title
+		c.URL = ""
+		if len(pr.Links.Self) > 0 {
+			c.URL = pr.Links.Self[0].Href
+		}
+		c.State = bitbucketServerState(pr)
+		c.ReviewState = bitbucketServerReviewState(pr)
+		c.CheckState = bitbucketServerCheckState(statuses)
+		c.ExternalCreatedAt = unixMilliToTime(pr.CreatedDate)
+		c.ExternalClosedAt = time.Time{}
+		c.ExternalMergedAt = time.Time{}
+		if pr.Closed {
+			// Bitbucket Server versions older than 5.10 don't report the
+			// closing date, but closed pull requests can't be updated.
+			closed := pr.ClosedDate
+			if closed == 0 {
+				closed = pr.UpdatedDate
+			}
+			c.ExternalClosedAt = unixMilliToTime(closed)
+			if pr.State == "MERGED" {
+				c.ExternalMergedAt = c.ExternalClosedAt
+			}
+		}
+		c.Metadata = metadata
+	}
+
+	return nil
+}
+
+// CreateChangeset opens a pull request from spec.HeadRef into spec.BaseRef and
+// sets the ExternalID of the given changeset to its ID.
+func (s BitbucketServerSource) CreateChangeset(ctx context.Context, c *Changeset, spec ChangesetSpec) error {
+	repo, ok := c.Repo.Metadata.(*bitbucketserver.Repo)
+	if !ok {
+		return errors.Errorf("repository %q is not a Bitbucket Server repository", c.Repo.Name)
+	}
+
+	pr := &bitbucketserver.PullRequest{Title: spec.Title, Description: spec.Body}
+	pr.FromRef.ID = spec.HeadRef
+	pr.ToRef.ID = spec.BaseRef
+	pr.ToRef.Repository.Slug = repo.Slug
+	pr.ToRef.Repository.Project = repo.Project
+
+	if err := s.client.CreatePullRequest(ctx, pr); err != nil {
+		return errors.Wrapf(err, "creating pull request in repository %q", c.Repo.Name)
+	}
+
+	c.ExternalID = strconv.Itoa(pr.ID)
+	return nil
+}
+
+func bitbucketServerState(pr *bitbucketserver.PullRequest) types.ChangesetState {
+	switch pr.State {
+	case "MERGED":
+		return types.ChangesetStateMerged
+	case "DECLINED":
+		return types.ChangesetStateClosed
+	default:
+		return types.ChangesetStateOpen
+	}
+}
+
+// bitbucketServerReviewState returns the review state of the given pull
+// request: a reviewer marking it as needing work takes precedence over
+// approvals.
+func bitbucketServerReviewState(pr *bitbucketserver.PullRequest) types.ChangesetReviewState {
+	state := types.ChangesetReviewStatePending
+	for _, r := range pr.Reviewers {
+		switch r.Status {
+		case "NEEDS_WORK":
+			return types.ChangesetReviewStateChangesRequested
+		case "APPROVED":
+			state = types.ChangesetReviewStateApproved
+		}
+	}
+	return state
+}
+
+// bitbucketServerCheckState combines the given build statuses of a commit: any
+// failed build fails the checks, and any build in progress keeps them pending.
+func bitbucketServerCheckState(statuses []*bitbucketserver.BuildStatus) types.ChangesetCheckState {
+	if len(statuses) == 0 {
+		return ""
+	}
+	state := types.ChangesetCheckStatePassed
+	for _, s := range statuses {
+		switch s.State {
+		case "FAILED":
+			return types.ChangesetCheckStateFailed
+		case "INPROGRESS":
+			state = types.ChangesetCheckStatePending
+		}
+	}
+	return state
+}
+
+// ListReviewComments returns the comments on the diffs of the pull requests of
+// the given repository, and their replies, from the Bitbucket Server API.
+func (s BitbucketServerSource) ListReviewComments(ctx context.Context, r *Repo, since time.Time) ([]*ReviewComment, error) {
+	repo, ok := r.Metadata.(*bitbucketserver.Repo)
+	if !ok {
+		return nil, errors.Errorf("repository %q is not a Bitbucket Server repository", r.Name)
+	}
+
+	var cs []*ReviewComment
+	var next *bitbucketserver.PageToken
+	for next.HasMore() {
+		var prs []*bitbucketserver.PullRequest
+		var err error
+		if prs, next, err = s.client.PullRequests(ctx, repo.Project.Key, repo.Slug, "ALL", next); err != nil {
+			return nil, err
+		}
+
+		for _, pr := range prs {
+			// Pull requests are listed most recently updated first, and
+			// adding a comment updates a pull request.
+			if !since.IsZero() && unixMilliToTime(pr.UpdatedDate).Before(since) {
+				return cs, nil
+			}
+
+			prComments, err := s.listPullRequestReviewComments(ctx, r, repo, pr, since)
+			if err != nil {
+				return nil, errors.Wrapf(err, "pull request %d", pr.ID)
+			}
+			cs = append(cs, prComments...)
+		}
+	}
+
+	return cs, nil
+}
+
+func (s BitbucketServerSource) listPullRequestReviewComments(ctx context.Context, r *Repo, repo *bitbucketserver.Repo, pr *bitbucketserver.PullRequest, since time.Time) ([]*ReviewComment, error) {
+	pr.ToRef.Repository.Slug = repo.Slug
+	pr.ToRef.Repository.Project = repo.Project
+
+	prURL := ""
+	if len(pr.Links.Self) > 0 {
+		prURL = pr.Links.Self[0].Href
+	}
+
+	var cs []*ReviewComment
+	var next *bitbucketserver.PageToken
+	for next.HasMore() {
+		var activities []*bitbucketserver.Activity
+		var err error
+		if activities, next, err = s.client.PullRequestActivities(ctx, pr, next); err != nil {
+			return nil, err
+		}
+
+		for _, a := range activities {
+			// Replies and edits are part of the comment of the activity
+			// that added it.
+			if a.Action != "COMMENTED" || a.CommentAction != "ADDED" || a.Comment == nil || a.CommentAnchor == nil {
+				continue
+			}
+
+			anchor := a.CommentAnchor
+			path, commit := anchor.Path, anchor.FromHash
+			if anchor.FileType == "FROM" {
+				commit = anchor.ToHash
+				if anchor.SrcPath != "" {
+					path = anchor.SrcPath
+				}
+			}
+
+			root := strconv.Itoa(a.Comment.ID)
+			var walk func(c *bitbucketserver.Comment, inReplyTo string)
+			walk = func(c *bitbucketserver.Comment, inReplyTo string) {
+				if since.IsZero() || !unixMilliToTime(c.UpdatedDate).Before(since) {
+					rc := &ReviewComment{
+						ExternalServiceType: r.ExternalRepo.ServiceType,
+						ExternalServiceID:   r.ExternalRepo.ServiceID,
+						ExternalID:          strconv.Itoa(c.ID),
+						InReplyTo:           inReplyTo,
+						Author:              c.Author.Name,
+						URL:                 fmt.Sprintf("%s/overview?commentId=%d", prURL, c.ID),
+						Body:                c.Text,
+						Path:                path,
+						Commit:              commit,
+						CreatedAt:           unixMilliToTime(c.CreatedDate),
+						UpdatedAt:           unixMilliToTime(c.UpdatedDate),
+					}
+					if inReplyTo == "" {
+						rc.Line = anchor.Line
+					}
+					cs = append(cs, rc)
+				}
+				for _, reply := range c.Comments {
+					walk(reply, root)
+				}
+			}
+			walk(a.Comment, "")
+		}
+	}
+
+	return cs, nil
+}
+
+func unixMilliToTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
+
 func (s BitbucketServerSource) makeRepo(repo *bitbucketserver.Repo) *Repo {
 	host, err := url.Parse(s.config.Url)
 	if err != nil {
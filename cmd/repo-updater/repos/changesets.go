@@ -0,0 +1,260 @@
+package repos
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// A ChangesetSource can load the state of changesets (such as pull requests)
+// from the code host of its external service.
+type ChangesetSource interface {
+	// LoadChangesets loads the given Changesets from the code host and
+	// updates their code host fields (Title, URL, State, ReviewState,
+	// CheckState, the External* fields and Metadata).
+	LoadChangesets(context.Context, ...*Changeset) error
+}
+
+// A ChangesetCreator can create changesets (such as pull requests) on the code
+// host of its external service.
+type ChangesetCreator interface {
+	// CreateChangeset opens a changeset in the repository of the given
+	// Changeset as described by spec and sets its ExternalID.
+	CreateChangeset(context.Context, *Changeset, ChangesetSpec) error
+}
+
+// ChangesetSpec describes a changeset to be created by a ChangesetCreator.
+type ChangesetSpec struct {
+	Title string
+	Body  string
+	// BaseRef is the full name of the ref that the changeset is merged into
+	// (e.g. "refs/heads/master").
+	BaseRef string
+	// HeadRef is the full name of the ref of the same repository that has the
+	// changes of the changeset.
+	HeadRef string
+}
+
+// A Changeset of a Repo.
+type Changeset struct {
+	*types.Changeset
+	Repo *Repo
+}
+
+// A ChangesetStore exposes methods to read and write the changesets of
+// campaigns.
+type ChangesetStore interface {
+	ListChangesets(context.Context, StoreListChangesetsArgs) ([]*types.Changeset, error)
+	UpdateChangesets(ctx context.Context, cs ...*types.Changeset) error
+}
+
+// StoreListChangesetsArgs is a query arguments type used by
+// the ListChangesets method of ChangesetStore implementations.
+type StoreListChangesetsArgs struct {
+	// IDs of changesets to list. When zero-valued, this is omitted from the predicate set.
+	IDs []int64
+}
+
+// A ChangesetSyncer periodically loads the state of the changesets of all
+// campaigns from their code hosts and stores it.
+type ChangesetSyncer struct {
+	Store      Store
+	Changesets ChangesetStore
+	// Sourcer is used to create the sources of the external services of the
+	// changesets' repositories. Sources that don't implement ChangesetSource
+	// can't sync changesets.
+	Sourcer Sourcer
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// Run runs Sync at the specified interval.
+func (s *ChangesetSyncer) Run(ctx context.Context, interval time.Duration) error {
+	for ctx.Err() == nil {
+		if err := s.Sync(ctx); err != nil {
+			log15.Error("ChangesetSyncer", "error", err)
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+		}
+	}
+
+	return ctx.Err()
+}
+
+// Sync syncs all changesets.
+func (s *ChangesetSyncer) Sync(ctx context.Context) error {
+	cs, err := s.Changesets.ListChangesets(ctx, StoreListChangesetsArgs{})
+	if err != nil {
+		return errors.Wrap(err, "changeset-syncer.sync.list-changesets")
+	}
+	return s.SyncChangesets(ctx, cs...)
+}
+
+// SyncChangesets loads the given changesets from their code hosts and stores
+// them. A failure to sync some of the changesets (e.g., because their code host
+// is unreachable) doesn't prevent the others from being synced; all errors are
+// returned combined.
+func (s *ChangesetSyncer) SyncChangesets(ctx context.Context, cs ...*types.Changeset) error {
+	if len(cs) == 0 {
+		return nil
+	}
+
+	repoIDs := make([]uint32, 0, len(cs))
+	seen := make(map[uint32]bool, len(cs))
+	for _, c := range cs {
+		if id := uint32(c.RepoID); !seen[id] {
+			seen[id] = true
+			repoIDs = append(repoIDs, id)
+		}
+	}
+
+	rs, err := s.Store.ListRepos(ctx, StoreListReposArgs{IDs: repoIDs})
+	if err != nil {
+		return errors.Wrap(err, "changeset-syncer.sync-changesets.list-repos")
+	}
+
+	reposByID := make(map[uint32]*Repo, len(rs))
+	var svcIDs []int64
+	seenSvc := make(map[int64]bool)
+	for _, r := range rs {
+		reposByID[r.ID] = r
+		for _, id := range r.ExternalServiceIDs() {
+			if !seenSvc[id] {
+				seenSvc[id] = true
+				svcIDs = append(svcIDs, id)
+			}
+		}
+	}
+
+	var errs *multierror.Error
+
+	sources, err := s.changesetSources(ctx, svcIDs)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	// Each changeset is loaded from the first of its repository's external
+	// services that can load changesets.
+	groups := make(map[int64][]*Changeset)
+	for _, c := range cs {
+		r := reposByID[uint32(c.RepoID)]
+		if r == nil {
+			errs = multierror.Append(errs, errors.Errorf("changeset %d: repository %d not found", c.ID, c.RepoID))
+			continue
+		}
+
+		ids := r.ExternalServiceIDs()
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		var found bool
+		for _, id := range ids {
+			if _, ok := sources[id]; ok {
+				groups[id] = append(groups[id], &Changeset{Changeset: c, Repo: r})
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = multierror.Append(errs, errors.Errorf("changeset %d: no code host connection of repository %q supports changesets", c.ID, r.Name))
+		}
+	}
+
+	for _, id := range svcIDs {
+		group := groups[id]
+		if len(group) == 0 {
+			continue
+		}
+
+		if err := sources[id].LoadChangesets(ctx, group...); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "loading changesets from external service %d", id))
+			continue
+		}
+
+		now := s.now()
+		synced := make([]*types.Changeset, 0, len(group))
+		for _, c := range group {
+			c.SyncedAt = now
+			c.UpdatedAt = now
+			synced = append(synced, c.Changeset)
+		}
+
+		if err := s.Changesets.UpdateChangesets(ctx, synced...); err != nil {
+			errs = multierror.Append(errs, errors.Wrap(err, "changeset-syncer.sync-changesets.update-changesets"))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// CreateChangeset opens a changeset described by spec on the code host of the
+// repository of the given changeset and sets its ExternalID. The changeset is
+// not stored: it is up to the caller to record and sync it.
+func (s *ChangesetSyncer) CreateChangeset(ctx context.Context, c *types.Changeset, spec ChangesetSpec) error {
+	rs, err := s.Store.ListRepos(ctx, StoreListReposArgs{IDs: []uint32{uint32(c.RepoID)}})
+	if err != nil {
+		return errors.Wrap(err, "changeset-syncer.create-changeset.list-repos")
+	}
+	if len(rs) == 0 {
+		return errors.Errorf("repository %d not found", c.RepoID)
+	}
+	r := rs[0]
+
+	ids := r.ExternalServiceIDs()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	sources, err := s.changesetSources(ctx, ids)
+	for _, id := range ids {
+		creator, ok := sources[id].(ChangesetCreator)
+		if !ok {
+			continue
+		}
+		return creator.CreateChangeset(ctx, &Changeset{Changeset: c, Repo: r}, spec)
+	}
+
+	if err != nil {
+		return err
+	}
+	return errors.Errorf("no code host connection of repository %q supports creating changesets", r.Name)
+}
+
+// changesetSources returns the ChangesetSources of the external services with
+// the given IDs, keyed by external service ID.
+func (s *ChangesetSyncer) changesetSources(ctx context.Context, svcIDs []int64) (map[int64]ChangesetSource, error) {
+	sources := make(map[int64]ChangesetSource, len(svcIDs))
+	if len(svcIDs) == 0 {
+		return sources, nil
+	}
+
+	es, err := s.Store.ListExternalServices(ctx, StoreListExternalServicesArgs{IDs: svcIDs})
+	if err != nil {
+		return sources, errors.Wrap(err, "changeset-syncer.list-external-services")
+	}
+
+	srcs, err := s.Sourcer(es...)
+	for _, src := range srcs {
+		cs, ok := src.(ChangesetSource)
+		if !ok {
+			continue
+		}
+		for _, svc := range src.ExternalServices() {
+			sources[svc.ID] = cs
+		}
+	}
+
+	return sources, err
+}
+
+func (s *ChangesetSyncer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,276 @@
+package repos_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+// fakeChangesetSource is a repos.Source that loads changesets by setting their
+// title to their repository name and external ID.
+type fakeChangesetSource struct {
+	*repos.FakeSource
+	err error
+}
+
+func (s fakeChangesetSource) LoadChangesets(ctx context.Context, cs ...*repos.Changeset) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, c := range cs {
+		c.Title = c.Repo.Name + "#" + c.ExternalID
+		c.State = types.ChangesetStateOpen
+	}
+	return nil
+}
+
+// CreateChangeset sets the external ID of the changeset to the head ref of
+// spec.
+func (s fakeChangesetSource) CreateChangeset(ctx context.Context, c *repos.Changeset, spec repos.ChangesetSpec) error {
+	if s.err != nil {
+		return s.err
+	}
+	c.ExternalID = spec.HeadRef
+	return nil
+}
+
+type fakeChangesetStore struct {
+	changesets []*types.Changeset
+	updated    []*types.Changeset
+}
+
+func (s *fakeChangesetStore) ListChangesets(ctx context.Context, args repos.StoreListChangesetsArgs) ([]*types.Changeset, error) {
+	ids := make(map[int64]bool, len(args.IDs))
+	for _, id := range args.IDs {
+		ids[id] = true
+	}
+
+	var cs []*types.Changeset
+	for _, c := range s.changesets {
+		if len(ids) == 0 || ids[c.ID] {
+			cs = append(cs, c)
+		}
+	}
+	return cs, nil
+}
+
+func (s *fakeChangesetStore) UpdateChangesets(ctx context.Context, cs ...*types.Changeset) error {
+	s.updated = append(s.updated, cs...)
+	return nil
+}
+
+func TestChangesetSyncer_Sync(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	github := &repos.ExternalService{ID: 1, Kind: "github"}
+	gitlab := &repos.ExternalService{ID: 2, Kind: "gitlab"}
+	bitbucket := &repos.ExternalService{ID: 3, Kind: "bitbucketserver"}
+
+	repo := func(name string, svcs ...*repos.ExternalService) *repos.Repo {
+		r := &repos.Repo{Name: name, Sources: map[string]*repos.SourceInfo{}}
+		for _, svc := range svcs {
+			r.Sources[svc.URN()] = &repos.SourceInfo{ID: svc.URN()}
+		}
+		return r
+	}
+
+	store := new(repos.FakeStore)
+	if err := store.UpsertExternalServices(ctx, github, gitlab, bitbucket); err != nil {
+		t.Fatal(err)
+	}
+	rs := []*repos.Repo{
+		repo("github.com/foo/bar", github),
+		repo("gitlab.com/foo/bar", gitlab),
+		repo("bitbucket.example.com/foo/bar", bitbucket),
+	}
+	if err := store.UpsertRepos(ctx, rs...); err != nil {
+		t.Fatal(err)
+	}
+
+	changesets := &fakeChangesetStore{changesets: []*types.Changeset{
+		{ID: 1, CampaignID: 1, RepoID: api.RepoID(rs[0].ID), ExternalID: "1"},
+		{ID: 2, CampaignID: 1, RepoID: api.RepoID(rs[1].ID), ExternalID: "2"},
+		{ID: 3, CampaignID: 1, RepoID: api.RepoID(rs[2].ID), ExternalID: "3"},
+		{ID: 4, CampaignID: 2, RepoID: api.RepoID(rs[0].ID), ExternalID: "4"},
+	}}
+
+	syncer := &repos.ChangesetSyncer{
+		Store:      store,
+		Changesets: changesets,
+		Sourcer: repos.NewFakeSourcer(nil,
+			fakeChangesetSource{FakeSource: repos.NewFakeSource(github, nil)},
+			repos.NewFakeSource(gitlab, nil), // doesn't support changesets
+			fakeChangesetSource{FakeSource: repos.NewFakeSource(bitbucket, nil), err: errors.New("boom")},
+		),
+		Now: func() time.Time { return now },
+	}
+
+	err := syncer.Sync(ctx)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"changeset 2", "boom"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	type synced struct {
+		ID       int64
+		Title    string
+		State    types.ChangesetState
+		SyncedAt time.Time
+	}
+	var have []synced
+	for _, c := range changesets.updated {
+		have = append(have, synced{c.ID, c.Title, c.State, c.SyncedAt})
+	}
+	want := []synced{
+		{1, "github.com/foo/bar#1", types.ChangesetStateOpen, now},
+		{4, "github.com/foo/bar#4", types.ChangesetStateOpen, now},
+	}
+	if diff := cmp.Diff(have, want); diff != "" {
+		t.Fatalf("updated changesets:\n%s", diff)
+	}
+}
+
+func TestChangesetSyncer_CreateChangeset(t *testing.T) {
+	ctx := context.Background()
+
+	github := &repos.ExternalService{ID: 1, Kind: "github"}
+	gitlab := &repos.ExternalService{ID: 2, Kind: "gitlab"}
+
+	store := new(repos.FakeStore)
+	if err := store.UpsertExternalServices(ctx, github, gitlab); err != nil {
+		t.Fatal(err)
+	}
+	rs := []*repos.Repo{
+		{Name: "github.com/foo/bar", Sources: map[string]*repos.SourceInfo{github.URN(): {ID: github.URN()}}},
+		{Name: "gitlab.com/foo/bar", Sources: map[string]*repos.SourceInfo{gitlab.URN(): {ID: gitlab.URN()}}},
+	}
+	if err := store.UpsertRepos(ctx, rs...); err != nil {
+		t.Fatal(err)
+	}
+
+	syncer := &repos.ChangesetSyncer{
+		Store: store,
+		Sourcer: repos.NewFakeSourcer(nil,
+			fakeChangesetSource{FakeSource: repos.NewFakeSource(github, nil)},
+			repos.NewFakeSource(gitlab, nil), // doesn't support changesets
+		),
+	}
+	spec := repos.ChangesetSpec{Title: "Campaign", BaseRef: "refs/heads/master", HeadRef: "refs/heads/sourcegraph/campaign-1"}
+
+	c := &types.Changeset{RepoID: api.RepoID(rs[0].ID)}
+	if err := syncer.CreateChangeset(ctx, c, spec); err != nil {
+		t.Fatal(err)
+	}
+	if c.ExternalID != spec.HeadRef {
+		t.Errorf("got external ID %q, want %q", c.ExternalID, spec.HeadRef)
+	}
+
+	c = &types.Changeset{RepoID: api.RepoID(rs[1].ID)}
+	if err := syncer.CreateChangeset(ctx, c, spec); err == nil || !strings.Contains(err.Error(), "supports creating changesets") {
+		t.Errorf("got error %v, want an error about creating changesets being unsupported", err)
+	}
+}
+
+func testDBStoreChangesets(db *sql.DB) func(*testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+
+		var userID, repoID int32
+		var campaignID int64
+		for _, q := range []struct {
+			query string
+			dst   interface{}
+		}{
+			{"INSERT INTO users (username) VALUES ('alice') RETURNING id", &userID},
+			{"INSERT INTO repo (name) VALUES ('github.com/foo/bar') RETURNING id", &repoID},
+		} {
+			if err = tx.QueryRowContext(ctx, q.query).Scan(q.dst); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = tx.QueryRowContext(ctx,
+			"INSERT INTO campaigns (name, author_id, namespace_user_id) VALUES ('campaign', $1, $1) RETURNING id",
+			userID,
+		).Scan(&campaignID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, externalID := range []string{"1", "2"} {
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO changesets (campaign_id, repo_id, external_id) VALUES ($1, $2, $3)",
+				campaignID, repoID, externalID,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		store := repos.NewDBStore(tx, sql.TxOptions{})
+
+		all, err := store.ListChangesets(ctx, repos.StoreListChangesetsArgs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 {
+			t.Fatalf("listed %d changesets, want 2", len(all))
+		}
+
+		listed, err := store.ListChangesets(ctx, repos.StoreListChangesetsArgs{IDs: []int64{all[1].ID}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listed) != 1 || listed[0].ExternalID != "2" {
+			t.Fatalf("listed %+v, want changeset with external ID 2", listed)
+		}
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		c := listed[0]
+		c.Title = "Upgrade ES-Lint"
+		c.State = types.ChangesetStateMerged
+		c.ReviewState = types.ChangesetReviewStateApproved
+		c.ExternalCreatedAt = now.Add(-time.Hour)
+		c.ExternalClosedAt = now
+		c.ExternalMergedAt = now
+		c.Metadata = []byte(`{"number": 2}`)
+		c.SyncedAt = now
+		c.UpdatedAt = now
+
+		want := *c
+		if err = store.UpdateChangesets(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+
+		have, err := store.ListChangesets(ctx, repos.StoreListChangesetsArgs{IDs: []int64{c.ID}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want.Metadata = have[0].Metadata // jsonb normalizes formatting
+		if diff := cmp.Diff(have[0], &want); diff != "" {
+			t.Fatalf("updated changeset:\n%s", diff)
+		}
+		if string(have[0].Metadata) != `{"number": 2}` {
+			t.Errorf("got metadata %s", have[0].Metadata)
+		}
+	}
+}
@@ -13,3 +13,26 @@ func GetUpdateInterval() time.Duration {
 	}
 	return time.Duration(v) * time.Minute
 }
+
+// GetChangesetSyncInterval returns the interval between syncs of the
+// changesets tracked by campaigns.
+func GetChangesetSyncInterval() time.Duration {
+	v := conf.Get().ChangesetSyncInterval
+	if v == 0 { // default to 15 minutes
+		v = 15
+	}
+	return time.Duration(v) * time.Minute
+}
+
+// GetReviewCommentSyncInterval returns the interval between imports of pull
+// request review comments.
+func GetReviewCommentSyncInterval() time.Duration {
+	v := 0
+	if d := conf.Get().Discussions; d != nil && d.ImportReviewComments != nil {
+		v = d.ImportReviewComments.Interval
+	}
+	if v == 0 { // default to 15 minutes
+		v = 15
+	}
+	return time.Duration(v) * time.Minute
+}
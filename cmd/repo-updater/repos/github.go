@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/pkg/httpcli"
@@ -155,6 +157,153 @@ func (s GithubSource) GetRepo(ctx context.Context, nameWithOwner string) (*Repo,
 	return s.makeRepo(r), nil
 }
 
+// LoadChangesets loads the pull requests of the given changesets from the
+// GitHub API. The ExternalID of each changeset must be a pull request number.
+func (s GithubSource) LoadChangesets(ctx context.Context, cs ...*Changeset) error {
+	prs := make([]*github.PullRequest, len(cs))
+	for i, c := range cs {
+		repo, ok := c.Repo.Metadata.(*github.Repository)
+		if !ok {
+			return errors.Errorf("changeset %d: repository %q is not a GitHub repository", c.ID, c.Repo.Name)
+		}
+
+		number, err := strconv.ParseInt(c.ExternalID, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "changeset %d: invalid pull request number %q", c.ID, c.ExternalID)
+		}
+
+		prs[i] = &github.PullRequest{RepoID: repo.ID, Number: number}
+	}
+
+	if err := s.client.LoadPullRequests(ctx, prs...); err != nil {
+		return err
+	}
+
+	for i, pr := range prs {
+		metadata, err := json.Marshal(pr)
+		if err != nil {
+			return err
+		}
+
+		c := cs[i]
+		c.Title = pr.Title
+		c.URL = pr.URL
+		c.State = types.ChangesetState(pr.State)
+		c.ReviewState = githubReviewState(pr)
+		c.CheckState = githubCheckState(pr)
+		c.ExternalCreatedAt = pr.CreatedAt
+		c.ExternalClosedAt = pr.ClosedAt
+		c.ExternalMergedAt = pr.MergedAt
+		c.Metadata = metadata
+	}
+
+	return nil
+}
+
+// CreateChangeset opens a pull request from spec.HeadRef into spec.BaseRef,
+// which must both be branches, and sets the ExternalID of the given changeset
+// to its number.
+func (s GithubSource) CreateChangeset(ctx context.Context, c *Changeset, spec ChangesetSpec) error {
+	repo, ok := c.Repo.Metadata.(*github.Repository)
+	if !ok {
+		return errors.Errorf("repository %q is not a GitHub repository", c.Repo.Name)
+	}
+
+	pr, err := s.client.CreatePullRequest(ctx, &github.CreatePullRequestInput{
+		RepositoryID: repo.ID,
+		BaseRefName:  strings.TrimPrefix(spec.BaseRef, "refs/heads/"),
+		HeadRefName:  strings.TrimPrefix(spec.HeadRef, "refs/heads/"),
+		Title:        spec.Title,
+		Body:         spec.Body,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "creating pull request in repository %q", c.Repo.Name)
+	}
+
+	c.ExternalID = strconv.FormatInt(pr.Number, 10)
+	return nil
+}
+
+// ListReviewComments returns the review comments on the pull requests of the
+// given repository from the GitHub API.
+func (s GithubSource) ListReviewComments(ctx context.Context, r *Repo, since time.Time) ([]*ReviewComment, error) {
+	repo, ok := r.Metadata.(*github.Repository)
+	if !ok {
+		return nil, errors.Errorf("repository %q is not a GitHub repository", r.Name)
+	}
+
+	owner, name, err := github.SplitRepositoryNameWithOwner(repo.NameWithOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs []*ReviewComment
+	for page, hasNextPage := 1, true; hasNextPage; page++ {
+		var comments []*github.PullRequestReviewComment
+		if comments, hasNextPage, err = s.client.ListRepositoryReviewComments(ctx, owner, name, since, page); err != nil {
+			return nil, err
+		}
+
+		for _, c := range comments {
+			rc := &ReviewComment{
+				ExternalServiceType: r.ExternalRepo.ServiceType,
+				ExternalServiceID:   r.ExternalRepo.ServiceID,
+				ExternalID:          strconv.FormatInt(c.ID, 10),
+				Author:              c.User.Login,
+				URL:                 c.HTMLURL,
+				Body:                c.Body,
+				Path:                c.Path,
+				Commit:              c.OriginalCommitID,
+				CreatedAt:           c.CreatedAt,
+				UpdatedAt:           c.UpdatedAt,
+			}
+			if c.InReplyToID != 0 {
+				rc.InReplyTo = strconv.FormatInt(c.InReplyToID, 10)
+			}
+			if line, ok := c.Line(); ok {
+				rc.Line = line
+			}
+			cs = append(cs, rc)
+		}
+	}
+
+	return cs, nil
+}
+
+// githubReviewState returns the review state of the given pull request: a
+// request for changes by any reviewer takes precedence over approvals.
+func githubReviewState(pr *github.PullRequest) types.ChangesetReviewState {
+	state := types.ChangesetReviewStatePending
+	for _, r := range pr.LatestReviews() {
+		switch r.State {
+		case "CHANGES_REQUESTED":
+			return types.ChangesetReviewStateChangesRequested
+		case "APPROVED":
+			state = types.ChangesetReviewStateApproved
+		}
+	}
+	return state
+}
+
+// githubCheckState returns the check state of the head commit of the given pull
+// request, derived from its combined commit status.
+func githubCheckState(pr *github.PullRequest) types.ChangesetCheckState {
+	commit := pr.HeadCommit()
+	if commit == nil {
+		return ""
+	}
+	switch commit.Status.State {
+	case "SUCCESS":
+		return types.ChangesetCheckStatePassed
+	case "FAILURE", "ERROR":
+		return types.ChangesetCheckStateFailed
+	case "PENDING", "EXPECTED":
+		return types.ChangesetCheckStatePending
+	default:
+		return ""
+	}
+}
+
 func (s GithubSource) makeRepo(r *github.Repository) *Repo {
 	urn := s.svc.URN()
 	return &Repo{
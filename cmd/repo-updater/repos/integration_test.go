@@ -56,6 +56,8 @@ func TestIntegration(t *testing.T) {
 		{"DBStore/ListRepos/Pagination", testStoreListReposPagination(store)},
 		{"DBStore/Syncer/Sync", testSyncerSync(store)},
 		{"DBStore/Syncer/SyncSubset", testSyncSubset(store)},
+		{"DBStore/Changesets", testDBStoreChangesets(db)},
+		{"DBStore/ReviewComments", testDBStoreReviewComments(db)},
 	} {
 		t.Run(tc.name, tc.test)
 	}
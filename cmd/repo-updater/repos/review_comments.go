@@ -0,0 +1,333 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// A ReviewCommentSource can list the review comments on the pull requests of
+// a repository from the code host of its external service.
+type ReviewCommentSource interface {
+	// ListReviewComments returns the review comments on the diffs of the pull
+	// requests of the given repository that were created or edited at or
+	// after since (or all of them if since is zero). Each reply is returned
+	// after the first comment of its thread.
+	ListReviewComments(ctx context.Context, r *Repo, since time.Time) ([]*ReviewComment, error)
+}
+
+// A ReviewComment is a comment on the diff of a pull request.
+type ReviewComment struct {
+	// ExternalServiceType, ExternalServiceID and ExternalID identify the
+	// comment on its code host.
+	ExternalServiceType string
+	ExternalServiceID   string
+	ExternalID          string
+	// InReplyTo is the ExternalID of the first comment of the thread if this
+	// comment is a reply, and empty otherwise.
+	InReplyTo string
+
+	Author string // the code host username of the comment's author
+	URL    string
+	Body   string
+
+	// Path and Line (1-based) are the file and line that the comment is on in
+	// the version of the file at Commit. Line is zero if the comment is on a
+	// whole file or on a line that is not in that version.
+	Path   string
+	Commit string
+	Line   int
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Target is the location of the thread that the comment starts. It is set
+	// by the ReviewCommentSyncer for comments that are not replies.
+	Target *types.DiscussionThreadTargetRepo
+}
+
+// reviewCommentTitleMaxLength is the maximum length of the title of a thread
+// created from a review comment.
+const reviewCommentTitleMaxLength = 100
+
+// Title returns the title of the thread that the comment starts, which is the
+// first line of its body.
+func (c *ReviewComment) Title() string {
+	title := strings.TrimSpace(c.Body)
+	if i := strings.Index(title, "\n"); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if title == "" {
+		return fmt.Sprintf("Review comment on %s", c.Path)
+	}
+	if r := []rune(title); len(r) > reviewCommentTitleMaxLength {
+		title = string(r[:reviewCommentTitleMaxLength-1]) + "…"
+	}
+	return title
+}
+
+// Contents returns the contents of the discussion comment that the comment is
+// imported as. It credits the comment's code host author, since imported
+// comments are authored by the Sourcegraph user configured in
+// discussions.importReviewComments.
+func (c *ReviewComment) Contents() string {
+	return fmt.Sprintf("**%s** [commented on the pull request](%s):\n\n%s", c.Author, c.URL, c.Body)
+}
+
+// A ReviewCommentStore stores review comments as discussion threads and
+// comments.
+type ReviewCommentStore interface {
+	// UpsertReviewComments stores each comment that is not stored yet as a
+	// discussion comment authored by the given user: comments that are not
+	// replies start a new thread targeting c.Target, and replies are added to
+	// the thread of the comment they reply to (and skipped if it is not
+	// stored). The contents of stored comments are updated.
+	UpsertReviewComments(ctx context.Context, authorUserID int32, cs ...*ReviewComment) error
+	// UserIDByUsername returns the ID of the user with the given username.
+	UserIDByUsername(ctx context.Context, username string) (int32, error)
+}
+
+// reviewCommentContextLines is the number of lines before and after the line
+// of a review comment that are stored with its thread.
+const reviewCommentContextLines = 3
+
+// reviewCommentMaxFileSize is the maximum size of a file that is read to store
+// the lines around a review comment.
+const reviewCommentMaxFileSize = 1 << 20
+
+// A ReviewCommentSyncer periodically imports the review comments on the pull
+// requests of the repositories configured in discussions.importReviewComments
+// as discussion threads.
+type ReviewCommentSyncer struct {
+	Store    Store
+	Comments ReviewCommentStore
+	// Sourcer is used to create the sources of the external services of the
+	// repositories. Sources that don't implement ReviewCommentSource can't
+	// import review comments.
+	Sourcer Sourcer
+	// ReadFile returns the contents of a file of a repository at a commit. If
+	// nil, the file is read from gitserver.
+	ReadFile func(ctx context.Context, repo api.RepoName, commit api.CommitID, path string) ([]byte, error)
+	// Config returns the discussions.importReviewComments site configuration.
+	Config func() *schema.ImportReviewComments
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	mu     sync.Mutex
+	synced map[uint32]time.Time // the start of the last successful sync of each repository
+}
+
+// Run runs Sync at the specified interval.
+func (s *ReviewCommentSyncer) Run(ctx context.Context, interval time.Duration) error {
+	for ctx.Err() == nil {
+		if err := s.Sync(ctx); err != nil {
+			log15.Error("ReviewCommentSyncer", "error", err)
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+		}
+	}
+
+	return ctx.Err()
+}
+
+// Sync imports the review comments on the pull requests of the configured
+// repositories that were created or edited since their last sync. A failure to
+// sync some of the repositories doesn't prevent the others from being synced;
+// all errors are returned combined.
+func (s *ReviewCommentSyncer) Sync(ctx context.Context) error {
+	cfg := s.Config()
+	if cfg == nil || len(cfg.Repos) == 0 {
+		return nil
+	}
+
+	authorUserID, err := s.Comments.UserIDByUsername(ctx, cfg.Author)
+	if err != nil {
+		return errors.Wrapf(err, "review-comment-syncer.sync.author %q", cfg.Author)
+	}
+
+	rs, err := s.Store.ListRepos(ctx, StoreListReposArgs{Names: cfg.Repos})
+	if err != nil {
+		return errors.Wrap(err, "review-comment-syncer.sync.list-repos")
+	}
+
+	var svcIDs []int64
+	seenSvc := make(map[int64]bool)
+	for _, r := range rs {
+		for _, id := range r.ExternalServiceIDs() {
+			if !seenSvc[id] {
+				seenSvc[id] = true
+				svcIDs = append(svcIDs, id)
+			}
+		}
+	}
+
+	var errs *multierror.Error
+
+	sources, err := s.reviewCommentSources(ctx, svcIDs)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	for _, r := range rs {
+		// The comments are listed from the first of the repository's
+		// external services that can list them.
+		ids := r.ExternalServiceIDs()
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		var src ReviewCommentSource
+		for _, id := range ids {
+			if src = sources[id]; src != nil {
+				break
+			}
+		}
+		if src == nil {
+			errs = multierror.Append(errs, errors.Errorf("no code host connection of repository %q supports review comments", r.Name))
+			continue
+		}
+
+		if err := s.syncRepo(ctx, src, r, authorUserID); err != nil {
+			errs = multierror.Append(errs, errors.Wrapf(err, "syncing review comments of %q", r.Name))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func (s *ReviewCommentSyncer) syncRepo(ctx context.Context, src ReviewCommentSource, r *Repo, authorUserID int32) error {
+	s.mu.Lock()
+	since := s.synced[r.ID]
+	s.mu.Unlock()
+
+	start := s.now()
+	cs, err := src.ListReviewComments(ctx, r, since)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cs {
+		if c.InReplyTo == "" {
+			c.Target = s.target(ctx, r, c)
+		}
+	}
+
+	// Threads must be created before their replies are stored.
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].InReplyTo == "" && cs[j].InReplyTo != "" })
+
+	if err := s.Comments.UpsertReviewComments(ctx, authorUserID, cs...); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.synced == nil {
+		s.synced = make(map[uint32]time.Time)
+	}
+	s.synced[r.ID] = start
+	s.mu.Unlock()
+	return nil
+}
+
+// target returns the location of the thread started by the given comment. It
+// includes the lines around the comment's line if the file can be read.
+func (s *ReviewCommentSyncer) target(ctx context.Context, r *Repo, c *ReviewComment) *types.DiscussionThreadTargetRepo {
+	t := &types.DiscussionThreadTargetRepo{RepoID: api.RepoID(r.ID)}
+	if c.Path != "" {
+		t.Path = &c.Path
+	}
+	if c.Commit != "" {
+		t.Revision = &c.Commit
+	}
+	if c.Path == "" || c.Commit == "" || c.Line <= 0 {
+		return t
+	}
+
+	content, err := s.readFile(ctx, api.RepoName(r.Name), api.CommitID(c.Commit), c.Path)
+	if err != nil {
+		log15.Warn("ReviewCommentSyncer: failed to read file of review comment", "repo", r.Name, "commit", c.Commit, "path", c.Path, "error", err)
+		return t
+	}
+	lines := strings.Split(string(content), "\n")
+	if c.Line > len(lines) {
+		return t
+	}
+
+	startLine, endLine := int32(c.Line-1), int32(c.Line)
+	startCharacter, endCharacter := int32(0), int32(0)
+	linesBefore := clampLines(lines, c.Line-1-reviewCommentContextLines, c.Line-1)
+	selected := clampLines(lines, c.Line-1, c.Line)
+	linesAfter := clampLines(lines, c.Line, c.Line+reviewCommentContextLines)
+	t.StartLine, t.EndLine = &startLine, &endLine
+	t.StartCharacter, t.EndCharacter = &startCharacter, &endCharacter
+	t.LinesBefore, t.Lines, t.LinesAfter = &linesBefore, &selected, &linesAfter
+	return t
+}
+
+// clampLines returns lines[start:end], with start and end clamped to the
+// bounds of lines.
+func clampLines(lines []string, start, end int) []string {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if start >= end {
+		return []string{}
+	}
+	return append([]string{}, lines[start:end]...)
+}
+
+// reviewCommentSources returns the ReviewCommentSources of the external
+// services with the given IDs, keyed by external service ID.
+func (s *ReviewCommentSyncer) reviewCommentSources(ctx context.Context, svcIDs []int64) (map[int64]ReviewCommentSource, error) {
+	sources := make(map[int64]ReviewCommentSource, len(svcIDs))
+	if len(svcIDs) == 0 {
+		return sources, nil
+	}
+
+	es, err := s.Store.ListExternalServices(ctx, StoreListExternalServicesArgs{IDs: svcIDs})
+	if err != nil {
+		return sources, errors.Wrap(err, "review-comment-syncer.list-external-services")
+	}
+
+	srcs, err := s.Sourcer(es...)
+	for _, src := range srcs {
+		rs, ok := src.(ReviewCommentSource)
+		if !ok {
+			continue
+		}
+		for _, svc := range src.ExternalServices() {
+			sources[svc.ID] = rs
+		}
+	}
+
+	return sources, err
+}
+
+func (s *ReviewCommentSyncer) readFile(ctx context.Context, repo api.RepoName, commit api.CommitID, path string) ([]byte, error) {
+	if s.ReadFile != nil {
+		return s.ReadFile(ctx, repo, commit, path)
+	}
+	return git.ReadFile(ctx, gitserver.Repo{Name: repo}, commit, path, reviewCommentMaxFileSize)
+}
+
+func (s *ReviewCommentSyncer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,266 @@
+package repos_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// fakeReviewCommentSource is a repos.Source that lists the given review
+// comments, recording the since argument of each call.
+type fakeReviewCommentSource struct {
+	*repos.FakeSource
+	comments []*repos.ReviewComment
+	since    *[]time.Time
+}
+
+func (s fakeReviewCommentSource) ListReviewComments(ctx context.Context, r *repos.Repo, since time.Time) ([]*repos.ReviewComment, error) {
+	*s.since = append(*s.since, since)
+	return s.comments, nil
+}
+
+type fakeReviewCommentStore struct {
+	upserted     []*repos.ReviewComment
+	authorUserID int32
+}
+
+func (s *fakeReviewCommentStore) UpsertReviewComments(ctx context.Context, authorUserID int32, cs ...*repos.ReviewComment) error {
+	s.authorUserID = authorUserID
+	s.upserted = append(s.upserted, cs...)
+	return nil
+}
+
+func (s *fakeReviewCommentStore) UserIDByUsername(ctx context.Context, username string) (int32, error) {
+	return 42, nil
+}
+
+func TestReviewCommentSyncer_Sync(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	github := &repos.ExternalService{ID: 1, Kind: "github"}
+
+	store := new(repos.FakeStore)
+	if err := store.UpsertExternalServices(ctx, github); err != nil {
+		t.Fatal(err)
+	}
+	repo := &repos.Repo{
+		Name:    "github.com/foo/bar",
+		Sources: map[string]*repos.SourceInfo{github.URN(): {ID: github.URN()}},
+	}
+	if err := store.UpsertRepos(ctx, repo, &repos.Repo{Name: "github.com/foo/other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var since []time.Time
+	src := fakeReviewCommentSource{
+		FakeSource: repos.NewFakeSource(github, nil),
+		comments: []*repos.ReviewComment{
+			{ExternalID: "2", InReplyTo: "1", Body: "Fixed"},
+			{ExternalID: "1", Body: "Typo", Path: "README.md", Commit: "deadbeef", Line: 2},
+		},
+		since: &since,
+	}
+	comments := &fakeReviewCommentStore{}
+	syncer := &repos.ReviewCommentSyncer{
+		Store:    store,
+		Comments: comments,
+		Sourcer:  repos.NewFakeSourcer(nil, src),
+		ReadFile: func(ctx context.Context, repo api.RepoName, commit api.CommitID, path string) ([]byte, error) {
+			return []byte("# Title\nHello wrld\n"), nil
+		},
+		Config: func() *schema.ImportReviewComments {
+			return &schema.ImportReviewComments{Author: "importer", Repos: []string{"github.com/foo/bar"}}
+		},
+		Now: func() time.Time { return now },
+	}
+
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if comments.authorUserID != 42 {
+		t.Errorf("got author user ID %d, want 42", comments.authorUserID)
+	}
+
+	var ids []string
+	for _, c := range comments.upserted {
+		ids = append(ids, c.ExternalID)
+	}
+	if want := []string{"1", "2"}; !cmp.Equal(ids, want) {
+		t.Fatalf("upserted comments %v, want threads before replies %v", ids, want)
+	}
+	if c := comments.upserted[1]; c.Target != nil {
+		t.Errorf("reply has target %+v", c.Target)
+	}
+
+	path, revision := "README.md", "deadbeef"
+	startLine, endLine, character := int32(1), int32(2), int32(0)
+	linesBefore, lines, linesAfter := []string{"# Title"}, []string{"Hello wrld"}, []string{""}
+	want := &types.DiscussionThreadTargetRepo{
+		RepoID:         api.RepoID(repo.ID),
+		Path:           &path,
+		Revision:       &revision,
+		StartLine:      &startLine,
+		EndLine:        &endLine,
+		StartCharacter: &character,
+		EndCharacter:   &character,
+		LinesBefore:    &linesBefore,
+		Lines:          &lines,
+		LinesAfter:     &linesAfter,
+	}
+	if diff := cmp.Diff(comments.upserted[0].Target, want); diff != "" {
+		t.Errorf("target:\n%s", diff)
+	}
+
+	// The next sync only lists the comments created or edited since the
+	// previous one.
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Time{{}, now}; !cmp.Equal(since, want) {
+		t.Errorf("listed comments since %v, want %v", since, want)
+	}
+}
+
+func TestReviewComment_Title(t *testing.T) {
+	for _, test := range []struct {
+		body string
+		want string
+	}{
+		{"Typo\n\nShould be world.", "Typo"},
+		{"  \n", "Review comment on README.md"},
+		{strings.Repeat("a", 150), strings.Repeat("a", 99) + "…"},
+	} {
+		c := &repos.ReviewComment{Body: test.body, Path: "README.md"}
+		if have := c.Title(); have != test.want {
+			t.Errorf("%q: got title %q, want %q", test.body, have, test.want)
+		}
+	}
+}
+
+func testDBStoreReviewComments(db *sql.DB) func(*testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+
+		var userID, repoID int32
+		for _, q := range []struct {
+			query string
+			dst   interface{}
+		}{
+			{"INSERT INTO users (username) VALUES ('importer') RETURNING id", &userID},
+			{"INSERT INTO repo (name) VALUES ('github.com/foo/bar') RETURNING id", &repoID},
+		} {
+			if err = tx.QueryRowContext(ctx, q.query).Scan(q.dst); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		store := repos.NewDBStore(tx, sql.TxOptions{})
+
+		if id, err := store.UserIDByUsername(ctx, "importer"); err != nil || id != userID {
+			t.Fatalf("got user ID %d (error %v), want %d", id, err, userID)
+		}
+		if _, err := store.UserIDByUsername(ctx, "nobody"); err == nil {
+			t.Fatal("got user ID of a user that doesn't exist")
+		}
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		path, revision := "README.md", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
+		startLine, endLine := int32(1), int32(2)
+		lines := []string{"Hello wrld"}
+		comment := func(id, inReplyTo, body string) *repos.ReviewComment {
+			c := &repos.ReviewComment{
+				ExternalServiceType: "github",
+				ExternalServiceID:   "https://github.com/",
+				ExternalID:          id,
+				InReplyTo:           inReplyTo,
+				Author:              "alice",
+				Body:                body,
+				CreatedAt:           now,
+				UpdatedAt:           now,
+			}
+			if inReplyTo == "" {
+				c.Target = &types.DiscussionThreadTargetRepo{
+					RepoID:    api.RepoID(repoID),
+					Path:      &path,
+					Revision:  &revision,
+					StartLine: &startLine,
+					EndLine:   &endLine,
+					Lines:     &lines,
+				}
+			}
+			return c
+		}
+
+		err = store.UpsertReviewComments(ctx, userID,
+			comment("1", "", "Typo"),
+			comment("2", "1", "Fixed"),
+			comment("3", "404", "Reply to a comment that isn't stored"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Syncing again updates the edited comment without duplicating any.
+		err = store.UpsertReviewComments(ctx, userID,
+			comment("1", "", "Typo"),
+			comment("2", "1", "Fixed, thanks"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		type stored struct {
+			ExternalID string
+			Title      string
+			Contents   string
+			Path       string
+			Lines      string
+		}
+		rows, err := tx.QueryContext(ctx, `
+SELECT c.external_id, t.title, c.contents, r.path, r.lines
+FROM discussion_comments c
+JOIN discussion_threads t ON t.id = c.thread_id
+JOIN discussion_threads_target_repo r ON r.id = t.target_repo_id AND r.thread_id = t.id
+WHERE c.author_user_id = $1
+ORDER BY c.external_id`, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var have []stored
+		for rows.Next() {
+			var s stored
+			if err := rows.Scan(&s.ExternalID, &s.Title, &s.Contents, &s.Path, &s.Lines); err != nil {
+				t.Fatal(err)
+			}
+			have = append(have, s)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []stored{
+			{"1", "Typo", comment("1", "", "Typo").Contents(), path, "Hello wrld"},
+			{"2", "Typo", comment("2", "1", "Fixed, thanks").Contents(), path, "Hello wrld"},
+		}
+		if diff := cmp.Diff(have, want); diff != "" {
+			t.Fatalf("stored comments:\n%s", diff)
+		}
+	}
+}
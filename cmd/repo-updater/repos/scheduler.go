@@ -113,6 +113,7 @@ type configuredRepo2 struct {
 	ID      uint32
 	Name    api.RepoName
 	Enabled bool
+	Pinned  bool
 }
 
 // sourceRepoMap is the set of repositories associated with a specific configuration source.
@@ -218,7 +219,7 @@ func (s *updateScheduler) runUpdateLoop(ctx context.Context) {
 
 // requestRepoUpdate sends a request to gitserver to request an update.
 var requestRepoUpdate = func(ctx context.Context, repo *configuredRepo2, since time.Duration) (*gitserverprotocol.RepoUpdateResponse, error) {
-	return gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repo.Name, URL: repo.URL}, since)
+	return gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repo.Name, URL: repo.URL}, since, repo.Pinned)
 }
 
 // configuredLimiter returns a mutable limiter that is
@@ -281,6 +282,7 @@ func configuredRepo2FromRepo(r *Repo) *configuredRepo2 {
 		ID:      r.ID,
 		Name:    api.RepoName(r.Name),
 		Enabled: r.Enabled,
+		Pinned:  r.Pinned,
 	}
 
 	if urls := r.CloneURLs(); len(urls) > 0 {
@@ -325,13 +327,16 @@ func (s *updateScheduler) updateSource(source string, newList sourceRepoMap) {
 }
 
 // UpdateOnce causes a single update of the given repository.
-// It neither adds nor removes the repo from the schedule.
-func (s *updateScheduler) UpdateOnce(id uint32, name api.RepoName, url string) {
+// It neither adds nor removes the repo from the schedule, but the scheduled
+// updates of the repo use the given pinned flag from now on.
+func (s *updateScheduler) UpdateOnce(id uint32, name api.RepoName, url string, pinned bool) {
 	repo := &configuredRepo2{
-		ID:   id,
-		Name: name,
-		URL:  url,
+		ID:     id,
+		Name:   name,
+		URL:    url,
+		Pinned: pinned,
 	}
+	s.schedule.setPinned(repo)
 	schedManualFetch.Inc()
 	s.updateQueue.enqueue(repo, priorityHigh)
 }
@@ -678,6 +683,23 @@ func (s *schedule) updateInterval(repo *configuredRepo2, interval time.Duration)
 	s.mu.Unlock()
 }
 
+// setPinned sets the pinned flag of a repo in the schedule to that of the given
+// repo. It does nothing if the repo is not in the schedule.
+func (s *schedule) setPinned(repo *configuredRepo2) {
+	if repo.ID == 0 {
+		panic("repo.id is zero")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if update := s.index[repo.ID]; update != nil && update.Repo.Pinned != repo.Pinned {
+		updated := *update.Repo
+		updated.Pinned = repo.Pinned
+		update.Repo = &updated
+	}
+}
+
 // remove removes a repo from the schedule.
 func (s *schedule) remove(repo *configuredRepo2) (removed bool) {
 	if repo.ID == 0 {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/keegancsmith/sqlf"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/db/dbutil"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/awscodecommit"
@@ -308,6 +309,7 @@ SELECT
   enabled,
   archived,
   fork,
+  pinned,
   sources,
   metadata
 FROM repo
@@ -408,6 +410,385 @@ func listAllRepoNamesQuery(cursor, limit int64) *sqlf.Query {
 	return sqlf.Sprintf(listAllRepoNamesQueryFmtstr, cursor, limit)
 }
 
+// ListChangesets lists all stored changesets matching the given args.
+func (s DBStore) ListChangesets(ctx context.Context, args StoreListChangesetsArgs) (cs []*types.Changeset, _ error) {
+	return cs, s.paginate(ctx, 0, 500, listChangesetsQuery(args),
+		func(sc scanner) (last, count int64, err error) {
+			var c types.Changeset
+			if err = scanChangeset(&c, sc); err != nil {
+				return 0, 0, err
+			}
+			cs = append(cs, &c)
+			return c.ID, 1, nil
+		},
+	)
+}
+
+const listChangesetsQueryFmtstr = `
+-- source: cmd/repo-updater/repos/store.go:DBStore.ListChangesets
+SELECT
+  id,
+  campaign_id,
+  repo_id,
+  external_id,
+  title,
+  url,
+  state,
+  review_state,
+  check_state,
+  external_created_at,
+  external_closed_at,
+  external_merged_at,
+  metadata,
+  synced_at,
+  created_at,
+  updated_at
+FROM changesets
+WHERE id > %s
+AND %s
+ORDER BY id ASC LIMIT %s
+`
+
+func listChangesetsQuery(args StoreListChangesetsArgs) paginatedQuery {
+	pred := sqlf.Sprintf("TRUE")
+
+	if len(args.IDs) > 0 {
+		ids := make([]*sqlf.Query, 0, len(args.IDs))
+		for _, id := range args.IDs {
+			ids = append(ids, sqlf.Sprintf("%d", id))
+		}
+		pred = sqlf.Sprintf("id IN (%s)", sqlf.Join(ids, ","))
+	}
+
+	return func(cursor, limit int64) *sqlf.Query {
+		return sqlf.Sprintf(listChangesetsQueryFmtstr, cursor, pred, limit)
+	}
+}
+
+// UpdateChangesets updates the code host fields (i.e. the fields loaded by a
+// ChangesetSource) and the SyncedAt and UpdatedAt fields of the given stored
+// changesets.
+func (s DBStore) UpdateChangesets(ctx context.Context, cs ...*types.Changeset) error {
+	if len(cs) == 0 {
+		return nil
+	}
+
+	q, err := updateChangesetsQuery(cs)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return err
+	}
+
+	i := -1
+	_, _, err = scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		i++
+		err = scanChangeset(cs[i], sc)
+		return cs[i].ID, 1, err
+	})
+
+	return err
+}
+
+func updateChangesetsQuery(cs []*types.Changeset) (*sqlf.Query, error) {
+	type record struct {
+		ID                int64           `json:"id"`
+		Title             string          `json:"title"`
+		URL               string          `json:"url"`
+		State             string          `json:"state"`
+		ReviewState       string          `json:"review_state"`
+		CheckState        string          `json:"check_state"`
+		ExternalCreatedAt *time.Time      `json:"external_created_at,omitempty"`
+		ExternalClosedAt  *time.Time      `json:"external_closed_at,omitempty"`
+		ExternalMergedAt  *time.Time      `json:"external_merged_at,omitempty"`
+		Metadata          json.RawMessage `json:"metadata"`
+		SyncedAt          *time.Time      `json:"synced_at,omitempty"`
+		UpdatedAt         *time.Time      `json:"updated_at,omitempty"`
+	}
+
+	records := make([]record, 0, len(cs))
+	for _, c := range cs {
+		metadata, err := metadataColumn(c.Metadata)
+		if err != nil {
+			return nil, errors.Wrapf(err, "updateChangesetsQuery: metadata marshalling failed")
+		}
+		if len(metadata) == 0 {
+			metadata = json.RawMessage("{}")
+		}
+
+		records = append(records, record{
+			ID:                c.ID,
+			Title:             c.Title,
+			URL:               c.URL,
+			State:             string(c.State),
+			ReviewState:       string(c.ReviewState),
+			CheckState:        string(c.CheckState),
+			ExternalCreatedAt: nullTimeColumn(c.ExternalCreatedAt.UTC()),
+			ExternalClosedAt:  nullTimeColumn(c.ExternalClosedAt.UTC()),
+			ExternalMergedAt:  nullTimeColumn(c.ExternalMergedAt.UTC()),
+			Metadata:          metadata,
+			SyncedAt:          nullTimeColumn(c.SyncedAt.UTC()),
+			UpdatedAt:         nullTimeColumn(c.UpdatedAt.UTC()),
+		})
+	}
+
+	batch, err := json.MarshalIndent(records, "    ", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlf.Sprintf(updateChangesetsQueryFmtstr, string(batch)), nil
+}
+
+const updateChangesetsQueryFmtstr = `
+-- source: cmd/repo-updater/repos/store.go:DBStore.UpdateChangesets
+--
+-- See batchReposQueryFmtstr for the use of the "batch" CTE.
+--
+WITH batch AS (
+  SELECT * FROM ROWS FROM (
+  json_to_recordset(%s)
+  AS (
+      id                  bigint,
+      title               text,
+      url                 text,
+      state               text,
+      review_state        text,
+      check_state         text,
+      external_created_at timestamptz,
+      external_closed_at  timestamptz,
+      external_merged_at  timestamptz,
+      metadata            jsonb,
+      synced_at           timestamptz,
+      updated_at          timestamptz
+    )
+  )
+  WITH ORDINALITY
+),
+updated AS (
+  UPDATE changesets
+  SET
+    title               = batch.title,
+    url                 = batch.url,
+    state               = batch.state,
+    review_state        = batch.review_state,
+    check_state         = batch.check_state,
+    external_created_at = batch.external_created_at,
+    external_closed_at  = batch.external_closed_at,
+    external_merged_at  = batch.external_merged_at,
+    metadata            = batch.metadata,
+    synced_at           = batch.synced_at,
+    updated_at          = COALESCE(batch.updated_at, now())
+  FROM batch
+  WHERE changesets.id = batch.id
+  RETURNING changesets.*
+)
+SELECT
+  updated.id,
+  updated.campaign_id,
+  updated.repo_id,
+  updated.external_id,
+  updated.title,
+  updated.url,
+  updated.state,
+  updated.review_state,
+  updated.check_state,
+  updated.external_created_at,
+  updated.external_closed_at,
+  updated.external_merged_at,
+  updated.metadata,
+  updated.synced_at,
+  updated.created_at,
+  updated.updated_at
+FROM updated
+LEFT JOIN batch ON batch.id = updated.id
+ORDER BY batch.ordinality
+`
+
+// UserIDByUsername returns the ID of the user with the given username.
+func (s DBStore) UserIDByUsername(ctx context.Context, username string) (id int32, err error) {
+	q := sqlf.Sprintf(userIDByUsernameQueryFmtstr, username)
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return 0, err
+	}
+
+	_, count, err := scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		return 0, 1, sc.Scan(&id)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, errors.Errorf("user %q not found", username)
+	}
+	return id, nil
+}
+
+const userIDByUsernameQueryFmtstr = `
+-- source: cmd/repo-updater/repos/store.go:DBStore.UserIDByUsername
+SELECT id FROM users WHERE username = %s AND deleted_at IS NULL
+`
+
+// UpsertReviewComments stores the given review comments as discussion
+// comments authored by the given user. Comments are identified by their
+// external IDs: the contents of stored comments are updated, replies are added
+// to the thread of the comment they reply to (and skipped if it is not
+// stored), and the other comments start a new thread.
+func (s DBStore) UpsertReviewComments(ctx context.Context, authorUserID int32, cs ...*ReviewComment) error {
+	for _, c := range cs {
+		if err := s.upsertReviewComment(ctx, authorUserID, c); err != nil {
+			return errors.Wrapf(err, "review comment %s", c.ExternalID)
+		}
+	}
+	return nil
+}
+
+func (s DBStore) upsertReviewComment(ctx context.Context, authorUserID int32, c *ReviewComment) error {
+	contents := c.Contents()
+	updatedAt := c.UpdatedAt.UTC()
+	if updatedAt.IsZero() {
+		updatedAt = time.Now().UTC()
+	}
+	createdAt := c.CreatedAt.UTC()
+	if createdAt.IsZero() {
+		createdAt = updatedAt
+	}
+
+	q := sqlf.Sprintf(updateReviewCommentQueryFmtstr,
+		contents, contents, updatedAt,
+		c.ExternalServiceType, c.ExternalServiceID, c.ExternalID,
+	)
+	if ok, err := s.execReturning(ctx, q); err != nil || ok {
+		return err
+	}
+
+	if c.InReplyTo != "" {
+		q = sqlf.Sprintf(insertReviewCommentReplyQueryFmtstr,
+			authorUserID, contents, createdAt, updatedAt,
+			c.ExternalServiceType, c.ExternalServiceID, c.ExternalID,
+			c.ExternalServiceType, c.ExternalServiceID, c.InReplyTo,
+		)
+		_, err := s.execReturning(ctx, q)
+		return err
+	}
+
+	t := c.Target
+	if t == nil {
+		return errors.New("review comment has no target")
+	}
+	joinLines := func(lines *[]string) *string {
+		if lines == nil {
+			return nil
+		}
+		joined := strings.Join(*lines, "\n")
+		return &joined
+	}
+	q = sqlf.Sprintf(insertReviewCommentThreadQueryFmtstr,
+		authorUserID, c.Title(), createdAt, updatedAt,
+		t.RepoID, t.Path, t.Branch, t.Revision,
+		t.StartLine, t.EndLine, t.StartCharacter, t.EndCharacter,
+		joinLines(t.LinesBefore), joinLines(t.Lines), joinLines(t.LinesAfter),
+		authorUserID, contents, createdAt, updatedAt,
+		c.ExternalServiceType, c.ExternalServiceID, c.ExternalID,
+	)
+	_, err := s.execReturning(ctx, q)
+	return err
+}
+
+// execReturning executes the given query and returns whether it returned any
+// rows.
+func (s DBStore) execReturning(ctx context.Context, q *sqlf.Query) (bool, error) {
+	rows, err := s.db.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return false, err
+	}
+	_, count, err := scanAll(rows, func(sc scanner) (last, count int64, err error) {
+		return 0, 1, nil
+	})
+	return count > 0, err
+}
+
+const updateReviewCommentQueryFmtstr = `
+-- source: cmd/repo-updater/repos/store.go:DBStore.UpsertReviewComments
+UPDATE discussion_comments
+SET
+  contents   = %s,
+  updated_at = CASE WHEN contents = %s THEN updated_at ELSE %s END
+WHERE external_service_type = %s
+AND external_service_id = %s
+AND external_id = %s
+RETURNING id
+`
+
+const insertReviewCommentReplyQueryFmtstr = `
+-- source: cmd/repo-updater/repos/store.go:DBStore.UpsertReviewComments
+INSERT INTO discussion_comments (
+  thread_id,
+  author_user_id,
+  contents,
+  created_at,
+  updated_at,
+  external_service_type,
+  external_service_id,
+  external_id
+)
+SELECT thread_id, %s, %s, %s, %s, %s, %s, %s
+FROM discussion_comments
+WHERE external_service_type = %s
+AND external_service_id = %s
+AND external_id = %s
+RETURNING id
+`
+
+// The thread and its target reference each other, so their IDs are allocated
+// upfront. Foreign keys are checked at the end of the statement.
+const insertReviewCommentThreadQueryFmtstr = `
+-- source: cmd/repo-updater/repos/store.go:DBStore.UpsertReviewComments
+WITH ids AS (
+  SELECT
+    nextval('discussion_threads_id_seq') AS thread_id,
+    nextval('discussion_threads_target_repo_id_seq') AS target_repo_id
+),
+thread AS (
+  INSERT INTO discussion_threads (id, author_user_id, title, target_repo_id, created_at, updated_at)
+  SELECT thread_id, %s, %s, target_repo_id, %s, %s FROM ids
+),
+target AS (
+  INSERT INTO discussion_threads_target_repo (
+    id,
+    thread_id,
+    repo_id,
+    path,
+    branch,
+    revision,
+    start_line,
+    end_line,
+    start_character,
+    end_character,
+    lines_before,
+    lines,
+    lines_after
+  )
+  SELECT target_repo_id, thread_id, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s FROM ids
+)
+INSERT INTO discussion_comments (
+  thread_id,
+  author_user_id,
+  contents,
+  created_at,
+  updated_at,
+  external_service_type,
+  external_service_id,
+  external_id
+)
+SELECT thread_id, %s, %s, %s, %s, %s, %s, %s FROM ids
+RETURNING id
+`
+
 // a paginatedQuery returns a query with the given pagination
 // parameters
 type paginatedQuery func(cursor, limit int64) *sqlf.Query
@@ -648,6 +1029,7 @@ SELECT
   updated.enabled,
   updated.archived,
   updated.fork,
+  updated.pinned,
   updated.sources,
   updated.metadata
 FROM updated
@@ -715,6 +1097,7 @@ SELECT
   inserted.enabled,
   inserted.archived,
   inserted.fork,
+  inserted.pinned,
   inserted.sources,
   inserted.metadata
 FROM inserted
@@ -811,6 +1194,7 @@ func scanRepo(r *Repo, s scanner) error {
 		&r.Enabled,
 		&r.Archived,
 		&r.Fork,
+		&r.Pinned,
 		&sources,
 		&metadata,
 	)
@@ -846,3 +1230,27 @@ func scanRepo(r *Repo, s scanner) error {
 
 	return nil
 }
+
+func scanChangeset(c *types.Changeset, s scanner) error {
+	var metadata json.RawMessage
+	err := s.Scan(
+		&c.ID,
+		&c.CampaignID,
+		&c.RepoID,
+		&c.ExternalID,
+		&c.Title,
+		&c.URL,
+		&c.State,
+		&c.ReviewState,
+		&c.CheckState,
+		&dbutil.NullTime{Time: &c.ExternalCreatedAt},
+		&dbutil.NullTime{Time: &c.ExternalClosedAt},
+		&dbutil.NullTime{Time: &c.ExternalMergedAt},
+		&metadata,
+		&dbutil.NullTime{Time: &c.SyncedAt},
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	)
+	c.Metadata = metadata
+	return err
+}
@@ -540,6 +540,10 @@ type Repo struct {
 	Enabled bool
 	// Archived is whether the repository has been archived.
 	Archived bool
+	// Pinned is whether gitserver keeps the repository on disk even when it
+	// needs to free up disk space. It is set by site admins through the
+	// frontend, never by syncing.
+	Pinned bool
 	// CreatedAt is when this repository was created on Sourcegraph.
 	CreatedAt time.Time
 	// UpdatedAt is when this repository's metadata was last updated on Sourcegraph.
@@ -12,6 +12,7 @@ import (
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/cmd/repo-updater/repos"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/awscodecommit"
@@ -32,12 +33,13 @@ type Server struct {
 	}
 	Scheduler interface {
 		UpdateQueueLen() int
-		UpdateOnce(id uint32, name api.RepoName, url string)
+		UpdateOnce(id uint32, name api.RepoName, url string, pinned bool)
 		ScheduleInfo(id uint32) *protocol.RepoUpdateSchedulerInfoResult
 	}
 	GitserverClient interface {
 		ListCloned(context.Context) ([]string, error)
 	}
+	ChangesetSyncer *repos.ChangesetSyncer
 
 	notClonedCountMu        sync.Mutex
 	notClonedCount          uint64
@@ -54,6 +56,8 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/exclude-repo", s.handleExcludeRepo)
 	mux.HandleFunc("/sync-external-service", s.handleExternalServiceSync)
 	mux.HandleFunc("/status-messages", s.handleStatusMessages)
+	mux.HandleFunc("/sync-changesets", s.handleChangesetSync)
+	mux.HandleFunc("/create-changeset", s.handleChangesetCreate)
 	return mux
 }
 
@@ -151,6 +155,70 @@ func (s *Server) handleExcludeRepo(w http.ResponseWriter, r *http.Request) {
 	respond(w, http.StatusOK, resp)
 }
 
+func (s *Server) handleChangesetSync(w http.ResponseWriter, r *http.Request) {
+	if s.ChangesetSyncer == nil {
+		respond(w, http.StatusServiceUnavailable, errors.New("changeset syncing is not enabled"))
+		return
+	}
+
+	var req protocol.ChangesetSyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var resp protocol.ChangesetSyncResponse
+	if len(req.IDs) == 0 {
+		respond(w, http.StatusOK, resp)
+		return
+	}
+
+	cs, err := s.ChangesetSyncer.Changesets.ListChangesets(r.Context(), repos.StoreListChangesetsArgs{
+		IDs: req.IDs,
+	})
+	if err != nil {
+		respond(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err = s.ChangesetSyncer.SyncChangesets(r.Context(), cs...); err != nil {
+		log15.Error("sync-changesets", "ids", req.IDs, "error", err)
+		resp.Error = err.Error()
+	}
+
+	respond(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleChangesetCreate(w http.ResponseWriter, r *http.Request) {
+	if s.ChangesetSyncer == nil {
+		respond(w, http.StatusServiceUnavailable, errors.New("changeset syncing is not enabled"))
+		return
+	}
+
+	var req protocol.ChangesetCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond(w, http.StatusBadRequest, err)
+		return
+	}
+
+	c := &types.Changeset{RepoID: req.RepoID}
+	err := s.ChangesetSyncer.CreateChangeset(r.Context(), c, repos.ChangesetSpec{
+		Title:   req.Title,
+		Body:    req.Body,
+		BaseRef: req.BaseRef,
+		HeadRef: req.HeadRef,
+	})
+
+	var resp protocol.ChangesetCreateResponse
+	if err != nil {
+		log15.Error("create-changeset", "repo", req.RepoID, "head", req.HeadRef, "error", err)
+		resp.Error = err.Error()
+	}
+	resp.ExternalID = c.ExternalID
+
+	respond(w, http.StatusOK, resp)
+}
+
 // TODO(tsenart): Reuse this function in all handlers.
 func respond(w http.ResponseWriter, code int, v interface{}) {
 	switch val := v.(type) {
@@ -278,7 +346,7 @@ func (s *Server) enqueueRepoUpdate(ctx context.Context, req *protocol.RepoUpdate
 			req.URL = urls[0]
 		}
 	}
-	s.Scheduler.UpdateOnce(repo.ID, req.Repo, req.URL)
+	s.Scheduler.UpdateOnce(repo.ID, req.Repo, req.URL, repo.Pinned)
 
 	return &protocol.RepoUpdateResponse{
 		ID:   repo.ID,
@@ -1041,7 +1041,7 @@ func (s *fakeScheduler) UpdateQueueLen() int {
 	return 0
 }
 
-func (s *fakeScheduler) UpdateOnce(_ uint32, _ api.RepoName, _ string) {}
+func (s *fakeScheduler) UpdateOnce(_ uint32, _ api.RepoName, _ string, _ bool) {}
 func (s *fakeScheduler) ScheduleInfo(id uint32) *protocol.RepoUpdateSchedulerInfoResult {
 	return &protocol.RepoUpdateSchedulerInfoResult{}
 }
@@ -12,10 +12,12 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
 	log15 "gopkg.in/inconshreveable/log15.v2"
 
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/search"
@@ -25,6 +27,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/store"
 	"github.com/sourcegraph/sourcegraph/pkg/tracer"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
 
 var cacheDir = env.Get("CACHE_DIR", "/tmp", "directory to store cached archives.")
@@ -52,6 +55,29 @@ func main() {
 			FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
 				return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar"})
 			},
+			FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+				pathspecs := make([]string, len(paths))
+				for i, path := range paths {
+					pathspecs[i] = ":(literal)" + path
+				}
+				return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar", Paths: pathspecs})
+			},
+			GitDiff: func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (git.Changes, error) {
+				return git.DiffNameStatus(ctx, gitserver.Repo{Name: repo}, commitA, commitB)
+			},
+			ListAncestors: func(ctx context.Context, repo api.RepoName, commit api.CommitID, n int) ([]api.CommitID, error) {
+				cmd := gitserver.DefaultClient.Command("git", "rev-list", "--first-parent", "--max-count="+strconv.Itoa(n), string(commit))
+				cmd.Repo = gitserver.Repo{Name: repo}
+				out, err := cmd.Output(ctx)
+				if err != nil {
+					return nil, errors.Wrap(err, "git rev-list")
+				}
+				var ancestors []api.CommitID
+				for _, line := range strings.Fields(string(out)) {
+					ancestors = append(ancestors, api.CommitID(line))
+				}
+				return ancestors, nil
+			},
 			Path:              filepath.Join(cacheDir, "searcher-archives"),
 			MaxCacheSizeBytes: cacheSizeBytes,
 		},
@@ -64,6 +64,11 @@ type PatternInfo struct {
 	// when finding matches.
 	IsCaseSensitive bool
 
+	// IsStructuralPat if true will treat the Pattern as a comby match
+	// template (e.g., "fmt.Sprintf(:[args])") instead of a regexp or fixed
+	// string. IsRegExp, IsWordMatch and IsCaseSensitive are ignored.
+	IsStructuralPat bool
+
 	// ExcludePattern is a pattern that may not match the returned files' paths.
 	// eg '**/node_modules'
 	ExcludePattern string
@@ -128,6 +133,9 @@ func (p *PatternInfo) String() string {
 	if p.IsCaseSensitive {
 		args = append(args, "case")
 	}
+	if p.IsStructuralPat {
+		args = append(args, "structural")
+	}
 	if !p.PatternMatchesContent {
 		args = append(args, "nocontent")
 	}
@@ -191,6 +199,29 @@ type LineMatch struct {
 	// Offsets and lengths are measured in characters, not bytes.
 	OffsetAndLengths [][2]int
 
+	// Ranges is the range of each match that starts on this line. Unlike
+	// OffsetAndLengths, a range may end on a later line (for patterns that
+	// match across line boundaries). The part of such a match that is on a
+	// later line is also in the OffsetAndLengths of that line's LineMatch.
+	Ranges []Range
+
 	// LimitHit is true if OffsetAndLengths may not include all OffsetAndLengths.
 	LimitHit bool
 }
+
+// Range is the range of a match in a file, which may span multiple lines. The
+// start is inclusive and the end is exclusive.
+type Range struct {
+	Start Location
+	End   Location
+}
+
+// Location is a position in a file.
+type Location struct {
+	// Line is the 0-based line number.
+	Line int
+
+	// Column is the 0-based offset in the line, measured in characters (not
+	// bytes).
+	Column int
+}
@@ -252,6 +252,9 @@ func hydrateLineNumbers(fileBuf []byte, lastLineNumber, lastMatchIndex, lineStar
 
 // matchLineBuf is a byte slice that contains the full line(s) that the match appears on.
 func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []byte, lineNumber, start, end int) []protocol.LineMatch {
+	// The full range of the match is reported on the line it starts on.
+	ranges := []protocol.Range{matchRange(fileBuf, lineNumber, start, end)}
+
 	// If any newlines appear between start and end, we need to append multiple LineMatch.
 	// We assume there are no newlines before start.
 	for len(matchLineBuf) > 0 {
@@ -273,7 +276,9 @@ func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []
 		}
 
 		offset := utf8.RuneCount(line[:start])
-		length := utf8.RuneCount(line[start:e])
+		// The newline that ends the line is not part of the preview, so
+		// it is not part of the match's length either.
+		length := utf8.RuneCount(bytes.TrimSuffix(line[start:e], []byte{'\n'}))
 		limit := eol
 		if limit < 0 {
 			limit = len(fileBuf)
@@ -287,8 +292,10 @@ func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []
 			Preview:          string(fileBuf[:limit]),
 			LineNumber:       lineNumber,
 			OffsetAndLengths: [][2]int{{offset, length}},
+			Ranges:           ranges,
 			LimitHit:         false, // We will always return false for this field since we no longer limit the number of offsets per line.
 		})
+		ranges = nil
 
 		if eol >= 0 {
 			fileBuf = fileBuf[eol+1:]
@@ -301,6 +308,23 @@ func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []
 	return matches
 }
 
+// matchRange returns the range of the match at fileBuf[start:end], where
+// fileBuf starts at the beginning of line lineNumber. The match may span
+// multiple lines.
+func matchRange(fileBuf []byte, lineNumber, start, end int) protocol.Range {
+	endLineStart := bytes.LastIndexByte(fileBuf[:end], '\n') + 1
+	return protocol.Range{
+		Start: protocol.Location{
+			Line:   lineNumber,
+			Column: utf8.RuneCount(fileBuf[:start]),
+		},
+		End: protocol.Location{
+			Line:   lineNumber + bytes.Count(fileBuf[start:end], []byte{'\n'}),
+			Column: utf8.RuneCount(fileBuf[endLineStart:end]),
+		},
+	}
+}
+
 // FindZip is a convenience function to run Find on f.
 func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile) (protocol.FileMatch, error) {
 	lm, limitHit, err := rg.Find(zf, f)
@@ -480,6 +480,59 @@ func TestMaxMatches(t *testing.T) {
 // - IncludePatterns can match the path in any order
 // - A path must match all (not any) of the IncludePatterns
 // - An empty pattern is allowed
+func TestFind_multiline(t *testing.T) {
+	zipData, err := createZip(map[string]string{
+		"a.go": "x := foo(\n\t\tbar)\nfoo(bar)\n// é foo(\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rg, err := compile(&protocol.PatternInfo{Pattern: `foo\(\s*(bar)?`, IsRegExp: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, limitHit, err := rg.Find(zf, &zf.Files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limitHit {
+		t.Error("got limitHit, want false")
+	}
+	want := []protocol.LineMatch{
+		{
+			Preview:          "x := foo(",
+			LineNumber:       0,
+			OffsetAndLengths: [][2]int{{5, 4}},
+			Ranges:           []protocol.Range{{Start: protocol.Location{Line: 0, Column: 5}, End: protocol.Location{Line: 1, Column: 5}}},
+		},
+		{
+			Preview:          "\t\tbar)",
+			LineNumber:       1,
+			OffsetAndLengths: [][2]int{{0, 5}},
+		},
+		{
+			Preview:          "foo(bar)",
+			LineNumber:       2,
+			OffsetAndLengths: [][2]int{{0, 7}},
+			Ranges:           []protocol.Range{{Start: protocol.Location{Line: 2, Column: 0}, End: protocol.Location{Line: 2, Column: 7}}},
+		},
+		{
+			Preview:          "// é foo(",
+			LineNumber:       3,
+			OffsetAndLengths: [][2]int{{5, 4}},
+			Ranges:           []protocol.Range{{Start: protocol.Location{Line: 3, Column: 5}, End: protocol.Location{Line: 4, Column: 0}}},
+		},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("got  %+v\nwant %+v", matches, want)
+	}
+}
+
 func TestPathMatches(t *testing.T) {
 	zipData, err := createZip(map[string]string{
 		"a":   "",
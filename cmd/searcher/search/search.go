@@ -129,6 +129,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	span.SetTag("isRegExp", strconv.FormatBool(p.IsRegExp))
 	span.SetTag("isWordMatch", strconv.FormatBool(p.IsWordMatch))
 	span.SetTag("isCaseSensitive", strconv.FormatBool(p.IsCaseSensitive))
+	span.SetTag("isStructuralPat", strconv.FormatBool(p.IsStructuralPat))
 	span.SetTag("pathPatternsAreRegExps", strconv.FormatBool(p.PathPatternsAreRegExps))
 	span.SetTag("pathPatternsAreCaseSensitive", strconv.FormatBool(p.PathPatternsAreCaseSensitive))
 	span.SetTag("fileMatchLimit", p.FileMatchLimit)
@@ -168,13 +169,16 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 		span.SetTag("deadlineHit", deadlineHit)
 		span.Finish()
 		if s.Log != nil {
-			s.Log.Debug("search request", "repo", p.Repo, "commit", p.Commit, "pattern", p.Pattern, "isRegExp", p.IsRegExp, "isWordMatch", p.IsWordMatch, "isCaseSensitive", p.IsCaseSensitive, "patternMatchesContent", p.PatternMatchesContent, "patternMatchesPath", p.PatternMatchesPath, "matches", len(matches), "code", code, "duration", time.Since(start), "err", err)
+			s.Log.Debug("search request", "repo", p.Repo, "commit", p.Commit, "pattern", p.Pattern, "isRegExp", p.IsRegExp, "isWordMatch", p.IsWordMatch, "isCaseSensitive", p.IsCaseSensitive, "isStructuralPat", p.IsStructuralPat, "patternMatchesContent", p.PatternMatchesContent, "patternMatchesPath", p.PatternMatchesPath, "matches", len(matches), "code", code, "duration", time.Since(start), "err", err)
 		}
 	}(time.Now())
 
-	rg, err := compile(&p.PatternInfo)
-	if err != nil {
-		return nil, false, false, badRequestError{err.Error()}
+	var rg *readerGrep
+	if !p.IsStructuralPat {
+		rg, err = compile(&p.PatternInfo)
+		if err != nil {
+			return nil, false, false, badRequestError{err.Error()}
+		}
 	}
 
 	if p.FetchTimeout == "" {
@@ -196,7 +200,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 		return path, zf, err
 	}
 
-	_, zf, err := store.GetZipFileWithRetry(getZf)
+	zipPath, zf, err := store.GetZipFileWithRetry(getZf)
 	if err != nil {
 		return nil, false, false, err
 	}
@@ -211,6 +215,11 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	archiveFiles.Observe(float64(nFiles))
 	archiveSize.Observe(float64(bytes))
 
+	if p.IsStructuralPat {
+		matches, limitHit, err = structuralSearch(ctx, zipPath, zf, &p.PatternInfo)
+		return matches, limitHit, false, err
+	}
+
 	matches, limitHit, err = concurrentFind(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, p.PatternMatchesPath)
 	return matches, limitHit, false, err
 }
@@ -223,6 +232,9 @@ func validateParams(p *protocol.Request) error {
 	if len(p.Commit) != 40 {
 		return errors.Errorf("Commit must be resolved (Commit=%q)", p.Commit)
 	}
+	if p.IsStructuralPat && p.Pattern == "" {
+		return errors.New("Pattern must be non-empty for structural search")
+	}
 	if p.Pattern == "" && p.ExcludePattern == "" && len(p.IncludePatterns) == 0 && p.IncludePattern == "" {
 		return errors.New("At least one of pattern and include/exclude pattners must be non-empty")
 	}
@@ -0,0 +1,171 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os/exec"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/pathmatch"
+	"github.com/sourcegraph/sourcegraph/pkg/store"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+)
+
+// combyPath is the path of the comby binary used for structural search.
+var combyPath = "comby"
+
+// combyFileMatch is a single line of the JSON lines output of comby when run
+// with -match-only.
+type combyFileMatch struct {
+	URI     string       `json:"uri"`
+	Matches []combyMatch `json:"matches"`
+}
+
+// combyMatch is a single match of a match template in a file.
+type combyMatch struct {
+	Range struct {
+		Start combyLocation `json:"start"`
+		End   combyLocation `json:"end"`
+	} `json:"range"`
+	Matched string `json:"matched"`
+}
+
+// combyLocation is a location in a file. Line and Column are 1-based; Offset
+// is the 0-based byte offset.
+type combyLocation struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// structuralSearch runs comby over the archive at zipPath (the same archive
+// that zf was read from) and returns the files containing matches of the
+// match template p.Pattern.
+func structuralSearch(ctx context.Context, zipPath string, zf *store.ZipFile, p *protocol.PatternInfo) (matches []protocol.FileMatch, limitHit bool, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "StructuralSearch")
+	ext.Component.Set(span, "matcher")
+	span.SetTag("matchTemplate", p.Pattern)
+	defer func() {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.SetTag("err", err.Error())
+		}
+		span.LogFields(otlog.Int("matches.len", len(matches)))
+		span.Finish()
+	}()
+
+	pathOptions := pathmatch.CompileOptions{
+		RegExp:        p.PathPatternsAreRegExps,
+		CaseSensitive: p.PathPatternsAreCaseSensitive,
+	}
+	matchPath, err := pathmatch.CompilePathPatterns(p.AllIncludePatterns(), p.ExcludePattern, pathOptions)
+	if err != nil {
+		return nil, false, badRequestError{err.Error()}
+	}
+
+	if _, err := exec.LookPath(combyPath); err != nil {
+		return nil, false, errors.New("comby is not installed on the PATH. Try running 'bash <(curl -sL get.comby.dev)'.")
+	}
+
+	// cmdCtx is canceled to stop comby once we have read enough matches.
+	cmdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(cmdCtx, combyPath, p.Pattern, "", "-zip", zipPath, "-json-lines", "-match-only")
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, false, errors.Wrap(err, "starting comby")
+	}
+
+	matches, limitHit, err = readCombyMatches(stdout, zf, matchPath, p.FileMatchLimit)
+	if err != nil || limitHit {
+		cancel()
+	}
+	waitErr := cmd.Wait()
+	if err != nil {
+		return nil, false, err
+	}
+	if ctx.Err() != nil {
+		return matches, limitHit, ctx.Err()
+	}
+	if waitErr != nil && !limitHit {
+		return nil, false, errors.Errorf("comby failed: %s: %s", waitErr, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return matches, limitHit, nil
+}
+
+// readCombyMatches decodes the JSON lines output of comby from r and
+// converts the matches to FileMatches. Files whose paths are not matched by
+// matchPath are skipped.
+func readCombyMatches(r io.Reader, zf *store.ZipFile, matchPath pathmatch.PathMatcher, fileMatchLimit int) (matches []protocol.FileMatch, limitHit bool, err error) {
+	if fileMatchLimit > maxFileMatches || fileMatchLimit <= 0 {
+		fileMatchLimit = maxFileMatches
+	}
+
+	files := make(map[string]*store.SrcFile, len(zf.Files))
+	for i := range zf.Files {
+		files[zf.Files[i].Name] = &zf.Files[i]
+	}
+
+	dec := json.NewDecoder(r)
+	for {
+		var cfm combyFileMatch
+		if err := dec.Decode(&cfm); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, false, errors.Wrap(err, "decoding comby output")
+		}
+
+		f, ok := files[cfm.URI]
+		if !ok || len(cfm.Matches) == 0 || !matchPath.MatchPath(f.Name) {
+			continue
+		}
+		if len(matches) == fileMatchLimit {
+			limitHit = true
+			break
+		}
+
+		fm := protocol.FileMatch{Path: f.Name}
+		fm.LineMatches, fm.LimitHit = combyLineMatches(zf.DataFor(f), cfm.Matches)
+		matches = append(matches, fm)
+	}
+	return matches, limitHit, nil
+}
+
+// combyLineMatches returns a LineMatch for each line spanned by the comby
+// matches in fileBuf.
+func combyLineMatches(fileBuf []byte, cms []combyMatch) (matches []protocol.LineMatch, limitHit bool) {
+	for _, cm := range cms {
+		start, end := cm.Range.Start.Offset, cm.Range.End.Offset
+		if start < 0 || end < start || end > len(fileBuf) {
+			continue
+		}
+
+		lineStart := bytes.LastIndexByte(fileBuf[:start], '\n') + 1
+		lineEnd := len(fileBuf)
+		if end > 0 && fileBuf[end-1] == '\n' {
+			lineEnd = end
+		} else if idx := bytes.IndexByte(fileBuf[end:], '\n'); idx >= 0 {
+			lineEnd = end + idx
+		}
+		lineNumber := bytes.Count(fileBuf[:lineStart], []byte{'\n'})
+
+		lineBuf := fileBuf[lineStart:lineEnd]
+		matches = appendMatches(matches, lineBuf, lineBuf, lineNumber, start-lineStart, end-lineStart)
+		if len(matches) > maxLineMatches {
+			return matches[:maxLineMatches], true
+		}
+	}
+	return matches, false
+}
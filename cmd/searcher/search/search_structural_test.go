@@ -0,0 +1,81 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/pathmatch"
+	"github.com/sourcegraph/sourcegraph/pkg/store"
+)
+
+func TestReadCombyMatches(t *testing.T) {
+	zipData, err := createZip(map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tfmt.Println(\"a\",\n\t\t\"b\")\n}\n",
+		"foo.go":  "package foo\n\nvar x = fmt.Println(1)\n",
+		"README":  "fmt.Println(2)\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matchPath, err := pathmatch.CompilePathPatterns([]string{`\.go$`}, "", pathmatch.CompileOptions{RegExp: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The output of `comby 'fmt.Println(:[args])' '' -zip z.zip -json-lines -match-only`.
+	output := strings.Join([]string{
+		`{"uri":"main.go","matches":[{"range":{"start":{"offset":29,"line":4,"column":2},"end":{"offset":52,"line":5,"column":7}},"environment":[],"matched":"fmt.Println(\"a\",\n\t\t\"b\")"}]}`,
+		`{"uri":"foo.go","matches":[{"range":{"start":{"offset":21,"line":3,"column":9},"end":{"offset":35,"line":3,"column":23}},"environment":[],"matched":"fmt.Println(1)"}]}`,
+		`{"uri":"README","matches":[{"range":{"start":{"offset":0,"line":1,"column":1},"end":{"offset":14,"line":1,"column":15}},"environment":[],"matched":"fmt.Println(2)"}]}`,
+	}, "\n")
+
+	matches, limitHit, err := readCombyMatches(strings.NewReader(output), zf, matchPath, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limitHit {
+		t.Error("got limitHit, want false")
+	}
+	want := []protocol.FileMatch{
+		{
+			Path: "main.go",
+			LineMatches: []protocol.LineMatch{
+				{
+					Preview:          "\tfmt.Println(\"a\",",
+					LineNumber:       3,
+					OffsetAndLengths: [][2]int{{1, 16}},
+					Ranges:           []protocol.Range{{Start: protocol.Location{Line: 3, Column: 1}, End: protocol.Location{Line: 4, Column: 6}}},
+				},
+				{Preview: "\t\t\"b\")", LineNumber: 4, OffsetAndLengths: [][2]int{{0, 6}}},
+			},
+		},
+		{
+			Path: "foo.go",
+			LineMatches: []protocol.LineMatch{
+				{
+					Preview:          "var x = fmt.Println(1)",
+					LineNumber:       2,
+					OffsetAndLengths: [][2]int{{8, 14}},
+					Ranges:           []protocol.Range{{Start: protocol.Location{Line: 2, Column: 8}, End: protocol.Location{Line: 2, Column: 22}}},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("got  %+v\nwant %+v", matches, want)
+	}
+
+	matches, limitHit, err = readCombyMatches(strings.NewReader(output), zf, matchPath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limitHit || len(matches) != 1 {
+		t.Errorf("got %d matches (limitHit %v), want 1 match (limitHit true)", len(matches), limitHit)
+	}
+}
@@ -0,0 +1,200 @@
+package symbols
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/diskcache"
+	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// maxAncestorsToSearch is the number of ancestors of a commit which are
+// checked for an existing symbols database to derive the commit's database
+// from.
+const maxAncestorsToSearch = 100
+
+// maxPathsPerFetch is the maximum number of paths included in a single
+// archive request when parsing the files changed since an ancestor.
+const maxPathsPerFetch = 500
+
+// canIndexIncrementally reports whether the service is configured to derive
+// the symbols of a commit from those of an ancestor. The commit ID must be
+// absolute, since it is passed to git commands other than archive.
+func (s *Service) canIndexIncrementally(commitID api.CommitID) bool {
+	return s.ListAncestors != nil && s.GitDiff != nil && s.FetchTarPaths != nil && git.IsAbsoluteRevision(string(commitID))
+}
+
+// writeSymbolsToNewDB writes the symbols of repo@commitID to the blank
+// database file `dbFile`. If the database of an ancestor of the commit is in
+// the cache, it is used as the starting point and only the files which changed
+// since the ancestor are parsed. Otherwise all symbols are parsed.
+func (s *Service) writeSymbolsToNewDB(ctx context.Context, dbFile string, repoName api.RepoName, commitID api.CommitID) error {
+	if s.canIndexIncrementally(commitID) {
+		ok, err := s.writeChangedSymbolsToNewDB(ctx, dbFile, repoName, commitID)
+		if ok {
+			indexBuilds.WithLabelValues("incremental").Inc()
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log15.Warn("Failed to index symbols incrementally, indexing all symbols instead.", "repo", repoName, "commit", commitID, "error", err)
+		}
+
+		// Start over with a blank database.
+		if err := os.Truncate(dbFile, 0); err != nil {
+			return err
+		}
+	}
+
+	if err := s.writeAllSymbolsToNewDB(ctx, dbFile, repoName, commitID); err != nil {
+		return err
+	}
+	indexBuilds.WithLabelValues("full").Inc()
+	return nil
+}
+
+// writeChangedSymbolsToNewDB copies the database of the nearest ancestor of
+// repo@commitID that is in the cache to `dbFile`, and updates it with the
+// symbols of the files which changed since the ancestor. It returns false if
+// no ancestor is in the cache.
+func (s *Service) writeChangedSymbolsToNewDB(ctx context.Context, dbFile string, repoName api.RepoName, commitID api.CommitID) (ok bool, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "writeChangedSymbolsToNewDB")
+	defer func() {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(otlog.Error(err))
+		}
+		span.Finish()
+	}()
+
+	ancestor, ancestorDB, err := s.findIndexedAncestor(ctx, repoName, commitID)
+	if err != nil || ancestorDB == nil {
+		return false, err
+	}
+	defer ancestorDB.Close()
+	span.SetTag("ancestor", string(ancestor))
+
+	changes, err := s.GitDiff(ctx, repoName, ancestor, commitID)
+	if err != nil {
+		return false, err
+	}
+	span.LogFields(
+		otlog.Int("added", len(changes.Added)),
+		otlog.Int("modified", len(changes.Modified)),
+		otlog.Int("deleted", len(changes.Deleted)),
+	)
+
+	if err := copyFile(dbFile, ancestorDB.File); err != nil {
+		return false, err
+	}
+
+	db, err := sqlx.Open("sqlite3_with_pcre", dbFile)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, paths := range [][]string{changes.Added, changes.Modified, changes.Deleted} {
+		for _, path := range paths {
+			if _, err := tx.Exec(`DELETE FROM symbols WHERE path = ?`, path); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	insertStatement, err := prepareInsertSymbolStatement(tx)
+	if err != nil {
+		return false, err
+	}
+
+	paths := append(append([]string{}, changes.Added...), changes.Modified...)
+	for len(paths) > 0 {
+		n := len(paths)
+		if n > maxPathsPerFetch {
+			n = maxPathsPerFetch
+		}
+		err = s.parseUncached(ctx, repoName, commitID, paths[:n], func(symbol protocol.Symbol) error {
+			symbolInDBValue := symbolToSymbolInDB(symbol)
+			_, err := insertStatement.Exec(&symbolInDBValue)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+		paths = paths[n:]
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// findIndexedAncestor returns the nearest ancestor of repo@commitID whose
+// symbols database is in the cache, along with the opened database file. The
+// file is nil if no such ancestor was found.
+func (s *Service) findIndexedAncestor(ctx context.Context, repoName api.RepoName, commitID api.CommitID) (api.CommitID, *diskcache.File, error) {
+	ancestors, err := s.ListAncestors(ctx, repoName, commitID, maxAncestorsToSearch)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, ancestor := range ancestors {
+		if ancestor == commitID {
+			continue
+		}
+		f, err := s.cache.OpenIfExists(dbCacheKey(repoName, ancestor))
+		if err == nil {
+			return ancestor, f, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, err
+		}
+	}
+	return "", nil, nil
+}
+
+// copyFile copies the contents of src to the existing file at path dst.
+func copyFile(dst string, src io.Reader) error {
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var indexBuilds = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "symbols",
+	Subsystem: "store",
+	Name:      "index_builds",
+	Help:      "The total number of symbols databases built, by whether they were derived from an ancestor's (incremental) or not (full).",
+}, []string{"type"})
+
+func init() {
+	prometheus.MustRegister(indexBuilds)
+}
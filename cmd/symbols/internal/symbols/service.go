@@ -13,6 +13,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/diskcache"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
 
 // Service is the symbols service.
@@ -22,6 +23,20 @@ type Service struct {
 	// determine if the error is a bad request (eg invalid repo).
 	FetchTar func(context.Context, gitserver.Repo, api.CommitID) (io.ReadCloser, error)
 
+	// FetchTarPaths is like FetchTar, but the archive only includes the files at the given paths.
+	FetchTarPaths func(context.Context, gitserver.Repo, api.CommitID, []string) (io.ReadCloser, error)
+
+	// GitDiff returns the paths of the files which changed between two commits of a repository.
+	GitDiff func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (git.Changes, error)
+
+	// ListAncestors returns up to n ancestors of a commit (including the commit itself), nearest
+	// first.
+	//
+	// If FetchTarPaths, GitDiff, and ListAncestors are all set, the symbols of a commit are derived
+	// from those of its nearest ancestor already in the cache by parsing only the files which
+	// changed since the ancestor. Otherwise, all files are parsed for every commit.
+	ListAncestors func(ctx context.Context, repo api.RepoName, commitID api.CommitID, n int) ([]api.CommitID, error)
+
 	// MaxConcurrentFetchTar is the maximum number of concurrent calls allowed
 	// to FetchTar. It defaults to 15.
 	MaxConcurrentFetchTar int
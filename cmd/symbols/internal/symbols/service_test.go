@@ -13,7 +13,9 @@ import (
 	"path"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
@@ -21,6 +23,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	symbolsclient "github.com/sourcegraph/sourcegraph/pkg/symbols"
 	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
 
 func init() {
@@ -72,8 +75,14 @@ func TestIsLiteralEquality(t *testing.T) {
 	}
 }
 
+var registerSqlite3Once sync.Once
+
+func mustRegisterSqlite3WithPcreOnce() {
+	registerSqlite3Once.Do(MustRegisterSqlite3WithPcre)
+}
+
 func TestService(t *testing.T) {
-	MustRegisterSqlite3WithPcre()
+	mustRegisterSqlite3WithPcreOnce()
 
 	tmpDir, err := ioutil.TempDir("", "")
 	if err != nil {
@@ -159,6 +168,92 @@ func TestService(t *testing.T) {
 	}
 }
 
+func TestService_Incremental(t *testing.T) {
+	mustRegisterSqlite3WithPcreOnce()
+
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { os.RemoveAll(tmpDir) }()
+
+	const (
+		parent = api.CommitID("1111111111111111111111111111111111111111")
+		child  = api.CommitID("2222222222222222222222222222222222222222")
+	)
+	commits := map[api.CommitID]map[string]string{
+		parent: {"a.js": "x", "b.js": "y", "c.js": "z"},
+		child:  {"a.js": "x2", "c.js": "z", "d.js": "w"},
+	}
+
+	var fetchedAll int
+	var fetchedPaths []string
+	service := Service{
+		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
+			fetchedAll++
+			return createTar(commits[commit])
+		},
+		FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			files := map[string]string{}
+			for _, path := range paths {
+				files[path] = commits[commit][path]
+			}
+			fetchedPaths = append(fetchedPaths, paths...)
+			return createTar(files)
+		},
+		GitDiff: func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (git.Changes, error) {
+			if commitA != parent || commitB != child {
+				t.Fatalf("unexpected diff %s..%s", commitA, commitB)
+			}
+			return git.Changes{Added: []string{"d.js"}, Modified: []string{"a.js"}, Deleted: []string{"b.js"}}, nil
+		},
+		ListAncestors: func(ctx context.Context, repo api.RepoName, commitID api.CommitID, n int) ([]api.CommitID, error) {
+			if commitID == child {
+				return []api.CommitID{child, parent}, nil
+			}
+			return []api.CommitID{commitID}, nil
+		},
+		NewParser: func() (ctags.Parser, error) {
+			return pathParser{}, nil
+		},
+		Path: tmpDir,
+	}
+
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(service.Handler())
+	defer server.Close()
+	client := symbolsclient.Client{URL: server.URL}
+
+	search := func(commitID api.CommitID) []protocol.Symbol {
+		t.Helper()
+		result, err := client.Search(context.Background(), protocol.SearchArgs{Repo: "foo", CommitID: commitID, First: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Slice(result.Symbols, func(i, j int) bool { return result.Symbols[i].Path < result.Symbols[j].Path })
+		return result.Symbols
+	}
+
+	want := []protocol.Symbol{{Name: "x", Path: "a.js"}, {Name: "y", Path: "b.js"}, {Name: "z", Path: "c.js"}}
+	if have := search(parent); !reflect.DeepEqual(have, want) {
+		t.Errorf("got %+v, want %+v", have, want)
+	}
+
+	want = []protocol.Symbol{{Name: "x2", Path: "a.js"}, {Name: "z", Path: "c.js"}, {Name: "w", Path: "d.js"}}
+	if have := search(child); !reflect.DeepEqual(have, want) {
+		t.Errorf("got %+v, want %+v", have, want)
+	}
+	if fetchedAll != 1 {
+		t.Errorf("fetched %d full archives, want 1", fetchedAll)
+	}
+	sort.Strings(fetchedPaths)
+	if want := []string{"a.js", "d.js"}; !reflect.DeepEqual(fetchedPaths, want) {
+		t.Errorf("fetched paths %q, want %q", fetchedPaths, want)
+	}
+}
+
 func createTar(files map[string]string) (io.ReadCloser, error) {
 	buf := new(bytes.Buffer)
 	w := tar.NewWriter(buf)
@@ -194,3 +289,16 @@ func (m mockParser) Parse(name string, content []byte) ([]ctags.Entry, error) {
 }
 
 func (mockParser) Close() {}
+
+// pathParser returns a symbol for each word in a file.
+type pathParser struct{}
+
+func (pathParser) Parse(name string, content []byte) ([]ctags.Entry, error) {
+	var entries []ctags.Entry
+	for _, word := range strings.Fields(string(content)) {
+		entries = append(entries, ctags.Entry{Name: word, Path: name})
+	}
+	return entries, nil
+}
+
+func (pathParser) Close() {}
@@ -13,6 +13,7 @@ import (
 	"os/signal"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
@@ -27,6 +28,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/env"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/tracer"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
 
 const port = "3184"
@@ -51,6 +53,29 @@ func main() {
 		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
 			return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar"})
 		},
+		FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			pathspecs := make([]string, len(paths))
+			for i, path := range paths {
+				pathspecs[i] = ":(literal)" + path
+			}
+			return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar", Paths: pathspecs})
+		},
+		GitDiff: func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (git.Changes, error) {
+			return git.DiffNameStatus(ctx, gitserver.Repo{Name: repo}, commitA, commitB)
+		},
+		ListAncestors: func(ctx context.Context, repo api.RepoName, commit api.CommitID, n int) ([]api.CommitID, error) {
+			cmd := gitserver.DefaultClient.Command("git", "rev-list", "--first-parent", "--max-count="+strconv.Itoa(n), string(commit))
+			cmd.Repo = gitserver.Repo{Name: repo}
+			out, err := cmd.Output(ctx)
+			if err != nil {
+				return nil, errors.Wrap(err, "git rev-list")
+			}
+			var ancestors []api.CommitID
+			for _, line := range strings.Fields(string(out)) {
+				ancestors = append(ancestors, api.CommitID(line))
+			}
+			return ancestors, nil
+		},
 		NewParser: func() (ctags.Parser, error) {
 			parser, err := ctags.NewParser(ctags.GetCommand())
 			if err != nil {
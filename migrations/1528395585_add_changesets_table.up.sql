@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE changesets (
+  id bigserial PRIMARY KEY,
+  campaign_id bigint NOT NULL REFERENCES campaigns(id)
+    ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+  repo_id integer NOT NULL REFERENCES repo(id)
+    ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+  external_id text NOT NULL,
+  title text NOT NULL DEFAULT '',
+  url text NOT NULL DEFAULT '',
+  state text NOT NULL DEFAULT '',
+  review_state text NOT NULL DEFAULT '',
+  check_state text NOT NULL DEFAULT '',
+  external_created_at timestamp with time zone,
+  external_closed_at timestamp with time zone,
+  external_merged_at timestamp with time zone,
+  metadata jsonb NOT NULL DEFAULT '{}',
+  synced_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+ALTER TABLE changesets ADD CONSTRAINT changesets_campaign_id_repo_id_external_id_unique
+UNIQUE (campaign_id, repo_id, external_id);
+
+CREATE INDEX changesets_repo_id ON changesets(repo_id);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE campaigns DROP COLUMN IF EXISTS campaign_plan_id;
+DROP TABLE IF EXISTS campaign_jobs;
+DROP TABLE IF EXISTS campaign_plans;
+
+COMMIT;
@@ -0,0 +1,36 @@
+BEGIN;
+
+CREATE TABLE campaign_plans (
+  id bigserial PRIMARY KEY,
+  query text NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE TABLE campaign_jobs (
+  id bigserial PRIMARY KEY,
+  campaign_plan_id bigint NOT NULL REFERENCES campaign_plans(id)
+    ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+  repo_id integer NOT NULL REFERENCES repo(id)
+    ON DELETE CASCADE DEFERRABLE INITIALLY IMMEDIATE,
+  rev text NOT NULL,
+  diff text NOT NULL DEFAULT '',
+  error text NOT NULL DEFAULT '',
+  published_ref text NOT NULL DEFAULT '',
+  started_at timestamp with time zone,
+  finished_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+ALTER TABLE campaign_jobs ADD CONSTRAINT campaign_jobs_campaign_plan_id_repo_id_unique
+UNIQUE (campaign_plan_id, repo_id);
+
+CREATE INDEX campaign_jobs_repo_id ON campaign_jobs(repo_id);
+
+ALTER TABLE campaigns ADD COLUMN campaign_plan_id bigint REFERENCES campaign_plans(id)
+  ON DELETE SET NULL DEFERRABLE INITIALLY IMMEDIATE;
+
+CREATE INDEX campaigns_campaign_plan_id ON campaigns(campaign_plan_id);
+
+COMMIT;
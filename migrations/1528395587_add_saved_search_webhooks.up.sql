@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE saved_searches ADD COLUMN webhook_url text;
+ALTER TABLE saved_searches ADD COLUMN webhook_secret text;
+ALTER TABLE saved_searches ADD COLUMN webhook_headers jsonb NOT NULL DEFAULT '{}';
+
+COMMIT;
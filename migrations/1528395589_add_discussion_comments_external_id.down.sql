@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS discussion_comments_external_id_unique;
+
+ALTER TABLE discussion_comments DROP COLUMN IF EXISTS external_service_type;
+ALTER TABLE discussion_comments DROP COLUMN IF EXISTS external_service_id;
+ALTER TABLE discussion_comments DROP COLUMN IF EXISTS external_id;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE discussion_comments ADD COLUMN external_service_type text;
+ALTER TABLE discussion_comments ADD COLUMN external_service_id text;
+ALTER TABLE discussion_comments ADD COLUMN external_id text;
+
+CREATE UNIQUE INDEX discussion_comments_external_id_unique ON discussion_comments(external_service_type, external_service_id, external_id);
+
+COMMIT;
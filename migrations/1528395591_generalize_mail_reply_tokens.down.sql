@@ -0,0 +1,22 @@
+BEGIN;
+
+DELETE FROM mail_reply_tokens WHERE kind != 'discussion-thread';
+
+DROP INDEX IF EXISTS mail_reply_tokens_user_id_kind_target_idx;
+
+ALTER TABLE mail_reply_tokens ADD COLUMN thread_id bigint;
+UPDATE mail_reply_tokens SET thread_id=target::bigint;
+DELETE FROM mail_reply_tokens WHERE thread_id NOT IN (SELECT id FROM discussion_threads);
+ALTER TABLE mail_reply_tokens ALTER COLUMN thread_id SET NOT NULL;
+ALTER TABLE mail_reply_tokens ADD CONSTRAINT discussion_mail_reply_tokens_thread_id_fkey FOREIGN KEY (thread_id) REFERENCES discussion_threads(id) ON DELETE CASCADE;
+ALTER TABLE mail_reply_tokens DROP COLUMN kind;
+ALTER TABLE mail_reply_tokens DROP COLUMN target;
+ALTER TABLE mail_reply_tokens DROP COLUMN created_at;
+
+ALTER TABLE mail_reply_tokens RENAME CONSTRAINT mail_reply_tokens_user_id_fkey TO discussion_mail_reply_tokens_user_id_fkey;
+ALTER TABLE mail_reply_tokens RENAME CONSTRAINT mail_reply_tokens_pkey TO discussion_mail_reply_tokens_pkey;
+ALTER TABLE mail_reply_tokens RENAME TO discussion_mail_reply_tokens;
+CREATE INDEX discussion_mail_reply_tokens_token_idx ON discussion_mail_reply_tokens(token);
+CREATE INDEX discussion_mail_reply_tokens_user_id_thread_id_idx ON discussion_mail_reply_tokens(user_id, thread_id);
+
+COMMIT;
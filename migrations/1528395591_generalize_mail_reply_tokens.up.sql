@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Mail reply tokens are no longer specific to discussion threads: each token
+-- has a kind (such as "discussion-thread" or "saved-search") that selects the
+-- handler of replies, and a target that the handler acts on.
+ALTER TABLE discussion_mail_reply_tokens RENAME TO mail_reply_tokens;
+ALTER TABLE mail_reply_tokens RENAME CONSTRAINT discussion_mail_reply_tokens_pkey TO mail_reply_tokens_pkey;
+ALTER TABLE mail_reply_tokens RENAME CONSTRAINT discussion_mail_reply_tokens_user_id_fkey TO mail_reply_tokens_user_id_fkey;
+DROP INDEX IF EXISTS discussion_mail_reply_tokens_token_idx;
+DROP INDEX IF EXISTS discussion_mail_reply_tokens_user_id_thread_id_idx;
+
+ALTER TABLE mail_reply_tokens ADD COLUMN kind text;
+ALTER TABLE mail_reply_tokens ADD COLUMN target text;
+ALTER TABLE mail_reply_tokens ADD COLUMN created_at timestamp with time zone NOT NULL DEFAULT now();
+UPDATE mail_reply_tokens SET kind='discussion-thread', target=thread_id::text;
+ALTER TABLE mail_reply_tokens ALTER COLUMN kind SET NOT NULL;
+ALTER TABLE mail_reply_tokens ALTER COLUMN target SET NOT NULL;
+ALTER TABLE mail_reply_tokens DROP COLUMN thread_id;
+
+CREATE INDEX mail_reply_tokens_user_id_kind_target_idx ON mail_reply_tokens(user_id, kind, target);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS saved_search_email_mutes;
+
+COMMIT;
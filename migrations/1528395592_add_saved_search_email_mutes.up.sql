@@ -0,0 +1,11 @@
+BEGIN;
+
+CREATE TABLE saved_search_email_mutes (
+    saved_search_id integer NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    muted_until timestamp with time zone,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (saved_search_id, user_id)
+);
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE event_logs (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    user_id integer NOT NULL,
+    anonymous_user_id text NOT NULL,
+    source text NOT NULL,
+    argument jsonb,
+    repository text,
+    "timestamp" timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT event_logs_check_name_not_empty CHECK (name <> ''),
+    CONSTRAINT event_logs_check_source_not_empty CHECK (source <> '')
+);
+
+CREATE INDEX event_logs_timestamp ON event_logs("timestamp");
+CREATE INDEX event_logs_name ON event_logs(name);
+CREATE INDEX event_logs_user_id ON event_logs(user_id);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS access_token_audit_logs;
+ALTER TABLE access_tokens DROP COLUMN IF EXISTS expiry_notified_at;
+
+COMMIT;
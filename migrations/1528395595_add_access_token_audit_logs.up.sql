@@ -0,0 +1,16 @@
+BEGIN;
+
+ALTER TABLE access_tokens ADD COLUMN expiry_notified_at timestamp with time zone;
+
+CREATE TABLE access_token_audit_logs (
+    id bigserial PRIMARY KEY,
+    access_token_id bigint NOT NULL REFERENCES access_tokens(id) ON DELETE CASCADE,
+    "timestamp" timestamp with time zone NOT NULL DEFAULT now(),
+    ip text NOT NULL,
+    endpoint text NOT NULL
+);
+
+CREATE INDEX access_token_audit_logs_access_token_id_timestamp ON access_token_audit_logs(access_token_id, "timestamp");
+CREATE INDEX access_token_audit_logs_timestamp ON access_token_audit_logs("timestamp");
+
+COMMIT;
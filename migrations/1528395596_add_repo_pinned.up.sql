@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE repo ADD COLUMN pinned boolean NOT NULL DEFAULT false;
+
+COMMIT;
@@ -231,6 +231,30 @@
 // 1528395583_add_default_repos_primary_key.up.sql (67B)
 // 1528395584_add_campaigns_table.down.sql (50B)
 // 1528395584_add_campaigns_table.up.sql (822B)
+// 1528395585_add_changesets_table.down.sql (50B)
+// 1528395585_add_changesets_table.up.sql (1.027kB)
+// 1528395586_add_campaign_plans.down.sql (152B)
+// 1528395586_add_campaign_plans.up.sql (1.238kB)
+// 1528395587_add_saved_search_webhooks.down.sql (210B)
+// 1528395587_add_saved_search_webhooks.up.sql (215B)
+// 1528395588_add_query_runner_result_fingerprints.down.sql (91B)
+// 1528395588_add_query_runner_result_fingerprints.up.sql (87B)
+// 1528395589_add_discussion_comments_external_id.down.sql (298B)
+// 1528395589_add_discussion_comments_external_id.up.sql (359B)
+// 1528395590_add_discussions_fulltext_indexes.down.sql (131B)
+// 1528395590_add_discussions_fulltext_indexes.up.sql (259B)
+// 1528395591_generalize_mail_reply_tokens.down.sql (1.251kB)
+// 1528395591_generalize_mail_reply_tokens.up.sql (1.241kB)
+// 1528395592_add_saved_search_email_mutes.down.sql (64B)
+// 1528395592_add_saved_search_email_mutes.up.sql (364B)
+// 1528395593_add_event_logs_table.down.sql (50B)
+// 1528395593_add_event_logs_table.up.sql (605B)
+// 1528395594_add_access_token_expires_at.down.sql (77B)
+// 1528395594_add_access_token_expires_at.up.sql (91B)
+// 1528395595_add_access_token_audit_logs.down.sql (131B)
+// 1528395595_add_access_token_audit_logs.up.sql (580B)
+// 1528395596_add_repo_pinned.down.sql (64B)
+// 1528395596_add_repo_pinned.up.sql (84B)
 
 package migrations
 
@@ -4919,6 +4943,486 @@ func _1528395584_add_campaigns_tableUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __1528395585_add_changesets_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\xce\x48\xcc\x4b\x4f\x2d\x4e\x2d\x29\x06\xaa\x70\xf6\xf7\xf5\xf5\x0c\xb1\xe6\x02\x00\xf0\x42\xbf\xe8\x32\x00\x00\x00")
+
+func _1528395585_add_changesets_tableDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395585_add_changesets_tableDownSql,
+		"1528395585_add_changesets_table.down.sql",
+	)
+}
+
+func _1528395585_add_changesets_tableDownSql() (*asset, error) {
+	bytes, err := _1528395585_add_changesets_tableDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395585_add_changesets_table.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xfc, 0x48, 0x11, 0xbf, 0x36, 0x84, 0x7b, 0x85, 0xd3, 0x88, 0xe1, 0x7, 0xb2, 0xf2, 0x8e, 0xa1, 0xec, 0xe3, 0x87, 0x92, 0xf9, 0x7e, 0x6c, 0x5f, 0x83, 0x15, 0x89, 0x58, 0x4d, 0xf8, 0x8b, 0x8f}}
+	return a, nil
+}
+
+var __1528395585_add_changesets_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x93\xdb\x6e\x83\x30\x0c\x86\xef\x79\x0a\xdf\x95\x4a\x7d\x83\x5e\xa5\x90\x4d\xd1\x20\x6c\x34\x95\xd6\x2b\x94\x81\x45\xb3\x41\xe8\x20\x5d\x77\xd0\xde\x7d\xa1\x47\xaa\x56\x6d\xb7\x5d\xda\xfe\x9c\xdf\x06\xff\x23\x7a\xcb\xf8\xd0\x71\xbc\x98\x12\x41\x41\x90\x51\x40\x21\x9d\x49\x9d\x63\x83\xa6\x01\xd7\x01\x50\x19\x3c\xa9\xbc\xc1\x5a\xc9\x02\xee\x63\x16\x92\x78\x0a\x77\x74\x3a\xb0\xb5\x54\x96\x73\xa9\x72\x9d\xac\x21\xa5\x0d\xf0\x48\x00\x9f\x04\x01\xc4\xf4\x86\xc6\x94\x7b\x74\xbc\xc3\x1a\x57\x65\x7d\xdb\x06\x10\x71\xf0\x69\x40\xad\xa6\x47\xc6\x1e\xf1\xa9\x0d\x2d\x1e\xaf\x06\x60\x9c\x09\x46\x82\x60\x0a\x2c\x0c\xa9\xcf\xec\x68\xad\x58\x8d\xf3\xaa\x15\xb2\x2a\x98\x63\x7d\x52\xa9\x65\xfe\x25\x82\xef\x06\x6b\x2d\x8b\x56\xc8\xd8\x60\xa7\xd2\x16\x8d\x32\x05\x1e\xa6\xdb\x27\xc9\x24\x10\xd0\xeb\xb5\xc4\xa2\x2e\xce\xd6\x1b\x23\xcd\xf9\x17\x6a\x7c\x53\xb8\x4c\x2e\x83\xe9\x0c\xd3\x97\x2b\xb8\xdd\x46\x69\x8d\x96\xcd\x12\x69\xec\x22\x25\xda\xce\x72\x0e\x4b\x65\x66\xab\x10\x3e\x2b\x8d\x87\x7c\x51\x35\xbf\xc0\x4b\xac\xf3\xcb\x78\x89\x46\x66\xd2\x48\x78\x6e\x2a\xfd\x74\x62\xe6\xaf\xef\xf5\x87\xfa\xd0\xe9\xe5\xd7\xae\x58\xe9\x58\x42\x57\x4b\xb7\xbf\xfa\x59\xf3\xec\x8f\xdd\x4e\xdf\x9a\x86\x04\x82\xc6\xc7\x9e\x21\xbe\x0f\x5e\xc4\xc7\x22\x26\x8c\x8b\x4e\x25\xe9\xb8\x25\xd9\x1c\x73\xd2\xb9\xb7\x64\xa1\xd5\xeb\x02\x9d\x09\x67\x0f\x13\x0a\x6e\x07\x1f\x6c\x8f\x7f\xd0\x3d\xd0\xfe\xde\xb9\x8c\xfb\xf4\xb1\xab\xb5\x35\x8b\xf5\xc0\x3e\xeb\x6e\xb2\xab\xc6\x28\x0c\x99\x18\x3a\x3f\x37\x9a\x3f\x01\x03\x04\x00\x00")
+
+func _1528395585_add_changesets_tableUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395585_add_changesets_tableUpSql,
+		"1528395585_add_changesets_table.up.sql",
+	)
+}
+
+func _1528395585_add_changesets_tableUpSql() (*asset, error) {
+	bytes, err := _1528395585_add_changesets_tableUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395585_add_changesets_table.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x85, 0x7d, 0xc2, 0x6a, 0xfb, 0x97, 0x8e, 0xdf, 0x57, 0x70, 0x37, 0x31, 0xf2, 0x9f, 0xfa, 0xd7, 0xec, 0x3b, 0xa3, 0x5a, 0xf1, 0x24, 0xc6, 0x60, 0xd6, 0x5, 0x8b, 0x74, 0x47, 0xa8, 0x91, 0xe6}}
+	return a, nil
+}
+
+var __1528395586_add_campaign_plansDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\x4e\xcc\x2d\x48\xcc\x4c\xcf\x2b\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x86\xcb\xc7\x17\xe4\x24\xe6\xc5\x67\xa6\x58\x73\x81\xd5\x41\x34\x63\x51\x96\x95\x9f\x54\x4c\x48\x0d\xc8\x28\xa0\x22\x2e\x67\x7f\x5f\x5f\xcf\x10\x6b\x2e\x00\xe1\x9d\x0f\xcf\x98\x00\x00\x00")
+
+func _1528395586_add_campaign_plansDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395586_add_campaign_plansDownSql,
+		"1528395586_add_campaign_plans.down.sql",
+	)
+}
+
+func _1528395586_add_campaign_plansDownSql() (*asset, error) {
+	bytes, err := _1528395586_add_campaign_plansDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395586_add_campaign_plans.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x53, 0x2c, 0x7f, 0xe, 0xe9, 0x86, 0x5e, 0xe0, 0xf5, 0x6f, 0x2f, 0xbb, 0x4f, 0x5e, 0x64, 0xae, 0x5d, 0xa5, 0xba, 0x19, 0xc9, 0x98, 0x39, 0x17, 0xb1, 0xb3, 0x99, 0x5a, 0x62, 0xd9, 0x20, 0x66}}
+	return a, nil
+}
+
+var __1528395586_add_campaign_plansUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xc5\x53\xcb\x6e\xc2\x30\x10\xbc\xe7\x2b\xf6\x46\x90\xf8\x03\x4e\x26\x59\x2a\xab\x8e\xd3\x3a\x8e\x54\x4e\x51\x20\x06\x5c\x41\x92\x3a\x49\x69\xfb\xf5\x75\xa0\xbc\x5f\x52\x7b\xe8\xd1\xf6\xec\xee\xcc\xec\x78\x80\x0f\x94\xf7\x1d\xc7\x13\x48\x24\x82\x24\x03\x86\x30\x49\x97\x65\xaa\x67\x79\x52\x2e\xd2\xbc\x02\xd7\x01\xd0\x19\x8c\xf5\xac\x52\x46\xa7\x0b\x78\x12\x34\x20\x62\x04\x8f\x38\xea\xd9\xb7\xb7\x46\x99\x4f\xa8\xd5\x47\x0d\x3c\x94\xc0\x63\xc6\xda\xeb\x89\x51\x69\xad\xb2\x24\xad\xa1\xd6\x4b\x55\xd5\xb6\x2b\xac\x74\x3d\x5f\x1f\xe1\xab\xc8\xd5\x0e\x0f\x3e\x0e\x49\xcc\x24\xe4\xc5\xca\xed\xb6\xd5\x4d\x99\xfd\xb2\xda\xe9\x5e\xd5\xf3\x5a\x8c\xef\xcb\x39\x52\x9f\x6c\x90\x3a\xdf\x6b\x03\x81\x43\x14\xc8\x3d\x8c\x4e\x9c\x72\x75\xd6\xb5\x0d\x00\x42\x6e\x29\x31\xb4\x04\x3c\x12\x79\xc4\xc7\x96\x21\x0a\xb1\x66\x43\x39\x95\x94\x30\x36\x02\x1a\x04\xe8\x53\xcb\xb3\x1d\x6b\x54\x59\xb4\xd3\xec\x28\x35\x53\xe6\xe2\xb8\x16\xf3\xc7\x21\xef\xe7\x8b\xca\xf4\x74\x7a\x7c\xbb\x33\xb4\xd3\x69\x01\xca\x98\xc2\xdc\x44\x94\xcd\x78\xa1\xab\xb9\xdd\x97\x51\xb7\x7b\xd9\x45\x9a\x3b\x7b\x6d\x61\x53\x9d\x6f\xfa\xdd\xc1\xfd\x6b\xc8\x08\x93\x28\x2e\x66\x8c\xf8\x3e\x78\x21\x8f\xa4\x20\x94\xcb\xe3\xc7\xe4\x34\x60\xc9\xcf\xea\x93\x26\xd7\xf6\x2f\x39\x31\xa7\xcf\x31\x82\x7b\x8a\xeb\x6d\x33\x72\x10\x70\xca\x7d\x7c\x39\xe9\xbf\x4d\x92\x0d\xc8\xd1\x83\x7b\x50\x7e\x89\xfa\x96\x36\x8b\x03\x7e\xf5\x17\xdc\x0b\xff\x3e\x95\x11\xee\x8d\xbb\x11\xcb\x6b\x5a\xce\x7d\x3a\x14\x54\x9d\xb9\xb3\x36\x25\x0c\x02\x2a\xfb\xce\x37\xfb\xc0\x55\x46\xd6\x04\x00\x00")
+
+func _1528395586_add_campaign_plansUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395586_add_campaign_plansUpSql,
+		"1528395586_add_campaign_plans.up.sql",
+	)
+}
+
+func _1528395586_add_campaign_plansUpSql() (*asset, error) {
+	bytes, err := _1528395586_add_campaign_plansUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395586_add_campaign_plans.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xd9, 0x60, 0x3c, 0xca, 0xb3, 0x29, 0x30, 0x4d, 0x3b, 0x3f, 0xb6, 0x61, 0xe6, 0x68, 0x44, 0x1d, 0xd8, 0x99, 0x70, 0xec, 0xe, 0x32, 0x7f, 0x45, 0x1b, 0x90, 0xf9, 0xe6, 0xb0, 0xb5, 0x60, 0x4f}}
+	return a, nil
+}
+
+var __1528395587_add_saved_search_webhooksDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x4e\x2c\x4b\x4d\x89\x2f\x4e\x4d\x2c\x4a\xce\x48\x2d\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x4f\x4d\xca\xc8\xcf\xcf\x8e\x2f\x2d\xca\xb1\x26\x5f\x77\x71\x6a\x72\x51\x6a\x09\x05\x06\x64\xa4\x26\xa6\xa4\x16\x15\x03\x7d\xe0\xec\xef\xeb\xeb\x19\x62\xcd\x05\x00\x6b\x40\xcd\x93\xd2\x00\x00\x00")
+
+func _1528395587_add_saved_search_webhooksDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395587_add_saved_search_webhooksDownSql,
+		"1528395587_add_saved_search_webhooks.down.sql",
+	)
+}
+
+func _1528395587_add_saved_search_webhooksDownSql() (*asset, error) {
+	bytes, err := _1528395587_add_saved_search_webhooksDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395587_add_saved_search_webhooks.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x9, 0x36, 0x79, 0xdd, 0x8c, 0x91, 0x63, 0x68, 0x8b, 0x60, 0x34, 0x44, 0x6a, 0x51, 0x3e, 0xbd, 0xe4, 0x0, 0x4d, 0xb, 0x3, 0x78, 0xd2, 0x37, 0xd8, 0x98, 0xaf, 0x6c, 0xde, 0x4d, 0xf8, 0x4d}}
+	return a, nil
+}
+
+var __1528395587_add_saved_search_webhooksUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9d\xcf\x41\x0a\xc3\x20\x10\x00\xc0\xbb\xaf\xd8\x5b\x1e\xe1\xc9\x44\x5b\x02\xab\x42\xd1\x73\x30\xc9\x82\xb4\xa5\x82\x6b\x9a\x40\xe9\xdf\xdb\x2f\xb4\x0f\x98\xc3\xf4\xe6\x3c\x3a\x29\x84\xc2\x60\x2e\x10\x54\x8f\x06\x38\x3d\x69\x9d\x98\x52\x5d\x32\x31\x28\xad\x61\xf0\x18\xad\x83\x9d\xe6\x5c\xca\x6d\xda\xea\x1d\x1a\x1d\x4d\xfe\xe8\x98\x96\x4a\xed\x2f\x9a\x29\xad\x54\x19\xae\x5c\x1e\x33\x38\x1f\xc0\x45\x44\xd0\xe6\xa4\x22\x06\xe8\x5e\xef\xee\xdb\x18\xbc\xb5\x63\x90\xe2\x03\x11\x1e\xce\x94\xd7\x00\x00\x00")
+
+func _1528395587_add_saved_search_webhooksUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395587_add_saved_search_webhooksUpSql,
+		"1528395587_add_saved_search_webhooks.up.sql",
+	)
+}
+
+func _1528395587_add_saved_search_webhooksUpSql() (*asset, error) {
+	bytes, err := _1528395587_add_saved_search_webhooksUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395587_add_saved_search_webhooks.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x70, 0x82, 0x80, 0xec, 0x9f, 0x2f, 0x25, 0xdd, 0xa6, 0x3f, 0x31, 0xd7, 0x4d, 0xe8, 0x41, 0xe8, 0x14, 0xa9, 0x3a, 0x8, 0x73, 0xed, 0x1d, 0x69, 0xd, 0x3c, 0x5d, 0xef, 0xfd, 0xca, 0x2c, 0xad}}
+	return a, nil
+}
+
+var __1528395588_add_query_runner_result_fingerprintsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x0d\xcc\x4b\x0a\x80\x20\x10\x00\xd0\xbd\xa7\x98\x7b\xb4\x2a\x9b\x42\xf0\x13\x66\xd0\x4e\x5a\x4c\x11\x84\xd4\xa8\x8b\x6e\x5f\xef\x00\xaf\xc3\x51\xd9\x46\x88\x56\x07\xf4\x10\xda\x4e\x23\x3c\x95\xf8\x8d\x5c\x53\x22\x8e\xb9\x6c\x85\xa0\xf7\x6e\x02\xe9\xf4\x62\x2c\xa8\x01\x70\x55\x73\x98\x81\x29\xd7\xab\xc4\xfd\x4c\x07\xf1\xcd\x67\x2a\xf9\xaf\xa4\x33\x46\x85\x46\x7c\x3f\xc1\x05\x0b\x5b\x00\x00\x00")
+
+func _1528395588_add_query_runner_result_fingerprintsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395588_add_query_runner_result_fingerprintsDownSql,
+		"1528395588_add_query_runner_result_fingerprints.down.sql",
+	)
+}
+
+func _1528395588_add_query_runner_result_fingerprintsDownSql() (*asset, error) {
+	bytes, err := _1528395588_add_query_runner_result_fingerprintsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395588_add_query_runner_result_fingerprints.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x70, 0x42, 0x71, 0x6d, 0xf2, 0xd1, 0x4, 0x22, 0xfa, 0x99, 0x80, 0xbd, 0x20, 0xc2, 0xcc, 0x67, 0x9a, 0xcc, 0x49, 0xfb, 0x73, 0x75, 0x8a, 0x8a, 0x90, 0xda, 0xc5, 0xd, 0xb5, 0xf, 0x84, 0xc}}
+	return a, nil
+}
+
+var __1528395588_add_query_runner_result_fingerprintsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x2c\x4d\x2d\xaa\x8c\x2f\x2a\xcd\xcb\x4b\x2d\x8a\x2f\x2e\x49\x2c\x49\x55\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x4a\x2d\x2e\xcd\x29\x89\x4f\xcb\xcc\x4b\x4f\x2d\x2a\x28\xca\xcc\x2b\x29\x56\x28\x49\xad\x28\x89\x8e\x05\x1a\xe3\xec\xef\xeb\xeb\x19\x62\xcd\x05\x00\xb0\xf1\x8e\x00\x57\x00\x00\x00")
+
+func _1528395588_add_query_runner_result_fingerprintsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395588_add_query_runner_result_fingerprintsUpSql,
+		"1528395588_add_query_runner_result_fingerprints.up.sql",
+	)
+}
+
+func _1528395588_add_query_runner_result_fingerprintsUpSql() (*asset, error) {
+	bytes, err := _1528395588_add_query_runner_result_fingerprintsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395588_add_query_runner_result_fingerprints.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xdc, 0x49, 0x3c, 0x15, 0x71, 0xe, 0x13, 0x4, 0xb4, 0xaf, 0xb1, 0xac, 0x4c, 0xae, 0x93, 0x37, 0x2a, 0x9, 0x75, 0xa5, 0x6a, 0x9f, 0x7a, 0x1b, 0x26, 0x32, 0x57, 0xd5, 0x48, 0xd8, 0x75, 0x9c}}
+	return a, nil
+}
+
+var __1528395589_add_discussion_comments_external_idDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xad\xcc\x4b\x0e\xc2\x20\x10\x00\xd0\x3d\xa7\x98\x7b\x74\xd5\x0f\x1a\x12\x3e\xa6\xc5\xa4\x3b\x62\x60\x16\x93\x58\x5a\x0b\x18\xbd\xbd\xd5\x85\x71\xe1\x4e\x0f\xf0\x5e\xc3\xf7\x42\x57\x8c\x75\xbd\x39\x80\xd0\x1d\x1f\x41\xec\x80\x8f\x62\xb0\x03\x04\x4a\xbe\xa4\x44\x73\x74\x7e\x9e\x26\x8c\x39\x39\xbc\x65\x5c\xe3\xe9\xec\x28\xb8\x12\xe9\x52\x70\xd3\xb5\xb4\xbc\x07\x5b\x37\x92\x7f\x43\xf0\xda\x5b\x23\x8f\x4a\x7f\xf4\xef\x2a\xe1\x7a\x25\x8f\x2e\xdf\x97\x6d\xfb\x53\x46\xe1\xe7\xea\x59\xb0\xd6\x28\x25\x6c\xc5\x1e\xe2\x9c\xc6\xaa\x2a\x01\x00\x00")
+
+func _1528395589_add_discussion_comments_external_idDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395589_add_discussion_comments_external_idDownSql,
+		"1528395589_add_discussion_comments_external_id.down.sql",
+	)
+}
+
+func _1528395589_add_discussion_comments_external_idDownSql() (*asset, error) {
+	bytes, err := _1528395589_add_discussion_comments_external_idDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395589_add_discussion_comments_external_id.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xa5, 0x84, 0x3d, 0x13, 0xca, 0xc6, 0xfb, 0x0, 0x88, 0xfe, 0x4a, 0xe2, 0x4c, 0xb6, 0x48, 0x0, 0x3, 0x41, 0xfe, 0xa8, 0x99, 0x85, 0xe0, 0x9f, 0xb5, 0x14, 0x6d, 0x82, 0x3a, 0x5c, 0xe8, 0x6}}
+	return a, nil
+}
+
+var __1528395589_add_discussion_comments_external_idUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x8f\xb1\x0a\xc2\x30\x14\x45\xf7\x7c\xc5\x1b\x15\xfa\x07\x9d\xd2\xe6\x21\x81\x24\xc5\x92\x82\x5b\x90\xf6\x0d\x01\x9b\x6a\x93\x88\xfe\xbd\xba\x48\x87\x6c\x5d\x2f\xf7\x1e\xee\x69\xf0\x24\x4d\xcd\x18\x57\x16\x7b\xb0\xbc\x51\x08\x93\x8f\x63\x8e\xd1\x2f\xc1\x8d\xcb\x3c\x53\x48\x11\xb8\x10\xd0\x76\x6a\xd0\x06\xe8\x95\x68\x0d\xd7\x9b\x8b\xb4\x3e\xfd\x48\x2e\xbd\xef\x04\xe9\x1b\xd7\xbb\x30\x7e\xda\x01\xf9\x8f\x59\xdb\x23\xb7\x08\x83\x91\xe7\x01\x41\x1a\x81\x97\x12\xc5\x6d\xa6\x2e\x07\xff\xc8\x04\x9d\x29\x35\x0f\x45\xe1\xaa\x24\x50\x6d\x0f\x1d\x7f\x67\x3a\xad\xa5\xad\xd9\x07\x4d\xfa\x32\x9a\x67\x01\x00\x00")
+
+func _1528395589_add_discussion_comments_external_idUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395589_add_discussion_comments_external_idUpSql,
+		"1528395589_add_discussion_comments_external_id.up.sql",
+	)
+}
+
+func _1528395589_add_discussion_comments_external_idUpSql() (*asset, error) {
+	bytes, err := _1528395589_add_discussion_comments_external_idUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395589_add_discussion_comments_external_id.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x26, 0xb4, 0xf7, 0x73, 0xee, 0x39, 0xfd, 0xba, 0xa4, 0xed, 0xb2, 0xa0, 0x74, 0xfa, 0x88, 0xb7, 0x9d, 0x7d, 0x66, 0x91, 0x58, 0xb7, 0xa8, 0xc8, 0xa7, 0x93, 0xa5, 0x6d, 0x37, 0x39, 0x29, 0xc8}}
+	return a, nil
+}
+
+var __1528395590_add_discussions_fulltext_indexesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\xc9\x2c\x4e\x2e\x2d\x2e\xce\xcc\xcf\x8b\x2f\xc9\x28\x4a\x4d\x4c\x29\x8e\x2f\xc9\x2c\xc9\x49\x8d\x4f\x2b\x29\x8e\xcf\x4c\xa9\xb0\x26\xa8\x2d\x39\x3f\x37\x37\x35\x0f\xa8\x3a\x39\x3f\xaf\x04\xcc\x80\x6b\xe5\x72\xf6\xf7\xf5\xf5\x0c\xb1\xe6\x02\x00\xb6\xe0\x84\xb6\x83\x00\x00\x00")
+
+func _1528395590_add_discussions_fulltext_indexesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395590_add_discussions_fulltext_indexesDownSql,
+		"1528395590_add_discussions_fulltext_indexes.down.sql",
+	)
+}
+
+func _1528395590_add_discussions_fulltext_indexesDownSql() (*asset, error) {
+	bytes, err := _1528395590_add_discussions_fulltext_indexesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395590_add_discussions_fulltext_indexes.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x4, 0x3b, 0xfe, 0x3e, 0x3b, 0x94, 0xa9, 0xea, 0x62, 0xd4, 0x43, 0x2, 0x7, 0xd8, 0x5c, 0x14, 0xf1, 0xb6, 0xea, 0xb6, 0x22, 0x41, 0xcf, 0x6e, 0xbc, 0xe4, 0x92, 0x7c, 0x27, 0x81, 0x78, 0x3a}}
+	return a, nil
+}
+
+var __1528395590_add_discussions_fulltext_indexesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x8e\xb1\x0a\x83\x30\x14\x45\xf7\x7c\xc5\xdb\xa2\xe0\x1f\x38\xd9\x34\x48\xa0\x26\x50\x2d\x74\x0b\x25\xa6\x1a\xd0\x04\x7c\xaf\xa5\x9f\xdf\x56\x70\xb3\x74\xbb\xc3\xe1\x9c\x7b\x90\xb5\xd2\x25\x63\xe2\x2c\xab\x4e\x82\xd2\x47\x79\x85\x3e\xa0\x7b\x20\x86\x14\x2d\x8d\x8b\xbf\xf5\x68\x29\xd0\xe4\xed\x9d\xd0\x86\xfe\x05\x46\xef\x30\x70\x69\x95\xae\x61\x08\x31\xa3\x64\x09\x9f\xde\x51\x5a\x32\xee\xe3\x30\x05\x1c\x79\x01\xc2\x54\x27\xd9\x0a\x99\xad\xb6\x02\x38\xcf\xf3\xbc\xfc\xd9\x76\x69\x9e\x7d\xfc\x24\x5d\x8a\xb4\x8e\xfd\xfe\xc6\xfd\x3f\xb0\x89\xbe\x55\x26\x4c\xd3\xa8\xae\x64\x6f\x59\xea\x29\x00\x03\x01\x00\x00")
+
+func _1528395590_add_discussions_fulltext_indexesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395590_add_discussions_fulltext_indexesUpSql,
+		"1528395590_add_discussions_fulltext_indexes.up.sql",
+	)
+}
+
+func _1528395590_add_discussions_fulltext_indexesUpSql() (*asset, error) {
+	bytes, err := _1528395590_add_discussions_fulltext_indexesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395590_add_discussions_fulltext_indexes.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xa8, 0x4d, 0x9e, 0x18, 0x5a, 0x1e, 0x91, 0xe, 0xbe, 0x6b, 0x42, 0x3c, 0xfb, 0xcc, 0x7c, 0xc6, 0xac, 0xa5, 0xd2, 0xa1, 0x6c, 0x2, 0x4c, 0xb, 0x2c, 0x16, 0x9a, 0x61, 0xae, 0xe8, 0x9, 0xdc}}
+	return a, nil
+}
+
+var __1528395591_generalize_mail_reply_tokensDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xad\x53\xd1\x6e\x82\x30\x14\x7d\xe7\x2b\xee\x9e\xc4\x64\xfb\x01\x89\x0f\x15\x2e\x8e\x0c\x5a\xd3\xd6\xcc\x3d\x35\x4c\x98\x23\x3a\x34\x80\xc9\xfc\xfb\xb5\xa0\xc3\x44\x27\x98\xec\xa9\x81\xde\x73\xce\x3d\xa7\xf7\x4e\x70\x1a\x50\xc7\xb2\x3c\x0c\x51\x22\xf8\x9c\x45\xf0\x15\x67\x1b\x55\xa4\xbb\xcd\x41\x55\xdb\x75\x9a\x97\xf0\xfa\x8c\x1c\x61\x9d\xe5\x09\x3c\x8c\x61\x90\x64\xe5\x72\x5f\x96\xd9\x36\x7f\xaa\x3e\x8b\x34\x4e\x06\x86\x81\xb3\x19\x04\xd4\xc3\x05\x04\x3e\xe0\x22\x10\x52\x5c\x52\xa9\x7d\x99\x16\x2a\x4b\x94\x21\x53\x55\x5c\xac\xd2\x4a\x7f\x7e\x6b\x02\x12\x4a\xe4\x20\xc9\x24\xc4\x2b\x2d\x10\xcf\x03\x97\x85\xf3\x88\x42\xa3\xa9\x51\xf0\x9e\xad\xb2\xbc\x72\xac\xf9\xcc\x23\xf2\x1a\x4a\xa0\x6c\xcb\xc7\x8d\xdc\x68\x74\x82\xf5\x31\xdd\x8a\x51\x26\xb5\x3f\xb0\x85\x46\xb9\x12\xf4\x9f\x1a\xd8\x86\xa1\x9a\xda\x72\xe8\x74\x79\xa9\x6f\x2f\xdc\x98\x66\x8d\x08\x9d\x87\xa1\xd3\x2b\x0e\x2a\x24\x27\x01\x95\xe7\x4d\x5c\x46\xfe\xab\xa0\x3e\xd6\xe9\x01\x7c\xc6\x31\x98\x52\x78\xc1\x37\xb0\x7f\xef\x86\xc0\xd1\xd7\x86\xa9\x8b\xe2\x8a\x27\xdb\x54\x30\x0a\xc7\xc8\x5c\x22\x5c\xe2\x61\x57\x97\xf5\x4c\x1c\x7d\x9a\x07\xbf\xa7\xbe\x79\xac\x7b\x10\x4b\xdd\x69\x95\x26\x2a\xae\x3a\xa7\x49\xfb\x24\x11\x9e\x27\xf8\xf7\xa4\xd6\xa1\x49\x76\x3b\xe3\xf3\x62\xe7\x1f\xc4\x77\x7d\x44\x77\xfd\xc5\x3a\xa8\x1c\xcb\xe5\x68\x56\xa8\x59\xe0\xdb\xe3\x64\x0e\xb3\xb3\x66\x1e\x6e\x55\xda\xf5\x31\xbc\x87\xfb\x14\x63\x3b\xb2\x7d\x74\x8e\xa8\xc7\x76\x97\xb4\xa8\xe5\xb2\x28\x0a\xa4\x63\xfd\x00\x70\x78\x70\x5a\xe3\x04\x00\x00")
+
+func _1528395591_generalize_mail_reply_tokensDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395591_generalize_mail_reply_tokensDownSql,
+		"1528395591_generalize_mail_reply_tokens.down.sql",
+	)
+}
+
+func _1528395591_generalize_mail_reply_tokensDownSql() (*asset, error) {
+	bytes, err := _1528395591_generalize_mail_reply_tokensDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395591_generalize_mail_reply_tokens.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xb6, 0x78, 0x59, 0x9d, 0x76, 0xbe, 0x13, 0xc, 0xb0, 0x31, 0x86, 0x50, 0xfa, 0x72, 0xc0, 0x61, 0xe7, 0xd9, 0x48, 0x6, 0xe7, 0x40, 0xd7, 0xe2, 0xb4, 0xb3, 0x5d, 0x37, 0x13, 0x67, 0x44, 0xc7}}
+	return a, nil
+}
+
+var __1528395591_generalize_mail_reply_tokensUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xad\x53\xdd\x6e\x82\x30\x14\xbe\xe7\x29\x4e\xbc\x51\x13\xdd\x03\x48\x76\x81\xa3\x2e\x24\x50\x16\xac\x89\x77\xa4\x81\xe3\x68\x44\x30\xb4\x6e\xba\xa7\x5f\x5b\x64\x73\x51\xb7\xb9\xec\x0a\xca\x39\xdf\xcf\x39\x7c\x9d\x92\xc7\x80\xba\x8e\x33\x1e\x43\xc4\x45\x09\x0d\x6e\xcb\x03\xa8\x7a\x8d\x95\x04\xde\x20\x54\x35\x94\x75\xf5\x8c\x0d\xc8\x2d\x66\x62\x25\x32\x5d\x85\x5c\xc8\x6c\x27\xa5\xa8\x2b\x50\x45\x83\x3c\x97\x13\x40\x9e\x15\x2d\xd2\xb0\x15\x5c\xe3\x61\x2d\xaa\x1c\x06\x72\xa7\x2b\xfa\xdc\xfb\x84\x8d\x5b\x58\x0f\xea\x06\x7a\x92\xbf\x60\x3e\x96\xc8\x9b\xac\xe8\x0d\x35\x23\x57\x20\xb1\xc4\x4c\x49\x7d\xc0\x96\xae\xca\x4b\x6d\xa2\x5e\x59\x8b\x02\xe5\x08\xf4\x27\x2d\xa1\x78\xf3\x8c\xaa\x05\xe9\xe6\x8f\x4e\x6e\xd0\x75\x75\xe7\x78\x21\x23\x09\x30\x6f\x1a\x92\x13\xdf\xe9\x46\x8f\x9b\xda\x71\xd3\xe3\xb8\x09\xa1\x5e\x44\x80\xc5\x70\x56\x73\xbf\xb0\x5c\x85\x3e\xc4\x74\xce\x12\x2f\xa0\xec\x5b\xa5\x74\xbb\xc6\xc3\x45\x1d\x5b\xf9\x67\xb1\x9d\xc4\x26\x15\x79\xba\xba\x2a\x7a\xda\xe1\x3a\x7e\x12\x3f\x41\x40\x7d\xb2\x84\x60\x06\x64\x19\xcc\xd9\xfc\x7b\x05\xfb\xd0\x04\xfb\xbf\x80\x3b\xf1\x36\x10\xe6\xcd\x12\xfd\xb0\x03\xcf\xf7\xf5\x02\xc2\x45\x44\xdb\x8c\x29\xdc\x2b\xf7\xf7\x98\x2e\x34\xb7\xa1\x32\xed\x50\x61\x9e\x9a\xa4\x89\x0d\x4a\xc5\x37\x5b\x78\x15\xaa\xb0\x47\x78\xab\x2b\x04\x1a\x33\xa0\x8b\x30\x04\x9f\xcc\xbc\x45\xc8\xf4\x05\x7a\x1d\x0c\x5d\x67\xf1\xe4\x7b\xec\x12\xff\x9c\x30\x3b\xc2\x7d\xff\xec\x76\xf4\x47\x47\xa3\xf7\x1f\xcb\x99\x4c\x7e\xe5\xd9\x56\x4f\xf7\x63\x54\x3a\x6b\x37\xa1\x8f\x9b\xba\x05\x6f\x33\xd0\xc1\x3b\xe3\xfa\x8f\x3e\x24\xc4\xac\xa0\x4d\xc7\xf5\x20\x18\xbf\x69\x2b\x6b\xa2\x00\x31\x3d\x6f\x1e\x1c\x9b\x47\x76\xba\x6e\x4d\x43\x23\x12\x47\x51\xc0\x5c\xe7\x1d\x5c\x3d\x36\x42\xd9\x04\x00\x00")
+
+func _1528395591_generalize_mail_reply_tokensUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395591_generalize_mail_reply_tokensUpSql,
+		"1528395591_generalize_mail_reply_tokens.up.sql",
+	)
+}
+
+func _1528395591_generalize_mail_reply_tokensUpSql() (*asset, error) {
+	bytes, err := _1528395591_generalize_mail_reply_tokensUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395591_generalize_mail_reply_tokens.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x97, 0xca, 0x3, 0x1c, 0xde, 0x5f, 0x84, 0xd8, 0x3f, 0x7b, 0x87, 0x80, 0x1a, 0xc4, 0x66, 0xcf, 0x51, 0x9, 0x42, 0xaa, 0xc0, 0xb4, 0x6b, 0xb, 0x94, 0xf, 0xdb, 0xcb, 0xe9, 0x15, 0xb6, 0x6c}}
+	return a, nil
+}
+
+var __1528395592_add_saved_search_email_mutesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\x4e\x2c\x4b\x4d\x89\x2f\x4e\x4d\x2c\x4a\xce\x88\x4f\xcd\x4d\xcc\xcc\x89\xcf\x2d\x2d\x49\x2d\x06\xaa\x77\xf6\xf7\xf5\xf5\x0c\xb1\xe6\x02\x00\x3f\xb4\xbc\x1b\x40\x00\x00\x00")
+
+func _1528395592_add_saved_search_email_mutesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395592_add_saved_search_email_mutesDownSql,
+		"1528395592_add_saved_search_email_mutes.down.sql",
+	)
+}
+
+func _1528395592_add_saved_search_email_mutesDownSql() (*asset, error) {
+	bytes, err := _1528395592_add_saved_search_email_mutesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395592_add_saved_search_email_mutes.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xc, 0x71, 0xc2, 0xe4, 0xa1, 0x7c, 0x7, 0xd5, 0x41, 0xc9, 0x85, 0xe4, 0xd4, 0x71, 0xa5, 0x38, 0xd1, 0xc5, 0x31, 0x49, 0x91, 0xf9, 0x65, 0x4e, 0xdb, 0xf6, 0x55, 0x4e, 0xe0, 0x50, 0x9d, 0x6e}}
+	return a, nil
+}
+
+var __1528395592_add_saved_search_email_mutesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x90\xc1\x0e\x82\x30\x0c\x86\xef\x7b\x8a\x1e\x21\xf1\x0d\x38\x4d\xa8\x86\x38\x86\x19\xf3\xe0\x89\x2c\xd0\xe8\x12\x41\xc3\x86\x24\x3e\xbd\x02\x31\x46\x13\xb5\xb7\xa6\x5f\xff\xfe\xfd\x97\xb8\x4e\x65\xc4\x58\xac\x90\x6b\x04\xcd\x97\x02\xc1\x99\x2b\xd5\xa5\x23\xd3\x55\xc7\x92\x1a\x63\x4f\x65\xd3\x7b\x72\x10\x30\x78\xd4\xdb\xd8\xd6\x60\x5b\x4f\x07\xea\x40\xe6\x1a\xe4\x4e\x08\x50\xb8\x42\x85\x32\xc6\xe2\x8d\x25\x17\xd8\x3a\x84\x5c\x42\x82\x02\x1f\xd7\x62\x5e\xc4\x3c\xc1\xc5\xa4\xda\x3b\xea\xfe\xa9\x8d\xcc\x4f\x91\xd1\x66\x5d\xf6\xad\xb7\x27\xf0\xb6\x21\xe7\x4d\x73\x81\xc1\xfa\xe3\xd4\xc2\xed\xdc\xd2\x4c\x56\x1d\x99\x91\x35\xfe\x2b\xf8\xb2\x90\xe0\x8a\xef\x84\x86\xf6\x3c\x04\xe1\xbc\xbf\x55\x69\xc6\xd5\x1e\x36\xb8\x87\xe0\x23\x91\xc5\xf3\x99\x90\x85\x63\xb6\x79\x96\xa5\x3a\x62\x77\xe1\x16\x96\x70\x6c\x01\x00\x00")
+
+func _1528395592_add_saved_search_email_mutesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395592_add_saved_search_email_mutesUpSql,
+		"1528395592_add_saved_search_email_mutes.up.sql",
+	)
+}
+
+func _1528395592_add_saved_search_email_mutesUpSql() (*asset, error) {
+	bytes, err := _1528395592_add_saved_search_email_mutesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395592_add_saved_search_email_mutes.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xed, 0xeb, 0xc0, 0x7f, 0xa0, 0x22, 0xbc, 0xce, 0x41, 0x45, 0x2d, 0xc5, 0xb7, 0xcd, 0x58, 0x1b, 0xd4, 0x44, 0xb6, 0xe4, 0xc, 0xce, 0x23, 0x21, 0xd5, 0x90, 0x7e, 0x5e, 0x7b, 0xb0, 0x17, 0x60}}
+	return a, nil
+}
+
+var __1528395593_add_event_logs_tableDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\x2d\x4b\xcd\x2b\x89\xcf\xc9\x4f\x2f\x06\xaa\x70\xf6\xf7\xf5\xf5\x0c\xb1\xe6\x02\x00\xfd\xdb\xa4\x84\x32\x00\x00\x00")
+
+func _1528395593_add_event_logs_tableDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395593_add_event_logs_tableDownSql,
+		"1528395593_add_event_logs_table.down.sql",
+	)
+}
+
+func _1528395593_add_event_logs_tableDownSql() (*asset, error) {
+	bytes, err := _1528395593_add_event_logs_tableDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395593_add_event_logs_table.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x7c, 0x55, 0xd, 0xe0, 0x3a, 0x6, 0x9, 0x6e, 0xf6, 0x44, 0x4b, 0x3a, 0x6, 0x1f, 0x74, 0x63, 0x63, 0x1b, 0x18, 0xd9, 0x2b, 0xa7, 0x33, 0x6c, 0x25, 0x98, 0x50, 0xa, 0x95, 0x57, 0xcc, 0xd2}}
+	return a, nil
+}
+
+var __1528395593_add_event_logs_tableUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x91\xdf\x4e\xc2\x30\x14\xc6\xef\xf7\x14\x27\xdc\x30\x12\xdf\x00\x63\x32\x46\xd1\x85\xad\x33\xb3\x24\x72\xd5\x0c\x6c\x46\x95\xb6\x4b\xdb\x89\xf3\xe9\x1d\x2d\x7f\x86\xa2\xb1\x57\xed\x77\xfa\xfd\x72\xbe\x73\x26\xe8\x3e\xc1\xe3\x20\x88\x0b\x14\x11\x04\x24\x9a\xa4\x08\xd8\x3b\x93\x96\x6e\x55\x65\x20\x0c\xa0\x3b\xfc\x05\x56\xbc\x32\x4c\xf3\x72\x0b\x8f\x45\x92\x45\xc5\x12\xe6\x68\x79\xe3\xaa\xb2\x14\x0c\x2c\xfb\xb0\x80\x73\x02\x78\x91\xa6\x5e\x6f\x3a\x03\xed\xac\x5c\x5a\x56\x31\xfd\xad\x5a\x4a\x25\x5b\xa1\x1a\x43\x8f\xff\xae\x20\x8c\x6a\xf4\xfa\x2a\xbc\xd4\x55\x23\xba\x36\xe1\xd5\x28\xb9\xf2\x9a\x66\xb5\x32\xdc\x2a\xdd\x3a\x87\x17\x07\x96\x0b\x66\x6c\x29\xea\x01\x9c\xae\xb0\xe3\x76\xe3\x9e\xf0\xa9\x24\x3b\xb1\x61\x8a\x66\xd1\x22\x25\x20\xd5\x2e\x1c\x79\x40\x9c\xe3\x27\x52\x44\x09\x26\xbd\xc1\xd0\xf5\x86\xad\xdf\xe8\x3e\x3a\x95\xca\x52\x26\x6a\xdb\x42\xfc\x80\xe2\x39\x84\x6e\x20\xb7\x77\x30\x1c\xfe\x03\xe1\x23\xfe\x84\x1c\xa2\x7b\x4c\x30\x3a\xef\x28\xc1\x53\xf4\xdc\xe7\x9c\x53\xe5\xb8\xa7\x87\xbd\xe4\x9d\xfd\x37\xb7\x6b\xf6\xd2\xb8\x97\xfe\x70\x1c\xf7\x75\x69\x3a\xa8\xae\xd1\x3c\xcb\x12\x32\x0e\xbe\x00\x79\x27\xfe\x9b\x5d\x02\x00\x00")
+
+func _1528395593_add_event_logs_tableUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395593_add_event_logs_tableUpSql,
+		"1528395593_add_event_logs_table.up.sql",
+	)
+}
+
+func _1528395593_add_event_logs_tableUpSql() (*asset, error) {
+	bytes, err := _1528395593_add_event_logs_tableUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395593_add_event_logs_table.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xd8, 0x87, 0xbe, 0xdc, 0x36, 0x28, 0xbc, 0x8, 0x2f, 0x8, 0x51, 0xe4, 0x8, 0xab, 0xfe, 0xc3, 0xdf, 0x65, 0x81, 0xeb, 0x97, 0x82, 0x62, 0x2a, 0x78, 0x7f, 0xe5, 0xcb, 0x3, 0x2d, 0x4e, 0x70}}
+	return a, nil
+}
+
+var __1528395594_add_access_token_expires_atDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\x4c\x4e\x4e\x2d\x2e\x8e\x2f\xc9\xcf\x4e\xcd\x2b\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\xad\x28\xc8\x2c\x4a\x2d\x8e\x4f\x2c\x01\x6a\x76\xf6\xf7\xf5\xf5\x0c\xb1\xe6\x02\x00\xfa\xc7\x84\x27\x4d\x00\x00\x00")
+
+func _1528395594_add_access_token_expires_atDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395594_add_access_token_expires_atDownSql,
+		"1528395594_add_access_token_expires_at.down.sql",
+	)
+}
+
+func _1528395594_add_access_token_expires_atDownSql() (*asset, error) {
+	bytes, err := _1528395594_add_access_token_expires_atDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395594_add_access_token_expires_at.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xe9, 0xf4, 0xa, 0x25, 0x55, 0xaa, 0xae, 0x58, 0x3c, 0x51, 0x71, 0x39, 0x6b, 0x80, 0xd2, 0xe4, 0xa4, 0xa0, 0xf3, 0xca, 0xd3, 0x94, 0x7b, 0xf5, 0xb3, 0x32, 0xd6, 0x27, 0xad, 0x2a, 0x5c, 0x23}}
+	return a, nil
+}
+
+var __1528395594_add_access_token_expires_atUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\x4c\x4e\x4e\x2d\x2e\x8e\x2f\xc9\xcf\x4e\xcd\x2b\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x48\xad\x28\xc8\x2c\x4a\x2d\x8e\x4f\x2c\x51\x28\xc9\xcc\x4d\x2d\x2e\x49\xcc\x2d\x50\x28\xcf\x2c\xc9\x00\x73\x15\xaa\xf2\xf3\x52\x81\x46\x39\xfb\xfb\xfa\x7a\x86\x58\x73\x01\x00\x51\x00\x9b\x79\x5b\x00\x00\x00")
+
+func _1528395594_add_access_token_expires_atUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395594_add_access_token_expires_atUpSql,
+		"1528395594_add_access_token_expires_at.up.sql",
+	)
+}
+
+func _1528395594_add_access_token_expires_atUpSql() (*asset, error) {
+	bytes, err := _1528395594_add_access_token_expires_atUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395594_add_access_token_expires_at.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x54, 0x37, 0x2e, 0x84, 0x31, 0xab, 0x9f, 0x76, 0xde, 0xc1, 0x34, 0x2b, 0xae, 0xce, 0xda, 0x4d, 0x9c, 0xd5, 0x4, 0x47, 0x1d, 0x5d, 0x6e, 0xdd, 0xc3, 0xe5, 0xe, 0x32, 0x6d, 0x21, 0xe5, 0xdb}}
+	return a, nil
+}
+
+var __1528395595_add_access_token_audit_logsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x48\x4c\x4e\x4e\x2d\x2e\x8e\x2f\xc9\xcf\x4e\xcd\x8b\x4f\x2c\x4d\xc9\x2c\x89\xcf\xc9\x4f\x2f\xb6\xe6\x72\xf4\x09\x71\x0d\x82\x2a\x47\x56\x54\xac\x00\x36\xc7\xd9\xdf\x27\xd4\xd7\x0f\xc9\xa0\xd4\x8a\x82\xcc\xa2\xca\xf8\xbc\xfc\x92\xcc\xb4\xcc\xd4\x94\xf8\xc4\x12\xa0\x95\xce\xfe\xbe\xbe\x9e\x21\xd6\x5c\x00\x74\x97\x74\x04\x83\x00\x00\x00")
+
+func _1528395595_add_access_token_audit_logsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395595_add_access_token_audit_logsDownSql,
+		"1528395595_add_access_token_audit_logs.down.sql",
+	)
+}
+
+func _1528395595_add_access_token_audit_logsDownSql() (*asset, error) {
+	bytes, err := _1528395595_add_access_token_audit_logsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395595_add_access_token_audit_logs.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x81, 0xd7, 0x61, 0x19, 0xc, 0x94, 0x5a, 0xd5, 0xaf, 0x4d, 0xdd, 0x1c, 0xa6, 0x1e, 0xe4, 0x3b, 0x46, 0x3c, 0xf8, 0x62, 0xeb, 0x67, 0xe4, 0xa9, 0x18, 0x9, 0xcf, 0x17, 0xcc, 0xbe, 0x19, 0x83}}
+	return a, nil
+}
+
+var __1528395595_add_access_token_audit_logsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x90\x51\x6f\xc2\x20\x14\x85\xdf\xf9\x15\x37\x3e\xb5\x89\xff\xc0\x27\x84\xeb\xd2\x8c\xd2\x05\x69\x32\x9f\x48\x67\x99\x23\x53\x68\x84\x45\xb7\x5f\xbf\xce\x26\xeb\x6a\x66\x22\x6f\x70\x39\xe7\x7c\xe7\x2e\xf1\xa1\x90\x0b\x42\xa8\xd0\xa8\x40\xd3\xa5\x40\x68\xb6\x5b\x1b\xa3\x49\xe1\xdd\xfa\x08\x94\x73\x60\x95\xa8\x4b\x09\xf6\xdc\xb9\xe3\xa7\xf1\x21\xb9\x57\x67\x5b\xd3\x24\x48\xee\x60\x63\x6a\x0e\x1d\x9c\x5c\x7a\xbb\x5c\xe1\x2b\x78\xdb\x5b\x32\x85\x54\xe3\x3f\x9e\xa6\xf9\x68\x5d\x32\xfb\xb0\x8b\x90\x11\xe8\x8f\x6b\xe1\xc5\xed\xa2\x3d\xba\x66\x0f\x4f\xaa\x28\xa9\xda\xc0\x23\x6e\xe6\x97\xe9\x44\x3b\x7c\x75\x3e\x81\xac\x34\xc8\x5a\x08\x50\xb8\x42\x85\x92\xe1\x7a\x8a\x9e\xb9\x36\x87\x4a\x02\x47\x81\x3d\x08\xa3\x6b\x46\x39\x0e\x9e\xb3\x5f\xf0\xd9\xcd\x0e\x63\x02\xc7\x15\xad\x85\x06\x1f\x4e\x59\x3e\x18\xb8\x0e\x92\x3d\x8f\x14\xc3\xab\xf5\x6d\x17\x7e\xe0\x26\x33\x92\x8f\xeb\x28\x24\xc7\xe7\x5b\xeb\x30\x57\x55\xcd\x88\xd6\xf7\xb8\x21\xca\xae\x44\xf3\xbf\xe5\xfa\xe4\xbb\x82\xef\x0a\x9a\xfa\x12\x56\x95\x65\xa1\x17\xe4\x1b\x47\x55\x9b\xcb\x44\x02\x00\x00")
+
+func _1528395595_add_access_token_audit_logsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395595_add_access_token_audit_logsUpSql,
+		"1528395595_add_access_token_audit_logs.up.sql",
+	)
+}
+
+func _1528395595_add_access_token_audit_logsUpSql() (*asset, error) {
+	bytes, err := _1528395595_add_access_token_audit_logsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395595_add_access_token_audit_logs.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x62, 0x91, 0x7d, 0x68, 0xa6, 0x3b, 0x1f, 0x1b, 0xdc, 0xf1, 0x27, 0x47, 0x9b, 0x18, 0xca, 0x98, 0x75, 0x64, 0x58, 0xde, 0x2a, 0x78, 0x63, 0x60, 0x79, 0xd9, 0x2d, 0xa8, 0xea, 0xbd, 0x42, 0xb1}}
+	return a, nil
+}
+
+var __1528395596_add_repo_pinnedDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x72\x75\xf7\xf4\xb3\xe6\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x4a\x2d\xc8\x57\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\xf0\x74\x53\x70\x8d\xf0\x0c\x0e\x09\x56\x28\xc8\xcc\xcb\x4b\x4d\x01\xaa\x77\xf6\xf7\xf5\xf5\x0c\xb1\xe6\x02\x00\x7a\x60\xe9\xc6\x40\x00\x00\x00")
+
+func _1528395596_add_repo_pinnedDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395596_add_repo_pinnedDownSql,
+		"1528395596_add_repo_pinned.down.sql",
+	)
+}
+
+func _1528395596_add_repo_pinnedDownSql() (*asset, error) {
+	bytes, err := _1528395596_add_repo_pinnedDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395596_add_repo_pinned.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xab, 0x5c, 0xeb, 0xe2, 0x48, 0x2f, 0xb3, 0x1a, 0x02, 0x18, 0xab, 0x2b, 0x5c, 0xaf, 0xf3, 0x90, 0xd4, 0x6e, 0x72, 0xb5, 0x0c, 0x10, 0xd9, 0xe5, 0x44, 0xb3, 0x30, 0xca, 0xb8, 0x74, 0x75, 0x6a}}
+	return a, nil
+}
+
+var __1528395596_add_repo_pinnedUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x0d\xcc\x5b\x0a\x80\x20\x10\x05\xd0\x7f\x57\x71\xf7\xd1\x97\xaf\x42\x18\x47\x88\x71\x01\x46\x06\x81\xa8\xd4\xfe\xa1\xce\x02\x8e\xf1\x5b\xe0\x45\x29\x4d\xe2\x77\x88\x36\xe4\xf1\xd4\x39\xa0\x9d\x83\x4d\x94\x23\x63\xde\xbd\xd7\x13\xc7\x18\xad\x96\x0e\x4e\x02\xce\x44\x70\x7e\xd5\x99\x04\x57\x69\x6f\xfd\x0f\x9b\x62\x0c\xb2\xa8\x0f\x7a\xd6\xda\xdb\x54\x00\x00\x00")
+
+func _1528395596_add_repo_pinnedUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395596_add_repo_pinnedUpSql,
+		"1528395596_add_repo_pinned.up.sql",
+	)
+}
+
+func _1528395596_add_repo_pinnedUpSql() (*asset, error) {
+	bytes, err := _1528395596_add_repo_pinnedUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395596_add_repo_pinned.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x74, 0x8a, 0xc8, 0x4e, 0x7c, 0xe8, 0x7d, 0x0e, 0x89, 0x05, 0x9a, 0x6a, 0xa5, 0x05, 0xf0, 0xca, 0xc3, 0x79, 0x03, 0x8e, 0x38, 0xac, 0x44, 0x38, 0xfb, 0xe6, 0xfd, 0x84, 0xae, 0x3d, 0x62, 0x28}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -5471,6 +5975,54 @@ var _bindata = map[string]func() (*asset, error){
 	"1528395584_add_campaigns_table.down.sql": _1528395584_add_campaigns_tableDownSql,
 
 	"1528395584_add_campaigns_table.up.sql": _1528395584_add_campaigns_tableUpSql,
+
+	"1528395585_add_changesets_table.down.sql": _1528395585_add_changesets_tableDownSql,
+
+	"1528395585_add_changesets_table.up.sql": _1528395585_add_changesets_tableUpSql,
+
+	"1528395586_add_campaign_plans.down.sql": _1528395586_add_campaign_plansDownSql,
+
+	"1528395586_add_campaign_plans.up.sql": _1528395586_add_campaign_plansUpSql,
+
+	"1528395587_add_saved_search_webhooks.down.sql": _1528395587_add_saved_search_webhooksDownSql,
+
+	"1528395587_add_saved_search_webhooks.up.sql": _1528395587_add_saved_search_webhooksUpSql,
+
+	"1528395588_add_query_runner_result_fingerprints.down.sql": _1528395588_add_query_runner_result_fingerprintsDownSql,
+
+	"1528395588_add_query_runner_result_fingerprints.up.sql": _1528395588_add_query_runner_result_fingerprintsUpSql,
+
+	"1528395589_add_discussion_comments_external_id.down.sql": _1528395589_add_discussion_comments_external_idDownSql,
+
+	"1528395589_add_discussion_comments_external_id.up.sql": _1528395589_add_discussion_comments_external_idUpSql,
+
+	"1528395590_add_discussions_fulltext_indexes.down.sql": _1528395590_add_discussions_fulltext_indexesDownSql,
+
+	"1528395590_add_discussions_fulltext_indexes.up.sql": _1528395590_add_discussions_fulltext_indexesUpSql,
+
+	"1528395591_generalize_mail_reply_tokens.down.sql": _1528395591_generalize_mail_reply_tokensDownSql,
+
+	"1528395591_generalize_mail_reply_tokens.up.sql": _1528395591_generalize_mail_reply_tokensUpSql,
+
+	"1528395592_add_saved_search_email_mutes.down.sql": _1528395592_add_saved_search_email_mutesDownSql,
+
+	"1528395592_add_saved_search_email_mutes.up.sql": _1528395592_add_saved_search_email_mutesUpSql,
+
+	"1528395593_add_event_logs_table.down.sql": _1528395593_add_event_logs_tableDownSql,
+
+	"1528395593_add_event_logs_table.up.sql": _1528395593_add_event_logs_tableUpSql,
+
+	"1528395594_add_access_token_expires_at.down.sql": _1528395594_add_access_token_expires_atDownSql,
+
+	"1528395594_add_access_token_expires_at.up.sql": _1528395594_add_access_token_expires_atUpSql,
+
+	"1528395595_add_access_token_audit_logs.down.sql": _1528395595_add_access_token_audit_logsDownSql,
+
+	"1528395595_add_access_token_audit_logs.up.sql": _1528395595_add_access_token_audit_logsUpSql,
+
+	"1528395596_add_repo_pinned.down.sql": _1528395596_add_repo_pinnedDownSql,
+
+	"1528395596_add_repo_pinned.up.sql": _1528395596_add_repo_pinnedUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -5745,6 +6297,30 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"1528395583_add_default_repos_primary_key.up.sql":             {_1528395583_add_default_repos_primary_keyUpSql, map[string]*bintree{}},
 	"1528395584_add_campaigns_table.down.sql":                     {_1528395584_add_campaigns_tableDownSql, map[string]*bintree{}},
 	"1528395584_add_campaigns_table.up.sql":                       {_1528395584_add_campaigns_tableUpSql, map[string]*bintree{}},
+	"1528395585_add_changesets_table.down.sql":                    {_1528395585_add_changesets_tableDownSql, map[string]*bintree{}},
+	"1528395585_add_changesets_table.up.sql":                      {_1528395585_add_changesets_tableUpSql, map[string]*bintree{}},
+	"1528395586_add_campaign_plans.down.sql":                      {_1528395586_add_campaign_plansDownSql, map[string]*bintree{}},
+	"1528395586_add_campaign_plans.up.sql":                        {_1528395586_add_campaign_plansUpSql, map[string]*bintree{}},
+	"1528395587_add_saved_search_webhooks.down.sql":               {_1528395587_add_saved_search_webhooksDownSql, map[string]*bintree{}},
+	"1528395587_add_saved_search_webhooks.up.sql":                 {_1528395587_add_saved_search_webhooksUpSql, map[string]*bintree{}},
+	"1528395588_add_query_runner_result_fingerprints.down.sql":    {_1528395588_add_query_runner_result_fingerprintsDownSql, map[string]*bintree{}},
+	"1528395588_add_query_runner_result_fingerprints.up.sql":      {_1528395588_add_query_runner_result_fingerprintsUpSql, map[string]*bintree{}},
+	"1528395589_add_discussion_comments_external_id.down.sql":     {_1528395589_add_discussion_comments_external_idDownSql, map[string]*bintree{}},
+	"1528395589_add_discussion_comments_external_id.up.sql":       {_1528395589_add_discussion_comments_external_idUpSql, map[string]*bintree{}},
+	"1528395590_add_discussions_fulltext_indexes.down.sql":        {_1528395590_add_discussions_fulltext_indexesDownSql, map[string]*bintree{}},
+	"1528395590_add_discussions_fulltext_indexes.up.sql":          {_1528395590_add_discussions_fulltext_indexesUpSql, map[string]*bintree{}},
+	"1528395591_generalize_mail_reply_tokens.down.sql":            {_1528395591_generalize_mail_reply_tokensDownSql, map[string]*bintree{}},
+	"1528395591_generalize_mail_reply_tokens.up.sql":              {_1528395591_generalize_mail_reply_tokensUpSql, map[string]*bintree{}},
+	"1528395592_add_saved_search_email_mutes.down.sql":            {_1528395592_add_saved_search_email_mutesDownSql, map[string]*bintree{}},
+	"1528395592_add_saved_search_email_mutes.up.sql":              {_1528395592_add_saved_search_email_mutesUpSql, map[string]*bintree{}},
+	"1528395593_add_event_logs_table.down.sql":                    {_1528395593_add_event_logs_tableDownSql, map[string]*bintree{}},
+	"1528395593_add_event_logs_table.up.sql":                      {_1528395593_add_event_logs_tableUpSql, map[string]*bintree{}},
+	"1528395594_add_access_token_expires_at.down.sql":             {_1528395594_add_access_token_expires_atDownSql, map[string]*bintree{}},
+	"1528395594_add_access_token_expires_at.up.sql":               {_1528395594_add_access_token_expires_atUpSql, map[string]*bintree{}},
+	"1528395595_add_access_token_audit_logs.down.sql":             {_1528395595_add_access_token_audit_logsDownSql, map[string]*bintree{}},
+	"1528395595_add_access_token_audit_logs.up.sql":               {_1528395595_add_access_token_audit_logsUpSql, map[string]*bintree{}},
+	"1528395596_add_repo_pinned.down.sql":                         {_1528395596_add_repo_pinnedDownSql, map[string]*bintree{}},
+	"1528395596_add_repo_pinned.up.sql":                           {_1528395596_add_repo_pinnedUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory.
@@ -86,6 +86,32 @@ type ConfigSavedQuery struct {
 	UserID          *int32  `json:"userID"`
 	OrgID           *int32  `json:"orgID"`
 	SlackWebhookURL *string `json:"slackWebhookURL"`
+
+	// Webhook is a pointer so that ConfigSavedQuery remains comparable (it
+	// is used in map keys).
+	Webhook *SavedQueryWebhook `json:"webhook,omitempty"`
+}
+
+// SavedQueryWebhook is a generic webhook that is notified about new results
+// for a saved query.
+type SavedQueryWebhook struct {
+	URL     string            `json:"url"`
+	Secret  string            `json:"secret,omitempty"`  // used to sign the payloads sent to URL
+	Headers map[string]string `json:"headers,omitempty"` // additional HTTP headers sent to URL
+}
+
+// IsReservedWebhookHeader reports whether name is an HTTP header that may not
+// be set in SavedQueryWebhook.Headers, because it is set by the query-runner or
+// it affects how the request is routed or framed (such as Host and the
+// hop-by-hop headers).
+func IsReservedWebhookHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Host", "Content-Length", "Content-Type",
+		"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+		"X-Sourcegraph-Event", "X-Sourcegraph-Signature":
+		return true
+	}
+	return false
 }
 
 func (sq ConfigSavedQuery) Equals(other ConfigSavedQuery) bool {
@@ -136,6 +162,11 @@ type SavedQueryInfo struct {
 
 	// ExecDuration is the amount of time it took for the query to execute.
 	ExecDuration time.Duration
+
+	// ResultFingerprints identifies the results found by the last execution
+	// of the search query (see the query-runner for their format). It is nil
+	// if they were never recorded.
+	ResultFingerprints []string
 }
 
 // SavedQueriesGetInfo gets the info from the DB for the given saved query. nil
@@ -166,6 +197,21 @@ func (c *internalClient) SettingsGetForSubject(ctx context.Context, subject Sett
 	return parsed, settings, err
 }
 
+var MockSavedQueriesMutedUsers func(key string) (userIDs []int32, err error)
+
+// SavedQueriesMutedUsers returns the IDs of the users who currently muted
+// email notifications about the saved query with the given key.
+func (c *internalClient) SavedQueriesMutedUsers(ctx context.Context, key string) (userIDs []int32, err error) {
+	if MockSavedQueriesMutedUsers != nil {
+		return MockSavedQueriesMutedUsers(key)
+	}
+	err = c.postInternal(ctx, "saved-queries/muted-users", key, &userIDs)
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
 var MockOrgsListUsers func(orgID int32) (users []int32, err error)
 
 func (c *internalClient) OrgsListUsers(ctx context.Context, orgID int32) (users []int32, err error) {
@@ -231,6 +277,28 @@ func (c *internalClient) SendEmail(ctx context.Context, message txtypes.Message)
 	return c.postInternal(ctx, "send-email", &message, nil)
 }
 
+// MailReplyToArgs are the arguments of MailReplyTo.
+type MailReplyToArgs struct {
+	UserID int32
+	Kind   string
+	Target string
+}
+
+// MailReplyTo returns the reply-to address of an email notification of the
+// given kind (such as "saved-search") sent to the user, so that the frontend
+// acts on replies to it. It returns nil if the frontend can't read email
+// replies.
+//
+// 🚨 SECURITY: The caller must ensure the address is ONLY sent to the given
+// user.
+func (c *internalClient) MailReplyTo(ctx context.Context, userID int32, kind, target string) (replyTo *string, err error) {
+	err = c.postInternal(ctx, "mail-reply-to", &MailReplyToArgs{UserID: userID, Kind: kind, Target: target}, &replyTo)
+	if err != nil {
+		return nil, err
+	}
+	return replyTo, nil
+}
+
 func (c *internalClient) ReposCreateIfNotExists(ctx context.Context, op RepoCreateOrUpdateRequest) (*Repo, error) {
 	var repo Repo
 	err := c.postInternal(ctx, "repos/create-if-not-exists", op, &repo)
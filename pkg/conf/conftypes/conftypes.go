@@ -11,6 +11,15 @@ type ServiceConnections struct {
 	// to.
 	GitServers []string `json:"gitServers"`
 
+	// GitServerReplicationFactor is the number of gitserver instances each
+	// repository is cloned on. Values less than 1 are treated as 1.
+	GitServerReplicationFactor int `json:"gitServerReplicationFactor"`
+
+	// GitServerHashRing is whether repositories are assigned to gitserver
+	// instances with a consistent hash ring (see gitserver.RingReplicaAddrs)
+	// rather than by hashing modulo the number of instances.
+	GitServerHashRing bool `json:"gitServerHashRing"`
+
 	// PostgresDSN is the PostgreSQL DB data source name.
 	// eg: "postgres://sg@pgsql/sourcegraph?sslmode=false"
 	PostgresDSN string `json:"postgresDSN"`
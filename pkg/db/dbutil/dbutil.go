@@ -219,3 +219,31 @@ func (n NullInt32) Value() (driver.Value, error) {
 	}
 	return *n.N, nil
 }
+
+// NullInt64 represents an int64 that may be null. NullInt64 implements the
+// sql.Scanner interface so it can be used as a scan destination, similar to
+// sql.NullString. When the scanned value is null, int64 is set to the zero value.
+type NullInt64 struct{ N *int64 }
+
+// Scan implements the Scanner interface.
+func (n *NullInt64) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case int64:
+		*n.N = value
+	case int32:
+		*n.N = int64(value)
+	case nil:
+		return nil
+	default:
+		return fmt.Errorf("value is not int64: %T", value)
+	}
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n NullInt64) Value() (driver.Value, error) {
+	if n.N == nil {
+		return nil, nil
+	}
+	return *n.N, nil
+}
@@ -19,8 +19,9 @@ var backendEventsTrackingSiteID = "SourcegraphBackend"
 // defaultLogger is a singleton for event logging from the backend
 var defaultLogger = new()
 
-// LogEvent sends a payload representing an event to the api/telemetry endpoint. This
-// endpoint only functions on Sourcegraph.com, not on self-hosted instances.
+// LogEvent sends a payload representing an event to the api/telemetry endpoint. On
+// self-hosted instances, the endpoint records the event in the site's event log (the
+// event_logs table) instead of forwarding it.
 //
 // This method should be invoked after the frontend service has started. It is
 // safe to not do so (it will just log an error), but logging the actual event
@@ -366,6 +366,99 @@ func (c *Client) CreateProject(ctx context.Context, p *Project) error {
 	return c.send(ctx, "POST", "rest/api/1.0/projects", nil, p, p)
 }
 
+// LoadPullRequest loads the given PullRequest returning an error in case of failure.
+// The ID field and the Project.Key and Slug fields of the ToRef.Repository field must be set.
+func (c *Client) LoadPullRequest(ctx context.Context, pr *PullRequest) error {
+	if pr.ToRef.Repository.Slug == "" || pr.ToRef.Repository.Project == nil || pr.ToRef.Repository.Project.Key == "" {
+		return errors.New("pull request repository not set")
+	}
+	path := fmt.Sprintf(
+		"rest/api/1.0/projects/%s/repos/%s/pull-requests/%d",
+		pr.ToRef.Repository.Project.Key,
+		pr.ToRef.Repository.Slug,
+		pr.ID,
+	)
+	return c.send(ctx, "GET", path, nil, nil, pr)
+}
+
+// CreatePullRequest opens the given PullRequest, which proposes to merge its
+// FromRef into its ToRef, and loads it. The Title and Description fields, the
+// ID fields of FromRef and ToRef, and the Project.Key and Slug fields of the
+// ToRef.Repository field must be set. The FromRef must be in the same
+// repository.
+func (c *Client) CreatePullRequest(ctx context.Context, pr *PullRequest) error {
+	if pr.ToRef.Repository.Slug == "" || pr.ToRef.Repository.Project == nil || pr.ToRef.Repository.Project.Key == "" {
+		return errors.New("pull request repository not set")
+	}
+	if pr.FromRef.ID == "" || pr.ToRef.ID == "" {
+		return errors.New("pull request refs not set")
+	}
+
+	type ref struct {
+		ID         string `json:"id"`
+		Repository struct {
+			Slug    string `json:"slug"`
+			Project struct {
+				Key string `json:"key"`
+			} `json:"project"`
+		} `json:"repository"`
+	}
+	payload := struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		FromRef     ref    `json:"fromRef"`
+		ToRef       ref    `json:"toRef"`
+	}{Title: pr.Title, Description: pr.Description}
+	for _, r := range []*ref{&payload.FromRef, &payload.ToRef} {
+		r.Repository.Slug = pr.ToRef.Repository.Slug
+		r.Repository.Project.Key = pr.ToRef.Repository.Project.Key
+	}
+	payload.FromRef.ID = pr.FromRef.ID
+	payload.ToRef.ID = pr.ToRef.ID
+
+	path := fmt.Sprintf(
+		"rest/api/1.0/projects/%s/repos/%s/pull-requests",
+		pr.ToRef.Repository.Project.Key,
+		pr.ToRef.Repository.Slug,
+	)
+	return c.send(ctx, "POST", path, nil, &payload, pr)
+}
+
+// PullRequests returns the pull requests of the given repository that are in
+// the given state (OPEN, DECLINED, MERGED or ALL), most recently updated first.
+func (c *Client) PullRequests(ctx context.Context, projectKey, repoSlug, state string, pageToken *PageToken) ([]*PullRequest, *PageToken, error) {
+	path := fmt.Sprintf("rest/api/1.0/projects/%s/repos/%s/pull-requests", projectKey, repoSlug)
+	qry := url.Values{"state": {state}, "order": {"NEWEST"}}
+	var prs []*PullRequest
+	next, err := c.page(ctx, path, qry, pageToken, &prs)
+	return prs, next, err
+}
+
+// PullRequestActivities returns the activities (such as comments) of the given
+// pull request, most recent first. The ID field and the Project.Key and Slug
+// fields of the ToRef.Repository field must be set.
+func (c *Client) PullRequestActivities(ctx context.Context, pr *PullRequest, pageToken *PageToken) ([]*Activity, *PageToken, error) {
+	if pr.ToRef.Repository.Slug == "" || pr.ToRef.Repository.Project == nil || pr.ToRef.Repository.Project.Key == "" {
+		return nil, pageToken, errors.New("pull request repository not set")
+	}
+	path := fmt.Sprintf(
+		"rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/activities",
+		pr.ToRef.Repository.Project.Key,
+		pr.ToRef.Repository.Slug,
+		pr.ID,
+	)
+	var activities []*Activity
+	next, err := c.page(ctx, path, nil, pageToken, &activities)
+	return activities, next, err
+}
+
+// CommitBuildStatuses returns the build statuses reported for the given commit.
+func (c *Client) CommitBuildStatuses(ctx context.Context, commit string, pageToken *PageToken) ([]*BuildStatus, *PageToken, error) {
+	var statuses []*BuildStatus
+	next, err := c.page(ctx, "rest/build-status/1.0/commits/"+commit, nil, pageToken, &statuses)
+	return statuses, next, err
+}
+
 func (c *Client) Repo(ctx context.Context, projectKey, repoSlug string) (*Repo, error) {
 	u := fmt.Sprintf("rest/api/1.0/projects/%s/repos/%s", projectKey, repoSlug)
 	req, err := http.NewRequest("GET", u, nil)
@@ -714,6 +807,89 @@ type Project struct {
 	} `json:"links"`
 }
 
+// PullRequest is a Bitbucket Server pull request.
+type PullRequest struct {
+	ID          int        `json:"id"`
+	Version     int        `json:"version"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"` // OPEN, DECLINED, or MERGED
+	Open        bool       `json:"open"`
+	Closed      bool       `json:"closed"`
+	CreatedDate int64      `json:"createdDate"` // milliseconds since the Unix epoch
+	UpdatedDate int64      `json:"updatedDate"` // milliseconds since the Unix epoch
+	ClosedDate  int64      `json:"closedDate"`  // milliseconds since the Unix epoch; not set by Bitbucket Server versions older than 5.10
+	FromRef     Ref        `json:"fromRef"`
+	ToRef       Ref        `json:"toRef"`
+	Author      Reviewer   `json:"author"`
+	Reviewers   []Reviewer `json:"reviewers"`
+	Links       struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+// Ref is a Git ref (such as a branch) of a pull request.
+type Ref struct {
+	ID           string `json:"id"`
+	DisplayID    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit"`
+	Repository   Repo   `json:"repository"`
+}
+
+// Reviewer is a participant in a pull request.
+type Reviewer struct {
+	User     *User  `json:"user"`
+	Role     string `json:"role"` // AUTHOR, REVIEWER, or PARTICIPANT
+	Approved bool   `json:"approved"`
+	Status   string `json:"status"` // APPROVED, NEEDS_WORK, or UNAPPROVED
+}
+
+// Activity is an event in a pull request, such as a comment or an approval.
+type Activity struct {
+	ID            int            `json:"id"`
+	CreatedDate   int64          `json:"createdDate"` // milliseconds since the Unix epoch
+	User          User           `json:"user"`
+	Action        string         `json:"action"`        // such as COMMENTED, APPROVED or MERGED
+	CommentAction string         `json:"commentAction"` // ADDED, EDITED, REPLIED or DELETED; only set for COMMENTED activities
+	Comment       *Comment       `json:"comment"`
+	CommentAnchor *CommentAnchor `json:"commentAnchor"` // only set for comments on the diff
+}
+
+// Comment is a comment on a pull request.
+type Comment struct {
+	ID          int        `json:"id"`
+	Version     int        `json:"version"`
+	Text        string     `json:"text"`
+	Author      User       `json:"author"`
+	CreatedDate int64      `json:"createdDate"` // milliseconds since the Unix epoch
+	UpdatedDate int64      `json:"updatedDate"` // milliseconds since the Unix epoch
+	Comments    []*Comment `json:"comments"`    // replies
+}
+
+// CommentAnchor is the location in the diff of a pull request that a comment
+// is on.
+type CommentAnchor struct {
+	FromHash string `json:"fromHash"` // commit of the pull request's source branch (the new version)
+	ToHash   string `json:"toHash"`   // commit of the pull request's target branch (the old version)
+	Line     int    `json:"line"`     // 1-based; zero for comments on a whole file
+	LineType string `json:"lineType"` // ADDED, REMOVED or CONTEXT
+	FileType string `json:"fileType"` // FROM if Line is in the old version of the file, TO if it is in the new version
+	Path     string `json:"path"`
+	SrcPath  string `json:"srcPath"` // the path of the old version of the file if it was renamed
+}
+
+// BuildStatus is the status of a build of a commit reported by a CI server.
+type BuildStatus struct {
+	State       string `json:"state"` // SUCCESSFUL, FAILED, or INPROGRESS
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	DateAdded   int64  `json:"dateAdded"` // milliseconds since the Unix epoch
+}
+
 // IsNotFound reports whether err is a Bitbucket Server API not found error.
 func IsNotFound(err error) bool {
 	switch e := errors.Cause(err).(type) {
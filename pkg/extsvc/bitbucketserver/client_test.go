@@ -2,8 +2,11 @@ package bitbucketserver
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
@@ -292,3 +295,202 @@ func TestClient_Users(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_LoadPullRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/1.0/projects/SOUR/repos/vegeta/pull-requests/2":
+			fmt.Fprint(w, `{
+				"id": 2,
+				"version": 3,
+				"title": "Upgrade ES-Lint",
+				"state": "MERGED",
+				"open": false,
+				"closed": true,
+				"createdDate": 1567332000000,
+				"updatedDate": 1567504800000,
+				"closedDate": 1567504800000,
+				"fromRef": {"id": "refs/heads/eslint", "displayId": "eslint", "latestCommit": "deadbeef"},
+				"reviewers": [{"user": {"name": "alice"}, "role": "REVIEWER", "approved": true, "status": "APPROVED"}],
+				"links": {"self": [{"href": "https://bitbucket.example.com/projects/SOUR/repos/vegeta/pull-requests/2"}]}
+			}`)
+		case "/rest/build-status/1.0/commits/deadbeef":
+			fmt.Fprint(w, `{"size": 1, "limit": 25, "isLastPage": true, "start": 0, "values": [
+				{"state": "SUCCESSFUL", "key": "ci", "name": "CI", "url": "https://ci.example.com/1", "dateAdded": 1567504800000}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewClient(u, nil)
+	ctx := context.Background()
+
+	pr := &PullRequest{ID: 2}
+	if err := cli.LoadPullRequest(ctx, pr); err == nil {
+		t.Error("loading a pull request without a repository succeeded")
+	}
+
+	pr.ToRef.Repository.Slug = "vegeta"
+	pr.ToRef.Repository.Project = &Project{Key: "SOUR"}
+	if err := cli.LoadPullRequest(ctx, pr); err != nil {
+		t.Fatal(err)
+	}
+	if pr.Title != "Upgrade ES-Lint" || pr.State != "MERGED" || pr.ClosedDate != 1567504800000 || pr.FromRef.LatestCommit != "deadbeef" {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+	if len(pr.Reviewers) != 1 || pr.Reviewers[0].Status != "APPROVED" {
+		t.Errorf("unexpected reviewers %+v", pr.Reviewers)
+	}
+
+	statuses, next, err := cli.CommitBuildStatuses(ctx, pr.FromRef.LatestCommit, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.HasMore() {
+		t.Error("have more build statuses, want none")
+	}
+	want := []*BuildStatus{{State: "SUCCESSFUL", Key: "ci", Name: "CI", URL: "https://ci.example.com/1", DateAdded: 1567504800000}}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Error(cmp.Diff(statuses, want))
+	}
+
+	missing := &PullRequest{ID: 3}
+	missing.ToRef.Repository = pr.ToRef.Repository
+	if err := cli.LoadPullRequest(ctx, missing); !IsNotFound(err) {
+		t.Errorf("have error %v, want a not found error", err)
+	}
+}
+
+func TestClient_CreatePullRequest(t *testing.T) {
+	var payload map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/rest/api/1.0/projects/SOUR/repos/vegeta/pull-requests" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{
+			"id": 4,
+			"title": "Campaign",
+			"state": "OPEN",
+			"open": true,
+			"fromRef": {"id": "refs/heads/sourcegraph/campaign-1", "latestCommit": "deadbeef"},
+			"toRef": {"id": "refs/heads/master"}
+		}`)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewClient(u, nil)
+
+	pr := &PullRequest{Title: "Campaign", Description: "Upgrade all the things"}
+	pr.FromRef.ID = "refs/heads/sourcegraph/campaign-1"
+	pr.ToRef.ID = "refs/heads/master"
+	pr.ToRef.Repository.Slug = "vegeta"
+	pr.ToRef.Repository.Project = &Project{Key: "SOUR"}
+	if err := cli.CreatePullRequest(context.Background(), pr); err != nil {
+		t.Fatal(err)
+	}
+	if pr.ID != 4 || pr.State != "OPEN" {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+
+	fromRef, _ := payload["fromRef"].(map[string]interface{})
+	if payload["title"] != "Campaign" || payload["description"] != "Upgrade all the things" || fromRef["id"] != "refs/heads/sourcegraph/campaign-1" {
+		t.Errorf("unexpected payload %v", payload)
+	}
+}
+
+func TestClient_PullRequestActivities(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/1.0/projects/SOUR/repos/vegeta/pull-requests":
+			if state := r.URL.Query().Get("state"); state != "ALL" {
+				t.Errorf("got state %q, want ALL", state)
+			}
+			fmt.Fprint(w, `{"size": 1, "limit": 25, "isLastPage": true, "start": 0, "values": [
+				{"id": 2, "title": "Upgrade ES-Lint", "state": "OPEN", "updatedDate": 1567504800000}
+			]}`)
+		case "/rest/api/1.0/projects/SOUR/repos/vegeta/pull-requests/2/activities":
+			fmt.Fprint(w, `{"size": 2, "limit": 25, "isLastPage": true, "start": 0, "values": [
+				{"id": 5, "createdDate": 1567504800000, "user": {"name": "bob"}, "action": "APPROVED"},
+				{
+					"id": 4,
+					"createdDate": 1567332000000,
+					"user": {"name": "alice"},
+					"action": "COMMENTED",
+					"commentAction": "ADDED",
+					"comment": {
+						"id": 7,
+						"text": "Typo",
+						"author": {"name": "alice"},
+						"createdDate": 1567332000000,
+						"updatedDate": 1567332000000,
+						"comments": [{"id": 8, "text": "Fixed", "author": {"name": "bob"}}]
+					},
+					"commentAnchor": {"fromHash": "deadbeef", "toHash": "cafebabe", "line": 3, "lineType": "ADDED", "fileType": "TO", "path": "README.md"}
+				}
+			]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := NewClient(u, nil)
+	ctx := context.Background()
+
+	prs, next, err := cli.PullRequests(ctx, "SOUR", "vegeta", "ALL", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.HasMore() {
+		t.Error("have more pull requests, want none")
+	}
+	if len(prs) != 1 || prs[0].ID != 2 || prs[0].UpdatedDate != 1567504800000 {
+		t.Fatalf("unexpected pull requests %+v", prs)
+	}
+
+	pr := prs[0]
+	if _, _, err := cli.PullRequestActivities(ctx, pr, nil); err == nil {
+		t.Error("listing activities of a pull request without a repository succeeded")
+	}
+
+	pr.ToRef.Repository.Slug = "vegeta"
+	pr.ToRef.Repository.Project = &Project{Key: "SOUR"}
+	activities, _, err := cli.PullRequestActivities(ctx, pr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 2 {
+		t.Fatalf("got %d activities, want 2", len(activities))
+	}
+	a := activities[1]
+	if a.Action != "COMMENTED" || a.CommentAction != "ADDED" || a.Comment == nil || a.Comment.Text != "Typo" {
+		t.Errorf("unexpected activity %+v", a)
+	}
+	if a.Comment != nil && (len(a.Comment.Comments) != 1 || a.Comment.Comments[0].Author.Name != "bob") {
+		t.Errorf("unexpected replies %+v", a.Comment.Comments)
+	}
+	want := &CommentAnchor{FromHash: "deadbeef", ToHash: "cafebabe", Line: 3, LineType: "ADDED", FileType: "TO", Path: "README.md"}
+	if !reflect.DeepEqual(a.CommentAnchor, want) {
+		t.Error(cmp.Diff(a.CommentAnchor, want))
+	}
+}
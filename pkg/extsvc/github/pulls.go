@@ -0,0 +1,303 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PullRequest is a GitHub pull request.
+type PullRequest struct {
+	// RepoID is the GraphQL node ID of the repository of the pull request. It
+	// must be set (along with Number) before calling LoadPullRequests.
+	RepoID string `json:"-"`
+
+	ID        string // GraphQL node ID of the pull request
+	Number    int64
+	Title     string
+	Body      string
+	State     string // OPEN, CLOSED, or MERGED
+	URL       string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ClosedAt  time.Time // zero if the pull request is open
+	MergedAt  time.Time // zero if the pull request is not merged
+	Reviews   struct {
+		Nodes []PullRequestReview
+	}
+	Commits struct {
+		Nodes []struct {
+			Commit PullRequestCommit
+		}
+	}
+}
+
+// PullRequestReview is a review of a pull request.
+type PullRequestReview struct {
+	Author struct {
+		Login string
+	}
+	State       string // PENDING, COMMENTED, APPROVED, CHANGES_REQUESTED, or DISMISSED
+	SubmittedAt time.Time
+}
+
+// PullRequestCommit is a commit of a pull request.
+type PullRequestCommit struct {
+	OID    string
+	Status struct {
+		// State is the combined state of the commit statuses: EXPECTED,
+		// ERROR, FAILURE, PENDING, or SUCCESS.
+		State string
+	}
+}
+
+// LatestReviews returns the latest approving or changes-requesting review of
+// each reviewer, in the order in which the reviewers first submitted such a
+// review. Comment-only, pending and dismissed reviews are ignored.
+func (pr *PullRequest) LatestReviews() []PullRequestReview {
+	var logins []string
+	latest := make(map[string]PullRequestReview)
+	for _, r := range pr.Reviews.Nodes { // oldest first
+		if r.State != "APPROVED" && r.State != "CHANGES_REQUESTED" {
+			continue
+		}
+		if _, ok := latest[r.Author.Login]; !ok {
+			logins = append(logins, r.Author.Login)
+		}
+		latest[r.Author.Login] = r
+	}
+
+	reviews := make([]PullRequestReview, 0, len(logins))
+	for _, login := range logins {
+		reviews = append(reviews, latest[login])
+	}
+	return reviews
+}
+
+// HeadCommit returns the latest commit of the pull request, or nil if it was
+// not loaded.
+func (pr *PullRequest) HeadCommit() *PullRequestCommit {
+	if n := len(pr.Commits.Nodes); n > 0 {
+		return &pr.Commits.Nodes[n-1].Commit
+	}
+	return nil
+}
+
+const pullRequestFieldsGraphQLFragment = `
+fragment PullRequestFields on PullRequest {
+	id
+	number
+	title
+	body
+	state
+	url
+	createdAt
+	updatedAt
+	closedAt
+	mergedAt
+	reviews(last: 100) {
+		nodes {
+			author { login }
+			state
+			submittedAt
+		}
+	}
+	commits(last: 1) {
+		nodes {
+			commit {
+				oid
+				status { state }
+			}
+		}
+	}
+}
+`
+
+// pullRequestsBatchSize is the maximum number of pull requests loaded in a
+// single GraphQL query. See GetReposByNameWithOwner for why the number of
+// aliases in a query is limited.
+const pullRequestsBatchSize = 30
+
+// LoadPullRequests loads the given pull requests, identified by their RepoID
+// and Number fields, from the GitHub API. It returns an error if any of them
+// could not be found.
+//
+// This method does not cache.
+func (c *Client) LoadPullRequests(ctx context.Context, prs ...*PullRequest) error {
+	for len(prs) > 0 {
+		batch := prs
+		if len(batch) > pullRequestsBatchSize {
+			batch = batch[:pullRequestsBatchSize]
+		}
+		prs = prs[len(batch):]
+
+		if err := c.loadPullRequests(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) loadPullRequests(ctx context.Context, prs []*PullRequest) error {
+	var result map[string]*struct {
+		PullRequest *PullRequest
+	}
+	err := c.requestGraphQL(ctx, "", buildLoadPullRequestsQuery(prs), map[string]interface{}{}, &result)
+	if err != nil {
+		if _, ok := err.(graphqlErrors); !ok {
+			return err
+		}
+		// Errors for individual pull requests are reported below.
+	}
+
+	var missing []string
+	for i, pr := range prs {
+		r := result[fmt.Sprintf("pr%d", i)]
+		if r == nil || r.PullRequest == nil {
+			missing = append(missing, fmt.Sprintf("#%d (repository %s)", pr.Number, pr.RepoID))
+			continue
+		}
+		repoID := pr.RepoID
+		*pr = *r.PullRequest
+		pr.RepoID = repoID
+	}
+
+	if len(missing) > 0 {
+		if err != nil {
+			return errors.Wrapf(err, "loading pull requests %s", strings.Join(missing, ", "))
+		}
+		return errors.Errorf("pull requests not found: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func buildLoadPullRequestsQuery(prs []*PullRequest) string {
+	var b strings.Builder
+	b.WriteString(pullRequestFieldsGraphQLFragment)
+	b.WriteString("query {\n")
+	for i, pr := range prs {
+		fmt.Fprintf(&b, "pr%d: node(id: %q) { ", i, pr.RepoID)
+		fmt.Fprintf(&b, "... on Repository { pullRequest(number: %d) { ...PullRequestFields } } }\n", pr.Number)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// CreatePullRequestInput is the input of CreatePullRequest.
+type CreatePullRequestInput struct {
+	// RepositoryID is the GraphQL node ID of the repository.
+	RepositoryID string `json:"repositoryId"`
+	// BaseRefName is the name of the branch that the changes are merged into.
+	BaseRefName string `json:"baseRefName"`
+	// HeadRefName is the name of the branch with the changes.
+	HeadRefName string `json:"headRefName"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+}
+
+// CreatePullRequest opens a pull request that proposes to merge the head
+// branch into the base branch of the repository and returns it.
+func (c *Client) CreatePullRequest(ctx context.Context, in *CreatePullRequestInput) (*PullRequest, error) {
+	var result struct {
+		CreatePullRequest struct {
+			PullRequest *PullRequest
+		}
+	}
+	q := pullRequestFieldsGraphQLFragment + `
+mutation CreatePullRequest($input: CreatePullRequestInput!) {
+	createPullRequest(input: $input) {
+		pullRequest { ...PullRequestFields }
+	}
+}`
+	if err := c.requestGraphQL(ctx, "", q, map[string]interface{}{"input": in}, &result); err != nil {
+		return nil, err
+	}
+
+	pr := result.CreatePullRequest.PullRequest
+	if pr == nil {
+		return nil, errors.Errorf("creating pull request from %q into %q: no pull request returned", in.HeadRefName, in.BaseRefName)
+	}
+	pr.RepoID = in.RepositoryID
+	return pr, nil
+}
+
+// PullRequestReviewComment is a review comment on the diff of a pull request.
+type PullRequestReviewComment struct {
+	ID          int64  `json:"id"`
+	InReplyToID int64  `json:"in_reply_to_id"` // ID of the first comment of the thread, or zero
+	Body        string `json:"body"`
+	Path        string `json:"path"`
+	// DiffHunk is the part of the diff of Path that the comment is on, up to
+	// and including the line that it is on.
+	DiffHunk string `json:"diff_hunk"`
+	// OriginalCommitID is the commit of the pull request that the comment
+	// was made on, which the line of DiffHunk refers to.
+	OriginalCommitID string `json:"original_commit_id"`
+	User             struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	HTMLURL   string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Line returns the line (1-based) in the version of Path at OriginalCommitID
+// that the comment is on, which is the last line of DiffHunk. It returns false
+// if the comment is on a removed line (which isn't in that version) or
+// DiffHunk can't be parsed.
+func (c *PullRequestReviewComment) Line() (int, bool) {
+	lines := strings.Split(strings.TrimSuffix(c.DiffHunk, "\n"), "\n")
+	if len(lines) < 2 {
+		return 0, false
+	}
+
+	// The hunk header is "@@ -origStart[,origLines] +newStart[,newLines] @@".
+	fields := strings.Fields(lines[0])
+	if len(fields) < 3 || fields[0] != "@@" || !strings.HasPrefix(fields[2], "+") {
+		return 0, false
+	}
+	newStart, err := strconv.Atoi(strings.SplitN(fields[2][1:], ",", 2)[0])
+	if err != nil {
+		return 0, false
+	}
+
+	line := newStart - 1
+	for _, l := range lines[1:] {
+		if !strings.HasPrefix(l, "-") && !strings.HasPrefix(l, "\\") {
+			line++
+		}
+	}
+	if last := lines[len(lines)-1]; strings.HasPrefix(last, "-") || line < 1 {
+		return 0, false
+	}
+	return line, true
+}
+
+// ListRepositoryReviewComments lists the review comments on all pull requests
+// of the repository that were updated at or after since (unless it is zero),
+// least recently updated first. page is the page of results to return. Pages
+// are 1-indexed (so the first call should be for page 1).
+//
+// This method does not cache.
+func (c *Client) ListRepositoryReviewComments(ctx context.Context, owner, name string, since time.Time, page int) (comments []*PullRequestReviewComment, hasNextPage bool, err error) {
+	const perPage = 100
+	q := url.Values{
+		"sort":      {"updated"},
+		"direction": {"asc"},
+		"page":      {strconv.Itoa(page)},
+		"per_page":  {strconv.Itoa(perPage)},
+	}
+	if !since.IsZero() {
+		q.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	path := fmt.Sprintf("repos/%s/%s/pulls/comments?%s", owner, name, q.Encode())
+	if err := c.requestGet(ctx, "", path, &comments); err != nil {
+		return nil, false, err
+	}
+	return comments, len(comments) == perPage, nil
+}
@@ -0,0 +1,226 @@
+package github
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/ratelimit"
+)
+
+type recordingHTTPDoer struct {
+	mockHTTPResponseBody
+	reqBody string
+}
+
+func (d *recordingHTTPDoer) Do(req *http.Request) (*http.Response, error) {
+	b, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	d.reqBody = string(b)
+	return d.mockHTTPResponseBody.Do(req)
+}
+
+func TestClient_LoadPullRequests(t *testing.T) {
+	doer := &recordingHTTPDoer{mockHTTPResponseBody: mockHTTPResponseBody{responseBody: `
+{
+  "data": {
+    "pr0": {
+      "pullRequest": {
+        "id": "MDExOlB1bGxSZXF1ZXN0MQ==",
+        "number": 1,
+        "title": "Upgrade ES-Lint",
+        "body": "All the Javascripts are belong to us",
+        "state": "MERGED",
+        "url": "https://github.com/sourcegraph/sourcegraph/pull/1",
+        "createdAt": "2019-09-01T10:00:00Z",
+        "updatedAt": "2019-09-03T10:00:00Z",
+        "closedAt": "2019-09-03T10:00:00Z",
+        "mergedAt": "2019-09-03T10:00:00Z",
+        "reviews": {
+          "nodes": [
+            {"author": {"login": "alice"}, "state": "CHANGES_REQUESTED", "submittedAt": "2019-09-01T11:00:00Z"},
+            {"author": {"login": "bob"}, "state": "COMMENTED", "submittedAt": "2019-09-01T12:00:00Z"},
+            {"author": {"login": "alice"}, "state": "APPROVED", "submittedAt": "2019-09-02T11:00:00Z"}
+          ]
+        },
+        "commits": {
+          "nodes": [
+            {"commit": {"oid": "deadbeef", "status": {"state": "SUCCESS"}}}
+          ]
+        }
+      }
+    },
+    "pr1": {
+      "pullRequest": null
+    }
+  },
+  "errors": [
+    {
+      "type": "NOT_FOUND",
+      "path": ["pr1", "pullRequest"],
+      "message": "Could not resolve to a PullRequest with the number of 2."
+    }
+  ]
+}
+`}}
+
+	c := &Client{
+		apiURL:     &url.URL{Scheme: "https", Host: "example.com", Path: "/"},
+		httpClient: doer,
+		RateLimit:  &ratelimit.Monitor{},
+	}
+
+	prs := []*PullRequest{
+		{RepoID: "MDEwOlJlcG9zaXRvcnkx", Number: 1},
+		{RepoID: "MDEwOlJlcG9zaXRvcnkx", Number: 2},
+	}
+	err := c.LoadPullRequests(context.Background(), prs...)
+	if err == nil || !strings.Contains(err.Error(), "#2") {
+		t.Errorf("got error %v, want an error mentioning #2", err)
+	}
+	if !strings.Contains(doer.reqBody, `pr1: node(id: \"MDEwOlJlcG9zaXRvcnkx\")`) || !strings.Contains(doer.reqBody, "pullRequest(number: 2)") {
+		t.Errorf("unexpected query %s", doer.reqBody)
+	}
+
+	pr := prs[0]
+	if pr.RepoID != "MDEwOlJlcG9zaXRvcnkx" {
+		t.Errorf("got RepoID %q, want it to be preserved", pr.RepoID)
+	}
+	if pr.Title != "Upgrade ES-Lint" || pr.State != "MERGED" || pr.URL != "https://github.com/sourcegraph/sourcegraph/pull/1" {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+	if want := time.Date(2019, 9, 3, 10, 0, 0, 0, time.UTC); !pr.MergedAt.Equal(want) {
+		t.Errorf("got MergedAt %v, want %v", pr.MergedAt, want)
+	}
+	if commit := pr.HeadCommit(); commit == nil || commit.OID != "deadbeef" || commit.Status.State != "SUCCESS" {
+		t.Errorf("unexpected head commit %+v", commit)
+	}
+
+	var reviews []string
+	for _, r := range pr.LatestReviews() {
+		reviews = append(reviews, r.Author.Login+":"+r.State)
+	}
+	if want := []string{"alice:APPROVED"}; !reflect.DeepEqual(reviews, want) {
+		t.Errorf("got latest reviews %v, want %v", reviews, want)
+	}
+}
+
+func TestClient_CreatePullRequest(t *testing.T) {
+	doer := &recordingHTTPDoer{mockHTTPResponseBody: mockHTTPResponseBody{responseBody: `
+{
+  "data": {
+    "createPullRequest": {
+      "pullRequest": {
+        "id": "MDExOlB1bGxSZXF1ZXN0Mw==",
+        "number": 3,
+        "title": "Campaign",
+        "state": "OPEN",
+        "url": "https://github.com/sourcegraph/sourcegraph/pull/3"
+      }
+    }
+  }
+}
+`}}
+
+	c := &Client{
+		apiURL:     &url.URL{Scheme: "https", Host: "example.com", Path: "/"},
+		httpClient: doer,
+		RateLimit:  &ratelimit.Monitor{},
+	}
+
+	pr, err := c.CreatePullRequest(context.Background(), &CreatePullRequestInput{
+		RepositoryID: "MDEwOlJlcG9zaXRvcnkx",
+		BaseRefName:  "master",
+		HeadRefName:  "sourcegraph/campaign-1",
+		Title:        "Campaign",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Number != 3 || pr.RepoID != "MDEwOlJlcG9zaXRvcnkx" || pr.URL != "https://github.com/sourcegraph/sourcegraph/pull/3" {
+		t.Errorf("unexpected pull request %+v", pr)
+	}
+	if !strings.Contains(doer.reqBody, "createPullRequest(input: $input)") || !strings.Contains(doer.reqBody, `"headRefName":"sourcegraph/campaign-1"`) {
+		t.Errorf("unexpected request %s", doer.reqBody)
+	}
+}
+
+func TestClient_ListRepositoryReviewComments(t *testing.T) {
+	mock := &mockHTTPResponseBody{responseBody: `
+[
+  {
+    "id": 10,
+    "body": "Typo",
+    "path": "README.md",
+    "diff_hunk": "@@ -1,2 +1,3 @@\n # Title\n+Hello wrld",
+    "original_commit_id": "deadbeef",
+    "user": {"login": "alice"},
+    "html_url": "https://github.com/sourcegraph/sourcegraph/pull/1#discussion_r10",
+    "created_at": "2019-09-01T10:00:00Z",
+    "updated_at": "2019-09-01T11:00:00Z"
+  },
+  {
+    "id": 11,
+    "in_reply_to_id": 10,
+    "body": "Fixed",
+    "path": "README.md",
+    "diff_hunk": "@@ -1,2 +1,3 @@\n # Title\n+Hello wrld",
+    "original_commit_id": "deadbeef",
+    "user": {"login": "bob"},
+    "html_url": "https://github.com/sourcegraph/sourcegraph/pull/1#discussion_r11",
+    "created_at": "2019-09-01T12:00:00Z",
+    "updated_at": "2019-09-01T12:00:00Z"
+  }
+]
+`}
+	c := &Client{
+		apiURL:     &url.URL{Scheme: "https", Host: "example.com", Path: "/"},
+		httpClient: mock,
+		RateLimit:  &ratelimit.Monitor{},
+	}
+
+	comments, hasNextPage, err := c.ListRepositoryReviewComments(context.Background(), "sourcegraph", "sourcegraph", time.Time{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasNextPage {
+		t.Error("got hasNextPage, want no next page")
+	}
+	if len(comments) != 2 {
+		t.Fatalf("got %d comments, want 2", len(comments))
+	}
+	if c := comments[1]; c.ID != 11 || c.InReplyToID != 10 || c.User.Login != "bob" || c.OriginalCommitID != "deadbeef" {
+		t.Errorf("unexpected comment %+v", c)
+	}
+	if line, ok := comments[0].Line(); !ok || line != 2 {
+		t.Errorf("got line %d, %v, want 2", line, ok)
+	}
+}
+
+func TestPullRequestReviewComment_Line(t *testing.T) {
+	for _, test := range []struct {
+		diffHunk string
+		wantLine int
+		wantOK   bool
+	}{
+		{"@@ -1,3 +1,4 @@\n a\n+b\n c", 3, true},
+		{"@@ -10,3 +12,2 @@\n a\n-b\n+c", 13, true},
+		{"@@ -10,3 +12,2 @@\n a\n-b", 0, false},
+		{"@@ -0,0 +1 @@\n+a", 1, true},
+		{"@@ -1 +1 @@\n a\n\\ No newline at end of file", 1, true},
+		{"not a hunk\n a", 0, false},
+		{"", 0, false},
+	} {
+		c := &PullRequestReviewComment{DiffHunk: test.diffHunk}
+		if line, ok := c.Line(); line != test.wantLine || ok != test.wantOK {
+			t.Errorf("%q: got %d, %v, want %d, %v", test.diffHunk, line, ok, test.wantLine, test.wantOK)
+		}
+	}
+}
@@ -3,8 +3,6 @@ package gitserver
 import (
 	"bytes"
 	"context"
-	"crypto/md5"
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -59,6 +57,12 @@ func NewClient(cli httpcli.Doer) *Client {
 		Addrs: func(ctx context.Context) []string {
 			return conf.Get().ServiceConnections.GitServers
 		},
+		ReplicationFactor: func(ctx context.Context) int {
+			return conf.Get().ServiceConnections.GitServerReplicationFactor
+		},
+		HashRing: func(ctx context.Context) bool {
+			return conf.Get().ServiceConnections.GitServerHashRing
+		},
 		HTTPClient:  cli,
 		HTTPLimiter: parallel.NewRun(500),
 		// Use the binary name for UserAgent. This should effectively identify
@@ -81,27 +85,56 @@ type Client struct {
 	// concurrent use. It may return different results at different times.
 	Addrs func(ctx context.Context) []string
 
+	// ReplicationFactor is a function which returns the number of gitservers
+	// each repository is cloned on. It is called each time a request is made.
+	// If it is nil or returns a value less than 1, each repository is cloned
+	// on a single gitserver.
+	ReplicationFactor func(ctx context.Context) int
+
+	// HashRing is a function which returns whether repositories are assigned
+	// to gitservers with RingReplicaAddrs rather than ReplicaAddrs. It is
+	// called each time a request is made. If it is nil, ReplicaAddrs is used.
+	HashRing func(ctx context.Context) bool
+
 	// UserAgent is a string identifing who the client is. It will be logged in
 	// the telemetry in gitserver.
 	UserAgent string
 }
 
-// addrForRepo returns the gitserver address to use for the given repo name.
+// addrForRepo returns the address of the primary gitserver for the given
+// repo name.
 func (c *Client) addrForRepo(ctx context.Context, repo api.RepoName) string {
+	return c.addrsForRepo(ctx, repo)[0]
+}
+
+// addrsForRepo returns the addresses of the gitservers holding a replica of
+// the given repo name, primary first.
+func (c *Client) addrsForRepo(ctx context.Context, repo api.RepoName) []string {
 	repo = protocol.NormalizeRepo(repo) // in case the caller didn't already normalize it
-	return c.addrForKey(ctx, string(repo))
+	n := 1
+	if c.ReplicationFactor != nil {
+		n = c.ReplicationFactor(ctx)
+	}
+	return c.addrsForKey(ctx, string(repo), n)
 }
 
 // addrForKey returns the gitserver address to use for the given string key,
 // which is hashed for sharding purposes.
 func (c *Client) addrForKey(ctx context.Context, key string) string {
+	return c.addrsForKey(ctx, key, 1)[0]
+}
+
+// addrsForKey returns the addresses of the n gitservers to use for the given
+// string key, which is hashed for sharding purposes.
+func (c *Client) addrsForKey(ctx context.Context, key string, n int) []string {
 	addrs := c.Addrs(ctx)
 	if len(addrs) == 0 {
 		panic("unexpected state: no gitserver addresses")
 	}
-	sum := md5.Sum([]byte(key))
-	serverIndex := binary.BigEndian.Uint64(sum[:]) % uint64(len(addrs))
-	return addrs[serverIndex]
+	if c.HashRing != nil && c.HashRing(ctx) {
+		return RingReplicaAddrs(key, addrs, n)
+	}
+	return ReplicaAddrs(key, addrs, n)
 }
 
 // ArchiveOptions contains options for the Archive func.
@@ -137,8 +170,17 @@ func (a *archiveReader) Close() error {
 }
 
 // ArchiveURL returns a URL from which an archive of the given Git repository can
-// be downloaded from.
+// be downloaded from. The URL refers to the repository's primary gitserver.
 func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions) *url.URL {
+	return &url.URL{
+		Scheme:   "http",
+		Host:     c.addrForRepo(ctx, repo.Name),
+		Path:     "/archive",
+		RawQuery: archiveQuery(repo, opt).Encode(),
+	}
+}
+
+func archiveQuery(repo Repo, opt ArchiveOptions) url.Values {
 	q := url.Values{
 		"repo":    {string(repo.Name)},
 		"treeish": {opt.Treeish},
@@ -148,13 +190,7 @@ func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions)
 	for _, path := range opt.Paths {
 		q.Add("path", path)
 	}
-
-	return &url.URL{
-		Scheme:   "http",
-		Host:     c.addrForRepo(ctx, repo.Name),
-		Path:     "/archive",
-		RawQuery: q.Encode(),
-	}
+	return q
 }
 
 // Archive produces an archive from a Git repository.
@@ -176,8 +212,9 @@ func (c *Client) Archive(ctx context.Context, repo Repo, opt ArchiveOptions) (_
 		return nil, err
 	}
 
-	u := c.ArchiveURL(ctx, repo, opt)
-	resp, err := c.do(ctx, repo.Name, "GET", u.String(), nil)
+	// Let c.do choose the gitserver, so that it can fail over to another
+	// replica of the repository.
+	resp, err := c.do(ctx, repo.Name, "GET", "archive?"+archiveQuery(repo, opt).Encode(), nil, true)
 	if err != nil {
 		return nil, err
 	}
@@ -212,6 +249,31 @@ func (c *Client) Archive(ctx context.Context, repo Repo, opt ArchiveOptions) (_
 	}
 }
 
+// readOnlyGitCommands are the git subcommands that don't modify the
+// repository, which can therefore be retried on another replica.
+var readOnlyGitCommands = map[string]bool{
+	"archive":      true,
+	"blame":        true,
+	"cat-file":     true,
+	"diff":         true,
+	"for-each-ref": true,
+	"log":          true,
+	"ls-files":     true,
+	"ls-tree":      true,
+	"merge-base":   true,
+	"rev-list":     true,
+	"rev-parse":    true,
+	"shortlog":     true,
+	"show":         true,
+	"show-ref":     true,
+}
+
+// isReadOnlyGitCommand reports whether the git command with the given
+// arguments (excluding "git") doesn't modify the repository.
+func isReadOnlyGitCommand(args []string) bool {
+	return len(args) > 0 && readOnlyGitCommands[args[0]]
+}
+
 type badRequestError struct{ error }
 
 func (e badRequestError) BadRequest() bool { return true }
@@ -244,7 +306,11 @@ func (c *Cmd) sendExec(ctx context.Context) (_ io.ReadCloser, _ http.Header, err
 		EnsureRevision: c.EnsureRevision,
 		Args:           c.Args[1:],
 	}
-	resp, err := c.client.httpPost(ctx, repoName, "exec", req)
+	post := c.client.httpPost
+	if isReadOnlyGitCommand(req.Args) {
+		post = c.client.httpPostReadOnly
+	}
+	resp, err := post(ctx, repoName, "exec", req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -275,8 +341,16 @@ var deadlineExceededCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Help:      "Times that Client.sendExec() returned context.DeadlineExceeded",
 })
 
+var replicaFailoverCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "client_replica_failover",
+	Help:      "Times that a request was retried on another replica of a repository because a gitserver was unavailable or didn't have the repository",
+})
+
 func init() {
 	prometheus.MustRegister(deadlineExceededCounter)
+	prometheus.MustRegister(replicaFailoverCounter)
 }
 
 // Cmd represents a command to be executed remotely.
@@ -540,13 +614,34 @@ func (c *Client) doListOne(ctx context.Context, urlSuffix, addr string) ([]strin
 // Repo updates are not guaranteed to occur. If a repo has been updated
 // recently (within the Since duration specified in the request), the
 // update won't happen.
-func (c *Client) RequestRepoUpdate(ctx context.Context, repo Repo, since time.Duration) (*protocol.RepoUpdateResponse, error) {
+//
+// The request is sent to the first reachable replica of the repository, which
+// forwards it to the replicas after it. The replicas record whether the
+// repository is pinned, so that a replica that clones the repository later
+// keeps it too.
+func (c *Client) RequestRepoUpdate(ctx context.Context, repo Repo, since time.Duration, pinned bool) (*protocol.RepoUpdateResponse, error) {
 	req := &protocol.RepoUpdateRequest{
-		Repo:  repo.Name,
-		URL:   repo.URL,
-		Since: since,
+		Repo:   repo.Name,
+		URL:    repo.URL,
+		Since:  since,
+		Pinned: pinned,
+	}
+	var (
+		resp *http.Response
+		err  error
+	)
+	addrs := c.addrsForRepo(ctx, repo.Name)
+	for i, addr := range addrs {
+		req.Replicas = addrs[i+1:]
+		resp, err = c.httpPost(ctx, repo.Name, "http://"+addr+"/repo-update", req)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+		if i < len(addrs)-1 {
+			replicaFailoverCounter.Inc()
+			log15.Warn("gitserver replica unavailable for repo-update, trying next replica", "repo", repo.Name, "addr", addr, "error", err)
+		}
 	}
-	resp, err := c.httpPost(ctx, repo.Name, "repo-update", req)
 	if err != nil {
 		return nil, err
 	}
@@ -574,7 +669,7 @@ func (c *Client) IsRepoCloneable(ctx context.Context, repo Repo) error {
 		Repo: repo.Name,
 		URL:  repo.URL,
 	}
-	r, err := c.httpPost(ctx, repo.Name, "is-repo-cloneable", req)
+	r, err := c.httpPostReadOnly(ctx, repo.Name, "is-repo-cloneable", req)
 	if err != nil {
 		return err
 	}
@@ -635,7 +730,7 @@ func (c *Client) IsRepoCloned(ctx context.Context, repo api.RepoName) (bool, err
 	req := &protocol.IsRepoClonedRequest{
 		Repo: repo,
 	}
-	resp, err := c.httpPost(ctx, repo, "is-repo-cloned", req)
+	resp, err := c.httpPostReadOnly(ctx, repo, "is-repo-cloned", req)
 	if err != nil {
 		return false, err
 	}
@@ -681,7 +776,7 @@ func (c *Client) RepoInfo(ctx context.Context, repos ...api.RepoName) (*protocol
 	for _, req := range shards {
 		go func(o op) {
 			var resp *http.Response
-			resp, o.err = c.httpPost(ctx, o.req.Repos[0], "repos", o.req)
+			resp, o.err = c.httpPostReadOnly(ctx, o.req.Repos[0], "repos", o.req)
 			if o.err != nil {
 				ch <- o
 				return
@@ -725,12 +820,23 @@ func (c *Client) RepoInfo(ctx context.Context, repos ...api.RepoName) (*protocol
 	return &res, err.ErrorOrNil()
 }
 
-// Remove removes the repository clone from gitserver.
+// Remove removes the repository clone from all gitservers holding a replica
+// of it.
 func (c *Client) Remove(ctx context.Context, repo api.RepoName) error {
+	var errs *multierror.Error
+	for _, addr := range c.addrsForRepo(ctx, repo) {
+		if err := c.removeFrom(ctx, addr, repo); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+func (c *Client) removeFrom(ctx context.Context, addr string, repo api.RepoName) error {
 	req := &protocol.RepoDeleteRequest{
 		Repo: repo,
 	}
-	resp, err := c.httpPost(ctx, repo, "delete", req)
+	resp, err := c.httpPost(ctx, repo, "http://"+addr+"/delete", req)
 	if err != nil {
 		return err
 	}
@@ -743,13 +849,65 @@ func (c *Client) Remove(ctx context.Context, repo api.RepoName) error {
 	return nil
 }
 
+// SetPinned sets whether the repository is pinned on the gitservers that have
+// cloned it. Pinned repositories are never removed to free up disk space. The
+// gitservers that haven't cloned it yet learn whether it is pinned from the
+// repo-update requests that make them clone it (see RequestRepoUpdate).
+func (c *Client) SetPinned(ctx context.Context, repo api.RepoName, pinned bool) error {
+	var errs *multierror.Error
+	for _, addr := range c.addrsForRepo(ctx, repo) {
+		if err := c.setPinnedOn(ctx, addr, repo, pinned); err != nil && err != errRepoNotCloned {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+var errRepoNotCloned = errors.New("repository not cloned")
+
+func (c *Client) setPinnedOn(ctx context.Context, addr string, repo api.RepoName, pinned bool) error {
+	req := &protocol.RepoPinRequest{
+		Repo:   repo,
+		Pinned: pinned,
+	}
+	resp, err := c.httpPost(ctx, repo, "http://"+addr+"/repo-pin", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		// The repository may be on this replica later, but it isn't now.
+		return errRepoNotCloned
+	default:
+		// best-effort inclusion of body in error message
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 200))
+		return &url.Error{URL: resp.Request.URL.String(), Op: "RepoPin", Err: fmt.Errorf("RepoPin: http status %d: %s", resp.StatusCode, string(body))}
+	}
+}
+
+// httpPost sends the request op to the primary replica of repo (or to op
+// itself, if it is an absolute URL).
 func (c *Client) httpPost(ctx context.Context, repo api.RepoName, op string, payload interface{}) (resp *http.Response, err error) {
-	return c.do(ctx, repo, "POST", op, payload)
+	return c.do(ctx, repo, "POST", op, payload, false)
+}
+
+// httpPostReadOnly is like httpPost, but fails over to the other replicas of
+// repo. It must only be used for ops that don't modify the repository, because
+// a request that failed with a transport error may still have been performed.
+func (c *Client) httpPostReadOnly(ctx context.Context, repo api.RepoName, op string, payload interface{}) (resp *http.Response, err error) {
+	return c.do(ctx, repo, "POST", op, payload, true)
 }
 
 // do performs a request to a gitserver, sharding based on the given
-// repo name (the repo name is otherwise not used).
-func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, payload interface{}) (resp *http.Response, err error) {
+// repo name (the repo name is otherwise not used). If op is not an absolute
+// URL, the request is sent to the primary replica of the repository. If
+// failover is true, it is instead sent to the replicas in turn until one of
+// them responds with a status other than 404 Not Found. If none does, the
+// first 404 response is returned.
+func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, payload interface{}, failover bool) (resp *http.Response, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "Client.do")
 	defer func() {
 		span.LogKV("repo", string(repo), "method", method, "op", op)
@@ -765,11 +923,48 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 		return nil, err
 	}
 
-	uri := op
-	if !strings.HasPrefix(op, "http") {
-		uri = "http://" + c.addrForRepo(ctx, repo) + "/" + op
+	if strings.HasPrefix(op, "http") {
+		return c.doOnce(ctx, span, method, op, reqBody)
+	}
+
+	if !failover {
+		return c.doOnce(ctx, span, method, "http://"+c.addrForRepo(ctx, repo)+"/"+op, reqBody)
+	}
+
+	var notFound *http.Response
+	addrs := c.addrsForRepo(ctx, repo)
+	for i, addr := range addrs {
+		resp, err = c.doOnce(ctx, span, method, "http://"+addr+"/"+op, reqBody)
+		if i == len(addrs)-1 || ctx.Err() != nil {
+			break
+		}
+		if err == nil {
+			if resp.StatusCode != http.StatusNotFound {
+				return resp, nil
+			}
+			// The replica doesn't have the repository (e.g. because it is
+			// still cloning it). Keep its response in case no other replica
+			// has the repository either.
+			if notFound == nil {
+				notFound = bufferResponse(resp)
+			} else {
+				resp.Body.Close()
+			}
+		}
+		replicaFailoverCounter.Inc()
+		span.LogKV("event", "failover", "addr", addr, "error", errorString(err))
+	}
+	if notFound != nil && (err != nil || resp.StatusCode == http.StatusNotFound) {
+		if err == nil {
+			resp.Body.Close()
+		}
+		return notFound, nil
 	}
+	return resp, err
+}
 
+// doOnce performs a single request to the gitserver at uri.
+func (c *Client) doOnce(ctx context.Context, span opentracing.Span, method, uri string, reqBody []byte) (*http.Response, error) {
 	req, err := http.NewRequest(method, uri, bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
@@ -793,21 +988,74 @@ func (c *Client) do(ctx context.Context, repo api.RepoName, method, op string, p
 	return c.HTTPClient.Do(req)
 }
 
+// bufferResponse reads the (small) body of resp into memory, so that another
+// request can be made before resp is returned.
+func bufferResponse(resp *http.Response) *http.Response {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// CreateCommitFromPatch creates a commit from the patch in req on top of its
+// base commit and returns the ref the commit is stored at in gitserver.
 func (c *Client) CreateCommitFromPatch(ctx context.Context, req protocol.CreateCommitFromPatchRequest) (string, error) {
-	resp, err := c.httpPost(ctx, req.Repo, "create-commit-from-patch", req)
+	res, err := c.createCommitFromPatch(ctx, req)
 	if err != nil {
 		return "", err
 	}
+	return res.Rev, nil
+}
+
+// MockCreateCommitFromPatchAndPush mocks (*Client).CreateCommitFromPatchAndPush
+// for tests.
+var MockCreateCommitFromPatchAndPush func(protocol.CreateCommitFromPatchRequest, protocol.PushConfig) (*protocol.PushResult, error)
+
+// CreateCommitFromPatchAndPush is like CreateCommitFromPatch, but also pushes
+// the created commit to the branch given by push on the repository's remote,
+// using the credentials gitserver clones the repository with. If the remote
+// rejects the push (e.g. because the branch doesn't point to push.ExpectedOID),
+// the reason is reported in the returned PushResult, not as an error.
+func (c *Client) CreateCommitFromPatchAndPush(ctx context.Context, req protocol.CreateCommitFromPatchRequest, push protocol.PushConfig) (*protocol.PushResult, error) {
+	if MockCreateCommitFromPatchAndPush != nil {
+		return MockCreateCommitFromPatchAndPush(req, push)
+	}
+
+	req.Push = &push
+	res, err := c.createCommitFromPatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Push == nil {
+		return nil, errors.New("CreateCommitFromPatchAndPush: gitserver did not push the commit")
+	}
+	return res.Push, nil
+}
+
+func (c *Client) createCommitFromPatch(ctx context.Context, req protocol.CreateCommitFromPatchRequest) (*protocol.CreatePatchFromPatchResponse, error) {
+	resp, err := c.httpPost(ctx, req.Repo, "create-commit-from-patch", req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		b, _ := ioutil.ReadAll(resp.Body)
 		log15.Warn("gitserver create-commit-from-patch error", "err", string(b))
 
-		return "", &url.Error{URL: resp.Request.URL.String(), Op: "CreateCommitFromPatch", Err: fmt.Errorf("CreateCommitFromPatch: http status %d %s", resp.StatusCode, string(b))}
+		return nil, &url.Error{URL: resp.Request.URL.String(), Op: "CreateCommitFromPatch", Err: fmt.Errorf("CreateCommitFromPatch: http status %d %s", resp.StatusCode, string(b))}
 	}
 
 	var res protocol.CreatePatchFromPatchResponse
-
-	return res.Rev, json.NewDecoder(resp.Body).Decode(&res)
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
 }
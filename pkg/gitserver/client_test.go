@@ -13,12 +13,15 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
 
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/gitserver/server"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/pkg/vcs/git/gittest"
 )
 
@@ -175,3 +178,99 @@ filemode=true
 	}
 	return nil
 }
+
+func TestClient_ReplicaFailover(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	newServer := func(name string, cloned bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests = append(requests, name+" "+r.URL.Path)
+			mu.Unlock()
+			if !cloned {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	}
+	cloned := newServer("cloned", true)
+	defer cloned.Close()
+	notCloned := newServer("not-cloned", false)
+	defer notCloned.Close()
+	down := newServer("down", true)
+	down.Close()
+
+	host := func(s *httptest.Server) string {
+		u, _ := url.Parse(s.URL)
+		return u.Host
+	}
+
+	tests := map[string]struct {
+		addrs      []string
+		wantCloned bool
+	}{
+		"replica without repo": {
+			addrs:      []string{host(notCloned), host(cloned)},
+			wantCloned: true,
+		},
+		"replica down": {
+			addrs:      []string{host(down), host(cloned)},
+			wantCloned: true,
+		},
+		"no replica with repo": {
+			addrs:      []string{host(down), host(notCloned)},
+			wantCloned: false,
+		},
+	}
+	for label, test := range tests {
+		cli := gitserver.NewClient(&http.Client{})
+		cli.Addrs = func(context.Context) []string { return test.addrs }
+		cli.ReplicationFactor = func(context.Context) int { return 2 }
+
+		// Check several repos, so that both replicas are the primary for
+		// some of them.
+		for i := 0; i < 10; i++ {
+			repo := api.RepoName(fmt.Sprintf("github.com/foo/bar%d", i))
+			isCloned, err := cli.IsRepoCloned(context.Background(), repo)
+			if err != nil {
+				t.Errorf("%s: %s: IsRepoCloned: %s", label, repo, err)
+			} else if isCloned != test.wantCloned {
+				t.Errorf("%s: %s: got cloned %v, want %v", label, repo, isCloned, test.wantCloned)
+			}
+		}
+	}
+
+	// Repos are removed from all replicas.
+	requests = nil
+	cli := gitserver.NewClient(&http.Client{})
+	cli.Addrs = func(context.Context) []string { return []string{host(notCloned), host(cloned)} }
+	cli.ReplicationFactor = func(context.Context) int { return 2 }
+	if err := cli.Remove(context.Background(), "github.com/foo/bar"); err == nil {
+		t.Error("Remove: expected error from replica responding with 404")
+	}
+	sort.Strings(requests)
+	if want := []string{"cloned /delete", "not-cloned /delete"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("got requests %q, want %q", requests, want)
+	}
+	// Ops that modify the repository are only sent to the primary, because
+	// a request that failed with a transport error may still have been
+	// performed.
+	requests = nil
+	addrs := []string{host(down), host(cloned)}
+	cli.Addrs = func(context.Context) []string { return addrs }
+	var wantRequests []string
+	for i := 0; i < 10; i++ {
+		repo := api.RepoName(fmt.Sprintf("github.com/foo/bar%d", i))
+		if gitserver.ReplicaAddrs(string(repo), addrs, 2)[0] == host(cloned) {
+			wantRequests = append(wantRequests, "cloned /create-commit-from-patch")
+		}
+		_, _ = cli.CreateCommitFromPatch(context.Background(), protocol.CreateCommitFromPatchRequest{Repo: repo})
+	}
+	if len(wantRequests) == 10 {
+		t.Fatal("want some repos whose primary is down")
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("got requests %q, want %q", requests, wantRequests)
+	}
+}
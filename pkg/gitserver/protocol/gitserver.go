@@ -44,6 +44,14 @@ type RepoUpdateRequest struct {
 	Repo  api.RepoName  `json:"repo"`  // identifying URL for repo
 	URL   string        `json:"url"`   // repo's remote URL
 	Since time.Duration `json:"since"` // debounce interval for queries, used only with request-repo-update
+
+	// Pinned is whether the repo is pinned. The gitserver records it with its
+	// clone of the repo, so that it is never removed to free up disk space.
+	Pinned bool `json:"pinned,omitempty"`
+
+	// Replicas are the addresses of the other gitservers holding a replica of
+	// the repo. The gitserver receiving the request forwards it to them.
+	Replicas []string `json:"replicas,omitempty"`
 }
 
 // RepoUpdateResponse returns meta information of the repo enqueued for
@@ -143,6 +151,30 @@ type RepoInfo struct {
 	// recloned automatically, so this time is likely to move forward
 	// periodically.
 	CloneTime *time.Time
+
+	// RebalanceInProgress is whether the repository is being moved to the
+	// gitservers that now hold it, after which it is deleted from this
+	// gitserver. RebalanceProgress is a progress message for the move.
+	RebalanceInProgress bool
+	RebalanceProgress   string
+
+	// Size is the size of the repository on disk in bytes, as of the last
+	// time it was computed (after the repository was last cloned, fetched or
+	// maintained).
+	Size int64
+	// LastAccessed is the time of the last exec or archive request for the
+	// repository, recorded to the minute.
+	LastAccessed *time.Time
+	// Pinned is whether the repository is exempt from being removed to free
+	// up disk space.
+	Pinned bool
+}
+
+// RepoPinRequest is a request to set whether a repository is pinned on
+// gitserver. Pinned repositories are never removed to free up disk space.
+type RepoPinRequest struct {
+	Repo   api.RepoName
+	Pinned bool
 }
 
 // RepoInfoResponse is the response to a repository information request
@@ -165,6 +197,22 @@ type CreateCommitFromPatchRequest struct {
 	TargetRef string
 	// CommitInfo is the information that will be used when creating the commit from a patch
 	CommitInfo PatchCommitInfo
+	// Push, if non-nil, is the information needed for pushing the commit to
+	// the repository's remote after it was created.
+	Push *PushConfig
+}
+
+// PushConfig is the information needed for pushing a commit created from a
+// patch to a branch on the repository's remote.
+type PushConfig struct {
+	// Branch is the name of the branch on the remote to push the commit to
+	// (e.g. "my-branch", not "refs/heads/my-branch").
+	Branch string
+	// ExpectedOID is the commit the branch must point to on the remote for the
+	// push to succeed, so that changes pushed to the branch by others aren't
+	// overwritten (like `git push --force-with-lease`). If empty, the branch
+	// must not exist on the remote yet.
+	ExpectedOID api.CommitID
 }
 
 // PatchCommitInfo will be used for commit information when creating a commit from a patch
@@ -180,4 +228,20 @@ type PatchCommitInfo struct {
 type CreatePatchFromPatchResponse struct {
 	// Rev is the tag that the staging object can be found at
 	Rev string
+	// Push is the result of pushing the commit, if requested.
+	Push *PushResult
+}
+
+// PushResult is the result of pushing a commit created from a patch to the
+// repository's remote.
+type PushResult struct {
+	// Ref is the ref on the remote that the commit was pushed to (e.g.
+	// "refs/heads/my-branch").
+	Ref string
+	// OID is the ID of the pushed commit.
+	OID api.CommitID
+	// RejectedReason is the reason given by the remote for rejecting the push
+	// (e.g. "stale info" if the branch didn't point to the expected commit).
+	// It is empty if the push succeeded.
+	RejectedReason string
 }
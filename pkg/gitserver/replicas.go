@@ -0,0 +1,178 @@
+package gitserver
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ringVirtualNodes is the number of points each address is placed at on the
+// hash ring. More points spread the keys more evenly over the addresses.
+const ringVirtualNodes = 128
+
+// ReplicaAddrs returns the addresses of the n gitservers (out of addrs) that
+// hold a replica of the repository (or other sharding key) key, primary
+// first.
+//
+// The primary is chosen by hashing the key modulo the number of addresses, so
+// that repositories stay on the gitserver they were cloned on before
+// replication was enabled. The other replicas are chosen among the remaining
+// addresses by rendezvous (highest random weight) hashing: every address is
+// ranked by a hash of the key and the address, and the n-1 highest ranked
+// addresses are chosen. The first n replicas of a key are therefore always the
+// first n of its n+1 replicas.
+//
+// Adding or removing an address moves the primaries of most keys. See
+// RingReplicaAddrs for an assignment that doesn't.
+func ReplicaAddrs(key string, addrs []string, n int) []string {
+	if len(addrs) == 0 {
+		return nil
+	}
+	n = clampReplicas(n, len(addrs))
+
+	primary := ringHash(key) % uint64(len(addrs))
+	replicas := make([]string, 0, n)
+	replicas = append(replicas, addrs[primary])
+	if n == 1 {
+		return replicas
+	}
+
+	type rankedAddr struct {
+		addr  string
+		score uint64
+	}
+	ranked := make([]rankedAddr, 0, len(addrs)-1)
+	for i, addr := range addrs {
+		if uint64(i) == primary {
+			continue
+		}
+		ranked = append(ranked, rankedAddr{addr: addr, score: ringHash(key + "\x00" + addr)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].addr < ranked[j].addr
+	})
+	for _, r := range ranked[:n-1] {
+		replicas = append(replicas, r.addr)
+	}
+	return replicas
+}
+
+// RingReplicaAddrs is like ReplicaAddrs, but places the addresses on a
+// consistent hash ring. The replicas of a key are the first n distinct
+// addresses found walking the ring clockwise from the hash of the key. Adding
+// or removing an address therefore only moves the replicas of about
+// 1/len(addrs) of the keys, and the first n replicas of a key are always the
+// first n of its n+1 replicas.
+//
+// The assignment differs from that of ReplicaAddrs for most keys, so
+// switching to it requires moving most repositories (see RebalanceRepos in
+// cmd/gitserver).
+func RingReplicaAddrs(key string, addrs []string, n int) []string {
+	if len(addrs) == 0 {
+		return nil
+	}
+	r := getRing(addrs)
+	n = clampReplicas(n, r.distinct) // a duplicated address is only one replica
+	h := ringHash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+
+	replicas := make([]string, 0, n)
+	for j := 0; len(replicas) < n; j++ {
+		addr := r.points[(i+j)%len(r.points)].addr
+		if !containsAddr(replicas, addr) {
+			replicas = append(replicas, addr)
+		}
+	}
+	return replicas
+}
+
+func clampReplicas(n, max int) int {
+	if n < 1 {
+		return 1
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+type ringPoint struct {
+	hash uint64
+	addr string
+}
+
+// ring is a consistent hash ring, i.e. the points of all addresses sorted by
+// hash.
+type ring struct {
+	addrs    []string
+	distinct int // the number of distinct addresses
+	points   []ringPoint
+}
+
+var (
+	ringMu   sync.Mutex
+	lastRing *ring // the most recently built ring, reused while addrs is unchanged
+)
+
+func getRing(addrs []string) *ring {
+	ringMu.Lock()
+	defer ringMu.Unlock()
+	if lastRing != nil && equalAddrs(lastRing.addrs, addrs) {
+		return lastRing
+	}
+
+	r := &ring{
+		addrs:  append([]string(nil), addrs...),
+		points: make([]ringPoint, 0, len(addrs)*ringVirtualNodes),
+	}
+	seen := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		r.distinct++
+		for i := 0; i < ringVirtualNodes; i++ {
+			r.points = append(r.points, ringPoint{hash: ringHash(addr + "#" + strconv.Itoa(i)), addr: addr})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+		return r.points[i].addr < r.points[j].addr
+	})
+	lastRing = r
+	return r
+}
+
+func ringHash(s string) uint64 {
+	sum := md5.Sum([]byte(s))
+	return binary.BigEndian.Uint64(sum[:])
+}
+
+func equalAddrs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAddr(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package gitserver_test
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+)
+
+func TestReplicaAddrs(t *testing.T) {
+	testReplicaAddrs(t, gitserver.ReplicaAddrs)
+
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2", "gitserver-3"}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("github.com/foo/repo%d", i)
+
+		// The primary is the gitserver the repo was on without replication.
+		sum := md5.Sum([]byte(key))
+		if have, want := gitserver.ReplicaAddrs(key, addrs, 2)[0], addrs[binary.BigEndian.Uint64(sum[:])%uint64(len(addrs))]; have != want {
+			t.Fatalf("%s: got primary %q, want %q", key, have, want)
+		}
+	}
+}
+
+func TestRingReplicaAddrs(t *testing.T) {
+	testReplicaAddrs(t, gitserver.RingReplicaAddrs)
+
+	// A duplicated address is only one replica.
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-0"}
+	for n, want := range map[int]int{1: 1, 2: 2, 3: 2} {
+		if have := gitserver.RingReplicaAddrs("repo", addrs, n); len(have) != want || (len(have) == 2 && have[0] == have[1]) {
+			t.Errorf("n=%d: got replicas %q, want %d distinct replicas", n, have, want)
+		}
+	}
+}
+
+func testReplicaAddrs(t *testing.T, replicaAddrs func(key string, addrs []string, n int) []string) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2", "gitserver-3"}
+
+	for n, want := range map[int]int{-1: 1, 0: 1, 1: 1, 3: 3, 4: 4, 10: 4} {
+		if have := len(replicaAddrs("repo", addrs, n)); have != want {
+			t.Errorf("n=%d: got %d replicas, want %d", n, have, want)
+		}
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("github.com/foo/repo%d", i)
+		all := replicaAddrs(key, addrs, len(addrs))
+
+		seen := map[string]bool{}
+		for _, addr := range all {
+			if seen[addr] {
+				t.Fatalf("%s: got duplicate replica %q in %q", key, addr, all)
+			}
+			seen[addr] = true
+		}
+
+		for n := 1; n < len(addrs); n++ {
+			if have := replicaAddrs(key, addrs, n); !reflect.DeepEqual(have, all[:n]) {
+				t.Fatalf("%s: got replicas %q, want %q", key, have, all[:n])
+			}
+		}
+
+		for _, addr := range all[:2] {
+			counts[addr]++
+		}
+	}
+	for addr, count := range counts {
+		if count < 400 || count > 600 {
+			t.Errorf("%s holds %d of 2000 replicas, want about 500", addr, count)
+		}
+	}
+}
+
+func TestRingReplicaAddrs_resharding(t *testing.T) {
+	addrs := []string{"gitserver-0", "gitserver-1", "gitserver-2", "gitserver-3"}
+	added := append(append([]string{}, addrs...), "gitserver-4")
+	removed := []string{"gitserver-0", "gitserver-1", "gitserver-3"}
+
+	var movedOnAdd, movedOnRemove int
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("github.com/foo/repo%d", i)
+		before := gitserver.RingReplicaAddrs(key, addrs, 1)[0]
+
+		// Adding a gitserver only moves repos to the new gitserver.
+		if after := gitserver.RingReplicaAddrs(key, added, 1)[0]; after != before {
+			if after != "gitserver-4" {
+				t.Fatalf("%s: moved from %q to %q after adding gitserver-4", key, before, after)
+			}
+			movedOnAdd++
+		}
+
+		// Removing a gitserver only moves the repos it held.
+		if after := gitserver.RingReplicaAddrs(key, removed, 1)[0]; after != before {
+			if before != "gitserver-2" {
+				t.Fatalf("%s: moved from %q to %q after removing gitserver-2", key, before, after)
+			}
+			movedOnRemove++
+		}
+	}
+	if movedOnAdd < 100 || movedOnAdd > 300 {
+		t.Errorf("%d of 1000 repos moved after adding a 5th gitserver, want about 200", movedOnAdd)
+	}
+	if movedOnRemove < 150 || movedOnRemove > 350 {
+		t.Errorf("%d of 1000 repos moved after removing 1 of 4 gitservers, want about 250", movedOnRemove)
+	}
+}
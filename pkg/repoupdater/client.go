@@ -264,6 +264,79 @@ func (c *Client) ExcludeRepo(ctx context.Context, id uint32) (*protocol.ExcludeR
 	return &res, nil
 }
 
+// MockSyncChangesets mocks (*Client).SyncChangesets for tests.
+var MockSyncChangesets func(ctx context.Context, ids []int64) error
+
+// SyncChangesets requests repo-updater to sync the changesets with the given
+// IDs from their code hosts.
+func (c *Client) SyncChangesets(ctx context.Context, ids []int64) error {
+	if MockSyncChangesets != nil {
+		return MockSyncChangesets(ctx, ids)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	req := protocol.ChangesetSyncRequest{IDs: ids}
+	resp, err := c.httpPost(ctx, "sync-changesets", &req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read response body")
+	}
+
+	var res protocol.ChangesetSyncResponse
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return errors.New(string(bs))
+	} else if err = json.Unmarshal(bs, &res); err != nil {
+		return err
+	}
+
+	if res.Error != "" {
+		return errors.New(res.Error)
+	}
+	return nil
+}
+
+// MockCreateChangeset mocks (*Client).CreateChangeset for tests.
+var MockCreateChangeset func(ctx context.Context, req *protocol.ChangesetCreateRequest) (externalID string, err error)
+
+// CreateChangeset requests repo-updater to open a changeset on the code host
+// of a repository. It returns the ID of the changeset on the code host.
+func (c *Client) CreateChangeset(ctx context.Context, req *protocol.ChangesetCreateRequest) (externalID string, err error) {
+	if MockCreateChangeset != nil {
+		return MockCreateChangeset(ctx, req)
+	}
+
+	resp, err := c.httpPost(ctx, "create-changeset", req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read response body")
+	}
+
+	var res protocol.ChangesetCreateResponse
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return "", errors.New(string(bs))
+	} else if err = json.Unmarshal(bs, &res); err != nil {
+		return "", err
+	}
+
+	if res.Error != "" {
+		return "", errors.New(res.Error)
+	}
+	return res.ExternalID, nil
+}
+
 // MockStatusMessages mocks (*Client).StatusMessages for tests.
 var MockStatusMessages func(context.Context) (*protocol.StatusMessagesResponse, error)
 
@@ -59,6 +59,38 @@ type ExcludeRepoResponse struct {
 	ExternalServices []api.ExternalService
 }
 
+// ChangesetSyncRequest is a request to sync the changesets with the given IDs
+// from their code hosts.
+type ChangesetSyncRequest struct {
+	IDs []int64
+}
+
+// ChangesetSyncResponse is returned in response to a ChangesetSyncRequest.
+type ChangesetSyncResponse struct {
+	// Error is the error that occurred while syncing the changesets, if any.
+	Error string
+}
+
+// ChangesetCreateRequest is a request to open a changeset (such as a pull
+// request) on the code host of a repository.
+type ChangesetCreateRequest struct {
+	RepoID api.RepoID
+	Title  string
+	Body   string
+	// BaseRef is the full name of the ref that the changeset is merged into.
+	BaseRef string
+	// HeadRef is the full name of the ref of the repository with the changes.
+	HeadRef string
+}
+
+// ChangesetCreateResponse is returned in response to a ChangesetCreateRequest.
+type ChangesetCreateResponse struct {
+	// ExternalID is the ID of the created changeset on the code host.
+	ExternalID string
+	// Error is the error that occurred while creating the changeset, if any.
+	Error string
+}
+
 // RepoLookupArgs is a request for information about a repository on repoupdater.
 //
 // Exactly one of Repo and ExternalRepo should be set.
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/diskcache"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+)
+
+// maxAncestorsToSearch is the number of ancestors of a commit which are
+// checked for an existing zip to build the commit's zip from.
+const maxAncestorsToSearch = 100
+
+// maxPathsPerFetch is the maximum number of paths included in a single
+// archive request when fetching the files changed since an ancestor.
+const maxPathsPerFetch = 500
+
+// canFetchIncrementally reports whether the store is configured to build the
+// zip of a commit from the zip of an ancestor.
+func (s *Store) canFetchIncrementally() bool {
+	return s.ListAncestors != nil && s.GitDiff != nil && s.FetchTarPaths != nil
+}
+
+// writeZip writes a zip of repo at commit to the blank file at path. If the
+// zip of an ancestor of the commit is in the cache, the unchanged files are
+// copied from it and only the files which changed since the ancestor are
+// fetched. Otherwise the whole archive is fetched.
+func (s *Store) writeZip(ctx context.Context, path string, repo gitserver.Repo, commit api.CommitID, largeFilePatterns []string) error {
+	if s.canFetchIncrementally() {
+		ok, err := s.writeChangedZip(ctx, path, repo, commit, largeFilePatterns)
+		if ok {
+			zipBuilds.WithLabelValues("incremental").Inc()
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("failed to build zip of %s@%s incrementally, fetching the whole archive instead: %s", repo.Name, commit, err)
+		}
+	}
+
+	rc, err := s.fetch(ctx, repo, commit, largeFilePatterns)
+	if err != nil {
+		return err
+	}
+	if err := copyToFile(path, rc); err != nil {
+		return err
+	}
+	zipBuilds.WithLabelValues("full").Inc()
+	return nil
+}
+
+// writeChangedZip writes a zip of repo at commit to the file at path, copying
+// the files which didn't change since the nearest ancestor of the commit whose
+// zip is in the cache from that zip, and fetching the others. It returns false
+// if no ancestor is in the cache.
+func (s *Store) writeChangedZip(ctx context.Context, path string, repo gitserver.Repo, commit api.CommitID, largeFilePatterns []string) (ok bool, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Store.writeChangedZip")
+	ext.Component.Set(span, "store")
+	defer func() {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(otlog.Error(err))
+		}
+		span.Finish()
+	}()
+
+	ancestor, ancestorZip, err := s.findCachedAncestor(ctx, repo.Name, commit, largeFilePatterns)
+	if err != nil || ancestorZip == nil {
+		return false, err
+	}
+	defer ancestorZip.Close()
+	span.SetTag("ancestor", string(ancestor))
+
+	changes, err := s.GitDiff(ctx, repo.Name, ancestor, commit)
+	if err != nil {
+		return false, err
+	}
+	span.LogFields(
+		otlog.Int("added", len(changes.Added)),
+		otlog.Int("modified", len(changes.Modified)),
+		otlog.Int("deleted", len(changes.Deleted)),
+	)
+
+	fi, err := ancestorZip.Stat()
+	if err != nil {
+		return false, err
+	}
+	zr, err := zip.NewReader(ancestorZip.File, fi.Size())
+	if err != nil {
+		return false, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err1 := f.Close(); err == nil && err1 != nil {
+			ok, err = false, err1
+		}
+	}()
+	zw := zip.NewWriter(f)
+
+	changed := make(map[string]struct{}, len(changes.Added)+len(changes.Modified)+len(changes.Deleted))
+	for _, paths := range [][]string{changes.Added, changes.Modified, changes.Deleted} {
+		for _, path := range paths {
+			changed[path] = struct{}{}
+		}
+	}
+	saved, err := copyUnchanged(zw, zr, changed)
+	if err != nil {
+		return false, err
+	}
+
+	paths := append(append([]string{}, changes.Added...), changes.Modified...)
+	for len(paths) > 0 {
+		n := len(paths)
+		if n > maxPathsPerFetch {
+			n = maxPathsPerFetch
+		}
+		if err := s.fetchPaths(ctx, zw, repo, commit, paths[:n], largeFilePatterns); err != nil {
+			return false, err
+		}
+		paths = paths[n:]
+	}
+
+	if err := zw.Close(); err != nil {
+		return false, err
+	}
+	fetchBytesSaved.Add(float64(saved))
+	return true, nil
+}
+
+// findCachedAncestor returns the nearest ancestor of repo@commit whose zip
+// is in the cache, along with the opened zip file. The file is nil if no such
+// ancestor was found.
+func (s *Store) findCachedAncestor(ctx context.Context, repo api.RepoName, commit api.CommitID, largeFilePatterns []string) (api.CommitID, *diskcache.File, error) {
+	ancestors, err := s.ListAncestors(ctx, repo, commit, maxAncestorsToSearch)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, ancestor := range ancestors {
+		if ancestor == commit {
+			continue
+		}
+		f, err := s.cache.OpenIfExists(zipCacheKey(repo, ancestor, largeFilePatterns))
+		if err == nil {
+			return ancestor, f, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, err
+		}
+	}
+	return "", nil, nil
+}
+
+// copyUnchanged copies the files in zr which are not in changed to zw. It
+// returns the total size of the copied files.
+func copyUnchanged(zw *zip.Writer, zr *zip.Reader, changed map[string]struct{}) (size int64, err error) {
+	// 32*1024 is the same size used by io.Copy
+	buf := make([]byte, 32*1024)
+	for _, file := range zr.File {
+		if _, ok := changed[file.Name]; ok {
+			continue
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:   file.Name,
+			Method: zip.Store,
+		})
+		if err != nil {
+			return size, err
+		}
+		r, err := file.Open()
+		if err != nil {
+			return size, err
+		}
+		n, err := io.CopyBuffer(w, r, buf)
+		r.Close()
+		if err != nil {
+			return size, err
+		}
+		size += n
+	}
+	return size, nil
+}
+
+// fetchPaths fetches an archive of the files at paths in repo@commit and
+// writes the searchable ones to zw.
+func (s *Store) fetchPaths(ctx context.Context, zw *zip.Writer, repo gitserver.Repo, commit api.CommitID, paths []string, largeFilePatterns []string) (err error) {
+	fetchQueueSize.Inc()
+	ctx, releaseFetchLimiter, err := s.fetchLimiter.Acquire(ctx) // Acquire concurrent fetches semaphore
+	fetchQueueSize.Dec()
+	if err != nil {
+		return err // err will be a context error
+	}
+	defer releaseFetchLimiter()
+
+	// Like the archive of a whole commit, we expect the archive of some of
+	// its paths to finish relatively quickly.
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	fetching.Inc()
+	defer func() {
+		if err != nil {
+			fetchFailed.Inc()
+		}
+		fetching.Dec()
+	}()
+
+	r, err := s.FetchTarPaths(ctx, repo, commit, paths)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return copySearchable(tar.NewReader(r), zw, largeFilePatterns)
+}
+
+// copyToFile copies the contents of rc to the existing file at path and
+// closes rc.
+func copyToFile(path string, rc io.ReadCloser) error {
+	defer rc.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var (
+	zipBuilds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "searcher",
+		Subsystem: "store",
+		Name:      "zip_builds",
+		Help:      "The total number of zips built, by whether they were derived from an ancestor's (incremental) or fetched whole (full).",
+	}, []string{"type"})
+	fetchBytesSaved = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "searcher",
+		Subsystem: "store",
+		Name:      "fetch_bytes_saved",
+		Help:      "The total size of the unchanged files copied from an ancestor's zip instead of being fetched.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(zipBuilds)
+	prometheus.MustRegister(fetchBytesSaved)
+}
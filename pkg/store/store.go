@@ -20,6 +20,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/diskcache"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -53,6 +54,21 @@ type Store struct {
 	// determine if the error is a bad request (eg invalid repo).
 	FetchTar func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error)
 
+	// FetchTarPaths is like FetchTar, but the archive only includes the files at the given paths.
+	FetchTarPaths func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error)
+
+	// GitDiff returns the paths of the files which changed between two commits of a repository.
+	GitDiff func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (git.Changes, error)
+
+	// ListAncestors returns up to n ancestors of a commit (including the commit itself), nearest
+	// first.
+	//
+	// If FetchTarPaths, GitDiff, and ListAncestors are all set, the zip of a commit is built from
+	// the zip of its nearest ancestor already in the cache by copying the unchanged files and
+	// fetching only the files which changed since the ancestor. Otherwise, the whole archive is
+	// fetched for every commit.
+	ListAncestors func(ctx context.Context, repo api.RepoName, commit api.CommitID, n int) ([]api.CommitID, error)
+
 	// Path is the directory to store the cache
 	Path string
 
@@ -130,9 +146,7 @@ func (s *Store) PrepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 
 	largeFilePatterns := conf.Get().SearchLargeFiles
 
-	// key is a sha256 hash since we want to use it for the disk name
-	h := sha256.Sum256([]byte(fmt.Sprintf("%q %q %q", repo.Name, commit, largeFilePatterns)))
-	key := hex.EncodeToString(h[:])
+	key := zipCacheKey(repo.Name, commit, largeFilePatterns)
 	span.LogKV("key", key)
 
 	// Our fetch can take a long time, and the frontend aggressively cancels
@@ -146,8 +160,8 @@ func (s *Store) PrepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 		// TODO: consider adding a cache method that doesn't actually bother opening the file,
 		// since we're just going to close it again immediately.
 		bgctx := opentracing.ContextWithSpan(context.Background(), opentracing.SpanFromContext(ctx))
-		f, err := s.cache.Open(bgctx, key, func(ctx context.Context) (io.ReadCloser, error) {
-			return s.fetch(ctx, repo, commit, largeFilePatterns)
+		f, err := s.cache.OpenWithPath(bgctx, key, func(ctx context.Context, path string) error {
+			return s.writeZip(ctx, path, repo, commit, largeFilePatterns)
 		})
 		var path string
 		if f != nil {
@@ -171,6 +185,15 @@ func (s *Store) PrepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 	}
 }
 
+// zipCacheKey returns the key of the zip of repo at commit in the disk cache.
+// Zips are only shared by requests with the same large file patterns, since
+// the patterns determine which files' contents are included.
+func zipCacheKey(repo api.RepoName, commit api.CommitID, largeFilePatterns []string) string {
+	// key is a sha256 hash since we want to use it for the disk name
+	h := sha256.Sum256([]byte(fmt.Sprintf("%q %q %q", repo, commit, largeFilePatterns)))
+	return hex.EncodeToString(h[:])
+}
+
 // fetch fetches an archive from the network and stores it on disk. It does
 // not populate the in-memory cache. You should probably be calling
 // prepareZip.
@@ -2,11 +2,14 @@ package store
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"context"
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"sort"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -14,6 +17,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
 )
 
 func TestPrepareZip(t *testing.T) {
@@ -117,6 +121,83 @@ func TestPrepareZip_errHeader(t *testing.T) {
 	}
 }
 
+func TestPrepareZip_incremental(t *testing.T) {
+	s, cleanup := tmpStore(t)
+	defer cleanup()
+
+	repo := gitserver.Repo{Name: "foo"}
+	parent := api.CommitID("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	child := api.CommitID("cafebabecafebabecafebabecafebabecafebabe")
+	commits := map[api.CommitID]map[string]string{
+		parent: {"a.txt": "a", "b.txt": "b", "c.txt": "c"},
+		child:  {"a.txt": "a2", "c.txt": "c", "d.txt": "d"},
+	}
+
+	var fetchedAll []api.CommitID
+	var fetchedPaths []string
+	var diffErr error
+	s.FetchTar = func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
+		fetchedAll = append(fetchedAll, commit)
+		return createTar(t, commits[commit]), nil
+	}
+	s.FetchTarPaths = func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+		files := map[string]string{}
+		for _, path := range paths {
+			files[path] = commits[commit][path]
+		}
+		fetchedPaths = append(fetchedPaths, paths...)
+		return createTar(t, files), nil
+	}
+	s.GitDiff = func(ctx context.Context, repo api.RepoName, commitA, commitB api.CommitID) (git.Changes, error) {
+		if commitA != parent || commitB != child {
+			t.Fatalf("unexpected diff %s..%s", commitA, commitB)
+		}
+		return git.Changes{Added: []string{"d.txt"}, Modified: []string{"a.txt"}, Deleted: []string{"b.txt"}}, diffErr
+	}
+	s.ListAncestors = func(ctx context.Context, repo api.RepoName, commit api.CommitID, n int) ([]api.CommitID, error) {
+		if commit == child {
+			return []api.CommitID{child, parent}, nil
+		}
+		return []api.CommitID{commit}, nil
+	}
+
+	if _, err := s.PrepareZip(context.Background(), repo, parent); err != nil {
+		t.Fatal(err)
+	}
+	path, err := s.PrepareZip(context.Background(), repo, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []api.CommitID{parent}; !reflect.DeepEqual(fetchedAll, want) {
+		t.Errorf("fetched whole archives of %v, want %v", fetchedAll, want)
+	}
+	sort.Strings(fetchedPaths)
+	if want := []string{"a.txt", "d.txt"}; !reflect.DeepEqual(fetchedPaths, want) {
+		t.Errorf("fetched paths %q, want %q", fetchedPaths, want)
+	}
+	if got := readZip(t, path); !reflect.DeepEqual(got, commits[child]) {
+		t.Errorf("got zip %v, want %v", got, commits[child])
+	}
+
+	// Fall back to fetching the whole archive if the diff fails.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	diffErr = errors.New("test")
+	fetchedAll = nil
+	path, err = s.PrepareZip(context.Background(), repo, child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []api.CommitID{child}; !reflect.DeepEqual(fetchedAll, want) {
+		t.Errorf("fetched whole archives of %v, want %v", fetchedAll, want)
+	}
+	if got := readZip(t, path); !reflect.DeepEqual(got, commits[child]) {
+		t.Errorf("got zip %v, want %v", got, commits[child])
+	}
+}
+
 func TestIngoreSizeMax(t *testing.T) {
 	patterns := []string{
 		"foo",
@@ -167,3 +248,47 @@ func emptyTar(t *testing.T) io.ReadCloser {
 	}
 	return ioutil.NopCloser(bytes.NewReader(buf.Bytes()))
 }
+
+func createTar(t *testing.T, files map[string]string) io.ReadCloser {
+	buf := new(bytes.Buffer)
+	w := tar.NewWriter(buf)
+	for name, body := range files {
+		hdr := &tar.Header{
+			Name: name,
+			Mode: 0600,
+			Size: int64(len(body)),
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(buf.Bytes()))
+}
+
+func readZip(t *testing.T, path string) map[string]string {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+	}
+	return files
+}
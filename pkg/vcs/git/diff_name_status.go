@@ -0,0 +1,74 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+)
+
+// Changes are the paths of the files which differ between two commits.
+type Changes struct {
+	Added    []string
+	Modified []string
+	Deleted  []string
+}
+
+// DiffNameStatus returns the paths of the files which differ between commits
+// a and b. Renamed files are reported as deleted and added.
+func DiffNameStatus(ctx context.Context, repo gitserver.Repo, a, b api.CommitID) (Changes, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: DiffNameStatus")
+	span.SetTag("A", a)
+	span.SetTag("B", b)
+	defer span.Finish()
+
+	if err := checkSpecArgSafety(string(a)); err != nil {
+		return Changes{}, err
+	}
+	if err := checkSpecArgSafety(string(b)); err != nil {
+		return Changes{}, err
+	}
+
+	cmd := gitserver.DefaultClient.Command("git", "diff", "-z", "--name-status", "--no-renames", string(a), string(b))
+	cmd.Repo = repo
+	out, err := cmd.Output(ctx)
+	if err != nil {
+		return Changes{}, errors.WithMessage(err, fmt.Sprintf("git command %v failed", cmd.Args))
+	}
+	return ParseDiffNameStatus(out)
+}
+
+// ParseDiffNameStatus parses the output of `git diff -z --name-status
+// --no-renames`.
+func ParseDiffNameStatus(out []byte) (Changes, error) {
+	var changes Changes
+	fields := bytes.Split(bytes.TrimSuffix(out, []byte{0}), []byte{0})
+	if len(fields) == 1 && len(fields[0]) == 0 {
+		return changes, nil
+	}
+	if len(fields)%2 != 0 {
+		return changes, fmt.Errorf("unexpected git diff output: %q", out)
+	}
+
+	for i := 0; i < len(fields); i += 2 {
+		status, path := fields[i], string(fields[i+1])
+		if len(status) == 0 {
+			return changes, fmt.Errorf("unexpected git diff output: %q", out)
+		}
+		switch status[0] {
+		case 'A':
+			changes.Added = append(changes.Added, path)
+		case 'M', 'T':
+			changes.Modified = append(changes.Modified, path)
+		case 'D':
+			changes.Deleted = append(changes.Deleted, path)
+		default:
+			return changes, fmt.Errorf("unexpected git diff status %q for %q", status, path)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,29 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiffNameStatus(t *testing.T) {
+	changes, err := ParseDiffNameStatus([]byte("A\x00new.go\x00M\x00sub/mod ified.go\x00D\x00old.go\x00T\x00link\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Changes{
+		Added:    []string{"new.go"},
+		Modified: []string{"sub/mod ified.go", "link"},
+		Deleted:  []string{"old.go"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("got %+v, want %+v", changes, want)
+	}
+
+	if changes, err := ParseDiffNameStatus(nil); err != nil || !reflect.DeepEqual(changes, Changes{}) {
+		t.Errorf("got (%+v, %v) for empty output", changes, err)
+	}
+
+	if _, err := ParseDiffNameStatus([]byte("R100\x00a.go\x00b.go\x00")); err == nil {
+		t.Error("expected error for unexpected output")
+	}
+}
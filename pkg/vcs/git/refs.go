@@ -174,6 +174,50 @@ func branches(ctx context.Context, repo gitserver.Repo, args ...string) ([]strin
 	return branches, nil
 }
 
+// A Ref is a Git reference, such as a branch or a tag.
+type Ref struct {
+	// Name is the full name of the ref, such as "refs/heads/master".
+	Name string
+
+	// CommitID is the ID of the commit that the ref points to. Annotated tags
+	// are dereferenced to the commit that they tag.
+	CommitID api.CommitID
+}
+
+// ListRefs returns all refs in the repository, sorted by name.
+func ListRefs(ctx context.Context, repo gitserver.Repo) ([]Ref, error) {
+	if Mocks.ListRefs != nil {
+		return Mocks.ListRefs()
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: ListRefs")
+	defer span.Finish()
+
+	lines, err := showRef(ctx, repo, "--dereference")
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []Ref
+	dereferenced := map[string]api.CommitID{}
+	for _, line := range lines {
+		// With --dereference, the commit tagged by an annotated tag is listed
+		// with the tag's name followed by "^{}".
+		if name := strings.TrimSuffix(line[1], "^{}"); name != line[1] {
+			dereferenced[name] = api.CommitID(line[0])
+			continue
+		}
+		refs = append(refs, Ref{Name: line[1], CommitID: api.CommitID(line[0])})
+	}
+	for i, ref := range refs {
+		if commitID, ok := dereferenced[ref.Name]; ok {
+			refs[i].CommitID = commitID
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs, nil
+}
+
 // GetBehindAhead returns the behind/ahead commit counts information for right vs. left (both Git
 // revspecs).
 func GetBehindAhead(ctx context.Context, repo gitserver.Repo, left, right string) (*BehindAhead, error) {
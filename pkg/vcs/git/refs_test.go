@@ -45,6 +45,31 @@ func TestRepository_ListBranches(t *testing.T) {
 	}
 }
 
+func TestRepository_ListRefs(t *testing.T) {
+	t.Parallel()
+
+	gitCommands := []string{
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit --allow-empty -m foo --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+		"git checkout -b b0",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git tag -a t0 -m tag",
+		"git tag t1",
+	}
+	const commitID = "ea167fe3d76b1e5fd3ed8ca44cbd2fe3897684f8"
+	refs, err := git.ListRefs(ctx, gittest.MakeGitRepository(t, gitCommands...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []git.Ref{
+		{Name: "refs/heads/b0", CommitID: commitID},
+		{Name: "refs/heads/master", CommitID: commitID},
+		{Name: "refs/tags/t0", CommitID: commitID},
+		{Name: "refs/tags/t1", CommitID: commitID},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got refs == %v, want %v", gittest.AsJSON(refs), gittest.AsJSON(want))
+	}
+}
+
 func TestRepository_Branches_MergedInto(t *testing.T) {
 	t.Parallel()
 
@@ -302,3 +302,54 @@ func lsTreeUncached(ctx context.Context, repo gitserver.Repo, commit api.CommitI
 
 	return fis, nil
 }
+
+// BlobOIDs returns the OIDs of the blobs at the given paths in the tree of
+// commit, keyed by path. Paths that don't exist or that aren't files are
+// omitted.
+func BlobOIDs(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (map[string]OID, error) {
+	if Mocks.BlobOIDs != nil {
+		return Mocks.BlobOIDs(commit, paths)
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Git: BlobOIDs")
+	span.SetTag("Commit", commit)
+	span.SetTag("Paths", len(paths))
+	defer span.Finish()
+
+	ensureAbsCommit(commit)
+	oids := make(map[string]OID, len(paths))
+	if len(paths) == 0 {
+		return oids, nil
+	}
+
+	args := append([]string{"ls-tree", "--full-name", "-z", string(commit), "--"}, paths...)
+	cmd := gitserver.DefaultClient.Command("git", args...)
+	cmd.Repo = repo
+	out, err := cmd.Output(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git ls-tree %s failed", commit))
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00") {
+		if line == "" {
+			continue
+		}
+		tabPos := strings.IndexByte(line, '\t')
+		if tabPos == -1 {
+			return nil, fmt.Errorf("invalid `git ls-tree` output: %q", line)
+		}
+		info := strings.Fields(line[:tabPos])
+		if len(info) != 3 {
+			return nil, fmt.Errorf("invalid `git ls-tree` output: %q", line)
+		}
+		if info[1] != "blob" {
+			continue
+		}
+		oid, err := decodeOID(info[2])
+		if err != nil {
+			return nil, err
+		}
+		oids[line[tabPos+1:]] = oid
+	}
+	return oids, nil
+}
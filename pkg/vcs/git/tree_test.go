@@ -380,6 +380,40 @@ func TestRepository_FileSystem_quoteChars(t *testing.T) {
 	}
 }
 
+func TestBlobOIDs(t *testing.T) {
+	t.Parallel()
+
+	repo := gittest.MakeGitRepository(t,
+		"mkdir dir",
+		"echo a > dir/a.txt",
+		"echo a > b.txt",
+		"echo c > c.txt",
+		"git add dir b.txt c.txt",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m commit1 --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+	)
+	commitID, err := git.ResolveRevision(ctx, repo, nil, "master", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oids, err := git.BlobOIDs(ctx, repo, commitID, []string{"dir/a.txt", "b.txt", "c.txt", "dir", "missing.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for path, oid := range oids {
+		got[path] = oid.String()
+	}
+	want := map[string]string{
+		"dir/a.txt": "78981922613b2afb6025042ff6bd878ac1994e85",
+		"b.txt":     "78981922613b2afb6025042ff6bd878ac1994e85",
+		"c.txt":     "f2ad6c76f0115a6ba5b00456a849810e7ec0af20",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
 func TestRepository_FileSystem_gitSubmodules(t *testing.T) {
 	t.Parallel()
 
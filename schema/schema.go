@@ -30,7 +30,8 @@ type AWSCodeCommitGitCredentials struct {
 
 // AuthAccessTokens description: Settings for access tokens, which enable external tools to access the Sourcegraph API with the privileges of the user.
 type AuthAccessTokens struct {
-	Allow string `json:"allow,omitempty"`
+	Allow          string   `json:"allow,omitempty"`
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
 }
 
 // AuthProviderCommon description: Common properties for authentication providers.
@@ -210,8 +211,9 @@ type CriticalConfiguration struct {
 
 // Discussions description: Configures Sourcegraph code discussions.
 type Discussions struct {
-	AbuseEmails     []string `json:"abuseEmails,omitempty"`
-	AbuseProtection bool     `json:"abuseProtection,omitempty"`
+	AbuseEmails          []string              `json:"abuseEmails,omitempty"`
+	AbuseProtection      bool                  `json:"abuseProtection,omitempty"`
+	ImportReviewComments *ImportReviewComments `json:"importReviewComments,omitempty"`
 }
 type ExcludedAWSCodeCommitRepo struct {
 	Id   string `json:"id,omitempty"`
@@ -379,6 +381,13 @@ func (v *IdentityProvider) UnmarshalJSON(data []byte) error {
 	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"oauth", "username", "external"})
 }
 
+// ImportReviewComments description: Imports the review comments on the pull requests of the given repositories from GitHub and Bitbucket Server as discussion threads. New and edited comments are imported periodically.
+type ImportReviewComments struct {
+	Author   string   `json:"author"`
+	Interval int      `json:"interval,omitempty"`
+	Repos    []string `json:"repos"`
+}
+
 // Log description: Configuration for logging and alerting, including to external services.
 type Log struct {
 	Sentry *Sentry `json:"sentry,omitempty"`
@@ -501,6 +510,7 @@ type Settings struct {
 type SiteConfiguration struct {
 	AuthAccessTokens                  *AuthAccessTokens           `json:"auth.accessTokens,omitempty"`
 	Branding                          *Branding                   `json:"branding,omitempty"`
+	ChangesetSyncInterval             int                         `json:"changesetSyncInterval,omitempty"`
 	CorsOrigin                        string                      `json:"corsOrigin,omitempty"`
 	DebugSearchSymbolsParallelism     int                         `json:"debug.search.symbolsParallelism,omitempty"`
 	DisableAutoGitUpdates             bool                        `json:"disableAutoGitUpdates,omitempty"`
@@ -525,6 +535,7 @@ type SiteConfiguration struct {
 	SearchIndexEnabled                *bool                       `json:"search.index.enabled,omitempty"`
 	SearchIndexSymbolsEnabled         *bool                       `json:"search.index.symbols.enabled,omitempty"`
 	SearchLargeFiles                  []string                    `json:"search.largeFiles,omitempty"`
+	UsageStatisticsRetentionDays      int                         `json:"usageStatistics.retentionDays,omitempty"`
 }
 type UsernameIdentity struct {
 	Type string `json:"type"`
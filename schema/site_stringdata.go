@@ -144,12 +144,26 @@ const SiteSchemaJSON = `{
       "default": 1,
       "group": "External services"
     },
+    "changesetSyncInterval": {
+      "description": "Interval (in minutes) for syncing the state of the changesets tracked by campaigns from their code hosts.",
+      "type": "integer",
+      "default": 15,
+      "minimum": 1,
+      "group": "Experimental"
+    },
     "maxReposToSearch": {
       "description": "The maximum number of repositories to search across. The user is prompted to narrow their query if exceeded. Any value less than or equal to zero means unlimited.",
       "type": "integer",
       "default": -1,
       "group": "Search"
     },
+    "usageStatistics.retentionDays": {
+      "description": "The number of days that usage events (such as page views and search queries) are kept in the event log, which site admins can query for usage statistics over arbitrary time ranges. Older events are deleted periodically.",
+      "type": "integer",
+      "minimum": 1,
+      "default": 365,
+      "group": "Misc."
+    },
     "parentSourcegraph": {
       "description": "URL to fetch unreachable repository details from. Defaults to \"https://sourcegraph.com\"",
       "type": "object",
@@ -172,6 +186,14 @@ const SiteSchemaJSON = `{
           "type": "string",
           "enum": ["all-users-create", "site-admin-create", "none"],
           "default": "all-users-create"
+        },
+        "trustedProxies": {
+          "description": "The addresses (in CIDR notation) of the reverse proxies in front of Sourcegraph that set the X-Forwarded-For header. The access token audit log records the client address from the X-Forwarded-For header only for requests from these proxies, and the address of the connection otherwise. In the single-container Docker image, the proxy is at 127.0.0.1/32.",
+          "type": "array",
+          "items": {
+            "type": "string"
+          },
+          "examples": [["127.0.0.1/32", "10.0.0.0/8"]]
         }
       },
       "default": {
@@ -362,6 +384,29 @@ const SiteSchemaJSON = `{
           "type": "array",
           "items": { "type": "string" },
           "default": []
+        },
+        "importReviewComments": {
+          "description": "Imports the review comments on the pull requests of the given repositories from GitHub and Bitbucket Server as discussion threads. New and edited comments are imported periodically.",
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["repos", "author"],
+          "properties": {
+            "repos": {
+              "description": "Names of the repositories (such as \"github.com/foo/bar\") whose pull request review comments are imported.",
+              "type": "array",
+              "items": { "type": "string" }
+            },
+            "author": {
+              "description": "Username of the Sourcegraph user who is the author of the imported threads and comments. Each imported comment names its author on the code host.",
+              "type": "string"
+            },
+            "interval": {
+              "description": "Interval (in minutes) between imports of new and edited review comments.",
+              "type": "integer",
+              "default": 15,
+              "minimum": 1
+            }
+          }
         }
       },
       "group": "Experimental",